@@ -12,13 +12,23 @@ import (
 	"app-platform-backend/internal/api/v1/admin"
 	"app-platform-backend/internal/api/v1/app"
 	moduleapi "app-platform-backend/internal/api/v1/module"
+	orgapi "app-platform-backend/internal/api/v1/org"
 	statsapi "app-platform-backend/internal/api/v1/stats"
 	"app-platform-backend/internal/api/v1/system"
+	userapi "app-platform-backend/internal/api/v1/user"
 	wsapi "app-platform-backend/internal/api/v1/websocket"
+	"app-platform-backend/internal/appauth"
 	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/dashboard"
+	"app-platform-backend/internal/identity"
 	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/quota"
 	"app-platform-backend/internal/scheduler"
+	"app-platform-backend/internal/userban"
 
 	// 导入所有功能模块（通过 import 的副作用触发模块注册）
 	_ "app-platform-backend/modules"
@@ -42,6 +52,54 @@ func main() {
 	// 初始化JWT
 	middleware.InitJWT(&cfg.JWT)
 
+	// 初始化管理员安全策略（双因素认证、登录锁定、密码策略）
+	if err := admin.InitSecurity(&cfg.Security); err != nil {
+		log.Fatalf("Failed to init admin security: %v", err)
+	}
+
+	// 空数据库时根据环境变量创建首个管理员
+	if err := admin.BootstrapFirstAdmin(); err != nil {
+		log.Fatalf("Failed to bootstrap admin: %v", err)
+	}
+
+	// 启用会话状态校验（管理员禁用/删除后令牌立即失效）
+	middleware.InitSessionDB(database.GetDB())
+
+	// 应用密钥加密存储及客户端SDK签名认证
+	secretBox, err := secretbox.New(cfg.SDK.SecretEncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to init app secret encryption: %v", err)
+	}
+	app.InitSecrets(secretBox, &cfg.SDK)
+	app.InitPurge(&cfg.AppPurge)
+	userapi.InitImport(&cfg.UserImport)
+	if err := app.MigrateLegacySecrets(); err != nil {
+		log.Fatalf("Failed to migrate app secrets: %v", err)
+	}
+	middleware.InitClientAuth(database.GetDB(), &cfg.SDK, secretBox)
+	// 推送通道凭证与应用密钥使用同一加密密钥
+	pusher.InitCredentials(secretBox)
+	if err := pusher.MigrateLegacyCredentials(database.GetDB()); err != nil {
+		log.Fatalf("Failed to migrate push credentials: %v", err)
+	}
+	middleware.InitAppScope(database.GetDB())
+	quota.Init(database.GetDB())
+	userban.Init(database.GetDB())
+
+	// 注册外部身份源，应用用户可从中同步
+	if err := identity.Init(cfg.IdentitySources); err != nil {
+		log.Fatalf("Failed to init identity sources: %v", err)
+	}
+	// 终端用户认证（验证码发送方式、令牌有效期）
+	if err := appauth.Init(database.GetDB(), cfg.AppAuth); err != nil {
+		log.Fatalf("Failed to init app user auth: %v", err)
+	}
+
+	// 非对称签名模式下加载会话令牌签名密钥（私钥与应用密钥使用同一加密密钥）
+	if err := middleware.InitJWTKeys(database.GetDB(), secretBox); err != nil {
+		log.Fatalf("Failed to init JWT signing keys: %v", err)
+	}
+
 	// 初始化审计日志数据库连接
 	middleware.InitAuditDB(database.GetDB())
 	log.Println("[Main] Audit logging initialized")
@@ -88,6 +146,43 @@ func main() {
 		log.Fatalf("Failed to sync modules to database: %v", err)
 	}
 
+	// 3. 启动已删除应用数据清理调度器，由各模块提供清理钩子
+	appPurgeScheduler := scheduler.InitAppPurgeScheduler(database.GetDB(), scheduler.AppPurgeConfig{
+		BatchSize: cfg.AppPurge.BatchSize,
+		Interval:  time.Duration(cfg.AppPurge.IntervalMinutes) * time.Minute,
+	})
+	appPurgeScheduler.Start()
+
+	// 4. 启动配额用量计数清理调度器
+	scheduler.InitUsageCleanupScheduler(database.GetDB()).Start()
+
+	// 5. 启动每日统计汇总调度器，控制台首页的趋势和应用排行读取汇总结果
+	scheduler.InitStatsRollupScheduler(database.GetDB(), scheduler.StatsRollupConfig{
+		Interval:     time.Duration(cfg.Dashboard.RollupIntervalMinutes) * time.Minute,
+		BackfillDays: cfg.Dashboard.TrendDays,
+	}).Start()
+	dashboardService := dashboard.NewService(database.GetDB(),
+		time.Duration(cfg.Dashboard.CacheSeconds)*time.Second, cfg.Dashboard.TrendDays)
+
+	// 6. 启动用户分群刷新调度器，推送、消息和版本灰度按最近一次物化的成员圈选用户
+	scheduler.InitSegmentRefreshScheduler(database.GetDB()).Start()
+
+	// 7. 启动用户数据导出和删除任务调度器，由各模块提供导出和删除钩子
+	scheduler.InitDataSubjectScheduler(database.GetDB()).Start()
+
+	// 8. 启动用户批量导入任务调度器
+	scheduler.InitUserImportScheduler(database.GetDB()).Start()
+
+	// 9. 启动封禁到期解除调度器，同时同步其他实例的封禁事件
+	scheduler.InitUserBanExpiryScheduler().Start()
+
+	// 10. 启动定时推送调度器，发送到期的定时和周期推送，并接手中断的发送
+	if cfg.Push.EnableMockProvider {
+		pusher.RegisterMock()
+		log.Println("[Main] Push mock provider enabled, do not use in production")
+	}
+	scheduler.InitPushDispatchScheduler(database.GetDB()).Start()
+
 	// ========================================
 	// API路由组
 	// ========================================
@@ -96,6 +191,15 @@ func main() {
 // 公开接口（无需认证）
 				// 登录接口使用更严格的限流 (5次/分钟/IP，防止暴力破解)
 				v1.POST("/admin/login", middleware.APIRateLimitMiddleware(5, time.Minute), admin.Login)
+				// 登录第二步（双因素认证），凭MFA挑战令牌访问
+				v1.POST("/admin/login/mfa", middleware.APIRateLimitMiddleware(10, time.Minute), admin.LoginMFA)
+				v1.POST("/admin/login/mfa/enroll", middleware.APIRateLimitMiddleware(5, time.Minute), admin.LoginMFAEnroll)
+				// 单点登录（OIDC 授权码 + PKCE）
+				v1.GET("/admin/sso/config", admin.GetSSOConfig)
+				v1.POST("/admin/sso/authorize", middleware.APIRateLimitMiddleware(20, time.Minute), admin.SSOAuthorize)
+				v1.POST("/admin/sso/callback", middleware.APIRateLimitMiddleware(20, time.Minute), admin.SSOCallback)
+				// 接受管理员邀请并设置密码
+				v1.POST("/admin/invitations/accept", middleware.APIRateLimitMiddleware(10, time.Minute), admin.AcceptInvitation)
 				
 				// 错误报告接口（限流30次/分钟/IP）
 				v1.POST("/system/error-report", middleware.APIRateLimitMiddleware(30, time.Minute), system.ErrorReportHandler)
@@ -114,44 +218,117 @@ func main() {
 				adminGroup.GET("/info", admin.GetInfo)
 				adminGroup.POST("/logout", admin.Logout)
 				adminGroup.PUT("/password", admin.UpdatePassword)
+
+				// 双因素认证
+				adminGroup.GET("/2fa", admin.GetMFAStatus)
+				adminGroup.POST("/2fa/enroll", admin.EnrollMFA)
+				adminGroup.POST("/2fa/verify", admin.VerifyMFA)
+				adminGroup.POST("/2fa/disable", admin.DisableMFA)
+				adminGroup.POST("/2fa/recovery-codes", admin.RegenerateRecoveryCodes)
+
+				// 关联单点登录身份
+				adminGroup.POST("/sso/link", admin.SSOLink)
+
+				// 管理员账号管理
+				adminGroup.GET("/admins", admin.ListAdmins)
+				adminGroup.POST("/admins", admin.CreateAdmin)
+				adminGroup.GET("/admins/:id", admin.GetAdmin)
+				adminGroup.PUT("/admins/:id", admin.UpdateAdmin)
+				adminGroup.DELETE("/admins/:id", admin.DeleteAdmin)
+				adminGroup.POST("/admins/:id/enable", admin.EnableAdmin)
+				adminGroup.POST("/admins/:id/disable", admin.DisableAdmin)
+				adminGroup.POST("/admins/:id/reset-password", admin.ResetAdminPassword)
+				adminGroup.POST("/admins/:id/invite", admin.ResendInvite)
+				adminGroup.POST("/admins/:id/2fa/reset", admin.ResetMFA)
+				adminGroup.POST("/admins/:id/unlock", admin.UnlockAdmin)
+				adminGroup.GET("/lockouts", admin.ListLockouts)
+
+				// 自动化API令牌
+				adminGroup.GET("/api-tokens", admin.ListAPITokens)
+				adminGroup.GET("/api-tokens/scopes", admin.ListAPITokenScopes)
+				adminGroup.POST("/api-tokens", admin.CreateAPIToken)
+				adminGroup.POST("/api-tokens/:id/revoke", admin.RevokeAPIToken)
+
+				// 会话令牌签名密钥
+				adminGroup.GET("/jwt-keys", admin.ListJWTKeys)
+				adminGroup.POST("/jwt-keys/rotate", admin.RotateJWTKey)
 			}
 
 			// 统计数据
-			statsHandler := statsapi.NewStatsHandler(database.GetDB())
+			statsHandler := statsapi.NewStatsHandler(dashboardService)
 			auth.GET("/stats", statsHandler.GetStats)
+			auth.GET("/stats/trends", statsHandler.GetTrends)
+			auth.GET("/stats/apps", statsHandler.GetAppRollups)
+
+			// 组织管理
+			auth.GET("/orgs", orgapi.List)
+			auth.POST("/orgs", orgapi.Create)
+			auth.POST("/org-invitations/accept", orgapi.AcceptInvitation)
+			orgGroup := auth.Group("/orgs/:id")
+			{
+				orgGroup.GET("", middleware.OrgScopeFromParam("id", model.OrgRoleViewer), orgapi.Detail)
+				orgGroup.PUT("", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.Update)
+				orgGroup.DELETE("", middleware.OrgScopeFromParam("id", model.OrgRoleOwner), orgapi.Delete)
+				orgGroup.GET("/members", middleware.OrgScopeFromParam("id", model.OrgRoleViewer), orgapi.ListMembers)
+				orgGroup.PUT("/members/:admin_id", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.UpdateMember)
+				orgGroup.DELETE("/members/:admin_id", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.RemoveMember)
+				orgGroup.POST("/leave", middleware.OrgScopeFromParam("id", model.OrgRoleViewer), orgapi.Leave)
+				orgGroup.GET("/invitations", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.ListInvitations)
+				orgGroup.POST("/invitations", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.CreateInvitation)
+				orgGroup.DELETE("/invitations/:invitation_id", middleware.OrgScopeFromParam("id", model.OrgRoleAdmin), orgapi.RevokeInvitation)
+			}
 
 			// APP管理
+			// 配额套餐
+			auth.GET("/plans", app.ListPlans)
+
 			appGroup := auth.Group("/apps")
 			{
 				appGroup.GET("", app.List)
 				appGroup.POST("", app.Create)
-				appGroup.GET("/:id", app.Detail)
-				appGroup.PUT("/:id", app.Update)
-				appGroup.DELETE("/:id", app.Delete)
-				appGroup.POST("/:id/reset-secret", app.ResetSecret)
+				// 已删除应用在恢复期内可以恢复
+				appGroup.GET("/deleted", app.ListDeleted)
+				appGroup.POST("/deleted/:id/restore", app.Restore)
+
+				// 以下路由按路径中的应用ID校验归属，API令牌只能访问授权的应用
+				scoped := appGroup.Group("/:id", middleware.AppScopeFromParam("id"))
+				scoped.GET("", app.Detail)
+				scoped.PUT("", app.Update)
+				scoped.DELETE("", app.Delete)
+				scoped.POST("/reset-secret", app.RotateSecret) // 兼容旧接口，等同于轮换
+				scoped.GET("/secrets", app.ListSecrets)
+				scoped.POST("/secrets/rotate", app.RotateSecret)
+				scoped.POST("/secrets/:secret_id/revoke", app.RevokeSecret)
+				scoped.POST("/transfer", app.Transfer)
+				scoped.GET("/usage", app.Usage)
+				scoped.PUT("/quota", app.UpdateQuota)
+				// 环境：密钥、模块配置按 env 查询参数或 X-App-Env 请求头选择环境
+				scoped.GET("/environments", app.ListEnvironments)
+				scoped.GET("/environments/diff", moduleapi.DiffEnvironments)
+				scoped.POST("/environments/promote", moduleapi.PromoteConfigs)
 
 				// APP模块管理
-				appGroup.GET("/:id/modules", moduleapi.GetAppModules)
-				appGroup.GET("/:id/modules/:module_code", moduleapi.GetAppModule)
-				appGroup.POST("/:id/modules", moduleapi.EnableModule)
-				appGroup.PUT("/:id/modules/:module_code", moduleapi.UpdateModule)
-				appGroup.DELETE("/:id/modules/:module_code", moduleapi.DisableModule)
-				appGroup.POST("/:id/modules/batch", moduleapi.BatchEnableModules)
+				scoped.GET("/modules", moduleapi.GetAppModules)
+				scoped.GET("/modules/:module_code", moduleapi.GetAppModule)
+				scoped.POST("/modules", moduleapi.EnableModule)
+				scoped.PUT("/modules/:module_code", moduleapi.UpdateModule)
+				scoped.DELETE("/modules/:module_code", moduleapi.DisableModule)
+				scoped.POST("/modules/batch", moduleapi.BatchEnableModules)
 
 				// APP模块配置管理
-				appGroup.PUT("/:id/modules/:module_code/config", moduleapi.SaveModuleConfig)
-				appGroup.GET("/:id/modules/:module_code/config", moduleapi.GetModuleConfig)
-				appGroup.DELETE("/:id/modules/:module_code/config", moduleapi.ResetModuleConfig)
-				appGroup.POST("/:id/modules/:module_code/config/test", moduleapi.TestModuleConfig)
+				scoped.PUT("/modules/:module_code/config", moduleapi.SaveModuleConfig)
+				scoped.GET("/modules/:module_code/config", moduleapi.GetModuleConfig)
+				scoped.DELETE("/modules/:module_code/config", moduleapi.ResetModuleConfig)
+				scoped.POST("/modules/:module_code/config/test", moduleapi.TestModuleConfig)
 				// 配置历史
-				appGroup.GET("/:id/modules/:module_code/config/history", moduleapi.GetConfigHistory)
-				appGroup.POST("/:id/modules/:module_code/config/rollback/:history_id", moduleapi.RollbackConfig)
-				appGroup.GET("/:id/modules/:module_code/config/compare", moduleapi.CompareConfig)
+				scoped.GET("/modules/:module_code/config/history", moduleapi.GetConfigHistory)
+				scoped.POST("/modules/:module_code/config/rollback/:history_id", moduleapi.RollbackConfig)
+				scoped.GET("/modules/:module_code/config/compare", moduleapi.CompareConfig)
 
 				// 模块依赖管理
-				appGroup.GET("/:id/modules/:module_code/dependencies/check", moduleapi.CheckModuleDependencies)
-				appGroup.GET("/:id/modules/:module_code/dependencies/reverse", moduleapi.CheckModuleReverseDependencies)
-				appGroup.POST("/:id/modules/:module_code/dependencies/auto-enable", moduleapi.AutoEnableModuleDependencies)
+				scoped.GET("/modules/:module_code/dependencies/check", moduleapi.CheckModuleDependencies)
+				scoped.GET("/modules/:module_code/dependencies/reverse", moduleapi.CheckModuleReverseDependencies)
+				scoped.POST("/modules/:module_code/dependencies/auto-enable", moduleapi.AutoEnableModuleDependencies)
 
 				// 批量配置导入导出 (暂时禁用)
 				// appGroup.POST("/:id/config/export", moduleapi.ExportConfig)
@@ -178,11 +355,27 @@ func main() {
 				moduleGroup.GET("/dependencies/detect/:module_code", moduleapi.DetectCircularDependency)
 			}
 		}
+
+		// 客户端SDK接口（AppID/AppSecret签名认证）
+		client := v1.Group("/client")
+		client.Use(middleware.ClientAuthMiddleware(), middleware.AppScopeMiddleware(), middleware.AppUserMiddleware())
+		{
+			for _, m := range module.GetAllModules() {
+				if r, ok := m.(module.ClientRouteRegistrar); ok {
+					r.RegisterClientRoutes(client)
+				}
+			}
+			// SDK的WebSocket连接，应用经签名认证确定后检查连接数配额
+			client.GET("/ws", wsapi.HandleClientWebSocket)
+		}
 	}
 
 	// 静态文件服务
 	r.Static("/uploads", "./uploads")
 
+	// 会话令牌校验公钥
+	r.GET("/.well-known/jwks.json", system.JWKSHandler)
+
 	// 健康检查
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
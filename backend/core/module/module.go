@@ -2,7 +2,11 @@
 // 所有功能模块都必须实现这些接口才能被主程序识别和加载
 package module
 
-import "github.com/gin-gonic/gin"
+import (
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
 
 // Meta 包含了模块的基本元数据
 type Meta struct {
@@ -42,6 +46,13 @@ type Module interface {
 	Init() error
 }
 
+// ClientRouteRegistrar 是模块可选实现的接口，用于注册面向客户端SDK的路由
+// router 已带有 /api/v1/client 前缀并经过 AppID/AppSecret 签名认证，
+// 处理函数应通过 middleware.GetClientApp 获取当前应用
+type ClientRouteRegistrar interface {
+	RegisterClientRoutes(router *gin.RouterGroup)
+}
+
 // BaseModule 提供了 Module 接口的基础实现
 // 模块可以嵌入此结构体来获得默认实现
 type BaseModule struct {
@@ -76,3 +87,41 @@ func (m *BaseModule) RegisterRoutes(router *gin.RouterGroup) {
 func (m *BaseModule) Init() error {
 	return nil
 }
+
+// AppDataPurger 是模块可选实现的接口，用于在应用删除并超过恢复期后清理该应用的模块数据
+// 清理任务反复调用 PurgeAppData，每次物理删除不超过 batchSize 条记录（包括磁盘文件等外部资源），
+// 返回本次删除的记录数，返回0表示该模块的数据已全部清理
+type AppDataPurger interface {
+	PurgeAppData(appID uint, batchSize int) (int64, error)
+}
+
+// DataSubject 数据主体请求（导出或删除某个应用用户的全部数据）的对象，由任务在调用各模块前一次性确定，
+// 删除过程中用户记录被匿名化后仍可用这些标识定位各模块数据
+type DataSubject struct {
+	AppID        uint
+	UserID       uint
+	OpenID       string
+	Email        string
+	Phone        string
+	AnonymousIDs []string // 已识别为该用户的匿名设备
+}
+
+// UserDataWriter 用户数据导出的写入目标，name 为归档内的相对路径
+type UserDataWriter interface {
+	// WriteRecords 写入一组记录，records 应为切片，归档中保存为 name.json
+	WriteRecords(name string, records interface{}) error
+	// WriteFile 写入原始文件内容，例如用户上传的文件
+	WriteFile(name string, r io.Reader) error
+}
+
+// UserDataExporter 是模块可选实现的接口，用于导出模块中与数据主体相关的全部数据
+// 删除后任务会再次调用导出并统计残留记录，因此已删除或匿名化的数据不应再被导出
+type UserDataExporter interface {
+	ExportUserData(subject DataSubject, w UserDataWriter) error
+}
+
+// UserDataEraser 是模块可选实现的接口，用于删除或匿名化模块中与数据主体相关的数据
+// 返回各类数据处理的记录数，键为数据类别，例如 {"messages": 12}；重复调用应是安全的
+type UserDataEraser interface {
+	EraseUserData(subject DataSubject) (map[string]int64, error)
+}
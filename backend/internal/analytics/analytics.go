@@ -0,0 +1,202 @@
+// Package analytics 事件行为分析
+// 漏斗和留存按行为主体统计：主体是已识别的用户，或尚未识别的匿名设备。
+// identify 会把匿名设备的事件回填为用户，因此登录前后的行为计入同一主体
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxFunnelSteps   = 10
+	MaxRetentionDays = 30
+	dateLayout       = "2006-01-02"
+)
+
+// ErrInvalidQuery 分析参数不合法
+var ErrInvalidQuery = errors.New("分析参数不合法")
+
+// actorExpr 行为主体，已识别用户用 user_id，匿名设备用 anonymous_id
+const actorExpr = "COALESCE(CAST(e.user_id AS CHAR), CONCAT('anon:', e.anonymous_id))"
+
+// Scope 分析范围，时间区间左闭右开
+type Scope struct {
+	AppID uint
+	Env   string // 为空时统计全部环境
+	Start time.Time
+	End   time.Time
+}
+
+// where 事件过滤条件，既没有用户也没有匿名设备的事件无法归属主体，不参与分析
+func (s Scope) where() (string, []interface{}) {
+	sql := "e.app_id = ? AND e.created_at >= ? AND e.created_at < ? AND (e.user_id IS NOT NULL OR e.anonymous_id <> '')"
+	args := []interface{}{s.AppID, s.Start, s.End}
+	if s.Env != "" {
+		sql += " AND e.env = ?"
+		args = append(args, s.Env)
+	}
+	return sql, args
+}
+
+// FunnelStep 漏斗的一步
+type FunnelStep struct {
+	Name        string  `json:"name"`
+	Count       int64   `json:"count"`        // 依次完成到这一步的主体数
+	Rate        float64 `json:"rate"`         // 相对上一步的转化率
+	OverallRate float64 `json:"overall_rate"` // 相对第一步的转化率
+}
+
+// Funnel 有序漏斗：主体必须在完成上一步之后（含同一时刻）发生下一步事件才计入
+func Funnel(db *gorm.DB, scope Scope, steps []string) ([]FunnelStep, error) {
+	if len(steps) == 0 || len(steps) > MaxFunnelSteps {
+		return nil, fmt.Errorf("%w: 漏斗步骤应在1-%d个之间", ErrInvalidQuery, MaxFunnelSteps)
+	}
+
+	result := make([]FunnelStep, len(steps))
+	for i := range steps {
+		sql, args := funnelQuery(scope, steps[:i+1])
+		var count int64
+		if err := db.Raw("SELECT COUNT(*) FROM ("+sql+") f", args...).Scan(&count).Error; err != nil {
+			return nil, err
+		}
+		result[i] = FunnelStep{Name: steps[i], Count: count}
+	}
+	fillFunnelRates(result)
+	return result, nil
+}
+
+// funnelQuery 完成前 n 步的主体及其完成最后一步的最早时间，每一步都连接上一步的结果
+func funnelQuery(scope Scope, steps []string) (string, []interface{}) {
+	where, args := scope.where()
+	last := len(steps) - 1
+	if last == 0 {
+		sql := "SELECT " + actorExpr + " AS actor, MIN(e.created_at) AS t FROM events e WHERE " + where +
+			" AND e.event_code = ? GROUP BY actor"
+		return sql, append(args, steps[0])
+	}
+
+	prev, prevArgs := funnelQuery(scope, steps[:last])
+	sql := "SELECT " + actorExpr + " AS actor, MIN(e.created_at) AS t FROM events e JOIN (" + prev + ") p" +
+		" ON p.actor = " + actorExpr + " AND e.created_at >= p.t WHERE " + where +
+		" AND e.event_code = ? GROUP BY actor"
+	args = append(prevArgs, args...)
+	return sql, append(args, steps[last])
+}
+
+func fillFunnelRates(steps []FunnelStep) {
+	for i := range steps {
+		if i == 0 {
+			if steps[0].Count > 0 {
+				steps[0].Rate, steps[0].OverallRate = 100, 100
+			}
+			continue
+		}
+		steps[i].Rate = percent(steps[i].Count, steps[i-1].Count)
+		steps[i].OverallRate = percent(steps[i].Count, steps[0].Count)
+	}
+}
+
+// RetentionCohort 某一天首次发生起始事件的主体，及其之后第1..N天发生回访事件的主体数
+type RetentionCohort struct {
+	Date     string    `json:"date"`
+	Users    int64     `json:"users"`
+	Retained []int64   `json:"retained"`
+	Rates    []float64 `json:"rates"`
+}
+
+// Retention 按天的留存：主体在区间内首次发生 startEvent 的日期为其所在批次，
+// 统计批次中在之后第1..days天发生 returnEvent 的主体数
+func Retention(db *gorm.DB, scope Scope, startEvent, returnEvent string, days int) ([]RetentionCohort, error) {
+	if startEvent == "" || returnEvent == "" {
+		return nil, fmt.Errorf("%w: 缺少起始事件或回访事件", ErrInvalidQuery)
+	}
+	if days <= 0 || days > MaxRetentionDays {
+		return nil, fmt.Errorf("%w: 留存天数应在1-%d之间", ErrInvalidQuery, MaxRetentionDays)
+	}
+
+	cohortSQL, cohortArgs := cohortQuery(scope, startEvent)
+
+	var sizes []struct {
+		CohortDay string
+		Users     int64
+	}
+	if err := db.Raw("SELECT DATE_FORMAT(c.day, '%Y-%m-%d') AS cohort_day, COUNT(*) AS users FROM ("+cohortSQL+") c GROUP BY cohort_day",
+		cohortArgs...).Scan(&sizes).Error; err != nil {
+		return nil, err
+	}
+
+	returnScope := scope
+	returnScope.End = scope.End.AddDate(0, 0, days)
+	returnWhere, returnArgs := returnScope.where()
+	returnSQL := "SELECT DISTINCT " + actorExpr + " AS actor, DATE(e.created_at) AS day FROM events e WHERE " +
+		returnWhere + " AND e.event_code = ?"
+
+	var rows []struct {
+		CohortDay string
+		DayN      int
+		Users     int64
+	}
+	args := append(append(append([]interface{}{}, cohortArgs...), returnArgs...), returnEvent, days)
+	if err := db.Raw("SELECT DATE_FORMAT(c.day, '%Y-%m-%d') AS cohort_day, DATEDIFF(r.day, c.day) AS day_n, COUNT(DISTINCT c.actor) AS users"+
+		" FROM ("+cohortSQL+") c JOIN ("+returnSQL+") r"+
+		" ON r.actor = c.actor AND r.day > c.day AND r.day <= DATE_ADD(c.day, INTERVAL ? DAY)"+
+		" GROUP BY cohort_day, day_n", args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	cohortSizes := make(map[string]int64, len(sizes))
+	for _, s := range sizes {
+		cohortSizes[s.CohortDay] = s.Users
+	}
+	retained := make(map[string]map[int]int64)
+	for _, r := range rows {
+		if retained[r.CohortDay] == nil {
+			retained[r.CohortDay] = make(map[int]int64)
+		}
+		retained[r.CohortDay][r.DayN] = r.Users
+	}
+	return buildCohorts(scope.Start, scope.End, days, cohortSizes, retained), nil
+}
+
+// cohortQuery 区间内每个主体首次发生起始事件的日期
+func cohortQuery(scope Scope, startEvent string) (string, []interface{}) {
+	where, args := scope.where()
+	sql := "SELECT " + actorExpr + " AS actor, DATE(MIN(e.created_at)) AS day FROM events e WHERE " + where +
+		" AND e.event_code = ? GROUP BY actor"
+	return sql, append(args, startEvent)
+}
+
+// buildCohorts 按日期补齐批次，没有数据的日期和天数补0
+func buildCohorts(start, end time.Time, days int, sizes map[string]int64, retained map[string]map[int]int64) []RetentionCohort {
+	var cohorts []RetentionCohort
+	for d := truncateDay(start); d.Before(end); d = d.AddDate(0, 0, 1) {
+		date := d.Format(dateLayout)
+		cohort := RetentionCohort{
+			Date:     date,
+			Users:    sizes[date],
+			Retained: make([]int64, days),
+			Rates:    make([]float64, days),
+		}
+		for n := 1; n <= days; n++ {
+			cohort.Retained[n-1] = retained[date][n]
+			cohort.Rates[n-1] = percent(cohort.Retained[n-1], cohort.Users)
+		}
+		cohorts = append(cohorts, cohort)
+	}
+	return cohorts
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func percent(n, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
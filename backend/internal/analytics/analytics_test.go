@@ -0,0 +1,93 @@
+package analytics
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testScope() Scope {
+	return Scope{
+		AppID: 7,
+		Env:   "prod",
+		Start: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestFunnelQuery(t *testing.T) {
+	scope := testScope()
+
+	sql, args := funnelQuery(scope, []string{"view"})
+	if strings.Contains(sql, "JOIN") {
+		t.Errorf("single step should not join: %s", sql)
+	}
+	if want := []interface{}{uint(7), scope.Start, scope.End, "prod", "view"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+
+	sql, args = funnelQuery(scope, []string{"view", "signup", "purchase"})
+	if n := strings.Count(sql, "JOIN"); n != 2 {
+		t.Errorf("3 steps should join twice, got %d: %s", n, sql)
+	}
+	if !strings.Contains(sql, "e.created_at >= p.t") {
+		t.Errorf("steps must be ordered: %s", sql)
+	}
+	if n := strings.Count(sql, "?"); n != len(args) {
+		t.Errorf("placeholders = %d, args = %d", n, len(args))
+	}
+	// 最内层是第一步，最外层是最后一步
+	if args[4] != "view" || args[len(args)-1] != "purchase" {
+		t.Errorf("step args out of order: %v", args)
+	}
+}
+
+func TestScopeWhere_AllEnvs(t *testing.T) {
+	scope := testScope()
+	scope.Env = ""
+	sql, args := scope.where()
+	if strings.Contains(sql, "e.env") || len(args) != 3 {
+		t.Errorf("empty env should not filter env: %s %v", sql, args)
+	}
+	if !strings.Contains(sql, "e.user_id IS NOT NULL OR e.anonymous_id <> ''") {
+		t.Errorf("events without actor must be excluded: %s", sql)
+	}
+}
+
+func TestFillFunnelRates(t *testing.T) {
+	steps := []FunnelStep{{Count: 200}, {Count: 100}, {Count: 25}}
+	fillFunnelRates(steps)
+	want := []FunnelStep{
+		{Count: 200, Rate: 100, OverallRate: 100},
+		{Count: 100, Rate: 50, OverallRate: 50},
+		{Count: 25, Rate: 25, OverallRate: 12.5},
+	}
+	if !reflect.DeepEqual(steps, want) {
+		t.Errorf("steps = %+v, want %+v", steps, want)
+	}
+
+	empty := []FunnelStep{{Count: 0}, {Count: 0}}
+	fillFunnelRates(empty)
+	if empty[0].Rate != 0 || empty[1].Rate != 0 {
+		t.Errorf("empty funnel rates = %+v", empty)
+	}
+}
+
+func TestBuildCohorts(t *testing.T) {
+	scope := testScope()
+	sizes := map[string]int64{"2024-06-01": 10, "2024-06-03": 4}
+	retained := map[string]map[int]int64{
+		"2024-06-01": {1: 5, 2: 2},
+		"2024-06-03": {1: 1},
+	}
+	cohorts := buildCohorts(scope.Start, scope.End, 2, sizes, retained)
+	want := []RetentionCohort{
+		{Date: "2024-06-01", Users: 10, Retained: []int64{5, 2}, Rates: []float64{50, 20}},
+		{Date: "2024-06-02", Users: 0, Retained: []int64{0, 0}, Rates: []float64{0, 0}},
+		{Date: "2024-06-03", Users: 4, Retained: []int64{1, 0}, Rates: []float64{25, 0}},
+	}
+	if !reflect.DeepEqual(cohorts, want) {
+		t.Errorf("cohorts = %+v, want %+v", cohorts, want)
+	}
+}
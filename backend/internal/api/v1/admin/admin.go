@@ -1,15 +1,54 @@
 package admin
 
 import (
+	"time"
+
+	"app-platform-backend/internal/config"
 	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/password"
 	"app-platform-backend/internal/response"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var securityCfg config.SecurityConfig
+
+// InitSecurity 初始化管理员账号安全配置
+func InitSecurity(cfg *config.SecurityConfig) error {
+	securityCfg = *cfg
+	if securityCfg.MFA.Issuer == "" {
+		securityCfg.MFA.Issuer = "App Platform"
+	}
+	if securityCfg.MFA.ChallengeTTLMinutes <= 0 {
+		securityCfg.MFA.ChallengeTTLMinutes = 5
+	}
+
+	lockout := &securityCfg.Lockout
+	if lockout.MaxAttempts <= 0 {
+		lockout.MaxAttempts = 5
+	}
+	if lockout.WindowMinutes <= 0 {
+		lockout.WindowMinutes = 15
+	}
+	if lockout.BaseLockMinutes <= 0 {
+		lockout.BaseLockMinutes = 5
+	}
+	if lockout.MaxLockMinutes < lockout.BaseLockMinutes {
+		lockout.MaxLockMinutes = 24 * 60
+	}
+
+	policy, err := password.NewPolicy(securityCfg.Password)
+	if err != nil {
+		return err
+	}
+	passwordPolicy = policy
+	return initSSO()
+}
+
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
@@ -29,32 +68,95 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if passwordLoginDisabled(req.Username) {
+		response.Forbidden(c, "已启用单点登录，请使用SSO登录")
+		return
+	}
+
+	// 按用户名锁定，不依赖客户端IP，可抵御分布式猜测
+	if until := checkLockout(req.Username); until != nil {
+		respondLocked(c, until)
+		return
+	}
+
 	var admin model.Admin
-	if err := database.GetDB().Where("username = ?", req.Username).First(&admin).Error; err != nil {
+	err := database.GetDB().Where("username = ?", req.Username).First(&admin).Error
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password))
+	}
+	if err != nil {
+		if until := recordLoginFailure(c, req.Username); until != nil {
+			respondLocked(c, until)
+			return
+		}
 		response.Unauthorized(c, "用户名或密码错误")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err != nil {
-		response.Unauthorized(c, "用户名或密码错误")
+	switch admin.Status {
+	case model.AdminStatusActive:
+	case model.AdminStatusInvited:
+		response.Forbidden(c, "账号尚未激活，请通过邀请链接设置密码")
+		return
+	default:
+		response.Forbidden(c, "账号已被禁用")
 		return
 	}
 
-	token, err := middleware.GenerateToken(admin.ID, admin.Username)
+	// 密码超过最长使用期限时要求修改
+	if !admin.MustChangePassword && passwordExpired(&admin) {
+		database.GetDB().Model(&admin).Update("must_change_password", true)
+		admin.MustChangePassword = true
+	}
+
+	// 已启用双因素认证或策略强制要求时，密码校验通过后只签发MFA挑战令牌
+	if admin.TOTPEnabled || securityCfg.MFA.Enforce {
+		ttl := time.Duration(securityCfg.MFA.ChallengeTTLMinutes) * time.Minute
+		mfaToken, err := middleware.GenerateMFAToken(admin.ID, admin.Username, ttl)
+		if err != nil {
+			response.InternalError(c, "生成令牌失败")
+			return
+		}
+
+		response.Success(c, gin.H{
+			"mfa_required":        true,
+			"mfa_token":           mfaToken,
+			"enrollment_required": !admin.TOTPEnabled,
+			"expires_in":          int(ttl.Seconds()),
+		})
+		return
+	}
+
+	issueSession(c, &admin)
+}
+
+// issueSession 签发正式会话令牌并返回管理员信息
+// recoveryCodes 仅在登录时完成双因素绑定的情况下返回，明文只展示这一次
+func issueSession(c *gin.Context, admin *model.Admin, recoveryCodes ...string) {
+	token, err := middleware.GenerateToken(admin.ID, admin.Username, admin.TokenVersion)
 	if err != nil {
 		response.InternalError(c, "生成令牌失败")
 		return
 	}
 
-	response.Success(c, gin.H{
-		"token": token,
+	clearLoginFailures(admin.Username)
+	database.GetDB().Model(admin).UpdateColumn("last_login_at", time.Now())
+
+	data := gin.H{
+		"token":                token,
+		"must_change_password": admin.MustChangePassword,
 		"user": gin.H{
 			"id":       admin.ID,
 			"username": admin.Username,
 			"nickname": admin.Nickname,
 			"avatar":   admin.Avatar,
 		},
-	})
+	}
+	if len(recoveryCodes) > 0 {
+		data["recovery_codes"] = recoveryCodes
+	}
+
+	response.Success(c, data)
 }
 
 // GetInfo 获取管理员信息
@@ -68,10 +170,13 @@ func GetInfo(c *gin.Context) {
 	}
 
 	response.Success(c, gin.H{
-		"id":       admin.ID,
-		"username": admin.Username,
-		"nickname": admin.Nickname,
-		"avatar":   admin.Avatar,
+		"id":                   admin.ID,
+		"username":             admin.Username,
+		"nickname":             admin.Nickname,
+		"email":                admin.Email,
+		"avatar":               admin.Avatar,
+		"totp_enabled":         admin.TOTPEnabled,
+		"must_change_password": admin.MustChangePassword,
 	})
 }
 
@@ -86,7 +191,7 @@ func UpdatePassword(c *gin.Context) {
 
 	var req struct {
 		OldPassword string `json:"old_password" binding:"required"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
+		NewPassword string `json:"new_password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -94,17 +199,6 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	// 验证新密码长度
-	if len(req.NewPassword) < 6 {
-		response.ParamError(c, "新密码长度至少6个字符")
-		return
-	}
-
-	if len(req.NewPassword) > 100 {
-		response.ParamError(c, "新密码长度不能超过100个字符")
-		return
-	}
-
 	var admin model.Admin
 	if err := database.GetDB().First(&admin, userID).Error; err != nil {
 		response.NotFound(c, "管理员不存在")
@@ -116,16 +210,39 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if !checkNewPassword(c, &admin, admin.Username, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := hashPassword(req.NewPassword)
 	if err != nil {
 		response.InternalError(c, "密码加密失败")
 		return
 	}
 
-	if err := database.GetDB().Model(&admin).Update("password", string(hashedPassword)).Error; err != nil {
+	// 修改密码后令牌版本递增，其他设备上的会话全部失效，当前会话换发新令牌
+	now := time.Now()
+	err = database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Model(&admin).Updates(map[string]interface{}{
+			"password":             hashedPassword,
+			"must_change_password": false,
+			"password_changed_at":  now,
+			"token_version":        gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, admin.ID, hashedPassword)
+	})
+	if err != nil {
 		response.DBError(c, err)
 		return
 	}
 
-	response.SuccessWithMessage(c, nil, "密码修改成功")
+	token, err := middleware.GenerateToken(admin.ID, admin.Username, admin.TokenVersion+1)
+	if err != nil {
+		response.InternalError(c, "生成令牌失败")
+		return
+	}
+
+	response.SuccessWithMessage(c, gin.H{"token": token}, "密码修改成功")
 }
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"log"
+	"os"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/validator"
+)
+
+// BootstrapFirstAdmin 数据库中没有任何管理员时，根据环境变量创建首个管理员
+//
+//	ADMIN_BOOTSTRAP_USERNAME  用户名
+//	ADMIN_BOOTSTRAP_PASSWORD  初始密码（首次登录必须修改）
+//	ADMIN_BOOTSTRAP_EMAIL     邮箱（可选）
+//
+// 首个管理员同时成为默认组织的所有者（默认组织由迁移创建，不存在时自动创建）
+// 已存在管理员（包括已删除的）时不做任何操作，因此环境变量可以在部署后保留
+func BootstrapFirstAdmin() error {
+	db := database.GetDB()
+
+	var count int64
+	if err := db.Unscoped().Model(&model.Admin{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	username := os.Getenv("ADMIN_BOOTSTRAP_USERNAME")
+	password := os.Getenv("ADMIN_BOOTSTRAP_PASSWORD")
+	if username == "" || password == "" {
+		log.Println("[Admin] No admin account exists; set ADMIN_BOOTSTRAP_USERNAME and ADMIN_BOOTSTRAP_PASSWORD to create one")
+		return nil
+	}
+
+	if err := validator.ValidateUsername(username); err != nil {
+		return err
+	}
+	if err := passwordPolicy.Validate(password, username); err != nil {
+		return err
+	}
+
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	admin := model.Admin{
+		Username:           username,
+		Password:           hashed,
+		Nickname:           "Administrator",
+		Email:              os.Getenv("ADMIN_BOOTSTRAP_EMAIL"),
+		Status:             model.AdminStatusActive,
+		MustChangePassword: true,
+	}
+	err = database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Create(&admin).Error; err != nil {
+			return err
+		}
+		if err := recordPasswordHistory(tx, admin.ID, hashed); err != nil {
+			return err
+		}
+
+		org := model.Organization{Name: "默认组织", Slug: "default", DefaultModules: "[]", CreatedBy: admin.ID}
+		if err := tx.Where("slug = ?", org.Slug).FirstOrCreate(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrgMember{OrgID: org.ID, AdminID: admin.ID, Role: model.OrgRoleOwner}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[Admin] Bootstrap admin created: %s (password change required on first login)", username)
+	return nil
+}
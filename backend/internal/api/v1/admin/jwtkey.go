@@ -0,0 +1,44 @@
+package admin
+
+import (
+	"errors"
+	"log"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListJWTKeys 会话令牌签名密钥列表（不含私钥）
+func ListJWTKeys(c *gin.Context) {
+	var keys []model.JWTSigningKey
+	if err := database.GetDB().Order("id DESC").Find(&keys).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, keys)
+}
+
+// RotateJWTKey 立即轮换会话令牌签名密钥
+// 新令牌使用新密钥签发，已签发的令牌在过期前仍可通过旧公钥校验，不会导致登录失效
+func RotateJWTKey(c *gin.Context) {
+	key, err := middleware.RotateJWTSigningKey(c.GetUint("user_id"))
+	if errors.Is(err, middleware.ErrJWTKeyRotationUnsupported) {
+		response.ParamError(c, "当前使用HS256签名，不支持密钥轮换")
+		return
+	}
+	if err != nil {
+		log.Printf("[JWT] Key rotation failed: %v", err)
+		response.InternalError(c, "签名密钥轮换失败")
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "rotate", "jwt_key", key.Kid, "轮换会话令牌签名密钥", gin.H{
+		"kid":       key.Kid,
+		"algorithm": key.Algorithm,
+	})
+	response.SuccessWithMessage(c, key, "签名密钥已轮换")
+}
@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkLockout 返回用户名当前的锁定截止时间，未锁定时返回nil
+func checkLockout(username string) *time.Time {
+	var attempt model.AdminLoginAttempt
+	if err := database.GetDB().Where("username = ?", username).First(&attempt).Error; err != nil {
+		return nil
+	}
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(time.Now()) {
+		return attempt.LockedUntil
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次登录失败，达到阈值时按锁定次数渐进锁定
+// 返回本次触发的锁定截止时间，未触发锁定时返回nil
+func recordLoginFailure(c *gin.Context, username string) *time.Time {
+	cfg := securityCfg.Lockout
+	now := time.Now()
+
+	var lockedUntil *time.Time
+	var attempt model.AdminLoginAttempt
+	err := database.WithTransaction(func(tx *database.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("username = ?", username).First(&attempt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			attempt = model.AdminLoginAttempt{Username: username}
+		} else if err != nil {
+			return err
+		}
+
+		// 超出统计窗口的失败不再累计
+		window := time.Duration(cfg.WindowMinutes) * time.Minute
+		if attempt.LastFailedAt == nil || now.Sub(*attempt.LastFailedAt) > window {
+			attempt.FailedCount = 0
+		}
+
+		attempt.FailedCount++
+		attempt.LastFailedAt = &now
+		attempt.LastFailedIP = c.ClientIP()
+
+		if attempt.FailedCount >= cfg.MaxAttempts {
+			until := now.Add(lockDuration(attempt.LockoutCount))
+			attempt.LockedUntil = &until
+			attempt.LockoutCount++
+			attempt.FailedCount = 0
+			lockedUntil = &until
+		}
+
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		log.Printf("[Admin] Failed to record login failure for %s: %v", username, err)
+		return nil
+	}
+
+	if lockedUntil != nil {
+		middleware.RecordAuditEvent(c, "lock", "admin_account", username, "连续登录失败，账号已临时锁定", gin.H{
+			"username":      username,
+			"locked_until":  lockedUntil,
+			"lockout_count": attempt.LockoutCount,
+			"ip":            c.ClientIP(),
+		})
+	}
+	return lockedUntil
+}
+
+// clearLoginFailures 登录成功后清除失败计数和渐进锁定等级
+func clearLoginFailures(username string) {
+	database.GetDB().Where("username = ?", username).Delete(&model.AdminLoginAttempt{})
+}
+
+// lockDuration 计算第 n 次（从0开始）锁定的时长：基础时长按2的幂递增，不超过上限
+func lockDuration(n int) time.Duration {
+	cfg := securityCfg.Lockout
+	d := time.Duration(cfg.BaseLockMinutes) * time.Minute
+	max := time.Duration(cfg.MaxLockMinutes) * time.Minute
+	for i := 0; i < n && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// respondLocked 返回账号锁定响应
+func respondLocked(c *gin.Context, until *time.Time) {
+	retryAfter := int(time.Until(*until).Seconds()) + 1
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.ErrorWithData(c, response.CodeTooManyRequests, "登录失败次数过多，账号已临时锁定，请稍后再试", gin.H{
+		"locked_until": until,
+		"retry_after":  retryAfter,
+	})
+}
+
+// ListLockouts 当前处于锁定状态的管理员账号，只包含当前管理员可见的账号
+func ListLockouts(c *gin.Context) {
+	visible := database.GetDB().Model(&model.Admin{}).Select("username").Scopes(visibleAdmins(c))
+	var attempts []model.AdminLoginAttempt
+	if err := database.GetDB().Where("locked_until > ? AND username IN (?)", time.Now(), visible).
+		Order("locked_until DESC").Limit(200).Find(&attempts).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, attempts)
+}
+
+// UnlockAdmin 解除管理员账号的登录锁定
+func UnlockAdmin(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+
+	until := checkLockout(admin.Username)
+	clearLoginFailures(admin.Username)
+
+	middleware.RecordAuditEvent(c, "unlock", "admin_account", strconv.Itoa(int(admin.ID)), "解除管理员登录锁定", gin.H{
+		"target_username": admin.Username,
+		"was_locked":      until != nil,
+	})
+	response.SuccessWithMessage(c, nil, "账号锁定已解除")
+}
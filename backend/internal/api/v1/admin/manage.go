@@ -0,0 +1,559 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// inviteTTL 邀请链接有效期
+const inviteTTL = 72 * time.Hour
+
+// ListAdmins 管理员列表
+func ListAdmins(c *gin.Context) {
+	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
+	page, size = validator.ValidatePagination(page, size)
+
+	query := database.GetDB().Model(&model.Admin{}).Scopes(visibleAdmins(c))
+
+	if keyword := c.Query("keyword"); keyword != "" {
+		query = query.Where("username LIKE ? OR nickname LIKE ? OR email LIKE ?",
+			"%"+keyword+"%", "%"+keyword+"%", "%"+keyword+"%")
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	var admins []model.Admin
+	offset := (page - 1) * size
+	if err := query.Offset(offset).Limit(size).Order("id ASC").Find(&admins).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	response.PageSuccess(c, admins, total, page, size)
+}
+
+// GetAdmin 管理员详情
+func GetAdmin(c *gin.Context) {
+	admin, ok := loadTargetAdmin(c)
+	if !ok {
+		return
+	}
+	response.Success(c, admin)
+}
+
+// CreateAdmin 创建管理员
+// 提供 password 时使用初始密码创建，首次登录必须修改；否则生成邀请令牌，由被邀请人自行设置密码
+func CreateAdmin(c *gin.Context) {
+	var req validator.AdminCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if err := validator.ValidateAdminCreate(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	// 软删除的账号仍占用用户名，避免审计日志中的用户名产生歧义
+	var exists int64
+	database.GetDB().Unscoped().Model(&model.Admin{}).Where("username = ?", req.Username).Count(&exists)
+	if exists > 0 {
+		response.Conflict(c, "用户名已存在")
+		return
+	}
+
+	admin := model.Admin{
+		Username:  req.Username,
+		Nickname:  req.Nickname,
+		Email:     req.Email,
+		Role:      req.Role,
+		CreatedBy: c.GetUint("user_id"),
+	}
+	if admin.Role == "" {
+		admin.Role = model.AdminRoleAdmin
+	}
+
+	var inviteToken string
+	if req.Password != "" {
+		if !checkNewPassword(c, nil, req.Username, req.Password) {
+			return
+		}
+		hashed, err := hashPassword(req.Password)
+		if err != nil {
+			response.InternalError(c, "密码加密失败")
+			return
+		}
+		admin.Password = hashed
+		admin.Status = model.AdminStatusActive
+		admin.MustChangePassword = true
+	} else {
+		token, hash, expiresAt, err := newInviteToken()
+		if err != nil {
+			response.InternalError(c, "生成邀请令牌失败")
+			return
+		}
+		inviteToken = token
+		admin.Status = model.AdminStatusInvited
+		admin.InviteTokenHash = hash
+		admin.InviteExpiresAt = &expiresAt
+	}
+
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Create(&admin).Error; err != nil {
+			return err
+		}
+		if admin.Password == "" {
+			return nil
+		}
+		return recordPasswordHistory(tx, admin.ID, admin.Password)
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	data := gin.H{"admin": admin}
+	if inviteToken != "" {
+		// 邀请令牌明文只返回这一次，由创建人转交被邀请人
+		data["invite_token"] = inviteToken
+	}
+	response.SuccessWithMessage(c, data, "管理员创建成功")
+}
+
+// UpdateAdmin 更新管理员资料
+func UpdateAdmin(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+
+	var req validator.AdminUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if err := validator.ValidateAdminUpdate(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Nickname != nil {
+		updates["nickname"] = *req.Nickname
+	}
+	if req.Email != nil {
+		updates["email"] = *req.Email
+	}
+	if req.Avatar != nil {
+		updates["avatar"] = *req.Avatar
+	}
+	if req.Role != nil && *req.Role != admin.Role {
+		if !checkRoleChange(c, admin, *req.Role) {
+			return
+		}
+		updates["role"] = *req.Role
+	}
+	if len(updates) == 0 {
+		response.Success(c, admin)
+		return
+	}
+
+	if err := database.GetDB().Model(admin).Updates(updates).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	database.GetDB().First(admin, admin.ID)
+	response.Success(c, admin)
+}
+
+// EnableAdmin 启用管理员
+func EnableAdmin(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.Status == model.AdminStatusInvited {
+		response.ParamError(c, "该管理员尚未接受邀请")
+		return
+	}
+	if admin.Status == model.AdminStatusActive {
+		response.SuccessWithMessage(c, nil, "管理员已是启用状态")
+		return
+	}
+
+	if err := database.GetDB().Model(admin).Update("status", model.AdminStatusActive).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "enable", "admin", strconv.Itoa(int(admin.ID)), "启用管理员", gin.H{
+		"target_username": admin.Username,
+	})
+	response.SuccessWithMessage(c, nil, "管理员已启用")
+}
+
+// DisableAdmin 禁用管理员，已签发的会话立即失效
+func DisableAdmin(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if !checkNotSelfOrLastAdmin(c, admin) {
+		return
+	}
+
+	if err := database.GetDB().Model(admin).Updates(map[string]interface{}{
+		"status":        model.AdminStatusDisabled,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "disable", "admin", strconv.Itoa(int(admin.ID)), "禁用管理员", gin.H{
+		"target_username": admin.Username,
+	})
+	response.SuccessWithMessage(c, nil, "管理员已禁用")
+}
+
+// DeleteAdmin 删除管理员（软删除）
+func DeleteAdmin(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if !checkNotSelfOrLastAdmin(c, admin) {
+		return
+	}
+
+	err := database.WithTransaction(func(tx *database.DB) error {
+		// 先递增令牌版本，确保已签发的会话失效
+		if err := tx.Model(admin).Updates(map[string]interface{}{
+			"status":            model.AdminStatusDisabled,
+			"token_version":     gorm.Expr("token_version + 1"),
+			"invite_token_hash": "",
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("admin_id = ?", admin.ID).Delete(&model.AdminRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(admin).Error
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "delete", "admin", strconv.Itoa(int(admin.ID)), "删除管理员", gin.H{
+		"target_username": admin.Username,
+	})
+	response.SuccessWithMessage(c, nil, "管理员删除成功")
+}
+
+// ResetAdminPassword 为其他管理员设置新的初始密码，下次登录时必须修改
+func ResetAdminPassword(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "新密码不能为空")
+		return
+	}
+
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.ID == c.GetUint("user_id") {
+		response.ParamError(c, "请通过修改密码功能修改自己的密码")
+		return
+	}
+	if admin.Status == model.AdminStatusInvited {
+		response.ParamError(c, "该管理员尚未接受邀请，请重新发送邀请")
+		return
+	}
+	if !checkNewPassword(c, admin, admin.Username, req.Password) {
+		return
+	}
+
+	hashed, err := hashPassword(req.Password)
+	if err != nil {
+		response.InternalError(c, "密码加密失败")
+		return
+	}
+
+	err = database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Model(admin).Updates(map[string]interface{}{
+			"password":             hashed,
+			"must_change_password": true,
+			"password_changed_at":  time.Now(),
+			"token_version":        gorm.Expr("token_version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return recordPasswordHistory(tx, admin.ID, hashed)
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "reset", "admin", strconv.Itoa(int(admin.ID)), "重置管理员密码", gin.H{
+		"target_username": admin.Username,
+	})
+	response.SuccessWithMessage(c, nil, "密码已重置，该管理员下次登录时需修改密码")
+}
+
+// ResendInvite 重新生成邀请令牌，旧令牌作废
+func ResendInvite(c *gin.Context) {
+	admin, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.Status != model.AdminStatusInvited {
+		response.ParamError(c, "该管理员已激活，无需邀请")
+		return
+	}
+
+	token, hash, expiresAt, err := newInviteToken()
+	if err != nil {
+		response.InternalError(c, "生成邀请令牌失败")
+		return
+	}
+
+	if err := database.GetDB().Model(admin).Updates(map[string]interface{}{
+		"invite_token_hash": hash,
+		"invite_expires_at": expiresAt,
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"invite_token": token,
+		"expires_at":   expiresAt,
+	})
+}
+
+// AcceptInvitation 被邀请人使用邀请令牌设置密码并激活账号
+func AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+		Nickname string `json:"nickname"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "邀请令牌和密码不能为空")
+		return
+	}
+
+	var admin model.Admin
+	if err := database.GetDB().Where("invite_token_hash = ? AND status = ?",
+		hashInviteToken(req.Token), model.AdminStatusInvited).First(&admin).Error; err != nil {
+		response.NotFound(c, "邀请链接无效或已使用")
+		return
+	}
+	if admin.InviteExpiresAt == nil || admin.InviteExpiresAt.Before(time.Now()) {
+		response.ParamError(c, "邀请链接已过期，请联系管理员重新邀请")
+		return
+	}
+	if !checkNewPassword(c, &admin, admin.Username, req.Password) {
+		return
+	}
+
+	hashed, err := hashPassword(req.Password)
+	if err != nil {
+		response.InternalError(c, "密码加密失败")
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"password":             hashed,
+		"status":               model.AdminStatusActive,
+		"must_change_password": false,
+		"password_changed_at":  now,
+		"invite_token_hash":    "",
+		"invite_expires_at":    nil,
+	}
+	if req.Nickname != "" {
+		updates["nickname"] = req.Nickname
+	}
+
+	// 以状态为条件更新，防止同一令牌并发重复激活
+	var activated bool
+	err = database.WithTransaction(func(tx *database.DB) error {
+		result := tx.Model(&model.Admin{}).
+			Where("id = ? AND status = ?", admin.ID, model.AdminStatusInvited).
+			Updates(updates)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		activated = true
+		return recordPasswordHistory(tx, admin.ID, hashed)
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if !activated {
+		response.NotFound(c, "邀请链接无效或已使用")
+		return
+	}
+
+	c.Set("user_id", admin.ID)
+	c.Set("username", admin.Username)
+	middleware.RecordAuditEvent(c, "activate", "admin", strconv.Itoa(int(admin.ID)), "管理员接受邀请并激活账号", nil)
+	response.SuccessWithMessage(c, gin.H{"username": admin.Username}, "账号已激活，请使用新密码登录")
+}
+
+// loadTargetAdmin 根据路径参数加载被操作的管理员，看不到的管理员按不存在处理
+func loadTargetAdmin(c *gin.Context) (*model.Admin, bool) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return nil, false
+	}
+
+	var admin model.Admin
+	if err := database.GetDB().Scopes(visibleAdmins(c)).First(&admin, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "管理员不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &admin, true
+}
+
+// loadManagedAdmin 加载被操作的管理员并校验当前管理员有权管理该账号
+func loadManagedAdmin(c *gin.Context) (*model.Admin, bool) {
+	admin, ok := loadTargetAdmin(c)
+	if !ok || !checkManageAdmin(c, admin) {
+		return nil, false
+	}
+	return admin, true
+}
+
+// visibleAdmins 管理员只能看到自己、同属一个组织的管理员以及自己创建的账号
+func visibleAdmins(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	uid := c.GetUint("user_id")
+	return func(db *gorm.DB) *gorm.DB {
+		myOrgs := database.GetDB().Model(&model.OrgMember{}).Select("org_id").Where("admin_id = ?", uid)
+		orgAdmins := database.GetDB().Model(&model.OrgMember{}).Select("admin_id").Where("org_id IN (?)", myOrgs)
+		return db.Where("id = ? OR created_by = ? OR id IN (?)", uid, uid, orgAdmins)
+	}
+}
+
+// checkManageAdmin 管理其他管理员须是其所属全部组织的所有者或管理员，避免跨组织重置密码、禁用或删除账号；
+// 尚未加入任何组织的账号只能由创建人管理
+func checkManageAdmin(c *gin.Context, admin *model.Admin) bool {
+	uid := c.GetUint("user_id")
+	if admin.ID == uid {
+		return true
+	}
+
+	var orgIDs []uint
+	if err := database.GetDB().Model(&model.OrgMember{}).
+		Where("admin_id = ?", admin.ID).Pluck("org_id", &orgIDs).Error; err != nil {
+		response.DBError(c, err)
+		return false
+	}
+	if len(orgIDs) == 0 {
+		if admin.CreatedBy != uid {
+			response.Forbidden(c, "只能管理自己创建的未加入组织的管理员")
+			return false
+		}
+		return true
+	}
+
+	var managed int64
+	if err := database.GetDB().Model(&model.OrgMember{}).
+		Where("admin_id = ? AND org_id IN ? AND role IN ?", uid, orgIDs, []string{model.OrgRoleOwner, model.OrgRoleAdmin}).
+		Count(&managed).Error; err != nil {
+		response.DBError(c, err)
+		return false
+	}
+	if managed < int64(len(orgIDs)) {
+		response.Forbidden(c, "需要是该管理员所属全部组织的所有者或管理员")
+		return false
+	}
+	return true
+}
+
+// checkNotSelfOrLastAdmin 禁止对自己执行禁用/删除，并保证至少保留一个可用管理员
+func checkNotSelfOrLastAdmin(c *gin.Context, admin *model.Admin) bool {
+	if admin.ID == c.GetUint("user_id") {
+		response.ParamError(c, "不能禁用或删除当前登录的账号")
+		return false
+	}
+
+	var others int64
+	database.GetDB().Model(&model.Admin{}).
+		Where("id <> ? AND status = ?", admin.ID, model.AdminStatusActive).
+		Count(&others)
+	if others == 0 {
+		response.ParamError(c, "至少需要保留一个可用的管理员")
+		return false
+	}
+	return true
+}
+
+// checkRoleChange 禁止修改自己的角色，并保证至少保留一个可用的 admin 角色管理员
+func checkRoleChange(c *gin.Context, admin *model.Admin, role string) bool {
+	if admin.ID == c.GetUint("user_id") {
+		response.ParamError(c, "不能修改自己的角色")
+		return false
+	}
+	if role == model.AdminRoleAdmin {
+		return true
+	}
+
+	var others int64
+	database.GetDB().Model(&model.Admin{}).
+		Where("id <> ? AND status = ? AND role = ?", admin.ID, model.AdminStatusActive, model.AdminRoleAdmin).
+		Count(&others)
+	if others == 0 {
+		response.ParamError(c, "至少需要保留一个可用的管理员角色账号")
+		return false
+	}
+	return true
+}
+
+// newInviteToken 生成邀请令牌，返回明文、哈希和过期时间
+func newInviteToken() (string, string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	return token, hashInviteToken(token), time.Now().Add(inviteTTL), nil
+}
+
+// hashInviteToken 计算邀请令牌哈希，数据库中不保存明文
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
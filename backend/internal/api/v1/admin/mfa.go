@@ -0,0 +1,518 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/totp"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var errInvalidCode = errors.New("验证码错误，请确认设备时间是否准确")
+
+const (
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// totpSkew 允许的时钟偏差（前后各1个时间步）
+	totpSkew = 1
+)
+
+// MFALoginRequest 登录第二步请求
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// LoginMFA 登录第二步：使用TOTP验证码或恢复码换取会话令牌
+// 若管理员尚未完成绑定（策略强制），此接口同时完成绑定并返回恢复码
+func LoginMFA(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "挑战令牌不能为空")
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		response.ParamError(c, "请输入验证码或恢复码")
+		return
+	}
+
+	admin, challenge, ok := loadChallengeAdmin(c, req.MFAToken)
+	if !ok {
+		return
+	}
+	if until := checkLockout(admin.Username); until != nil {
+		respondLocked(c, until)
+		return
+	}
+
+	// 强制绑定流程：校验待激活密钥后启用
+	if !admin.TOTPEnabled {
+		if admin.TOTPSecret == "" || req.Code == "" {
+			response.ParamError(c, "请先获取绑定密钥并输入验证码")
+			return
+		}
+		codes, err := enableTOTP(admin, req.Code)
+		if err != nil {
+			recordMFAFailure(c, admin, "enroll")
+			response.Unauthorized(c, err.Error())
+			return
+		}
+		if !consumeMFAChallenge(c, admin, challenge) {
+			return
+		}
+		middleware.RecordAuditEvent(c, "enable", "admin_mfa", strconv.Itoa(int(admin.ID)), "登录时绑定双因素认证", nil)
+		issueSession(c, admin, codes...)
+		return
+	}
+
+	verified := false
+	method := "totp"
+	if req.Code != "" {
+		verified = verifyTOTP(admin, req.Code)
+	} else {
+		method = "recovery_code"
+		verified = useRecoveryCode(admin.ID, req.RecoveryCode)
+	}
+	if !verified {
+		recordMFAFailure(c, admin, method)
+		// 第二因素失败同样计入登录失败，防止持有密码后暴力猜测验证码
+		if until := recordLoginFailure(c, admin.Username); until != nil {
+			respondLocked(c, until)
+			return
+		}
+		response.Unauthorized(c, "验证码错误或已使用")
+		return
+	}
+	if !consumeMFAChallenge(c, admin, challenge) {
+		return
+	}
+
+	if method == "recovery_code" {
+		middleware.RecordAuditEvent(c, "login", "admin_mfa", strconv.Itoa(int(admin.ID)), "使用恢复码完成登录", gin.H{
+			"remaining_recovery_codes": countRecoveryCodes(admin.ID),
+		})
+	}
+
+	issueSession(c, admin)
+}
+
+// LoginMFAEnroll 登录过程中的强制绑定：为尚未启用TOTP的管理员生成密钥
+// 挑战令牌在此消费，同时签发新的挑战令牌用于提交首个验证码
+func LoginMFAEnroll(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "挑战令牌不能为空")
+		return
+	}
+
+	admin, challenge, ok := loadChallengeAdmin(c, req.MFAToken)
+	if !ok {
+		return
+	}
+	if admin.TOTPEnabled {
+		response.Conflict(c, "双因素认证已启用")
+		return
+	}
+	if !consumeMFAChallenge(c, admin, challenge) {
+		return
+	}
+
+	ttl := time.Duration(securityCfg.MFA.ChallengeTTLMinutes) * time.Minute
+	mfaToken, err := middleware.GenerateMFAToken(admin.ID, admin.Username, ttl)
+	if err != nil {
+		response.InternalError(c, "生成令牌失败")
+		return
+	}
+	startEnrollment(c, admin, mfaToken)
+}
+
+// GetMFAStatus 获取当前管理员的双因素认证状态
+func GetMFAStatus(c *gin.Context) {
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+
+	response.Success(c, gin.H{
+		"enabled":                  admin.TOTPEnabled,
+		"enabled_at":               admin.TOTPEnabledAt,
+		"enforced":                 securityCfg.MFA.Enforce,
+		"remaining_recovery_codes": countRecoveryCodes(admin.ID),
+	})
+}
+
+// EnrollMFA 开始绑定TOTP，返回密钥和二维码配置URI
+func EnrollMFA(c *gin.Context) {
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.TOTPEnabled {
+		response.Conflict(c, "双因素认证已启用，如需更换请先停用")
+		return
+	}
+
+	startEnrollment(c, admin, "")
+}
+
+// VerifyMFA 校验首个验证码并启用双因素认证，返回一次性恢复码
+func VerifyMFA(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "验证码不能为空")
+		return
+	}
+
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.TOTPEnabled {
+		response.Conflict(c, "双因素认证已启用")
+		return
+	}
+	if admin.TOTPSecret == "" {
+		response.ParamError(c, "请先获取绑定密钥")
+		return
+	}
+
+	codes, err := enableTOTP(admin, req.Code)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "enable", "admin_mfa", strconv.Itoa(int(admin.ID)), "启用双因素认证", nil)
+	response.SuccessWithMessage(c, gin.H{"recovery_codes": codes}, "双因素认证已启用，请妥善保存恢复码")
+}
+
+// DisableMFA 停用当前管理员的双因素认证，需要同时校验密码和验证码
+func DisableMFA(c *gin.Context) {
+	var req struct {
+		Password     string `json:"password" binding:"required"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请输入密码")
+		return
+	}
+
+	if securityCfg.MFA.Enforce {
+		response.Forbidden(c, "当前安全策略要求启用双因素认证，不能停用")
+		return
+	}
+
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+	if !admin.TOTPEnabled {
+		response.ParamError(c, "双因素认证未启用")
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(req.Password)); err != nil {
+		response.ParamError(c, "密码不正确")
+		return
+	}
+	if !verifySecondFactor(admin, req.Code, req.RecoveryCode) {
+		response.ParamError(c, "验证码错误或已使用")
+		return
+	}
+
+	if err := clearTOTP(admin.ID); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "disable", "admin_mfa", strconv.Itoa(int(admin.ID)), "停用双因素认证", nil)
+	response.SuccessWithMessage(c, nil, "双因素认证已停用")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧恢复码全部作废
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "验证码不能为空")
+		return
+	}
+
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+	if !admin.TOTPEnabled {
+		response.ParamError(c, "双因素认证未启用")
+		return
+	}
+	if !verifyTOTP(admin, req.Code) {
+		response.ParamError(c, "验证码错误或已使用")
+		return
+	}
+
+	var codes []string
+	err := database.WithTransaction(func(tx *database.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "reset", "admin_mfa", strconv.Itoa(int(admin.ID)), "重新生成双因素恢复码", nil)
+	response.SuccessWithMessage(c, gin.H{"recovery_codes": codes}, "恢复码已重新生成")
+}
+
+// ResetMFA 重置其他管理员的双因素认证（用于丢失设备等场景）
+func ResetMFA(c *gin.Context) {
+	target, ok := loadManagedAdmin(c)
+	if !ok {
+		return
+	}
+	if target.ID == c.GetUint("user_id") {
+		response.ParamError(c, "不能重置自己的双因素认证，请使用停用功能")
+		return
+	}
+
+	if err := clearTOTP(target.ID); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "reset", "admin_mfa", strconv.Itoa(int(target.ID)), "重置管理员双因素认证", gin.H{
+		"target_username": target.Username,
+		"was_enabled":     target.TOTPEnabled,
+	})
+	response.SuccessWithMessage(c, nil, "双因素认证已重置，该管理员下次登录时需要重新绑定")
+}
+
+// loadChallengeAdmin 解析MFA挑战令牌并加载对应管理员，已使用过的挑战令牌视为无效
+func loadChallengeAdmin(c *gin.Context, token string) (*model.Admin, *middleware.Claims, bool) {
+	claims, err := middleware.ParseMFAToken(token)
+	if err != nil {
+		response.Unauthorized(c, "挑战令牌无效或已过期，请重新登录")
+		return nil, nil, false
+	}
+
+	var used int64
+	database.GetDB().Model(&model.AdminMFAChallenge{}).Where("token_id = ?", claims.ID).Count(&used)
+	if used > 0 {
+		response.Unauthorized(c, "挑战令牌无效或已过期，请重新登录")
+		return nil, nil, false
+	}
+
+	var admin model.Admin
+	if err := database.GetDB().First(&admin, claims.UserID).Error; err != nil || admin.Status != model.AdminStatusActive {
+		response.Unauthorized(c, "挑战令牌无效或已过期，请重新登录")
+		return nil, nil, false
+	}
+
+	// 审计日志需要识别操作人
+	c.Set("user_id", admin.ID)
+	c.Set("username", admin.Username)
+	return &admin, claims, true
+}
+
+// consumeMFAChallenge 标记挑战令牌已使用，令牌ID唯一索引保证并发重放时只有一次成功
+// 失败时写入响应并返回false
+func consumeMFAChallenge(c *gin.Context, admin *model.Admin, claims *middleware.Claims) bool {
+	db := database.GetDB()
+	// 顺带清理已过期的记录，过期令牌本身已无法通过校验
+	db.Where("expires_at < ?", time.Now()).Delete(&model.AdminMFAChallenge{})
+	if err := db.Create(&model.AdminMFAChallenge{
+		TokenID:   claims.ID,
+		AdminID:   admin.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}).Error; err != nil {
+		response.Unauthorized(c, "挑战令牌无效或已过期，请重新登录")
+		return false
+	}
+	return true
+}
+
+// loadCurrentAdmin 加载当前登录的管理员
+func loadCurrentAdmin(c *gin.Context) (*model.Admin, bool) {
+	var admin model.Admin
+	if err := database.GetDB().First(&admin, c.GetUint("user_id")).Error; err != nil {
+		response.NotFound(c, "管理员不存在")
+		return nil, false
+	}
+	return &admin, true
+}
+
+// startEnrollment 生成新的待激活密钥并返回配置信息，登录中的强制绑定同时返回新的挑战令牌
+func startEnrollment(c *gin.Context, admin *model.Admin, mfaToken string) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		response.InternalError(c, "生成密钥失败")
+		return
+	}
+
+	if err := database.GetDB().Model(admin).Updates(map[string]interface{}{
+		"totp_secret":    secret,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	data := gin.H{
+		"secret":      secret,
+		"otpauth_uri": totp.ProvisioningURI(secret, securityCfg.MFA.Issuer, admin.Username),
+		"issuer":      securityCfg.MFA.Issuer,
+		"digits":      totp.Digits,
+		"period":      totp.Period,
+	}
+	if mfaToken != "" {
+		data["mfa_token"] = mfaToken
+	}
+	response.Success(c, data)
+}
+
+// enableTOTP 校验待激活密钥的验证码，成功后启用并生成恢复码
+func enableTOTP(admin *model.Admin, code string) ([]string, error) {
+	step, ok := totp.Validate(admin.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	var codes []string
+	err := database.WithTransaction(func(tx *database.DB) error {
+		now := time.Now()
+		if err := tx.Model(admin).Updates(map[string]interface{}{
+			"totp_enabled":    true,
+			"totp_enabled_at": now,
+			"totp_last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = replaceRecoveryCodes(tx, admin.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// verifySecondFactor 校验TOTP验证码或恢复码
+func verifySecondFactor(admin *model.Admin, code, recoveryCode string) bool {
+	if code != "" {
+		return verifyTOTP(admin, code)
+	}
+	if recoveryCode != "" {
+		return useRecoveryCode(admin.ID, recoveryCode)
+	}
+	return false
+}
+
+// verifyTOTP 校验验证码，并通过条件更新拒绝同一时间步验证码的重放
+func verifyTOTP(admin *model.Admin, code string) bool {
+	step, ok := totp.Validate(admin.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return false
+	}
+
+	result := database.GetDB().Model(&model.Admin{}).
+		Where("id = ? AND totp_last_step < ?", admin.ID, step).
+		Update("totp_last_step", step)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// useRecoveryCode 消耗一个恢复码，每个恢复码只能成功使用一次
+func useRecoveryCode(adminID uint, code string) bool {
+	result := database.GetDB().Model(&model.AdminRecoveryCode{}).
+		Where("admin_id = ? AND code_hash = ? AND used_at IS NULL", adminID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// countRecoveryCodes 统计剩余可用恢复码数量
+func countRecoveryCodes(adminID uint) int64 {
+	var count int64
+	database.GetDB().Model(&model.AdminRecoveryCode{}).
+		Where("admin_id = ? AND used_at IS NULL", adminID).
+		Count(&count)
+	return count
+}
+
+// replaceRecoveryCodes 作废旧恢复码并生成新的一组，返回明文（仅此一次）
+func replaceRecoveryCodes(tx *gorm.DB, adminID uint) ([]string, error) {
+	if err := tx.Where("admin_id = ?", adminID).Delete(&model.AdminRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]model.AdminRecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		records = append(records, model.AdminRecoveryCode{
+			AdminID:  adminID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// clearTOTP 清除管理员的TOTP密钥和全部恢复码
+func clearTOTP(adminID uint) error {
+	return database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Model(&model.Admin{}).Where("id = ?", adminID).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled":    false,
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("admin_id = ?", adminID).Delete(&model.AdminRecoveryCode{}).Error
+	})
+}
+
+// hashRecoveryCode 恢复码为高熵随机值，使用SHA-256即可，忽略大小写和分隔符
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// recordMFAFailure 记录第二步验证失败的审计事件
+func recordMFAFailure(c *gin.Context, admin *model.Admin, method string) {
+	middleware.RecordAuditEvent(c, "login_failed", "admin_mfa", strconv.Itoa(int(admin.ID)), "双因素验证失败", gin.H{
+		"method": method,
+	})
+}
@@ -0,0 +1,110 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/password"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var passwordPolicy *password.Policy
+
+// checkNewPassword 校验新密码是否符合密码策略及历史记录要求
+// 不符合时写入错误响应并记录审计日志；admin 为nil表示新建账号，不检查历史
+func checkNewPassword(c *gin.Context, admin *model.Admin, username, newPassword string) bool {
+	err := passwordPolicy.Validate(newPassword, username)
+	if err == nil && admin != nil && passwordReused(admin, newPassword) {
+		err = &password.Violation{
+			Rule:    password.RuleReused,
+			Message: "新密码不能与最近使用过的" + strconv.Itoa(passwordPolicy.HistoryCount()) + "个密码相同",
+		}
+	}
+	if err == nil {
+		return true
+	}
+
+	rule := ""
+	var v *password.Violation
+	if errors.As(err, &v) {
+		rule = v.Rule
+	}
+
+	resourceID := username
+	if admin != nil {
+		resourceID = strconv.Itoa(int(admin.ID))
+	}
+	middleware.RecordAuditEvent(c, "password_policy_violation", "admin_account", resourceID, "密码不符合安全策略", gin.H{
+		"username": username,
+		"rule":     rule,
+	})
+
+	response.ParamError(c, err.Error())
+	return false
+}
+
+// passwordReused 检查新密码是否与当前密码或最近N个历史密码相同
+func passwordReused(admin *model.Admin, newPassword string) bool {
+	if admin.Password != "" && bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(newPassword)) == nil {
+		return true
+	}
+
+	n := passwordPolicy.HistoryCount()
+	if n <= 0 {
+		return false
+	}
+
+	var history []model.AdminPasswordHistory
+	database.GetDB().Where("admin_id = ?", admin.ID).Order("id DESC").Limit(n).Find(&history)
+	for _, h := range history {
+		if bcrypt.CompareHashAndPassword([]byte(h.PasswordHash), []byte(newPassword)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPasswordHistory 保存密码哈希到历史记录，并只保留策略要求的数量
+func recordPasswordHistory(tx *gorm.DB, adminID uint, hash string) error {
+	n := passwordPolicy.HistoryCount()
+	if n <= 0 {
+		return nil
+	}
+
+	if err := tx.Create(&model.AdminPasswordHistory{AdminID: adminID, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+
+	var keepIDs []uint
+	if err := tx.Model(&model.AdminPasswordHistory{}).Where("admin_id = ?", adminID).
+		Order("id DESC").Limit(n).Pluck("id", &keepIDs).Error; err != nil {
+		return err
+	}
+	return tx.Where("admin_id = ? AND id NOT IN ?", adminID, keepIDs).Delete(&model.AdminPasswordHistory{}).Error
+}
+
+// passwordExpired 判断管理员密码是否超过最长使用期限
+func passwordExpired(admin *model.Admin) bool {
+	changedAt := admin.CreatedAt
+	if admin.PasswordChangedAt != nil {
+		changedAt = *admin.PasswordChangedAt
+	}
+	return passwordPolicy.Expired(changedAt, time.Now())
+}
+
+// hashPassword 使用bcrypt计算密码哈希
+func hashPassword(plain string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
@@ -0,0 +1,414 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/oidc"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ssoStateTTL 授权请求有效期，超过后需重新发起登录
+const ssoStateTTL = 10 * time.Minute
+
+var (
+	ssoProvider *oidc.Provider
+
+	usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// initSSO 校验单点登录配置并创建身份提供方客户端
+func initSSO() error {
+	cfg := &securityCfg.SSO
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return errors.New("sso: issuer, client_id and redirect_url are required")
+	}
+	for value, role := range cfg.RoleMapping {
+		if err := validator.ValidateAdminRole(role); err != nil {
+			return fmt.Errorf("sso: invalid role mapping %q: %v", value, err)
+		}
+	}
+	if cfg.DefaultRole != "" {
+		if err := validator.ValidateAdminRole(cfg.DefaultRole); err != nil {
+			return fmt.Errorf("sso: invalid default_role: %v", err)
+		}
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = "groups"
+	}
+
+	ssoProvider = oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+	}, nil)
+	return nil
+}
+
+// passwordLoginDisabled 仅SSO模式下禁止密码登录，应急账号除外
+func passwordLoginDisabled(username string) bool {
+	cfg := securityCfg.SSO
+	if !cfg.Enabled || !cfg.SSOOnly {
+		return false
+	}
+	for _, u := range cfg.BreakGlassUsernames {
+		if u == username {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSSOConfig 登录页获取单点登录配置
+func GetSSOConfig(c *gin.Context) {
+	response.Success(c, gin.H{
+		"enabled":  securityCfg.SSO.Enabled,
+		"sso_only": securityCfg.SSO.Enabled && securityCfg.SSO.SSOOnly,
+	})
+}
+
+// SSOAuthorize 发起单点登录：生成 state、nonce 和 PKCE 参数，返回身份提供方授权地址
+func SSOAuthorize(c *gin.Context) {
+	startSSO(c, 0)
+}
+
+// SSOLink 已登录的本地管理员发起关联：在身份提供方完成认证后，回调把身份绑定到当前账号
+// 单点登录不再按邮箱自动关联已有账号，这是关联本地账号的唯一方式
+func SSOLink(c *gin.Context) {
+	admin, ok := loadCurrentAdmin(c)
+	if !ok {
+		return
+	}
+	if admin.SSOSubject != "" {
+		response.Conflict(c, "当前账号已关联单点登录身份")
+		return
+	}
+	startSSO(c, admin.ID)
+}
+
+// startSSO 创建授权请求并返回授权地址，linkAdminID 非0时回调只关联该管理员而不登录
+func startSSO(c *gin.Context, linkAdminID uint) {
+	if ssoProvider == nil {
+		response.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	state, err1 := oidc.NewState()
+	nonce, err2 := oidc.NewState()
+	verifier, err3 := oidc.NewCodeVerifier()
+	if err1 != nil || err2 != nil || err3 != nil {
+		response.InternalError(c, "生成登录参数失败")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	authURL, err := ssoProvider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("[SSO] Failed to build authorization url: %v", err)
+		response.ServiceUnavailable(c, "身份提供方暂不可用")
+		return
+	}
+
+	db := database.GetDB()
+	// 顺带清理过期的授权请求
+	db.Where("expires_at < ?", time.Now()).Delete(&model.AdminSSOState{})
+	if err := db.Create(&model.AdminSSOState{
+		StateHash:    hashSSOState(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkAdminID:  linkAdminID,
+		ExpiresAt:    time.Now().Add(ssoStateTTL),
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"authorization_url": authURL})
+}
+
+// SSOCallback 单点登录回调：前端回调页提交 code 和 state，校验后签发会话
+func SSOCallback(c *gin.Context) {
+	if ssoProvider == nil {
+		response.NotFound(c, "未启用单点登录")
+		return
+	}
+
+	var req struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "code 和 state 不能为空")
+		return
+	}
+
+	pending, ok := consumeSSOState(req.State)
+	if !ok {
+		response.Unauthorized(c, "登录请求无效或已过期，请重新登录")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	token, err := ssoProvider.Exchange(ctx, req.Code, pending.CodeVerifier)
+	if err != nil {
+		log.Printf("[SSO] Code exchange failed: %v", err)
+		response.Unauthorized(c, "单点登录失败，请重新登录")
+		return
+	}
+	claims, err := ssoProvider.VerifyIDToken(ctx, token.IDToken, pending.Nonce)
+	if err != nil {
+		log.Printf("[SSO] ID token verification failed: %v", err)
+		response.Unauthorized(c, "单点登录失败，请重新登录")
+		return
+	}
+
+	if !ssoEmailAllowed(claims.Email) {
+		recordSSODenied(c, claims, "email_domain")
+		response.Forbidden(c, "该账号所属域名不允许登录管理后台")
+		return
+	}
+	role := mapSSORole(claims)
+	if role == "" {
+		recordSSODenied(c, claims, "no_role")
+		response.Forbidden(c, "该账号未被授权访问管理后台")
+		return
+	}
+	if pending.LinkAdminID != 0 {
+		linkSSOAdmin(c, pending.LinkAdminID, claims)
+		return
+	}
+
+	admin, created, err := provisionSSOAdmin(claims, role)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if admin.Status != model.AdminStatusActive {
+		recordSSODenied(c, claims, "disabled")
+		response.Forbidden(c, "账号已被禁用")
+		return
+	}
+
+	c.Set("user_id", admin.ID)
+	c.Set("username", admin.Username)
+	if created {
+		middleware.RecordAuditEvent(c, "create", "admin", strconv.Itoa(int(admin.ID)), "单点登录自动创建管理员", gin.H{
+			"sso_subject": claims.Subject,
+			"email":       claims.Email,
+			"role":        role,
+		})
+	}
+	middleware.RecordAuditEvent(c, "login", "admin_sso", strconv.Itoa(int(admin.ID)), "单点登录", gin.H{
+		"sso_subject": claims.Subject,
+		"role":        role,
+	})
+
+	// 身份提供方已完成身份验证（含其多因素策略），不再要求本地TOTP
+	issueSession(c, admin)
+}
+
+// consumeSSOState 一次性消费授权请求，防止回调被重放
+func consumeSSOState(state string) (*model.AdminSSOState, bool) {
+	db := database.GetDB()
+
+	var pending model.AdminSSOState
+	if err := db.Where("state_hash = ? AND expires_at > ?", hashSSOState(state), time.Now()).First(&pending).Error; err != nil {
+		return nil, false
+	}
+	result := db.Where("id = ?", pending.ID).Delete(&model.AdminSSOState{})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return nil, false
+	}
+	return &pending, true
+}
+
+// mapSSORole 根据角色声明映射管理员角色，同时命中多个映射时取权限最高的角色
+func mapSSORole(claims *oidc.Claims) string {
+	cfg := securityCfg.SSO
+	role := ""
+	for _, value := range claims.StringsClaim(cfg.RoleClaim) {
+		switch cfg.RoleMapping[value] {
+		case model.AdminRoleAdmin:
+			return model.AdminRoleAdmin
+		case model.AdminRoleViewer:
+			role = model.AdminRoleViewer
+		}
+	}
+	if role == "" {
+		role = cfg.DefaultRole
+	}
+	return role
+}
+
+// ssoEmailAllowed 校验邮箱域名白名单
+func ssoEmailAllowed(email string) bool {
+	domains := securityCfg.SSO.AllowedEmailDomains
+	if len(domains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+	for _, d := range domains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// linkSSOAdmin 把身份提供方的 sub 绑定到发起关联的管理员，同一身份只能关联一个账号
+func linkSSOAdmin(c *gin.Context, adminID uint, claims *oidc.Claims) {
+	db := database.GetDB()
+
+	var taken int64
+	db.Model(&model.Admin{}).Where("sso_subject = ?", claims.Subject).Count(&taken)
+	if taken > 0 {
+		response.Conflict(c, "该单点登录身份已关联其他账号")
+		return
+	}
+
+	result := db.Model(&model.Admin{}).
+		Where("id = ? AND status = ? AND sso_subject = ''", adminID, model.AdminStatusActive).
+		Update("sso_subject", claims.Subject)
+	if result.Error != nil {
+		response.DBError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.Conflict(c, "账号不可用或已关联单点登录身份")
+		return
+	}
+
+	c.Set("user_id", adminID)
+	middleware.RecordAuditEvent(c, "link", "admin_sso", strconv.Itoa(int(adminID)), "关联单点登录身份", gin.H{
+		"sso_subject": claims.Subject,
+		"email":       claims.Email,
+	})
+	response.SuccessWithMessage(c, gin.H{"linked": true}, "单点登录身份已关联")
+}
+
+// provisionSSOAdmin 按 sub 查找管理员，未找到时自动创建；已有本地账号只能通过 SSOLink 显式关联，
+// 不按邮箱匹配，避免身份提供方中同邮箱的账号接管本地管理员
+// 每次登录同步角色和邮箱，身份提供方是角色的唯一来源
+func provisionSSOAdmin(claims *oidc.Claims, role string) (*model.Admin, bool, error) {
+	db := database.GetDB()
+
+	var admin model.Admin
+	err := db.Where("sso_subject = ?", claims.Subject).First(&admin).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		username, err := ssoUsername(claims)
+		if err != nil {
+			return nil, false, err
+		}
+		admin = model.Admin{
+			Username:   username,
+			Nickname:   claims.Name,
+			Email:      claims.Email,
+			Status:     model.AdminStatusActive,
+			Role:       role,
+			SSOSubject: claims.Subject,
+		}
+		if err := db.Create(&admin).Error; err != nil {
+			return nil, false, err
+		}
+		return &admin, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	updates := map[string]interface{}{"role": role}
+	if claims.Email != "" {
+		updates["email"] = claims.Email
+	}
+	if err := db.Model(&admin).Updates(updates).Error; err != nil {
+		return nil, false, err
+	}
+	if err := db.First(&admin, admin.ID).Error; err != nil {
+		return nil, false, err
+	}
+	return &admin, false, nil
+}
+
+// ssoUsername 根据声明生成符合规则且未被占用的用户名
+func ssoUsername(claims *oidc.Claims) (string, error) {
+	var candidates []string
+	if claims.PreferredUsername != "" {
+		candidates = append(candidates, claims.PreferredUsername)
+	}
+	if at := strings.Index(claims.Email, "@"); at > 0 {
+		candidates = append(candidates, claims.Email[:at])
+	}
+	candidates = append(candidates, "sso-"+hashSSOState(claims.Subject)[:12])
+
+	db := database.GetDB()
+	for _, candidate := range candidates {
+		base := usernameInvalidChars.ReplaceAllString(candidate, "-")
+		if base != "" && !isASCIILetter(base[0]) {
+			base = "u" + base
+		}
+		if len(base) > 45 {
+			base = base[:45]
+		}
+		if validator.ValidateUsername(base) != nil {
+			continue
+		}
+
+		for i := 1; i < 100; i++ {
+			name := base
+			if i > 1 {
+				name = base + "-" + strconv.Itoa(i)
+			}
+			var count int64
+			db.Unscoped().Model(&model.Admin{}).Where("username = ?", name).Count(&count)
+			if count == 0 {
+				return name, nil
+			}
+		}
+	}
+	return "", errors.New("sso: unable to allocate username")
+}
+
+func recordSSODenied(c *gin.Context, claims *oidc.Claims, reason string) {
+	middleware.RecordAuditEvent(c, "login_denied", "admin_sso", claims.Subject, "单点登录被拒绝", gin.H{
+		"sso_subject": claims.Subject,
+		"email":       claims.Email,
+		"reason":      reason,
+	})
+}
+
+// hashSSOState 计算 state 哈希，数据库中不保存明文
+func hashSSOState(state string) string {
+	sum := sha256.Sum256([]byte(state))
+	return hex.EncodeToString(sum[:])
+}
+
+func isASCIILetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
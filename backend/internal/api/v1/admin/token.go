@@ -0,0 +1,250 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTokenDays = 90
+	maxTokenDays     = 365
+)
+
+// CreateAPITokenRequest 签发API令牌请求
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	AppIDs        []uint   `json:"app_ids" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// ListAPITokenScopes 可授予API令牌的功能列表
+func ListAPITokenScopes(c *gin.Context) {
+	response.Success(c, middleware.TokenScopes())
+}
+
+// ListAPITokens API令牌列表（不含明文），只包含授权应用全部属于当前管理员所属组织的令牌
+func ListAPITokens(c *gin.Context) {
+	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
+	page, size = validator.ValidatePagination(page, size)
+
+	apps, err := manageableAppIDs(c)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	query := database.GetDB().Model(&model.APIToken{})
+	if c.Query("include_revoked") != "true" {
+		query = query.Where("revoked_at IS NULL")
+	}
+
+	// 授权应用以JSON保存，按应用归属在内存中筛选后分页
+	var all []model.APIToken
+	if err := query.Order("id DESC").Find(&all).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	tokens := make([]model.APIToken, 0, size)
+	for _, token := range all {
+		if tokenInScope(&token, apps) {
+			tokens = append(tokens, token)
+		}
+	}
+	total := int64(len(tokens))
+	start, end := (page-1)*size, page*size
+	if start > len(tokens) {
+		start = len(tokens)
+	}
+	if end > len(tokens) {
+		end = len(tokens)
+	}
+	response.PageSuccess(c, tokens[start:end], total, page, size)
+}
+
+// CreateAPIToken 签发API令牌，明文只在创建时返回一次
+func CreateAPIToken(c *gin.Context) {
+	var req CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "令牌名称、授权功能和应用不能为空")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > 100 {
+		response.ParamError(c, "令牌名称长度应在1-100个字符之间")
+		return
+	}
+	if len(req.Scopes) == 0 || len(req.AppIDs) == 0 {
+		response.ParamError(c, "至少需要授权一个功能和一个应用")
+		return
+	}
+
+	allowed := make(map[string]bool)
+	for _, s := range middleware.TokenScopes() {
+		allowed[s] = true
+	}
+	for _, s := range req.Scopes {
+		if !allowed[s] {
+			response.ParamError(c, "不支持的授权功能: "+s)
+			return
+		}
+	}
+
+	// 只能授权签发人所属组织（成员及以上角色）的应用
+	apps, err := manageableAppIDs(c)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	for _, id := range req.AppIDs {
+		if !apps[id] {
+			response.ParamError(c, "授权的应用不存在或不属于您的组织")
+			return
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultTokenDays
+	}
+	if days < 1 || days > maxTokenDays {
+		response.ParamError(c, "有效期应在1-"+strconv.Itoa(maxTokenDays)+"天之间")
+		return
+	}
+
+	raw, err := newAPIToken()
+	if err != nil {
+		response.InternalError(c, "生成令牌失败")
+		return
+	}
+
+	scopesJSON, _ := json.Marshal(req.Scopes)
+	appIDsJSON, _ := json.Marshal(uniqueUints(req.AppIDs))
+	token := model.APIToken{
+		Name:        req.Name,
+		TokenHash:   middleware.HashAPIToken(raw),
+		TokenPrefix: raw[:len(middleware.APITokenPrefix)+8],
+		Scopes:      string(scopesJSON),
+		AppIDs:      string(appIDsJSON),
+		CreatedBy:   c.GetUint("user_id"),
+		ExpiresAt:   time.Now().AddDate(0, 0, days),
+	}
+	if err := database.GetDB().Create(&token).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "create", "api_token", strconv.Itoa(int(token.ID)), "签发API令牌", gin.H{
+		"name":       token.Name,
+		"scopes":     req.Scopes,
+		"app_ids":    req.AppIDs,
+		"expires_at": token.ExpiresAt,
+	})
+	response.SuccessWithMessage(c, gin.H{
+		"token":     raw,
+		"api_token": token,
+	}, "令牌已签发，请立即保存，关闭后将无法再次查看")
+}
+
+// RevokeAPIToken 吊销API令牌，立即生效
+func RevokeAPIToken(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	apps, err := manageableAppIDs(c)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	var token model.APIToken
+	if err := database.GetDB().First(&token, id).Error; err != nil || !tokenInScope(&token, apps) {
+		response.NotFound(c, "令牌不存在")
+		return
+	}
+	if token.RevokedAt != nil {
+		response.ParamError(c, "令牌已吊销")
+		return
+	}
+
+	if err := database.GetDB().Model(&token).Updates(map[string]interface{}{
+		"revoked_at": time.Now(),
+		"revoked_by": c.GetUint("user_id"),
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "revoke", "api_token", strconv.Itoa(int(token.ID)), "吊销API令牌", gin.H{
+		"name": token.Name,
+	})
+	response.SuccessWithMessage(c, nil, "令牌已吊销")
+}
+
+// manageableAppIDs 当前管理员以成员及以上角色所属组织的全部应用，签发和管理API令牌都限定在这些应用内
+func manageableAppIDs(c *gin.Context) (map[uint]bool, error) {
+	var ids []uint
+	err := database.GetDB().Model(&model.App{}).
+		Where("org_id IN (?)", database.GetDB().Model(&model.OrgMember{}).Select("org_id").
+			Where("admin_id = ? AND role <> ?", c.GetUint("user_id"), model.OrgRoleViewer)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	apps := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		apps[id] = true
+	}
+	return apps, nil
+}
+
+// tokenInScope 令牌授权的应用全部在 apps 中时才可查看和吊销，避免跨组织操作他人的令牌
+func tokenInScope(token *model.APIToken, apps map[uint]bool) bool {
+	var appIDs []uint
+	if err := json.Unmarshal([]byte(token.AppIDs), &appIDs); err != nil || len(appIDs) == 0 {
+		return false
+	}
+	for _, id := range appIDs {
+		if !apps[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// newAPIToken 生成带前缀的随机令牌
+func newAPIToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return middleware.APITokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// uniqueUints 去重并保持原有顺序
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
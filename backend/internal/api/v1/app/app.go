@@ -3,16 +3,24 @@ package app
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"strconv"
+	"time"
 
+	"app-platform-backend/internal/dashboard"
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
 	"app-platform-backend/internal/response"
 	"app-platform-backend/internal/validator"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
 )
 
+var errOrgAppLimit = errors.New("组织应用数量已达上限")
+
 func generateAppID() string {
 	bytes := make([]byte, 8)
 	rand.Read(bytes)
@@ -29,7 +37,13 @@ func generateAppSecret() string {
 func List(c *gin.Context) {
 	var apps []model.App
 
-	query := database.GetDB().Model(&model.App{})
+	// 只列出当前管理员所属组织的应用
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	query := database.GetDB().Model(&model.App{}).Where("org_id IN ?", orgIDs)
 
 	// 搜索
 	if keyword := c.Query("keyword"); keyword != "" {
@@ -55,18 +69,28 @@ func List(c *gin.Context) {
 		return
 	}
 
-	// 获取每个APP的模块数量
+	// 批量统计当前页应用的模块数和用户数
 	type AppWithModules struct {
 		model.App
 		ModuleCount int64 `json:"module_count"`
 		UserCount   int64 `json:"user_count"`
 	}
 
+	appIDs := make([]uint, len(apps))
+	for i, app := range apps {
+		appIDs[i] = app.ID
+	}
+	counts, err := dashboard.AppCounts(database.GetDB(), appIDs)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
 	result := make([]AppWithModules, len(apps))
 	for i, app := range apps {
 		result[i].App = app
-		database.GetDB().Model(&model.AppModule{}).Where("app_id = ? AND status = 1", app.ID).Count(&result[i].ModuleCount)
-		result[i].UserCount = 0 // 暂时设为0
+		result[i].ModuleCount = counts[app.ID].ModuleCount
+		result[i].UserCount = counts[app.ID].UserCount
 	}
 
 	response.PageSuccess(c, result, total, page, pageSize)
@@ -87,6 +111,11 @@ func Create(c *gin.Context) {
 		return
 	}
 
+	orgID, ok := resolveCreateOrg(c, req.OrgID)
+	if !ok {
+		return
+	}
+
 	// 获取实际的名称
 	appName := req.Name
 	if appName == "" {
@@ -94,23 +123,53 @@ func Create(c *gin.Context) {
 	}
 
 	app := model.App{
+		OrgID:       orgID,
 		Name:        appName,
 		AppID:       generateAppID(),
-		AppSecret:   generateAppSecret(),
 		PackageName: req.PackageName,
 		Description: req.Description,
 		Icon:        req.Icon,
 		Status:      1,
 	}
 
-	// 使用事务创建APP和关联模块
+	// 使用事务创建APP、各环境及其密钥和关联模块
+	var secret string
+	envSecrets := map[string]string{}
 	err := database.WithTransaction(func(tx *database.DB) error {
+		// 锁定组织记录，避免并发创建突破应用数量上限
+		var org model.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, orgID).Error; err != nil {
+			return err
+		}
+		if err := checkOrgAppLimit(tx, &org); err != nil {
+			return err
+		}
+
 		if err := tx.Create(&app).Error; err != nil {
 			return err
 		}
 
-		// 启用选中的模块
-		for _, sourceModule := range req.Modules {
+		envs, err := ensureEnvironments(tx, app.ID)
+		if err != nil {
+			return err
+		}
+		for _, env := range envs {
+			envSecret, _, err := issueCredential(tx, app.ID, env.ID, c.GetUint("user_id"))
+			if err != nil {
+				return err
+			}
+			envSecrets[env.Code] = envSecret
+			if env.IsDefault {
+				secret = envSecret
+			}
+		}
+
+		// 启用选中的模块，未选择时使用组织的默认模块
+		modules := req.Modules
+		if len(modules) == 0 && org.DefaultModules != "" {
+			json.Unmarshal([]byte(org.DefaultModules), &modules)
+		}
+		for _, sourceModule := range modules {
 			appModule := model.AppModule{
 				AppID:        app.ID,
 				ModuleCode:   sourceModule,
@@ -121,16 +180,35 @@ func Create(c *gin.Context) {
 			if err := tx.Create(&appModule).Error; err != nil {
 				return err
 			}
+			for _, env := range envs {
+				if err := tx.Create(&model.AppModuleConfig{
+					AppID:      app.ID,
+					EnvID:      env.ID,
+					ModuleCode: sourceModule,
+					Config:     "{}",
+				}).Error; err != nil {
+					return err
+				}
+			}
 		}
 		return nil
 	})
 
+	if errors.Is(err, errOrgAppLimit) {
+		response.Forbidden(c, err.Error())
+		return
+	}
 	if err != nil {
 		response.DBError(c, err)
 		return
 	}
 
-	response.Success(c, app)
+	// 密钥明文只在创建时返回这一次，app_secret 为默认环境的密钥
+	response.Success(c, struct {
+		model.App
+		AppSecret  string            `json:"app_secret"`
+		EnvSecrets map[string]string `json:"env_secrets"`
+	}{app, secret, envSecrets})
 }
 
 // Detail 获取APP详情
@@ -207,59 +285,128 @@ func Update(c *gin.Context) {
 	response.Success(c, app)
 }
 
-// Delete 删除APP
+// Delete 删除APP，需要组织管理员权限
+// 应用进入待清理状态，恢复期内可以恢复，期满后由后台任务清理全部应用数据
 func Delete(c *gin.Context) {
-	id := c.Param("id")
-
-	// 验证ID
-	if _, err := validator.ValidateID(id); err != nil {
-		response.ParamError(c, err.Error())
+	app, _ := middleware.GetScopedApp(c)
+	if !requireOrgAdmin(c) {
 		return
 	}
 
-	// 使用事务删除APP和关联数据
+	purgeAfter := time.Now().AddDate(0, 0, restoreDays)
 	err := database.WithTransaction(func(tx *database.DB) error {
-		if err := tx.Delete(&model.App{}, id).Error; err != nil {
+		if err := tx.Model(&model.App{}).Where("id = ?", app.ID).Updates(map[string]interface{}{
+			"purge_after": purgeAfter,
+			"deleted_by":  c.GetUint("user_id"),
+		}).Error; err != nil {
 			return err
 		}
-		// 删除关联的模块
-		if err := tx.Where("app_id = ?", id).Delete(&model.AppModule{}).Error; err != nil {
-			return err
-		}
-		return nil
+		return tx.Delete(&model.App{}, app.ID).Error
 	})
-
 	if err != nil {
 		response.DBError(c, err)
 		return
 	}
 
-	response.SuccessWithMessage(c, nil, "应用删除成功")
+	middleware.RecordAuditEvent(c, "delete", "app", strconv.Itoa(int(app.ID)), "删除应用", gin.H{
+		"name":        app.Name,
+		"org_id":      app.OrgID,
+		"purge_after": purgeAfter,
+	})
+	response.SuccessWithMessage(c, gin.H{"purge_after": purgeAfter}, "应用已删除，恢复期内可以恢复")
 }
 
-// ResetSecret 重置APP密钥
-func ResetSecret(c *gin.Context) {
-	id := c.Param("id")
-
-	// 验证ID
-	if _, err := validator.ValidateID(id); err != nil {
-		response.ParamError(c, err.Error())
+// Transfer 将应用转移到另一个组织，需要同时是原组织和目标组织的管理员
+func Transfer(c *gin.Context) {
+	app, _ := middleware.GetScopedApp(c)
+	if !requireOrgAdmin(c) {
 		return
 	}
 
-	var app model.App
-	if err := database.GetDB().First(&app, id).Error; err != nil {
-		response.NotFound(c, "应用不存在")
+	var req struct {
+		OrgID uint `json:"org_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "目标组织不能为空")
+		return
+	}
+	if req.OrgID == app.OrgID {
+		response.ParamError(c, "应用已属于该组织")
+		return
+	}
+	if !middleware.RequireOrgRole(c, req.OrgID, model.OrgRoleAdmin) {
 		return
 	}
 
-	newSecret := generateAppSecret()
-	if err := database.GetDB().Model(&app).Update("app_secret", newSecret).Error; err != nil {
+	fromOrgID := app.OrgID
+	err := database.WithTransaction(func(tx *database.DB) error {
+		var org model.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, req.OrgID).Error; err != nil {
+			return err
+		}
+		if err := checkOrgAppLimit(tx, &org); err != nil {
+			return err
+		}
+		return tx.Model(&model.App{}).Where("id = ? AND org_id = ?", app.ID, fromOrgID).Update("org_id", org.ID).Error
+	})
+	if errors.Is(err, errOrgAppLimit) {
+		response.Forbidden(c, err.Error())
+		return
+	}
+	if err != nil {
 		response.DBError(c, err)
 		return
 	}
 
-	response.Success(c, gin.H{
-		"app_secret": newSecret,
+	middleware.RecordAuditEvent(c, "transfer", "app", strconv.Itoa(int(app.ID)), "转移应用所属组织", gin.H{
+		"from_org_id": fromOrgID,
+		"to_org_id":   req.OrgID,
 	})
+	app.OrgID = req.OrgID
+	response.SuccessWithMessage(c, app, "应用已转移")
+}
+
+// resolveCreateOrg 确定新应用所属组织：未指定时使用管理员唯一所属的组织，创建需要成员及以上角色
+func resolveCreateOrg(c *gin.Context, orgID uint) (uint, bool) {
+	if orgID == 0 {
+		ids, err := middleware.MemberOrgIDs(c)
+		if err != nil {
+			response.DBError(c, err)
+			return 0, false
+		}
+		if len(ids) != 1 {
+			response.ParamError(c, "请选择应用所属组织")
+			return 0, false
+		}
+		orgID = ids[0]
+	}
+	if !middleware.RequireOrgRole(c, orgID, model.OrgRoleMember) {
+		return 0, false
+	}
+	return orgID, true
+}
+
+// checkOrgAppLimit 校验组织应用数量上限，调用方需已锁定组织记录
+func checkOrgAppLimit(tx *database.DB, org *model.Organization) error {
+	if org.MaxApps <= 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.App{}).Where("org_id = ?", org.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(org.MaxApps) {
+		return errOrgAppLimit
+	}
+	return nil
+}
+
+// requireOrgAdmin 校验当前管理员在应用所属组织中具有管理员权限
+func requireOrgAdmin(c *gin.Context) bool {
+	member, ok := middleware.GetOrgMember(c)
+	if !ok || !middleware.OrgRoleAtLeast(member.Role, model.OrgRoleAdmin) {
+		response.Forbidden(c, "需要组织管理员权限")
+		return false
+	}
+	return true
 }
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// defaultEnvironments 新建应用的环境，按晋级顺序排列，生产环境为默认环境
+var defaultEnvironments = []model.AppEnvironment{
+	{Code: model.EnvDev, Name: "开发", SortOrder: 1},
+	{Code: model.EnvStaging, Name: "预发布", SortOrder: 2},
+	{Code: model.EnvProd, Name: "生产", SortOrder: 3, IsDefault: true},
+}
+
+// ensureEnvironments 创建应用缺少的默认环境，返回应用的全部环境（按晋级顺序）
+func ensureEnvironments(tx *database.DB, appID uint) ([]model.AppEnvironment, error) {
+	envs := make([]model.AppEnvironment, len(defaultEnvironments))
+	for i, e := range defaultEnvironments {
+		e.AppID = appID
+		envs[i] = e
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&envs).Error; err != nil {
+		return nil, err
+	}
+
+	var result []model.AppEnvironment
+	err := tx.Where("app_id = ?", appID).Order("sort_order ASC").Find(&result).Error
+	return result, err
+}
+
+// ListEnvironments 应用环境列表
+func ListEnvironments(c *gin.Context) {
+	var envs []model.AppEnvironment
+	if err := database.GetDB().Where("app_id = ?", middleware.ScopedAppID(c)).
+		Order("sort_order ASC").Find(&envs).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, envs)
+}
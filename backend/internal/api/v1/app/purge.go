@@ -0,0 +1,159 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// restoreDays 应用删除后可恢复的天数
+var restoreDays = 30
+
+var errRestoreExpired = errors.New("应用已过恢复期，数据正在清理")
+
+// deletedAppView 待清理的应用及清理进度
+type deletedAppView struct {
+	model.App
+	DeletedAt  time.Time          `json:"deleted_at"`
+	Restorable bool               `json:"restorable"`
+	PurgeJob   *model.AppPurgeJob `json:"purge_job"`
+}
+
+// InitPurge 初始化应用删除恢复期
+func InitPurge(cfg *config.AppPurgeConfig) {
+	if cfg.RestoreDays > 0 {
+		restoreDays = cfg.RestoreDays
+	}
+}
+
+// ListDeleted 已删除、待清理的应用及清理进度
+func ListDeleted(c *gin.Context) {
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	var apps []model.App
+	if err := database.GetDB().Unscoped().
+		Where("org_id IN ? AND deleted_at IS NOT NULL AND purge_after IS NOT NULL", orgIDs).
+		Order("deleted_at DESC").
+		Find(&apps).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	appIDs := make([]uint, len(apps))
+	for i, a := range apps {
+		appIDs[i] = a.ID
+	}
+	var jobs []model.AppPurgeJob
+	if err := database.GetDB().Where("app_id IN ?", appIDs).Find(&jobs).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	jobByApp := make(map[uint]*model.AppPurgeJob, len(jobs))
+	for i := range jobs {
+		jobByApp[jobs[i].AppID] = &jobs[i]
+	}
+
+	now := time.Now()
+	result := make([]deletedAppView, len(apps))
+	for i, a := range apps {
+		job := jobByApp[a.ID]
+		result[i] = deletedAppView{
+			App:        a,
+			DeletedAt:  a.DeletedAt.Time,
+			Restorable: job == nil && a.PurgeAfter.After(now),
+			PurgeJob:   job,
+		}
+	}
+	response.Success(c, result)
+}
+
+// Restore 在恢复期内恢复已删除的应用，需要所属组织的管理员权限
+func Restore(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	var app model.App
+	if err := database.GetDB().Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND purge_after IS NOT NULL", id).
+		First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "应用不存在")
+		} else {
+			response.DBError(c, err)
+		}
+		return
+	}
+	if !middleware.RequireOrgRole(c, app.OrgID, model.OrgRoleAdmin) {
+		return
+	}
+
+	err = database.WithTransaction(func(tx *database.DB) error {
+		var org model.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, app.OrgID).Error; err != nil {
+			return err
+		}
+
+		// 清理任务一旦创建，应用数据可能已被部分删除，不能再恢复
+		var jobs int64
+		if err := tx.Model(&model.AppPurgeJob{}).Where("app_id = ?", app.ID).Count(&jobs).Error; err != nil {
+			return err
+		}
+		if jobs > 0 {
+			return errRestoreExpired
+		}
+		if err := checkOrgAppLimit(tx, &org); err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Model(&model.App{}).
+			Where("id = ? AND deleted_at IS NOT NULL AND purge_after > ?", app.ID, time.Now()).
+			Updates(map[string]interface{}{
+				"deleted_at":  nil,
+				"purge_after": nil,
+				"deleted_by":  0,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRestoreExpired
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errRestoreExpired):
+		response.Conflict(c, err.Error())
+		return
+	case errors.Is(err, errOrgAppLimit):
+		response.Forbidden(c, err.Error())
+		return
+	case err != nil:
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "restore", "app", strconv.Itoa(int(app.ID)), "恢复已删除的应用", gin.H{
+		"name":   app.Name,
+		"org_id": app.OrgID,
+	})
+	database.GetDB().First(&app, app.ID)
+	response.SuccessWithMessage(c, app, "应用已恢复")
+}
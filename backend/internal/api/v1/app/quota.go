@@ -0,0 +1,138 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// usageItem 配额项的上限和当前用量，Limit 为 0 表示不限制
+type usageItem struct {
+	Metric string `json:"metric"`
+	Limit  int64  `json:"limit"`
+	Used   int64  `json:"used"`
+	Window string `json:"window"` // day、minute，存量配额项为空
+}
+
+// ListPlans 配额套餐列表
+func ListPlans(c *gin.Context) {
+	var plans []model.Plan
+	if err := database.GetDB().Order("id ASC").Find(&plans).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, plans)
+}
+
+// Usage 应用各配额项的用量和上限
+func Usage(c *gin.Context) {
+	app, _ := middleware.GetScopedApp(c)
+
+	limits, planID, err := quota.Resolve(app.ID)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	used, err := quota.Usage(app.ID)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	items := make([]usageItem, len(quota.Metrics))
+	for i, m := range quota.Metrics {
+		items[i] = usageItem{Metric: m, Limit: limits[m], Used: used[m], Window: quota.Window(m)}
+	}
+
+	var plan *model.Plan
+	if planID > 0 {
+		var p model.Plan
+		if err := database.GetDB().First(&p, planID).Error; err == nil {
+			plan = &p
+		}
+	}
+
+	var overrides model.AppQuota
+	if err := database.GetDB().Where("app_id = ?", app.ID).First(&overrides).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		response.DBError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"plan":      plan,
+		"overrides": overrides,
+		"items":     items,
+	})
+}
+
+// UpdateQuota 修改应用套餐和单独设置的配额，需要组织所有者权限
+func UpdateQuota(c *gin.Context) {
+	app, _ := middleware.GetScopedApp(c)
+	member, ok := middleware.GetOrgMember(c)
+	if !ok || member.Role != model.OrgRoleOwner {
+		response.Forbidden(c, "需要组织所有者权限")
+		return
+	}
+
+	var req validator.QuotaUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if err := validator.ValidateQuotaUpdate(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{"updated_by": c.GetUint("user_id")}
+	if req.PlanID != nil {
+		if *req.PlanID == 0 {
+			updates["plan_id"] = nil
+		} else {
+			var plan model.Plan
+			if err := database.GetDB().First(&plan, *req.PlanID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					response.ParamError(c, "套餐不存在")
+				} else {
+					response.DBError(c, err)
+				}
+				return
+			}
+			updates["plan_id"] = plan.ID
+		}
+	}
+	for metric, v := range req.Overrides {
+		column, _ := quota.Column(metric)
+		if v == nil {
+			updates[column] = nil
+		} else {
+			updates[column] = *v
+		}
+	}
+
+	var q model.AppQuota
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Where(model.AppQuota{AppID: app.ID}).FirstOrCreate(&q).Error; err != nil {
+			return err
+		}
+		return tx.Model(&q).Updates(updates).Error
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	quota.Invalidate(app.ID)
+
+	middleware.RecordAuditEvent(c, "update_quota", "app", strconv.Itoa(int(app.ID)), "修改应用配额", updates)
+	database.GetDB().First(&q, q.ID)
+	response.Success(c, q)
+}
@@ -0,0 +1,282 @@
+package app
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxGraceHours 轮换过渡期上限
+const maxGraceHours = 30 * 24
+
+var (
+	secretBox   *secretbox.Box
+	secretGrace = 72 * time.Hour
+)
+
+// InitSecrets 初始化应用密钥加密存储
+func InitSecrets(box *secretbox.Box, cfg *config.SDKConfig) {
+	secretBox = box
+	if cfg.SecretGraceHours > 0 {
+		secretGrace = time.Duration(cfg.SecretGraceHours) * time.Hour
+	}
+}
+
+// MigrateLegacySecrets 将 apps 表中的明文密钥迁移为加密存储的 AppCredential 并清空原字段
+func MigrateLegacySecrets() error {
+	var apps []model.App
+	if err := database.GetDB().Unscoped().Where("app_secret <> ''").Find(&apps).Error; err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		encrypted, err := secretBox.Encrypt(app.AppSecret)
+		if err != nil {
+			return err
+		}
+		err = database.WithTransaction(func(tx *database.DB) error {
+			// 旧密钥归入默认环境
+			envs, err := ensureEnvironments(tx, app.ID)
+			if err != nil {
+				return err
+			}
+			var envID uint
+			for _, env := range envs {
+				if env.IsDefault {
+					envID = env.ID
+				}
+			}
+			if err := tx.Create(&model.AppCredential{
+				AppID:           app.ID,
+				EnvID:           envID,
+				SecretEncrypted: encrypted,
+				SecretHint:      secretHint(app.AppSecret),
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Unscoped().Model(&model.App{}).Where("id = ?", app.ID).Update("app_secret", "").Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(apps) > 0 {
+		log.Printf("[App] Migrated %d legacy app secrets to encrypted storage", len(apps))
+	}
+	return nil
+}
+
+// issueCredential 生成新密钥并加密保存，返回明文（仅此一次可见）
+func issueCredential(tx *database.DB, appID, envID, createdBy uint) (string, *model.AppCredential, error) {
+	secret := generateAppSecret()
+	encrypted, err := secretBox.Encrypt(secret)
+	if err != nil {
+		return "", nil, err
+	}
+	cred := &model.AppCredential{
+		AppID:           appID,
+		EnvID:           envID,
+		SecretEncrypted: encrypted,
+		SecretHint:      secretHint(secret),
+		CreatedBy:       createdBy,
+	}
+	if err := tx.Create(cred).Error; err != nil {
+		return "", nil, err
+	}
+	return secret, cred, nil
+}
+
+// secretHint 密钥末4位
+func secretHint(secret string) string {
+	if len(secret) <= 4 {
+		return secret
+	}
+	return secret[len(secret)-4:]
+}
+
+// credentialStatus 计算密钥当前状态
+func credentialStatus(cred *model.AppCredential, now time.Time) string {
+	switch {
+	case cred.RevokedAt != nil:
+		return "revoked"
+	case cred.ExpiresAt == nil:
+		return "active"
+	case cred.ExpiresAt.After(now):
+		return "expiring"
+	default:
+		return "expired"
+	}
+}
+
+// ListSecrets 应用当前环境的密钥列表（不含明文）
+func ListSecrets(c *gin.Context) {
+	app, ok := loadApp(c)
+	if !ok {
+		return
+	}
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+
+	var creds []model.AppCredential
+	if err := database.GetDB().Where("app_id = ? AND env_id = ?", app.ID, env.ID).Order("id DESC").Find(&creds).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	type credentialItem struct {
+		model.AppCredential
+		Status string `json:"status"`
+	}
+	now := time.Now()
+	items := make([]credentialItem, len(creds))
+	for i := range creds {
+		items[i] = credentialItem{AppCredential: creds[i], Status: credentialStatus(&creds[i], now)}
+	}
+	response.Success(c, items)
+}
+
+// RotateSecret 轮换应用当前环境的密钥：生成新密钥，当前有效的旧密钥在过渡期内继续可用
+func RotateSecret(c *gin.Context) {
+	app, ok := loadApp(c)
+	if !ok {
+		return
+	}
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		GraceHours *int `json:"grace_hours"`
+	}
+	// 请求体可为空，使用默认过渡期
+	_ = c.ShouldBindJSON(&req)
+
+	grace := secretGrace
+	if req.GraceHours != nil {
+		if *req.GraceHours < 0 || *req.GraceHours > maxGraceHours {
+			response.ParamError(c, "过渡期应在0-"+strconv.Itoa(maxGraceHours)+"小时之间")
+			return
+		}
+		grace = time.Duration(*req.GraceHours) * time.Hour
+	}
+
+	now := time.Now()
+	oldExpiresAt := now.Add(grace)
+	var secret string
+	var cred *model.AppCredential
+	err := database.WithTransaction(func(tx *database.DB) error {
+		// 只缩短旧密钥的有效期，不延长已在过渡期中的密钥
+		if err := tx.Model(&model.AppCredential{}).
+			Where("app_id = ? AND env_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", app.ID, env.ID, oldExpiresAt).
+			Update("expires_at", oldExpiresAt).Error; err != nil {
+			return err
+		}
+		var err error
+		secret, cred, err = issueCredential(tx, app.ID, env.ID, c.GetUint("user_id"))
+		return err
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "rotate", "app_secret", strconv.Itoa(int(app.ID)), "轮换应用密钥", gin.H{
+		"app_id":         app.AppID,
+		"env":            env.Code,
+		"credential_id":  cred.ID,
+		"old_expires_at": oldExpiresAt,
+	})
+
+	// 新密钥明文只返回这一次
+	response.Success(c, gin.H{
+		"id":             cred.ID,
+		"app_secret":     secret,
+		"old_expires_at": oldExpiresAt,
+	})
+}
+
+// RevokeSecret 立即吊销密钥（用于密钥泄露）
+func RevokeSecret(c *gin.Context) {
+	app, ok := loadApp(c)
+	if !ok {
+		return
+	}
+
+	credID, err := validator.ValidateID(c.Param("secret_id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.ShouldBindJSON(&req)
+
+	var cred model.AppCredential
+	if err := database.GetDB().Where("id = ? AND app_id = ?", credID, app.ID).First(&cred).Error; err != nil {
+		response.NotFound(c, "密钥不存在")
+		return
+	}
+	if cred.RevokedAt != nil {
+		response.ParamError(c, "密钥已吊销")
+		return
+	}
+
+	// 保证每个环境至少保留一个可用密钥，否则该环境的客户端将无法访问
+	now := time.Now()
+	var others int64
+	database.GetDB().Model(&model.AppCredential{}).
+		Where("app_id = ? AND env_id = ? AND id <> ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", app.ID, cred.EnvID, cred.ID, now).
+		Count(&others)
+	if others == 0 {
+		response.ParamError(c, "这是该环境唯一可用的密钥，请先轮换生成新密钥再吊销")
+		return
+	}
+
+	if err := database.GetDB().Model(&cred).Updates(map[string]interface{}{
+		"revoked_at":    now,
+		"revoked_by":    c.GetUint("user_id"),
+		"revoke_reason": req.Reason,
+	}).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "revoke", "app_secret", strconv.Itoa(int(app.ID)), "吊销应用密钥", gin.H{
+		"app_id":        app.AppID,
+		"credential_id": cred.ID,
+		"reason":        req.Reason,
+	})
+	response.SuccessWithMessage(c, nil, "密钥已吊销")
+}
+
+// loadApp 根据路径参数加载应用
+func loadApp(c *gin.Context) (*model.App, bool) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return nil, false
+	}
+
+	var app model.App
+	if err := database.GetDB().First(&app, id).Error; err != nil {
+		response.NotFound(c, "应用不存在")
+		return nil, false
+	}
+	return &app, true
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/scheduler"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -104,7 +106,11 @@ func List(c *gin.Context) {
 		pageSize = 20
 	}
 
-	query := db.Model(&AuditLog{})
+	scope, ok := orgScope(c)
+	if !ok {
+		return
+	}
+	query := db.Model(&AuditLog{}).Scopes(scope)
 
 	if appIDStr != "" {
 		if appID, err := strconv.ParseUint(appIDStr, 10, 32); err == nil {
@@ -160,7 +166,11 @@ func Stats(c *gin.Context) {
 	appIDStr := c.Query("app_id")
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
-	query := db.Model(&AuditLog{})
+	scope, ok := orgScope(c)
+	if !ok {
+		return
+	}
+	query := db.Model(&AuditLog{}).Scopes(scope)
 	if appIDStr != "" {
 		if appID, err := strconv.ParseUint(appIDStr, 10, 32); err == nil {
 			query = query.Where("app_id = ?", appID)
@@ -180,7 +190,7 @@ func Stats(c *gin.Context) {
 		Count  int64  `json:"count"`
 	}
 	var actionStats []ActionStat
-	db.Model(&AuditLog{}).
+	db.Model(&AuditLog{}).Scopes(scope).
 		Select("action, COUNT(*) as count").
 		Where("created_at >= ?", startTime).
 		Group("action").
@@ -192,7 +202,7 @@ func Stats(c *gin.Context) {
 		Count    int64  `json:"count"`
 	}
 	var resourceStats []ResourceStat
-	db.Model(&AuditLog{}).
+	db.Model(&AuditLog{}).Scopes(scope).
 		Select("resource, COUNT(*) as count").
 		Where("created_at >= ?", startTime).
 		Group("resource").
@@ -205,7 +215,7 @@ func Stats(c *gin.Context) {
 		Count    int64  `json:"count"`
 	}
 	var userStats []UserStat
-	db.Model(&AuditLog{}).
+	db.Model(&AuditLog{}).Scopes(scope).
 		Select("user_id, user_name, COUNT(*) as count").
 		Where("created_at >= ?", startTime).
 		Group("user_id, user_name").
@@ -219,7 +229,7 @@ func Stats(c *gin.Context) {
 		Count int64  `json:"count"`
 	}
 	var dailyStats []DailyStat
-	db.Model(&AuditLog{}).
+	db.Model(&AuditLog{}).Scopes(scope).
 		Select("DATE(created_at) as date, COUNT(*) as count").
 		Where("created_at >= ?", startTime).
 		Group("DATE(created_at)").
@@ -245,7 +255,11 @@ func Export(c *gin.Context) {
 	endTime := c.Query("end_time")
 	format := c.DefaultQuery("format", "csv")
 
-	query := db.Model(&AuditLog{})
+	scope, ok := orgScope(c)
+	if !ok {
+		return
+	}
+	query := db.Model(&AuditLog{}).Scopes(scope)
 	if appIDStr != "" {
 		if appID, err := strconv.ParseUint(appIDStr, 10, 32); err == nil {
 			query = query.Where("app_id = ?", appID)
@@ -296,6 +310,21 @@ func Export(c *gin.Context) {
 	}
 }
 
+// orgScope 审计日志只对所属组织可见：组织内应用的日志，以及组织成员的平台级操作（app_id 为0）
+// 查询参数 org_id 可进一步限定到单个组织
+func orgScope(c *gin.Context) (func(*gorm.DB) *gorm.DB, bool) {
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return nil, false
+	}
+	return func(q *gorm.DB) *gorm.DB {
+		apps := db.Model(&model.App{}).Select("id").Where("org_id IN ?", orgIDs)
+		members := db.Model(&model.OrgMember{}).Select("CAST(admin_id AS CHAR)").Where("org_id IN ?", orgIDs)
+		return q.Where("app_id IN (?) OR (app_id = 0 AND user_id IN (?))", apps, members)
+	}, true
+}
+
 // Cleanup 手动清理审计日志
 func Cleanup(c *gin.Context) {
 	retentionDays, _ := strconv.Atoi(c.DefaultQuery("retention_days", "90"))
@@ -1,26 +1,290 @@
 package config
 
 import (
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/segment"
+	"app-platform-backend/internal/validator"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+var db *gorm.DB
+
+func InitDB(database *gorm.DB) {
+	db = database
+}
+
+// List 配置列表，按请求环境（env 参数或 X-App-Env 请求头，默认环境兜底）返回该命名空间的配置
 func List(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": 0, "data": []interface{}{}})
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	query := repo.Model(&model.Config{})
+	if key := c.Query("key"); key != "" {
+		query = query.Where("config_key LIKE ?", "%"+key+"%")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var configs []model.Config
+	query.Offset((page - 1) * size).Limit(size).Order("config_key ASC").Find(&configs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": gin.H{
+			"list":  configs,
+			"total": total,
+			"page":  page,
+			"size":  size,
+			"env":   repo.Env(),
+		},
+	})
 }
 
+// Create 在请求环境中创建配置，同一环境内配置键唯一
+// target_type/target_ids 指定定向对象（all/user/tag/segment），默认对所有用户下发
 func Create(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config created"})
+	var req struct {
+		ConfigKey   string `json:"config_key" binding:"required,max=255"`
+		ConfigValue string `json:"config_value"`
+		TargetType  string `json:"target_type"`
+		TargetIDs   []uint `json:"target_ids"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	if req.TargetType == "" {
+		req.TargetType = segment.TargetAll
+	}
+	if !validateTarget(c, repo, req.TargetType, req.TargetIDs) {
+		return
+	}
+
+	var count int64
+	repo.Model(&model.Config{}).Where("config_key = ?", req.ConfigKey).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"code": 409, "message": "Config key already exists in this environment"})
+		return
+	}
+
+	config := model.Config{
+		ConfigKey:   req.ConfigKey,
+		ConfigValue: req.ConfigValue,
+		TargetType:  req.TargetType,
+		TargetIDs:   segment.FormatIDs(req.TargetIDs),
+		Description: req.Description,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := scoped(tx, repo).Create(&config); err != nil {
+			return err
+		}
+		return recordHistory(tx, c, &config, "create")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to create config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config created", "data": config})
 }
 
+// Update 更新请求环境中的配置，修改后需重新发布
 func Update(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config updated"})
+	var req struct {
+		ConfigValue *string `json:"config_value"`
+		TargetType  *string `json:"target_type"` // 修改定向时与 target_ids 一起提交
+		TargetIDs   []uint  `json:"target_ids"`
+		Description *string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+
+	repo, config, ok := loadConfig(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{"is_published": 0}
+	config.IsPublished = 0
+	if req.ConfigValue != nil {
+		updates["config_value"] = *req.ConfigValue
+		config.ConfigValue = *req.ConfigValue
+	}
+	if req.TargetType != nil {
+		if !validateTarget(c, repo, *req.TargetType, req.TargetIDs) {
+			return
+		}
+		config.TargetType, config.TargetIDs = *req.TargetType, segment.FormatIDs(req.TargetIDs)
+		updates["target_type"], updates["target_ids"] = config.TargetType, config.TargetIDs
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+		config.Description = *req.Description
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := scoped(tx, repo).Updates(config, updates); err != nil {
+			return err
+		}
+		return recordHistory(tx, c, config, "update")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to update config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config updated", "data": config})
 }
 
+// Publish 发布请求环境中的配置
 func Publish(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config published"})
+	repo, config, ok := loadConfig(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := scoped(tx, repo).Updates(config, map[string]interface{}{"is_published": 1, "published_at": now}); err != nil {
+			return err
+		}
+		return recordHistory(tx, c, config, "publish")
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to publish config"})
+		return
+	}
+
+	config.IsPublished, config.PublishedAt = 1, &now
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "Config published", "data": config})
 }
 
+// History 配置的变更历史，只能查看请求环境中的配置
 func History(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"code": 0, "data": []interface{}{}})
+	_, config, ok := loadConfig(c)
+	if !ok {
+		return
+	}
+
+	var histories []model.ConfigHistory
+	db.Where("config_id = ?", config.ID).Order("created_at DESC").Limit(100).Find(&histories)
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "data": histories})
+}
+
+// ClientList 客户端读取请求环境中已发布的配置，定向配置只下发给命中目标的登录用户
+// 分群按当前规则实时计算，与版本灰度一致
+func ClientList(c *gin.Context) {
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	var configs []model.Config
+	if err := repo.Model(&model.Config{}).Where("is_published = ?", 1).Order("config_key ASC").Find(&configs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to load configs"})
+		return
+	}
+
+	userID, _ := middleware.AppUserID(c)
+	values := make(map[string]string, len(configs))
+	matched := make(map[string]bool) // 相同定向只判断一次
+	for _, config := range configs {
+		target := config.TargetType + ":" + config.TargetIDs
+		hit, seen := matched[target]
+		if !seen {
+			var err error
+			hit, err = segment.Matches(db, repo.AppID(), userID, config.TargetType, segment.ParseIDs(config.TargetIDs))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to resolve config target"})
+				return
+			}
+			matched[target] = hit
+		}
+		if hit {
+			values[config.ConfigKey] = config.ConfigValue
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"configs": values, "env": repo.Env()}})
+}
+
+// loadConfig 按路径参数加载属于请求应用和环境的配置，失败时写入响应并返回false
+func loadConfig(c *gin.Context) (*repository.AppScoped, *model.Config, bool) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid config ID"})
+		return nil, nil, false
+	}
+
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return nil, nil, false
+	}
+	var config model.Config
+	if err := repo.First(&config, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Config not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to load config"})
+		}
+		return nil, nil, false
+	}
+	return repo, &config, true
+}
+
+// validateTarget 校验定向对象属于请求应用，失败时写入响应并返回false
+func validateTarget(c *gin.Context, repo *repository.AppScoped, targetType string, ids []uint) bool {
+	if err := segment.ValidateTarget(db, repo.AppID(), targetType, ids); err != nil {
+		if errors.Is(err, segment.ErrInvalidTarget) {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Config target not found in this app"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to validate config target"})
+		}
+		return false
+	}
+	return true
+}
+
+// scoped 在事务中沿用请求的应用和环境范围
+func scoped(tx *gorm.DB, repo *repository.AppScoped) *repository.AppScoped {
+	return repository.ForApp(tx, repo.AppID()).InEnv(repo.Env())
+}
+
+// recordHistory 记录配置变更，值取自更新后的记录
+func recordHistory(tx *gorm.DB, c *gin.Context, config *model.Config, operation string) error {
+	history := model.ConfigHistory{
+		ConfigID:    config.ID,
+		ConfigValue: config.ConfigValue,
+		Operation:   operation,
+	}
+	if uid := c.GetUint("user_id"); uid != 0 {
+		history.OperatorID = &uid
+	}
+	return tx.Create(&history).Error
 }
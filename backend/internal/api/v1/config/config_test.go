@@ -0,0 +1,224 @@
+package config
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/gin-gonic/gin"
+)
+
+// configStore 应用1的 dev、prod 两个环境，每个环境一条同名配置
+type configStore struct {
+	t       *testing.T
+	configs []model.Config
+}
+
+var configColumns = []string{"id", "app_id", "env", "config_key", "config_value", "target_type", "target_ids", "is_published"}
+
+func (s *configStore) handle(query string, args []driver.Value) sqltest.Reply {
+	switch {
+	case strings.Contains(query, "FROM `apps`"):
+		return sqltest.Reply{Columns: []string{"id", "org_id"}, Rows: [][]driver.Value{{int64(1), int64(1)}}}
+	case strings.Contains(query, "FROM `org_members`"):
+		return sqltest.Reply{Columns: []string{"id", "org_id", "role"}, Rows: [][]driver.Value{{int64(1), int64(1), model.OrgRoleAdmin}}}
+	case strings.Contains(query, "FROM `app_environments`"):
+		code := "prod"
+		if strings.Contains(query, "code = ?") {
+			code = fmt.Sprint(args[1])
+		}
+		if code != "dev" && code != "prod" {
+			return sqltest.Reply{}
+		}
+		return sqltest.Reply{Columns: []string{"id", "app_id", "code"}, Rows: [][]driver.Value{{int64(1), int64(1), code}}}
+	case strings.Contains(query, "FROM `user_tags`"):
+		// 测试中的应用没有标签，任何标签定向都不属于该应用
+		return sqltest.Reply{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(0)}}}
+	case strings.Contains(query, "FROM `configs`"):
+		return s.selectConfigs(query, args)
+	case strings.HasPrefix(query, "INSERT INTO `configs`"), strings.HasPrefix(query, "INSERT INTO `config_history`"):
+		return sqltest.Reply{Affected: 1, LastInsertID: 10}
+	case strings.HasPrefix(query, "UPDATE `configs`"):
+		return sqltest.Reply{Affected: 1}
+	}
+	s.t.Errorf("unexpected statement: %s", query)
+	return sqltest.Reply{}
+}
+
+// selectConfigs 按语句中的 app_id、env、主键和发布状态条件筛选，条件缺失时不筛选，从而暴露遗漏的环境条件
+func (s *configStore) selectConfigs(query string, args []driver.Value) sqltest.Reply {
+	env := ""
+	if strings.Contains(query, "`configs`.`env` = ?") {
+		env = fmt.Sprint(args[1])
+	}
+	id, key := "", ""
+	if strings.Contains(query, "`configs`.`id` = ?") {
+		id = fmt.Sprint(args[len(args)-1])
+	}
+	if strings.Contains(query, "config_key = ?") {
+		key = fmt.Sprint(args[len(args)-1])
+	}
+	published := strings.Contains(query, "is_published = ?")
+	var matched []model.Config
+	for _, c := range s.configs {
+		if (env == "" || c.Env == env) && (id == "" || fmt.Sprint(c.ID) == id) && (key == "" || c.ConfigKey == key) &&
+			(!published || c.IsPublished == 1) {
+			matched = append(matched, c)
+		}
+	}
+	if strings.HasPrefix(query, "SELECT count(*)") {
+		return sqltest.Reply{Columns: []string{"count"}, Rows: [][]driver.Value{{int64(len(matched))}}}
+	}
+	reply := sqltest.Reply{Columns: configColumns}
+	for _, c := range matched {
+		reply.Rows = append(reply.Rows, []driver.Value{int64(c.ID), int64(c.AppID), c.Env, c.ConfigKey, c.ConfigValue,
+			c.TargetType, c.TargetIDs, int64(c.IsPublished)})
+	}
+	return reply
+}
+
+func setupRouter(t *testing.T) (*gin.Engine, *sqltest.DB) {
+	gin.SetMode(gin.TestMode)
+	store := &configStore{t: t, configs: []model.Config{
+		{ID: 1, AppID: 1, Env: "dev", ConfigKey: "api_host", ConfigValue: "dev.example.com"},
+		{ID: 2, AppID: 1, Env: "prod", ConfigKey: "api_host", ConfigValue: "example.com"},
+	}}
+	db, fake := sqltest.Open(t, store.handle)
+	InitDB(db)
+	middleware.InitAppScope(db)
+
+	r := gin.New()
+	g := r.Group("/configs", middleware.AppScopeMiddleware())
+	g.GET("", List)
+	g.POST("", Create)
+	g.PUT("/:id", Update)
+	g.POST("/:id/publish", Publish)
+	return r, fake
+}
+
+func TestList_ReturnsOnlyRequestedEnv(t *testing.T) {
+	r, _ := setupRouter(t)
+
+	for env, want := range map[string]string{"dev": "dev.example.com", "": "example.com"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/configs?app_id=1&env="+env, nil))
+		body := w.Body.String()
+		if w.Code != http.StatusOK || !strings.Contains(body, want) || strings.Count(body, `"config_key"`) != 1 {
+			t.Errorf("env %q: status=%d body=%s, want only %s", env, w.Code, body, want)
+		}
+	}
+}
+
+func TestCreate_WritesRequestedEnv(t *testing.T) {
+	r, fake := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/configs?app_id=1", strings.NewReader(`{"config_key":"feature_x","config_value":"on"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.HeaderAppEnv, "dev")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	inserts := fake.Calls("INSERT INTO `configs`")
+	if len(inserts) != 1 || !strings.Contains(fmt.Sprint(inserts[0].Args), "dev") {
+		t.Errorf("insert = %+v, want env dev", inserts)
+	}
+	if len(fake.Calls("INSERT INTO `config_history`")) != 1 {
+		t.Error("create did not record history")
+	}
+
+	// 同名配置在同一环境中冲突
+	req = httptest.NewRequest(http.MethodPost, "/configs?app_id=1&env=dev", strings.NewReader(`{"config_key":"api_host"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("duplicate key: status = %d, want 409", w.Code)
+	}
+}
+
+func TestUpdate_RejectsConfigFromOtherEnv(t *testing.T) {
+	r, fake := setupRouter(t)
+
+	// 配置1属于 dev，在 prod 命名空间中不可见
+	for _, tt := range []struct{ method, path, body string }{
+		{http.MethodPut, "/configs/1?app_id=1&env=prod", `{"config_value":"x"}`},
+		{http.MethodPost, "/configs/1/publish?app_id=1&env=prod", ``},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status = %d, want 404", tt.method, tt.path, w.Code)
+		}
+	}
+	if n := len(fake.Calls("UPDATE `configs`")); n != 0 {
+		t.Errorf("executed %d updates on another env's config", n)
+	}
+}
+
+func TestList_UnknownEnv(t *testing.T) {
+	r, _ := setupRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/configs?app_id=1&env=qa", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+}
+
+func TestCreate_RejectsTargetFromOtherApp(t *testing.T) {
+	r, fake := setupRouter(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/configs?app_id=1&env=dev",
+		strings.NewReader(`{"config_key":"feature_x","target_type":"tag","target_ids":[9]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if n := len(fake.Calls("INSERT INTO `configs`")); n != 0 {
+		t.Errorf("inserted %d configs with a foreign target", n)
+	}
+}
+
+func TestClientList_ResolvesTargeting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := &configStore{t: t, configs: []model.Config{
+		{ID: 1, AppID: 1, Env: "prod", ConfigKey: "api_host", ConfigValue: "example.com", TargetType: "all", IsPublished: 1},
+		{ID: 2, AppID: 1, Env: "prod", ConfigKey: "beta_ui", ConfigValue: "on", TargetType: "user", TargetIDs: "5", IsPublished: 1},
+		{ID: 3, AppID: 1, Env: "prod", ConfigKey: "draft", ConfigValue: "x", TargetType: "all"},
+	}}
+	db, _ := sqltest.Open(t, store.handle)
+	InitDB(db)
+	middleware.InitAppScope(db)
+
+	for userID, want := range map[uint]string{
+		0: `{"api_host":"example.com"}`,
+		5: `{"api_host":"example.com","beta_ui":"on"}`,
+		6: `{"api_host":"example.com"}`,
+	} {
+		r := gin.New()
+		r.GET("/client/configs", middleware.AppScopeMiddleware(), func(c *gin.Context) {
+			if userID != 0 {
+				c.Set("app_user", &model.User{ID: userID})
+			}
+		}, ClientList)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/client/configs?app_id=1", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"configs":`+want) {
+			t.Errorf("user %d: status=%d body=%s, want configs %s", userID, w.Code, w.Body.String(), want)
+		}
+	}
+}
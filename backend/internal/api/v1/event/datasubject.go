@@ -0,0 +1,68 @@
+package event
+
+import (
+	"fmt"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userDataBatchSize 导出和匿名化事件时每批处理的记录数
+const userDataBatchSize = 1000
+
+// subjectEvents 数据主体的事件：属于该用户，或来自已识别为该用户的匿名设备且尚未归属其他用户
+func subjectEvents(database *gorm.DB, subject module.DataSubject) *gorm.DB {
+	query := database.Model(&model.Event{}).Where("app_id = ?", subject.AppID)
+	if len(subject.AnonymousIDs) == 0 {
+		return query.Where("user_id = ?", subject.UserID)
+	}
+	return query.Where("(user_id = ? OR (user_id IS NULL AND anonymous_id IN ?))", subject.UserID, subject.AnonymousIDs)
+}
+
+// ExportUserData 按ID分批导出数据主体的事件
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	var lastID uint64
+	for part := 1; ; part++ {
+		var events []model.Event
+		if err := subjectEvents(database, subject).Where("id > ?", lastID).
+			Order("id").Limit(userDataBatchSize).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) > 0 || part == 1 {
+			if err := w.WriteRecords(fmt.Sprintf("events/part-%04d", part), events); err != nil {
+				return err
+			}
+		}
+		if len(events) < userDataBatchSize {
+			return nil
+		}
+		lastID = events[len(events)-1].ID
+	}
+}
+
+// EraseUserData 匿名化数据主体的事件：去掉用户、设备、IP、UA和事件属性，保留事件本身以免影响汇总统计
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	var total int64
+	for {
+		var ids []uint64
+		if err := subjectEvents(database, subject).Order("id").Limit(userDataBatchSize).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return map[string]int64{"events": total}, nil
+		}
+		result := database.Model(&model.Event{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"user_id":      nil,
+			"anonymous_id": "",
+			"ip":           "",
+			"user_agent":   "",
+			"properties":   "{}",
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		total += result.RowsAffected
+	}
+}
@@ -1,8 +1,16 @@
 package event
 
 import (
+	"app-platform-backend/internal/analytics"
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/profile"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/userban"
+	"app-platform-backend/internal/validator"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -20,17 +28,23 @@ func InitDB(database *gorm.DB) {
 // Report 上报事件
 func Report(c *gin.Context) {
 	var req struct {
-		AppID      uint                   `json:"app_id" binding:"required"`
-		UserID     *uint                  `json:"user_id"`
-		EventCode  string                 `json:"event_code" binding:"required"`
-		EventName  string                 `json:"event_name"`
-		Properties map[string]interface{} `json:"properties"`
+		UserID      *uint                  `json:"user_id"`
+		AnonymousID string                 `json:"anonymous_id"`
+		EventCode   string                 `json:"event_code" binding:"required"`
+		EventName   string                 `json:"event_name"`
+		Properties  map[string]interface{} `json:"properties"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
 		return
 	}
+	if req.AnonymousID != "" {
+		if err := profile.ValidateAnonymousID(req.AnonymousID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid anonymous_id"})
+			return
+		}
+	}
 
 	propertiesJSON := "{}"
 	if req.Properties != nil {
@@ -39,20 +53,47 @@ func Report(c *gin.Context) {
 		}
 	}
 
+	// 携带终端用户令牌时以令牌中的用户为准
+	if uid, ok := middleware.AppUserID(c); ok {
+		req.UserID = &uid
+	}
+
 	event := model.Event{
-		AppID:      req.AppID,
-		UserID:     req.UserID,
-		EventCode:  req.EventCode,
-		EventName:  req.EventName,
-		Properties: propertiesJSON,
-		IP:         c.ClientIP(),
-		UserAgent:  c.GetHeader("User-Agent"),
+		UserID:      req.UserID,
+		AnonymousID: req.AnonymousID,
+		EventCode:   req.EventCode,
+		EventName:   req.EventName,
+		Properties:  propertiesJSON,
+		IP:          c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
 	}
 
-	if err := db.Create(&event).Error; err != nil {
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	if err := attributeAnonymousEvents(repo.AppID(), &event); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to report event"})
 		return
 	}
+	// 封禁用户的事件直接丢弃，不计入配额，返回成功以免客户端重试
+	if isBannedEvent(repo.AppID(), &event) {
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "Event dropped",
+			"data": gin.H{
+				"dropped": 1,
+			},
+		})
+		return
+	}
+	// 写入成功后才计入配额，超出配额时写入一并回滚
+	if err := quota.ConsumeWith(repo.AppID(), quota.MetricEventsPerDay, 1, func(tx *gorm.DB) error {
+		return repo.WithTx(tx).Create(&event)
+	}); err != nil {
+		quota.Reject(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -63,12 +104,12 @@ func Report(c *gin.Context) {
 // BatchReport 批量上报事件
 func BatchReport(c *gin.Context) {
 	var req struct {
-		AppID  uint `json:"app_id" binding:"required"`
 		Events []struct {
-			UserID     *uint                  `json:"user_id"`
-			EventCode  string                 `json:"event_code"`
-			EventName  string                 `json:"event_name"`
-			Properties map[string]interface{} `json:"properties"`
+			UserID      *uint                  `json:"user_id"`
+			AnonymousID string                 `json:"anonymous_id"`
+			EventCode   string                 `json:"event_code"`
+			EventName   string                 `json:"event_name"`
+			Properties  map[string]interface{} `json:"properties"`
 		} `json:"events" binding:"required"`
 	}
 
@@ -81,7 +122,17 @@ func BatchReport(c *gin.Context) {
 	clientIP := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
 
+	verifiedUserID, verified := middleware.AppUserID(c)
 	for _, e := range req.Events {
+		if verified {
+			e.UserID = &verifiedUserID
+		}
+		if e.AnonymousID != "" {
+			if err := profile.ValidateAnonymousID(e.AnonymousID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid anonymous_id"})
+				return
+			}
+		}
 		propertiesJSON := "{}"
 		if e.Properties != nil {
 			if data, err := json.Marshal(e.Properties); err == nil {
@@ -90,33 +141,59 @@ func BatchReport(c *gin.Context) {
 		}
 
 		events = append(events, model.Event{
-			AppID:      req.AppID,
-			UserID:     e.UserID,
-			EventCode:  e.EventCode,
-			EventName:  e.EventName,
-			Properties: propertiesJSON,
-			IP:         clientIP,
-			UserAgent:  userAgent,
+			UserID:      e.UserID,
+			AnonymousID: e.AnonymousID,
+			EventCode:   e.EventCode,
+			EventName:   e.EventName,
+			Properties:  propertiesJSON,
+			IP:          clientIP,
+			UserAgent:   userAgent,
 		})
 	}
 
-	if err := db.Create(&events).Error; err != nil {
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	pending := make([]*model.Event, len(events))
+	for i := range events {
+		pending[i] = &events[i]
+	}
+	if err := attributeAnonymousEvents(repo.AppID(), pending...); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to report events"})
 		return
 	}
+	// 丢弃封禁用户的事件
+	accepted := events[:0]
+	for i := range events {
+		if !isBannedEvent(repo.AppID(), &events[i]) {
+			accepted = append(accepted, events[i])
+		}
+	}
+	dropped := len(events) - len(accepted)
+	events = accepted
+
+	if len(events) > 0 {
+		if err := quota.ConsumeWith(repo.AppID(), quota.MetricEventsPerDay, int64(len(events)), func(tx *gorm.DB) error {
+			return repo.WithTx(tx).Create(&events)
+		}); err != nil {
+			quota.Reject(c, err)
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "Events reported successfully",
 		"data": gin.H{
-			"count": len(events),
+			"count":   len(events),
+			"dropped": dropped,
 		},
 	})
 }
 
 // List 事件列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	eventCode := c.Query("event_code")
 	userID := c.Query("user_id")
 	startTime := c.Query("start_time")
@@ -124,12 +201,11 @@ func List(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
-
-	query := db.Model(&model.Event{}).Where("app_id = ?", appID)
+	query := repo.Model(&model.Event{})
 
 	if eventCode != "" {
 		query = query.Where("event_code = ?", eventCode)
@@ -137,6 +213,9 @@ func List(c *gin.Context) {
 	if userID != "" {
 		query = query.Where("user_id = ?", userID)
 	}
+	if anonymousID := c.Query("anonymous_id"); anonymousID != "" {
+		query = query.Where("anonymous_id = ?", anonymousID)
+	}
 	if startTime != "" {
 		query = query.Where("created_at >= ?", startTime)
 	}
@@ -164,27 +243,25 @@ func List(c *gin.Context) {
 
 // Stats 事件统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
 
 	var total, todayCount, uniqueUsers int64
-	db.Model(&model.Event{}).Where("app_id = ?", appID).Count(&total)
+	repo.Model(&model.Event{}).Count(&total)
 
 	today := time.Now().Format("2006-01-02")
-	db.Model(&model.Event{}).Where("app_id = ? AND DATE(created_at) = ?", appID, today).Count(&todayCount)
-	db.Model(&model.Event{}).Where("app_id = ?", appID).Distinct("user_id").Count(&uniqueUsers)
+	repo.Model(&model.Event{}).Where("DATE(created_at) = ?", today).Count(&todayCount)
+	repo.Model(&model.Event{}).Distinct("user_id").Count(&uniqueUsers)
 
 	// 获取事件类型统计
 	var eventStats []struct {
 		EventCode string `json:"event_code"`
 		Count     int64  `json:"count"`
 	}
-	db.Model(&model.Event{}).
+	repo.Model(&model.Event{}).
 		Select("event_code, COUNT(*) as count").
-		Where("app_id = ?", appID).
 		Group("event_code").
 		Order("count DESC").
 		Limit(10).
@@ -195,9 +272,9 @@ func Stats(c *gin.Context) {
 		Date  string `json:"date"`
 		Count int64  `json:"count"`
 	}
-	db.Model(&model.Event{}).
+	repo.Model(&model.Event{}).
 		Select("DATE(created_at) as date, COUNT(*) as count").
-		Where("app_id = ? AND created_at >= ?", appID, time.Now().AddDate(0, 0, -7)).
+		Where("created_at >= ?", time.Now().AddDate(0, 0, -7)).
 		Group("DATE(created_at)").
 		Order("date ASC").
 		Scan(&trends)
@@ -214,17 +291,9 @@ func Stats(c *gin.Context) {
 	})
 }
 
-// Funnel 漏斗分析
+// Funnel 漏斗分析，按用户（或尚未识别的匿名设备）统计依次完成各步骤的人数
 func Funnel(c *gin.Context) {
-	appID := c.Query("app_id")
 	steps := c.QueryArray("steps")
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
-
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
-		return
-	}
 
 	if len(steps) == 0 {
 		// 返回默认漏斗示例
@@ -242,53 +311,144 @@ func Funnel(c *gin.Context) {
 		return
 	}
 
-	var funnelData []gin.H
-	var prevCount int64 = 0
-
-	for i, step := range steps {
-		query := db.Model(&model.Event{}).Where("app_id = ? AND event_code = ?", appID, step)
-		if startTime != "" {
-			query = query.Where("created_at >= ?", startTime)
-		}
-		if endTime != "" {
-			query = query.Where("created_at <= ?", endTime)
-		}
-
-		var count int64
-		query.Count(&count)
+	scope, ok := analysisScope(c, 30)
+	if !ok {
+		return
+	}
+	funnelData, err := analytics.Funnel(db, scope, steps)
+	if err != nil {
+		analysisError(c, err)
+		return
+	}
 
-		rate := float64(100)
-		if i > 0 && prevCount > 0 {
-			rate = float64(count) / float64(prevCount) * 100
-		}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": gin.H{
+			"steps": funnelData,
+		},
+	})
+}
 
-		funnelData = append(funnelData, gin.H{
-			"name":  step,
-			"count": count,
-			"rate":  rate,
-		})
+// Retention 留存分析，按起始事件的首次发生日期分批，统计之后每天发生回访事件的用户数
+func Retention(c *gin.Context) {
+	startEvent := c.Query("start_event")
+	returnEvent := c.DefaultQuery("return_event", startEvent)
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "7"))
 
-		prevCount = count
+	scope, ok := analysisScope(c, 7)
+	if !ok {
+		return
+	}
+	cohorts, err := analytics.Retention(db, scope, startEvent, returnEvent, days)
+	if err != nil {
+		analysisError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"data": gin.H{
-			"steps": funnelData,
+			"start_event":  startEvent,
+			"return_event": returnEvent,
+			"days":         days,
+			"cohorts":      cohorts,
 		},
 	})
 }
 
+// maxAnalysisDays 漏斗和留存单次可查询的最大天数
+const maxAnalysisDays = 90
+
+// analysisScope 解析分析的应用、环境和时间区间，未指定时间时统计最近 defaultDays 天
+// 只有日期的 end_time 包含当天
+func analysisScope(c *gin.Context, defaultDays int) (analytics.Scope, bool) {
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return analytics.Scope{}, false
+	}
+
+	now := time.Now()
+	end := now
+	if v := c.Query("end_time"); v != "" {
+		t, dateOnly, err := parseAnalysisTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid end_time"})
+			return analytics.Scope{}, false
+		}
+		end = t
+		if dateOnly {
+			end = t.AddDate(0, 0, 1)
+		}
+	}
+	start := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, end.Location()).AddDate(0, 0, -defaultDays+1)
+	if v := c.Query("start_time"); v != "" {
+		t, _, err := parseAnalysisTime(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid start_time"})
+			return analytics.Scope{}, false
+		}
+		start = t
+	}
+	if !start.Before(end) || end.Sub(start) > maxAnalysisDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Time range must be positive and at most 90 days"})
+		return analytics.Scope{}, false
+	}
+
+	return analytics.Scope{AppID: repo.AppID(), Env: repo.Env(), Start: start, End: end}, true
+}
+
+// parseAnalysisTime 支持 2006-01-02 15:04:05 和 2006-01-02 两种格式，按本地时区解析
+func parseAnalysisTime(v string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", v, time.Local); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	return t, true, err
+}
+
+func analysisError(c *gin.Context, err error) {
+	if errors.Is(err, analytics.ErrInvalidQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to analyze events"})
+}
+
+// attributeAnonymousEvents 没有用户的事件若其匿名设备已识别，归属到对应用户
+func attributeAnonymousEvents(appID uint, events ...*model.Event) error {
+	var anonymousIDs []string
+	for _, e := range events {
+		if e.UserID == nil && e.AnonymousID != "" {
+			anonymousIDs = append(anonymousIDs, e.AnonymousID)
+		}
+	}
+	if len(anonymousIDs) == 0 {
+		return nil
+	}
+	identified, err := profile.ResolveAnonymous(db, appID, anonymousIDs)
+	if err != nil {
+		return err
+	}
+	for _, e := range events {
+		if uid, ok := identified[e.AnonymousID]; ok && e.UserID == nil {
+			uid := uid
+			e.UserID = &uid
+		}
+	}
+	return nil
+}
+
+// isBannedEvent 事件归属的用户是否处于封禁中
+func isBannedEvent(appID uint, e *model.Event) bool {
+	return e.UserID != nil && userban.IsBanned(appID, *e.UserID)
+}
+
 // Definitions 事件定义列表
 func Definitions(c *gin.Context) {
-	appID := c.Query("app_id")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
 
-	query := db.Model(&model.EventDefinition{})
-	if appID != "" {
-		query = query.Where("app_id = ?", appID)
-	}
+	query := repository.FromContext(c, db).Model(&model.EventDefinition{})
 
 	var total int64
 	query.Count(&total)
@@ -311,7 +471,6 @@ func Definitions(c *gin.Context) {
 // CreateDefinition 创建事件定义
 func CreateDefinition(c *gin.Context) {
 	var req struct {
-		AppID            uint   `json:"app_id" binding:"required"`
 		EventCode        string `json:"event_code" binding:"required"`
 		EventName        string `json:"event_name" binding:"required"`
 		Description      string `json:"description"`
@@ -324,7 +483,6 @@ func CreateDefinition(c *gin.Context) {
 	}
 
 	definition := model.EventDefinition{
-		AppID:            req.AppID,
 		EventCode:        req.EventCode,
 		EventName:        req.EventName,
 		Description:      req.Description,
@@ -332,7 +490,7 @@ func CreateDefinition(c *gin.Context) {
 		IsActive:         1,
 	}
 
-	if err := db.Create(&definition).Error; err != nil {
+	if err := repository.FromContext(c, db).Create(&definition); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to create event definition"})
 		return
 	}
@@ -346,10 +504,15 @@ func CreateDefinition(c *gin.Context) {
 
 // UpdateDefinition 更新事件定义
 func UpdateDefinition(c *gin.Context) {
-	id := c.Param("id")
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid event definition ID"})
+		return
+	}
 
+	repo := repository.FromContext(c, db)
 	var definition model.EventDefinition
-	if err := db.First(&definition, id).Error; err != nil {
+	if err := repo.First(&definition, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Event definition not found"})
 			return
@@ -384,7 +547,10 @@ func UpdateDefinition(c *gin.Context) {
 		updates["is_active"] = *req.IsActive
 	}
 
-	db.Model(&definition).Updates(updates)
+	if err := repo.Updates(&definition, updates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to update event definition"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -394,10 +560,15 @@ func UpdateDefinition(c *gin.Context) {
 
 // DeleteDefinition 删除事件定义
 func DeleteDefinition(c *gin.Context) {
-	id := c.Param("id")
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid event definition ID"})
+		return
+	}
 
+	repo := repository.FromContext(c, db)
 	var definition model.EventDefinition
-	if err := db.First(&definition, id).Error; err != nil {
+	if err := repo.First(&definition, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Event definition not found"})
 			return
@@ -406,7 +577,10 @@ func DeleteDefinition(c *gin.Context) {
 		return
 	}
 
-	db.Delete(&definition)
+	if _, err := repo.Delete(&definition); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to delete event definition"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -0,0 +1,26 @@
+package event
+
+import (
+	"net/http"
+	"testing"
+
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlers_RejectNonNumericID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, fake := sqltest.Open(t, sqltest.NoRows)
+	InitDB(db)
+
+	r := gin.New()
+	r.PUT("/events/definitions/:id", UpdateDefinition)
+	r.DELETE("/events/definitions/:id", DeleteDefinition)
+
+	sqltest.RejectsWithoutQuery(t, r, fake, []sqltest.Request{
+		{Method: http.MethodPut, Path: "/events/definitions/1%20OR%201=1"},
+		{Method: http.MethodDelete, Path: "/events/definitions/abc"},
+		{Method: http.MethodDelete, Path: "/events/definitions/-1"},
+	})
+}
@@ -0,0 +1,70 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// subjectFiles 该用户上传的文件，包括已删除记录
+func subjectFiles(database *gorm.DB, subject module.DataSubject) ([]model.File, error) {
+	var files []model.File
+	err := database.Unscoped().Where("app_id = ? AND upload_by = ?", subject.AppID, subject.UserID).
+		Order("id").Find(&files).Error
+	return files, err
+}
+
+// ExportUserData 导出该用户上传的文件记录和文件内容，物理文件已不存在的只导出记录
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	files, err := subjectFiles(database, subject)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteRecords("files", files); err != nil {
+		return err
+	}
+	for _, f := range files {
+		content, err := os.Open(f.FilePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = w.WriteFile(fmt.Sprintf("content/%d_%s", f.ID, filepath.Base(f.Filename)), content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EraseUserData 删除该用户上传的物理文件和文件记录
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	files, err := subjectFiles(database, subject)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return map[string]int64{"files": 0}, nil
+	}
+
+	ids := make([]uint, len(files))
+	for i, f := range files {
+		if err := os.Remove(f.FilePath); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		ids[i] = f.ID
+	}
+	result := database.Unscoped().Delete(&model.File{}, ids)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return map[string]int64{"files": result.RowsAffected}, nil
+}
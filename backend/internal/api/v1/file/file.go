@@ -1,7 +1,10 @@
 package file
 
 import (
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/repository"
 	"app-platform-backend/internal/response"
 	"app-platform-backend/internal/validator"
 	"crypto/md5"
@@ -9,7 +12,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -56,21 +58,33 @@ func InitDB(database *gorm.DB) {
 	db = database
 	// 确保上传目录存在
 	os.MkdirAll(uploadDir, 0755)
+
+	quota.RegisterGauge(quota.MetricStorageBytes, func(appID uint) (int64, error) {
+		size, _, err := storageUsage(repository.ForApp(db, appID))
+		return size, err
+	})
+	quota.RegisterGauge(quota.MetricFiles, func(appID uint) (int64, error) {
+		_, count, err := storageUsage(repository.ForApp(db, appID))
+		return count, err
+	})
 }
 
-// Upload 上传文件
-func Upload(c *gin.Context) {
-	appIDStr := c.PostForm("app_id")
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
+// storageUsage 应用已用存储空间和文件数量
+func storageUsage(repo *repository.AppScoped) (int64, int64, error) {
+	var usage struct {
+		Size  int64
+		Count int64
 	}
+	err := repo.Model(&model.File{}).
+		Select("COALESCE(SUM(file_size), 0) AS size, COUNT(*) AS count").
+		Scan(&usage).Error
+	return usage.Size, usage.Count, err
+}
 
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
+// Upload 上传文件
+func Upload(c *gin.Context) {
+	repo := repository.FromContext(c, db)
+	appID := repo.AppID()
 
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
@@ -85,6 +99,21 @@ func Upload(c *gin.Context) {
 		return
 	}
 
+	// 检查文件数量和存储空间配额
+	usedSize, usedCount, err := storageUsage(repo)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if err := quota.Check(appID, quota.MetricFiles, usedCount, 1); err != nil {
+		quota.Reject(c, err)
+		return
+	}
+	if err := quota.Check(appID, quota.MetricStorageBytes, usedSize, header.Size); err != nil {
+		quota.Reject(c, err)
+		return
+	}
+
 	// 获取MIME类型
 	mimeType := header.Header.Get("Content-Type")
 	if mimeType == "" {
@@ -138,14 +167,16 @@ func Upload(c *gin.Context) {
 
 	// 保存到数据库
 	fileRecord := model.File{
-		AppID:    uint(appID),
 		Filename: header.Filename,
 		FilePath: filePath,
 		FileSize: header.Size,
 		MimeType: mimeType,
 	}
+	if uid, ok := middleware.AppUserID(c); ok {
+		fileRecord.UploadBy = &uid
+	}
 
-	if err := db.Create(&fileRecord).Error; err != nil {
+	if err := repo.Create(&fileRecord); err != nil {
 		// 删除已上传的文件
 		os.Remove(filePath)
 		response.DBError(c, err)
@@ -153,7 +184,7 @@ func Upload(c *gin.Context) {
 	}
 
 	// 生成访问URL
-	fileURL := fmt.Sprintf("/api/v1/files/download/%d", fileRecord.ID)
+	fileURL := downloadURL(&fileRecord)
 
 	response.SuccessWithMessage(c, gin.H{
 		"id":        fileRecord.ID,
@@ -166,19 +197,13 @@ func Upload(c *gin.Context) {
 
 // List 文件列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	mimeType := c.Query("mime_type")
 	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-
 	// 验证分页参数
 	page, size = validator.ValidatePagination(page, size)
 
-	query := db.Model(&model.File{}).Where("app_id = ?", appID)
+	query := repository.FromContext(c, db).Model(&model.File{})
 
 	if mimeType != "" {
 		query = query.Where("mime_type LIKE ?", mimeType+"%")
@@ -205,7 +230,7 @@ func List(c *gin.Context) {
 			"filename":   f.Filename,
 			"file_size":  f.FileSize,
 			"mime_type":  f.MimeType,
-			"url":        fmt.Sprintf("/api/v1/files/download/%d", f.ID),
+			"url":        downloadURL(&f),
 			"created_at": f.CreatedAt,
 		})
 	}
@@ -216,7 +241,6 @@ func List(c *gin.Context) {
 // Detail 文件详情
 func Detail(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -224,20 +248,10 @@ func Detail(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var file model.File
-	// 同时验证id和app_id，防止越权访问
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&file).Error; err != nil {
+	// 只能查到当前应用的文件，防止越权访问
+	if err := repo.First(&file, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "文件不存在或无权限访问")
 			return
@@ -251,7 +265,7 @@ func Detail(c *gin.Context) {
 		"filename":   file.Filename,
 		"file_size":  file.FileSize,
 		"mime_type":  file.MimeType,
-		"url":        fmt.Sprintf("/api/v1/files/download/%d", file.ID),
+		"url":        downloadURL(&file),
 		"created_at": file.CreatedAt,
 	})
 }
@@ -259,7 +273,6 @@ func Detail(c *gin.Context) {
 // Download 下载文件
 func Download(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -267,20 +280,10 @@ func Download(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var file model.File
-	// 同时验证id和app_id，防止越权访问
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&file).Error; err != nil {
+	// 只能查到当前应用的文件，防止越权访问
+	if err := repo.First(&file, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "文件不存在或无权限访问")
 			return
@@ -303,7 +306,6 @@ func Download(c *gin.Context) {
 // Delete 删除文件
 func Delete(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -311,20 +313,10 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var file model.File
-	// 同时验证id和app_id，防止越权删除
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&file).Error; err != nil {
+	// 只能查到当前应用的文件，防止越权删除
+	if err := repo.First(&file, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "文件不存在或无权限删除")
 			return
@@ -337,7 +329,7 @@ func Delete(c *gin.Context) {
 	os.Remove(file.FilePath)
 
 	// 删除数据库记录
-	if err := db.Delete(&file).Error; err != nil {
+	if _, err := repo.Delete(&file); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -348,8 +340,7 @@ func Delete(c *gin.Context) {
 // BatchDelete 批量删除文件
 func BatchDelete(c *gin.Context) {
 	var req struct {
-		AppID uint   `json:"app_id" binding:"required"`
-		IDs   []uint `json:"ids" binding:"required"`
+		IDs []uint `json:"ids" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -368,8 +359,9 @@ func BatchDelete(c *gin.Context) {
 	}
 
 	// 只查询属于该APP的文件，防止越权删除
+	repo := repository.FromContext(c, db)
 	var files []model.File
-	if err := db.Where("id IN ? AND app_id = ?", req.IDs, req.AppID).Find(&files).Error; err != nil {
+	if err := repo.Find(&files, "id IN ?", req.IDs); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -389,34 +381,30 @@ func BatchDelete(c *gin.Context) {
 	for _, f := range files {
 		fileIDs = append(fileIDs, f.ID)
 	}
-	result := db.Delete(&model.File{}, fileIDs)
-	if result.Error != nil {
-		response.DBError(c, result.Error)
+	affected, err := repo.Delete(&model.File{}, fileIDs)
+	if err != nil {
+		response.DBError(c, err)
 		return
 	}
 
 	response.SuccessWithMessage(c, gin.H{
-		"affected": result.RowsAffected,
+		"affected": affected,
 	}, "文件批量删除成功")
 }
 
 // Stats 文件统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
+	repo := repository.FromContext(c, db)
 
 	var total int64
 	var totalSize int64
 	var todayCount int64
 
-	db.Model(&model.File{}).Where("app_id = ?", appID).Count(&total)
-	db.Model(&model.File{}).Where("app_id = ?", appID).Select("COALESCE(SUM(file_size), 0)").Scan(&totalSize)
+	repo.Model(&model.File{}).Count(&total)
+	repo.Model(&model.File{}).Select("COALESCE(SUM(file_size), 0)").Scan(&totalSize)
 
 	today := time.Now().Format("2006-01-02")
-	db.Model(&model.File{}).Where("app_id = ? AND DATE(created_at) = ?", appID, today).Count(&todayCount)
+	repo.Model(&model.File{}).Where("DATE(created_at) = ?", today).Count(&todayCount)
 
 	// 按类型统计
 	var typeStats []struct {
@@ -424,9 +412,8 @@ func Stats(c *gin.Context) {
 		Count    int64  `json:"count"`
 		Size     int64  `json:"size"`
 	}
-	db.Model(&model.File{}).
+	repo.Model(&model.File{}).
 		Select("SUBSTRING_INDEX(mime_type, '/', 1) as mime_type, COUNT(*) as count, SUM(file_size) as size").
-		Where("app_id = ?", appID).
 		Group("SUBSTRING_INDEX(mime_type, '/', 1)").
 		Scan(&typeStats)
 
@@ -437,3 +424,31 @@ func Stats(c *gin.Context) {
 		"type_stats":  typeStats,
 	})
 }
+
+// downloadURL 文件下载地址，携带 app_id 以通过应用范围校验
+func downloadURL(f *model.File) string {
+	return fmt.Sprintf("/api/v1/files/download/%d?app_id=%d", f.ID, f.AppID)
+}
+
+// PurgeAppData 清理已删除应用的一批文件：先删物理文件再删记录，全部清理后移除应用上传目录
+func PurgeAppData(database *gorm.DB, appID uint, batchSize int) (int64, error) {
+	repo := repository.ForApp(database, appID)
+
+	var files []model.File
+	if err := database.Unscoped().Scopes(repo.Scope).Order("id").Limit(batchSize).Find(&files).Error; err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, os.RemoveAll(filepath.Join(uploadDir, fmt.Sprintf("%d", appID)))
+	}
+
+	ids := make([]uint, len(files))
+	for i, f := range files {
+		if err := os.Remove(f.FilePath); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		ids[i] = f.ID
+	}
+	result := database.Unscoped().Delete(&model.File{}, ids)
+	return result.RowsAffected, result.Error
+}
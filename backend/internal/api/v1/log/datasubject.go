@@ -0,0 +1,66 @@
+package log
+
+import (
+	"fmt"
+	"strconv"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// userDataBatchSize 导出和匿名化日志时每批处理的记录数
+const userDataBatchSize = 1000
+
+// subjectLogs 上下文中 user_id 为该用户ID或 open_id 的日志，context_user_id 是按上下文生成的虚拟列
+func subjectLogs(database *gorm.DB, subject module.DataSubject) *gorm.DB {
+	keys := []string{strconv.FormatUint(uint64(subject.UserID), 10)}
+	if subject.OpenID != "" {
+		keys = append(keys, subject.OpenID)
+	}
+	return database.Model(&model.Log{}).Where("app_id = ? AND context_user_id IN ?", subject.AppID, keys)
+}
+
+// ExportUserData 按ID分批导出上下文中包含该用户的日志
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	var lastID uint64
+	for part := 1; ; part++ {
+		var logs []model.Log
+		if err := subjectLogs(database, subject).Where("id > ?", lastID).
+			Order("id").Limit(userDataBatchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) > 0 || part == 1 {
+			if err := w.WriteRecords(fmt.Sprintf("logs/part-%04d", part), logs); err != nil {
+				return err
+			}
+		}
+		if len(logs) < userDataBatchSize {
+			return nil
+		}
+		lastID = logs[len(logs)-1].ID
+	}
+}
+
+// EraseUserData 匿名化日志：从上下文中移除 user_id 并清空IP，日志内容保留用于排障
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	var total int64
+	for {
+		var ids []uint64
+		if err := subjectLogs(database, subject).Order("id").Limit(userDataBatchSize).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return map[string]int64{"logs": total}, nil
+		}
+		result := database.Model(&model.Log{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"context": gorm.Expr("JSON_REMOVE(context, '$.user_id')"),
+			"ip":      "",
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		total += result.RowsAffected
+	}
+}
@@ -2,6 +2,10 @@ package log
 
 import (
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/userban"
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,7 +22,6 @@ func InitDB(database *gorm.DB) {
 
 // List 日志列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	level := c.Query("level")
 	module := c.Query("module")
 	keyword := c.Query("keyword")
@@ -27,12 +30,11 @@ func List(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
-
-	query := db.Model(&model.Log{}).Where("app_id = ?", appID)
+	query := repo.Model(&model.Log{})
 
 	if level != "" {
 		query = query.Where("level = ?", level)
@@ -49,6 +51,9 @@ func List(c *gin.Context) {
 	if endTime != "" {
 		query = query.Where("created_at <= ?", endTime)
 	}
+	if flagged, err := strconv.ParseBool(c.Query("flagged")); err == nil {
+		query = query.Where("flagged = ?", flagged)
+	}
 
 	var total int64
 	query.Count(&total)
@@ -71,7 +76,6 @@ func List(c *gin.Context) {
 // Report 上报日志
 func Report(c *gin.Context) {
 	var req struct {
-		AppID   uint   `json:"app_id" binding:"required"`
 		Level   string `json:"level"`
 		Module  string `json:"module"`
 		Message string `json:"message" binding:"required"`
@@ -88,7 +92,6 @@ func Report(c *gin.Context) {
 	}
 
 	log := model.Log{
-		AppID:   req.AppID,
 		Level:   req.Level,
 		Module:  req.Module,
 		Message: req.Message,
@@ -96,8 +99,17 @@ func Report(c *gin.Context) {
 		IP:      c.ClientIP(),
 	}
 
-	if err := db.Create(&log).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to report log"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	log.Flagged = isBannedContext(repo.AppID(), log.Context)
+
+	// 写入成功后才计入配额，超出配额时写入一并回滚
+	if err := quota.ConsumeWith(repo.AppID(), quota.MetricLogsPerDay, 1, func(tx *gorm.DB) error {
+		return repo.WithTx(tx).Create(&log)
+	}); err != nil {
+		quota.Reject(c, err)
 		return
 	}
 
@@ -110,8 +122,7 @@ func Report(c *gin.Context) {
 // BatchReport 批量上报日志
 func BatchReport(c *gin.Context) {
 	var req struct {
-		AppID uint `json:"app_id" binding:"required"`
-		Logs  []struct {
+		Logs []struct {
 			Level   string `json:"level"`
 			Module  string `json:"module"`
 			Message string `json:"message"`
@@ -132,7 +143,6 @@ func BatchReport(c *gin.Context) {
 			level = "info"
 		}
 		logs = append(logs, model.Log{
-			AppID:   req.AppID,
 			Level:   level,
 			Module:  l.Module,
 			Message: l.Message,
@@ -141,8 +151,18 @@ func BatchReport(c *gin.Context) {
 		})
 	}
 
-	if err := db.Create(&logs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to report logs"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	for i := range logs {
+		logs[i].Flagged = isBannedContext(repo.AppID(), logs[i].Context)
+	}
+
+	if err := quota.ConsumeWith(repo.AppID(), quota.MetricLogsPerDay, int64(len(logs)), func(tx *gorm.DB) error {
+		return repo.WithTx(tx).Create(&logs)
+	}); err != nil {
+		quota.Reject(c, err)
 		return
 	}
 
@@ -157,30 +177,29 @@ func BatchReport(c *gin.Context) {
 
 // Stats 日志统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
 
 	var total, errorCount, warnCount, infoCount, debugCount, todayCount int64
-	db.Model(&model.Log{}).Where("app_id = ?", appID).Count(&total)
-	db.Model(&model.Log{}).Where("app_id = ? AND level = ?", appID, "error").Count(&errorCount)
-	db.Model(&model.Log{}).Where("app_id = ? AND level = ?", appID, "warn").Count(&warnCount)
-	db.Model(&model.Log{}).Where("app_id = ? AND level = ?", appID, "info").Count(&infoCount)
-	db.Model(&model.Log{}).Where("app_id = ? AND level = ?", appID, "debug").Count(&debugCount)
+	repo.Model(&model.Log{}).Count(&total)
+	repo.Model(&model.Log{}).Where("level = ?", "error").Count(&errorCount)
+	repo.Model(&model.Log{}).Where("level = ?", "warn").Count(&warnCount)
+	repo.Model(&model.Log{}).Where("level = ?", "info").Count(&infoCount)
+	repo.Model(&model.Log{}).Where("level = ?", "debug").Count(&debugCount)
 
 	today := time.Now().Format("2006-01-02")
-	db.Model(&model.Log{}).Where("app_id = ? AND DATE(created_at) = ?", appID, today).Count(&todayCount)
+	repo.Model(&model.Log{}).Where("DATE(created_at) = ?", today).Count(&todayCount)
 
 	// 获取最近7天的日志趋势
 	var trends []struct {
 		Date  string `json:"date"`
 		Count int64  `json:"count"`
 	}
-	db.Model(&model.Log{}).
+	repo.Model(&model.Log{}).
 		Select("DATE(created_at) as date, COUNT(*) as count").
-		Where("app_id = ? AND created_at >= ?", appID, time.Now().AddDate(0, 0, -7)).
+		Where("created_at >= ?", time.Now().AddDate(0, 0, -7)).
 		Group("DATE(created_at)").
 		Order("date ASC").
 		Scan(&trends)
@@ -201,17 +220,15 @@ func Stats(c *gin.Context) {
 
 // Export 导出日志
 func Export(c *gin.Context) {
-	appID := c.Query("app_id")
 	level := c.Query("level")
 	startTime := c.Query("start_time")
 	endTime := c.Query("end_time")
 
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
-
-	query := db.Model(&model.Log{}).Where("app_id = ?", appID)
+	query := repo.Model(&model.Log{})
 
 	if level != "" {
 		query = query.Where("level = ?", level)
@@ -238,7 +255,6 @@ func Export(c *gin.Context) {
 // Clean 清理日志
 func Clean(c *gin.Context) {
 	var req struct {
-		AppID      uint   `json:"app_id" binding:"required"`
 		BeforeDate string `json:"before_date"`
 		Level      string `json:"level"`
 	}
@@ -248,7 +264,11 @@ func Clean(c *gin.Context) {
 		return
 	}
 
-	query := db.Where("app_id = ?", req.AppID)
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	query := repo.Model(&model.Log{})
 
 	if req.BeforeDate != "" {
 		query = query.Where("created_at < ?", req.BeforeDate)
@@ -281,3 +301,23 @@ func System(c *gin.Context) {
 func Operation(c *gin.Context) {
 	List(c)
 }
+
+// isBannedContext 日志上下文中的 user_id（用户ID或 open_id）是否处于封禁中，封禁用户的日志保留但标记，便于排查
+func isBannedContext(appID uint, context string) bool {
+	if context == "" {
+		return false
+	}
+	var ctx struct {
+		UserID interface{} `json:"user_id"`
+	}
+	if err := json.Unmarshal([]byte(context), &ctx); err != nil {
+		return false
+	}
+	switch v := ctx.UserID.(type) {
+	case string:
+		return userban.IsBannedRef(appID, v)
+	case float64:
+		return userban.IsBannedRef(appID, strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return false
+}
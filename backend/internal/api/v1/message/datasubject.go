@@ -0,0 +1,27 @@
+package message
+
+import (
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ExportUserData 导出发给该用户的消息，包括已删除的
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	var messages []model.Message
+	if err := database.Unscoped().Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).
+		Order("id").Find(&messages).Error; err != nil {
+		return err
+	}
+	return w.WriteRecords("messages", messages)
+}
+
+// EraseUserData 物理删除发给该用户的消息
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	result := database.Unscoped().Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).Delete(&model.Message{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return map[string]int64{"messages": result.RowsAffected}, nil
+}
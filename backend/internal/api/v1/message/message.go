@@ -2,6 +2,10 @@ package message
 
 import (
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/segment"
+	"app-platform-backend/internal/userban"
+	"app-platform-backend/internal/validator"
 	"net/http"
 	"strconv"
 	"time"
@@ -18,16 +22,10 @@ func InitDB(database *gorm.DB) {
 
 // List 消息列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
-		return
-	}
-
-	query := db.Model(&model.Message{}).Where("app_id = ?", appID)
+	query := repository.FromContext(c, db).Model(&model.Message{})
 
 	var total int64
 	query.Count(&total)
@@ -50,7 +48,6 @@ func List(c *gin.Context) {
 // Send 发送消息
 func Send(c *gin.Context) {
 	var req struct {
-		AppID   uint   `json:"app_id" binding:"required"`
 		UserID  *uint  `json:"user_id"`
 		Title   string `json:"title" binding:"required"`
 		Content string `json:"content" binding:"required"`
@@ -66,8 +63,13 @@ func Send(c *gin.Context) {
 		req.Type = "system"
 	}
 
+	repo := repository.FromContext(c, db)
+	if req.UserID != nil && userban.IsBanned(repo.AppID(), *req.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "User is disabled"})
+		return
+	}
+
 	message := model.Message{
-		AppID:   req.AppID,
 		UserID:  req.UserID,
 		Title:   req.Title,
 		Content: req.Content,
@@ -75,7 +77,7 @@ func Send(c *gin.Context) {
 		Status:  0,
 	}
 
-	if err := db.Create(&message).Error; err != nil {
+	if err := repo.Create(&message); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to send message"})
 		return
 	}
@@ -92,31 +94,24 @@ func Templates(c *gin.Context) {
 }
 
 func UnreadCount(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
-		return
-	}
-
 	var count int64
-	db.Model(&model.Message{}).Where("app_id = ? AND status = 0", appID).Count(&count)
+	repository.FromContext(c, db).Model(&model.Message{}).Where("status = 0").Count(&count)
 
 	c.JSON(http.StatusOK, gin.H{"code": 0, "data": gin.H{"count": count}})
 }
 
 // Detail 消息详情
 func Detail(c *gin.Context) {
-	id := c.Param("id")
-	appID := c.Query("app_id")
-
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid message ID"})
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var message model.Message
-	// 同时验证id和app_id，防止越权访问
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&message).Error; err != nil {
+	// 只能查到当前应用的消息，防止越权访问
+	if err := repo.First(&message, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Message not found or no permission"})
 			return
@@ -133,18 +128,14 @@ func Detail(c *gin.Context) {
 
 // Stats 消息统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
-		return
-	}
+	repo := repository.FromContext(c, db)
 
 	var total, unread, todayCount int64
-	db.Model(&model.Message{}).Where("app_id = ?", appID).Count(&total)
-	db.Model(&model.Message{}).Where("app_id = ? AND status = 0", appID).Count(&unread)
+	repo.Model(&model.Message{}).Count(&total)
+	repo.Model(&model.Message{}).Where("status = 0").Count(&unread)
 
 	today := time.Now().Format("2006-01-02")
-	db.Model(&model.Message{}).Where("app_id = ? AND DATE(created_at) = ?", appID, today).Count(&todayCount)
+	repo.Model(&model.Message{}).Where("DATE(created_at) = ?", today).Count(&todayCount)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -159,17 +150,16 @@ func Stats(c *gin.Context) {
 
 // MarkRead 标记消息已读
 func MarkRead(c *gin.Context) {
-	id := c.Param("id")
-	appID := c.Query("app_id")
-
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid message ID"})
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var message model.Message
-	// 同时验证id和app_id，防止越权操作
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&message).Error; err != nil {
+	// 只能查到当前应用的消息，防止越权操作
+	if err := repo.First(&message, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Message not found or no permission"})
 			return
@@ -178,7 +168,10 @@ func MarkRead(c *gin.Context) {
 		return
 	}
 
-	db.Model(&message).Update("status", 1)
+	if err := repo.Updates(&message, map[string]interface{}{"status": 1}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to update message"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -189,7 +182,6 @@ func MarkRead(c *gin.Context) {
 // MarkAllRead 标记所有消息已读
 func MarkAllRead(c *gin.Context) {
 	var req struct {
-		AppID  uint  `json:"app_id" binding:"required"`
 		UserID *uint `json:"user_id"`
 	}
 
@@ -198,7 +190,7 @@ func MarkAllRead(c *gin.Context) {
 		return
 	}
 
-	query := db.Model(&model.Message{}).Where("app_id = ? AND status = 0", req.AppID)
+	query := repository.FromContext(c, db).Model(&model.Message{}).Where("status = 0")
 	if req.UserID != nil {
 		query = query.Where("user_id = ?", *req.UserID)
 	}
@@ -216,17 +208,16 @@ func MarkAllRead(c *gin.Context) {
 
 // Delete 删除消息
 func Delete(c *gin.Context) {
-	id := c.Param("id")
-	appID := c.Query("app_id")
-
-	if appID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "app_id is required"})
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid message ID"})
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var message model.Message
-	// 同时验证id和app_id，防止越权删除
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&message).Error; err != nil {
+	// 只能查到当前应用的消息，防止越权删除
+	if err := repo.First(&message, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "Message not found or no permission"})
 			return
@@ -235,7 +226,10 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	db.Delete(&message)
+	if _, err := repo.Delete(&message); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to delete message"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -246,8 +240,7 @@ func Delete(c *gin.Context) {
 // BatchDelete 批量删除消息
 func BatchDelete(c *gin.Context) {
 	var req struct {
-		AppID uint   `json:"app_id" binding:"required"`
-		IDs   []uint `json:"ids" binding:"required"`
+		IDs []uint `json:"ids" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -256,13 +249,17 @@ func BatchDelete(c *gin.Context) {
 	}
 
 	// 只删除属于该APP的消息，防止越权删除
-	result := db.Where("id IN ? AND app_id = ?", req.IDs, req.AppID).Delete(&model.Message{})
+	affected, err := repository.FromContext(c, db).Delete(&model.Message{}, "id IN ?", req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to delete messages"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "Messages deleted successfully",
 		"data": gin.H{
-			"affected": result.RowsAffected,
+			"affected": affected,
 		},
 	})
 }
@@ -270,11 +267,12 @@ func BatchDelete(c *gin.Context) {
 // BatchSend 批量发送消息
 func BatchSend(c *gin.Context) {
 	var req struct {
-		AppID   uint    `json:"app_id" binding:"required"`
-		UserIDs []uint  `json:"user_ids"`
-		Title   string  `json:"title" binding:"required"`
-		Content string  `json:"content" binding:"required"`
-		Type    string  `json:"type"`
+		UserIDs    []uint `json:"user_ids"`
+		TagIDs     []uint `json:"tag_ids"`
+		SegmentIDs []uint `json:"segment_ids"`
+		Title      string `json:"title" binding:"required"`
+		Content    string `json:"content" binding:"required"`
+		Type       string `json:"type"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -286,11 +284,38 @@ func BatchSend(c *gin.Context) {
 		req.Type = "system"
 	}
 
+	// 按用户、标签或分群发送时解析为具体用户，封禁和禁用的用户不会收到消息
+	repo := repository.FromContext(c, db)
+	explicitUserIDs := req.UserIDs
+	req.UserIDs = nil
+	for _, target := range []struct {
+		targetType string
+		ids        []uint
+	}{{segment.TargetUser, explicitUserIDs}, {segment.TargetTag, req.TagIDs}, {segment.TargetSegment, req.SegmentIDs}} {
+		if len(target.ids) == 0 {
+			continue
+		}
+		if err := segment.ValidateTarget(db, repo.AppID(), target.targetType, target.ids); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "Invalid " + target.targetType + " target"})
+			return
+		}
+		userIDs, err := segment.Resolve(db, repo.AppID(), target.targetType, target.ids)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to resolve recipients"})
+			return
+		}
+		if len(userIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "No recipients in " + target.targetType + " target"})
+			return
+		}
+		req.UserIDs = append(req.UserIDs, userIDs...)
+	}
+	req.UserIDs = uniqueUserIDs(req.UserIDs)
+
 	var messages []model.Message
 	if len(req.UserIDs) == 0 {
 		// 发送给所有用户（广播）
 		messages = append(messages, model.Message{
-			AppID:   req.AppID,
 			UserID:  nil,
 			Title:   req.Title,
 			Content: req.Content,
@@ -302,7 +327,6 @@ func BatchSend(c *gin.Context) {
 		for _, userID := range req.UserIDs {
 			uid := userID
 			messages = append(messages, model.Message{
-				AppID:   req.AppID,
 				UserID:  &uid,
 				Title:   req.Title,
 				Content: req.Content,
@@ -312,7 +336,7 @@ func BatchSend(c *gin.Context) {
 		}
 	}
 
-	if err := db.Create(&messages).Error; err != nil {
+	if err := repo.Create(&messages); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "Failed to send messages"})
 		return
 	}
@@ -325,3 +349,16 @@ func BatchSend(c *gin.Context) {
 		},
 	})
 }
+
+// uniqueUserIDs 去重，保持原有顺序
+func uniqueUserIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := ids[:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,28 @@
+package message
+
+import (
+	"net/http"
+	"testing"
+
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlers_RejectNonNumericID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, fake := sqltest.Open(t, sqltest.NoRows)
+	InitDB(db)
+
+	r := gin.New()
+	r.GET("/messages/:id", Detail)
+	r.PUT("/messages/:id/read", MarkRead)
+	r.DELETE("/messages/:id", Delete)
+
+	sqltest.RejectsWithoutQuery(t, r, fake, []sqltest.Request{
+		{Method: http.MethodGet, Path: "/messages/1%20OR%201=1"},
+		{Method: http.MethodPut, Path: "/messages/1%20OR%201=1/read"},
+		{Method: http.MethodDelete, Path: "/messages/abc"},
+		{Method: http.MethodGet, Path: "/messages/0"},
+	})
+}
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -217,9 +220,12 @@ func SaveModuleConfig(c *gin.Context) {
 	appID := c.Param("id")
 	moduleCode := c.Param("module_code")
 
-	var module model.AppModule
-	if err := database.GetDB().Where("app_id = ? AND module_code = ?", appID, moduleCode).First(&module).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+	envConfig, ok := loadEnvConfig(c, env, moduleCode)
+	if !ok {
 		return
 	}
 
@@ -232,24 +238,36 @@ func SaveModuleConfig(c *gin.Context) {
 		return
 	}
 
-	// 保存配置历史
+	configJSON, _ := json.Marshal(req.Config)
+	config := string(configJSON)
+	if moduleCode == pusher.ModuleCode {
+		// 推送通道密钥加密保存，脱敏后提交的密钥字段沿用已保存的值
+		sealed, err := pusher.SealConfig(config, envConfig.Config)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt credentials"})
+			return
+		}
+		config = sealed
+	}
+
+	// 保存配置历史，版本号按环境递增
 	var maxVersion int
 	database.GetDB().Model(&model.ModuleConfigHistory{}).
-		Where("app_id = ? AND module_code = ?", appID, moduleCode).
+		Where("app_id = ? AND env_id = ? AND module_code = ?", appID, env.ID, moduleCode).
 		Select("COALESCE(MAX(version), 0)").Scan(&maxVersion)
 
 	history := model.ModuleConfigHistory{
 		AppID:      parseUint(appID),
+		EnvID:      env.ID,
 		ModuleCode: moduleCode,
-		Config:     module.Config,
+		Config:     envConfig.Config,
 		Version:    maxVersion + 1,
 		Operator:   c.GetString("username"),
 	}
 	database.GetDB().Create(&history)
 
 	// 更新配置
-	configJSON, _ := json.Marshal(req.Config)
-	database.GetDB().Model(&module).Update("config", string(configJSON))
+	database.GetDB().Model(envConfig).Update("config", config)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -258,34 +276,39 @@ func SaveModuleConfig(c *gin.Context) {
 }
 
 func GetModuleConfig(c *gin.Context) {
-	appID := c.Param("id")
 	moduleCode := c.Param("module_code")
 
-	var module model.AppModule
-	if err := database.GetDB().Where("app_id = ? AND module_code = ?", appID, moduleCode).First(&module).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+	envConfig, ok := loadEnvConfig(c, env, moduleCode)
+	if !ok {
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"data": gin.H{
-			"config": module.Config,
+			"env":    env.Code,
+			"config": redactConfig(moduleCode, envConfig.Config),
 		},
 	})
 }
 
 func ResetModuleConfig(c *gin.Context) {
-	appID := c.Param("id")
 	moduleCode := c.Param("module_code")
 
-	var module model.AppModule
-	if err := database.GetDB().Where("app_id = ? AND module_code = ?", appID, moduleCode).First(&module).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+	envConfig, ok := loadEnvConfig(c, env, moduleCode)
+	if !ok {
 		return
 	}
 
-	database.GetDB().Model(&module).Update("config", "{}")
+	database.GetDB().Model(envConfig).Update("config", "{}")
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -307,9 +330,17 @@ func GetConfigHistory(c *gin.Context) {
 	appID := c.Param("id")
 	moduleCode := c.Param("module_code")
 
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+
 	var history []model.ModuleConfigHistory
-	database.GetDB().Where("app_id = ? AND module_code = ?", appID, moduleCode).
+	database.GetDB().Where("app_id = ? AND env_id = ? AND module_code = ?", appID, env.ID, moduleCode).
 		Order("version DESC").Limit(20).Find(&history)
+	for i := range history {
+		history[i].Config = redactConfig(moduleCode, history[i].Config)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -318,19 +349,32 @@ func GetConfigHistory(c *gin.Context) {
 }
 
 func RollbackConfig(c *gin.Context) {
-	appID := c.Param("id")
 	moduleCode := c.Param("module_code")
 	historyID := c.Param("history_id")
 
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+
+	repo := repository.FromContext(c, database.GetDB())
 	var history model.ModuleConfigHistory
-	if err := database.GetDB().First(&history, historyID).Error; err != nil {
+	// 历史记录必须属于当前应用、环境和模块，不能用其他应用或环境的配置覆盖
+	if err := repo.Find(&history, "id = ? AND env_id = ? AND module_code = ?", historyID, env.ID, moduleCode); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query history"})
+		return
+	}
+	if history.ID == 0 {
 		c.JSON(http.StatusNotFound, gin.H{"error": "History not found"})
 		return
 	}
 
-	database.GetDB().Model(&model.AppModule{}).
-		Where("app_id = ? AND module_code = ?", appID, moduleCode).
-		Update("config", history.Config)
+	if err := repo.Model(&model.AppModuleConfig{}).
+		Where("env_id = ? AND module_code = ?", env.ID, moduleCode).
+		Update("config", history.Config).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rollback config"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -384,6 +428,32 @@ func DetectCircularDependency(c *gin.Context) {
 	})
 }
 
+// loadEnvConfig 加载已启用模块在指定环境中的配置，尚无配置时创建空配置
+func loadEnvConfig(c *gin.Context, env *model.AppEnvironment, moduleCode string) (*model.AppModuleConfig, bool) {
+	var module model.AppModule
+	if err := database.GetDB().Where("app_id = ? AND module_code = ?", env.AppID, moduleCode).First(&module).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Module not found"})
+		return nil, false
+	}
+
+	envConfig := model.AppModuleConfig{AppID: env.AppID, EnvID: env.ID, ModuleCode: moduleCode}
+	if err := database.GetDB().Where(envConfig).
+		Attrs(model.AppModuleConfig{Config: "{}"}).
+		FirstOrCreate(&envConfig).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load module config"})
+		return nil, false
+	}
+	return &envConfig, true
+}
+
+// redactConfig 返回给管理后台的模块配置，推送通道密钥只写不读
+func redactConfig(moduleCode, config string) string {
+	if moduleCode != pusher.ModuleCode {
+		return config
+	}
+	return pusher.RedactConfig(config)
+}
+
 func parseUint(s string) uint {
 	var id uint
 	fmt.Sscanf(s, "%d", &id)
@@ -0,0 +1,291 @@
+package module
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/configdiff"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errEnvNotFound       = errors.New("环境不存在")
+	errNoNextEnv         = errors.New("已是最后一个环境，无法继续晋级")
+	errBackwardPromotion = errors.New("配置只能晋级到后续环境")
+)
+
+// moduleDiff 单个模块在两个环境间的配置差异
+type moduleDiff struct {
+	ModuleCode string              `json:"module_code"`
+	Changes    []configdiff.Change `json:"changes"`
+}
+
+// DiffEnvironments 比较两个环境的模块配置，to 为空时与下一个环境比较
+func DiffEnvironments(c *gin.Context) {
+	appID := middleware.ScopedAppID(c)
+	from, to, ok := resolvePromotion(c, appID, c.Query("from"), c.Query("to"))
+	if !ok {
+		return
+	}
+
+	diffs, err := diffModuleConfigs(database.GetDB(), appID, from, to, nil)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, gin.H{
+		"from":    from.Code,
+		"to":      to.Code,
+		"modules": diffs,
+	})
+}
+
+// PromoteConfigs 将模块配置从一个环境晋级到下一个环境，目标环境的原配置写入配置历史，可回滚
+// dry_run 时只返回将要产生的变化
+func PromoteConfigs(c *gin.Context) {
+	var req struct {
+		From    string   `json:"from" binding:"required"`
+		To      string   `json:"to"`
+		Modules []string `json:"modules"` // 为空时晋级全部已启用模块
+		DryRun  bool     `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	appID := middleware.ScopedAppID(c)
+	from, to, ok := resolvePromotion(c, appID, req.From, req.To)
+	if !ok {
+		return
+	}
+	if err := checkPromotionOrder(from, to); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	if req.DryRun {
+		diffs, err := diffModuleConfigs(database.GetDB(), appID, from, to, req.Modules)
+		if err != nil {
+			response.DBError(c, err)
+			return
+		}
+		response.Success(c, gin.H{"from": from.Code, "to": to.Code, "modules": diffs, "dry_run": true})
+		return
+	}
+
+	var diffs []moduleDiff
+	operator := c.GetString("username")
+	err := database.WithTransaction(func(tx *database.DB) error {
+		var err error
+		diffs, err = promoteModules(tx, appID, from, to, req.Modules, operator)
+		return err
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	modules := make([]string, len(diffs))
+	for i, d := range diffs {
+		modules[i] = d.ModuleCode
+	}
+	middleware.RecordAuditEvent(c, "promote_config", "app", strconv.Itoa(int(appID)), "晋级模块配置", gin.H{
+		"from":    from.Code,
+		"to":      to.Code,
+		"modules": modules,
+	})
+	response.Success(c, gin.H{"from": from.Code, "to": to.Code, "modules": diffs})
+}
+
+// resolvePromotion 解析源环境和目标环境，目标环境为空时取源环境的下一个环境
+func resolvePromotion(c *gin.Context, appID uint, fromCode, toCode string) (*model.AppEnvironment, *model.AppEnvironment, bool) {
+	if fromCode == "" {
+		response.ParamError(c, "源环境不能为空")
+		return nil, nil, false
+	}
+	if fromCode == toCode {
+		response.ParamError(c, "源环境和目标环境不能相同")
+		return nil, nil, false
+	}
+
+	var envs []model.AppEnvironment
+	if err := database.GetDB().Where("app_id = ?", appID).Order("sort_order ASC").Find(&envs).Error; err != nil {
+		response.DBError(c, err)
+		return nil, nil, false
+	}
+
+	from, to, err := pickPromotion(envs, fromCode, toCode)
+	switch {
+	case errors.Is(err, errEnvNotFound):
+		response.NotFound(c, err.Error())
+		return nil, nil, false
+	case err != nil:
+		response.ParamError(c, err.Error())
+		return nil, nil, false
+	}
+	return from, to, true
+}
+
+// pickPromotion 在按晋级顺序排列的环境中选出源环境和目标环境
+func pickPromotion(envs []model.AppEnvironment, fromCode, toCode string) (*model.AppEnvironment, *model.AppEnvironment, error) {
+	var from, to *model.AppEnvironment
+	for i := range envs {
+		switch {
+		case envs[i].Code == fromCode:
+			from = &envs[i]
+		case toCode != "" && envs[i].Code == toCode:
+			to = &envs[i]
+		case toCode == "" && from != nil && to == nil:
+			to = &envs[i]
+		}
+	}
+	switch {
+	case from == nil:
+		return nil, nil, fmt.Errorf("源%w", errEnvNotFound)
+	case to == nil && toCode == "":
+		return nil, nil, errNoNextEnv
+	case to == nil:
+		return nil, nil, fmt.Errorf("目标%w", errEnvNotFound)
+	}
+	return from, to, nil
+}
+
+// checkPromotionOrder 配置只能从前面的环境晋级到后面的环境
+func checkPromotionOrder(from, to *model.AppEnvironment) error {
+	if to.SortOrder <= from.SortOrder {
+		return errBackwardPromotion
+	}
+	return nil
+}
+
+// promoteModules 将有差异的模块配置从源环境覆盖到目标环境，返回晋级的差异，需在事务中调用
+func promoteModules(tx *database.DB, appID uint, from, to *model.AppEnvironment, only []string, operator string) ([]moduleDiff, error) {
+	if err := checkPromotionOrder(from, to); err != nil {
+		return nil, err
+	}
+	diffs, err := diffModuleConfigs(tx, appID, from, to, only)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range diffs {
+		if err := promoteModuleConfig(tx, appID, from, to, d.ModuleCode, operator); err != nil {
+			return nil, err
+		}
+	}
+	return diffs, nil
+}
+
+// diffModuleConfigs 比较已启用模块在两个环境中的配置，只返回有差异的模块
+func diffModuleConfigs(db *database.DB, appID uint, from, to *model.AppEnvironment, only []string) ([]moduleDiff, error) {
+	query := db.Model(&model.AppModule{}).Where("app_id = ?", appID)
+	if len(only) > 0 {
+		query = query.Where("module_code IN ?", only)
+	}
+	var codes []string
+	if err := query.Order("module_code ASC").Pluck("module_code", &codes).Error; err != nil {
+		return nil, err
+	}
+
+	var configs []model.AppModuleConfig
+	if err := db.Where("app_id = ? AND env_id IN ?", appID, []uint{from.ID, to.ID}).Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	fromConfigs, toConfigs := map[string]string{}, map[string]string{}
+	for _, cfg := range configs {
+		if cfg.EnvID == from.ID {
+			fromConfigs[cfg.ModuleCode] = cfg.Config
+		} else {
+			toConfigs[cfg.ModuleCode] = cfg.Config
+		}
+	}
+
+	diffs := make([]moduleDiff, 0)
+	for _, code := range codes {
+		changes, err := diffModuleConfig(code, toConfigs[code], fromConfigs[code])
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", code, err)
+		}
+		if len(changes) > 0 {
+			diffs = append(diffs, moduleDiff{ModuleCode: code, Changes: changes})
+		}
+	}
+	return diffs, nil
+}
+
+// diffModuleConfig 比较单个模块的配置；推送通道密钥每次加密的密文不同，解密后比较，差异中只标明密钥有变化
+func diffModuleConfig(code, from, to string) ([]configdiff.Change, error) {
+	if code != pusher.ModuleCode {
+		return configdiff.DiffJSON(from, to)
+	}
+	from, err := pusher.OpenConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	if to, err = pusher.OpenConfig(to); err != nil {
+		return nil, err
+	}
+	changes, err := configdiff.DiffJSON(from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range changes {
+		if !pusher.IsSecretPath(changes[i].Path) {
+			continue
+		}
+		if changes[i].From != nil {
+			changes[i].From = pusher.RedactedValue
+		}
+		if changes[i].To != nil {
+			changes[i].To = pusher.RedactedValue
+		}
+	}
+	return changes, nil
+}
+
+// promoteModuleConfig 用源环境的配置覆盖目标环境，覆盖前的配置写入目标环境的配置历史
+func promoteModuleConfig(tx *database.DB, appID uint, from, to *model.AppEnvironment, moduleCode, operator string) error {
+	var source model.AppModuleConfig
+	err := tx.Where("app_id = ? AND env_id = ? AND module_code = ?", appID, from.ID, moduleCode).First(&source).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		source.Config = "{}"
+	} else if err != nil {
+		return err
+	}
+
+	target := model.AppModuleConfig{AppID: appID, EnvID: to.ID, ModuleCode: moduleCode}
+	if err := tx.Where(target).Attrs(model.AppModuleConfig{Config: "{}"}).FirstOrCreate(&target).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&target, target.ID).Error; err != nil {
+		return err
+	}
+
+	var maxVersion int
+	if err := tx.Model(&model.ModuleConfigHistory{}).
+		Where("app_id = ? AND env_id = ? AND module_code = ?", appID, to.ID, moduleCode).
+		Select("COALESCE(MAX(version), 0)").Scan(&maxVersion).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&model.ModuleConfigHistory{
+		AppID:      appID,
+		EnvID:      to.ID,
+		ModuleCode: moduleCode,
+		Config:     target.Config,
+		Version:    maxVersion + 1,
+		Operator:   operator,
+		Remark:     fmt.Sprintf("从 %s 晋级", from.Code),
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&target).Update("config", source.Config).Error
+}
@@ -0,0 +1,284 @@
+package module
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/pkg/sqltest"
+	"app-platform-backend/internal/pusher"
+)
+
+var (
+	envDev     = model.AppEnvironment{ID: 1, AppID: 1, Code: "dev", SortOrder: 0}
+	envStaging = model.AppEnvironment{ID: 2, AppID: 1, Code: "staging", SortOrder: 1}
+	envProd    = model.AppEnvironment{ID: 3, AppID: 1, Code: "prod", SortOrder: 2, IsDefault: true}
+)
+
+func testEnvs() []model.AppEnvironment {
+	return []model.AppEnvironment{envDev, envStaging, envProd}
+}
+
+func TestPickPromotion(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wantFrom string
+		wantTo   string
+		wantErr  error
+	}{
+		{name: "dev to next", from: "dev", wantFrom: "dev", wantTo: "staging"},
+		{name: "staging to next", from: "staging", wantFrom: "staging", wantTo: "prod"},
+		{name: "explicit target", from: "dev", to: "prod", wantFrom: "dev", wantTo: "prod"},
+		{name: "prod has no next", from: "prod", wantErr: errNoNextEnv},
+		{name: "unknown source", from: "qa", wantErr: errEnvNotFound},
+		{name: "unknown target", from: "dev", to: "qa", wantErr: errEnvNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := pickPromotion(testEnvs(), tt.from, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from.Code != tt.wantFrom || to.Code != tt.wantTo {
+				t.Errorf("got %s -> %s, want %s -> %s", from.Code, to.Code, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+// moduleStore 晋级测试的模块、各环境模块配置和配置历史，按晋级流程发出的语句读写
+type moduleStore struct {
+	t         *testing.T
+	modules   []string
+	configs   []model.AppModuleConfig
+	histories []model.ModuleConfigHistory
+}
+
+func (s *moduleStore) config(envID uint, code string) *model.AppModuleConfig {
+	for i := range s.configs {
+		if s.configs[i].EnvID == envID && s.configs[i].ModuleCode == code {
+			return &s.configs[i]
+		}
+	}
+	return nil
+}
+
+var moduleConfigColumns = []string{"id", "app_id", "env_id", "module_code", "config"}
+
+func (s *moduleStore) handle(query string, args []driver.Value) sqltest.Reply {
+	switch {
+	case strings.Contains(query, "FROM `app_modules`"):
+		only := map[string]bool{}
+		for _, a := range args[1:] {
+			only[fmt.Sprint(a)] = true
+		}
+		reply := sqltest.Reply{Columns: []string{"module_code"}}
+		for _, code := range s.modules {
+			if len(only) == 0 || only[code] {
+				reply.Rows = append(reply.Rows, []driver.Value{code})
+			}
+		}
+		return reply
+	case strings.Contains(query, "FROM `app_module_configs`") && strings.Contains(query, "env_id IN"):
+		var matched []*model.AppModuleConfig
+		for i := range s.configs {
+			for _, env := range args[1:] {
+				if fmt.Sprint(s.configs[i].EnvID) == fmt.Sprint(env) {
+					matched = append(matched, &s.configs[i])
+				}
+			}
+		}
+		return configRows(matched...)
+	case strings.Contains(query, "FROM `app_module_configs`") && strings.Contains(query, "FOR UPDATE"):
+		for i := range s.configs {
+			if fmt.Sprint(s.configs[i].ID) == fmt.Sprint(args[0]) {
+				return configRows(&s.configs[i])
+			}
+		}
+		return configRows()
+	case strings.Contains(query, "FROM `app_module_configs`"):
+		var env uint
+		fmt.Sscan(fmt.Sprint(args[1]), &env)
+		if cfg := s.config(env, fmt.Sprint(args[2])); cfg != nil {
+			return configRows(cfg)
+		}
+		return configRows()
+	case strings.HasPrefix(query, "INSERT INTO `app_module_configs`"):
+		cfg := model.AppModuleConfig{ID: uint(len(s.configs) + 1), ModuleCode: fmt.Sprint(args[2]), Config: fmt.Sprint(args[3])}
+		fmt.Sscan(fmt.Sprint(args[0]), &cfg.AppID)
+		fmt.Sscan(fmt.Sprint(args[1]), &cfg.EnvID)
+		s.configs = append(s.configs, cfg)
+		return sqltest.Reply{Affected: 1, LastInsertID: int64(cfg.ID)}
+	case strings.HasPrefix(query, "UPDATE `app_module_configs` SET `config`=?"):
+		for i := range s.configs {
+			if fmt.Sprint(s.configs[i].ID) == fmt.Sprint(args[2]) {
+				s.configs[i].Config = fmt.Sprint(args[0])
+				return sqltest.Reply{Affected: 1}
+			}
+		}
+		return sqltest.Reply{}
+	case strings.Contains(query, "FROM `module_config_histories`"):
+		version := 0
+		for _, h := range s.histories {
+			if fmt.Sprint(h.EnvID) == fmt.Sprint(args[1]) && h.ModuleCode == fmt.Sprint(args[2]) && h.Version > version {
+				version = h.Version
+			}
+		}
+		return sqltest.Reply{Columns: []string{"version"}, Rows: [][]driver.Value{{int64(version)}}}
+	case strings.HasPrefix(query, "INSERT INTO `module_config_histories`"):
+		h := model.ModuleConfigHistory{ModuleCode: fmt.Sprint(args[2]), Config: fmt.Sprint(args[3]), Remark: fmt.Sprint(args[6])}
+		fmt.Sscan(fmt.Sprint(args[1]), &h.EnvID)
+		fmt.Sscan(fmt.Sprint(args[4]), &h.Version)
+		s.histories = append(s.histories, h)
+		return sqltest.Reply{Affected: 1, LastInsertID: int64(len(s.histories))}
+	}
+	s.t.Errorf("unexpected statement: %s", query)
+	return sqltest.Reply{}
+}
+
+func configRows(configs ...*model.AppModuleConfig) sqltest.Reply {
+	reply := sqltest.Reply{Columns: moduleConfigColumns}
+	for _, c := range configs {
+		reply.Rows = append(reply.Rows, []driver.Value{int64(c.ID), int64(c.AppID), int64(c.EnvID), c.ModuleCode, c.Config})
+	}
+	return reply
+}
+
+func promotedModules(diffs []moduleDiff) string {
+	codes := make([]string, len(diffs))
+	for i, d := range diffs {
+		codes[i] = d.ModuleCode
+	}
+	return strings.Join(codes, ",")
+}
+
+func TestPromoteModules_DevToStagingToProd(t *testing.T) {
+	store := &moduleStore{
+		t:       t,
+		modules: []string{"log", "push"},
+		configs: []model.AppModuleConfig{
+			{ID: 1, AppID: 1, EnvID: envDev.ID, ModuleCode: "push", Config: `{"batch":500}`},
+			{ID: 2, AppID: 1, EnvID: envDev.ID, ModuleCode: "log", Config: `{"level":"info"}`},
+			{ID: 3, AppID: 1, EnvID: envStaging.ID, ModuleCode: "push", Config: `{"batch":100}`},
+			{ID: 4, AppID: 1, EnvID: envStaging.ID, ModuleCode: "log", Config: `{"level":"info"}`},
+		},
+	}
+	db, _ := sqltest.Open(t, store.handle)
+
+	// dev -> staging：只有配置不同的 push 被覆盖，原配置写入 staging 的历史
+	diffs, err := promoteModules(db, 1, &envDev, &envStaging, nil, "admin")
+	if err != nil {
+		t.Fatalf("dev -> staging: %v", err)
+	}
+	if got := promotedModules(diffs); got != "push" {
+		t.Errorf("dev -> staging promoted %q, want push", got)
+	}
+	if got := store.config(envStaging.ID, "push").Config; got != `{"batch":500}` {
+		t.Errorf("staging push = %s, want dev config", got)
+	}
+	if len(store.histories) != 1 || store.histories[0].EnvID != envStaging.ID ||
+		store.histories[0].Config != `{"batch":100}` || store.histories[0].Version != 1 {
+		t.Errorf("staging history = %+v, want previous push config as version 1", store.histories)
+	}
+
+	// staging -> prod：prod 尚无配置，全部模块新建后覆盖
+	diffs, err = promoteModules(db, 1, &envStaging, &envProd, nil, "admin")
+	if err != nil {
+		t.Fatalf("staging -> prod: %v", err)
+	}
+	if got := promotedModules(diffs); got != "log,push" {
+		t.Errorf("staging -> prod promoted %q, want log,push", got)
+	}
+	for code, want := range map[string]string{"push": `{"batch":500}`, "log": `{"level":"info"}`} {
+		if cfg := store.config(envProd.ID, code); cfg == nil || cfg.Config != want {
+			t.Errorf("prod %s = %+v, want %s", code, cfg, want)
+		}
+	}
+	if got := store.config(envDev.ID, "push").Config; got != `{"batch":500}` {
+		t.Errorf("source dev push changed to %s", got)
+	}
+
+	// 再次晋级没有差异，不产生新的历史
+	before := len(store.histories)
+	diffs, err = promoteModules(db, 1, &envStaging, &envProd, nil, "admin")
+	if err != nil || len(diffs) != 0 || len(store.histories) != before {
+		t.Errorf("repeated promotion: diffs=%v err=%v histories %d -> %d", diffs, err, before, len(store.histories))
+	}
+}
+
+func TestPromoteModules_RejectsBackwardPromotion(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		from, to model.AppEnvironment
+	}{
+		{"prod to staging", envProd, envStaging},
+		{"staging to dev", envStaging, envDev},
+		{"same order", envStaging, envStaging},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &moduleStore{t: t, modules: []string{"push"}}
+			db, fake := sqltest.Open(t, store.handle)
+			if _, err := promoteModules(db, 1, &tt.from, &tt.to, nil, "admin"); !errors.Is(err, errBackwardPromotion) {
+				t.Errorf("err = %v, want errBackwardPromotion", err)
+			}
+			if calls := fake.Calls(""); len(calls) != 0 {
+				t.Errorf("rejected promotion executed %d statements", len(calls))
+			}
+		})
+	}
+}
+
+func TestDiffModuleConfigs_RedactsPushSecrets(t *testing.T) {
+	box, err := secretbox.New("promote-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pusher.InitCredentials(box)
+	defer pusher.InitCredentials(nil)
+
+	seal := func(raw string) string {
+		sealed, err := pusher.SealConfig(raw, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+	store := &moduleStore{
+		t:       t,
+		modules: []string{pusher.ModuleCode},
+		configs: []model.AppModuleConfig{
+			// 相同密钥两次加密的密文不同，不应视为差异
+			{ID: 1, AppID: 1, EnvID: envDev.ID, ModuleCode: pusher.ModuleCode, Config: seal(`{"providers":{"xiaomi":{"appSecret":"same","packageName":"dev"},"oppo":{"masterSecret":"new"}}}`)},
+			{ID: 2, AppID: 1, EnvID: envStaging.ID, ModuleCode: pusher.ModuleCode, Config: seal(`{"providers":{"xiaomi":{"appSecret":"same","packageName":"staging"},"oppo":{"masterSecret":"old"}}}`)},
+		},
+	}
+	db, _ := sqltest.Open(t, store.handle)
+
+	diffs, err := diffModuleConfigs(db, 1, &envDev, &envStaging, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("diffs = %+v, want the push module", diffs)
+	}
+	got := fmt.Sprintf("%+v", diffs[0].Changes)
+	if len(diffs[0].Changes) != 2 || !strings.Contains(got, "providers.oppo.masterSecret") || !strings.Contains(got, "providers.xiaomi.packageName") {
+		t.Errorf("changes = %s, want packageName and masterSecret only", got)
+	}
+	for _, leaked := range []string{"old", "new", "same", "v1:"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("changes = %s, leaked %q", got, leaked)
+		}
+	}
+}
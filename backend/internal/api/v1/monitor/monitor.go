@@ -2,6 +2,8 @@ package monitor
 
 import (
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/repository"
 	"app-platform-backend/internal/response"
 	"app-platform-backend/internal/validator"
 	"encoding/json"
@@ -21,7 +23,6 @@ func InitDB(database *gorm.DB) {
 // ReportMetric 上报监控指标
 func ReportMetric(c *gin.Context) {
 	var req struct {
-		AppID       uint              `json:"app_id" binding:"required"`
 		MetricName  string            `json:"metric_name" binding:"required"`
 		MetricValue float64           `json:"metric_value" binding:"required"`
 		Tags        map[string]string `json:"tags"`
@@ -45,28 +46,34 @@ func ReportMetric(c *gin.Context) {
 		}
 	}
 
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
 	metric := model.MonitorMetric{
-		AppID:       req.AppID,
 		MetricName:  req.MetricName,
 		MetricValue: req.MetricValue,
 		Tags:        tagsJSON,
 	}
 
-	if err := db.Create(&metric).Error; err != nil {
-		response.DBError(c, err)
+	// 写入成功后才计入配额，超出配额时写入一并回滚
+	if err := quota.ConsumeWith(repo.AppID(), quota.MetricMetricsPerMinute, 1, func(tx *gorm.DB) error {
+		return repo.WithTx(tx).Create(&metric)
+	}); err != nil {
+		quota.Reject(c, err)
 		return
 	}
 
 	// 检查是否触发告警
-	checkAlerts(req.AppID, req.MetricName, req.MetricValue)
+	checkAlerts(repo, req.MetricName, req.MetricValue)
 
 	response.SuccessWithMessage(c, nil, "指标上报成功")
 }
 
 // 检查告警规则
-func checkAlerts(appID uint, metricName string, value float64) {
+func checkAlerts(repo *repository.AppScoped, metricName string, value float64) {
 	var alerts []model.MonitorAlert
-	db.Where("app_id = ? AND metric_name = ? AND is_active = 1", appID, metricName).Find(&alerts)
+	repo.Find(&alerts, "metric_name = ? AND is_active = 1", metricName)
 
 	for _, alert := range alerts {
 		triggered := false
@@ -85,7 +92,7 @@ func checkAlerts(appID uint, metricName string, value float64) {
 
 		if triggered {
 			now := time.Now()
-			db.Model(&alert).Updates(map[string]interface{}{
+			repo.Updates(&alert, map[string]interface{}{
 				"status":        "alerting",
 				"last_alert_at": now,
 			})
@@ -95,22 +102,20 @@ func checkAlerts(appID uint, metricName string, value float64) {
 
 // Metrics 获取监控指标
 func Metrics(c *gin.Context) {
-	appID := c.Query("app_id")
 	metricName := c.Query("metric_name")
 	startTime := c.Query("start_time")
 	endTime := c.Query("end_time")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "100"))
 
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-
 	// 验证分页参数
 	page, size = validator.ValidatePagination(page, size)
 
-	query := db.Model(&model.MonitorMetric{}).Where("app_id = ?", appID)
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
+		return
+	}
+	query := repo.Model(&model.MonitorMetric{})
 
 	if metricName != "" {
 		query = query.Where("metric_name = ?", metricName)
@@ -137,11 +142,15 @@ func Metrics(c *gin.Context) {
 
 // MetricStats 指标统计
 func MetricStats(c *gin.Context) {
-	appID := c.Query("app_id")
 	metricName := c.Query("metric_name")
 
-	if appID == "" || metricName == "" {
-		response.ParamError(c, "app_id 和 metric_name 不能为空")
+	if metricName == "" {
+		response.ParamError(c, "metric_name 不能为空")
+		return
+	}
+
+	repo, ok := repository.FromEnvContext(c, db)
+	if !ok {
 		return
 	}
 
@@ -152,8 +161,8 @@ func MetricStats(c *gin.Context) {
 		Count int64   `json:"count"`
 	}
 
-	if err := db.Model(&model.MonitorMetric{}).
-		Where("app_id = ? AND metric_name = ?", appID, metricName).
+	if err := repo.Model(&model.MonitorMetric{}).
+		Where("metric_name = ?", metricName).
 		Select("AVG(metric_value) as avg, MAX(metric_value) as max, MIN(metric_value) as min, COUNT(*) as count").
 		Scan(&stats).Error; err != nil {
 		response.DBError(c, err)
@@ -165,8 +174,8 @@ func MetricStats(c *gin.Context) {
 		Time  time.Time `json:"time"`
 		Value float64   `json:"value"`
 	}
-	db.Model(&model.MonitorMetric{}).
-		Where("app_id = ? AND metric_name = ?", appID, metricName).
+	repo.Model(&model.MonitorMetric{}).
+		Where("metric_name = ?", metricName).
 		Select("created_at as time, metric_value as value").
 		Order("created_at DESC").
 		Limit(100).
@@ -183,7 +192,6 @@ func MetricStats(c *gin.Context) {
 
 // Alerts 告警列表
 func Alerts(c *gin.Context) {
-	appID := c.Query("app_id")
 	status := c.Query("status")
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
@@ -191,10 +199,7 @@ func Alerts(c *gin.Context) {
 	// 验证分页参数
 	page, size = validator.ValidatePagination(page, size)
 
-	query := db.Model(&model.MonitorAlert{})
-	if appID != "" {
-		query = query.Where("app_id = ?", appID)
-	}
+	query := repository.FromContext(c, db).Model(&model.MonitorAlert{})
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
@@ -215,7 +220,6 @@ func Alerts(c *gin.Context) {
 // CreateAlert 创建告警规则
 func CreateAlert(c *gin.Context) {
 	var req struct {
-		AppID      uint    `json:"app_id" binding:"required"`
 		AlertName  string  `json:"alert_name" binding:"required"`
 		MetricName string  `json:"metric_name" binding:"required"`
 		Condition  string  `json:"condition" binding:"required"`
@@ -241,7 +245,6 @@ func CreateAlert(c *gin.Context) {
 	}
 
 	alert := model.MonitorAlert{
-		AppID:      req.AppID,
 		AlertName:  req.AlertName,
 		MetricName: req.MetricName,
 		Condition:  req.Condition,
@@ -250,7 +253,7 @@ func CreateAlert(c *gin.Context) {
 		IsActive:   1,
 	}
 
-	if err := db.Create(&alert).Error; err != nil {
+	if err := repository.FromContext(c, db).Create(&alert); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -261,7 +264,6 @@ func CreateAlert(c *gin.Context) {
 // UpdateAlert 更新告警规则
 func UpdateAlert(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -269,20 +271,10 @@ func UpdateAlert(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var alert model.MonitorAlert
-	// 同时验证id和app_id，防止越权操作
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&alert).Error; err != nil {
+	// 只能查到当前应用的告警规则，防止越权操作
+	if err := repo.First(&alert, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "告警规则不存在或无权限操作")
 			return
@@ -330,7 +322,7 @@ func UpdateAlert(c *gin.Context) {
 		updates["is_active"] = *req.IsActive
 	}
 
-	if err := db.Model(&alert).Updates(updates).Error; err != nil {
+	if err := repo.Updates(&alert, updates); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -341,7 +333,6 @@ func UpdateAlert(c *gin.Context) {
 // DeleteAlert 删除告警规则
 func DeleteAlert(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -349,20 +340,10 @@ func DeleteAlert(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var alert model.MonitorAlert
-	// 同时验证id和app_id，防止越权删除
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&alert).Error; err != nil {
+	// 只能查到当前应用的告警规则，防止越权删除
+	if err := repo.First(&alert, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "告警规则不存在或无权限删除")
 			return
@@ -371,7 +352,7 @@ func DeleteAlert(c *gin.Context) {
 		return
 	}
 
-	if err := db.Delete(&alert).Error; err != nil {
+	if _, err := repo.Delete(&alert); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -382,7 +363,6 @@ func DeleteAlert(c *gin.Context) {
 // ResolveAlert 解决告警
 func ResolveAlert(c *gin.Context) {
 	id := c.Param("id")
-	appIDStr := c.Query("app_id")
 
 	// 验证ID
 	if _, err := validator.ValidateID(id); err != nil {
@@ -390,20 +370,10 @@ func ResolveAlert(c *gin.Context) {
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
+	repo := repository.FromContext(c, db)
 	var alert model.MonitorAlert
-	// 同时验证id和app_id，防止越权操作
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&alert).Error; err != nil {
+	// 只能查到当前应用的告警规则，防止越权操作
+	if err := repo.First(&alert, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "告警规则不存在或无权限操作")
 			return
@@ -412,7 +382,7 @@ func ResolveAlert(c *gin.Context) {
 		return
 	}
 
-	if err := db.Model(&alert).Update("status", "normal").Error; err != nil {
+	if err := repo.Updates(&alert, map[string]interface{}{"status": "normal"}); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -436,26 +406,21 @@ func Health(c *gin.Context) {
 
 // Stats 监控统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
+	repo := repository.FromContext(c, db)
 
 	var totalMetrics, totalAlerts, activeAlerts, alertingCount int64
-	db.Model(&model.MonitorMetric{}).Where("app_id = ?", appID).Count(&totalMetrics)
-	db.Model(&model.MonitorAlert{}).Where("app_id = ?", appID).Count(&totalAlerts)
-	db.Model(&model.MonitorAlert{}).Where("app_id = ? AND is_active = 1", appID).Count(&activeAlerts)
-	db.Model(&model.MonitorAlert{}).Where("app_id = ? AND status = ?", appID, "alerting").Count(&alertingCount)
+	repo.Model(&model.MonitorMetric{}).Count(&totalMetrics)
+	repo.Model(&model.MonitorAlert{}).Count(&totalAlerts)
+	repo.Model(&model.MonitorAlert{}).Where("is_active = 1").Count(&activeAlerts)
+	repo.Model(&model.MonitorAlert{}).Where("status = ?", "alerting").Count(&alertingCount)
 
 	// 获取指标类型统计
 	var metricStats []struct {
 		MetricName string `json:"metric_name"`
 		Count      int64  `json:"count"`
 	}
-	db.Model(&model.MonitorMetric{}).
+	repo.Model(&model.MonitorMetric{}).
 		Select("metric_name, COUNT(*) as count").
-		Where("app_id = ?", appID).
 		Group("metric_name").
 		Order("count DESC").
 		Limit(10).
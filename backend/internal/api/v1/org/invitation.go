@@ -0,0 +1,245 @@
+package org
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// invitationTTL 组织邀请有效期
+const invitationTTL = 7 * 24 * time.Hour
+
+var (
+	errInvitationInvalid = errors.New("邀请无效或已过期")
+	errInvitationEmail   = errors.New("邀请邮箱与当前账号邮箱不一致")
+	errMemberLimit       = errors.New("组织成员数量已达上限")
+)
+
+// ListInvitations 组织待接受的邀请
+func ListInvitations(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+
+	var invitations []model.OrgInvitation
+	if err := database.GetDB().
+		Where("org_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", org.ID, time.Now()).
+		Order("id DESC").
+		Find(&invitations).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, invitations)
+}
+
+// CreateInvitation 按邮箱邀请成员，邀请令牌明文只返回一次，由邀请人转交
+func CreateInvitation(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+	operator, _ := middleware.GetOrgMember(c)
+
+	var req validator.OrgInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if err := validator.ValidateOrgInvite(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if req.Role == model.OrgRoleOwner && operator.Role != model.OrgRoleOwner {
+		response.Forbidden(c, "只有所有者可以邀请所有者")
+		return
+	}
+
+	var existing int64
+	database.GetDB().Model(&model.OrgMember{}).
+		Joins("JOIN admins ON admins.id = org_members.admin_id AND admins.deleted_at IS NULL").
+		Where("org_members.org_id = ? AND LOWER(admins.email) = ?", org.ID, req.Email).
+		Count(&existing)
+	if existing > 0 {
+		response.Conflict(c, "该邮箱的管理员已是组织成员")
+		return
+	}
+	if org.MaxMembers > 0 {
+		var count int64
+		database.GetDB().Model(&model.OrgMember{}).Where("org_id = ?", org.ID).Count(&count)
+		if count >= int64(org.MaxMembers) {
+			response.Forbidden(c, errMemberLimit.Error())
+			return
+		}
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		response.InternalError(c, "生成邀请令牌失败")
+		return
+	}
+
+	invitation := model.OrgInvitation{
+		OrgID:     org.ID,
+		Email:     req.Email,
+		Role:      req.Role,
+		TokenHash: hashInvitationToken(token),
+		InvitedBy: c.GetUint("user_id"),
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	if err := database.GetDB().Create(&invitation).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "invite", "org_member", strconv.Itoa(int(invitation.ID)), "邀请组织成员", gin.H{
+		"org_id": org.ID,
+		"email":  invitation.Email,
+		"role":   invitation.Role,
+	})
+	response.SuccessWithMessage(c, gin.H{
+		"invitation":   invitation,
+		"invite_token": token,
+	}, "邀请已创建")
+}
+
+// RevokeInvitation 撤销未接受的邀请
+func RevokeInvitation(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+
+	id, err := validator.ValidateID(c.Param("invitation_id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	result := database.GetDB().Model(&model.OrgInvitation{}).
+		Where("id = ? AND org_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id, org.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		response.DBError(c, result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		response.NotFound(c, "邀请不存在或已失效")
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "revoke", "org_invitation", strconv.Itoa(int(id)), "撤销组织邀请", gin.H{
+		"org_id": org.ID,
+	})
+	response.SuccessWithMessage(c, nil, "邀请已撤销")
+}
+
+// AcceptInvitation 当前管理员接受邀请加入组织，账号邮箱必须与邀请邮箱一致
+func AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "邀请令牌不能为空")
+		return
+	}
+
+	adminID := c.GetUint("user_id")
+	var admin model.Admin
+	if err := database.GetDB().First(&admin, adminID).Error; err != nil {
+		response.Unauthorized(c, "账号不存在")
+		return
+	}
+
+	var invitation model.OrgInvitation
+	var member model.OrgMember
+	err := database.WithTransaction(func(tx *database.DB) error {
+		// 锁定邀请记录，保证同一令牌只能被接受一次
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashInvitationToken(req.Token)).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInvitationInvalid
+			}
+			return err
+		}
+		if invitation.AcceptedAt != nil || invitation.RevokedAt != nil || !invitation.ExpiresAt.After(time.Now()) {
+			return errInvitationInvalid
+		}
+		if !strings.EqualFold(strings.TrimSpace(admin.Email), invitation.Email) {
+			return errInvitationEmail
+		}
+
+		var org model.Organization
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&org, invitation.OrgID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInvitationInvalid
+			}
+			return err
+		}
+
+		err := tx.Where("org_id = ? AND admin_id = ?", org.ID, admin.ID).First(&member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if org.MaxMembers > 0 {
+				var count int64
+				tx.Model(&model.OrgMember{}).Where("org_id = ?", org.ID).Count(&count)
+				if count >= int64(org.MaxMembers) {
+					return errMemberLimit
+				}
+			}
+			member = model.OrgMember{
+				OrgID:     org.ID,
+				AdminID:   admin.ID,
+				Role:      invitation.Role,
+				InvitedBy: invitation.InvitedBy,
+			}
+			err = tx.Create(&member).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&invitation).Updates(map[string]interface{}{
+			"accepted_at": now,
+			"accepted_by": admin.ID,
+		}).Error
+	})
+	switch {
+	case errors.Is(err, errInvitationInvalid):
+		response.NotFound(c, err.Error())
+		return
+	case errors.Is(err, errInvitationEmail), errors.Is(err, errMemberLimit):
+		response.Forbidden(c, err.Error())
+		return
+	case err != nil:
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "accept", "org_invitation", strconv.Itoa(int(invitation.ID)), "接受组织邀请", gin.H{
+		"org_id": invitation.OrgID,
+		"role":   member.Role,
+	})
+	response.SuccessWithMessage(c, member, "已加入组织")
+}
+
+func newInvitationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// hashInvitationToken 计算邀请令牌哈希，数据库中不保存明文
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,186 @@
+package org
+
+import (
+	"errors"
+	"strconv"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errLastOwner = errors.New("组织至少需要保留一名所有者")
+
+// memberView 成员及管理员资料
+type memberView struct {
+	model.OrgMember
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+}
+
+// ListMembers 组织成员列表
+func ListMembers(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+
+	var members []memberView
+	if err := database.GetDB().Table("org_members").
+		Select("org_members.*, admins.username, admins.nickname, admins.email").
+		Joins("JOIN admins ON admins.id = org_members.admin_id AND admins.deleted_at IS NULL").
+		Where("org_members.org_id = ?", org.ID).
+		Order("org_members.id ASC").
+		Scan(&members).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, members)
+}
+
+// UpdateMember 修改成员角色，只有所有者可以授予或收回所有者角色
+func UpdateMember(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+	operator, _ := middleware.GetOrgMember(c)
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "角色不能为空")
+		return
+	}
+	if err := validator.ValidateOrgRole(req.Role); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	target, ok := loadTargetMember(c, org.ID)
+	if !ok {
+		return
+	}
+	if target.Role == req.Role {
+		response.Success(c, target)
+		return
+	}
+	if (target.Role == model.OrgRoleOwner || req.Role == model.OrgRoleOwner) && operator.Role != model.OrgRoleOwner {
+		response.Forbidden(c, "只有所有者可以变更所有者角色")
+		return
+	}
+
+	oldRole := target.Role
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if oldRole == model.OrgRoleOwner {
+			if err := ensureOtherOwner(tx, org.ID, target.AdminID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(target).Update("role", req.Role).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		response.Conflict(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "update_role", "org_member", strconv.Itoa(int(target.ID)), "修改组织成员角色", gin.H{
+		"org_id":   org.ID,
+		"admin_id": target.AdminID,
+		"old_role": oldRole,
+		"new_role": req.Role,
+	})
+	target.Role = req.Role
+	response.Success(c, target)
+}
+
+// RemoveMember 移除组织成员
+func RemoveMember(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+	operator, _ := middleware.GetOrgMember(c)
+
+	target, ok := loadTargetMember(c, org.ID)
+	if !ok {
+		return
+	}
+	if target.Role == model.OrgRoleOwner && operator.Role != model.OrgRoleOwner {
+		response.Forbidden(c, "只有所有者可以移除所有者")
+		return
+	}
+	removeMember(c, org, target, "移除组织成员")
+}
+
+// Leave 当前管理员退出组织
+func Leave(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+	member, _ := middleware.GetOrgMember(c)
+	removeMember(c, org, member, "退出组织")
+}
+
+func removeMember(c *gin.Context, org *model.Organization, target *model.OrgMember, desc string) {
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if target.Role == model.OrgRoleOwner {
+			if err := ensureOtherOwner(tx, org.ID, target.AdminID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(target).Error
+	})
+	if errors.Is(err, errLastOwner) {
+		response.Conflict(c, err.Error())
+		return
+	}
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "remove", "org_member", strconv.Itoa(int(target.ID)), desc, gin.H{
+		"org_id":   org.ID,
+		"admin_id": target.AdminID,
+		"role":     target.Role,
+	})
+	response.SuccessWithMessage(c, nil, desc+"成功")
+}
+
+// ensureOtherOwner 确认除指定管理员外仍有其他所有者，锁定所有者记录避免并发操作移除全部所有者
+func ensureOtherOwner(tx *database.DB, orgID, adminID uint) error {
+	var owners []model.OrgMember
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("org_id = ? AND role = ?", orgID, model.OrgRoleOwner).
+		Find(&owners).Error; err != nil {
+		return err
+	}
+	for _, o := range owners {
+		if o.AdminID != adminID {
+			return nil
+		}
+	}
+	return errLastOwner
+}
+
+// loadTargetMember 根据路径参数加载组织成员
+func loadTargetMember(c *gin.Context, orgID uint) (*model.OrgMember, bool) {
+	adminID, err := validator.ValidateID(c.Param("admin_id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return nil, false
+	}
+
+	var member model.OrgMember
+	if err := database.GetDB().Where("org_id = ? AND admin_id = ?", orgID, adminID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "成员不存在")
+		} else {
+			response.DBError(c, err)
+		}
+		return nil, false
+	}
+	return &member, true
+}
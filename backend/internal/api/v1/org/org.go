@@ -0,0 +1,228 @@
+// Package org 组织管理：组织拥有应用，管理员以成员身份按组织角色访问
+package org
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// orgWithRole 组织及当前管理员在其中的角色
+type orgWithRole struct {
+	model.Organization
+	Role        string `json:"role"`
+	AppCount    int64  `json:"app_count"`
+	MemberCount int64  `json:"member_count"`
+}
+
+// List 当前管理员所属的组织
+func List(c *gin.Context) {
+	var members []model.OrgMember
+	if err := database.GetDB().Where("admin_id = ?", c.GetUint("user_id")).Find(&members).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	roles := make(map[uint]string, len(members))
+	orgIDs := make([]uint, 0, len(members))
+	for _, m := range members {
+		roles[m.OrgID] = m.Role
+		orgIDs = append(orgIDs, m.OrgID)
+	}
+
+	var orgs []model.Organization
+	if err := database.GetDB().Where("id IN ?", orgIDs).Order("id ASC").Find(&orgs).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	appCounts := countByOrg(&model.App{}, orgIDs)
+	memberCounts := countByOrg(&model.OrgMember{}, orgIDs)
+
+	result := make([]orgWithRole, len(orgs))
+	for i, o := range orgs {
+		result[i] = orgWithRole{
+			Organization: o,
+			Role:         roles[o.ID],
+			AppCount:     appCounts[o.ID],
+			MemberCount:  memberCounts[o.ID],
+		}
+	}
+	response.Success(c, result)
+}
+
+// Create 创建组织，创建人成为所有者
+func Create(c *gin.Context) {
+	var req validator.OrgCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if err := validator.ValidateOrgCreate(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	// 软删除的组织仍占用标识
+	var exists int64
+	database.GetDB().Unscoped().Model(&model.Organization{}).Where("slug = ?", req.Slug).Count(&exists)
+	if exists > 0 {
+		response.Conflict(c, "组织标识已存在")
+		return
+	}
+
+	adminID := c.GetUint("user_id")
+	org := model.Organization{
+		Name:           req.Name,
+		Slug:           req.Slug,
+		Description:    req.Description,
+		DefaultModules: "[]",
+		CreatedBy:      adminID,
+	}
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Create(&org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.OrgMember{OrgID: org.ID, AdminID: adminID, Role: model.OrgRoleOwner}).Error
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "create", "organization", strconv.Itoa(int(org.ID)), "创建组织", gin.H{
+		"name": org.Name,
+		"slug": org.Slug,
+	})
+	response.SuccessWithMessage(c, org, "组织创建成功")
+}
+
+// Detail 组织详情
+func Detail(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+	member, _ := middleware.GetOrgMember(c)
+
+	result := orgWithRole{Organization: *org, Role: member.Role}
+	database.GetDB().Model(&model.App{}).Where("org_id = ?", org.ID).Count(&result.AppCount)
+	database.GetDB().Model(&model.OrgMember{}).Where("org_id = ?", org.ID).Count(&result.MemberCount)
+	response.Success(c, result)
+}
+
+// Update 更新组织资料和设置（默认模块、配额）
+func Update(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+
+	var req validator.OrgUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	if err := validator.ValidateOrgUpdate(&req); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.DefaultModules != nil {
+		var known []string
+		database.GetDB().Model(&model.ModuleTemplate{}).
+			Where("source_module IN ?", *req.DefaultModules).
+			Distinct().Pluck("source_module", &known)
+		exists := make(map[string]bool, len(known))
+		for _, m := range known {
+			exists[m] = true
+		}
+		for _, m := range *req.DefaultModules {
+			if !exists[m] {
+				response.ParamError(c, "默认模块中包含不存在的模块: "+m)
+				return
+			}
+		}
+		data, _ := json.Marshal(*req.DefaultModules)
+		updates["default_modules"] = string(data)
+	}
+	if req.MaxApps != nil {
+		updates["max_apps"] = *req.MaxApps
+	}
+	if req.MaxMembers != nil {
+		updates["max_members"] = *req.MaxMembers
+	}
+	if len(updates) == 0 {
+		response.Success(c, org)
+		return
+	}
+
+	if err := database.GetDB().Model(org).Updates(updates).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "update", "organization", strconv.Itoa(int(org.ID)), "更新组织设置", updates)
+	database.GetDB().First(org, org.ID)
+	response.Success(c, org)
+}
+
+// Delete 删除组织，组织内仍有应用（包括等待清理的已删除应用）时拒绝
+func Delete(c *gin.Context) {
+	org, _ := middleware.GetScopedOrg(c)
+
+	// 已删除的应用在清理完成前仍可能被恢复到该组织
+	var appCount int64
+	database.GetDB().Unscoped().Model(&model.App{}).Where("org_id = ?", org.ID).Count(&appCount)
+	if appCount > 0 {
+		response.Conflict(c, "请先转移或删除组织内的应用，已删除的应用需等待数据清理完成")
+		return
+	}
+
+	err := database.WithTransaction(func(tx *database.DB) error {
+		if err := tx.Where("org_id = ?", org.ID).Delete(&model.OrgMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ? AND accepted_at IS NULL", org.ID).Delete(&model.OrgInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(org).Error
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "delete", "organization", strconv.Itoa(int(org.ID)), "删除组织", gin.H{
+		"name": org.Name,
+		"slug": org.Slug,
+	})
+	response.SuccessWithMessage(c, nil, "组织删除成功")
+}
+
+// countByOrg 按组织统计记录数
+func countByOrg(value interface{}, orgIDs []uint) map[uint]int64 {
+	var rows []struct {
+		OrgID uint
+		Count int64
+	}
+	database.GetDB().Model(value).
+		Select("org_id, COUNT(*) as count").
+		Where("org_id IN ?", orgIDs).
+		Group("org_id").
+		Scan(&rows)
+
+	counts := make(map[uint]int64, len(rows))
+	for _, r := range rows {
+		counts[r.OrgID] = r.Count
+	}
+	return counts
+}
@@ -0,0 +1,65 @@
+package push
+
+import (
+	"strconv"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/segment"
+
+	"gorm.io/gorm"
+)
+
+// subjectPushes 直接指定了该用户为目标的推送，按标签和分群推送的成员关系由用户模块处理
+func subjectPushes(database *gorm.DB, subject module.DataSubject) *gorm.DB {
+	return database.Unscoped().Model(&model.PushRecord{}).
+		Where("app_id = ? AND target_type = ? AND FIND_IN_SET(?, target_ids) > 0",
+			subject.AppID, segment.TargetUser, strconv.FormatUint(uint64(subject.UserID), 10))
+}
+
+// ExportUserData 导出以该用户为目标的推送和该用户的推送设备
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	var records []model.PushRecord
+	if err := subjectPushes(database, subject).Order("id").Find(&records).Error; err != nil {
+		return err
+	}
+	if err := w.WriteRecords("push_targets", records); err != nil {
+		return err
+	}
+
+	var devices []model.PushDevice
+	if err := database.Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).Order("id").Find(&devices).Error; err != nil {
+		return err
+	}
+	return w.WriteRecords("push_devices", devices)
+}
+
+// EraseUserData 删除该用户的推送设备并从推送目标中移除该用户，目标为空的待发送和定时推送改为已取消
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	res := database.Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).Delete(&model.PushDevice{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	var records []model.PushRecord
+	if err := subjectPushes(database, subject).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		var remaining []uint
+		for _, id := range segment.ParseIDs(r.TargetIDs) {
+			if id != subject.UserID {
+				remaining = append(remaining, id)
+			}
+		}
+		updates := map[string]interface{}{"target_ids": segment.FormatIDs(remaining)}
+		if len(remaining) == 0 && (r.Status == model.PushPending || r.Status == model.PushScheduled) {
+			updates["status"] = model.PushCancelled
+		}
+		if err := database.Unscoped().Model(&model.PushRecord{}).Where("id = ?", r.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return map[string]int64{"push_devices": res.RowsAffected, "push_targets": int64(len(records))}, nil
+}
@@ -0,0 +1,144 @@
+package push
+
+import (
+	"errors"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RegisterDevice 终端登记或刷新当前用户的设备令牌，设备归属SDK签名密钥所属的环境；
+// 令牌轮换时携带 previous_token 移除旧令牌
+func RegisterDevice(c *gin.Context) {
+	var req pusher.DeviceInfo
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if err := req.Normalize(); err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetAppUser(c)
+	device, err := pusher.RegisterDevice(db, user.AppID, env.Code, user.ID, req)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, device)
+}
+
+// UnregisterDevice 终端注销当前用户的设备令牌，例如退出登录或关闭通知时
+func UnregisterDevice(c *gin.Context) {
+	var req struct {
+		Provider string `json:"provider" binding:"required"`
+		Token    string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+	user, _ := middleware.GetAppUser(c)
+	removed, err := pusher.UnregisterDevice(db, user.AppID, env.Code, user.ID, req.Provider, req.Token)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if !removed {
+		response.NotFound(c, "设备不存在")
+		return
+	}
+	response.SuccessWithMessage(c, nil, "设备已注销")
+}
+
+// Devices 查询应用用户的推送设备，可按环境、平台和通道筛选，status 为 active 或 invalid 时按令牌是否失效筛选
+func Devices(c *gin.Context) {
+	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
+	page, size = validator.ValidatePagination(page, size)
+
+	query := repository.FromContext(c, db).Model(&model.PushDevice{})
+	if userID := c.Query("user_id"); userID != "" {
+		if _, err := validator.ValidateID(userID); err != nil {
+			response.ParamError(c, err.Error())
+			return
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+	if env := c.Query("env"); env != "" {
+		query = query.Where("env = ?", env)
+	}
+	if platform := c.Query("platform"); platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	switch c.Query("status") {
+	case "":
+	case "active":
+		query = query.Where("invalidated_at IS NULL")
+	case "invalid":
+		query = query.Where("invalidated_at IS NOT NULL")
+	default:
+		response.ParamError(c, "无效的状态，请使用: active, invalid")
+		return
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	var devices []model.PushDevice
+	if err := query.Offset((page - 1) * size).Limit(size).Order("last_seen_at DESC").Find(&devices).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	response.PageSuccess(c, devices, total, page, size)
+}
+
+// DeleteDevice 删除推送设备，终端再次登记后恢复
+func DeleteDevice(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var device model.PushDevice
+	if err := repo.First(&device, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "设备不存在")
+			return
+		}
+		response.DBError(c, err)
+		return
+	}
+
+	if _, err := repo.Delete(&device); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	response.SuccessWithMessage(c, nil, "设备删除成功")
+}
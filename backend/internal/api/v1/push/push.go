@@ -1,9 +1,17 @@
 package push
 
 import (
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/repository"
 	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/scheduler"
+	"app-platform-backend/internal/segment"
 	"app-platform-backend/internal/validator"
+	"errors"
+	"log"
 	"strings"
 	"time"
 
@@ -19,23 +27,25 @@ func InitDB(database *gorm.DB) {
 
 // List 推送列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	status := c.Query("status")
 	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-
 	// 验证分页参数
 	page, size = validator.ValidatePagination(page, size)
 
-	query := db.Model(&model.PushRecord{}).Where("app_id = ?", appID)
+	query := repository.FromContext(c, db).Model(&model.PushRecord{})
 
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
+	// 周期推送每次发送生成的子推送
+	if parentID := c.Query("parent_id"); parentID != "" {
+		if _, err := validator.ValidateID(parentID); err != nil {
+			response.ParamError(c, err.Error())
+			return
+		}
+		query = query.Where("parent_id = ?", parentID)
+	}
 
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -56,12 +66,14 @@ func List(c *gin.Context) {
 // Create 创建推送任务
 func Create(c *gin.Context) {
 	var req struct {
-		AppID       uint     `json:"app_id" binding:"required"`
-		Title       string   `json:"title" binding:"required"`
-		Content     string   `json:"content" binding:"required"`
-		TargetType  string   `json:"target_type"`
-		TargetIDs   []string `json:"target_ids"`
-		ScheduledAt string   `json:"scheduled_at"`
+		Title       string                 `json:"title"`
+		Content     string                 `json:"content"`
+		TemplateID  uint                   `json:"template_id"` // 使用模板时标题和内容由模板按接收用户和设备语言渲染
+		Variables   map[string]interface{} `json:"variables"`   // 模板变量，按模板声明的类型校验
+		TargetType  string                 `json:"target_type"`
+		TargetIDs   []string               `json:"target_ids"`
+		ScheduledAt string                 `json:"scheduled_at"`
+		Cron        string                 `json:"cron"` // 周期推送，例如每天9点 "0 9 * * *"；同时指定 scheduled_at 时从该时间后开始
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -69,16 +81,28 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	// 验证标题长度
-	if len(req.Title) < 1 || len(req.Title) > 100 {
-		response.ParamError(c, "标题长度应在1-100个字符之间")
-		return
-	}
+	if req.TemplateID != 0 {
+		if req.Title != "" || req.Content != "" {
+			response.ParamError(c, "使用模板时标题和内容由模板渲染，不能同时指定")
+			return
+		}
+	} else {
+		// 验证标题长度
+		if len(req.Title) < 1 || len(req.Title) > 100 {
+			response.ParamError(c, "标题长度应在1-100个字符之间")
+			return
+		}
 
-	// 验证内容长度
-	if len(req.Content) < 1 || len(req.Content) > 1000 {
-		response.ParamError(c, "内容长度应在1-1000个字符之间")
-		return
+		// 验证内容长度
+		if len(req.Content) < 1 || len(req.Content) > 1000 {
+			response.ParamError(c, "内容长度应在1-1000个字符之间")
+			return
+		}
+
+		if len(req.Variables) > 0 {
+			response.ParamError(c, "未使用模板时不能指定模板变量")
+			return
+		}
 	}
 
 	if req.TargetType == "" {
@@ -92,17 +116,48 @@ func Create(c *gin.Context) {
 		return
 	}
 
+	repo := repository.FromContext(c, db)
+
+	// 指定用户、标签或分群时，目标必须属于当前应用
+	targetIDs := segment.ParseIDs(strings.Join(req.TargetIDs, ","))
+	if err := segment.ValidateTarget(db, repo.AppID(), req.TargetType, targetIDs); err != nil {
+		if errors.Is(err, segment.ErrInvalidTarget) {
+			response.ParamError(c, "推送目标不存在或不属于当前应用")
+			return
+		}
+		response.DBError(c, err)
+		return
+	}
+
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return
+	}
+
 	record := model.PushRecord{
-		AppID:      req.AppID,
+		Env:        env.Code,
 		Title:      req.Title,
 		Content:    req.Content,
 		TargetType: req.TargetType,
-		TargetIDs:  strings.Join(req.TargetIDs, ","),
-		Status:     "pending",
+		TargetIDs:  segment.FormatIDs(targetIDs),
+		Status:     model.PushPending,
+	}
+
+	if req.TemplateID != 0 {
+		n, variables, ok := bindTemplate(c, repo.AppID(), req.TemplateID, req.Variables)
+		if !ok {
+			return
+		}
+		title := []rune(n.Title)
+		if len(title) > 255 {
+			title = title[:255]
+		}
+		record.Title, record.Content = string(title), n.Body
+		record.TemplateID, record.Variables = &req.TemplateID, variables
 	}
 
 	if req.ScheduledAt != "" {
-		scheduledTime, err := time.Parse("2006-01-02 15:04:05", req.ScheduledAt)
+		scheduledTime, err := time.ParseInLocation("2006-01-02 15:04:05", req.ScheduledAt, time.Local)
 		if err != nil {
 			response.ParamError(c, "计划发送时间格式错误，请使用: 2006-01-02 15:04:05")
 			return
@@ -112,10 +167,38 @@ func Create(c *gin.Context) {
 			return
 		}
 		record.ScheduledAt = &scheduledTime
+		record.Status = model.PushScheduled
 	}
 
-	if err := db.Create(&record).Error; err != nil {
-		response.DBError(c, err)
+	if req.Cron != "" {
+		schedule, err := pusher.ParseCron(req.Cron)
+		if err != nil {
+			response.ParamError(c, err.Error())
+			return
+		}
+		from := time.Now()
+		if record.ScheduledAt != nil {
+			from = record.ScheduledAt.Add(-time.Minute)
+		}
+		next := schedule.Next(from)
+		if next.IsZero() {
+			response.ParamError(c, "cron 表达式没有可用的发送时间")
+			return
+		}
+		record.Cron = strings.TrimSpace(req.Cron)
+		record.ScheduledAt = &next
+		record.Status = model.PushScheduled
+	}
+
+	// 周期推送本身不发送，每次生成的子推送在调度时计入配额；其余推送写入成功后才计入
+	var consume int64
+	if record.Cron == "" {
+		consume = 1
+	}
+	if err := quota.ConsumeWith(repo.AppID(), quota.MetricPushesPerDay, consume, func(tx *gorm.DB) error {
+		return repo.WithTx(tx).Create(&record)
+	}); err != nil {
+		quota.Reject(c, err)
 		return
 	}
 
@@ -132,8 +215,9 @@ func Detail(c *gin.Context) {
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var record model.PushRecord
-	if err := db.First(&record, id).Error; err != nil {
+	if err := repo.First(&record, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "推送记录不存在")
 			return
@@ -145,7 +229,7 @@ func Detail(c *gin.Context) {
 	response.Success(c, record)
 }
 
-// Send 立即发送推送
+// Send 立即发送推送，认领后交给推送调度器在后台发送，返回202，发送结果写回推送记录
 func Send(c *gin.Context) {
 	id := c.Param("id")
 
@@ -155,8 +239,15 @@ func Send(c *gin.Context) {
 		return
 	}
 
+	dispatcher := scheduler.GetPushDispatchScheduler()
+	if dispatcher == nil || !dispatcher.Running() {
+		response.ServiceUnavailable(c, "推送调度器未运行")
+		return
+	}
+
+	repo := repository.FromContext(c, db)
 	var record model.PushRecord
-	if err := db.First(&record, id).Error; err != nil {
+	if err := repo.First(&record, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "推送记录不存在")
 			return
@@ -165,33 +256,42 @@ func Send(c *gin.Context) {
 		return
 	}
 
-	if record.Status != "pending" {
-		response.ParamError(c, "只有待发送状态的推送可以发送")
+	if record.Cron != "" {
+		response.ParamError(c, "周期推送按计划发送，不能立即发送")
 		return
 	}
 
-	// 模拟发送推送
-	now := time.Now()
-	sentCount := 100
-	successCount := 95
-	failedCount := 5
+	// 认领前检查推送服务配置，未配置或未启用时直接返回，不产生失败的推送记录
+	channels, err := pusher.LoadChannels(db, record.AppID, record.Env)
+	if err != nil {
+		if errors.Is(err, pusher.ErrNotConfigured) {
+			response.ParamError(c, err.Error())
+			return
+		}
+		response.ServerError(c, "加载推送服务配置失败: "+err.Error())
+		return
+	}
+	if !channels.Enabled {
+		response.ParamError(c, pusher.ErrDisabled.Error())
+		return
+	}
 
-	if err := db.Model(&record).Updates(map[string]interface{}{
-		"status":        "sent",
-		"sent_at":       now,
-		"sent_count":    sentCount,
-		"success_count": successCount,
-		"failed_count":  failedCount,
-	}).Error; err != nil {
+	// 条件更新认领推送，与调度器、取消和重复的发送请求互斥
+	claimed, err := pusher.Claim(db, &record)
+	if err != nil {
 		response.DBError(c, err)
 		return
 	}
+	if !claimed {
+		response.ParamError(c, "只有待发送或定时状态的推送可以发送")
+		return
+	}
 
-	response.SuccessWithMessage(c, gin.H{
-		"sent_count":    sentCount,
-		"success_count": successCount,
-		"failed_count":  failedCount,
-	}, "推送发送成功")
+	// 调度器在认领后停止时推送保持发送中，租约过期后由其他实例或重启后接手
+	if !dispatcher.Dispatch(record) {
+		log.Printf("[Push] Push %d of app %d claimed while dispatcher stopped, left for lease takeover", record.ID, record.AppID)
+	}
+	response.Accepted(c, record, "推送已提交发送")
 }
 
 // Cancel 取消推送任务
@@ -204,8 +304,9 @@ func Cancel(c *gin.Context) {
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var record model.PushRecord
-	if err := db.First(&record, id).Error; err != nil {
+	if err := repo.First(&record, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "推送记录不存在")
 			return
@@ -214,17 +315,24 @@ func Cancel(c *gin.Context) {
 		return
 	}
 
-	if record.Status != "pending" {
-		response.ParamError(c, "只有待发送状态的推送可以取消")
+	// 条件更新与调度器认领互斥；发送中的推送在当前批次完成后停止
+	result := repo.Model(&model.PushRecord{}).
+		Where("id = ? AND status IN ?", record.ID, []string{model.PushPending, model.PushScheduled, model.PushSending}).
+		Updates(map[string]interface{}{"status": model.PushCancelled, "lease_until": nil})
+	if result.Error != nil {
+		response.DBError(c, result.Error)
 		return
 	}
-
-	if err := db.Model(&record).Update("status", "cancelled").Error; err != nil {
-		response.DBError(c, err)
+	if result.RowsAffected == 0 {
+		response.ParamError(c, "只有待发送、定时或发送中的推送可以取消")
 		return
 	}
 
-	response.SuccessWithMessage(c, nil, "推送已取消")
+	message := "推送已取消"
+	if record.Status == model.PushSending {
+		message = "推送已取消，已投递的设备无法撤回"
+	}
+	response.SuccessWithMessage(c, nil, message)
 }
 
 // Delete 删除推送记录
@@ -237,8 +345,9 @@ func Delete(c *gin.Context) {
 		return
 	}
 
+	repo := repository.FromContext(c, db)
 	var record model.PushRecord
-	if err := db.First(&record, id).Error; err != nil {
+	if err := repo.First(&record, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "推送记录不存在")
 			return
@@ -247,7 +356,12 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if err := db.Delete(&record).Error; err != nil {
+	if record.Status == model.PushSending {
+		response.ParamError(c, "发送中的推送不能删除，请先取消")
+		return
+	}
+
+	if _, err := repo.Delete(&record); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -257,25 +371,24 @@ func Delete(c *gin.Context) {
 
 // Stats 推送统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
+	repo := repository.FromContext(c, db)
 
-	var total, pending, sent, cancelled int64
+	var total, pending, scheduled, sending, sent, failed, cancelled int64
 	var totalSent, totalSuccess, totalFailed int64
 
-	db.Model(&model.PushRecord{}).Where("app_id = ?", appID).Count(&total)
-	db.Model(&model.PushRecord{}).Where("app_id = ? AND status = ?", appID, "pending").Count(&pending)
-	db.Model(&model.PushRecord{}).Where("app_id = ? AND status = ?", appID, "sent").Count(&sent)
-	db.Model(&model.PushRecord{}).Where("app_id = ? AND status = ?", appID, "cancelled").Count(&cancelled)
+	repo.Model(&model.PushRecord{}).Count(&total)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushPending).Count(&pending)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushScheduled).Count(&scheduled)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushSending).Count(&sending)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushSent).Count(&sent)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushFailed).Count(&failed)
+	repo.Model(&model.PushRecord{}).Where("status = ?", model.PushCancelled).Count(&cancelled)
 
-	db.Model(&model.PushRecord{}).Where("app_id = ?", appID).
+	repo.Model(&model.PushRecord{}).
 		Select("COALESCE(SUM(sent_count), 0)").Scan(&totalSent)
-	db.Model(&model.PushRecord{}).Where("app_id = ?", appID).
+	repo.Model(&model.PushRecord{}).
 		Select("COALESCE(SUM(success_count), 0)").Scan(&totalSuccess)
-	db.Model(&model.PushRecord{}).Where("app_id = ?", appID).
+	repo.Model(&model.PushRecord{}).
 		Select("COALESCE(SUM(failed_count), 0)").Scan(&totalFailed)
 
 	successRate := float64(0)
@@ -286,7 +399,10 @@ func Stats(c *gin.Context) {
 	response.Success(c, gin.H{
 		"total":         total,
 		"pending":       pending,
+		"scheduled":     scheduled,
+		"sending":       sending,
 		"sent":          sent,
+		"failed":        failed,
 		"cancelled":     cancelled,
 		"total_sent":    totalSent,
 		"total_success": totalSuccess,
@@ -300,11 +416,7 @@ func Tasks(c *gin.Context) {
 	List(c)
 }
 
-// Templates 推送模板（兼容旧接口）
-func Templates(c *gin.Context) {
-	response.Success(c, []gin.H{
-		{"id": 1, "name": "系统通知", "title_template": "【系统通知】{{title}}", "content_template": "{{content}}"},
-		{"id": 2, "name": "活动推送", "title_template": "【活动】{{title}}", "content_template": "{{content}}，点击查看详情"},
-		{"id": 3, "name": "订单通知", "title_template": "订单{{order_id}}状态更新", "content_template": "您的订单{{order_id}}{{status}}"},
-	})
+// Providers 支持的推送通道，凭证在推送服务模块配置的 providers 中按通道名称填写
+func Providers(c *gin.Context) {
+	response.Success(c, pusher.Names())
 }
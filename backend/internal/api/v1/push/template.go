@@ -0,0 +1,283 @@
+package push
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pusher"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TemplateRequest 创建或更新推送模板请求参数，标题、正文、数据、变量和语言版本见 pusher.Template
+type TemplateRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description" binding:"max=255"`
+	pusher.Template
+}
+
+// PreviewTemplateRequest 预览推送模板请求参数
+type PreviewTemplateRequest struct {
+	Variables map[string]interface{} `json:"variables"`
+	UserID    uint                   `json:"user_id"` // 按该用户的属性渲染，为空时用户属性变量取默认值
+	Locale    string                 `json:"locale"`  // 为空时取该用户最近活跃设备的语言
+}
+
+// ListTemplates 推送模板列表
+func ListTemplates(c *gin.Context) {
+	var templates []model.PushTemplate
+	if err := repository.FromContext(c, db).Model(&model.PushTemplate{}).
+		Order("id DESC").Find(&templates).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, templates)
+}
+
+// GetTemplate 推送模板详情
+func GetTemplate(c *gin.Context) {
+	tpl, ok := loadTemplate(c)
+	if !ok {
+		return
+	}
+	response.Success(c, tpl)
+}
+
+// CreateTemplate 创建推送模板
+func CreateTemplate(c *gin.Context) {
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	repo := repository.FromContext(c, db)
+	tpl := model.PushTemplate{Description: req.Description, CreatedBy: c.GetUint("user_id")}
+	if !validateTemplateRequest(c, repo, &req, &tpl) {
+		return
+	}
+
+	if err := repo.Create(&tpl); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "create", "push_template", strconv.Itoa(int(tpl.ID)), "创建推送模板", gin.H{
+		"app_id": tpl.AppID,
+		"name":   tpl.Name,
+	})
+	response.SuccessWithMessage(c, tpl, "推送模板创建成功")
+}
+
+// UpdateTemplate 更新推送模板，引用该模板且尚未发送的推送按新内容渲染
+func UpdateTemplate(c *gin.Context) {
+	tpl, ok := loadTemplate(c)
+	if !ok {
+		return
+	}
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	repo := repository.FromContext(c, db)
+	if !validateTemplateRequest(c, repo, &req, tpl) {
+		return
+	}
+	tpl.Description = req.Description
+
+	if err := repo.Updates(tpl, map[string]interface{}{
+		"name":        tpl.Name,
+		"description": tpl.Description,
+		"title":       tpl.Title,
+		"body":        tpl.Body,
+		"data":        tpl.Data,
+		"variables":   tpl.Variables,
+		"locales":     tpl.Locales,
+	}); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "update", "push_template", strconv.Itoa(int(tpl.ID)), "更新推送模板", gin.H{
+		"app_id": tpl.AppID,
+		"name":   tpl.Name,
+	})
+	response.SuccessWithMessage(c, tpl, "推送模板更新成功")
+}
+
+// DeleteTemplate 删除推送模板，有待发送、定时或发送中的推送引用时不能删除
+func DeleteTemplate(c *gin.Context) {
+	tpl, ok := loadTemplate(c)
+	if !ok {
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var count int64
+	if err := repo.Model(&model.PushRecord{}).
+		Where("template_id = ? AND status IN ?", tpl.ID, []string{model.PushPending, model.PushScheduled, model.PushSending}).
+		Count(&count).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if count > 0 {
+		response.Conflict(c, "有未发送的推送引用该模板，请先取消或等待发送完成")
+		return
+	}
+
+	if _, err := repo.Delete(tpl); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "delete", "push_template", strconv.Itoa(int(tpl.ID)), "删除推送模板", gin.H{
+		"app_id": tpl.AppID,
+		"name":   tpl.Name,
+	})
+	response.SuccessWithMessage(c, nil, "推送模板删除成功")
+}
+
+// PreviewTemplate 按变量、用户属性和语言渲染推送模板，不发送
+func PreviewTemplate(c *gin.Context) {
+	m, ok := loadTemplate(c)
+	if !ok {
+		return
+	}
+	var req PreviewTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	tpl, err := pusher.FromModel(m)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	params, err := tpl.Bind(req.Variables)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var recipient *pusher.Recipient
+	if req.UserID != 0 {
+		var user model.User
+		if err := repo.First(&user, req.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				response.NotFound(c, "用户不存在")
+				return
+			}
+			response.DBError(c, err)
+			return
+		}
+		recipients, err := pusher.LoadRecipients(db, repo.AppID(), []uint{user.ID})
+		if err != nil {
+			response.DBError(c, err)
+			return
+		}
+		recipient = recipients[user.ID]
+
+		if req.Locale == "" {
+			var device model.PushDevice
+			err := repo.Model(&model.PushDevice{}).
+				Where("user_id = ? AND invalidated_at IS NULL", user.ID).
+				Order("last_seen_at DESC").Limit(1).Find(&device).Error
+			if err != nil {
+				response.DBError(c, err)
+				return
+			}
+			req.Locale = device.Locale
+		}
+	}
+
+	n := tpl.Render(params, recipient, req.Locale)
+	response.Success(c, gin.H{
+		"title":  n.Title,
+		"body":   n.Body,
+		"data":   n.Data,
+		"locale": req.Locale,
+	})
+}
+
+// loadTemplate 加载路由中的推送模板，不存在时写入响应
+func loadTemplate(c *gin.Context) (*model.PushTemplate, bool) {
+	id, err := validator.ValidateID(c.Param("template_id"))
+	if err != nil {
+		response.ParamError(c, "无效的模板ID")
+		return nil, false
+	}
+	var tpl model.PushTemplate
+	if err := repository.FromContext(c, db).First(&tpl, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "推送模板不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &tpl, true
+}
+
+// validateTemplateRequest 校验模板名称和内容，名称在应用内唯一，校验通过后写入 tpl
+func validateTemplateRequest(c *gin.Context, repo *repository.AppScoped, req *TemplateRequest, tpl *model.PushTemplate) bool {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > 100 {
+		response.ParamError(c, "模板名称长度应在1-100个字符之间")
+		return false
+	}
+	if err := req.Template.Validate(); err != nil {
+		response.ParamError(c, err.Error())
+		return false
+	}
+
+	var count int64
+	if err := repo.Model(&model.PushTemplate{}).Where("name = ? AND id <> ?", name, tpl.ID).Count(&count).Error; err != nil {
+		response.DBError(c, err)
+		return false
+	}
+	if count > 0 {
+		response.Conflict(c, "模板名称已存在")
+		return false
+	}
+
+	if err := req.Template.ToModel(tpl); err != nil {
+		response.ServerError(c, "模板序列化失败")
+		return false
+	}
+	tpl.Name = name
+	return true
+}
+
+// bindTemplate 校验推送引用的模板和变量，返回不区分用户和语言的默认渲染结果和规范化后的变量JSON
+func bindTemplate(c *gin.Context, appID, templateID uint, variables map[string]interface{}) (*pusher.Notification, string, bool) {
+	_, tpl, err := pusher.LoadTemplate(db, appID, templateID)
+	if err != nil {
+		if errors.Is(err, pusher.ErrTemplateNotFound) {
+			response.ParamError(c, "推送模板不存在或不属于当前应用")
+			return nil, "", false
+		}
+		response.DBError(c, err)
+		return nil, "", false
+	}
+	params, err := tpl.Bind(variables)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return nil, "", false
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		response.ServerError(c, "模板变量序列化失败")
+		return nil, "", false
+	}
+	return tpl.Render(params, nil, ""), string(data), true
+}
@@ -1,40 +1,80 @@
 package stats
 
 import (
-	"net/http"
+	"strconv"
 
-	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/dashboard"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/response"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+)
+
+// appRollupLimit 应用排行默认和最多返回的应用数
+const (
+	appRollupLimit    = 10
+	appRollupMaxLimit = 100
 )
 
 type StatsHandler struct {
-	db *gorm.DB
+	svc *dashboard.Service
 }
 
-func NewStatsHandler(db *gorm.DB) *StatsHandler {
-	return &StatsHandler{db: db}
+func NewStatsHandler(svc *dashboard.Service) *StatsHandler {
+	return &StatsHandler{svc: svc}
 }
 
+// GetStats 平台总览，只统计当前管理员所属组织的应用
 func (h *StatsHandler) GetStats(c *gin.Context) {
-	var appCount int64
-	h.db.Model(&model.App{}).Where("status = 1").Count(&appCount)
-
-	var moduleCount int64
-	h.db.Model(&model.ModuleTemplate{}).Where("status = 1").Count(&moduleCount)
-
-	var activeAppCount int64
-	h.db.Model(&model.App{}).Where("status = 1").Count(&activeAppCount)
-
-	c.JSON(http.StatusOK, gin.H{
-		"code": 0,
-		"data": gin.H{
-			"app_count":        appCount,
-			"user_count":       5000,
-			"today_new":        0,
-			"active_app_count": activeAppCount,
-			"module_count":     moduleCount,
-		},
-	})
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	overview, err := h.svc.Overview(orgIDs)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, overview)
+}
+
+// GetTrends 最近若干天（默认30天）的每日新增用户、事件、错误和推送趋势
+func (h *StatsHandler) GetTrends(c *gin.Context) {
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(h.svc.MaxDays())))
+	trends, err := h.svc.Trends(orgIDs, days)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, trends)
+}
+
+// GetAppRollups 各应用的用户、模块、事件、错误和存储汇总，按区间内事件数降序
+func (h *StatsHandler) GetAppRollups(c *gin.Context) {
+	orgIDs, err := middleware.VisibleOrgIDs(c)
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(h.svc.MaxDays())))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(appRollupLimit)))
+	if limit <= 0 || limit > appRollupMaxLimit {
+		limit = appRollupLimit
+	}
+
+	rollups, err := h.svc.AppRollups(orgIDs, days, limit)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, rollups)
 }
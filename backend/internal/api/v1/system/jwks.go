@@ -0,0 +1,16 @@
+package system
+
+import (
+	"net/http"
+
+	"app-platform-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler 发布会话令牌的校验公钥（RFC 7517），供其他服务离线校验令牌
+// 使用标准 JWKS 格式，不包装统一响应结构
+func JWKSHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": middleware.JWTPublicKeys()})
+}
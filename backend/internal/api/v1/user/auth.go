@@ -0,0 +1,229 @@
+package user
+
+import (
+	"errors"
+	"log"
+
+	"app-platform-backend/internal/appauth"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SendCodeRequest 发送验证码请求参数
+type SendCodeRequest struct {
+	Target  string `json:"target" binding:"required"` // 邮箱或手机号
+	Purpose string `json:"purpose" binding:"required,oneof=login register reset_password"`
+}
+
+// RegisterRequest 密码注册请求参数
+type RegisterRequest struct {
+	Target   string `json:"target" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"` // 配置要求验证时必填
+	Nickname string `json:"nickname" binding:"max=255"`
+}
+
+// LoginRequest 密码登录请求参数
+type LoginRequest struct {
+	Target   string `json:"target" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// CodeLoginRequest 验证码登录请求参数
+type CodeLoginRequest struct {
+	Target string `json:"target" binding:"required"`
+	Code   string `json:"code" binding:"required"`
+}
+
+// RefreshRequest 刷新令牌请求参数
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ResetPasswordRequest 重置密码请求参数
+type ResetPasswordRequest struct {
+	Target      string `json:"target" binding:"required"`
+	Code        string `json:"code" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// authResult 登录成功的响应
+type authResult struct {
+	User *model.User `json:"user"`
+	*appauth.Tokens
+}
+
+// SendCode 向邮箱或手机号发送一次性验证码
+func SendCode(c *gin.Context) {
+	var req SendCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	target, err := appauth.ParseTarget(req.Target)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	if err := appauth.SendCode(c.Request.Context(), app, target, req.Purpose); err != nil {
+		authError(c, err)
+		return
+	}
+	response.SuccessWithMessage(c, nil, "验证码已发送")
+}
+
+// Register 邮箱或手机号+密码注册，注册成功后直接登录
+func Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	target, err := appauth.ParseTarget(req.Target)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	user, err := appauth.Register(app.ID, target, req.Password, req.Code, req.Nickname)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	issueSession(c, user)
+}
+
+// Login 邮箱或手机号+密码登录
+func Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	target, err := appauth.ParseTarget(req.Target)
+	if err != nil {
+		authError(c, appauth.ErrInvalidCredentials)
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	user, err := appauth.Login(app.ID, target, req.Password)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	issueSession(c, user)
+}
+
+// LoginByCode 验证码登录，账号不存在时自动注册
+func LoginByCode(c *gin.Context) {
+	var req CodeLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	target, err := appauth.ParseTarget(req.Target)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	user, _, err := appauth.LoginByCode(app.ID, target, req.Code)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	issueSession(c, user)
+}
+
+// Refresh 使用刷新令牌换取新的令牌
+func Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	tokens, user, err := appauth.Refresh(app.ID, req.RefreshToken)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+	response.Success(c, authResult{User: user, Tokens: tokens})
+}
+
+// Logout 注销当前会话
+func Logout(c *gin.Context) {
+	session, _ := middleware.GetAppUserSession(c)
+	if err := appauth.Revoke(session.ID); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.SuccessWithMessage(c, nil, "已退出登录")
+}
+
+// ResetPassword 通过验证码重置密码，该用户的全部会话随之失效
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	target, err := appauth.ParseTarget(req.Target)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+
+	app, _ := middleware.GetClientApp(c)
+	if err := appauth.ResetPassword(app.ID, target, req.Code, req.NewPassword); err != nil {
+		authError(c, err)
+		return
+	}
+	response.SuccessWithMessage(c, nil, "密码已重置，请重新登录")
+}
+
+// Me 当前登录的终端用户
+func Me(c *gin.Context) {
+	user, _ := middleware.GetAppUser(c)
+	response.Success(c, user)
+}
+
+// issueSession 为登录成功的用户签发令牌
+func issueSession(c *gin.Context, user *model.User) {
+	tokens, err := appauth.IssueSession(user, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, authResult{User: user, Tokens: tokens})
+}
+
+// authError 将认证错误转换为响应
+func authError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, appauth.ErrInvalidTarget), errors.Is(err, appauth.ErrInvalidPassword),
+		errors.Is(err, appauth.ErrInvalidPurpose):
+		response.ParamError(c, err.Error())
+	case errors.Is(err, appauth.ErrInvalidCredentials), errors.Is(err, appauth.ErrCodeInvalid),
+		errors.Is(err, appauth.ErrInvalidToken):
+		response.Unauthorized(c, err.Error())
+	case errors.Is(err, appauth.ErrUserDisabled):
+		response.Forbidden(c, err.Error())
+	case errors.Is(err, appauth.ErrUserExists):
+		response.Conflict(c, err.Error())
+	case errors.Is(err, appauth.ErrCodeTooFrequent):
+		response.TooManyRequests(c, err.Error())
+	default:
+		log.Printf("[UserAuth] %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		response.ServerError(c, "认证服务暂时不可用")
+	}
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/userban"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BanRequest 封禁请求参数，banned_until 为空表示永久封禁
+type BanRequest struct {
+	Reason      string `json:"reason"`
+	BannedUntil string `json:"banned_until"` // 格式 2006-01-02 15:04:05
+}
+
+// Ban 封禁应用用户：拒绝SDK请求、断开WebSocket连接、丢弃其事件、标记其日志，并排除在推送和消息之外
+func Ban(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, "无效的用户ID")
+		return
+	}
+	var req BanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	var until *time.Time
+	if req.BannedUntil != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", req.BannedUntil, time.Local)
+		if err != nil {
+			response.ParamError(c, "封禁到期时间格式错误，请使用: 2006-01-02 15:04:05")
+			return
+		}
+		until = &t
+	}
+
+	user, err := userban.Ban(middleware.ScopedAppID(c), id, until, req.Reason)
+	if err != nil {
+		banError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "ban", "app_user", strconv.Itoa(int(user.ID)), "封禁应用用户", gin.H{
+		"app_id":       user.AppID,
+		"open_id":      user.OpenID,
+		"reason":       req.Reason,
+		"banned_until": until,
+	})
+	response.SuccessWithMessage(c, user, "用户已封禁")
+}
+
+// Unban 解除封禁
+func Unban(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, "无效的用户ID")
+		return
+	}
+	user, err := userban.Unban(middleware.ScopedAppID(c), id)
+	if err != nil {
+		banError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "unban", "app_user", strconv.Itoa(int(user.ID)), "解除应用用户封禁", gin.H{
+		"app_id":  user.AppID,
+		"open_id": user.OpenID,
+	})
+	response.SuccessWithMessage(c, user, "已解除封禁")
+}
+
+// banError 将封禁错误转换为响应
+func banError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, userban.ErrUserNotFound):
+		response.NotFound(c, err.Error())
+	case errors.Is(err, userban.ErrInvalidExpiry), errors.Is(err, userban.ErrReasonTooLong):
+		response.ParamError(c, err.Error())
+	default:
+		response.DBError(c, err)
+	}
+}
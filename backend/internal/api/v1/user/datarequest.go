@@ -0,0 +1,229 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/datasubject"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DataRequestCreateRequest 创建数据主体请求参数
+type DataRequestCreateRequest struct {
+	Type string `json:"type" binding:"required,oneof=export erase"`
+}
+
+// DataRequestListRequest 数据主体请求列表参数
+type DataRequestListRequest struct {
+	Page   int    `form:"page"`
+	Size   int    `form:"size"`
+	UserID uint   `form:"user_id"`
+	Type   string `form:"type"`
+	Status string `form:"status"`
+}
+
+// CreateDataRequest 为用户创建数据导出或删除请求，由后台任务执行，需要所属组织的管理员权限
+func CreateDataRequest(c *gin.Context) {
+	if !requireDataRequestRole(c) {
+		return
+	}
+	user, ok := loadUser(c)
+	if !ok {
+		return
+	}
+	var req DataRequestCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var active int64
+	if err := repo.Model(&model.DataSubjectRequest{}).
+		Where("user_id = ? AND type = ? AND status IN ?", user.ID, req.Type,
+			[]string{model.DataRequestPending, model.DataRequestRunning}).
+		Count(&active).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if active > 0 {
+		response.Conflict(c, "该用户已有进行中的同类请求")
+		return
+	}
+
+	subject, err := datasubject.LoadSubject(db, user.AppID, user.ID)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	snapshot, err := datasubject.EncodeSubject(subject)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	request := model.DataSubjectRequest{
+		UserID:      user.ID,
+		Type:        req.Type,
+		Status:      model.DataRequestPending,
+		Subject:     snapshot,
+		Report:      "{}",
+		RequestedBy: c.GetUint("user_id"),
+	}
+	if err := repo.Create(&request); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "data_request_"+req.Type, "app_user", strconv.Itoa(int(user.ID)),
+		"创建用户数据"+dataRequestTypeName(req.Type)+"请求", gin.H{
+			"request_id": request.ID,
+			"open_id":    user.OpenID,
+		})
+	response.SuccessWithMessage(c, request, "请求已创建，将在后台执行")
+}
+
+// ListDataRequests 数据主体请求列表，需要所属组织的管理员权限
+func ListDataRequests(c *gin.Context) {
+	if !requireDataRequestRole(c) {
+		return
+	}
+	var req DataRequestListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.Page, req.Size = validator.ValidatePagination(req.Page, req.Size)
+
+	query := repository.FromContext(c, db).Model(&model.DataSubjectRequest{})
+	if req.UserID > 0 {
+		query = query.Where("user_id = ?", req.UserID)
+	}
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	var requests []model.DataSubjectRequest
+	offset := (req.Page - 1) * req.Size
+	if err := query.Offset(offset).Limit(req.Size).Order("id DESC").Find(&requests).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.PageSuccess(c, requests, total, req.Page, req.Size)
+}
+
+// GetDataRequest 数据主体请求详情，包含报告及其摘要，需要所属组织的管理员权限
+func GetDataRequest(c *gin.Context) {
+	request, ok := loadDataRequest(c)
+	if !ok {
+		return
+	}
+	response.Success(c, request)
+}
+
+// DownloadDataRequest 下载导出归档，归档过期后删除不可再下载，需要所属组织的管理员权限
+func DownloadDataRequest(c *gin.Context) {
+	request, ok := loadDataRequest(c)
+	if !ok {
+		return
+	}
+	if request.Type != model.DataRequestExport || request.Status != model.DataRequestCompleted {
+		response.Conflict(c, "导出尚未完成")
+		return
+	}
+	if request.ArchivePath == "" || (request.ExpiresAt != nil && time.Now().After(*request.ExpiresAt)) {
+		response.NotFound(c, "导出归档已过期")
+		return
+	}
+	if _, err := os.Stat(request.ArchivePath); err != nil {
+		response.NotFound(c, "导出归档已过期")
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "data_request_download", "app_user", strconv.Itoa(int(request.UserID)),
+		"下载用户数据导出归档", gin.H{"request_id": request.ID})
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user_%d_export_%d.zip", request.UserID, request.ID))
+	c.Header("Content-Type", "application/zip")
+	c.File(request.ArchivePath)
+}
+
+// loadDataRequest 按路径参数加载当前应用的数据主体请求，先校验组织角色
+func loadDataRequest(c *gin.Context) (*model.DataSubjectRequest, bool) {
+	if !requireDataRequestRole(c) {
+		return nil, false
+	}
+	id, err := validator.ValidateID(c.Param("request_id"))
+	if err != nil {
+		response.ParamError(c, "无效的请求ID")
+		return nil, false
+	}
+	var request model.DataSubjectRequest
+	if err := repository.FromContext(c, db).First(&request, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "请求不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &request, true
+}
+
+// requireDataRequestRole 数据主体请求涉及用户的全部个人数据，创建和查看都需要应用所属组织的管理员权限
+func requireDataRequestRole(c *gin.Context) bool {
+	app, ok := middleware.GetScopedApp(c)
+	if !ok {
+		response.Forbidden(c, "缺少应用上下文")
+		return false
+	}
+	return middleware.RequireOrgRole(c, app.OrgID, model.OrgRoleAdmin)
+}
+
+// PurgeDataRequestArchives 清理已删除应用中带导出归档的一批数据请求：先删归档文件再删记录
+// 返回0表示已没有归档，其余记录由模块的 PurgeAppData 按批删除
+func PurgeDataRequestArchives(database *gorm.DB, appID uint, batchSize int) (int64, error) {
+	repo := repository.ForApp(database, appID)
+
+	var requests []model.DataSubjectRequest
+	if err := database.Scopes(repo.Scope).Where("archive_path <> ''").
+		Order("id").Limit(batchSize).Find(&requests).Error; err != nil {
+		return 0, err
+	}
+	if len(requests) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(requests))
+	for i, r := range requests {
+		if err := os.Remove(r.ArchivePath); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		ids[i] = r.ID
+	}
+	result := database.Delete(&model.DataSubjectRequest{}, ids)
+	return result.RowsAffected, result.Error
+}
+
+func dataRequestTypeName(t string) string {
+	if t == model.DataRequestErase {
+		return "删除"
+	}
+	return "导出"
+}
@@ -0,0 +1,117 @@
+package user
+
+import (
+	"fmt"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ExportUserData 导出用户记录、自定义属性、匿名设备、登录会话、标签和分群成员关系以及验证码记录
+func ExportUserData(database *gorm.DB, subject module.DataSubject, w module.UserDataWriter) error {
+	var users []model.User
+	if err := database.Where("app_id = ? AND id = ?", subject.AppID, subject.UserID).Find(&users).Error; err != nil {
+		return err
+	}
+	if err := w.WriteRecords("user", users); err != nil {
+		return err
+	}
+
+	byUser := database.Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).Order("id")
+	sets := []struct {
+		name    string
+		records interface{}
+	}{
+		{"properties", &[]model.UserProfile{}},
+		{"anonymous_profiles", &[]model.AnonymousProfile{}},
+		{"sessions", &[]model.UserSession{}},
+		{"tags", &[]model.UserTagMember{}},
+		{"segments", &[]model.UserSegmentMember{}},
+	}
+	for _, set := range sets {
+		if err := byUser.Session(&gorm.Session{}).Find(set.records).Error; err != nil {
+			return fmt.Errorf("%s: %w", set.name, err)
+		}
+		if err := w.WriteRecords(set.name, set.records); err != nil {
+			return err
+		}
+	}
+
+	codes := []model.UserVerifyCode{}
+	if targets := verifyTargets(subject); len(targets) > 0 {
+		if err := database.Where("app_id = ? AND target IN ?", subject.AppID, targets).
+			Order("id").Find(&codes).Error; err != nil {
+			return err
+		}
+	}
+	return w.WriteRecords("verify_codes", codes)
+}
+
+// EraseUserData 删除用户的属性、匿名设备、会话、标签和分群成员关系及验证码，
+// 用户记录清空个人信息后软删除，open_id 改写以释放原标识
+func EraseUserData(database *gorm.DB, subject module.DataSubject) (map[string]int64, error) {
+	counts := map[string]int64{}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		byUser := []struct {
+			name  string
+			model interface{}
+		}{
+			{"properties", &model.UserProfile{}},
+			{"anonymous_profiles", &model.AnonymousProfile{}},
+			{"sessions", &model.UserSession{}},
+			{"tags", &model.UserTagMember{}},
+			{"segments", &model.UserSegmentMember{}},
+		}
+		for _, set := range byUser {
+			result := tx.Where("app_id = ? AND user_id = ?", subject.AppID, subject.UserID).Delete(set.model)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", set.name, result.Error)
+			}
+			counts[set.name] = result.RowsAffected
+		}
+
+		if targets := verifyTargets(subject); len(targets) > 0 {
+			result := tx.Where("app_id = ? AND target IN ?", subject.AppID, targets).Delete(&model.UserVerifyCode{})
+			if result.Error != nil {
+				return result.Error
+			}
+			counts["verify_codes"] = result.RowsAffected
+		}
+
+		result := tx.Unscoped().Model(&model.User{}).
+			Where("app_id = ? AND id = ?", subject.AppID, subject.UserID).
+			Updates(map[string]interface{}{
+				"open_id":       fmt.Sprintf("erased_%d", subject.UserID),
+				"nickname":      "",
+				"avatar":        "",
+				"phone":         "",
+				"email":         "",
+				"password_hash": "",
+				"status":        model.UserStatusDisabled,
+				"last_login_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		counts["user"] = result.RowsAffected
+		return tx.Where("app_id = ? AND id = ?", subject.AppID, subject.UserID).Delete(&model.User{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// verifyTargets 用户的邮箱和手机号，验证码按发送目标保存
+func verifyTargets(subject module.DataSubject) []string {
+	var targets []string
+	if subject.Email != "" {
+		targets = append(targets, subject.Email)
+	}
+	if subject.Phone != "" {
+		targets = append(targets, subject.Phone)
+	}
+	return targets
+}
@@ -0,0 +1,348 @@
+package user
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/userimport"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// importDir 导入文件和错误报告的存储目录，由 user_import.path 配置
+var importDir = "./uploads/user-imports"
+
+// 导入文件大小上限
+const maxImportFileSize = 50 * 1024 * 1024
+
+// exportBatchSize 导出时每批读取的用户数
+const exportBatchSize = 1000
+
+// exportColumns 导出的列，与导入字段同名，导出的文件可直接导入
+var exportColumns = []string{"id", "open_id", "nickname", "avatar", "phone", "email", "status", "source", "created_at", "last_login_at"}
+
+// ImportJobListRequest 导入任务列表参数
+type ImportJobListRequest struct {
+	Page   int    `form:"page"`
+	Size   int    `form:"size"`
+	Status string `form:"status"`
+}
+
+// InitImport 加载导入配置
+func InitImport(cfg *config.UserImportConfig) {
+	if cfg.Path != "" {
+		importDir = cfg.Path
+	}
+}
+
+// Import 上传 CSV 或 NDJSON 文件创建导入任务，由后台任务执行
+// 表单参数：file、format（默认按扩展名）、mapping（目标字段到列名的JSON）、on_conflict（skip/update）、dry_run
+func Import(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.ParamError(c, "请选择要导入的文件")
+		return
+	}
+	if header.Size > maxImportFileSize {
+		response.ParamError(c, fmt.Sprintf("文件大小不能超过 %dMB", maxImportFileSize/1024/1024))
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+		if format == "jsonl" {
+			format = userimport.FormatNDJSON
+		}
+	}
+	if format != userimport.FormatCSV && format != userimport.FormatNDJSON {
+		response.ParamError(c, "文件格式只支持 csv 和 ndjson")
+		return
+	}
+	mapping, err := userimport.ParseMapping(c.PostForm("mapping"))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return
+	}
+	onConflict := c.DefaultPostForm("on_conflict", userimport.OnConflictSkip)
+	if onConflict != userimport.OnConflictSkip && onConflict != userimport.OnConflictUpdate {
+		response.ParamError(c, "on_conflict 只能是 skip 或 update")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+
+	repo := repository.FromContext(c, db)
+	dir := filepath.Join(importDir, fmt.Sprintf("app_%d", repo.AppID()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		response.ServerError(c, "创建目录失败")
+		return
+	}
+	base := strconv.FormatInt(time.Now().UnixNano(), 36)
+	filePath := filepath.Join(dir, base+"."+format)
+	if err := c.SaveUploadedFile(header, filePath); err != nil {
+		response.ServerError(c, "保存文件失败")
+		return
+	}
+
+	mappingJSON, _ := json.Marshal(mapping)
+	job := model.UserImportJob{
+		Filename:        filepath.Base(header.Filename),
+		Format:          format,
+		Mapping:         string(mappingJSON),
+		OnConflict:      onConflict,
+		DryRun:          dryRun,
+		FilePath:        filePath,
+		Status:          model.UserImportPending,
+		ErrorReportPath: filepath.Join(dir, base+".errors.csv"),
+		CreatedBy:       c.GetUint("user_id"),
+	}
+	if err := repo.Create(&job); err != nil {
+		os.Remove(filePath)
+		response.DBError(c, err)
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "import", "app_user", strconv.Itoa(int(job.ID)), "批量导入应用用户", gin.H{
+		"filename": job.Filename,
+		"format":   job.Format,
+		"dry_run":  job.DryRun,
+	})
+	response.SuccessWithMessage(c, job, "导入任务已创建，将在后台执行")
+}
+
+// ListImportJobs 导入任务列表
+func ListImportJobs(c *gin.Context) {
+	var req ImportJobListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.Page, req.Size = validator.ValidatePagination(req.Page, req.Size)
+
+	query := repository.FromContext(c, db).Model(&model.UserImportJob{})
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	var jobs []model.UserImportJob
+	offset := (req.Page - 1) * req.Size
+	if err := query.Offset(offset).Limit(req.Size).Order("id DESC").Find(&jobs).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.PageSuccess(c, jobs, total, req.Page, req.Size)
+}
+
+// GetImportJob 导入任务详情和进度
+func GetImportJob(c *gin.Context) {
+	job, ok := loadImportJob(c)
+	if !ok {
+		return
+	}
+	response.Success(c, job)
+}
+
+// DownloadImportErrors 下载出错行的 CSV 报告
+func DownloadImportErrors(c *gin.Context) {
+	job, ok := loadImportJob(c)
+	if !ok {
+		return
+	}
+	if job.ErrorReportPath == "" {
+		response.NotFound(c, "错误报告已过期")
+		return
+	}
+	if _, err := os.Stat(job.ErrorReportPath); err != nil {
+		response.NotFound(c, "错误报告不存在")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=user_import_%d_errors.csv", job.ID))
+	c.Header("Content-Type", "text/csv")
+	c.File(job.ErrorReportPath)
+}
+
+// Export 按列表筛选条件流式导出用户，format 为 csv（默认）或 ndjson，ndjson 包含自定义属性
+func Export(c *gin.Context) {
+	var req ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	format := c.DefaultQuery("format", userimport.FormatCSV)
+	if format != userimport.FormatCSV && format != userimport.FormatNDJSON {
+		response.ParamError(c, "文件格式只支持 csv 和 ndjson")
+		return
+	}
+
+	appID := middleware.ScopedAppID(c)
+	middleware.RecordAuditEvent(c, "export", "app_user", strconv.Itoa(int(appID)), "导出应用用户", gin.H{
+		"format": format,
+		"status": req.Status,
+		"source": req.Source,
+		"search": req.Search,
+	})
+
+	filename := fmt.Sprintf("users_%d_%s.%s", appID, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if format == userimport.FormatCSV {
+		c.Header("Content-Type", "text/csv")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if format == userimport.FormatCSV {
+		csvWriter = csv.NewWriter(c.Writer)
+		csvWriter.Write(exportColumns)
+	} else {
+		jsonEncoder = json.NewEncoder(c.Writer)
+	}
+
+	var lastID uint
+	for {
+		var users []model.User
+		if err := listQuery(c, &req).Where("id > ?", lastID).Order("id").Limit(exportBatchSize).Find(&users).Error; err != nil {
+			// 响应已开始写出，只能中断
+			log.Printf("[UserAPI] Export users for app %d failed: %v", appID, err)
+			return
+		}
+		if len(users) == 0 {
+			break
+		}
+		if csvWriter != nil {
+			for i := range users {
+				csvWriter.Write(exportRow(&users[i]))
+			}
+			csvWriter.Flush()
+		} else {
+			props, err := batchProperties(appID, users)
+			if err != nil {
+				log.Printf("[UserAPI] Export user properties for app %d failed: %v", appID, err)
+				return
+			}
+			for i := range users {
+				jsonEncoder.Encode(exportObject(&users[i], props[users[i].ID]))
+			}
+		}
+		c.Writer.Flush()
+		if len(users) < exportBatchSize {
+			break
+		}
+		lastID = users[len(users)-1].ID
+	}
+}
+
+// exportRow 用户的 CSV 行，列顺序同 exportColumns
+func exportRow(u *model.User) []string {
+	lastLogin := ""
+	if u.LastLoginAt != nil {
+		lastLogin = u.LastLoginAt.Format("2006-01-02 15:04:05")
+	}
+	return []string{
+		strconv.Itoa(int(u.ID)), u.OpenID, u.Nickname, u.Avatar, u.Phone, u.Email,
+		strconv.Itoa(u.Status), u.Source, u.CreatedAt.Format("2006-01-02 15:04:05"), lastLogin,
+	}
+}
+
+// exportObject 用户的 NDJSON 对象，字段同 exportColumns，另含自定义属性
+func exportObject(u *model.User, props map[string]interface{}) map[string]interface{} {
+	row := exportRow(u)
+	obj := make(map[string]interface{}, len(row)+1)
+	for i, column := range exportColumns {
+		obj[column] = row[i]
+	}
+	obj["id"] = u.ID
+	obj["status"] = u.Status
+	if props != nil {
+		obj["properties"] = props
+	}
+	return obj
+}
+
+// batchProperties 一批用户的自定义属性
+func batchProperties(appID uint, users []model.User) (map[uint]map[string]interface{}, error) {
+	ids := make([]uint, len(users))
+	for i := range users {
+		ids[i] = users[i].ID
+	}
+	var profiles []model.UserProfile
+	if err := db.Where("app_id = ? AND user_id IN ?", appID, ids).Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]map[string]interface{}, len(profiles))
+	for _, p := range profiles {
+		props := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(p.Properties), &props); err == nil {
+			result[p.UserID] = props
+		}
+	}
+	return result, nil
+}
+
+// loadImportJob 按路径参数加载当前应用的导入任务
+func loadImportJob(c *gin.Context) (*model.UserImportJob, bool) {
+	id, err := validator.ValidateID(c.Param("job_id"))
+	if err != nil {
+		response.ParamError(c, "无效的任务ID")
+		return nil, false
+	}
+	var job model.UserImportJob
+	if err := repository.FromContext(c, db).First(&job, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "导入任务不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &job, true
+}
+
+// PurgeImportFiles 清理已删除应用的一批导入任务：先删上传文件和错误报告再删记录，
+// 全部清理后移除应用导入目录
+func PurgeImportFiles(database *gorm.DB, appID uint, batchSize int) (int64, error) {
+	repo := repository.ForApp(database, appID)
+
+	var jobs []model.UserImportJob
+	if err := database.Scopes(repo.Scope).Order("id").Limit(batchSize).Find(&jobs).Error; err != nil {
+		return 0, err
+	}
+	if len(jobs) == 0 {
+		return 0, os.RemoveAll(filepath.Join(importDir, fmt.Sprintf("app_%d", appID)))
+	}
+
+	ids := make([]uint, len(jobs))
+	for i, job := range jobs {
+		for _, path := range []string{job.FilePath, job.ErrorReportPath} {
+			if path == "" {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return 0, err
+			}
+		}
+		ids[i] = job.ID
+	}
+	result := database.Delete(&model.UserImportJob{}, ids)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,181 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/profile"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ProfileRequest SDK更新属性请求参数，未登录时按 anonymous_id 更新匿名设备的属性
+type ProfileRequest struct {
+	AnonymousID string `json:"anonymous_id"`
+	profile.Operations
+}
+
+// IdentifyRequest SDK识别请求参数
+type IdentifyRequest struct {
+	AnonymousID string `json:"anonymous_id" binding:"required"`
+}
+
+// AliasRequest 服务端识别请求参数，user_id 和 open_id 提供一个
+type AliasRequest struct {
+	AnonymousID string `json:"anonymous_id" binding:"required"`
+	UserID      uint   `json:"user_id"`
+	OpenID      string `json:"open_id"`
+}
+
+// GetUserProperties 用户自定义属性
+func GetUserProperties(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok {
+		return
+	}
+	props, err := profile.UserProperties(db, user.AppID, user.ID)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, props)
+}
+
+// UpdateUserProperties 服务端更新用户自定义属性
+func UpdateUserProperties(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok {
+		return
+	}
+	var ops profile.Operations
+	if err := c.ShouldBindJSON(&ops); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	props, err := profile.UpdateUser(db, user.AppID, user.ID, &ops)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	response.Success(c, props)
+}
+
+// Alias 服务端将匿名设备识别为已知用户，合并匿名属性和事件
+func Alias(c *gin.Context) {
+	var req AliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	if (req.UserID == 0) == (req.OpenID == "") {
+		response.ParamError(c, "user_id 和 open_id 需提供且只提供一个")
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var user model.User
+	var err error
+	if req.UserID != 0 {
+		err = repo.First(&user, req.UserID)
+	} else {
+		err = repo.Model(&model.User{}).Where("open_id = ?", req.OpenID).First(&user).Error
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "用户不存在")
+			return
+		}
+		response.DBError(c, err)
+		return
+	}
+
+	result, err := profile.Identify(db, user.AppID, req.AnonymousID, user.ID)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	middleware.RecordAuditEvent(c, "alias", "app_user", strconv.Itoa(int(user.ID)), "识别匿名设备", gin.H{
+		"app_id":        user.AppID,
+		"anonymous_id":  req.AnonymousID,
+		"merged_events": result.MergedEvents,
+	})
+	response.Success(c, result)
+}
+
+// UpdateMyProfile SDK更新当前用户的属性，未登录时更新匿名设备的属性
+func UpdateMyProfile(c *gin.Context) {
+	var req ProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	var props map[string]interface{}
+	var err error
+	if user, ok := middleware.GetAppUser(c); ok {
+		props, err = profile.UpdateUser(db, user.AppID, user.ID, &req.Operations)
+	} else if req.AnonymousID != "" {
+		props, err = profile.UpdateAnonymous(db, middleware.ScopedAppID(c), req.AnonymousID, &req.Operations)
+	} else {
+		response.Unauthorized(c, "未登录时需提供 anonymous_id")
+		return
+	}
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	response.Success(c, props)
+}
+
+// Identify SDK在用户登录后调用，将本设备登录前的匿名属性和事件合并到当前用户
+func Identify(c *gin.Context) {
+	var req IdentifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	user, _ := middleware.GetAppUser(c)
+	result, err := profile.Identify(db, user.AppID, req.AnonymousID, user.ID)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+// loadUser 加载路由中的用户，不存在时写入响应
+func loadUser(c *gin.Context) (*model.User, bool) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, "无效的用户ID")
+		return nil, false
+	}
+	var user model.User
+	if err := repository.FromContext(c, db).First(&user, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "用户不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &user, true
+}
+
+// profileError 将属性和识别错误转换为响应
+func profileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, profile.ErrInvalidOperation), errors.Is(err, profile.ErrInvalidAnonymousID):
+		response.ParamError(c, err.Error())
+	case errors.Is(err, profile.ErrAlreadyIdentified):
+		response.Conflict(c, err.Error())
+	default:
+		response.DBError(c, err)
+	}
+}
@@ -0,0 +1,268 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/segment"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SegmentRequest 创建或更新分群请求参数
+type SegmentRequest struct {
+	Name        string          `json:"name" binding:"required"`
+	Description string          `json:"description" binding:"max=255"`
+	Rules       json.RawMessage `json:"rules" binding:"required"`
+}
+
+// PreviewSegmentRequest 预估分群规模请求参数
+type PreviewSegmentRequest struct {
+	Rules json.RawMessage `json:"rules" binding:"required"`
+}
+
+// ListSegments 分群列表
+func ListSegments(c *gin.Context) {
+	var segments []model.UserSegment
+	if err := repository.FromContext(c, db).Model(&model.UserSegment{}).
+		Order("id DESC").Find(&segments).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, segments)
+}
+
+// GetSegment 分群详情
+func GetSegment(c *gin.Context) {
+	seg, ok := loadSegment(c)
+	if !ok {
+		return
+	}
+	response.Success(c, seg)
+}
+
+// CreateSegment 创建分群，创建后立即物化成员
+func CreateSegment(c *gin.Context) {
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	repo := repository.FromContext(c, db)
+	name, rules, ok := validateSegmentRequest(c, repo, &req)
+	if !ok {
+		return
+	}
+
+	seg := model.UserSegment{
+		Name:        name,
+		Description: req.Description,
+		Rules:       rules,
+		CreatedBy:   c.GetUint("user_id"),
+	}
+	if err := repo.Create(&seg); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if _, err := segment.Materialize(db, &seg); err != nil {
+		log.Printf("[UserAPI] Failed to materialize segment %d: %v", seg.ID, err)
+	}
+
+	middleware.RecordAuditEvent(c, "create", "user_segment", strconv.Itoa(int(seg.ID)), "创建用户分群", gin.H{
+		"app_id": seg.AppID,
+		"name":   seg.Name,
+	})
+	response.SuccessWithMessage(c, seg, "分群创建成功")
+}
+
+// UpdateSegment 更新分群规则，更新后重新物化成员
+func UpdateSegment(c *gin.Context) {
+	seg, ok := loadSegment(c)
+	if !ok {
+		return
+	}
+	var req SegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	repo := repository.FromContext(c, db)
+	name, rules, ok := validateSegmentRequest(c, repo, &req)
+	if !ok {
+		return
+	}
+
+	if err := repo.Updates(seg, map[string]interface{}{
+		"name":        name,
+		"description": req.Description,
+		"rules":       rules,
+	}); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	seg.Name, seg.Description, seg.Rules = name, req.Description, rules
+	if _, err := segment.Materialize(db, seg); err != nil {
+		log.Printf("[UserAPI] Failed to materialize segment %d: %v", seg.ID, err)
+	}
+
+	middleware.RecordAuditEvent(c, "update", "user_segment", strconv.Itoa(int(seg.ID)), "更新用户分群", gin.H{
+		"app_id": seg.AppID,
+		"name":   seg.Name,
+	})
+	response.SuccessWithMessage(c, seg, "分群更新成功")
+}
+
+// DeleteSegment 删除分群及其成员
+func DeleteSegment(c *gin.Context) {
+	seg, ok := loadSegment(c)
+	if !ok {
+		return
+	}
+
+	err := repository.FromContext(c, db).Transaction(func(tx *repository.AppScoped) error {
+		if _, err := tx.Delete(&model.UserSegmentMember{}, "segment_id = ?", seg.ID); err != nil {
+			return err
+		}
+		_, err := tx.Delete(seg)
+		return err
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	middleware.RecordAuditEvent(c, "delete", "user_segment", strconv.Itoa(int(seg.ID)), "删除用户分群", gin.H{
+		"app_id": seg.AppID,
+		"name":   seg.Name,
+	})
+	response.SuccessWithMessage(c, nil, "分群删除成功")
+}
+
+// PreviewSegment 按规则预估分群规模，不保存
+func PreviewSegment(c *gin.Context) {
+	var req PreviewSegmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	repo := repository.FromContext(c, db)
+	rules, ok := parseSegmentRules(c, repo, req.Rules)
+	if !ok {
+		return
+	}
+
+	count, sample, err := segment.Preview(db, repo.AppID(), rules)
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"count": count, "sample": sample})
+}
+
+// RefreshSegment 立即重新物化分群成员
+func RefreshSegment(c *gin.Context) {
+	seg, ok := loadSegment(c)
+	if !ok {
+		return
+	}
+	if _, err := segment.Materialize(db, seg); err != nil {
+		if errors.Is(err, segment.ErrInvalidRules) {
+			response.ParamError(c, err.Error())
+			return
+		}
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, seg)
+}
+
+// SegmentUsers 分群最近一次物化的成员
+func SegmentUsers(c *gin.Context) {
+	seg, ok := loadSegment(c)
+	if !ok {
+		return
+	}
+	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
+	page, size = validator.ValidatePagination(page, size)
+
+	query := repository.FromContext(c, db).Model(&model.User{}).
+		Where("id IN (?)", db.Model(&model.UserSegmentMember{}).Select("user_id").Where("segment_id = ?", seg.ID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	var users []model.User
+	if err := query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&users).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.PageSuccess(c, users, total, page, size)
+}
+
+// loadSegment 加载路由中的分群，不存在时写入响应
+func loadSegment(c *gin.Context) (*model.UserSegment, bool) {
+	id, err := validator.ValidateID(c.Param("segment_id"))
+	if err != nil {
+		response.ParamError(c, "无效的分群ID")
+		return nil, false
+	}
+	var seg model.UserSegment
+	if err := repository.FromContext(c, db).First(&seg, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "分群不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &seg, true
+}
+
+// validateSegmentRequest 校验分群名称和规则，返回规范化后的名称和规则JSON
+func validateSegmentRequest(c *gin.Context, repo *repository.AppScoped, req *SegmentRequest) (string, string, bool) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len([]rune(name)) > 100 {
+		response.ParamError(c, "分群名称长度应在1-100个字符之间")
+		return "", "", false
+	}
+	rules, ok := parseSegmentRules(c, repo, req.Rules)
+	if !ok {
+		return "", "", false
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		response.ServerError(c, "规则序列化失败")
+		return "", "", false
+	}
+	return name, string(data), true
+}
+
+// parseSegmentRules 解析规则并校验引用的标签属于当前应用
+func parseSegmentRules(c *gin.Context, repo *repository.AppScoped, raw json.RawMessage) (*segment.Rules, bool) {
+	rules, err := segment.Parse(string(raw))
+	if err != nil {
+		response.ParamError(c, err.Error())
+		return nil, false
+	}
+	if tagIDs := rules.TagIDs(); len(tagIDs) > 0 {
+		if err := segment.ValidateTarget(db, repo.AppID(), segment.TargetTag, tagIDs); err != nil {
+			if errors.Is(err, segment.ErrInvalidTarget) {
+				response.ParamError(c, "规则引用的标签不存在")
+				return nil, false
+			}
+			response.DBError(c, err)
+			return nil, false
+		}
+	}
+	return rules, true
+}
@@ -0,0 +1,318 @@
+package user
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/validator"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxTagMembersPerRequest 单次打标或移除的用户数上限
+const maxTagMembersPerRequest = 1000
+
+// TagRequest 创建或更新标签请求参数
+type TagRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description" binding:"max=255"`
+	Color       string `json:"color" binding:"max=20"`
+}
+
+// TagMembersRequest 批量打标或移除标签请求参数，user_ids 和 open_ids 至少提供一个
+type TagMembersRequest struct {
+	UserIDs []uint   `json:"user_ids"`
+	OpenIDs []string `json:"open_ids"`
+}
+
+// ListTags 标签列表，含各标签的用户数
+func ListTags(c *gin.Context) {
+	repo := repository.FromContext(c, db)
+	var tags []model.UserTag
+	if err := repo.Find(&tags); err != nil {
+		response.DBError(c, err)
+		return
+	}
+
+	var counts []struct {
+		TagID uint
+		N     int64
+	}
+	if err := repo.Model(&model.UserTagMember{}).Select("tag_id, COUNT(*) AS n").
+		Group("tag_id").Scan(&counts).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	byTag := make(map[uint]int64, len(counts))
+	for _, row := range counts {
+		byTag[row.TagID] = row.N
+	}
+	for i := range tags {
+		tags[i].MemberCount = byTag[tags[i].ID]
+	}
+
+	response.Success(c, tags)
+}
+
+// CreateTag 创建标签
+func CreateTag(c *gin.Context) {
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > 50 {
+		response.ParamError(c, "标签名长度应在1-50个字符之间")
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var count int64
+	if err := repo.Model(&model.UserTag{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if count > 0 {
+		response.Conflict(c, "标签名已存在")
+		return
+	}
+
+	tag := model.UserTag{Name: req.Name, Description: req.Description, Color: req.Color}
+	if err := repo.Create(&tag); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	middleware.RecordAuditEvent(c, "create", "user_tag", strconv.Itoa(int(tag.ID)), "创建用户标签", gin.H{
+		"app_id": tag.AppID,
+		"name":   tag.Name,
+	})
+	response.SuccessWithMessage(c, tag, "标签创建成功")
+}
+
+// UpdateTag 更新标签
+func UpdateTag(c *gin.Context) {
+	tag, ok := loadTag(c)
+	if !ok {
+		return
+	}
+	var req TagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > 50 {
+		response.ParamError(c, "标签名长度应在1-50个字符之间")
+		return
+	}
+
+	repo := repository.FromContext(c, db)
+	var count int64
+	if err := repo.Model(&model.UserTag{}).Where("name = ? AND id <> ?", req.Name, tag.ID).Count(&count).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	if count > 0 {
+		response.Conflict(c, "标签名已存在")
+		return
+	}
+
+	if err := repo.Updates(tag, map[string]interface{}{
+		"name":        req.Name,
+		"description": req.Description,
+		"color":       req.Color,
+	}); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	tag.Name, tag.Description, tag.Color = req.Name, req.Description, req.Color
+	response.SuccessWithMessage(c, tag, "标签更新成功")
+}
+
+// DeleteTag 删除标签及其全部关联
+func DeleteTag(c *gin.Context) {
+	tag, ok := loadTag(c)
+	if !ok {
+		return
+	}
+
+	err := repository.FromContext(c, db).Transaction(func(tx *repository.AppScoped) error {
+		if _, err := tx.Delete(&model.UserTagMember{}, "tag_id = ?", tag.ID); err != nil {
+			return err
+		}
+		_, err := tx.Delete(tag)
+		return err
+	})
+	if err != nil {
+		response.DBError(c, err)
+		return
+	}
+	middleware.RecordAuditEvent(c, "delete", "user_tag", strconv.Itoa(int(tag.ID)), "删除用户标签", gin.H{
+		"app_id": tag.AppID,
+		"name":   tag.Name,
+	})
+	response.SuccessWithMessage(c, nil, "标签删除成功")
+}
+
+// TagUsers 标签下的用户
+func TagUsers(c *gin.Context) {
+	tag, ok := loadTag(c)
+	if !ok {
+		return
+	}
+	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
+	page, size = validator.ValidatePagination(page, size)
+
+	query := repository.FromContext(c, db).Model(&model.User{}).
+		Where("id IN (?)", db.Model(&model.UserTagMember{}).Select("user_id").Where("tag_id = ?", tag.ID))
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	var users []model.User
+	if err := query.Order("id DESC").Offset((page - 1) * size).Limit(size).Find(&users).Error; err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.PageSuccess(c, users, total, page, size)
+}
+
+// AddTagMembers 为用户打标签，已有标签的用户忽略
+func AddTagMembers(c *gin.Context) {
+	tag, ok := loadTag(c)
+	if !ok {
+		return
+	}
+	userIDs, ok := resolveTagMembers(c)
+	if !ok {
+		return
+	}
+
+	members := make([]model.UserTagMember, len(userIDs))
+	for i, uid := range userIDs {
+		members[i] = model.UserTagMember{AppID: tag.AppID, TagID: tag.ID, UserID: uid}
+	}
+	var added int64
+	if len(members) > 0 {
+		result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&members)
+		if result.Error != nil {
+			response.DBError(c, result.Error)
+			return
+		}
+		added = result.RowsAffected
+	}
+
+	middleware.RecordAuditEvent(c, "add_members", "user_tag", strconv.Itoa(int(tag.ID)), "为用户打标签", gin.H{
+		"app_id": tag.AppID,
+		"count":  added,
+	})
+	response.Success(c, gin.H{"added": added})
+}
+
+// RemoveTagMembers 移除用户的标签
+func RemoveTagMembers(c *gin.Context) {
+	tag, ok := loadTag(c)
+	if !ok {
+		return
+	}
+	userIDs, ok := resolveTagMembers(c)
+	if !ok {
+		return
+	}
+
+	var removed int64
+	if len(userIDs) > 0 {
+		n, err := repository.FromContext(c, db).Delete(&model.UserTagMember{}, "tag_id = ? AND user_id IN ?", tag.ID, userIDs)
+		if err != nil {
+			response.DBError(c, err)
+			return
+		}
+		removed = n
+	}
+
+	middleware.RecordAuditEvent(c, "remove_members", "user_tag", strconv.Itoa(int(tag.ID)), "移除用户标签", gin.H{
+		"app_id": tag.AppID,
+		"count":  removed,
+	})
+	response.Success(c, gin.H{"removed": removed})
+}
+
+// UserTags 用户拥有的标签
+func UserTags(c *gin.Context) {
+	id, err := validator.ValidateID(c.Param("id"))
+	if err != nil {
+		response.ParamError(c, "无效的用户ID")
+		return
+	}
+
+	var tags []model.UserTag
+	if err := repository.FromContext(c, db).Find(&tags,
+		"id IN (?)", db.Model(&model.UserTagMember{}).Select("tag_id").Where("user_id = ?", id)); err != nil {
+		response.DBError(c, err)
+		return
+	}
+	response.Success(c, tags)
+}
+
+// loadTag 加载路由中的标签，不存在时写入响应
+func loadTag(c *gin.Context) (*model.UserTag, bool) {
+	id, err := validator.ValidateID(c.Param("tag_id"))
+	if err != nil {
+		response.ParamError(c, "无效的标签ID")
+		return nil, false
+	}
+	var tag model.UserTag
+	if err := repository.FromContext(c, db).First(&tag, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "标签不存在")
+			return nil, false
+		}
+		response.DBError(c, err)
+		return nil, false
+	}
+	return &tag, true
+}
+
+// resolveTagMembers 将请求中的用户ID和 open_id 解析为当前应用下的用户ID
+func resolveTagMembers(c *gin.Context) ([]uint, bool) {
+	var req TagMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return nil, false
+	}
+	if len(req.UserIDs) == 0 && len(req.OpenIDs) == 0 {
+		response.ParamError(c, "user_ids 和 open_ids 至少提供一个")
+		return nil, false
+	}
+	if len(req.UserIDs)+len(req.OpenIDs) > maxTagMembersPerRequest {
+		response.ParamError(c, "单次最多处理"+strconv.Itoa(maxTagMembersPerRequest)+"个用户")
+		return nil, false
+	}
+
+	query := repository.FromContext(c, db).Model(&model.User{})
+	switch {
+	case len(req.UserIDs) > 0 && len(req.OpenIDs) > 0:
+		query = query.Where("id IN ? OR open_id IN ?", req.UserIDs, req.OpenIDs)
+	case len(req.UserIDs) > 0:
+		query = query.Where("id IN ?", req.UserIDs)
+	default:
+		query = query.Where("open_id IN ?", req.OpenIDs)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		response.DBError(c, err)
+		return nil, false
+	}
+	return ids, true
+}
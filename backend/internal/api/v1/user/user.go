@@ -1,10 +1,17 @@
 package user
 
 import (
+	"errors"
 	"log"
+	"strconv"
 	"time"
 
+	"app-platform-backend/internal/identity"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
 	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/userban"
 	"app-platform-backend/internal/validator"
 
 	"github.com/gin-gonic/gin"
@@ -19,160 +26,65 @@ func InitDB(database *gorm.DB) {
 	log.Println("[UserAPI] Database connection initialized")
 }
 
-// ManusUser Manus平台用户表结构
-type ManusUser struct {
-	ID           int        `gorm:"column:id;primaryKey" json:"id"`
-	OpenID       string     `gorm:"column:openId" json:"open_id"`
-	Name         *string    `gorm:"column:name" json:"name"`
-	Email        *string    `gorm:"column:email" json:"email"`
-	LoginMethod  *string    `gorm:"column:loginMethod" json:"login_method"`
-	Role         string     `gorm:"column:role" json:"role"`
-	CreatedAt    time.Time  `gorm:"column:createdAt" json:"created_at"`
-	UpdatedAt    time.Time  `gorm:"column:updatedAt" json:"updated_at"`
-	LastSignedIn *time.Time `gorm:"column:lastSignedIn" json:"last_signed_in"`
-}
-
-// TableName 指定表名
-func (ManusUser) TableName() string {
-	return "users"
-}
-
-// UserResponse 用户响应结构（适配前端）
-type UserResponse struct {
-	ID          int        `json:"id"`
-	Nickname    string     `json:"nickname"`
-	Phone       string     `json:"phone"`
-	Email       string     `json:"email"`
-	Status      int        `json:"status"`
-	Role        string     `json:"role"`
-	CreatedAt   time.Time  `json:"created_at"`
-	LastLoginAt *time.Time `json:"last_login_at"`
-}
-
 // ListRequest 用户列表请求参数
 type ListRequest struct {
-	AppID  uint   `form:"app_id"`
-	Page   int    `form:"page" binding:"min=1"`
-	Size   int    `form:"size" binding:"min=1,max=100"`
+	Page   int    `form:"page"`
+	Size   int    `form:"size"`
 	Status *int   `form:"status"`
+	Source string `form:"source"`
 	Search string `form:"search"`
 }
 
-// List 获取用户列表
+// List 应用用户列表，支持按状态、来源筛选和按昵称、邮箱、手机号、open_id 搜索
 func List(c *gin.Context) {
 	var req ListRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		log.Printf("[UserAPI] List - Invalid request: %v", err)
 		response.ParamError(c, "请求参数错误: "+err.Error())
 		return
 	}
-
-	// 验证分页参数
 	req.Page, req.Size = validator.ValidatePagination(req.Page, req.Size)
 
-	log.Printf("[UserAPI] List - Request: page=%d, size=%d, search=%s", req.Page, req.Size, req.Search)
-
-	// 查询Manus平台的users表
-	query := db.Model(&ManusUser{})
-
-	// 搜索（按名称或邮箱）
-	if req.Search != "" {
-		query = query.Where("name LIKE ? OR email LIKE ?",
-			"%"+req.Search+"%", "%"+req.Search+"%")
-	}
-
-	// 统计总数
+	query := listQuery(c, &req)
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
-		log.Printf("[UserAPI] List - Count error: %v", err)
 		response.DBError(c, err)
 		return
 	}
 
-	// 分页查询
-	var users []ManusUser
+	var users []model.User
 	offset := (req.Page - 1) * req.Size
-	if err := query.Offset(offset).Limit(req.Size).Order("createdAt DESC").Find(&users).Error; err != nil {
-		log.Printf("[UserAPI] List - Query error: %v", err)
+	if err := query.Offset(offset).Limit(req.Size).Order("created_at DESC").Find(&users).Error; err != nil {
 		response.DBError(c, err)
 		return
 	}
 
-	// 转换为前端期望的格式
-	var responseList []UserResponse
-	for _, u := range users {
-		name := ""
-		if u.Name != nil {
-			name = *u.Name
-		}
-		email := ""
-		if u.Email != nil {
-			email = *u.Email
-		}
-		responseList = append(responseList, UserResponse{
-			ID:          u.ID,
-			Nickname:    name,
-			Phone:       "", // Manus平台没有phone字段
-			Email:       email,
-			Status:      1, // 默认正常状态
-			Role:        u.Role,
-			CreatedAt:   u.CreatedAt,
-			LastLoginAt: u.LastSignedIn,
-		})
-	}
-
-	log.Printf("[UserAPI] List - Found %d users, total %d", len(users), total)
-
-	response.PageSuccess(c, responseList, total, req.Page, req.Size)
+	response.PageSuccess(c, users, total, req.Page, req.Size)
 }
 
-// Detail 获取用户详情
-func Detail(c *gin.Context) {
-	idStr := c.Param("id")
-
-	// 验证ID
-	id, err := validator.ValidateID(idStr)
-	if err != nil {
-		log.Printf("[UserAPI] Detail - Invalid user ID: %s", idStr)
-		response.ParamError(c, "无效的用户ID")
-		return
-	}
-
-	log.Printf("[UserAPI] Detail - Getting user %d", id)
-
-	var user ManusUser
-	if err := db.First(&user, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("[UserAPI] Detail - User %d not found", id)
-			response.NotFound(c, "用户不存在")
-			return
-		}
-		log.Printf("[UserAPI] Detail - Query error: %v", err)
-		response.DBError(c, err)
-		return
+// listQuery 按列表筛选条件查询当前应用的用户，列表和导出共用
+func listQuery(c *gin.Context, req *ListRequest) *gorm.DB {
+	query := repository.FromContext(c, db).Model(&model.User{})
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
 	}
-
-	name := ""
-	if user.Name != nil {
-		name = *user.Name
+	if req.Source != "" {
+		query = query.Where("source = ?", req.Source)
 	}
-	email := ""
-	if user.Email != nil {
-		email = *user.Email
+	if req.Search != "" {
+		keyword := "%" + req.Search + "%"
+		query = query.Where("nickname LIKE ? OR email LIKE ? OR phone LIKE ? OR open_id LIKE ?",
+			keyword, keyword, keyword, keyword)
 	}
+	return query
+}
 
-	userResponse := UserResponse{
-		ID:          user.ID,
-		Nickname:    name,
-		Phone:       "",
-		Email:       email,
-		Status:      1,
-		Role:        user.Role,
-		CreatedAt:   user.CreatedAt,
-		LastLoginAt: user.LastSignedIn,
+// Detail 应用用户详情
+func Detail(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok {
+		return
 	}
-
-	response.Success(c, userResponse)
+	response.Success(c, user)
 }
 
 // UpdateStatusRequest 更新用户状态请求参数
@@ -180,73 +92,131 @@ type UpdateStatusRequest struct {
 	Status int `json:"status" binding:"oneof=0 1"`
 }
 
-// UpdateStatus 更新用户状态（Manus平台不支持，返回成功但不实际修改）
+// UpdateStatus 启用或禁用应用用户，禁用等同于不附带原因的永久封禁
 func UpdateStatus(c *gin.Context) {
-	idStr := c.Param("id")
-
-	// 验证ID
-	id, err := validator.ValidateID(idStr)
+	id, err := validator.ValidateID(c.Param("id"))
 	if err != nil {
-		log.Printf("[UserAPI] UpdateStatus - Invalid user ID: %s", idStr)
 		response.ParamError(c, "无效的用户ID")
 		return
 	}
 
 	var req UpdateStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[UserAPI] UpdateStatus - Invalid request: %v", err)
 		response.ParamError(c, "请求参数错误: "+err.Error())
 		return
 	}
 
-	log.Printf("[UserAPI] UpdateStatus - User %d status to %d (note: Manus platform doesn't support status field)", id, req.Status)
+	var user *model.User
+	appID := middleware.ScopedAppID(c)
+	if req.Status == model.UserStatusDisabled {
+		user, err = userban.Ban(appID, id, nil, "")
+	} else {
+		user, err = userban.Unban(appID, id)
+	}
+	if err != nil {
+		banError(c, err)
+		return
+	}
 
-	// Manus平台的users表没有status字段，这里只返回成功
-	response.SuccessWithMessage(c, nil, "用户状态更新成功")
+	middleware.RecordAuditEvent(c, "update_status", "app_user", strconv.Itoa(int(user.ID)), "修改应用用户状态", gin.H{
+		"app_id":  user.AppID,
+		"open_id": user.OpenID,
+		"status":  req.Status,
+	})
+	response.SuccessWithMessage(c, user, "用户状态更新成功")
 }
 
-// Stats 用户统计
+// Stats 应用用户统计
 func Stats(c *gin.Context) {
-	log.Printf("[UserAPI] Stats - Getting user statistics")
-
-	// 总用户数
-	var total int64
-	if err := db.Model(&ManusUser{}).Count(&total).Error; err != nil {
-		log.Printf("[UserAPI] Stats - Count error: %v", err)
-		total = 0
+	repo := repository.FromContext(c, db)
+
+	var counts struct {
+		Total    int64
+		Normal   int64
+		Disabled int64
+	}
+	if err := repo.Model(&model.User{}).
+		Select("COUNT(*) AS total, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS normal, "+
+			"COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) AS disabled",
+			model.UserStatusNormal, model.UserStatusDisabled).
+		Scan(&counts).Error; err != nil {
+		response.DBError(c, err)
+		return
 	}
 
-	// 活跃用户数（最近7天登录）
+	// 活跃用户：最近7天登录过
 	var active int64
-	sevenDaysAgo := time.Now().AddDate(0, 0, -7)
-	if err := db.Model(&ManusUser{}).Where("lastSignedIn > ?", sevenDaysAgo).Count(&active).Error; err != nil {
-		log.Printf("[UserAPI] Stats - Active count error: %v", err)
-		active = 0
+	if err := repo.Model(&model.User{}).
+		Where("last_login_at > ?", time.Now().AddDate(0, 0, -7)).
+		Count(&active).Error; err != nil {
+		response.DBError(c, err)
+		return
 	}
 
-	// 今日新增
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	var todayNew int64
-	today := time.Now().Format("2006-01-02")
-	if err := db.Model(&ManusUser{}).Where("DATE(createdAt) = ?", today).Count(&todayNew).Error; err != nil {
-		log.Printf("[UserAPI] Stats - Today new count error: %v", err)
-		todayNew = 0
+	if err := repo.Model(&model.User{}).Where("created_at >= ?", today).Count(&todayNew).Error; err != nil {
+		response.DBError(c, err)
+		return
 	}
 
-	// 管理员数量
-	var adminCount int64
-	if err := db.Model(&ManusUser{}).Where("role = ?", "admin").Count(&adminCount).Error; err != nil {
-		log.Printf("[UserAPI] Stats - Admin count error: %v", err)
-		adminCount = 0
+	var sources []struct {
+		Source string `json:"source"`
+		Count  int64  `json:"count"`
+	}
+	if err := repo.Model(&model.User{}).
+		Select("source, COUNT(*) AS count").Group("source").
+		Scan(&sources).Error; err != nil {
+		response.DBError(c, err)
+		return
 	}
-
-	log.Printf("[UserAPI] Stats - total=%d, active=%d, todayNew=%d, admin=%d", total, active, todayNew, adminCount)
 
 	response.Success(c, gin.H{
-		"total":     total,
+		"total":     counts.Total,
 		"active":    active,
 		"today_new": todayNew,
-		"disabled":  0,
-		"normal":    total,
-		"admin":     adminCount,
+		"normal":    counts.Normal,
+		"disabled":  counts.Disabled,
+		"sources":   sources,
+	})
+}
+
+// Sources 可同步的外部身份源
+func Sources(c *gin.Context) {
+	response.Success(c, identity.Names())
+}
+
+// SyncRequest 从外部身份源同步用户
+type SyncRequest struct {
+	Source string `json:"source" binding:"required"`
+}
+
+// Sync 将外部身份源中的用户同步为当前应用的用户
+func Sync(c *gin.Context) {
+	var req SyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ParamError(c, "请求参数错误: "+err.Error())
+		return
+	}
+
+	appID := middleware.ScopedAppID(c)
+	result, err := identity.Sync(c.Request.Context(), db, appID, req.Source, 0)
+	if errors.Is(err, identity.ErrUnknownSource) {
+		response.NotFound(c, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("[UserAPI] Sync from %s for app %d failed: %v", req.Source, appID, err)
+		response.ServerError(c, "同步失败: "+err.Error())
+		return
+	}
+
+	middleware.RecordAuditEvent(c, "sync", "app_user", strconv.Itoa(int(appID)), "从外部身份源同步应用用户", gin.H{
+		"source":  result.Source,
+		"fetched": result.Fetched,
+		"created": result.Created,
 	})
+	response.Success(c, result)
 }
@@ -1,10 +1,13 @@
 package version
 
 import (
+	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
 	"app-platform-backend/internal/response"
+	"app-platform-backend/internal/segment"
 	"app-platform-backend/internal/validator"
-	"strconv"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -19,19 +22,13 @@ func InitDB(database *gorm.DB) {
 
 // List 版本列表
 func List(c *gin.Context) {
-	appID := c.Query("app_id")
 	status := c.Query("status")
 	page, size := validator.ParsePagination(c.DefaultQuery("page", "1"), c.DefaultQuery("size", "20"))
 
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-
 	// 验证分页参数
 	page, size = validator.ValidatePagination(page, size)
 
-	query := db.Model(&model.Version{}).Where("app_id = ?", appID)
+	query := repository.FromContext(c, db).Model(&model.Version{})
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -63,6 +60,8 @@ func List(c *gin.Context) {
 			"download_url": v.DownloadURL,
 			"force_update": v.IsForceUpdate == 1,
 			"status":       v.Status,
+			"target_type":  v.TargetType,
+			"target_ids":   segment.ParseIDs(v.TargetIDs),
 			"published_at": v.PublishedAt,
 			"created_at":   v.CreatedAt,
 		})
@@ -74,7 +73,6 @@ func List(c *gin.Context) {
 // Create 创建版本
 func Create(c *gin.Context) {
 	var req struct {
-		AppID       uint   `json:"app_id" binding:"required"`
 		Version     string `json:"version" binding:"required"`
 		Platform    string `json:"platform"`
 		Description string `json:"description"`
@@ -82,6 +80,8 @@ func Create(c *gin.Context) {
 		ForceUpdate bool   `json:"force_update"`
 		GrayRelease bool   `json:"gray_release"`
 		GrayPercent int    `json:"gray_percent"`
+		TargetType  string `json:"target_type"`
+		TargetIDs   []uint `json:"target_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -103,9 +103,17 @@ func Create(c *gin.Context) {
 		}
 	}
 
+	repo := repository.FromContext(c, db)
+	if req.TargetType == "" {
+		req.TargetType = segment.TargetAll
+	}
+	if !validateTarget(c, repo, req.TargetType, req.TargetIDs) {
+		return
+	}
+
 	// 获取最大版本号
 	var maxVersionCode int
-	db.Model(&model.Version{}).Where("app_id = ?", req.AppID).Select("COALESCE(MAX(version_code), 0)").Scan(&maxVersionCode)
+	repo.Model(&model.Version{}).Select("COALESCE(MAX(version_code), 0)").Scan(&maxVersionCode)
 
 	forceUpdate := 0
 	if req.ForceUpdate {
@@ -113,16 +121,17 @@ func Create(c *gin.Context) {
 	}
 
 	version := model.Version{
-		AppID:         req.AppID,
 		VersionName:   req.Version,
 		VersionCode:   maxVersionCode + 1,
 		Description:   req.Description,
 		DownloadURL:   req.DownloadURL,
 		IsForceUpdate: forceUpdate,
 		Status:        "draft",
+		TargetType:    req.TargetType,
+		TargetIDs:     segment.FormatIDs(req.TargetIDs),
 	}
 
-	if err := db.Create(&version).Error; err != nil {
+	if err := repo.Create(&version); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -132,30 +141,17 @@ func Create(c *gin.Context) {
 
 // Update 更新版本
 func Update(c *gin.Context) {
-	idStr := c.Param("id")
-	appIDStr := c.Query("app_id")
-
 	// 验证ID
-	id, err := validator.ValidateID(idStr)
+	id, err := validator.ValidateID(c.Param("id"))
 	if err != nil {
 		response.ParamError(c, "无效的版本ID")
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
 	// 检查版本是否存在且属于该APP
+	repo := repository.FromContext(c, db)
 	var existingVersion model.Version
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&existingVersion).Error; err != nil {
+	if err := repo.First(&existingVersion, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "版本不存在或无权限操作")
 			return
@@ -169,6 +165,8 @@ func Update(c *gin.Context) {
 		Description string `json:"description"`
 		DownloadURL string `json:"download_url"`
 		ForceUpdate bool   `json:"force_update"`
+		TargetType  string `json:"target_type"`
+		TargetIDs   []uint `json:"target_ids"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -176,6 +174,11 @@ func Update(c *gin.Context) {
 		return
 	}
 
+	// 未传 target_type 时保持原有灰度目标
+	if req.TargetType != "" && !validateTarget(c, repo, req.TargetType, req.TargetIDs) {
+		return
+	}
+
 	// 验证下载URL
 	if req.DownloadURL != "" {
 		if err := validator.ValidateURL(req.DownloadURL); err != nil {
@@ -198,8 +201,12 @@ func Update(c *gin.Context) {
 	if req.ForceUpdate {
 		updates["is_force_update"] = 1
 	}
+	if req.TargetType != "" {
+		updates["target_type"] = req.TargetType
+		updates["target_ids"] = segment.FormatIDs(req.TargetIDs)
+	}
 
-	if err := db.Model(&model.Version{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	if err := repo.Updates(&existingVersion, updates); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -209,30 +216,17 @@ func Update(c *gin.Context) {
 
 // Publish 发布版本
 func Publish(c *gin.Context) {
-	idStr := c.Param("id")
-	appIDStr := c.Query("app_id")
-
 	// 验证ID
-	id, err := validator.ValidateID(idStr)
+	id, err := validator.ValidateID(c.Param("id"))
 	if err != nil {
 		response.ParamError(c, "无效的版本ID")
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
 	// 检查版本是否存在且属于该APP
+	repo := repository.FromContext(c, db)
 	var existingVersion model.Version
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&existingVersion).Error; err != nil {
+	if err := repo.First(&existingVersion, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "版本不存在或无权限操作")
 			return
@@ -247,10 +241,10 @@ func Publish(c *gin.Context) {
 	}
 
 	now := time.Now()
-	if err := db.Model(&model.Version{}).Where("id = ?", id).Updates(map[string]interface{}{
+	if err := repo.Updates(&existingVersion, map[string]interface{}{
 		"status":       "published",
 		"published_at": now,
-	}).Error; err != nil {
+	}); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -260,30 +254,17 @@ func Publish(c *gin.Context) {
 
 // Offline 下线版本
 func Offline(c *gin.Context) {
-	idStr := c.Param("id")
-	appIDStr := c.Query("app_id")
-
 	// 验证ID
-	id, err := validator.ValidateID(idStr)
+	id, err := validator.ValidateID(c.Param("id"))
 	if err != nil {
 		response.ParamError(c, "无效的版本ID")
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
 	// 检查版本是否存在且属于该APP
+	repo := repository.FromContext(c, db)
 	var existingVersion model.Version
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&existingVersion).Error; err != nil {
+	if err := repo.First(&existingVersion, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "版本不存在或无权限操作")
 			return
@@ -297,7 +278,7 @@ func Offline(c *gin.Context) {
 		return
 	}
 
-	if err := db.Model(&model.Version{}).Where("id = ?", id).Update("status", "offline").Error; err != nil {
+	if err := repo.Updates(&existingVersion, map[string]interface{}{"status": "offline"}); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -307,30 +288,17 @@ func Offline(c *gin.Context) {
 
 // Delete 删除版本
 func Delete(c *gin.Context) {
-	idStr := c.Param("id")
-	appIDStr := c.Query("app_id")
-
 	// 验证ID
-	id, err := validator.ValidateID(idStr)
+	id, err := validator.ValidateID(c.Param("id"))
 	if err != nil {
 		response.ParamError(c, "无效的版本ID")
 		return
 	}
 
-	// 验证app_id
-	if appIDStr == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
-	appID, err := strconv.ParseUint(appIDStr, 10, 32)
-	if err != nil {
-		response.ParamError(c, "无效的 app_id")
-		return
-	}
-
 	// 检查版本是否存在且属于该APP
+	repo := repository.FromContext(c, db)
 	var existingVersion model.Version
-	if err := db.Where("id = ? AND app_id = ?", id, appID).First(&existingVersion).Error; err != nil {
+	if err := repo.First(&existingVersion, id); err != nil {
 		if err == gorm.ErrRecordNotFound {
 			response.NotFound(c, "版本不存在或无权限删除")
 			return
@@ -345,7 +313,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if err := db.Delete(&model.Version{}, id).Error; err != nil {
+	if _, err := repo.Delete(&existingVersion); err != nil {
 		response.DBError(c, err)
 		return
 	}
@@ -353,34 +321,45 @@ func Delete(c *gin.Context) {
 	response.SuccessWithMessage(c, nil, "版本删除成功")
 }
 
+// checkUpdateScanLimit 检查更新时最多遍历的已发布版本数
+const checkUpdateScanLimit = 50
+
 // CheckUpdate 检查更新
+// 按版本号从高到低返回第一个对当前用户可见的已发布版本，定向到标签或分群的版本只对命中的登录用户可见
 func CheckUpdate(c *gin.Context) {
-	appID := c.Query("app_id")
 	currentVersion := c.Query("version")
 
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
+	repo := repository.FromContext(c, db)
+	var versions []model.Version
+	if err := repo.Model(&model.Version{}).Where("status = ?", "published").
+		Order("version_code DESC").Limit(checkUpdateScanLimit).
+		Find(&versions).Error; err != nil {
+		response.DBError(c, err)
 		return
 	}
 
-	// 获取最新发布的版本
-	var latestVersion model.Version
-	err := db.Where("app_id = ? AND status = ?", appID, "published").
-		Order("version_code DESC").
-		First(&latestVersion).Error
+	userID, _ := middleware.AppUserID(c)
+	var latestVersion *model.Version
+	for i := range versions {
+		v := &versions[i]
+		matched, err := segment.Matches(db, repo.AppID(), userID, v.TargetType, segment.ParseIDs(v.TargetIDs))
+		if err != nil {
+			response.DBError(c, err)
+			return
+		}
+		if matched {
+			latestVersion = v
+			break
+		}
+	}
 
-	if err == gorm.ErrRecordNotFound {
+	if latestVersion == nil {
 		response.Success(c, gin.H{
 			"has_update": false,
 		})
 		return
 	}
 
-	if err != nil {
-		response.DBError(c, err)
-		return
-	}
-
 	hasUpdate := latestVersion.VersionName != currentVersion
 
 	response.Success(c, gin.H{
@@ -395,17 +374,13 @@ func CheckUpdate(c *gin.Context) {
 
 // Stats 版本统计
 func Stats(c *gin.Context) {
-	appID := c.Query("app_id")
-	if appID == "" {
-		response.ParamError(c, "app_id 不能为空")
-		return
-	}
+	repo := repository.FromContext(c, db)
 
 	var total, published, draft, offline int64
-	db.Model(&model.Version{}).Where("app_id = ?", appID).Count(&total)
-	db.Model(&model.Version{}).Where("app_id = ? AND status = ?", appID, "published").Count(&published)
-	db.Model(&model.Version{}).Where("app_id = ? AND status = ?", appID, "draft").Count(&draft)
-	db.Model(&model.Version{}).Where("app_id = ? AND status = ?", appID, "offline").Count(&offline)
+	repo.Model(&model.Version{}).Count(&total)
+	repo.Model(&model.Version{}).Where("status = ?", "published").Count(&published)
+	repo.Model(&model.Version{}).Where("status = ?", "draft").Count(&draft)
+	repo.Model(&model.Version{}).Where("status = ?", "offline").Count(&offline)
 
 	response.Success(c, gin.H{
 		"total":     total,
@@ -414,3 +389,16 @@ func Stats(c *gin.Context) {
 		"offline":   offline,
 	})
 }
+
+// validateTarget 校验版本灰度目标属于当前应用，失败时写入响应
+func validateTarget(c *gin.Context, repo *repository.AppScoped, targetType string, ids []uint) bool {
+	if err := segment.ValidateTarget(db, repo.AppID(), targetType, ids); err != nil {
+		if errors.Is(err, segment.ErrInvalidTarget) {
+			response.ParamError(c, "灰度目标不存在或不属于当前应用")
+			return false
+		}
+		response.DBError(c, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,23 @@
+package websocket
+
+import (
+	"strconv"
+
+	"app-platform-backend/core/module"
+)
+
+// ExportUserData 导出该用户在当前实例上的WebSocket连接，连接只保存在内存中，多实例部署时只包含本实例
+func ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return w.WriteRecords("sessions", hub.UserConnections(subject.AppID, subjectKey(subject)))
+}
+
+// EraseUserData 断开该用户在当前实例上的WebSocket连接
+func EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	n := hub.DisconnectUser(subject.AppID, subjectKey(subject))
+	return map[string]int64{"sessions": int64(n)}, nil
+}
+
+// subjectKey 连接时 user_id 参数为应用用户ID
+func subjectKey(subject module.DataSubject) string {
+	return strconv.FormatUint(uint64(subject.UserID), 10)
+}
@@ -2,12 +2,20 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	"app-platform-backend/internal/appauth"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/quota"
+	"app-platform-backend/internal/userban"
+
 	"github.com/gin-gonic/gin"
 	ws "github.com/gorilla/websocket"
 )
@@ -29,6 +37,12 @@ type Client struct {
 	Send     chan []byte
 	Hub      *Hub
 	mu       sync.Mutex
+
+	// Metered 应用经SDK签名或终端用户令牌认证确定，计入应用的连接数配额
+	Metered bool
+
+	ConnectedAt time.Time
+	RemoteAddr  string
 }
 
 // Hub 管理所有WebSocket连接
@@ -76,6 +90,18 @@ var hub *Hub
 func init() {
 	hub = NewHub()
 	go hub.Run()
+
+	quota.RegisterGauge(quota.MetricWSConnections, func(appID uint) (int64, error) {
+		return int64(hub.AppClientCount(appID)), nil
+	})
+
+	// 封禁用户时断开其在本实例上的连接，其他实例通过封禁事件同步后断开
+	userban.OnBan(func(user *model.User) {
+		n := hub.DisconnectUser(user.AppID, strconv.FormatUint(uint64(user.ID), 10))
+		if n > 0 {
+			log.Printf("[WebSocket] Disconnected %d connections of banned user %d", n, user.ID)
+		}
+	})
 }
 
 // NewHub 创建新的Hub
@@ -153,6 +179,66 @@ func GetHub() *Hub {
 	return hub
 }
 
+// AppClientCount 当前实例上指定APP计入配额的连接数，不含未经认证的匿名连接
+func (h *Hub) AppClientCount(appID uint) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := 0
+	for client := range h.appClients[appID] {
+		if client.Metered {
+			n++
+		}
+	}
+	return n
+}
+
+// ConnectionInfo 连接信息，用于导出用户数据
+type ConnectionInfo struct {
+	ID          string    `json:"id"`
+	AppID       uint      `json:"app_id"`
+	UserID      string    `json:"user_id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+}
+
+// UserConnections 当前实例上指定APP用户的连接
+func (h *Hub) UserConnections(appID uint, userID string) []ConnectionInfo {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	conns := []ConnectionInfo{}
+	for client := range h.appClients[appID] {
+		if client.UserID == userID {
+			conns = append(conns, ConnectionInfo{
+				ID:          client.ID,
+				AppID:       client.AppID,
+				UserID:      client.UserID,
+				RemoteAddr:  client.RemoteAddr,
+				ConnectedAt: client.ConnectedAt,
+			})
+		}
+	}
+	return conns
+}
+
+// DisconnectUser 断开当前实例上指定APP用户的全部连接，返回断开的连接数
+// 连接立即从Hub移除，读写协程随连接关闭退出
+func (h *Hub) DisconnectUser(appID uint, userID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for client := range h.appClients[appID] {
+		if client.UserID == userID {
+			delete(h.clients, client)
+			delete(h.appClients[appID], client)
+			client.Conn.Close()
+			n++
+		}
+	}
+	return n
+}
+
 // Broadcast 广播消息
 func (h *Hub) Broadcast(msg *Message) {
 	msg.Timestamp = time.Now().UnixMilli()
@@ -187,9 +273,10 @@ func BroadcastNotification(appID uint, title, message string) {
 }
 
 // HandleWebSocket WebSocket连接处理器
+// 终端用户通过 access_token 参数携带访问令牌（浏览器无法为WebSocket设置请求头），用户身份只取自令牌；
+// 未携带令牌的连接为匿名连接，不接收定向到用户的消息，app_id 未经认证，不计入该应用的连接数配额
 func HandleWebSocket(c *gin.Context) {
 	appIDStr := c.Query("app_id")
-	userID := c.Query("user_id")
 	
 	var appID uint
 	if appIDStr != "" {
@@ -200,6 +287,52 @@ func HandleWebSocket(c *gin.Context) {
 		}
 	}
 
+	var userID string
+	if token := c.Query("access_token"); token != "" {
+		if appID == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "Invalid access token"})
+			return
+		}
+		// 封禁即禁用，Authenticate 对禁用用户返回 ErrUserDisabled
+		user, _, err := appauth.Authenticate(appID, token)
+		if errors.Is(err, appauth.ErrUserDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "User disabled"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "Invalid access token"})
+			return
+		}
+		userID = strconv.FormatUint(uint64(user.ID), 10)
+	}
+
+	connect(c, appID, userID, userID != "")
+}
+
+// HandleClientWebSocket 客户端SDK的WebSocket连接，应用取自签名认证，终端用户取自 Authorization 访问令牌
+func HandleClientWebSocket(c *gin.Context) {
+	app, ok := middleware.GetClientApp(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "Invalid app"})
+		return
+	}
+	var userID string
+	if uid, ok := middleware.AppUserID(c); ok {
+		userID = strconv.FormatUint(uint64(uid), 10)
+	}
+	connect(c, app.ID, userID, true)
+}
+
+// connect 升级为WebSocket连接并注册到Hub，metered 表示应用已经认证，需检查连接数配额
+func connect(c *gin.Context, appID uint, userID string, metered bool) {
+	// 连接数配额按单个实例统计
+	if metered {
+		if err := quota.Check(appID, quota.MetricWSConnections, int64(hub.AppClientCount(appID)), 1); err != nil {
+			quota.Reject(c, err)
+			return
+		}
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("[WebSocket] Upgrade error: %v", err)
@@ -213,6 +346,10 @@ func HandleWebSocket(c *gin.Context) {
 		Conn:   conn,
 		Send:   make(chan []byte, 256),
 		Hub:    hub,
+
+		Metered:     metered,
+		ConnectedAt: time.Now(),
+		RemoteAddr:  c.ClientIP(),
 	}
 
 	hub.register <- client
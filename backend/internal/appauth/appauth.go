@@ -0,0 +1,448 @@
+// Package appauth 应用终端用户认证
+// 支持邮箱/手机号+密码、一次性验证码注册登录，签发按应用隔离的访问令牌和刷新令牌，
+// 令牌只在数据库中保存哈希，每次校验都会检查用户当前状态
+package appauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/codesender"
+	"app-platform-backend/internal/validator"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 令牌前缀，便于和管理员令牌、API令牌区分
+const (
+	AccessTokenPrefix  = "uat_"
+	RefreshTokenPrefix = "urt_"
+)
+
+// maxPasswordLength bcrypt 只使用前72字节
+const maxPasswordLength = 72
+
+var (
+	ErrInvalidTarget      = errors.New("请输入有效的邮箱或手机号")
+	ErrInvalidPassword    = errors.New("密码不符合要求")
+	ErrInvalidCredentials = errors.New("账号或密码错误")
+	ErrUserDisabled       = errors.New("账号已被禁用")
+	ErrUserExists         = errors.New("该账号已注册")
+	ErrInvalidToken       = errors.New("登录已失效，请重新登录")
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,19}$`)
+
+var (
+	db          *gorm.DB
+	cfg         config.AppAuthConfig
+	emailSender codesender.Sender
+	smsSender   codesender.Sender
+)
+
+// Init 初始化终端用户认证，创建验证码发送器并启动过期会话和验证码清理
+func Init(database *gorm.DB, c config.AppAuthConfig) error {
+	c = applyDefaults(c)
+
+	email, err := codesender.NewEmail(c.EmailSender, c.SMTP)
+	if err != nil {
+		return err
+	}
+	sms, err := codesender.NewSMS(c.SMSSender, c.SMS)
+	if err != nil {
+		return err
+	}
+
+	db = database
+	cfg = c
+	emailSender = email
+	smsSender = sms
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			cleanup()
+		}
+	}()
+	log.Printf("[AppAuth] Initialized (email sender: %s, sms sender: %s)", c.EmailSender, c.SMSSender)
+	return nil
+}
+
+// applyDefaults 补全未配置的项
+func applyDefaults(c config.AppAuthConfig) config.AppAuthConfig {
+	if c.AccessTokenMinutes <= 0 {
+		c.AccessTokenMinutes = 120
+	}
+	if c.RefreshTokenDays <= 0 {
+		c.RefreshTokenDays = 30
+	}
+	if c.CodeTTLSeconds <= 0 {
+		c.CodeTTLSeconds = 300
+	}
+	if c.CodeResendSeconds <= 0 {
+		c.CodeResendSeconds = 60
+	}
+	if c.CodeMaxAttempts <= 0 {
+		c.CodeMaxAttempts = 5
+	}
+	if c.PasswordMinLength <= 0 {
+		c.PasswordMinLength = 8
+	}
+	if c.EmailSender == "" {
+		c.EmailSender = codesender.KindLog
+	}
+	if c.SMSSender == "" {
+		c.SMSSender = codesender.KindLog
+	}
+	return c
+}
+
+// cleanup 删除已过期的验证码和会话
+func cleanup() {
+	now := time.Now()
+	if err := db.Where("expires_at < ?", now.Add(-time.Hour)).Delete(&model.UserVerifyCode{}).Error; err != nil {
+		log.Printf("[AppAuth] Failed to clean expired codes: %v", err)
+	}
+	if err := db.Where("refresh_expires_at < ? OR revoked_at < ?", now, now.AddDate(0, 0, -7)).
+		Delete(&model.UserSession{}).Error; err != nil {
+		log.Printf("[AppAuth] Failed to clean expired sessions: %v", err)
+	}
+}
+
+// Target 登录标识：邮箱或手机号
+type Target struct {
+	Value   string
+	Channel string // email 或 sms
+}
+
+// ParseTarget 解析并规范化登录标识，邮箱转为小写，手机号去掉空格和连字符
+func ParseTarget(s string) (Target, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "@") {
+		s = strings.ToLower(s)
+		if validator.ValidateEmail(s) != nil {
+			return Target{}, ErrInvalidTarget
+		}
+		return Target{Value: s, Channel: model.UserCodeChannelEmail}, nil
+	}
+
+	s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if !phonePattern.MatchString(s) {
+		return Target{}, ErrInvalidTarget
+	}
+	return Target{Value: s, Channel: model.UserCodeChannelSMS}, nil
+}
+
+// column 登录标识对应的用户字段
+func (t Target) column() string {
+	if t.Channel == model.UserCodeChannelEmail {
+		return "email"
+	}
+	return "phone"
+}
+
+// ValidatePassword 校验终端用户密码长度
+func ValidatePassword(password string) error {
+	if len(password) < cfg.PasswordMinLength || len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: 长度应在%d-%d位之间", ErrInvalidPassword, cfg.PasswordMinLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// findUser 按登录标识查找应用用户，同一标识有多个用户时（如外部同步的数据）取最早的一个
+func findUser(appID uint, t Target) (*model.User, error) {
+	var user model.User
+	err := db.Where("app_id = ? AND "+t.column()+" = ?", appID, t.Value).Order("id").First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// createUser 创建原生应用用户，open_id 随机生成
+func createUser(appID uint, t Target, passwordHash, nickname string) (*model.User, error) {
+	openID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	user := model.User{
+		AppID:        appID,
+		OpenID:       "u_" + openID,
+		Nickname:     nickname,
+		Status:       model.UserStatusNormal,
+		Source:       "native",
+		PasswordHash: passwordHash,
+	}
+	if t.Channel == model.UserCodeChannelEmail {
+		user.Email = t.Value
+	} else {
+		user.Phone = t.Value
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Register 使用密码注册；配置要求验证时需先通过注册验证码
+func Register(appID uint, t Target, password, code, nickname string) (*model.User, error) {
+	if err := ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	if _, err := findUser(appID, t); err == nil {
+		return nil, ErrUserExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if cfg.RegisterRequiresCode {
+		if err := VerifyCode(appID, t, model.UserCodePurposeRegister, code); err != nil {
+			return nil, err
+		}
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return createUser(appID, t, string(hashed), nickname)
+}
+
+// Login 密码登录
+func Login(appID uint, t Target, password string) (*model.User, error) {
+	user, err := findUser(appID, t)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if user.Status != model.UserStatusNormal {
+		return nil, ErrUserDisabled
+	}
+	return user, nil
+}
+
+// LoginByCode 验证码登录，用户不存在时自动注册，created 表示本次新建了用户
+func LoginByCode(appID uint, t Target, code string) (user *model.User, created bool, err error) {
+	if err := VerifyCode(appID, t, model.UserCodePurposeLogin, code); err != nil {
+		return nil, false, err
+	}
+
+	user, err = findUser(appID, t)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = createUser(appID, t, "", "")
+		return user, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if user.Status != model.UserStatusNormal {
+		return nil, false, ErrUserDisabled
+	}
+	return user, false, nil
+}
+
+// ResetPassword 通过验证码重置密码，并注销该用户的全部会话
+func ResetPassword(appID uint, t Target, code, newPassword string) error {
+	if err := ValidatePassword(newPassword); err != nil {
+		return err
+	}
+	if err := VerifyCode(appID, t, model.UserCodePurposeResetPassword, code); err != nil {
+		return err
+	}
+
+	user, err := findUser(appID, t)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCodeInvalid
+	}
+	if err != nil {
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(user).Update("password_hash", string(hashed)).Error; err != nil {
+		return err
+	}
+	return RevokeUser(appID, user.ID)
+}
+
+// Tokens 签发给终端用户的令牌
+type Tokens struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int       `json:"expires_in"` // 访问令牌有效期（秒）
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// newTokenPair 生成一对令牌及其哈希
+func newTokenPair() (tokens Tokens, accessHash, refreshHash string, err error) {
+	access, err := randomHex(32)
+	if err != nil {
+		return tokens, "", "", err
+	}
+	refresh, err := randomHex(32)
+	if err != nil {
+		return tokens, "", "", err
+	}
+	tokens = Tokens{
+		AccessToken:  AccessTokenPrefix + access,
+		RefreshToken: RefreshTokenPrefix + refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    cfg.AccessTokenMinutes * 60,
+	}
+	return tokens, HashToken(tokens.AccessToken), HashToken(tokens.RefreshToken), nil
+}
+
+// IssueSession 为登录成功的用户创建会话，并记录最近登录时间
+func IssueSession(user *model.User, clientIP, userAgent string) (*Tokens, error) {
+	tokens, accessHash, refreshHash, err := newTokenPair()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := model.UserSession{
+		AppID:            user.AppID,
+		UserID:           user.ID,
+		AccessTokenHash:  accessHash,
+		RefreshTokenHash: refreshHash,
+		AccessExpiresAt:  now.Add(time.Duration(cfg.AccessTokenMinutes) * time.Minute),
+		RefreshExpiresAt: now.AddDate(0, 0, cfg.RefreshTokenDays),
+		ClientIP:         clientIP,
+		UserAgent:        truncate(userAgent, 500),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(user).UpdateColumn("last_login_at", now).Error; err != nil {
+		log.Printf("[AppAuth] Failed to update last login of user %d: %v", user.ID, err)
+	}
+	user.LastLoginAt = &now
+
+	tokens.RefreshExpiresAt = session.RefreshExpiresAt
+	return &tokens, nil
+}
+
+// Refresh 使用刷新令牌换取新的令牌对，旧的访问令牌和刷新令牌同时失效
+func Refresh(appID uint, refreshToken string) (*Tokens, *model.User, error) {
+	var tokens Tokens
+	var user model.User
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var session model.UserSession
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_token_hash = ? AND app_id = ?", HashToken(refreshToken), appID).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		if session.RevokedAt != nil || time.Now().After(session.RefreshExpiresAt) {
+			return ErrInvalidToken
+		}
+
+		if err := tx.Where("app_id = ?", appID).First(&user, session.UserID).Error; err != nil {
+			return ErrInvalidToken
+		}
+		if user.Status != model.UserStatusNormal {
+			return ErrUserDisabled
+		}
+
+		var accessHash, refreshHash string
+		tokens, accessHash, refreshHash, err = newTokenPair()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		session.RefreshExpiresAt = now.AddDate(0, 0, cfg.RefreshTokenDays)
+		tokens.RefreshExpiresAt = session.RefreshExpiresAt
+		return tx.Model(&session).Updates(map[string]interface{}{
+			"access_token_hash":  accessHash,
+			"refresh_token_hash": refreshHash,
+			"access_expires_at":  now.Add(time.Duration(cfg.AccessTokenMinutes) * time.Minute),
+			"refresh_expires_at": session.RefreshExpiresAt,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tokens, &user, nil
+}
+
+// Authenticate 校验访问令牌，返回令牌所属的用户和会话；用户被禁用时返回 ErrUserDisabled
+func Authenticate(appID uint, accessToken string) (*model.User, *model.UserSession, error) {
+	if !strings.HasPrefix(accessToken, AccessTokenPrefix) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var session model.UserSession
+	if err := db.Where("access_token_hash = ? AND app_id = ?", HashToken(accessToken), appID).
+		First(&session).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	if session.RevokedAt != nil || time.Now().After(session.AccessExpiresAt) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user model.User
+	if err := db.Where("app_id = ?", appID).First(&user, session.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidToken
+	}
+	if user.Status != model.UserStatusNormal {
+		return nil, nil, ErrUserDisabled
+	}
+	return &user, &session, nil
+}
+
+// Revoke 注销一个会话
+func Revoke(sessionID uint) error {
+	return db.Model(&model.UserSession{}).Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUser 注销用户的全部会话
+func RevokeUser(appID, userID uint) error {
+	return db.Model(&model.UserSession{}).Where("app_id = ? AND user_id = ? AND revoked_at IS NULL", appID, userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// HashToken 计算令牌哈希，数据库中不保存明文
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,101 @@
+package appauth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/model"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		value   string
+		channel string
+		wantErr bool
+	}{
+		{" Alice@Example.COM ", "alice@example.com", model.UserCodeChannelEmail, false},
+		{"+86 138-0000-0000", "+8613800000000", model.UserCodeChannelSMS, false},
+		{"13800000000", "13800000000", model.UserCodeChannelSMS, false},
+		{"alice@", "", "", true},
+		{"12345", "", "", true},
+		{"abc", "", "", true},
+		{"", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTarget(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTarget) {
+					t.Errorf("err = %v, want ErrInvalidTarget", err)
+				}
+				return
+			}
+			if err != nil || got.Value != tt.value || got.Channel != tt.channel {
+				t.Errorf("ParseTarget(%q) = %+v, %v; want %s/%s", tt.in, got, err, tt.value, tt.channel)
+			}
+		})
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	cfg = applyDefaults(config.AppAuthConfig{PasswordMinLength: 10})
+	defer func() { cfg = config.AppAuthConfig{} }()
+
+	if err := ValidatePassword("short"); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("short password: err = %v", err)
+	}
+	if err := ValidatePassword(strings.Repeat("a", maxPasswordLength+1)); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("long password: err = %v", err)
+	}
+	if err := ValidatePassword("long-enough-pw"); err != nil {
+		t.Errorf("valid password: err = %v", err)
+	}
+}
+
+func TestNewTokenPair(t *testing.T) {
+	cfg = applyDefaults(config.AppAuthConfig{})
+	defer func() { cfg = config.AppAuthConfig{} }()
+
+	tokens, accessHash, refreshHash, err := newTokenPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(tokens.AccessToken, AccessTokenPrefix) || !strings.HasPrefix(tokens.RefreshToken, RefreshTokenPrefix) {
+		t.Errorf("unexpected token prefixes: %+v", tokens)
+	}
+	if accessHash != HashToken(tokens.AccessToken) || refreshHash != HashToken(tokens.RefreshToken) || accessHash == refreshHash {
+		t.Error("hashes do not match tokens")
+	}
+	if tokens.ExpiresIn != 120*60 || tokens.TokenType != "Bearer" {
+		t.Errorf("ExpiresIn = %d, TokenType = %q", tokens.ExpiresIn, tokens.TokenType)
+	}
+
+	other, _, _, _ := newTokenPair()
+	if other.AccessToken == tokens.AccessToken {
+		t.Error("tokens should be random")
+	}
+}
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := generateCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != codeLength || strings.Trim(code, "0123456789") != "" {
+			t.Fatalf("code %q is not %d digits", code, codeLength)
+		}
+	}
+}
+
+func TestAuthenticate_RejectsForeignTokens(t *testing.T) {
+	// 非终端用户令牌在查库前即被拒绝
+	for _, token := range []string{"", "apt_abc", "eyJhbGciOiJIUzI1NiJ9.x.y", RefreshTokenPrefix + "abc"} {
+		if _, _, err := Authenticate(1, token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Authenticate(%q): err = %v, want ErrInvalidToken", token, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package appauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/codesender"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// codeLength 验证码位数
+const codeLength = 6
+
+var (
+	ErrInvalidPurpose  = errors.New("无效的验证码用途")
+	ErrCodeInvalid     = errors.New("验证码错误或已过期")
+	ErrCodeTooFrequent = errors.New("验证码发送过于频繁，请稍后再试")
+)
+
+// validPurposes 支持的验证码用途
+var validPurposes = map[string]bool{
+	model.UserCodePurposeLogin:         true,
+	model.UserCodePurposeRegister:      true,
+	model.UserCodePurposeResetPassword: true,
+}
+
+// SendCode 生成并发送一次性验证码
+// 注册验证码在账号已存在时返回 ErrUserExists；重置密码的目标账号不存在时不发送也不报错，避免泄露账号是否注册
+func SendCode(ctx context.Context, app *model.App, t Target, purpose string) error {
+	if !validPurposes[purpose] {
+		return ErrInvalidPurpose
+	}
+
+	var last model.UserVerifyCode
+	err := db.Where("app_id = ? AND target = ? AND purpose = ?", app.ID, t.Value, purpose).
+		Order("id DESC").First(&last).Error
+	if err == nil && time.Since(last.CreatedAt) < time.Duration(cfg.CodeResendSeconds)*time.Second {
+		return ErrCodeTooFrequent
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if purpose != model.UserCodePurposeLogin {
+		_, err := findUser(app.ID, t)
+		exists := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if purpose == model.UserCodePurposeRegister && exists {
+			return ErrUserExists
+		}
+		if purpose == model.UserCodePurposeResetPassword && !exists {
+			return nil
+		}
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return err
+	}
+	ttl := time.Duration(cfg.CodeTTLSeconds) * time.Second
+	record := model.UserVerifyCode{
+		AppID:     app.ID,
+		Target:    t.Value,
+		Channel:   t.Channel,
+		Purpose:   purpose,
+		CodeHash:  HashToken(code),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return err
+	}
+
+	sender := smsSender
+	if t.Channel == model.UserCodeChannelEmail {
+		sender = emailSender
+	}
+	msg := codesender.Message{To: t.Value, Code: code, Purpose: purpose, AppName: app.Name, TTL: ttl}
+	if err := sender.Send(ctx, msg); err != nil {
+		db.Delete(&record)
+		return fmt.Errorf("send code: %w", err)
+	}
+	return nil
+}
+
+// VerifyCode 校验并消费验证码，只有最近发送的一条有效，错误次数达到上限后作废
+func VerifyCode(appID uint, t Target, purpose, code string) error {
+	if code == "" {
+		return ErrCodeInvalid
+	}
+
+	matched := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var record model.UserVerifyCode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("app_id = ? AND target = ? AND purpose = ?", appID, t.Value, purpose).
+			Order("id DESC").First(&record).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if record.UsedAt != nil || time.Now().After(record.ExpiresAt) || record.Attempts >= cfg.CodeMaxAttempts {
+			return nil
+		}
+
+		if subtle.ConstantTimeCompare([]byte(HashToken(code)), []byte(record.CodeHash)) != 1 {
+			return tx.Model(&record).UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+		}
+		matched = true
+		return tx.Model(&record).UpdateColumn("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return err
+	}
+	if !matched {
+		return ErrCodeInvalid
+	}
+	return nil
+}
+
+// generateCode 生成数字验证码
+func generateCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < codeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", codeLength, n), nil
+}
@@ -7,10 +7,20 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	JWT      JWTConfig      `yaml:"jwt"`
-	CORS     CORSConfig     `yaml:"cors"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Security  SecurityConfig  `yaml:"security"`
+	SDK       SDKConfig       `yaml:"sdk"`
+	AppPurge  AppPurgeConfig  `yaml:"app_purge"`
+	Dashboard DashboardConfig `yaml:"dashboard"`
+	AppAuth   AppAuthConfig   `yaml:"app_auth"`
+	Push      PushConfig      `yaml:"push"`
+	// UserImport 应用用户批量导入配置
+	UserImport UserImportConfig `yaml:"user_import"`
+	// IdentitySources 外部身份源，可将其中的用户同步为应用用户
+	IdentitySources []IdentitySourceConfig `yaml:"identity_sources"`
 }
 
 type ServerConfig struct {
@@ -29,6 +39,144 @@ type DatabaseConfig struct {
 type JWTConfig struct {
 	Secret string `yaml:"secret"`
 	Expire int    `yaml:"expire_hours"`
+	// Algorithm 签名算法：HS256（默认，使用 secret）、RS256 或 EdDSA
+	Algorithm string `yaml:"algorithm"`
+	// RotationHours 非对称密钥自动轮换周期，0 表示只手动轮换
+	RotationHours int `yaml:"rotation_hours"`
+	// AllowHS256 非对称模式下仍接受 secret 签发的 HS256 令牌，用于平滑迁移
+	AllowHS256 bool `yaml:"allow_hs256"`
+}
+
+// SecurityConfig 管理员账号安全相关配置
+type SecurityConfig struct {
+	MFA      MFAConfig            `yaml:"mfa"`
+	Lockout  LockoutConfig        `yaml:"lockout"`
+	Password PasswordPolicyConfig `yaml:"password"`
+	SSO      SSOConfig            `yaml:"sso"`
+}
+
+// MFAConfig 双因素认证配置
+type MFAConfig struct {
+	Enforce             bool   `yaml:"enforce"`               // 是否强制所有管理员启用TOTP
+	Issuer              string `yaml:"issuer"`                // 验证器App中显示的发行方名称
+	ChallengeTTLMinutes int    `yaml:"challenge_ttl_minutes"` // 登录挑战令牌有效期（分钟）
+}
+
+// LockoutConfig 按用户名统计登录失败并渐进锁定
+type LockoutConfig struct {
+	MaxAttempts     int `yaml:"max_attempts"`      // 窗口期内允许的连续失败次数
+	WindowMinutes   int `yaml:"window_minutes"`    // 失败次数统计窗口（分钟）
+	BaseLockMinutes int `yaml:"base_lock_minutes"` // 首次锁定时长，之后每次锁定翻倍
+	MaxLockMinutes  int `yaml:"max_lock_minutes"`  // 单次锁定时长上限
+}
+
+// PasswordPolicyConfig 管理员密码策略
+type PasswordPolicyConfig struct {
+	MinLength        int    `yaml:"min_length"`
+	MaxLength        int    `yaml:"max_length"`
+	RequireUpper     bool   `yaml:"require_upper"`
+	RequireLower     bool   `yaml:"require_lower"`
+	RequireDigit     bool   `yaml:"require_digit"`
+	RequireSymbol    bool   `yaml:"require_symbol"`
+	BreachedListPath string `yaml:"breached_list_path"` // 本地泄露密码列表文件，每行一个
+	HistoryCount     int    `yaml:"history_count"`      // 禁止重复使用最近N个密码
+	MaxAgeDays       int    `yaml:"max_age_days"`       // 密码最长使用天数，0表示不限制
+}
+
+// SSOConfig 管理后台 OpenID Connect 单点登录配置
+type SSOConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	SSOOnly      bool     `yaml:"sso_only"` // 启用后禁用密码登录（break_glass_usernames 除外）
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"` // 前端回调页地址，需在身份提供方登记
+	Scopes       []string `yaml:"scopes"`
+	// RoleClaim 用于角色映射的声明名称，如 groups、roles
+	RoleClaim string `yaml:"role_claim"`
+	// RoleMapping 声明值到管理员角色（admin/viewer）的映射
+	RoleMapping map[string]string `yaml:"role_mapping"`
+	// DefaultRole 未匹配任何映射时的角色，为空表示拒绝登录
+	DefaultRole string `yaml:"default_role"`
+	// AllowedEmailDomains 允许登录的邮箱域名，为空表示不限制
+	AllowedEmailDomains []string `yaml:"allowed_email_domains"`
+	// BreakGlassUsernames 仅SSO模式下仍允许密码登录的应急账号
+	BreakGlassUsernames []string `yaml:"break_glass_usernames"`
+}
+
+// SDKConfig 客户端SDK签名认证配置
+type SDKConfig struct {
+	ClockSkewSeconds int   `yaml:"clock_skew_seconds"` // 允许的客户端时钟偏差（秒），同时决定nonce保留时长
+	MaxBodyBytes     int64 `yaml:"max_body_bytes"`     // 参与签名的请求体大小上限
+	// SecretEncryptionKey 应用密钥加密存储使用的主密钥，修改后已有密钥将无法解密
+	SecretEncryptionKey string `yaml:"secret_encryption_key"`
+	SecretGraceHours    int    `yaml:"secret_grace_hours"` // 轮换后旧密钥的默认保留时长（小时）
+}
+
+// AppPurgeConfig 已删除应用的恢复期和数据清理配置
+type AppPurgeConfig struct {
+	RestoreDays     int `yaml:"restore_days"`     // 删除后可恢复的天数，期满后清理全部应用数据
+	BatchSize       int `yaml:"batch_size"`       // 每批删除的记录数
+	IntervalMinutes int `yaml:"interval_minutes"` // 检查待清理应用的间隔（分钟）
+}
+
+// DashboardConfig 控制台首页统计配置
+type DashboardConfig struct {
+	CacheSeconds          int `yaml:"cache_seconds"`           // 统计结果缓存时长（秒）
+	RollupIntervalMinutes int `yaml:"rollup_interval_minutes"` // 每日统计汇总的刷新间隔（分钟）
+	TrendDays             int `yaml:"trend_days"`              // 首次启动时回填的天数，同时是趋势接口的最大天数
+}
+
+// IdentitySourceConfig 外部身份源配置
+type IdentitySourceConfig struct {
+	Name  string `yaml:"name"`  // 身份源名称，同步接口按名称选择，同时记录为用户来源
+	Type  string `yaml:"type"`  // 身份源类型，目前支持 manus
+	DSN   string `yaml:"dsn"`   // 身份源数据库连接
+	Table string `yaml:"table"` // 用户表名，默认 users
+}
+
+// AppAuthConfig 应用终端用户认证配置
+type AppAuthConfig struct {
+	AccessTokenMinutes   int  `yaml:"access_token_minutes"`   // 访问令牌有效期（分钟）
+	RefreshTokenDays     int  `yaml:"refresh_token_days"`     // 刷新令牌有效期（天）
+	CodeTTLSeconds       int  `yaml:"code_ttl_seconds"`       // 验证码有效期（秒）
+	CodeResendSeconds    int  `yaml:"code_resend_seconds"`    // 同一目标重新发送验证码的最小间隔（秒）
+	CodeMaxAttempts      int  `yaml:"code_max_attempts"`      // 验证码最多可尝试次数，超过后作废
+	PasswordMinLength    int  `yaml:"password_min_length"`    // 终端用户密码最小长度
+	RegisterRequiresCode bool `yaml:"register_requires_code"` // 密码注册是否需要先验证邮箱或手机号
+	// EmailSender 邮件验证码发送方式：log（仅写日志，用于本地测试）或 smtp
+	EmailSender string           `yaml:"email_sender"`
+	SMSSender   string           `yaml:"sms_sender"` // 短信验证码发送方式：log 或 http
+	SMTP        SMTPSenderConfig `yaml:"smtp"`
+	SMS         SMSSenderConfig  `yaml:"sms"`
+}
+
+// SMTPSenderConfig 邮件验证码发送配置
+type SMTPSenderConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+// SMSSenderConfig 短信服务商HTTP接口配置，验证码以JSON POST到 Endpoint
+type SMSSenderConfig struct {
+	Endpoint string `yaml:"endpoint"`
+	APIKey   string `yaml:"api_key"`  // 以 Authorization: Bearer 头发送
+	Sign     string `yaml:"sign"`     // 短信签名
+	Template string `yaml:"template"` // 短信模板ID
+}
+
+// PushConfig 推送服务配置
+type PushConfig struct {
+	// EnableMockProvider 注册本地模拟通道（mock），只记录不投递，仅用于测试和本地联调
+	EnableMockProvider bool `yaml:"enable_mock_provider"`
+}
+
+// UserImportConfig 应用用户批量导入配置
+type UserImportConfig struct {
+	Path string `yaml:"path"` // 上传的导入文件和错误报告的存储目录
 }
 
 type CORSConfig struct {
@@ -0,0 +1,376 @@
+// Package dashboard 控制台首页统计：总量从业务表统计，趋势和应用排行读取每日统计汇总表，结果按组织范围缓存一小段时间
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// dateLayout 汇总表日期格式
+const dateLayout = "2006-01-02"
+
+// Overview 平台总览
+type Overview struct {
+	AppCount        int64     `json:"app_count"`
+	ActiveAppCount  int64     `json:"active_app_count"`
+	ModuleCount     int64     `json:"module_count"`
+	UserCount       int64     `json:"user_count"`
+	TodayNew        int64     `json:"today_new"` // 今日新增用户
+	EventsToday     int64     `json:"events_today"`
+	ErrorsToday     int64     `json:"errors_today"`
+	ActiveAlerts    int64     `json:"active_alerts"`
+	StorageBytes    int64     `json:"storage_bytes"`
+	PushesSentToday int64     `json:"pushes_sent_today"`
+	GeneratedAt     time.Time `json:"generated_at"`
+}
+
+// TrendPoint 某一天的汇总，没有数据的日期补0
+type TrendPoint struct {
+	Date       string `json:"date"`
+	NewUsers   int64  `json:"new_users"`
+	Events     int64  `json:"events"`
+	Errors     int64  `json:"errors"`
+	PushesSent int64  `json:"pushes_sent"`
+}
+
+// AppRollup 单个应用的汇总
+type AppRollup struct {
+	AppID        uint   `json:"app_id"`
+	Name         string `json:"name"`
+	Status       int    `json:"status"`
+	UserCount    int64  `json:"user_count"`
+	ModuleCount  int64  `json:"module_count"`
+	EventsToday  int64  `json:"events_today"`
+	ErrorsToday  int64  `json:"errors_today"`
+	Events       int64  `json:"events"` // 统计区间内的事件数
+	Errors       int64  `json:"errors"`
+	StorageBytes int64  `json:"storage_bytes"`
+}
+
+// AppCount 应用列表展示的启用模块数和用户数
+type AppCount struct {
+	ModuleCount int64
+	UserCount   int64
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// Service 控制台统计服务
+type Service struct {
+	db      *gorm.DB
+	ttl     time.Duration
+	maxDays int
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+// NewService 创建统计服务，ttl 为结果缓存时长，maxDays 为趋势可查询的最大天数
+func NewService(db *gorm.DB, ttl time.Duration, maxDays int) *Service {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	if maxDays <= 0 {
+		maxDays = 30
+	}
+	return &Service{db: db, ttl: ttl, maxDays: maxDays, cache: map[string]cacheEntry{}}
+}
+
+// MaxDays 趋势可查询的最大天数
+func (s *Service) MaxDays() int {
+	return s.maxDays
+}
+
+// Overview 组织范围内的平台总览
+func (s *Service) Overview(orgIDs []uint) (*Overview, error) {
+	v, err := s.cached(cacheKey("overview", orgIDs), func() (interface{}, error) {
+		return s.overview(orgIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*Overview), nil
+}
+
+// Trends 组织范围内最近 days 天的每日汇总，按日期升序
+func (s *Service) Trends(orgIDs []uint, days int) ([]TrendPoint, error) {
+	if days <= 0 || days > s.maxDays {
+		days = s.maxDays
+	}
+	v, err := s.cached(cacheKey(fmt.Sprintf("trends:%d", days), orgIDs), func() (interface{}, error) {
+		return s.trends(orgIDs, days)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]TrendPoint), nil
+}
+
+// AppRollups 组织范围内各应用最近 days 天的汇总，按事件数降序取前 limit 个
+func (s *Service) AppRollups(orgIDs []uint, days, limit int) ([]AppRollup, error) {
+	if days <= 0 || days > s.maxDays {
+		days = s.maxDays
+	}
+	v, err := s.cached(cacheKey(fmt.Sprintf("apps:%d:%d", days, limit), orgIDs), func() (interface{}, error) {
+		return s.appRollups(orgIDs, days, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]AppRollup), nil
+}
+
+func (s *Service) overview(orgIDs []uint) (*Overview, error) {
+	o := &Overview{GeneratedAt: time.Now()}
+	today := today(o.GeneratedAt).Format(dateLayout)
+
+	var apps struct {
+		Total  int64
+		Active int64
+	}
+	if err := s.db.Model(&model.App{}).Where("org_id IN ?", orgIDs).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END), 0) AS active").
+		Scan(&apps).Error; err != nil {
+		return nil, err
+	}
+	o.AppCount, o.ActiveAppCount = apps.Total, apps.Active
+
+	appIDs := s.appIDs(orgIDs)
+	steps := []func() error{
+		func() error {
+			return s.db.Model(&model.ModuleTemplate{}).Where("status = 1").Count(&o.ModuleCount).Error
+		},
+		func() error {
+			return s.db.Model(&model.User{}).Where("app_id IN (?)", appIDs).Count(&o.UserCount).Error
+		},
+		func() error {
+			return s.db.Model(&model.MonitorAlert{}).
+				Where("app_id IN (?) AND status = ? AND is_active = 1", appIDs, "alerting").
+				Count(&o.ActiveAlerts).Error
+		},
+		func() error {
+			return s.db.Model(&model.File{}).Where("app_id IN (?)", appIDs).
+				Select("COALESCE(SUM(file_size), 0)").Scan(&o.StorageBytes).Error
+		},
+		func() error {
+			var day TrendPoint
+			if err := s.db.Model(&model.AppDailyStat{}).Where("app_id IN (?) AND date = ?", appIDs, today).
+				Select("COALESCE(SUM(new_users), 0) AS new_users, COALESCE(SUM(events), 0) AS events, " +
+					"COALESCE(SUM(errors), 0) AS errors, COALESCE(SUM(pushes_sent), 0) AS pushes_sent").
+				Scan(&day).Error; err != nil {
+				return err
+			}
+			o.TodayNew, o.EventsToday, o.ErrorsToday, o.PushesSentToday = day.NewUsers, day.Events, day.Errors, day.PushesSent
+			return nil
+		},
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (s *Service) trends(orgIDs []uint, days int) ([]TrendPoint, error) {
+	start := today(time.Now()).AddDate(0, 0, -(days - 1))
+
+	var rows []TrendPoint
+	if err := s.db.Model(&model.AppDailyStat{}).
+		Where("app_id IN (?) AND date >= ?", s.appIDs(orgIDs), start.Format(dateLayout)).
+		Select("DATE_FORMAT(date, '%Y-%m-%d') AS date, SUM(new_users) AS new_users, SUM(events) AS events, " +
+			"SUM(errors) AS errors, SUM(pushes_sent) AS pushes_sent").
+		Group("date").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return FillTrend(rows, start, days), nil
+}
+
+func (s *Service) appRollups(orgIDs []uint, days, limit int) ([]AppRollup, error) {
+	var apps []model.App
+	if err := s.db.Where("org_id IN ?", orgIDs).Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	if len(apps) == 0 {
+		return []AppRollup{}, nil
+	}
+	ids := make([]uint, len(apps))
+	for i, a := range apps {
+		ids[i] = a.ID
+	}
+
+	counts, err := AppCounts(s.db, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start := today(now).AddDate(0, 0, -(days - 1)).Format(dateLayout)
+	var stats []struct {
+		AppID       uint
+		Events      int64
+		Errors      int64
+		EventsToday int64
+		ErrorsToday int64
+	}
+	if err := s.db.Model(&model.AppDailyStat{}).
+		Where("app_id IN ? AND date >= ?", ids, start).
+		Select("app_id, SUM(events) AS events, SUM(errors) AS errors, "+
+			"SUM(CASE WHEN date = ? THEN events ELSE 0 END) AS events_today, "+
+			"SUM(CASE WHEN date = ? THEN errors ELSE 0 END) AS errors_today", today(now).Format(dateLayout), today(now).Format(dateLayout)).
+		Group("app_id").
+		Scan(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	var storage []struct {
+		AppID uint
+		Bytes int64
+	}
+	if err := s.db.Model(&model.File{}).Where("app_id IN ?", ids).
+		Select("app_id, COALESCE(SUM(file_size), 0) AS bytes").Group("app_id").
+		Scan(&storage).Error; err != nil {
+		return nil, err
+	}
+
+	rollups := make(map[uint]*AppRollup, len(apps))
+	result := make([]AppRollup, len(apps))
+	for i, a := range apps {
+		result[i] = AppRollup{
+			AppID:       a.ID,
+			Name:        a.Name,
+			Status:      a.Status,
+			UserCount:   counts[a.ID].UserCount,
+			ModuleCount: counts[a.ID].ModuleCount,
+		}
+		rollups[a.ID] = &result[i]
+	}
+	for _, st := range stats {
+		if r, ok := rollups[st.AppID]; ok {
+			r.Events, r.Errors, r.EventsToday, r.ErrorsToday = st.Events, st.Errors, st.EventsToday, st.ErrorsToday
+		}
+	}
+	for _, st := range storage {
+		if r, ok := rollups[st.AppID]; ok {
+			r.StorageBytes = st.Bytes
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Events != result[j].Events {
+			return result[i].Events > result[j].Events
+		}
+		return result[i].AppID < result[j].AppID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// AppCounts 批量统计应用的启用模块数和用户数
+func AppCounts(db *gorm.DB, appIDs []uint) (map[uint]AppCount, error) {
+	counts := make(map[uint]AppCount, len(appIDs))
+	if len(appIDs) == 0 {
+		return counts, nil
+	}
+
+	var modules, users []struct {
+		AppID uint
+		N     int64
+	}
+	if err := db.Model(&model.AppModule{}).Where("app_id IN ? AND status = 1", appIDs).
+		Select("app_id, COUNT(*) AS n").Group("app_id").Scan(&modules).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Model(&model.User{}).Where("app_id IN ?", appIDs).
+		Select("app_id, COUNT(*) AS n").Group("app_id").Scan(&users).Error; err != nil {
+		return nil, err
+	}
+
+	for _, m := range modules {
+		c := counts[m.AppID]
+		c.ModuleCount = m.N
+		counts[m.AppID] = c
+	}
+	for _, u := range users {
+		c := counts[u.AppID]
+		c.UserCount = u.N
+		counts[u.AppID] = c
+	}
+	return counts, nil
+}
+
+// FillTrend 按日期排列从 start 开始的 days 天，缺少汇总的日期补0
+func FillTrend(rows []TrendPoint, start time.Time, days int) []TrendPoint {
+	byDate := make(map[string]TrendPoint, len(rows))
+	for _, r := range rows {
+		byDate[r.Date] = r
+	}
+	points := make([]TrendPoint, days)
+	for i := range points {
+		date := start.AddDate(0, 0, i).Format(dateLayout)
+		p := byDate[date]
+		p.Date = date
+		points[i] = p
+	}
+	return points
+}
+
+// appIDs 组织范围内应用ID的子查询
+func (s *Service) appIDs(orgIDs []uint) *gorm.DB {
+	return s.db.Model(&model.App{}).Select("id").Where("org_id IN ?", orgIDs)
+}
+
+// cached 返回未过期的缓存结果，否则重新计算并缓存
+func (s *Service) cached(key string, load func() (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	if e, ok := s.cache[key]; ok && time.Now().Before(e.expires) {
+		s.mu.Unlock()
+		return e.value, nil
+	}
+	s.mu.Unlock()
+
+	v, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 顺带清理过期项，组织范围组合有限，缓存不会无限增长
+	for k, e := range s.cache {
+		if now.After(e.expires) {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = cacheEntry{value: v, expires: now.Add(s.ttl)}
+	return v, nil
+}
+
+// cacheKey 统计类型加排序后的组织ID，同一组织范围的管理员共享缓存
+func cacheKey(kind string, orgIDs []uint) string {
+	ids := append([]uint(nil), orgIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return kind + ":" + strings.Join(parts, ",")
+}
+
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,70 @@
+package dashboard
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFillTrend(t *testing.T) {
+	start := time.Date(2024, 2, 27, 0, 0, 0, 0, time.Local)
+	rows := []TrendPoint{
+		{Date: "2024-02-28", Events: 5, NewUsers: 1},
+		{Date: "2024-03-01", Errors: 2},
+		{Date: "2024-01-01", Events: 99}, // 超出范围的日期被忽略
+	}
+
+	got := FillTrend(rows, start, 4)
+	want := []TrendPoint{
+		{Date: "2024-02-27"},
+		{Date: "2024-02-28", Events: 5, NewUsers: 1},
+		{Date: "2024-02-29"},
+		{Date: "2024-03-01", Errors: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("FillTrend() returned %d points, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("point %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCacheKeyIgnoresOrgOrder(t *testing.T) {
+	if a, b := cacheKey("overview", []uint{3, 1, 2}), cacheKey("overview", []uint{1, 2, 3}); a != b {
+		t.Errorf("cacheKey() = %q and %q, want equal", a, b)
+	}
+	if a, b := cacheKey("overview", []uint{1}), cacheKey("trends:30", []uint{1}); a == b {
+		t.Errorf("cacheKey() for different kinds should differ: %q", a)
+	}
+}
+
+func TestCached(t *testing.T) {
+	s := NewService(nil, time.Minute, 30)
+	calls := 0
+	load := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if v, err := s.cached("k", load); err != nil || v.(int) != 1 {
+			t.Fatalf("cached() = %v, %v; want 1", v, err)
+		}
+	}
+
+	s.cache["k"] = cacheEntry{value: 1, expires: time.Now().Add(-time.Second)}
+	if v, _ := s.cached("k", load); v.(int) != 2 {
+		t.Errorf("expired entry not reloaded: got %v", v)
+	}
+
+	// 加载失败的结果不缓存
+	failing := func() (interface{}, error) { return nil, errors.New("db down") }
+	if _, err := s.cached("err", failing); err == nil {
+		t.Error("cached() should return load error")
+	}
+	if _, ok := s.cache["err"]; ok {
+		t.Error("failed load should not be cached")
+	}
+}
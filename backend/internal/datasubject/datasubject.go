@@ -0,0 +1,230 @@
+// Package datasubject 数据主体请求：导出某个应用用户在各模块中的全部数据，或删除并匿名化这些数据
+// 各模块实现 module.UserDataExporter 和 module.UserDataEraser 参与，新模块实现接口后自动覆盖。
+// 删除完成后会再次调用各模块的导出统计残留记录，作为可核验的删除报告
+package datasubject
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrSubjectNotFound 用户不存在或已删除
+var ErrSubjectNotFound = errors.New("用户不存在")
+
+// Report 导出或删除报告
+type Report struct {
+	RequestID   uint                     `json:"request_id"`
+	Type        string                   `json:"type"`
+	AppID       uint                     `json:"app_id"`
+	UserID      uint                     `json:"user_id"`
+	GeneratedAt time.Time                `json:"generated_at"`
+	Modules     map[string]*ModuleReport `json:"modules"`
+	Verified    *bool                    `json:"verified,omitempty"` // 删除请求：删除后各模块均无残留记录
+}
+
+// ModuleReport 单个模块的处理结果，键为数据类别
+type ModuleReport struct {
+	Exported  map[string]int64 `json:"exported,omitempty"`
+	Erased    map[string]int64 `json:"erased,omitempty"`
+	Remaining map[string]int64 `json:"remaining,omitempty"`
+}
+
+// Seal 序列化报告并计算其SHA-256，保存的报告和摘要可用于事后核对
+func (r *Report) Seal() (string, string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return string(data), hex.EncodeToString(sum[:]), nil
+}
+
+// hook 一个模块的导出和删除钩子，模块可只实现其中之一
+type hook struct {
+	code     string
+	exporter module.UserDataExporter
+	eraser   module.UserDataEraser
+}
+
+// registeredHooks 已注册模块中实现了导出或删除接口的，按模块编码排序
+func registeredHooks() []hook {
+	var hooks []hook
+	for _, m := range module.GetAllModules() {
+		h := hook{code: m.Meta().Code}
+		h.exporter, _ = m.(module.UserDataExporter)
+		h.eraser, _ = m.(module.UserDataEraser)
+		if h.exporter != nil || h.eraser != nil {
+			hooks = append(hooks, h)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].code < hooks[j].code })
+	return hooks
+}
+
+// LoadSubject 按用户记录和已识别为该用户的匿名设备确定数据主体
+func LoadSubject(db *gorm.DB, appID, userID uint) (*module.DataSubject, error) {
+	var user model.User
+	if err := db.Where("app_id = ? AND id = ?", appID, userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSubjectNotFound
+		}
+		return nil, err
+	}
+	subject := &module.DataSubject{
+		AppID:  appID,
+		UserID: user.ID,
+		OpenID: user.OpenID,
+		Email:  user.Email,
+		Phone:  user.Phone,
+	}
+	if err := db.Model(&model.AnonymousProfile{}).
+		Where("app_id = ? AND user_id = ?", appID, userID).
+		Order("id").Pluck("anonymous_id", &subject.AnonymousIDs).Error; err != nil {
+		return nil, err
+	}
+	return subject, nil
+}
+
+// EncodeSubject 序列化数据主体快照
+func EncodeSubject(subject *module.DataSubject) (string, error) {
+	data, err := json.Marshal(subject)
+	return string(data), err
+}
+
+// DecodeSubject 解析数据主体快照
+func DecodeSubject(raw string) (*module.DataSubject, error) {
+	var subject module.DataSubject
+	if err := json.Unmarshal([]byte(raw), &subject); err != nil {
+		return nil, err
+	}
+	return &subject, nil
+}
+
+// Export 将各模块中与数据主体相关的数据写入 zip 归档，归档根目录包含报告，返回报告和归档大小
+func Export(req *model.DataSubjectRequest, subject *module.DataSubject, archivePath string) (*Report, int64, error) {
+	return export(registeredHooks(), req, subject, archivePath)
+}
+
+func export(hooks []hook, req *model.DataSubjectRequest, subject *module.DataSubject, archivePath string) (*Report, int64, error) {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return nil, 0, err
+	}
+	tmpPath := archivePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer os.Remove(tmpPath)
+	defer f.Close()
+
+	report := newReport(req)
+	zw := zip.NewWriter(f)
+	for _, h := range hooks {
+		if h.exporter == nil {
+			continue
+		}
+		w := &zipWriter{zw: zw, prefix: h.code, counts: map[string]int64{}}
+		if err := h.exporter.ExportUserData(*subject, w); err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", h.code, err)
+		}
+		report.module(h.code).Exported = w.counts
+	}
+
+	rf, err := zw.Create("report.json")
+	if err != nil {
+		return nil, 0, err
+	}
+	enc := json.NewEncoder(rf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, 0, err
+	}
+	if err := os.Rename(tmpPath, archivePath); err != nil {
+		return nil, 0, err
+	}
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	return report, info.Size(), nil
+}
+
+// Erase 调用各模块删除或匿名化数据主体的数据，再统计各模块的残留记录
+func Erase(req *model.DataSubjectRequest, subject *module.DataSubject) (*Report, error) {
+	return erase(registeredHooks(), req, subject)
+}
+
+func erase(hooks []hook, req *model.DataSubjectRequest, subject *module.DataSubject) (*Report, error) {
+	report := newReport(req)
+	for _, h := range hooks {
+		if h.eraser == nil {
+			continue
+		}
+		erased, err := h.eraser.EraseUserData(*subject)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", h.code, err)
+		}
+		report.module(h.code).Erased = erased
+	}
+
+	verified := true
+	for _, h := range hooks {
+		if h.exporter == nil {
+			continue
+		}
+		w := &countWriter{counts: map[string]int64{}}
+		if err := h.exporter.ExportUserData(*subject, w); err != nil {
+			return nil, fmt.Errorf("%s: verify: %w", h.code, err)
+		}
+		remaining := make(map[string]int64)
+		for name, n := range w.counts {
+			remaining[name] = n
+			if n > 0 {
+				verified = false
+			}
+		}
+		report.module(h.code).Remaining = remaining
+	}
+	report.Verified = &verified
+	return report, nil
+}
+
+func newReport(req *model.DataSubjectRequest) *Report {
+	return &Report{
+		RequestID:   req.ID,
+		Type:        req.Type,
+		AppID:       req.AppID,
+		UserID:      req.UserID,
+		GeneratedAt: time.Now(),
+		Modules:     map[string]*ModuleReport{},
+	}
+}
+
+func (r *Report) module(code string) *ModuleReport {
+	m, ok := r.Modules[code]
+	if !ok {
+		m = &ModuleReport{}
+		r.Modules[code] = m
+	}
+	return m
+}
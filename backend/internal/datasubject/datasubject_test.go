@@ -0,0 +1,185 @@
+package datasubject
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+)
+
+// fakeModule 内存中的模块数据，按用户ID保存消息和文件
+type fakeModule struct {
+	messages map[uint][]string
+	files    map[uint]string
+}
+
+func (m *fakeModule) ExportUserData(s module.DataSubject, w module.UserDataWriter) error {
+	if err := w.WriteRecords("messages", m.messages[s.UserID]); err != nil {
+		return err
+	}
+	if content, ok := m.files[s.UserID]; ok {
+		return w.WriteFile("files/avatar.png", strings.NewReader(content))
+	}
+	return nil
+}
+
+func (m *fakeModule) EraseUserData(s module.DataSubject) (map[string]int64, error) {
+	erased := map[string]int64{"messages": int64(len(m.messages[s.UserID]))}
+	delete(m.messages, s.UserID)
+	if _, ok := m.files[s.UserID]; ok {
+		erased["files"] = 1
+		delete(m.files, s.UserID)
+	}
+	return erased, nil
+}
+
+// leakyEraser 删除后仍能导出数据，用于验证核验失败
+type leakyEraser struct{ fakeModule }
+
+func (m *leakyEraser) EraseUserData(s module.DataSubject) (map[string]int64, error) {
+	return map[string]int64{"messages": 0}, nil
+}
+
+func newFake() *fakeModule {
+	return &fakeModule{
+		messages: map[uint][]string{7: {"hello", "world"}, 8: {"other"}},
+		files:    map[uint]string{7: "png-bytes"},
+	}
+}
+
+func TestExport(t *testing.T) {
+	fake := newFake()
+	hooks := []hook{{code: "message", exporter: fake}}
+	req := &model.DataSubjectRequest{ID: 1, AppID: 3, UserID: 7, Type: model.DataRequestExport}
+	archive := filepath.Join(t.TempDir(), "app_3", "request_1.zip")
+
+	report, size, err := export(hooks, req, &module.DataSubject{AppID: 3, UserID: 7}, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size <= 0 {
+		t.Errorf("size = %d", size)
+	}
+	got := report.Modules["message"].Exported
+	if got["messages"] != 2 || got["files"] != 1 {
+		t.Errorf("exported = %v", got)
+	}
+
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var names []string
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(data)
+	}
+	sort.Strings(names)
+	want := []string{"message/files/avatar.png", "message/messages.json", "report.json"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("archive entries = %v, want %v", names, want)
+	}
+	var messages []string
+	if err := json.Unmarshal([]byte(contents["message/messages.json"]), &messages); err != nil || len(messages) != 2 {
+		t.Errorf("messages.json = %q", contents["message/messages.json"])
+	}
+	if contents["message/files/avatar.png"] != "png-bytes" {
+		t.Errorf("file content = %q", contents["message/files/avatar.png"])
+	}
+}
+
+func TestErase_Verified(t *testing.T) {
+	fake := newFake()
+	hooks := []hook{{code: "message", exporter: fake, eraser: fake}}
+	req := &model.DataSubjectRequest{ID: 2, AppID: 3, UserID: 7, Type: model.DataRequestErase}
+
+	report, err := erase(hooks, req, &module.DataSubject{AppID: 3, UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := report.Modules["message"]
+	if m.Erased["messages"] != 2 || m.Erased["files"] != 1 {
+		t.Errorf("erased = %v", m.Erased)
+	}
+	if m.Remaining["messages"] != 0 {
+		t.Errorf("remaining = %v", m.Remaining)
+	}
+	if report.Verified == nil || !*report.Verified {
+		t.Error("report should be verified")
+	}
+	if len(fake.messages[8]) != 1 {
+		t.Error("other users' data must not be erased")
+	}
+}
+
+func TestErase_NotVerifiedWhenDataRemains(t *testing.T) {
+	leaky := &leakyEraser{*newFake()}
+	hooks := []hook{{code: "message", exporter: leaky, eraser: leaky}}
+	req := &model.DataSubjectRequest{ID: 3, AppID: 3, UserID: 7, Type: model.DataRequestErase}
+
+	report, err := erase(hooks, req, &module.DataSubject{AppID: 3, UserID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Verified == nil || *report.Verified {
+		t.Error("report should not be verified")
+	}
+	if report.Modules["message"].Remaining["messages"] != 2 {
+		t.Errorf("remaining = %v", report.Modules["message"].Remaining)
+	}
+}
+
+func TestReportSeal(t *testing.T) {
+	report := &Report{RequestID: 1, Type: model.DataRequestErase, Modules: map[string]*ModuleReport{}}
+	data1, hash1, err := report.Seal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, hash2, _ := report.Seal()
+	if data1 != data2 || hash1 != hash2 || len(hash1) != 64 {
+		t.Errorf("seal not stable: %s %s", hash1, hash2)
+	}
+	report.UserID = 9
+	if _, hash3, _ := report.Seal(); hash3 == hash1 {
+		t.Error("hash should change with report content")
+	}
+}
+
+func TestCountKey(t *testing.T) {
+	tests := map[string]string{
+		"events":           "events",
+		"events/part-0001": "events",
+		"../files/a.png":   "files",
+	}
+	for name, want := range tests {
+		if got := countKey(name); got != want {
+			t.Errorf("countKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSubjectRoundTrip(t *testing.T) {
+	subject := &module.DataSubject{AppID: 1, UserID: 2, Email: "a@example.com", AnonymousIDs: []string{"dev-1"}}
+	raw, err := EncodeSubject(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeSubject(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != subject.Email || len(got.AnonymousIDs) != 1 || got.UserID != 2 {
+		t.Errorf("decoded = %+v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package datasubject
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+)
+
+// zipWriter 将各模块导出的数据写入 zip 归档，每个模块一个目录
+type zipWriter struct {
+	zw     *zip.Writer
+	prefix string
+	counts map[string]int64
+}
+
+// WriteRecords 以JSON数组写入一组记录，并按记录数计数
+func (w *zipWriter) WriteRecords(name string, records interface{}) error {
+	n, err := recordCount(records)
+	if err != nil {
+		return err
+	}
+	f, err := w.zw.Create(w.path(name) + ".json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(records); err != nil {
+		return err
+	}
+	w.counts[countKey(name)] += n
+	return nil
+}
+
+// WriteFile 原样写入文件内容，按文件数计数
+func (w *zipWriter) WriteFile(name string, r io.Reader) error {
+	f, err := w.zw.Create(w.path(name))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		return err
+	}
+	w.counts[countKey(name)]++
+	return nil
+}
+
+func (w *zipWriter) path(name string) string {
+	return path.Join(w.prefix, path.Clean("/" + name)[1:])
+}
+
+// countWriter 只统计记录数不保存内容，用于删除后核验残留数据
+type countWriter struct {
+	counts map[string]int64
+}
+
+func (w *countWriter) WriteRecords(name string, records interface{}) error {
+	n, err := recordCount(records)
+	if err != nil {
+		return err
+	}
+	w.counts[countKey(name)] += n
+	return nil
+}
+
+func (w *countWriter) WriteFile(name string, r io.Reader) error {
+	w.counts[countKey(name)]++
+	return nil
+}
+
+// recordCount 记录数，records 必须是切片或数组
+func recordCount(records interface{}) (int64, error) {
+	v := reflect.ValueOf(records)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0, fmt.Errorf("records must be a slice, got %T", records)
+	}
+	return int64(v.Len()), nil
+}
+
+// countKey 计数的类别取路径的第一段，分批导出的 events/part-0001 计入 events
+func countKey(name string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
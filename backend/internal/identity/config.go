@@ -0,0 +1,33 @@
+package identity
+
+import (
+	"fmt"
+	"log"
+
+	"app-platform-backend/internal/config"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Init 按配置连接并注册外部身份源
+func Init(cfgs []config.IdentitySourceConfig) error {
+	for _, cfg := range cfgs {
+		if cfg.Name == "" || cfg.Name == SourceNative {
+			return fmt.Errorf("identity source name %q is invalid", cfg.Name)
+		}
+		switch cfg.Type {
+		case "manus":
+			db, err := gorm.Open(mysql.Open(cfg.DSN), &gorm.Config{Logger: logger.Default.LogMode(logger.Warn)})
+			if err != nil {
+				return fmt.Errorf("identity source %s: %w", cfg.Name, err)
+			}
+			Register(cfg.Name, NewManusSource(db, cfg.Table))
+		default:
+			return fmt.Errorf("identity source %s: unsupported type %q", cfg.Name, cfg.Type)
+		}
+		log.Printf("[Identity] Registered %s source %s", cfg.Type, cfg.Name)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Package identity 外部身份源适配：将其他系统中的用户同步到应用用户表
+// 应用用户以 (app_id, open_id) 唯一，外部用户的ID作为 open_id，来源记录在 source 字段
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SourceNative 通过本平台接口注册的用户
+const SourceNative = "native"
+
+// defaultBatchSize 每次从外部身份源拉取的用户数
+const defaultBatchSize = 500
+
+// ErrUnknownSource 未注册的身份源
+var ErrUnknownSource = errors.New("身份源不存在")
+
+// ExternalUser 外部身份源中的用户
+type ExternalUser struct {
+	ExternalID  string
+	Nickname    string
+	Email       string
+	Phone       string
+	Avatar      string
+	Disabled    bool
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+// Source 外部身份源，按游标分页返回用户，游标为空表示从头开始，返回空游标表示已全部返回
+type Source interface {
+	Fetch(ctx context.Context, cursor string, limit int) (users []ExternalUser, next string, err error)
+}
+
+// SyncResult 一次同步的结果
+type SyncResult struct {
+	Source  string `json:"source"`
+	Fetched int    `json:"fetched"` // 从身份源读取的用户数
+	Created int64  `json:"created"` // 新增的应用用户数
+}
+
+var (
+	mu      sync.RWMutex
+	sources = map[string]Source{}
+)
+
+// Register 注册身份源，名称重复时覆盖
+func Register(name string, src Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	sources[name] = src
+}
+
+// Get 按名称获取身份源
+func Get(name string) (Source, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	src, ok := sources[name]
+	return src, ok
+}
+
+// Names 已注册的身份源名称，按名称排序
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sync 将身份源中的全部用户同步到应用：新用户按外部状态创建，已有用户只更新资料，
+// 不覆盖在本平台修改过的状态
+func Sync(ctx context.Context, db *gorm.DB, appID uint, name string, batchSize int) (*SyncResult, error) {
+	src, ok := Get(name)
+	if !ok {
+		return nil, ErrUnknownSource
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	var before int64
+	if err := db.Model(&model.User{}).Where("app_id = ?", appID).Count(&before).Error; err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{Source: name}
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		users, next, err := src.Fetch(ctx, cursor, batchSize)
+		if err != nil {
+			return result, fmt.Errorf("fetch from %s: %w", name, err)
+		}
+		if len(users) > 0 {
+			if err := upsert(db, appID, name, users); err != nil {
+				return result, err
+			}
+			result.Fetched += len(users)
+		}
+		if next == "" || next == cursor {
+			break
+		}
+		cursor = next
+	}
+
+	var after int64
+	if err := db.Model(&model.User{}).Where("app_id = ?", appID).Count(&after).Error; err != nil {
+		return result, err
+	}
+	result.Created = after - before
+	return result, nil
+}
+
+// upsert 按 (app_id, open_id) 写入一批用户
+func upsert(db *gorm.DB, appID uint, source string, users []ExternalUser) error {
+	rows := ToModels(appID, source, users)
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}, {Name: "open_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"nickname", "email", "phone", "avatar", "last_login_at", "source", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+// ToModels 将外部用户转换为应用用户
+func ToModels(appID uint, source string, users []ExternalUser) []model.User {
+	rows := make([]model.User, len(users))
+	for i, u := range users {
+		status := model.UserStatusNormal
+		if u.Disabled {
+			status = model.UserStatusDisabled
+		}
+		rows[i] = model.User{
+			AppID:       appID,
+			OpenID:      u.ExternalID,
+			Nickname:    u.Nickname,
+			Avatar:      u.Avatar,
+			Phone:       u.Phone,
+			Email:       u.Email,
+			Status:      status,
+			Source:      source,
+			LastLoginAt: u.LastLoginAt,
+			CreatedAt:   u.CreatedAt,
+		}
+	}
+	return rows
+}
@@ -0,0 +1,86 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"app-platform-backend/internal/model"
+)
+
+type staticSource struct{}
+
+func (staticSource) Fetch(ctx context.Context, cursor string, limit int) ([]ExternalUser, string, error) {
+	return nil, "", nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("test_b", staticSource{})
+	Register("test_a", staticSource{})
+	defer func() {
+		mu.Lock()
+		delete(sources, "test_a")
+		delete(sources, "test_b")
+		mu.Unlock()
+	}()
+
+	if _, ok := Get("test_a"); !ok {
+		t.Fatal("registered source not found")
+	}
+	if _, ok := Get("missing"); ok {
+		t.Error("unregistered source should not be found")
+	}
+	names := Names()
+	if len(names) != 2 || names[0] != "test_a" || names[1] != "test_b" {
+		t.Errorf("Names() = %v, want sorted [test_a test_b]", names)
+	}
+}
+
+func TestSync_UnknownSource(t *testing.T) {
+	if _, err := Sync(context.Background(), nil, 1, "missing", 0); !errors.Is(err, ErrUnknownSource) {
+		t.Errorf("err = %v, want ErrUnknownSource", err)
+	}
+}
+
+func TestToModels(t *testing.T) {
+	rows := ToModels(7, "manus", []ExternalUser{
+		{ExternalID: "a", Nickname: "Alice"},
+		{ExternalID: "b", Disabled: true},
+	})
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	for _, r := range rows {
+		if r.AppID != 7 || r.Source != "manus" {
+			t.Errorf("row %s: app_id = %d, source = %q", r.OpenID, r.AppID, r.Source)
+		}
+	}
+	if rows[0].Status != model.UserStatusNormal || rows[0].Nickname != "Alice" {
+		t.Errorf("rows[0] = %+v", rows[0])
+	}
+	if rows[1].Status != model.UserStatusDisabled {
+		t.Errorf("disabled user status = %d, want %d", rows[1].Status, model.UserStatusDisabled)
+	}
+}
+
+func TestManusToExternal(t *testing.T) {
+	name, email := "Bob", "bob@example.com"
+	tests := []struct {
+		name string
+		in   manusUser
+		want ExternalUser
+	}{
+		{"full", manusUser{ID: 1, OpenID: "o1", Name: &name, Email: &email},
+			ExternalUser{ExternalID: "o1", Nickname: "Bob", Email: "bob@example.com"}},
+		{"nil fields", manusUser{ID: 2, OpenID: "o2"}, ExternalUser{ExternalID: "o2"}},
+		{"missing open id", manusUser{ID: 3}, ExternalUser{ExternalID: "manus:3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := manusToExternal(&tt.in)
+			if got.ExternalID != tt.want.ExternalID || got.Nickname != tt.want.Nickname || got.Email != tt.want.Email {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package identity
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// manusUser Manus 平台用户表结构，字段为驼峰命名，没有应用归属
+type manusUser struct {
+	ID           int        `gorm:"column:id;primaryKey"`
+	OpenID       string     `gorm:"column:openId"`
+	Name         *string    `gorm:"column:name"`
+	Email        *string    `gorm:"column:email"`
+	CreatedAt    time.Time  `gorm:"column:createdAt"`
+	LastSignedIn *time.Time `gorm:"column:lastSignedIn"`
+}
+
+// ManusSource 从 Manus 平台用户表读取用户，游标为已读取的最大ID
+type ManusSource struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewManusSource 创建 Manus 用户表身份源，table 为空时使用 users
+func NewManusSource(db *gorm.DB, table string) *ManusSource {
+	if table == "" {
+		table = "users"
+	}
+	return &ManusSource{db: db, table: table}
+}
+
+// Fetch 按ID升序读取一批用户
+func (s *ManusSource) Fetch(ctx context.Context, cursor string, limit int) ([]ExternalUser, string, error) {
+	lastID, _ := strconv.Atoi(cursor)
+
+	var rows []manusUser
+	if err := s.db.WithContext(ctx).Table(s.table).
+		Where("id > ?", lastID).Order("id ASC").Limit(limit).
+		Find(&rows).Error; err != nil {
+		return nil, "", err
+	}
+	if len(rows) == 0 {
+		return nil, "", nil
+	}
+
+	users := make([]ExternalUser, len(rows))
+	for i, r := range rows {
+		users[i] = manusToExternal(&r)
+	}
+	next := ""
+	if len(rows) == limit {
+		next = strconv.Itoa(rows[len(rows)-1].ID)
+	}
+	return users, next, nil
+}
+
+func manusToExternal(r *manusUser) ExternalUser {
+	u := ExternalUser{
+		ExternalID:  r.OpenID,
+		CreatedAt:   r.CreatedAt,
+		LastLoginAt: r.LastSignedIn,
+	}
+	if u.ExternalID == "" {
+		u.ExternalID = "manus:" + strconv.Itoa(r.ID)
+	}
+	if r.Name != nil {
+		u.Nickname = *r.Name
+	}
+	if r.Email != nil {
+		u.Email = *r.Email
+	}
+	return u
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestScopedRoute_RegistersFullPath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v1 := r.Group("/api/v1")
+	files := v1.Group("/files")
+
+	ScopedRoute(v1, http.MethodPost, "/versions/:id/publish", "version_publish", func(c *gin.Context) {
+		c.String(http.StatusOK, c.FullPath())
+	})
+	ScopedRoute(files, http.MethodPost, "", "file_upload", func(c *gin.Context) {
+		c.String(http.StatusOK, c.FullPath())
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		key    string
+		scope  string
+	}{
+		{http.MethodPost, "/api/v1/versions/12/publish", "POST /api/v1/versions/:id/publish", "version_publish"},
+		{http.MethodPost, "/api/v1/files", "POST /api/v1/files", "file_upload"},
+	}
+
+	for _, tt := range tests {
+		if got := tokenScopes[tt.key]; got != tt.scope {
+			t.Errorf("tokenScopes[%q] = %q, want %q", tt.key, got, tt.scope)
+		}
+
+		// 登记的键必须与运行时 c.FullPath() 一致，否则令牌将无法匹配路由
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		r.ServeHTTP(w, req)
+		if key := tt.method + " " + w.Body.String(); key != tt.key {
+			t.Errorf("runtime route key = %q, want %q", key, tt.key)
+		}
+	}
+
+	scopes := TokenScopes()
+	if len(scopes) < 2 || scopes[0] > scopes[len(scopes)-1] {
+		t.Errorf("TokenScopes() = %v, want sorted list containing registered scopes", scopes)
+	}
+}
+
+func TestCheckAppAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 管理员会话不受应用范围限制
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if !CheckAppAccess(c, 99) {
+		t.Error("admin session should access any app")
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Set(apiTokenKey, &APITokenContext{ID: 1, AppIDs: map[uint]bool{3: true}})
+	if !CheckAppAccess(c, 3) {
+		t.Error("token should access granted app")
+	}
+	if CheckAppAccess(c, 4) {
+		t.Error("token should not access other apps")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAuthenticateAPIToken_AttributesToCreator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	expires := time.Now().Add(time.Hour)
+	db, _ := sqltest.Open(t, func(query string, args []driver.Value) sqltest.Reply {
+		if strings.Contains(query, "FROM `api_tokens`") {
+			return sqltest.Reply{
+				Columns: []string{"id", "name", "scopes", "app_ids", "created_by", "expires_at", "last_used_at"},
+				Rows:    [][]driver.Value{{int64(7), "ci", `["version_publish"]`, `[3]`, int64(42), expires, time.Now()}},
+			}
+		}
+		return sqltest.Reply{Affected: 1}
+	})
+	defer func(old *gorm.DB) { sessionDB = old }(sessionDB)
+	sessionDB = db
+
+	r := gin.New()
+	var userID uint
+	var actor string
+	ScopedRoute(r.Group("/api/v1"), http.MethodPost, "/token-test/:id", "version_publish", func(c *gin.Context) {
+		if !authenticateAPIToken(c, APITokenPrefix+"secret") {
+			return
+		}
+		userID, actor = c.GetUint("user_id"), auditActorID(c)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/token-test/1", nil))
+
+	// created_by 等字段记录签发人，审计日志记录令牌
+	if userID != 42 {
+		t.Errorf("user_id = %d, want token creator 42", userID)
+	}
+	if actor != "token:7" {
+		t.Errorf("audit actor = %q, want token:7", actor)
+	}
+}
@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopedAppKey 应用范围中间件写入上下文的应用
+const scopedAppKey = "scoped_app"
+
+// appScopeMaxPeekBytes 从请求体中查找 app_id 时最多读取的字节数
+const appScopeMaxPeekBytes = 4 << 20
+
+var appScopeDB *gorm.DB
+
+var (
+	errAppIDMissing = errors.New("app_id 不能为空")
+	errAppIDInvalid = errors.New("无效的 app_id")
+)
+
+// InitAppScope 初始化应用范围中间件
+func InitAppScope(db *gorm.DB) {
+	appScopeDB = db
+}
+
+// AppScopeMiddleware 解析请求所属应用并校验访问权限，通过后写入上下文
+// 来源优先级：SDK签名认证的应用 > 路径参数 app_id > 查询参数 app_id > 请求体 app_id（JSON或表单）
+// 资源按ID访问的接口也必须携带 app_id，处理函数通过 repository 按应用过滤，跨应用访问返回不存在
+func AppScopeMiddleware() gin.HandlerFunc {
+	return appScope(func(c *gin.Context) (uint, error) {
+		return requestAppID(c)
+	})
+}
+
+// AppScopeFromParam 从指定路径参数解析应用ID，用于 /apps/:id 这类以应用为资源的路由
+func AppScopeFromParam(name string) gin.HandlerFunc {
+	return appScope(func(c *gin.Context) (uint, error) {
+		return parseAppID(c.Param(name))
+	})
+}
+
+func appScope(resolve func(c *gin.Context) (uint, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// SDK请求只能访问签名所用的应用，忽略请求中携带的 app_id
+		if app, ok := GetClientApp(c); ok {
+			c.Set(scopedAppKey, app)
+			c.Next()
+			return
+		}
+
+		appID, err := resolve(c)
+		if err != nil {
+			response.ParamError(c, err.Error())
+			c.Abort()
+			return
+		}
+
+		var app model.App
+		if err := appScopeDB.First(&app, appID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				response.NotFound(c, "应用不存在")
+			} else {
+				response.DBError(c, err)
+			}
+			c.Abort()
+			return
+		}
+		if !CheckAppAccess(c, app.ID) || !checkAppOrgAccess(c, &app) {
+			c.Abort()
+			return
+		}
+
+		c.Set(scopedAppKey, &app)
+		c.Next()
+	}
+}
+
+// GetScopedApp 获取应用范围中间件解析出的应用
+func GetScopedApp(c *gin.Context) (*model.App, bool) {
+	v, ok := c.Get(scopedAppKey)
+	if !ok {
+		return nil, false
+	}
+	app, ok := v.(*model.App)
+	return app, ok
+}
+
+// ScopedAppID 获取当前请求所属应用ID，未经过应用范围中间件时返回0
+func ScopedAppID(c *gin.Context) uint {
+	if app, ok := GetScopedApp(c); ok {
+		return app.ID
+	}
+	return 0
+}
+
+// requestAppID 按优先级从路径、查询参数和请求体中读取 app_id
+func requestAppID(c *gin.Context) (uint, error) {
+	if v := c.Param("app_id"); v != "" {
+		return parseAppID(v)
+	}
+	if v := c.Query("app_id"); v != "" {
+		return parseAppID(v)
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		return 0, errAppIDMissing
+	}
+
+	contentType := c.ContentType()
+	switch {
+	case contentType == gin.MIMEJSON:
+		return bodyAppID(c)
+	case contentType == gin.MIMEMultipartPOSTForm || contentType == gin.MIMEPOSTForm:
+		// 表单解析结果会被缓存，处理函数仍可正常读取文件和字段
+		return parseAppID(c.PostForm("app_id"))
+	}
+	return 0, errAppIDMissing
+}
+
+// bodyAppID 读取JSON请求体中的 app_id，读取后恢复请求体供处理函数绑定
+func bodyAppID(c *gin.Context) (uint, error) {
+	if c.Request.Body == nil {
+		return 0, errAppIDMissing
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, appScopeMaxPeekBytes+1))
+	if err != nil {
+		return 0, errAppIDInvalid
+	}
+	if len(body) > appScopeMaxPeekBytes {
+		return 0, errors.New("请求体过大")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		AppID json.RawMessage `json:"app_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.AppID) == 0 {
+		return 0, errAppIDMissing
+	}
+	// 兼容数字和字符串两种写法
+	return parseAppID(strings.Trim(string(payload.AppID), `"`))
+}
+
+func parseAppID(s string) (uint, error) {
+	if s == "" || s == "null" {
+		return 0, errAppIDMissing
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, errAppIDInvalid
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"app-platform-backend/internal/appauth"
+	"app-platform-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 终端用户认证结果在上下文中的键
+const (
+	appUserKey        = "app_user"
+	appUserSessionKey = "app_user_session"
+	appUserErrKey     = "app_user_error"
+)
+
+// AppUserMiddleware 解析客户端请求携带的终端用户访问令牌（Authorization: Bearer uat_...）
+// 令牌有效时将用户写入上下文；未携带或无效时按匿名请求继续处理，需要登录的路由再使用 RequireAppUser。
+// 被封禁用户的请求直接拒绝，不降级为匿名请求
+func AppUserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+		app, ok := GetClientApp(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		user, session, err := appauth.Authenticate(app.ID, token)
+		if errors.Is(err, appauth.ErrUserDisabled) {
+			clientAbort(c, http.StatusForbidden, "User disabled")
+			return
+		}
+		if err != nil {
+			c.Set(appUserErrKey, err)
+			c.Next()
+			return
+		}
+		c.Set(appUserKey, user)
+		c.Set(appUserSessionKey, session)
+		c.Next()
+	}
+}
+
+// RequireAppUser 要求请求携带有效的终端用户访问令牌，必须位于 AppUserMiddleware 之后
+func RequireAppUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := GetAppUser(c); ok {
+			c.Next()
+			return
+		}
+		if v, ok := c.Get(appUserErrKey); ok {
+			if err, _ := v.(error); errors.Is(err, appauth.ErrUserDisabled) {
+				clientAbort(c, http.StatusForbidden, "User disabled")
+				return
+			}
+		}
+		clientAbort(c, http.StatusUnauthorized, "User login required")
+	}
+}
+
+// GetAppUser 获取令牌校验通过的终端用户
+func GetAppUser(c *gin.Context) (*model.User, bool) {
+	v, ok := c.Get(appUserKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := v.(*model.User)
+	return user, ok
+}
+
+// AppUserID 令牌校验通过的终端用户ID，其他模块可信任该ID而不是请求体中的 user_id
+func AppUserID(c *gin.Context) (uint, bool) {
+	user, ok := GetAppUser(c)
+	if !ok {
+		return 0, false
+	}
+	return user.ID, true
+}
+
+// GetAppUserSession 获取当前终端用户会话
+func GetAppUserSession(c *gin.Context) (*model.UserSession, bool) {
+	v, ok := c.Get(appUserSessionKey)
+	if !ok {
+		return nil, false
+	}
+	session, ok := v.(*model.UserSession)
+	return session, ok
+}
+
+func bearerToken(c *gin.Context) string {
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"app-platform-backend/internal/appauth"
+	"app-platform-backend/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireAppUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		setup func(c *gin.Context)
+		want  int
+	}{
+		{"anonymous", func(c *gin.Context) {}, http.StatusUnauthorized},
+		{"invalid token", func(c *gin.Context) { c.Set(appUserErrKey, appauth.ErrInvalidToken) }, http.StatusUnauthorized},
+		{"disabled user", func(c *gin.Context) { c.Set(appUserErrKey, appauth.ErrUserDisabled) }, http.StatusForbidden},
+		{"authenticated", func(c *gin.Context) { c.Set(appUserKey, &model.User{ID: 5}) }, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/me", func(c *gin.Context) { tt.setup(c) }, RequireAppUser(), func(c *gin.Context) {
+				if id, ok := AppUserID(c); !ok || id != 5 {
+					t.Errorf("AppUserID = %d, %v", id, ok)
+				}
+				c.Status(http.StatusOK)
+			})
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppUserMiddleware_AnonymousWithoutToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/events", AppUserMiddleware(), func(c *gin.Context) {
+		if _, ok := AppUserID(c); ok {
+			t.Error("request without token should be anonymous")
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for _, header := range []string{"", "Basic abc", "Bearer"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/events", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Authorization %q: status = %d", header, w.Code)
+		}
+	}
+}
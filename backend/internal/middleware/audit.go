@@ -164,8 +164,11 @@ func recordAuditLogEnhanced(c *gin.Context, duration int64, requestBody string)
 	}
 
 	// 从上下文获取用户信息
-	userID := getStringFromContext(c, "user_id")
+	userID := auditActorID(c)
 	userName := getStringFromContext(c, "user_name")
+	if userName == "" {
+		userName = getStringFromContext(c, "username")
+	}
 	
 	// 尝试从JWT claims获取
 	if userID == "" {
@@ -221,7 +224,87 @@ func recordAuditLogEnhanced(c *gin.Context, duration int64, requestBody string)
 	}
 }
 
+// RecordAuditEvent 记录业务层显式审计事件
+// 用于登录挑战、双因素重置等无法从请求路径推断语义的安全操作
+func RecordAuditEvent(c *gin.Context, action, resource, resourceID, description string, extra map[string]interface{}) {
+	if auditDB == nil {
+		return
+	}
+
+	extraJSON := ""
+	if extra != nil {
+		if data, err := json.Marshal(extra); err == nil {
+			extraJSON = string(data)
+		}
+	}
+
+	userName := getStringFromContext(c, "user_name")
+	if userName == "" {
+		userName = getStringFromContext(c, "username")
+	}
+
+	// 在当前协程中提取请求信息，gin.Context 在请求结束后会被复用
+	log := &AuditLog{
+		AppID:         extractAppID(c),
+		UserID:        auditActorID(c),
+		UserName:      userName,
+		Action:        action,
+		Resource:      resource,
+		ResourceID:    resourceID,
+		Description:   description,
+		IPAddress:     c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		RequestPath:   c.Request.URL.Path,
+		RequestMethod: c.Request.Method,
+		StatusCode:    c.Writer.Status(),
+		Extra:         extraJSON,
+		CreatedAt:     time.Now(),
+	}
+
+	go func() {
+		if err := auditDB.Create(log).Error; err != nil {
+			println("Failed to create audit log:", err.Error())
+		}
+	}()
+}
+
+// RecordSystemAuditEvent 记录后台任务触发的审计事件，例如封禁到期自动解除，操作人记为 system
+func RecordSystemAuditEvent(appID uint, action, resource, resourceID, description string, extra map[string]interface{}) {
+	if auditDB == nil {
+		return
+	}
+
+	extraJSON := ""
+	if extra != nil {
+		if data, err := json.Marshal(extra); err == nil {
+			extraJSON = string(data)
+		}
+	}
+
+	log := &AuditLog{
+		AppID:       appID,
+		UserName:    "system",
+		Action:      action,
+		Resource:    resource,
+		ResourceID:  resourceID,
+		Description: description,
+		Extra:       extraJSON,
+		CreatedAt:   time.Now(),
+	}
+	if err := auditDB.Create(log).Error; err != nil {
+		println("Failed to create audit log:", err.Error())
+	}
+}
+
 // getStringFromContext 从上下文获取字符串值
+// auditActorID 审计日志的操作者，API令牌请求记录为 token:<令牌ID>
+func auditActorID(c *gin.Context) string {
+	if id, ok := c.Get(apiTokenIDKey); ok {
+		return "token:" + toString(id)
+	}
+	return getStringFromContext(c, "user_id")
+}
+
 func getStringFromContext(c *gin.Context, key string) string {
 	if value, exists := c.Get(key); exists {
 		return toString(value)
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/pkg/signature"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// clientAppKey 签名校验通过后写入上下文的应用
+const clientAppKey = "client_app"
+
+// credentialTouchInterval 密钥最近使用时间的更新间隔，避免每个请求都写库
+const credentialTouchInterval = time.Minute
+
+var (
+	clientDB           *gorm.DB
+	clientSecretBox    *secretbox.Box
+	clientClockSkew          = 5 * time.Minute
+	clientMaxBodyBytes int64 = 2 << 20
+)
+
+// InitClientAuth 初始化客户端SDK签名认证，并启动过期nonce清理
+// box 用于解密加密存储的应用密钥
+func InitClientAuth(db *gorm.DB, cfg *config.SDKConfig, box *secretbox.Box) {
+	clientDB = db
+	clientSecretBox = box
+	if cfg.ClockSkewSeconds > 0 {
+		clientClockSkew = time.Duration(cfg.ClockSkewSeconds) * time.Second
+	}
+	if cfg.MaxBodyBytes > 0 {
+		clientMaxBodyBytes = cfg.MaxBodyBytes
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			// 时间戳超出偏差范围的请求会被直接拒绝，其nonce无需继续保留
+			cutoff := time.Now().Add(-2 * clientClockSkew)
+			if err := clientDB.Where("created_at < ?", cutoff).Delete(&model.SDKNonce{}).Error; err != nil {
+				log.Printf("[ClientAuth] Failed to clean expired nonces: %v", err)
+			}
+		}
+	}()
+}
+
+// ClientAuthMiddleware 客户端SDK签名认证中间件
+// 使用应用当前有效的密钥校验 HMAC-SHA256 签名，拒绝时钟偏差过大或nonce重复的请求，
+// 通过后将应用写入上下文，处理函数应使用 GetClientApp 获取应用而不是信任请求体中的 app_id
+func ClientAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		appKey := c.GetHeader(signature.HeaderAppID)
+		timestamp := c.GetHeader(signature.HeaderTimestamp)
+		nonce := c.GetHeader(signature.HeaderNonce)
+		sig := c.GetHeader(signature.HeaderSignature)
+		if appKey == "" || timestamp == "" || nonce == "" || sig == "" {
+			clientAbort(c, http.StatusUnauthorized, "Missing signature headers")
+			return
+		}
+		if len(nonce) < 8 || len(nonce) > 64 {
+			clientAbort(c, http.StatusUnauthorized, "Invalid nonce")
+			return
+		}
+
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			clientAbort(c, http.StatusUnauthorized, "Invalid timestamp")
+			return
+		}
+		skew := time.Since(time.Unix(ts, 0))
+		if skew > clientClockSkew || skew < -clientClockSkew {
+			clientAbort(c, http.StatusUnauthorized, "Request timestamp expired")
+			return
+		}
+
+		var app model.App
+		if err := clientDB.Where("app_id = ?", appKey).First(&app).Error; err != nil {
+			clientAbort(c, http.StatusUnauthorized, "Invalid app")
+			return
+		}
+		if app.Status != 1 {
+			clientAbort(c, http.StatusForbidden, "App disabled")
+			return
+		}
+
+		// 读取请求体计算摘要后放回，供后续处理函数绑定
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, clientMaxBodyBytes+1))
+		if err != nil {
+			clientAbort(c, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		if int64(len(body)) > clientMaxBodyBytes {
+			clientAbort(c, http.StatusRequestEntityTooLarge, "Request body too large")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		stringToSign := signature.StringToSign(
+			c.Request.Method,
+			c.Request.URL.Path,
+			signature.CanonicalQuery(c.Request.URL.Query()),
+			signature.BodyHash(body),
+			timestamp,
+			nonce,
+		)
+		cred := matchCredential(app.ID, stringToSign, sig)
+		if cred == nil {
+			clientAbort(c, http.StatusUnauthorized, "Invalid signature")
+			return
+		}
+
+		// 签名通过后再记录nonce，避免伪造请求占用nonce；唯一索引保证多实例下同样生效
+		result := clientDB.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.SDKNonce{AppID: app.ID, Nonce: nonce})
+		if result.Error != nil {
+			clientAbort(c, http.StatusInternalServerError, "Failed to verify nonce")
+			return
+		}
+		if result.RowsAffected == 0 {
+			clientAbort(c, http.StatusUnauthorized, "Nonce already used")
+			return
+		}
+
+		touchCredential(cred)
+
+		// SDK请求的环境由签名所用密钥决定
+		var env model.AppEnvironment
+		if err := clientDB.First(&env, cred.EnvID).Error; err != nil {
+			clientAbort(c, http.StatusForbidden, "Invalid environment")
+			return
+		}
+
+		c.Set(clientAppKey, &app)
+		c.Set(scopedEnvKey, &env)
+		c.Next()
+	}
+}
+
+// matchCredential 依次尝试应用当前有效的密钥（轮换过渡期内新旧密钥同时有效），返回签名匹配的密钥
+func matchCredential(appID uint, stringToSign, sig string) *model.AppCredential {
+	var creds []model.AppCredential
+	if err := clientDB.Where("app_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", appID, time.Now()).
+		Order("id DESC").Find(&creds).Error; err != nil {
+		return nil
+	}
+
+	for i := range creds {
+		secret, err := clientSecretBox.Decrypt(creds[i].SecretEncrypted)
+		if err != nil {
+			log.Printf("[ClientAuth] Failed to decrypt credential %d: %v", creds[i].ID, err)
+			continue
+		}
+		if signature.Verify(secret, stringToSign, sig) {
+			return &creds[i]
+		}
+	}
+	return nil
+}
+
+// touchCredential 更新密钥最近使用时间
+func touchCredential(cred *model.AppCredential) {
+	now := time.Now()
+	if cred.LastUsedAt != nil && now.Sub(*cred.LastUsedAt) < credentialTouchInterval {
+		return
+	}
+	clientDB.Model(&model.AppCredential{}).Where("id = ?", cred.ID).Update("last_used_at", now)
+}
+
+// GetClientApp 获取签名认证通过的应用，非客户端路由返回false
+func GetClientApp(c *gin.Context) (*model.App, bool) {
+	v, ok := c.Get(clientAppKey)
+	if !ok {
+		return nil, false
+	}
+	app, ok := v.(*model.App)
+	return app, ok
+}
+
+func clientAbort(c *gin.Context, status int, msg string) {
+	c.JSON(status, gin.H{"error": msg})
+	c.Abort()
+}
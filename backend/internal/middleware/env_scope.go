@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// scopedEnvKey 解析出的请求环境写入上下文的键
+const scopedEnvKey = "scoped_env"
+
+// HeaderAppEnv 管理端指定环境的请求头，也可使用查询参数 env
+const HeaderAppEnv = "X-App-Env"
+
+// RequestEnv 解析请求所属的应用环境，失败时写入响应并返回false
+// SDK请求使用签名密钥所属的环境；管理端按查询参数 env > 请求头 X-App-Env 选择，未指定时使用应用的默认环境
+// 需要在应用范围中间件之后调用
+func RequestEnv(c *gin.Context) (*model.AppEnvironment, bool) {
+	if v, ok := c.Get(scopedEnvKey); ok {
+		if env, ok := v.(*model.AppEnvironment); ok {
+			return env, true
+		}
+	}
+
+	appID := ScopedAppID(c)
+	code := c.Query("env")
+	if code == "" {
+		code = c.GetHeader(HeaderAppEnv)
+	}
+
+	var env model.AppEnvironment
+	query := appScopeDB.Where("app_id = ?", appID)
+	if code != "" {
+		query = query.Where("code = ?", code)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+	if err := query.Order("sort_order ASC").First(&env).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "环境不存在")
+		} else {
+			response.DBError(c, err)
+		}
+		return nil, false
+	}
+
+	c.Set(scopedEnvKey, &env)
+	return &env, true
+}
@@ -0,0 +1,355 @@
+package middleware
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/pkg/signingkey"
+
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// JWTAlgHS256 对称签名（兼容模式），使用配置中的 secret
+const JWTAlgHS256 = "HS256"
+
+const (
+	// jwtKeyReloadInterval 从数据库同步其他实例轮换结果的间隔
+	jwtKeyReloadInterval = time.Minute
+	// jwtKeyRetireMargin 旧公钥在最长令牌有效期之外额外保留的时间，覆盖各实例同步延迟
+	jwtKeyRetireMargin = time.Hour
+	// jwtUnknownKidReloadEvery 遇到未知kid时按需重新加载的最小间隔
+	jwtUnknownKidReloadEvery = 10 * time.Second
+)
+
+// ErrJWTKeyRotationUnsupported HS256 模式下不支持密钥轮换
+var ErrJWTKeyRotationUnsupported = errors.New("jwt: key rotation requires RS256 or EdDSA")
+
+var (
+	jwtAlgorithm   = JWTAlgHS256
+	jwtRotateEvery time.Duration
+	jwtAllowHS256  bool
+
+	jwtKeyDB  *gorm.DB
+	jwtKeyBox *secretbox.Box
+	jwtKeys   jwtKeyRing
+)
+
+// verifyKey 可用于校验的公钥
+type verifyKey struct {
+	alg string
+	pub crypto.PublicKey
+}
+
+// jwtKeyRing 当前签名私钥与全部有效公钥
+type jwtKeyRing struct {
+	mu       sync.RWMutex
+	kid      string
+	method   jwt.SigningMethod
+	private  crypto.Signer
+	public   map[string]verifyKey
+	loadedAt time.Time
+}
+
+// asymmetricJWT 是否使用非对称密钥签发令牌
+func asymmetricJWT() bool {
+	return jwtAlgorithm != JWTAlgHS256
+}
+
+// InitJWTKeys 加载非对称签名密钥，没有可用密钥时生成一个，并启动定时同步和轮换
+// HS256 模式下不做任何处理
+func InitJWTKeys(db *gorm.DB, box *secretbox.Box) error {
+	if !asymmetricJWT() {
+		return nil
+	}
+	if _, err := signingkey.Method(jwtAlgorithm); err != nil {
+		return fmt.Errorf("jwt: unsupported algorithm %q", jwtAlgorithm)
+	}
+	jwtKeyDB = db
+	jwtKeyBox = box
+
+	if err := reloadJWTKeys(); err != nil {
+		return err
+	}
+	if !hasSigningKey() {
+		if _, err := rotateJWTKey(0, false); err != nil {
+			return err
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(jwtKeyReloadInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if jwtRotateEvery > 0 {
+				if _, err := rotateJWTKey(0, false); err != nil {
+					log.Printf("[JWT] Scheduled key rotation failed: %v", err)
+				}
+			}
+			if err := jwtKeyDB.Where("status = ? AND verify_until < ?", model.JWTKeyStatusRetired, time.Now()).
+				Delete(&model.JWTSigningKey{}).Error; err != nil {
+				log.Printf("[JWT] Failed to clean expired keys: %v", err)
+			}
+			if err := reloadJWTKeys(); err != nil {
+				log.Printf("[JWT] Failed to reload keys: %v", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// RotateJWTSigningKey 立即轮换签名密钥，旧公钥保留到其签发的令牌全部过期
+func RotateJWTSigningKey(operatorID uint) (*model.JWTSigningKey, error) {
+	if !asymmetricJWT() {
+		return nil, ErrJWTKeyRotationUnsupported
+	}
+	return rotateJWTKey(operatorID, true)
+}
+
+// JWTPublicKeys 以 JWK 形式返回当前全部有效公钥，HS256 模式下为空
+func JWTPublicKeys() []map[string]interface{} {
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+
+	kids := make([]string, 0, len(jwtKeys.public))
+	for kid := range jwtKeys.public {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	keys := make([]map[string]interface{}, 0, len(kids))
+	for _, kid := range kids {
+		k := jwtKeys.public[kid]
+		if jwk, err := signingkey.JWK(kid, k.alg, k.pub); err == nil {
+			keys = append(keys, jwk)
+		}
+	}
+	return keys
+}
+
+// rotateJWTKey 在事务中停用当前密钥并生成新密钥
+// force 为 false 时仅在没有未到期的活跃密钥时轮换，多个实例同时触发也只会轮换一次
+func rotateJWTKey(operatorID uint, force bool) (*model.JWTSigningKey, error) {
+	// 先以普通查询判断是否到期，未到期时不生成密钥；事务中加锁后再次判断，避免多个实例重复轮换
+	if !force {
+		var active []model.JWTSigningKey
+		if err := jwtKeyDB.Where("status = ?", model.JWTKeyStatusActive).Find(&active).Error; err != nil {
+			return nil, err
+		}
+		if !jwtRotationDue(active) {
+			return nil, nil
+		}
+	}
+
+	priv, err := signingkey.Generate(jwtAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	privPEM, err := signingkey.MarshalPrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := jwtKeyBox.Encrypt(privPEM)
+	if err != nil {
+		return nil, err
+	}
+	pubPEM, err := signingkey.MarshalPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid, err := signingkey.NewKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	var created *model.JWTSigningKey
+	err = jwtKeyDB.Transaction(func(tx *gorm.DB) error {
+		var active []model.JWTSigningKey
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ?", model.JWTKeyStatusActive).Find(&active).Error; err != nil {
+			return err
+		}
+		if !force && !jwtRotationDue(active) {
+			return nil
+		}
+
+		now := time.Now()
+		verifyUntil := now.Add(time.Duration(jwtExpire)*time.Hour + jwtKeyRetireMargin)
+		if len(active) > 0 {
+			if err := tx.Model(&model.JWTSigningKey{}).Where("status = ?", model.JWTKeyStatusActive).
+				Updates(map[string]interface{}{
+					"status":       model.JWTKeyStatusRetired,
+					"retired_at":   now,
+					"verify_until": verifyUntil,
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		key := model.JWTSigningKey{
+			Kid:                 kid,
+			Algorithm:           jwtAlgorithm,
+			PublicKey:           pubPEM,
+			PrivateKeyEncrypted: encrypted,
+			Status:              model.JWTKeyStatusActive,
+			CreatedBy:           operatorID,
+		}
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		created = &key
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if created != nil {
+		log.Printf("[JWT] Signing key rotated, new kid=%s alg=%s", created.Kid, created.Algorithm)
+		if err := reloadJWTKeys(); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// jwtRotationDue 活跃密钥中没有当前算法且未到轮换周期的密钥时需要轮换
+func jwtRotationDue(active []model.JWTSigningKey) bool {
+	for _, k := range active {
+		if k.Algorithm == jwtAlgorithm && (jwtRotateEvery <= 0 || time.Since(k.CreatedAt) < jwtRotateEvery) {
+			return false
+		}
+	}
+	return true
+}
+
+// reloadJWTKeys 从数据库加载签名私钥和全部有效公钥
+func reloadJWTKeys() error {
+	var keys []model.JWTSigningKey
+	if err := jwtKeyDB.Where("status = ? OR verify_until > ?", model.JWTKeyStatusActive, time.Now()).
+		Order("id ASC").Find(&keys).Error; err != nil {
+		return err
+	}
+
+	public := make(map[string]verifyKey, len(keys))
+	var signer *model.JWTSigningKey
+	for i := range keys {
+		k := &keys[i]
+		pub, err := signingkey.ParsePublicKey(k.PublicKey)
+		if err != nil {
+			log.Printf("[JWT] Skipping invalid public key kid=%s: %v", k.Kid, err)
+			continue
+		}
+		public[k.Kid] = verifyKey{alg: k.Algorithm, pub: pub}
+		// 活跃密钥有多个时（实例并发初始化）使用最新的一个
+		if k.Status == model.JWTKeyStatusActive && k.Algorithm == jwtAlgorithm {
+			signer = k
+		}
+	}
+
+	var (
+		private crypto.Signer
+		method  jwt.SigningMethod
+	)
+	if signer != nil {
+		plain, err := jwtKeyBox.Decrypt(signer.PrivateKeyEncrypted)
+		if err != nil {
+			return fmt.Errorf("jwt: decrypt signing key %s: %w", signer.Kid, err)
+		}
+		if private, err = signingkey.ParsePrivateKey(plain); err != nil {
+			return fmt.Errorf("jwt: parse signing key %s: %w", signer.Kid, err)
+		}
+		method, _ = signingkey.Method(signer.Algorithm)
+	}
+
+	jwtKeys.mu.Lock()
+	defer jwtKeys.mu.Unlock()
+	jwtKeys.public = public
+	jwtKeys.loadedAt = time.Now()
+	if signer != nil {
+		jwtKeys.kid = signer.Kid
+		jwtKeys.method = method
+		jwtKeys.private = private
+	}
+	return nil
+}
+
+func hasSigningKey() bool {
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+	return jwtKeys.private != nil
+}
+
+// signJWT 使用当前密钥签发令牌
+func signJWT(claims jwt.Claims) (string, error) {
+	if !asymmetricJWT() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+	}
+
+	jwtKeys.mu.RLock()
+	kid, method, private := jwtKeys.kid, jwtKeys.method, jwtKeys.private
+	jwtKeys.mu.RUnlock()
+	if private == nil {
+		return "", errors.New("jwt: no signing key available")
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(private)
+}
+
+// jwtValidMethods 当前模式下允许的签名算法，防止算法混淆攻击
+func jwtValidMethods() []string {
+	if !asymmetricJWT() {
+		return []string{JWTAlgHS256}
+	}
+	methods := []string{signingkey.AlgRS256, signingkey.AlgEdDSA}
+	if jwtAllowHS256 && len(jwtSecret) > 0 {
+		methods = append(methods, JWTAlgHS256)
+	}
+	return methods
+}
+
+// jwtKeyFunc 按令牌头部的算法和kid选择校验密钥
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return jwtSecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := lookupVerifyKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if key.alg != token.Method.Alg() {
+		return nil, fmt.Errorf("key %q does not match algorithm %s", kid, token.Method.Alg())
+	}
+	return key.pub, nil
+}
+
+// lookupVerifyKey 查找公钥；其他实例刚轮换的新密钥尚未同步时，按需从数据库重新加载
+func lookupVerifyKey(kid string) (verifyKey, bool) {
+	jwtKeys.mu.RLock()
+	key, ok := jwtKeys.public[kid]
+	stale := time.Since(jwtKeys.loadedAt) >= jwtUnknownKidReloadEvery
+	jwtKeys.mu.RUnlock()
+	if ok || !stale || kid == "" || jwtKeyDB == nil {
+		return key, ok
+	}
+
+	if err := reloadJWTKeys(); err != nil {
+		log.Printf("[JWT] Failed to reload keys: %v", err)
+		return verifyKey{}, false
+	}
+	jwtKeys.mu.RLock()
+	defer jwtKeys.mu.RUnlock()
+	key, ok = jwtKeys.public[kid]
+	return key, ok
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"crypto"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/secretbox"
+	"app-platform-backend/internal/pkg/signingkey"
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// useTestKeys 切换到非对称模式并装载内存中的密钥，测试结束后恢复
+func useTestKeys(t *testing.T, alg string, allowHS256 bool) crypto.Signer {
+	t.Helper()
+	priv, err := signingkey.Generate(alg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	method, _ := signingkey.Method(alg)
+
+	prevAlg, prevAllow, prevSecret, prevExpire := jwtAlgorithm, jwtAllowHS256, jwtSecret, jwtExpire
+	t.Cleanup(func() {
+		jwtAlgorithm, jwtAllowHS256, jwtSecret, jwtExpire = prevAlg, prevAllow, prevSecret, prevExpire
+		jwtKeys.mu.Lock()
+		jwtKeys.kid, jwtKeys.method, jwtKeys.private, jwtKeys.public = "", nil, nil, nil
+		jwtKeys.mu.Unlock()
+	})
+
+	jwtAlgorithm, jwtAllowHS256, jwtSecret, jwtExpire = alg, allowHS256, []byte("legacy-secret"), 1
+	jwtKeys.mu.Lock()
+	jwtKeys.kid, jwtKeys.method, jwtKeys.private = "k-current", method, priv
+	jwtKeys.public = map[string]verifyKey{"k-current": {alg: alg, pub: priv.Public()}}
+	jwtKeys.loadedAt = time.Now()
+	jwtKeys.mu.Unlock()
+	return priv
+}
+
+func legacyHS256Token(t *testing.T) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		UserID: 1,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte("legacy-secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAsymmetricTokenRoundTrip(t *testing.T) {
+	for _, alg := range []string{signingkey.AlgRS256, signingkey.AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			useTestKeys(t, alg, false)
+
+			token, err := GenerateToken(7, "alice", 3)
+			if err != nil {
+				t.Fatalf("GenerateToken() error: %v", err)
+			}
+			parsed, _, _ := jwt.NewParser().ParseUnverified(token, &Claims{})
+			if parsed.Header["kid"] != "k-current" || parsed.Header["alg"] != alg {
+				t.Errorf("header = %v, want kid k-current and alg %s", parsed.Header, alg)
+			}
+
+			claims, err := ParseToken(token)
+			if err != nil {
+				t.Fatalf("ParseToken() error: %v", err)
+			}
+			if claims.UserID != 7 || claims.TokenVersion != 3 {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+
+			keys := JWTPublicKeys()
+			if len(keys) != 1 || keys[0]["kid"] != "k-current" {
+				t.Errorf("JWTPublicKeys() = %v", keys)
+			}
+		})
+	}
+}
+
+func TestParseToken_RotationAndFallback(t *testing.T) {
+	useTestKeys(t, signingkey.AlgRS256, false)
+
+	// 轮换后旧密钥签发的令牌仍可校验
+	oldToken, _ := GenerateToken(1, "alice", 0)
+	next, _ := signingkey.Generate(signingkey.AlgRS256)
+	jwtKeys.mu.Lock()
+	old := jwtKeys.public["k-current"]
+	jwtKeys.kid, jwtKeys.private = "k-next", next
+	jwtKeys.public = map[string]verifyKey{
+		"k-current": old,
+		"k-next":    {alg: signingkey.AlgRS256, pub: next.Public()},
+	}
+	jwtKeys.mu.Unlock()
+	if _, err := ParseToken(oldToken); err != nil {
+		t.Errorf("token signed by retired key should verify: %v", err)
+	}
+
+	// 公钥移除后旧令牌失效
+	jwtKeys.mu.Lock()
+	delete(jwtKeys.public, "k-current")
+	jwtKeys.mu.Unlock()
+	if _, err := ParseToken(oldToken); err == nil {
+		t.Error("token with unknown kid should be rejected")
+	}
+
+	// 未开启兼容时拒绝 HS256 令牌
+	legacy := legacyHS256Token(t)
+	if _, err := ParseToken(legacy); err == nil {
+		t.Error("HS256 token should be rejected when allow_hs256 is off")
+	}
+	jwtAllowHS256 = true
+	if _, err := ParseToken(legacy); err != nil {
+		t.Errorf("HS256 token should verify when allow_hs256 is on: %v", err)
+	}
+}
+
+func TestParseToken_RejectsAlgorithmMismatch(t *testing.T) {
+	useTestKeys(t, signingkey.AlgRS256, false)
+
+	// 使用 EdDSA 私钥签名却声明 RS256 密钥的 kid
+	other, _ := signingkey.Generate(signingkey.AlgEdDSA)
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, Claims{UserID: 1})
+	token.Header["kid"] = "k-current"
+	signed, err := token.SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseToken(signed); err == nil {
+		t.Error("token whose alg does not match the key should be rejected")
+	}
+}
+
+func TestRotateJWTKey_GeneratesOnlyWhenDue(t *testing.T) {
+	useTestKeys(t, signingkey.AlgEdDSA, false)
+	prevDB, prevBox, prevEvery := jwtKeyDB, jwtKeyBox, jwtRotateEvery
+	t.Cleanup(func() { jwtKeyDB, jwtKeyBox, jwtRotateEvery = prevDB, prevBox, prevEvery })
+	jwtRotateEvery = time.Hour
+	box, err := secretbox.New("jwt-key-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtKeyBox = box
+
+	for _, tt := range []struct {
+		name    string
+		age     time.Duration
+		rotated bool
+	}{
+		{"active key within period", time.Minute, false},
+		{"active key past period", 2 * time.Hour, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			createdAt := time.Now().Add(-tt.age)
+			db, fake := sqltest.Open(t, func(query string, args []driver.Value) sqltest.Reply {
+				if strings.Contains(query, "FROM `jwt_signing_keys`") && strings.Contains(query, "status = ?") {
+					return sqltest.Reply{
+						Columns: []string{"id", "kid", "algorithm", "status", "created_at"},
+						Rows:    [][]driver.Value{{int64(1), "k-current", signingkey.AlgEdDSA, model.JWTKeyStatusActive, createdAt}},
+					}
+				}
+				return sqltest.Reply{Affected: 1, LastInsertID: 2}
+			})
+			jwtKeyDB = db
+
+			created, err := rotateJWTKey(0, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (created != nil) != tt.rotated {
+				t.Errorf("rotated = %v, want %v", created != nil, tt.rotated)
+			}
+			// 未到期时不进入加锁事务，也不生成和写入密钥
+			locked, inserted := len(fake.Calls("FOR UPDATE")) > 0, len(fake.Calls("INSERT INTO `jwt_signing_keys`")) > 0
+			if locked != tt.rotated || inserted != tt.rotated {
+				t.Errorf("locked=%v inserted=%v, want both %v", locked, inserted, tt.rotated)
+			}
+		})
+	}
+}
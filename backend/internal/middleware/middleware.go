@@ -1,48 +1,120 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"strings"
 	"time"
 
 	"app-platform-backend/internal/config"
+	"app-platform-backend/internal/model"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
 var jwtSecret []byte
 var jwtExpire int
 
+// sessionDB 用于校验令牌对应管理员的当前状态，为nil时只校验签名
+var sessionDB *gorm.DB
+
+// InitSessionDB 启用会话状态校验
+// 启用后，管理员被禁用、删除或令牌版本变更时，已签发的令牌立即失效
+func InitSessionDB(db *gorm.DB) {
+	sessionDB = db
+}
+
+// passwordChangeAllowedPaths 必须修改密码的管理员仍可访问的接口
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/v1/admin/info":     true,
+	"/api/v1/admin/password": true,
+	"/api/v1/admin/logout":   true,
+}
+
+// viewerWritablePaths 只读角色仍可提交的接口（个人账号相关，以及接受组织邀请）
+var viewerWritablePaths = []string{
+	"/api/v1/admin/logout",
+	"/api/v1/admin/password",
+	"/api/v1/admin/2fa",
+	"/api/v1/org-invitations/accept",
+}
+
 func InitJWT(cfg *config.JWTConfig) {
 	jwtSecret = []byte(cfg.Secret)
 	jwtExpire = cfg.Expire
+	if cfg.Algorithm != "" {
+		jwtAlgorithm = cfg.Algorithm
+	}
+	jwtRotateEvery = time.Duration(cfg.RotationHours) * time.Hour
+	jwtAllowHS256 = cfg.AllowHS256
 }
 
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
+	// Purpose 非空表示受限用途的令牌（如MFA登录挑战），不能用于访问业务接口
+	Purpose string `json:"purpose,omitempty"`
+	// TokenVersion 签发时管理员的令牌版本，版本变更后旧令牌全部失效
+	TokenVersion int `json:"ver,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, username string) (string, error) {
+// TokenPurposeMFA 双因素认证登录挑战令牌
+const TokenPurposeMFA = "mfa_challenge"
+
+func GenerateToken(userID uint, username string, tokenVersion int) (string, error) {
+	claims := Claims{
+		UserID:       userID,
+		Username:     username,
+		TokenVersion: tokenVersion,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtExpire) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return signJWT(claims)
+}
+
+// GenerateMFAToken 生成短期有效的MFA登录挑战令牌
+// 密码校验通过后签发，只能用于换取正式会话令牌；令牌ID（jti）用于标记挑战已使用
+func GenerateMFAToken(userID uint, username string, ttl time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
+		Purpose:  TokenPurposeMFA,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(jwtExpire) * time.Hour)),
+			ID:        hex.EncodeToString(jti),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return signJWT(claims)
+}
+
+// ParseMFAToken 解析MFA登录挑战令牌
+func ParseMFAToken(tokenString string) (*Claims, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TokenPurposeMFA || claims.ID == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
 }
 
 func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+	parser := jwt.NewParser(jwt.WithValidMethods(jwtValidMethods()))
+	token, err := parser.ParseWithClaims(tokenString, &Claims{}, jwtKeyFunc)
 
 	if err != nil {
 		return nil, err
@@ -71,19 +143,64 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 自动化API令牌，按scope和应用授权
+		if isAPIToken(parts[1]) {
+			if !authenticateAPIToken(c, parts[1]) {
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		claims, err := ParseToken(parts[1])
-		if err != nil {
+		if err != nil || claims.Purpose != "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
+		if sessionDB != nil {
+			var admin model.Admin
+			err := sessionDB.Select("id", "status", "role", "must_change_password", "token_version").
+				First(&admin, claims.UserID).Error
+			if err != nil || admin.Status != model.AdminStatusActive || admin.TokenVersion != claims.TokenVersion {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+				c.Abort()
+				return
+			}
+			if admin.MustChangePassword && !passwordChangeAllowedPaths[c.Request.URL.Path] {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Password change required", "code": "password_change_required"})
+				c.Abort()
+				return
+			}
+			if admin.Role == model.AdminRoleViewer && !viewerCanAccess(c.Request) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Read-only account", "code": "read_only"})
+				c.Abort()
+				return
+			}
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Next()
 	}
 }
 
+// viewerCanAccess 只读角色只能执行查询，以及修改自己的密码和双因素设置
+func viewerCanAccess(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	for _, p := range viewerWritablePaths {
+		if r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 func CORSMiddleware(cfg *config.CORSConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.GetHeader("Origin")
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	scopedOrgKey = "scoped_org"
+	orgMemberKey = "org_member"
+)
+
+// orgRoleRank 组织角色权限等级，数值越大权限越高
+var orgRoleRank = map[string]int{
+	model.OrgRoleViewer: 1,
+	model.OrgRoleMember: 2,
+	model.OrgRoleAdmin:  3,
+	model.OrgRoleOwner:  4,
+}
+
+// OrgRoleAtLeast 判断角色是否不低于要求的角色，未知角色一律不满足
+func OrgRoleAtLeast(role, min string) bool {
+	r, ok := orgRoleRank[role]
+	return ok && r >= orgRoleRank[min]
+}
+
+// OrgScopeFromParam 从路径参数解析组织并校验当前管理员的成员角色，通过后写入上下文
+func OrgScopeFromParam(name, minRole string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, err := parseAppID(c.Param(name))
+		if err != nil {
+			response.ParamError(c, "无效的组织ID")
+			c.Abort()
+			return
+		}
+
+		var org model.Organization
+		if err := appScopeDB.First(&org, orgID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				response.NotFound(c, "组织不存在")
+			} else {
+				response.DBError(c, err)
+			}
+			c.Abort()
+			return
+		}
+
+		member, ok := loadOrgMember(c, org.ID)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if member == nil {
+			// 非成员看不到组织，避免通过ID探测
+			response.NotFound(c, "组织不存在")
+			c.Abort()
+			return
+		}
+		if !OrgRoleAtLeast(member.Role, minRole) {
+			response.Forbidden(c, "组织权限不足")
+			c.Abort()
+			return
+		}
+
+		c.Set(scopedOrgKey, &org)
+		c.Set(orgMemberKey, member)
+		c.Next()
+	}
+}
+
+// GetScopedOrg 获取组织范围中间件解析出的组织
+func GetScopedOrg(c *gin.Context) (*model.Organization, bool) {
+	v, ok := c.Get(scopedOrgKey)
+	if !ok {
+		return nil, false
+	}
+	org, ok := v.(*model.Organization)
+	return org, ok
+}
+
+// GetOrgMember 获取当前管理员在所访问组织中的成员关系
+// 应用范围中间件同样会写入应用所属组织的成员关系，API令牌和SDK请求没有成员关系
+func GetOrgMember(c *gin.Context) (*model.OrgMember, bool) {
+	v, ok := c.Get(orgMemberKey)
+	if !ok {
+		return nil, false
+	}
+	member, ok := v.(*model.OrgMember)
+	return member, ok
+}
+
+// RequireOrgRole 校验当前管理员在指定组织中的角色，不满足时写入响应并返回false
+func RequireOrgRole(c *gin.Context, orgID uint, minRole string) bool {
+	member, ok := loadOrgMember(c, orgID)
+	if !ok {
+		return false
+	}
+	if member == nil || !OrgRoleAtLeast(member.Role, minRole) {
+		response.Forbidden(c, "组织权限不足")
+		return false
+	}
+	return true
+}
+
+// MemberOrgIDs 当前管理员所属的全部组织ID
+func MemberOrgIDs(c *gin.Context) ([]uint, error) {
+	var ids []uint
+	err := appScopeDB.Model(&model.OrgMember{}).
+		Where("admin_id = ?", c.GetUint("user_id")).
+		Pluck("org_id", &ids).Error
+	return ids, err
+}
+
+// checkAppOrgAccess 管理员会话只能访问所属组织的应用，组织只读成员只能查询
+// API令牌已按授权的应用ID校验，不再要求组织成员关系
+func checkAppOrgAccess(c *gin.Context, app *model.App) bool {
+	if _, ok := GetAPIToken(c); ok {
+		return true
+	}
+
+	member, ok := loadOrgMember(c, app.OrgID)
+	if !ok {
+		return false
+	}
+	if member == nil {
+		response.NotFound(c, "应用不存在")
+		return false
+	}
+	if member.Role == model.OrgRoleViewer && !isReadMethod(c.Request.Method) {
+		response.Forbidden(c, "组织只读成员不能修改应用")
+		return false
+	}
+
+	c.Set(orgMemberKey, member)
+	return true
+}
+
+// loadOrgMember 查询当前管理员在组织中的成员关系，不是成员时返回nil；查询失败时写入响应
+func loadOrgMember(c *gin.Context, orgID uint) (*model.OrgMember, bool) {
+	var member model.OrgMember
+	err := appScopeDB.Where("org_id = ? AND admin_id = ?", orgID, c.GetUint("user_id")).First(&member).Error
+	if err == nil {
+		return &member, true
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, true
+	}
+	response.DBError(c, err)
+	return nil, false
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// VisibleOrgIDs 列表和统计可见的组织ID：查询参数 org_id 指定时只返回该组织（须为成员），否则返回全部所属组织
+func VisibleOrgIDs(c *gin.Context) ([]uint, error) {
+	ids, err := MemberOrgIDs(c)
+	if err != nil {
+		return nil, err
+	}
+	v := c.Query("org_id")
+	if v == "" {
+		return ids, nil
+	}
+	orgID, err := parseAppID(v)
+	if err != nil {
+		return nil, errors.New("无效的 org_id")
+	}
+	for _, id := range ids {
+		if id == orgID {
+			return []uint{orgID}, nil
+		}
+	}
+	return []uint{}, nil
+}
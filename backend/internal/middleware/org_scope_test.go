@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"testing"
+
+	"app-platform-backend/internal/model"
+)
+
+func TestOrgRoleAtLeast(t *testing.T) {
+	tests := []struct {
+		role string
+		min  string
+		want bool
+	}{
+		{model.OrgRoleOwner, model.OrgRoleAdmin, true},
+		{model.OrgRoleAdmin, model.OrgRoleAdmin, true},
+		{model.OrgRoleMember, model.OrgRoleAdmin, false},
+		{model.OrgRoleViewer, model.OrgRoleMember, false},
+		{model.OrgRoleViewer, model.OrgRoleViewer, true},
+		{"", model.OrgRoleViewer, false},
+		{"superuser", model.OrgRoleViewer, false},
+	}
+
+	for _, tt := range tests {
+		if got := OrgRoleAtLeast(tt.role, tt.min); got != tt.want {
+			t.Errorf("OrgRoleAtLeast(%q, %q) = %v, want %v", tt.role, tt.min, got, tt.want)
+		}
+	}
+}
@@ -6,34 +6,357 @@ import (
 	"gorm.io/gorm"
 )
 
+// 管理员状态
+const (
+	AdminStatusDisabled = 0 // 已禁用
+	AdminStatusActive   = 1 // 正常
+	AdminStatusInvited  = 2 // 已邀请，待设置密码
+)
+
+// 管理员角色
+const (
+	AdminRoleAdmin  = "admin"  // 管理员，可执行全部操作
+	AdminRoleViewer = "viewer" // 只读，只能查看数据
+)
+
+// 组织成员角色，权限从高到低
+const (
+	OrgRoleOwner  = "owner"  // 所有者，可删除组织和管理所有者
+	OrgRoleAdmin  = "admin"  // 管理员，可管理成员、设置和应用归属
+	OrgRoleMember = "member" // 成员，可管理组织内的应用
+	OrgRoleViewer = "viewer" // 只读，只能查看组织内的应用
+)
+
+// JWT签名密钥状态
+const (
+	JWTKeyStatusActive  = "active"  // 当前用于签发令牌
+	JWTKeyStatusRetired = "retired" // 已轮换，仅用于校验未过期的旧令牌
+)
+
 // Admin 管理员模型
 type Admin struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Username  string         `gorm:"uniqueIndex;size:50" json:"username"`
-	Password  string         `gorm:"size:255" json:"-"`
-	Nickname  string         `gorm:"size:100" json:"nickname"`
-	Avatar    string         `gorm:"size:255" json:"avatar"`
-	Status    int            `gorm:"default:1" json:"status"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	Username           string         `gorm:"uniqueIndex;size:50" json:"username"`
+	Password           string         `gorm:"size:255" json:"-"`
+	Nickname           string         `gorm:"size:100" json:"nickname"`
+	Email              string         `gorm:"size:100" json:"email"`
+	Avatar             string         `gorm:"size:255" json:"avatar"`
+	Status             int            `gorm:"default:1" json:"status"`
+	Role               string         `gorm:"size:20;default:admin" json:"role"`
+	SSOSubject         string         `gorm:"column:sso_subject;size:255;index" json:"-"`
+	MustChangePassword bool           `gorm:"default:false" json:"must_change_password"`
+	TokenVersion       int            `gorm:"default:0" json:"-"`
+	InviteTokenHash    string         `gorm:"size:64;index" json:"-"`
+	InviteExpiresAt    *time.Time     `json:"invite_expires_at,omitempty"`
+	PasswordChangedAt  *time.Time     `json:"password_changed_at"`
+	LastLoginAt        *time.Time     `json:"last_login_at"`
+	CreatedBy          uint           `gorm:"default:0" json:"created_by"`
+	TOTPSecret         string         `gorm:"column:totp_secret;size:64" json:"-"`
+	TOTPEnabled        bool           `gorm:"column:totp_enabled;default:false" json:"totp_enabled"`
+	TOTPLastStep       int64          `gorm:"column:totp_last_step;default:0" json:"-"`
+	TOTPEnabledAt      *time.Time     `gorm:"column:totp_enabled_at" json:"totp_enabled_at"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// AdminRecoveryCode 管理员双因素认证恢复码（仅保存哈希，每个只能使用一次）
+type AdminRecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	AdminID   uint       `gorm:"index" json:"admin_id"`
+	CodeHash  string     `gorm:"size:64" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AdminSSOState 单点登录进行中的授权请求，回调时一次性消费
+type AdminSSOState struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	StateHash    string    `gorm:"uniqueIndex;size:64" json:"-"`
+	Nonce        string    `gorm:"size:64" json:"-"`
+	CodeVerifier string    `gorm:"size:128" json:"-"`
+	LinkAdminID  uint      `json:"link_admin_id"` // 非0表示由已登录管理员发起的身份关联
+	ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AdminMFAChallenge 已使用的MFA登录挑战令牌，令牌ID唯一，同一挑战只能成功使用一次
+type AdminMFAChallenge struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TokenID   string    `gorm:"uniqueIndex;size:64" json:"-"`
+	AdminID   uint      `json:"admin_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AdminLoginAttempt 按用户名统计的登录失败记录（用户名不存在时同样记录，避免枚举账号）
+type AdminLoginAttempt struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	Username     string     `gorm:"uniqueIndex;size:50" json:"username"`
+	FailedCount  int        `gorm:"default:0" json:"failed_count"`
+	LockoutCount int        `gorm:"default:0" json:"lockout_count"`
+	LockedUntil  *time.Time `gorm:"index" json:"locked_until"`
+	LastFailedAt *time.Time `json:"last_failed_at"`
+	LastFailedIP string     `gorm:"size:50" json:"last_failed_ip"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// AdminPasswordHistory 管理员历史密码哈希，用于禁止重复使用
+type AdminPasswordHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	AdminID      uint      `gorm:"index" json:"admin_id"`
+	PasswordHash string    `gorm:"size:255" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Organization 组织，应用归属于组织，管理员通过成员关系访问组织内的应用
+type Organization struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	Name           string         `gorm:"size:100" json:"name"`
+	Slug           string         `gorm:"uniqueIndex;size:50" json:"slug"`
+	Description    string         `gorm:"size:255" json:"description"`
+	DefaultModules string         `gorm:"type:json" json:"default_modules"` // 新建应用未指定模块时默认启用的模块
+	MaxApps        int            `gorm:"default:0" json:"max_apps"`        // 应用数量上限，0表示不限制
+	MaxMembers     int            `gorm:"default:0" json:"max_members"`     // 成员数量上限，0表示不限制
+	CreatedBy      uint           `json:"created_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// OrgMember 组织成员
+type OrgMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	OrgID     uint      `gorm:"uniqueIndex:idx_org_admin" json:"org_id"`
+	AdminID   uint      `gorm:"uniqueIndex:idx_org_admin;index" json:"admin_id"`
+	Role      string    `gorm:"size:20" json:"role"`
+	InvitedBy uint      `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrgInvitation 组织成员邀请，令牌只保存哈希，由邮箱匹配的管理员一次性接受
+type OrgInvitation struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	OrgID      uint       `gorm:"index" json:"org_id"`
+	Email      string     `gorm:"size:100" json:"email"`
+	Role       string     `gorm:"size:20" json:"role"`
+	TokenHash  string     `gorm:"uniqueIndex;size:64" json:"-"`
+	InvitedBy  uint       `json:"invited_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	AcceptedBy uint       `json:"accepted_by"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 // App 应用模型
 type App struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
+	OrgID       uint           `gorm:"index" json:"org_id"`
 	Name        string         `gorm:"size:100" json:"name" binding:"-"`
 	AppID       string         `gorm:"uniqueIndex;size:50" json:"app_id"`
-	AppSecret   string         `gorm:"size:100" json:"app_secret"`
+	AppSecret   string         `gorm:"size:100" json:"-"` // 已废弃：密钥改为加密存储在 AppCredential，启动时自动迁移
 	PackageName string         `gorm:"size:100" json:"package_name"`
 	Description string         `gorm:"type:text" json:"description"`
 	Icon        string         `gorm:"size:255" json:"icon"`
 	Status      int            `gorm:"default:1" json:"status"`
+	PurgeAfter  *time.Time     `gorm:"index" json:"purge_after,omitempty"` // 删除后可恢复的截止时间，之后由后台任务清理全部数据
+	DeletedBy   uint           `json:"deleted_by,omitempty"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// 应用默认环境，按晋级顺序排列
+const (
+	EnvDev     = "dev"
+	EnvStaging = "staging"
+	EnvProd    = "prod"
+)
+
+// AppEnvironment 应用环境，每个环境有独立的密钥、模块配置和配置中心命名空间
+type AppEnvironment struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AppID     uint      `gorm:"uniqueIndex:idx_app_env" json:"app_id"`
+	Code      string    `gorm:"uniqueIndex:idx_app_env;size:20" json:"code"`
+	Name      string    `gorm:"size:50" json:"name"`
+	SortOrder int       `json:"sort_order"` // 晋级顺序，配置从小到大依次晋级
+	IsDefault bool      `json:"is_default"` // 未指定环境的请求使用默认环境
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 应用数据清理任务状态
+const (
+	AppPurgePending   = "pending"
+	AppPurgeRunning   = "running"
+	AppPurgeCompleted = "completed"
+	AppPurgeFailed    = "failed"
+)
+
+// AppPurgeJob 已删除应用的数据清理任务，按模块分批清理并记录进度，失败后下次调度继续
+type AppPurgeJob struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	AppID         uint       `gorm:"uniqueIndex" json:"app_id"`
+	Status        string     `gorm:"size:20;index" json:"status"`
+	CurrentModule string     `gorm:"size:50" json:"current_module"`
+	DeletedRows   int64      `json:"deleted_rows"`
+	Progress      string     `gorm:"type:json" json:"progress"` // 各模块已删除行数
+	Error         string     `gorm:"type:text" json:"error"`
+	LeaseUntil    *time.Time `json:"-"` // 执行实例的租约，多实例部署时避免重复执行
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// 数据主体请求类型和状态
+const (
+	DataRequestExport = "export"
+	DataRequestErase  = "erase"
+
+	DataRequestPending   = "pending"
+	DataRequestRunning   = "running"
+	DataRequestCompleted = "completed"
+	DataRequestFailed    = "failed"
+)
+
+// DataSubjectRequest 数据主体请求：导出某个应用用户在各模块中的全部数据为归档，或删除并匿名化这些数据
+type DataSubjectRequest struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	AppID       uint       `gorm:"index" json:"app_id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Type        string     `gorm:"size:20" json:"type"`
+	Status      string     `gorm:"size:20;index" json:"status"`
+	Subject     string     `gorm:"type:json" json:"-"`         // 创建时的数据主体快照，删除中断后据此继续，删除完成后清空
+	Report      string     `gorm:"type:json" json:"report"`    // 各模块导出或删除的记录数，删除请求还包含删除后的残留核验
+	ReportHash  string     `gorm:"size:64" json:"report_hash"` // 报告的SHA-256，便于核对报告未被修改
+	ArchivePath string     `gorm:"size:500" json:"-"`          // 导出归档的存储路径
+	ArchiveSize int64      `json:"archive_size"`               // 导出归档大小
+	ExpiresAt   *time.Time `json:"expires_at"`                 // 导出归档的下载截止时间
+	Error       string     `gorm:"type:text" json:"error"`
+	RequestedBy uint       `json:"requested_by"`
+	LeaseUntil  *time.Time `json:"-"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Plan 配额套餐，应用未单独设置的配额使用所属套餐的默认值，0 表示不限制
+type Plan struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	Code             string    `gorm:"uniqueIndex;size:50" json:"code"`
+	Name             string    `gorm:"size:100" json:"name"`
+	IsDefault        bool      `json:"is_default"` // 未指定套餐的应用使用默认套餐
+	MaxStorageBytes  int64     `json:"max_storage_bytes"`
+	MaxFiles         int64     `json:"max_files"`
+	EventsPerDay     int64     `json:"events_per_day"`
+	LogsPerDay       int64     `json:"logs_per_day"`
+	MetricsPerMinute int64     `json:"metrics_per_minute"`
+	PushesPerDay     int64     `json:"pushes_per_day"`
+	MaxWSConnections int64     `gorm:"column:max_ws_connections" json:"max_ws_connections"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AppQuota 应用的套餐和单独设置的配额，字段为空表示沿用套餐
+type AppQuota struct {
+	ID               uint      `gorm:"primarykey" json:"id"`
+	AppID            uint      `gorm:"uniqueIndex" json:"app_id"`
+	PlanID           *uint     `json:"plan_id"`
+	MaxStorageBytes  *int64    `json:"max_storage_bytes"`
+	MaxFiles         *int64    `json:"max_files"`
+	EventsPerDay     *int64    `json:"events_per_day"`
+	LogsPerDay       *int64    `json:"logs_per_day"`
+	MetricsPerMinute *int64    `json:"metrics_per_minute"`
+	PushesPerDay     *int64    `json:"pushes_per_day"`
+	MaxWSConnections *int64    `gorm:"column:max_ws_connections" json:"max_ws_connections"`
+	UpdatedBy        uint      `json:"updated_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// AppUsageCounter 应用按时间窗口累计的用量，用于按天、按分钟计算的配额
+type AppUsageCounter struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AppID       uint      `gorm:"uniqueIndex:idx_app_metric_window" json:"app_id"`
+	Metric      string    `gorm:"uniqueIndex:idx_app_metric_window;size:30" json:"metric"`
+	WindowStart time.Time `gorm:"uniqueIndex:idx_app_metric_window;index" json:"window_start"`
+	Count       int64     `json:"count"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// AppDailyStat 应用按天汇总的统计，由定时任务从明细表汇总，供控制台首页的趋势和应用排行使用
+type AppDailyStat struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AppID      uint      `gorm:"uniqueIndex:idx_app_date" json:"app_id"`
+	Date       time.Time `gorm:"uniqueIndex:idx_app_date;type:date;index" json:"date"`
+	NewUsers   int64     `json:"new_users"`
+	Events     int64     `json:"events"`
+	Errors     int64     `json:"errors"`      // error 级别日志数
+	PushesSent int64     `json:"pushes_sent"` // 当天发送的推送送达设备数
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AppCredential 应用密钥，加密存储，轮换时新旧密钥在过渡期内同时有效
+type AppCredential struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	AppID           uint       `gorm:"index" json:"app_id"`
+	EnvID           uint       `gorm:"index" json:"env_id"` // 密钥所属环境，SDK请求按签名密钥确定环境
+	SecretEncrypted string     `gorm:"size:255" json:"-"`
+	SecretHint      string     `gorm:"size:20" json:"secret_hint"` // 密钥末4位，便于辨认
+	CreatedBy       uint       `json:"created_by"`
+	ExpiresAt       *time.Time `json:"expires_at"` // 为空表示长期有效，轮换后设置为过渡期截止时间
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	RevokedBy       uint       `json:"revoked_by"`
+	RevokeReason    string     `gorm:"size:255" json:"revoke_reason"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// APIToken 管理员签发的自动化API令牌，按模块功能和应用授权
+type APIToken struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	Name        string     `gorm:"size:100" json:"name"`
+	TokenHash   string     `gorm:"uniqueIndex;size:64" json:"-"`
+	TokenPrefix string     `gorm:"size:20" json:"token_prefix"` // 令牌前缀，便于辨认
+	Scopes      string     `gorm:"type:json" json:"scopes"`     // 允许的模块功能Code列表
+	AppIDs      string     `gorm:"type:json" json:"app_ids"`    // 允许访问的应用ID列表
+	CreatedBy   uint       `json:"created_by"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	LastUsedIP  string     `gorm:"size:50" json:"last_used_ip"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+	RevokedBy   uint       `json:"revoked_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// SDKNonce 客户端签名请求使用过的随机串，用于防重放
+type SDKNonce struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AppID     uint      `gorm:"uniqueIndex:idx_app_nonce" json:"app_id"`
+	Nonce     string    `gorm:"uniqueIndex:idx_app_nonce;size:64" json:"nonce"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// JWTSigningKey 管理后台会话令牌的非对称签名密钥，私钥加密存储，多实例共享
+type JWTSigningKey struct {
+	ID                  uint       `gorm:"primarykey" json:"id"`
+	Kid                 string     `gorm:"uniqueIndex;size:64" json:"kid"`
+	Algorithm           string     `gorm:"size:16" json:"algorithm"`
+	PublicKey           string     `gorm:"type:text" json:"-"` // PEM格式公钥
+	PrivateKeyEncrypted string     `gorm:"type:text" json:"-"`
+	Status              string     `gorm:"size:20;index" json:"status"`
+	CreatedBy           uint       `json:"created_by"` // 0 表示系统自动轮换
+	RetiredAt           *time.Time `json:"retired_at"`
+	VerifyUntil         *time.Time `json:"verify_until"` // 轮换后公钥继续保留到该时间
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
 // ModuleTemplate 模块模板
 type ModuleTemplate struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
@@ -58,17 +381,29 @@ type AppModule struct {
 	AppID        uint           `gorm:"index" json:"app_id"`
 	ModuleCode   string         `gorm:"size:50" json:"module_code"`
 	SourceModule string         `gorm:"size:50" json:"source_module"`
-	Config       string         `gorm:"type:json" json:"config"`
+	Config       string         `gorm:"type:json" json:"config"` // 已废弃：配置按环境保存在 AppModuleConfig
 	Status       int            `gorm:"default:1" json:"status"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// AppModuleConfig 应用模块在各环境中的配置
+type AppModuleConfig struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AppID      uint      `gorm:"uniqueIndex:idx_app_env_module" json:"app_id"`
+	EnvID      uint      `gorm:"uniqueIndex:idx_app_env_module" json:"env_id"`
+	ModuleCode string    `gorm:"uniqueIndex:idx_app_env_module;size:50" json:"module_code"`
+	Config     string    `gorm:"type:json" json:"config"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
 // ModuleConfigHistory 模块配置历史
 type ModuleConfigHistory struct {
 	ID         uint      `gorm:"primarykey" json:"id"`
 	AppID      uint      `gorm:"index" json:"app_id"`
+	EnvID      uint      `gorm:"index" json:"env_id"`
 	ModuleCode string    `gorm:"size:50" json:"module_code"`
 	Config     string    `gorm:"type:json" json:"config"`
 	Version    int       `json:"version"`
@@ -77,21 +412,185 @@ type ModuleConfigHistory struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
+// 应用用户状态
+const (
+	UserStatusDisabled = 0 // 已禁用
+	UserStatusNormal   = 1 // 正常
+)
 
-// User 用户模型
+// User 应用用户，按应用隔离，open_id 在应用内唯一
 type User struct {
-	ID          uint           `gorm:"primarykey" json:"id"`
-	AppID       uint           `gorm:"index" json:"app_id"`
-	OpenID      string         `gorm:"size:255;index" json:"open_id"`
-	Nickname    string         `gorm:"size:255" json:"nickname"`
-	Avatar      string         `gorm:"size:500" json:"avatar"`
-	Phone       string         `gorm:"size:20" json:"phone"`
-	Email       string         `gorm:"size:255" json:"email"`
-	Status      int            `gorm:"default:1" json:"status"`
-	LastLoginAt *time.Time     `json:"last_login_at"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID           uint           `gorm:"primarykey" json:"id"`
+	AppID        uint           `gorm:"uniqueIndex:uk_app_open_id" json:"app_id"`
+	OpenID       string         `gorm:"uniqueIndex:uk_app_open_id;size:255" json:"open_id"`
+	Nickname     string         `gorm:"size:255" json:"nickname"`
+	Avatar       string         `gorm:"size:500" json:"avatar"`
+	Phone        string         `gorm:"size:20" json:"phone"`
+	Email        string         `gorm:"size:255" json:"email"`
+	Status       int            `gorm:"default:1" json:"status"`
+	Source       string         `gorm:"size:50;default:native" json:"source"` // 用户来源：native 或外部身份源名称
+	PasswordHash string         `gorm:"size:255" json:"-"`                    // 终端用户登录密码，未设置时只能验证码登录
+	BanReason    string         `gorm:"size:255" json:"ban_reason"`           // 封禁原因
+	BannedUntil  *time.Time     `json:"banned_until"`                         // 封禁到期时间，禁用且为空表示永久封禁
+	BannedAt     *time.Time     `json:"banned_at"`
+	LastLoginAt  *time.Time     `json:"last_login_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// UserBanEvent 封禁变更事件，各实例轮询后清除本地封禁缓存，封禁时断开本实例上该用户的连接
+type UserBanEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AppID     uint      `gorm:"index" json:"app_id"`
+	UserID    uint      `json:"user_id"`
+	OpenID    string    `gorm:"size:255" json:"open_id"`
+	Banned    bool      `json:"banned"` // true 为封禁，false 为解除封禁
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// UserTag 应用用户标签，可在控制台手动打标或通过API令牌批量打标
+type UserTag struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AppID       uint      `gorm:"uniqueIndex:uk_app_tag_name" json:"app_id"`
+	Name        string    `gorm:"uniqueIndex:uk_app_tag_name;size:50" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Color       string    `gorm:"size:20" json:"color"`
+	MemberCount int64     `gorm:"-" json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UserTagMember 用户与标签的关联
+type UserTagMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AppID     uint      `gorm:"index" json:"app_id"`
+	TagID     uint      `gorm:"uniqueIndex:uk_tag_user" json:"tag_id"`
+	UserID    uint      `gorm:"uniqueIndex:uk_tag_user;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserSegment 动态用户分群，按规则圈选用户，成员定期物化到 UserSegmentMember
+type UserSegment struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	AppID       uint       `gorm:"index" json:"app_id"`
+	Name        string     `gorm:"size:100" json:"name"`
+	Description string     `gorm:"size:255" json:"description"`
+	Rules       string     `gorm:"type:json" json:"rules"` // 分群规则，见 segment.Rules
+	MemberCount int64      `json:"member_count"`
+	EvaluatedAt *time.Time `json:"evaluated_at"` // 最近一次物化成员的时间
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// UserSegmentMember 分群物化后的成员
+type UserSegmentMember struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	AppID     uint      `gorm:"index" json:"app_id"`
+	SegmentID uint      `gorm:"uniqueIndex:uk_segment_user" json:"segment_id"`
+	UserID    uint      `gorm:"uniqueIndex:uk_segment_user;index" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserProfile 已识别用户的自定义属性
+type UserProfile struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	AppID      uint      `gorm:"uniqueIndex:uk_app_user" json:"app_id"`
+	UserID     uint      `gorm:"uniqueIndex:uk_app_user" json:"user_id"`
+	Properties string    `gorm:"type:json" json:"properties"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AnonymousProfile 匿名设备的属性，识别为已知用户后合并，UserID 记录合并到的用户
+type AnonymousProfile struct {
+	ID          uint       `gorm:"primarykey" json:"id"`
+	AppID       uint       `gorm:"uniqueIndex:uk_app_anonymous" json:"app_id"`
+	AnonymousID string     `gorm:"uniqueIndex:uk_app_anonymous;size:64" json:"anonymous_id"`
+	Properties  string     `gorm:"type:json" json:"properties"`
+	UserID      *uint      `gorm:"index" json:"user_id"`
+	MergedAt    *time.Time `json:"merged_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// 用户导入任务状态
+const (
+	UserImportPending   = "pending"
+	UserImportRunning   = "running"
+	UserImportCompleted = "completed"
+	UserImportFailed    = "failed"
+)
+
+// UserImportJob 应用用户批量导入任务，按已处理行数断点续跑
+type UserImportJob struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	AppID           uint       `gorm:"index" json:"app_id"`
+	Filename        string     `gorm:"size:255" json:"filename"`
+	Format          string     `gorm:"size:20" json:"format"`      // csv 或 ndjson
+	Mapping         string     `gorm:"type:json" json:"mapping"`   // 目标字段到文件列名的映射
+	OnConflict      string     `gorm:"size:20" json:"on_conflict"` // 已存在用户的处理方式：skip 或 update
+	DryRun          bool       `json:"dry_run"`                    // 只校验和统计，不写入
+	FilePath        string     `gorm:"size:500" json:"-"`          // 上传文件路径，完成后删除
+	Status          string     `gorm:"size:20;index" json:"status"`
+	TotalRows       int64      `json:"total_rows"`
+	ProcessedRows   int64      `json:"processed_rows"`
+	CreatedRows     int64      `json:"created_rows"`
+	UpdatedRows     int64      `json:"updated_rows"`
+	SkippedRows     int64      `json:"skipped_rows"`
+	FailedRows      int64      `json:"failed_rows"`
+	ErrorReportPath string     `gorm:"size:500" json:"-"` // 出错行的 CSV 报告
+	Error           string     `gorm:"type:text" json:"error"`
+	CreatedBy       uint       `json:"created_by"`
+	LeaseUntil      *time.Time `json:"-"`
+	StartedAt       *time.Time `json:"started_at"`
+	FinishedAt      *time.Time `json:"finished_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// 终端用户验证码用途
+const (
+	UserCodePurposeLogin         = "login"
+	UserCodePurposeRegister      = "register"
+	UserCodePurposeResetPassword = "reset_password"
+)
+
+// 终端用户验证码发送渠道
+const (
+	UserCodeChannelEmail = "email"
+	UserCodeChannelSMS   = "sms"
+)
+
+// UserSession 终端用户登录会话，访问令牌和刷新令牌只保存哈希
+type UserSession struct {
+	ID               uint       `gorm:"primarykey" json:"id"`
+	AppID            uint       `gorm:"index" json:"app_id"`
+	UserID           uint       `gorm:"index" json:"user_id"`
+	AccessTokenHash  string     `gorm:"uniqueIndex;size:64" json:"-"`
+	RefreshTokenHash string     `gorm:"uniqueIndex;size:64" json:"-"`
+	AccessExpiresAt  time.Time  `json:"access_expires_at"`
+	RefreshExpiresAt time.Time  `json:"refresh_expires_at"`
+	ClientIP         string     `gorm:"size:50" json:"client_ip"`
+	UserAgent        string     `gorm:"size:500" json:"user_agent"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// UserVerifyCode 终端用户一次性验证码，只保存哈希
+type UserVerifyCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	AppID     uint       `gorm:"index:idx_app_target" json:"app_id"`
+	Target    string     `gorm:"index:idx_app_target;size:255" json:"target"` // 邮箱或手机号
+	Channel   string     `gorm:"size:20" json:"channel"`
+	Purpose   string     `gorm:"size:30" json:"purpose"`
+	CodeHash  string     `gorm:"size:64" json:"-"`
+	Attempts  int        `json:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
 // Message 消息模型
@@ -108,10 +607,21 @@ type Message struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
-// PushRecord 推送记录模型
+// 推送状态：未设置发送时间的推送待手动发送，定时和周期推送到期后由调度器发送
+const (
+	PushPending   = "pending"
+	PushScheduled = "scheduled"
+	PushSending   = "sending"
+	PushSent      = "sent"
+	PushFailed    = "failed"
+	PushCancelled = "cancelled"
+)
+
+// PushRecord 推送记录模型，按设备投递，计数为设备数
 type PushRecord struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
 	AppID        uint           `gorm:"index" json:"app_id"`
+	Env          string         `gorm:"size:20" json:"env"` // 使用该环境推送服务模块配置中的通道凭证
 	Title        string         `gorm:"size:255" json:"title"`
 	Content      string         `gorm:"type:text" json:"content"`
 	TargetType   string         `gorm:"size:50;default:all" json:"target_type"`
@@ -120,24 +630,69 @@ type PushRecord struct {
 	SentCount    int            `gorm:"default:0" json:"sent_count"`
 	SuccessCount int            `gorm:"default:0" json:"success_count"`
 	FailedCount  int            `gorm:"default:0" json:"failed_count"`
-	ScheduledAt  *time.Time     `json:"scheduled_at"`
+	Error        string         `gorm:"size:500" json:"error"`      // 发送失败原因或最近一次设备投递错误
+	TemplateID   *uint          `gorm:"index" json:"template_id"`   // 使用模板时按接收用户和设备语言渲染，标题和内容为默认渲染结果
+	Variables    string         `gorm:"type:text" json:"variables"` // 创建推送时传入的模板变量，JSON对象
+	Cron         string         `gorm:"size:100" json:"cron"`       // 周期推送的 cron 表达式，每次到期生成一条子推送发送
+	ParentID     *uint          `gorm:"index" json:"parent_id"`     // 周期推送生成的子推送所属的周期推送
+	ScheduledAt  *time.Time     `json:"scheduled_at"`               // 定时发送时间，周期推送为下次发送时间
+	Cursor       uint           `json:"-"`                          // 已投递到的设备ID，发送中断后从此继续
+	LeaseUntil   *time.Time     `json:"-"`                          // 发送实例的租约，过期后由其他实例接手
 	SentAt       *time.Time     `json:"sent_at"`
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// PushTemplate 推送模板，Data、Variables 和 Locales 的结构见 pusher.Template
+type PushTemplate struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AppID       uint      `gorm:"uniqueIndex:uk_app_push_template" json:"app_id"`
+	Name        string    `gorm:"uniqueIndex:uk_app_push_template;size:100" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	Title       string    `gorm:"size:255" json:"title"`
+	Body        string    `gorm:"type:text" json:"body"`
+	Data        string    `gorm:"type:json" json:"data"`      // 随通知下发的数据，值中可引用变量
+	Variables   string    `gorm:"type:json" json:"variables"` // 变量声明
+	Locales     string    `gorm:"type:json" json:"locales"`   // 按语言标签的标题、正文和数据
+	CreatedBy   uint      `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PushDevice 应用用户的推送设备，Provider 决定通过哪个推送通道投递
+// 令牌在应用、环境和通道内唯一，重装后以新用户登记同一令牌时归属转移到新用户；
+// Env 为登记时SDK签名密钥所属的环境，只接收该环境的推送，避免测试凭证的推送发到生产设备
+type PushDevice struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	AppID         uint       `gorm:"uniqueIndex:idx_app_env_provider_token;index:idx_app_user" json:"app_id"`
+	Env           string     `gorm:"uniqueIndex:idx_app_env_provider_token;size:20" json:"env"`
+	UserID        uint       `gorm:"index:idx_app_user" json:"user_id"`
+	Platform      string     `gorm:"size:20" json:"platform"` // ios/android/harmony/web
+	Provider      string     `gorm:"uniqueIndex:idx_app_env_provider_token;size:20" json:"provider"`
+	Token         string     `gorm:"uniqueIndex:idx_app_env_provider_token;size:255" json:"token"`
+	AppVersion    string     `gorm:"size:50" json:"app_version"`
+	Locale        string     `gorm:"size:20" json:"locale"`
+	Timezone      string     `gorm:"size:64" json:"timezone"`
+	LastSeenAt    time.Time  `json:"last_seen_at"`
+	InvalidatedAt *time.Time `json:"invalidated_at"` // 通道报告令牌失效的时间，失效后不再投递，重新登记时恢复
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
 // Event 事件模型
 type Event struct {
-	ID         uint64    `gorm:"primarykey" json:"id"`
-	AppID      uint      `gorm:"index" json:"app_id"`
-	UserID     *uint     `gorm:"index" json:"user_id"`
-	EventCode  string    `gorm:"size:100;index" json:"event_code"`
-	EventName  string    `gorm:"size:255" json:"event_name"`
-	Properties string    `gorm:"type:json" json:"properties"`
-	IP         string    `gorm:"size:50" json:"ip"`
-	UserAgent  string    `gorm:"size:500" json:"user_agent"`
-	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	ID          uint64    `gorm:"primarykey" json:"id"`
+	AppID       uint      `gorm:"index" json:"app_id"`
+	UserID      *uint     `gorm:"index" json:"user_id"`
+	AnonymousID string    `gorm:"size:64;index" json:"anonymous_id"` // 匿名设备ID，识别用户后按此回填 UserID
+	Env         string    `gorm:"size:20;default:prod" json:"env"`
+	EventCode   string    `gorm:"size:100;index" json:"event_code"`
+	EventName   string    `gorm:"size:255" json:"event_name"`
+	Properties  string    `gorm:"type:json" json:"properties"`
+	IP          string    `gorm:"size:50" json:"ip"`
+	UserAgent   string    `gorm:"size:500" json:"user_agent"`
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 }
 
 // EventDefinition 事件定义模型
@@ -158,11 +713,13 @@ type EventDefinition struct {
 type Log struct {
 	ID        uint64    `gorm:"primarykey" json:"id"`
 	AppID     uint      `gorm:"index" json:"app_id"`
+	Env       string    `gorm:"size:20;default:prod" json:"env"`
 	Level     string    `gorm:"size:20;default:info;index" json:"level"`
 	Module    string    `gorm:"size:100;index" json:"module"`
 	Message   string    `gorm:"type:text" json:"message"`
 	Context   string    `gorm:"type:json" json:"context"`
 	IP        string    `gorm:"size:50" json:"ip"`
+	Flagged   bool      `json:"flagged"` // 上报时上下文中的用户处于封禁中
 	CreatedAt time.Time `gorm:"index" json:"created_at"`
 }
 
@@ -170,6 +727,7 @@ type Log struct {
 type MonitorMetric struct {
 	ID          uint64    `gorm:"primarykey" json:"id"`
 	AppID       uint      `gorm:"index" json:"app_id"`
+	Env         string    `gorm:"size:20;default:prod" json:"env"`
 	MetricName  string    `gorm:"size:100;index" json:"metric_name"`
 	MetricValue float64   `gorm:"type:decimal(20,4)" json:"metric_value"`
 	Tags        string    `gorm:"type:json" json:"tags"`
@@ -200,7 +758,7 @@ type File struct {
 	FilePath  string         `gorm:"size:500" json:"file_path"`
 	FileSize  int64          `gorm:"default:0" json:"file_size"`
 	MimeType  string         `gorm:"size:100" json:"mime_type"`
-	UploadBy  *uint          `gorm:"index" json:"upload_by"`
+	UploadBy  *uint          `gorm:"index" json:"upload_by"` // 上传的应用用户，控制台和API令牌上传时为空
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -210,8 +768,11 @@ type File struct {
 type Config struct {
 	ID          uint           `gorm:"primarykey" json:"id"`
 	AppID       uint           `gorm:"index" json:"app_id"`
+	Env         string         `gorm:"size:20;index" json:"env"` // 配置中心按环境划分命名空间
 	ConfigKey   string         `gorm:"size:255" json:"config_key"`
 	ConfigValue string         `gorm:"type:text" json:"config_value"`
+	TargetType  string         `gorm:"size:50;default:all" json:"target_type"` // 定向对象：all/user/tag/segment
+	TargetIDs   string         `gorm:"type:text" json:"target_ids"`            // 定向对象ID，逗号分隔
 	Description string         `gorm:"type:text" json:"description"`
 	IsPublished int            `gorm:"default:0" json:"is_published"`
 	PublishedAt *time.Time     `json:"published_at"`
@@ -230,6 +791,11 @@ type ConfigHistory struct {
 	CreatedAt   time.Time `gorm:"index" json:"created_at"`
 }
 
+// TableName 配置历史表名，与迁移脚本一致
+func (ConfigHistory) TableName() string {
+	return "config_history"
+}
+
 // Version 版本模型
 type Version struct {
 	ID            uint           `gorm:"primarykey" json:"id"`
@@ -239,6 +805,8 @@ type Version struct {
 	Description   string         `gorm:"type:text" json:"description"`
 	DownloadURL   string         `gorm:"size:500" json:"download_url"`
 	IsForceUpdate int            `gorm:"default:0" json:"is_force_update"`
+	TargetType    string         `gorm:"size:50;default:all" json:"target_type"` // 灰度对象：all/user/tag/segment
+	TargetIDs     string         `gorm:"type:text" json:"target_ids"`            // 灰度对象ID，逗号分隔
 	Status        string         `gorm:"size:50;default:draft" json:"status"`
 	PublishedAt   *time.Time     `json:"published_at"`
 	CreatedAt     time.Time      `json:"created_at"`
@@ -0,0 +1,168 @@
+// Package codesender 发送终端用户一次性验证码
+// 邮件和短信分别配置发送方式，本地测试可使用 log 只写日志
+package codesender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/config"
+)
+
+// 发送方式
+const (
+	KindLog  = "log"
+	KindSMTP = "smtp"
+	KindHTTP = "http"
+)
+
+// Message 一条验证码消息
+type Message struct {
+	To      string        // 邮箱或手机号
+	Code    string        // 验证码明文
+	Purpose string        // 用途：login/register/reset_password
+	AppName string        // 应用名称，用于消息正文
+	TTL     time.Duration // 有效期
+}
+
+// Text 消息正文
+func (m Message) Text() string {
+	return fmt.Sprintf("【%s】您的验证码是 %s，%d 分钟内有效。如非本人操作请忽略。",
+		m.AppName, m.Code, int(m.TTL.Minutes()))
+}
+
+// Sender 验证码发送器
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewEmail 按配置创建邮件验证码发送器，kind 为空时使用 log
+func NewEmail(kind string, cfg config.SMTPSenderConfig) (Sender, error) {
+	switch kind {
+	case "", KindLog:
+		return LogSender{Channel: "email"}, nil
+	case KindSMTP:
+		if cfg.Host == "" || cfg.From == "" {
+			return nil, fmt.Errorf("codesender: smtp host and from are required")
+		}
+		return &SMTPSender{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("codesender: unsupported email sender %q", kind)
+	}
+}
+
+// NewSMS 按配置创建短信验证码发送器，kind 为空时使用 log
+func NewSMS(kind string, cfg config.SMSSenderConfig) (Sender, error) {
+	switch kind {
+	case "", KindLog:
+		return LogSender{Channel: "sms"}, nil
+	case KindHTTP:
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("codesender: sms endpoint is required")
+		}
+		return &HTTPSMSSender{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("codesender: unsupported sms sender %q", kind)
+	}
+}
+
+// LogSender 只把验证码写入日志，用于本地开发和测试，不要在生产环境使用
+type LogSender struct {
+	Channel string
+}
+
+// Send 写日志
+func (s LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("[CodeSender] %s to %s (%s): %s", s.Channel, msg.To, msg.Purpose, msg.Code)
+	return nil
+}
+
+// SMTPSender 通过SMTP发送邮件验证码
+type SMTPSender struct {
+	cfg config.SMTPSenderConfig
+}
+
+// Send 发送邮件
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	port := s.cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+	return smtp.SendMail(addr, auth, s.cfg.From, []string{msg.To}, buildMail(s.cfg.From, msg))
+}
+
+// buildMail 生成纯文本邮件
+func buildMail(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.AppName + " 验证码\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Text() + "\r\n")
+	return []byte(b.String())
+}
+
+// HTTPSMSSender 通过短信服务商的HTTP接口发送短信验证码
+type HTTPSMSSender struct {
+	cfg    config.SMSSenderConfig
+	client *http.Client
+}
+
+// smsRequest 发送到短信服务商的请求体
+type smsRequest struct {
+	Phone    string            `json:"phone"`
+	Sign     string            `json:"sign,omitempty"`
+	Template string            `json:"template,omitempty"`
+	Params   map[string]string `json:"params"`
+	Content  string            `json:"content"`
+}
+
+// Send 发送短信，服务商返回非2xx时视为失败
+func (s *HTTPSMSSender) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(smsRequest{
+		Phone:    msg.To,
+		Sign:     s.cfg.Sign,
+		Template: s.cfg.Template,
+		Params:   map[string]string{"code": msg.Code, "minutes": strconv.Itoa(int(msg.TTL.Minutes()))},
+		Content:  msg.Text(),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.APIKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("codesender: sms provider returned %s", resp.Status)
+	}
+	return nil
+}
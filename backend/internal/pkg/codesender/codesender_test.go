@@ -0,0 +1,82 @@
+package codesender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/config"
+)
+
+func TestNewSenders(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func() (Sender, error)
+		wantErr bool
+	}{
+		{"email default log", func() (Sender, error) { return NewEmail("", config.SMTPSenderConfig{}) }, false},
+		{"smtp without host", func() (Sender, error) { return NewEmail(KindSMTP, config.SMTPSenderConfig{}) }, true},
+		{"smtp", func() (Sender, error) {
+			return NewEmail(KindSMTP, config.SMTPSenderConfig{Host: "smtp.example.com", From: "no-reply@example.com"})
+		}, false},
+		{"email unknown", func() (Sender, error) { return NewEmail("pigeon", config.SMTPSenderConfig{}) }, true},
+		{"sms log", func() (Sender, error) { return NewSMS(KindLog, config.SMSSenderConfig{}) }, false},
+		{"sms http without endpoint", func() (Sender, error) { return NewSMS(KindHTTP, config.SMSSenderConfig{}) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && s == nil {
+				t.Error("nil sender without error")
+			}
+		})
+	}
+}
+
+func TestHTTPSMSSender(t *testing.T) {
+	var got smsRequest
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Phone == "13800000000" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	s, err := NewSMS(KindHTTP, config.SMSSenderConfig{Endpoint: srv.URL, APIKey: "k", Sign: "Demo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := Message{To: "13800000000", Code: "123456", AppName: "Demo", TTL: 5 * time.Minute}
+	if err := s.Send(context.Background(), msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if auth != "Bearer k" || got.Params["code"] != "123456" || got.Params["minutes"] != "5" || got.Sign != "Demo" {
+		t.Errorf("request = %+v, auth = %q", got, auth)
+	}
+
+	msg.To = "bad"
+	if err := s.Send(context.Background(), msg); err == nil {
+		t.Error("non-2xx response should fail")
+	}
+}
+
+func TestBuildMail(t *testing.T) {
+	mail := string(buildMail("no-reply@example.com", Message{To: "a@example.com", Code: "654321", AppName: "Demo", TTL: 10 * time.Minute}))
+	for _, want := range []string{"To: a@example.com\r\n", "Subject: Demo 验证码\r\n", "654321", "10 分钟"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("mail missing %q:\n%s", want, mail)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package configdiff 比较两份JSON配置的差异，嵌套对象按点分隔的路径展开
+package configdiff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// 差异类型
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change 单个配置项的变化
+type Change struct {
+	Path string      `json:"path"`
+	Type string      `json:"type"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Diff 比较 from 到 to 的差异，结果按路径排序
+// 对象逐层展开比较，数组和其他值整体比较
+func Diff(from, to map[string]interface{}) []Change {
+	a, b := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", from, a)
+	flatten("", to, b)
+
+	changes := make([]Change, 0)
+	for path, v := range a {
+		w, ok := b[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Type: Removed, From: v})
+		case !reflect.DeepEqual(v, w):
+			changes = append(changes, Change{Path: path, Type: Changed, From: v, To: w})
+		}
+	}
+	for path, w := range b {
+		if _, ok := a[path]; !ok {
+			changes = append(changes, Change{Path: path, Type: Added, To: w})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// DiffJSON 比较两份JSON文本，空文本视为空对象
+func DiffJSON(from, to string) ([]Change, error) {
+	a, err := Parse(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := Parse(to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(a, b), nil
+}
+
+// Parse 解析JSON对象，空文本返回空对象
+func Parse(s string) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if s == "" || s == "null" {
+		return m, nil
+	}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// flatten 将嵌套对象展开为路径到值的映射，空对象保留为一个值
+func flatten(prefix string, m map[string]interface{}, out map[string]interface{}) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			flatten(path, child, out)
+			continue
+		}
+		out[path] = v
+	}
+}
@@ -0,0 +1,72 @@
+package configdiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []Change
+	}{
+		{
+			name: "identical",
+			from: `{"a":1,"b":{"c":"x"}}`,
+			to:   `{"b":{"c":"x"},"a":1}`,
+			want: []Change{},
+		},
+		{
+			name: "empty to populated",
+			from: "",
+			to:   `{"enabled":true}`,
+			want: []Change{{Path: "enabled", Type: Added, To: true}},
+		},
+		{
+			name: "nested changes sorted by path",
+			from: `{"push":{"key":"old","retries":3},"debug":true}`,
+			to:   `{"push":{"key":"new"},"timeout":30}`,
+			want: []Change{
+				{Path: "debug", Type: Removed, From: true},
+				{Path: "push.key", Type: Changed, From: "old", To: "new"},
+				{Path: "push.retries", Type: Removed, From: float64(3)},
+				{Path: "timeout", Type: Added, To: float64(30)},
+			},
+		},
+		{
+			name: "arrays compared as a whole",
+			from: `{"hosts":["a","b"]}`,
+			to:   `{"hosts":["a","c"]}`,
+			want: []Change{{Path: "hosts", Type: Changed, From: []interface{}{"a", "b"}, To: []interface{}{"a", "c"}}},
+		},
+		{
+			name: "object replaced by scalar",
+			from: `{"s":{"x":1}}`,
+			to:   `{"s":"off"}`,
+			want: []Change{
+				{Path: "s", Type: Added, To: "off"},
+				{Path: "s.x", Type: Removed, From: float64(1)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffJSON(tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("DiffJSON() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffJSON() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffJSONInvalid(t *testing.T) {
+	if _, err := DiffJSON(`{"a":`, `{}`); err == nil {
+		t.Error("DiffJSON() with invalid JSON should return error")
+	}
+}
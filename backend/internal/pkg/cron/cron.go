@@ -0,0 +1,167 @@
+// Package cron 解析标准5段 cron 表达式（分 时 日 月 周）并计算下次触发时间
+// 支持 *、列表(1,15)、范围(1-5)、步长(*/10, 8-18/2)，以及 @hourly、@daily、@weekly、@monthly 简写；
+// 日和周同时限定时满足任一即触发，与 Vixie cron 一致
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid cron 表达式不合法
+var ErrInvalid = errors.New("cron 表达式不合法")
+
+// maxSearchYears 查找下次触发时间的最大年数，超过视为永不触发（例如 2月30日）
+const maxSearchYears = 5
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+type bounds struct{ min, max int }
+
+var fieldBounds = [5]bounds{
+	{0, 59}, // 分
+	{0, 23}, // 时
+	{1, 31}, // 日
+	{1, 12}, // 月
+	{0, 6},  // 周，0为周日，7同样表示周日
+}
+
+// Schedule 解析后的 cron 表达式
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // 各字段允许值的位图
+	domStar, dowStar              bool
+}
+
+// Parse 解析 cron 表达式
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[expr]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: 需要5段，实际%d段", ErrInvalid, len(fields))
+	}
+
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseField(f, fieldBounds[i], i == 4)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+		bits[i] = b
+	}
+	return &Schedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseField 解析单个字段为允许值位图
+func parseField(field string, b bounds, dow bool) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("步长 %q 无效", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := b.min, b.max
+		if dow {
+			hi = 7
+		}
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			ends := strings.SplitN(rng, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(ends[0])
+			hi, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil || lo > hi {
+				return 0, fmt.Errorf("范围 %q 无效", part)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("值 %q 无效", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				// 5/15 表示从5开始每15个单位
+				hi = b.max
+			}
+		}
+
+		max := b.max
+		if dow {
+			max = 7
+		}
+		if lo < b.min || hi > max {
+			return 0, fmt.Errorf("%q 超出范围 %d-%d", part, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			if dow && v == 7 {
+				v = 0
+				bits |= 1
+				break
+			}
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next 严格晚于 t 的下一次触发时间，按 t 所在时区计算；永不触发时返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches 日和周都限定时满足任一即可，只限定其一时按该字段判断
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
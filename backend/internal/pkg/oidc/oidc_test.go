@@ -0,0 +1,124 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"app-platform-backend/internal/pkg/oidc"
+	"app-platform-backend/internal/pkg/oidc/oidctest"
+)
+
+// login 走一遍授权流程，返回授权码
+func login(t *testing.T, p *oidc.Provider, state, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error: %v", err)
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize request error: %v", err)
+	}
+	resp.Body.Close()
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+	if got := loc.Query().Get("state"); got != state {
+		t.Fatalf("state = %q, want %q", got, state)
+	}
+	return loc.Query().Get("code")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	idp, srv, err := oidctest.NewServer("console", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	idp.SetUser(map[string]interface{}{
+		"sub":                "u-42",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"preferred_username": "alice",
+		"groups":             []string{"platform-admins", "dev"},
+	})
+
+	p := oidc.NewProvider(oidc.Config{
+		Issuer:       srv.URL,
+		ClientID:     "console",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:3000/sso/callback",
+	}, nil)
+
+	verifier, _ := oidc.NewCodeVerifier()
+	code := login(t, p, "st-1", "n-1", verifier)
+
+	token, err := p.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error: %v", err)
+	}
+	claims, err := p.VerifyIDToken(context.Background(), token.IDToken, "n-1")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() error: %v", err)
+	}
+
+	if claims.Subject != "u-42" || claims.Email != "alice@example.com" || !claims.EmailVerified || claims.PreferredUsername != "alice" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+	if got := claims.StringsClaim("groups"); !reflect.DeepEqual(got, []string{"platform-admins", "dev"}) {
+		t.Errorf("groups = %v", got)
+	}
+
+	// 授权码只能使用一次
+	if _, err := p.Exchange(context.Background(), code, verifier); err == nil {
+		t.Error("reused authorization code should be rejected")
+	}
+}
+
+func TestProvider_RejectsBadPKCEAndNonce(t *testing.T) {
+	_, srv, err := oidctest.NewServer("console", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	p := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "console", RedirectURL: "http://localhost/cb"}, nil)
+
+	verifier, _ := oidc.NewCodeVerifier()
+	other, _ := oidc.NewCodeVerifier()
+	if _, err := p.Exchange(context.Background(), login(t, p, "s", "n", verifier), other); err == nil {
+		t.Error("wrong code_verifier should be rejected")
+	}
+
+	token, err := p.Exchange(context.Background(), login(t, p, "s", "n", verifier), verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error: %v", err)
+	}
+	if _, err := p.VerifyIDToken(context.Background(), token.IDToken, "other-nonce"); !errors.Is(err, oidc.ErrNonceMismatch) {
+		t.Errorf("VerifyIDToken() error = %v, want ErrNonceMismatch", err)
+	}
+
+	// 其他客户端的 ID Token 不能通过受众校验
+	wrongAud := oidc.NewProvider(oidc.Config{Issuer: srv.URL, ClientID: "another"}, nil)
+	if _, err := wrongAud.VerifyIDToken(context.Background(), token.IDToken, "n"); !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("VerifyIDToken() error = %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 附录B 示例
+	got := oidc.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	if want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"; got != want {
+		t.Errorf("CodeChallengeS256() = %q, want %q", got, want)
+	}
+}
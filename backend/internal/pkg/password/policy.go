@@ -0,0 +1,165 @@
+// Package password 提供可配置的密码策略校验
+// 包括长度、字符类别、本地泄露密码库检查，以及密码有效期计算
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	"app-platform-backend/internal/config"
+)
+
+// 违规规则标识，用于审计日志归类
+const (
+	RuleLength   = "length"
+	RuleClasses  = "character_classes"
+	RuleUsername = "contains_username"
+	RuleBreached = "breached"
+	RuleReused   = "reused"
+)
+
+// Violation 密码不符合策略
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// builtinBreached 内置的高频泄露密码，未配置外部列表时也能拦截最常见的弱密码
+var builtinBreached = []string{
+	"123456", "12345678", "123456789", "1234567890", "password", "password1",
+	"qwerty", "qwerty123", "abc123", "111111", "000000", "123123", "admin",
+	"admin123", "admin@123", "root", "welcome", "iloveyou", "letmein",
+	"p@ssw0rd", "passw0rd", "1q2w3e4r", "aa123456", "woaini1314", "qq123456",
+}
+
+// Policy 密码策略
+type Policy struct {
+	cfg      config.PasswordPolicyConfig
+	breached map[string]struct{}
+}
+
+// NewPolicy 根据配置创建密码策略，配置了泄露密码列表文件时一并加载
+func NewPolicy(cfg config.PasswordPolicyConfig) (*Policy, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 100
+	}
+
+	p := &Policy{
+		cfg:      cfg,
+		breached: make(map[string]struct{}, len(builtinBreached)),
+	}
+	for _, pw := range builtinBreached {
+		p.breached[pw] = struct{}{}
+	}
+
+	if cfg.BreachedListPath != "" {
+		if err := p.loadBreachedList(cfg.BreachedListPath); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// loadBreachedList 加载泄露密码列表，每行一个，忽略空行和 # 注释
+func (p *Policy) loadBreachedList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Config 返回策略配置
+func (p *Policy) Config() config.PasswordPolicyConfig {
+	return p.cfg
+}
+
+// Validate 校验密码是否符合策略，username 用于禁止密码包含用户名
+func (p *Policy) Validate(password, username string) error {
+	length := len([]rune(password))
+	if length < p.cfg.MinLength || length > p.cfg.MaxLength {
+		return &Violation{
+			Rule:    RuleLength,
+			Message: fmt.Sprintf("密码长度应在%d-%d个字符之间", p.cfg.MinLength, p.cfg.MaxLength),
+		}
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	var missing []string
+	if p.cfg.RequireUpper && !hasUpper {
+		missing = append(missing, "大写字母")
+	}
+	if p.cfg.RequireLower && !hasLower {
+		missing = append(missing, "小写字母")
+	}
+	if p.cfg.RequireDigit && !hasDigit {
+		missing = append(missing, "数字")
+	}
+	if p.cfg.RequireSymbol && !hasSymbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return &Violation{
+			Rule:    RuleClasses,
+			Message: "密码必须包含" + strings.Join(missing, "、"),
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if username != "" && len(username) >= 3 && strings.Contains(lower, strings.ToLower(username)) {
+		return &Violation{Rule: RuleUsername, Message: "密码不能包含用户名"}
+	}
+
+	if _, ok := p.breached[lower]; ok {
+		return &Violation{Rule: RuleBreached, Message: "该密码已出现在泄露密码库中，请更换"}
+	}
+
+	return nil
+}
+
+// Expired 判断密码是否超过最长使用期限，MaxAgeDays 为0表示不限制
+func (p *Policy) Expired(changedAt time.Time, now time.Time) bool {
+	if p.cfg.MaxAgeDays <= 0 {
+		return false
+	}
+	return now.Sub(changedAt) > time.Duration(p.cfg.MaxAgeDays)*24*time.Hour
+}
+
+// HistoryCount 返回禁止重复使用的历史密码数量
+func (p *Policy) HistoryCount() int {
+	return p.cfg.HistoryCount
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/config"
+)
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := NewPolicy(config.PasswordPolicyConfig{
+		MinLength:    8,
+		MaxLength:    64,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+		MaxAgeDays:   90,
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy error: %v", err)
+	}
+	return p
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	p := testPolicy(t)
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		wantRule string
+	}{
+		{"valid", "Str0ngPassw", "ops", ""},
+		{"too short", "Ab1", "ops", RuleLength},
+		{"missing upper", "lowercase1", "ops", RuleClasses},
+		{"missing digit", "NoDigitsHere", "ops", RuleClasses},
+		{"contains username", "Operator2024", "operator", RuleUsername},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.password, tt.username)
+			if tt.wantRule == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			var v *Violation
+			if !errors.As(err, &v) {
+				t.Fatalf("Validate() error = %v, want violation %s", err, tt.wantRule)
+			}
+			if v.Rule != tt.wantRule {
+				t.Errorf("Validate() rule = %s, want %s", v.Rule, tt.wantRule)
+			}
+		})
+	}
+
+	// 内置泄露库比对忽略大小写
+	var v *Violation
+	if err := p.Validate("P@ssw0rd", "ops"); !errors.As(err, &v) || v.Rule != RuleBreached {
+		t.Errorf("P@ssw0rd should be rejected as breached, got %v", err)
+	}
+}
+
+func TestPolicy_BreachedListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := "# common passwords\nSummer2024!\n\nCompany123\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewPolicy(config.PasswordPolicyConfig{BreachedListPath: path})
+	if err != nil {
+		t.Fatalf("NewPolicy error: %v", err)
+	}
+
+	var v *Violation
+	if err := p.Validate("company123", ""); !errors.As(err, &v) || v.Rule != RuleBreached {
+		t.Errorf("company123 should be rejected as breached, got %v", err)
+	}
+	if err := p.Validate("Unlisted#Pass9", ""); err != nil {
+		t.Errorf("unlisted password should pass, got %v", err)
+	}
+
+	if _, err := NewPolicy(config.PasswordPolicyConfig{BreachedListPath: path + ".missing"}); err == nil {
+		t.Error("missing breached list file should return error")
+	}
+}
+
+func TestPolicy_Expired(t *testing.T) {
+	p := testPolicy(t)
+	now := time.Now()
+
+	if p.Expired(now.AddDate(0, 0, -30), now) {
+		t.Error("password changed 30 days ago should not be expired")
+	}
+	if !p.Expired(now.AddDate(0, 0, -91), now) {
+		t.Error("password changed 91 days ago should be expired")
+	}
+
+	unlimited, _ := NewPolicy(config.PasswordPolicyConfig{})
+	if unlimited.Expired(now.AddDate(-5, 0, 0), now) {
+		t.Error("max_age_days 0 should never expire")
+	}
+}
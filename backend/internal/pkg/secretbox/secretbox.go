@@ -0,0 +1,77 @@
+// Package secretbox 使用 AES-256-GCM 加密存储需要还原明文的敏感数据（如应用密钥）
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// version 密文前缀，便于以后更换算法或密钥
+const version = "v1:"
+
+var (
+	ErrEmptyKey   = errors.New("secretbox: encryption key is empty")
+	ErrCiphertext = errors.New("secretbox: malformed ciphertext")
+)
+
+// Box 对称加解密器
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New 根据配置的密钥创建加解密器，密钥经SHA-256派生为32字节
+func New(key string) (*Box, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Encrypt 加密明文，返回带版本前缀的base64密文
+func (b *Box) Encrypt(plain string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return version + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// IsCiphertext 判断文本是否为 Encrypt 生成的密文格式，用于区分加密前保存的明文
+func IsCiphertext(s string) bool {
+	return strings.HasPrefix(s, version)
+}
+
+// Decrypt 解密 Encrypt 生成的密文
+func (b *Box) Decrypt(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, version) {
+		return "", ErrCiphertext
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(ciphertext, version))
+	if err != nil {
+		return "", ErrCiphertext
+	}
+	n := b.aead.NonceSize()
+	if len(data) < n {
+		return "", ErrCiphertext
+	}
+	plain, err := b.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
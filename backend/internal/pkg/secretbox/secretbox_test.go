@@ -0,0 +1,57 @@
+package secretbox
+
+import "testing"
+
+func TestBox_RoundTrip(t *testing.T) {
+	box, err := New("test-key")
+	if err != nil {
+		t.Fatalf("New() error: %v", err)
+	}
+
+	c1, err := box.Encrypt("9f86d081884c7d65")
+	if err != nil {
+		t.Fatalf("Encrypt() error: %v", err)
+	}
+	c2, _ := box.Encrypt("9f86d081884c7d65")
+	if c1 == c2 {
+		t.Error("Encrypt() should use a random nonce for every call")
+	}
+
+	plain, err := box.Decrypt(c1)
+	if err != nil {
+		t.Fatalf("Decrypt() error: %v", err)
+	}
+	if plain != "9f86d081884c7d65" {
+		t.Errorf("Decrypt() = %q, want original plaintext", plain)
+	}
+}
+
+func TestBox_DecryptErrors(t *testing.T) {
+	box, _ := New("test-key")
+	other, _ := New("other-key")
+	ciphertext, _ := box.Encrypt("secret")
+
+	tests := []struct {
+		name       string
+		box        *Box
+		ciphertext string
+	}{
+		{"wrong key", other, ciphertext},
+		{"missing prefix", box, ciphertext[len(version):]},
+		{"bad base64", box, version + "!!!"},
+		{"too short", box, version + "AAAA"},
+		{"tampered", box, ciphertext[:len(ciphertext)-4] + "AAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.box.Decrypt(tt.ciphertext); err == nil {
+				t.Error("Decrypt() expected error")
+			}
+		})
+	}
+
+	if _, err := New(""); err != ErrEmptyKey {
+		t.Errorf("New(\"\") error = %v, want ErrEmptyKey", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Package signature 实现客户端SDK请求签名
+//
+// 待签名串由以下字段按顺序以换行符拼接：
+//
+//	HTTP方法（大写）
+//	请求路径（不含查询参数，如 /api/v1/client/events）
+//	规范化查询串（按键名排序后URL编码，无参数时为空串）
+//	请求体SHA256（十六进制小写，空请求体取空串的哈希）
+//	时间戳（Unix秒）
+//	随机串 nonce
+//
+// 签名为以 AppSecret 为密钥的 HMAC-SHA256，十六进制小写
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// 客户端请求头
+const (
+	HeaderAppID     = "X-App-Id"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// BodyHash 计算请求体的SHA256十六进制摘要
+func BodyHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// CanonicalQuery 将查询参数按键名排序后编码，保证客户端和服务端拼接结果一致
+func CanonicalQuery(query url.Values) string {
+	return query.Encode()
+}
+
+// StringToSign 拼接待签名串
+func StringToSign(method, path, canonicalQuery, bodyHash, timestamp, nonce string) string {
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		canonicalQuery,
+		bodyHash,
+		timestamp,
+		nonce,
+	}, "\n")
+}
+
+// Sign 使用密钥计算待签名串的HMAC-SHA256
+func Sign(secret, stringToSign string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stringToSign))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 以常量时间比较签名
+func Verify(secret, stringToSign, sig string) bool {
+	expected := Sign(secret, stringToSign)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(sig)))
+}
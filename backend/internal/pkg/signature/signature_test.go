@@ -0,0 +1,47 @@
+package signature
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestStringToSign(t *testing.T) {
+	query := url.Values{}
+	query.Set("version", "1.2.0")
+	query.Set("channel", "store")
+
+	got := StringToSign("get", "/api/v1/client/versions/check", CanonicalQuery(query), BodyHash(nil), "1700000000", "n0nce")
+	want := "GET\n/api/v1/client/versions/check\nchannel=store&version=1.2.0\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n1700000000\nn0nce"
+	if got != want {
+		t.Errorf("StringToSign() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	sts := StringToSign("POST", "/api/v1/client/events", "", BodyHash([]byte(`{"event_code":"open"}`)), "1700000000", "abc123")
+	sig := Sign("secret", sts)
+
+	tests := []struct {
+		name   string
+		secret string
+		sts    string
+		sig    string
+		want   bool
+	}{
+		{"valid", "secret", sts, sig, true},
+		{"uppercase hex accepted", "secret", sts, strings.ToUpper(sig), true},
+		{"wrong secret", "other", sts, sig, false},
+		{"tampered body", "secret", StringToSign("POST", "/api/v1/client/events", "", BodyHash([]byte(`{}`)), "1700000000", "abc123"), sig, false},
+		{"empty signature", "secret", sts, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.sts, tt.sig); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
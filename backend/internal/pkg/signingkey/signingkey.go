@@ -0,0 +1,132 @@
+// Package signingkey 生成、序列化非对称签名密钥，并按 RFC 7517 导出 JWK 公钥
+package signingkey
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// 支持的签名算法（JWS alg）
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+var ErrUnsupportedAlgorithm = errors.New("signingkey: unsupported algorithm")
+
+// Generate 生成指定算法的私钥
+func Generate(alg string) (crypto.Signer, error) {
+	switch alg {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// Method 返回算法对应的JWT签名方法
+func Method(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+// NewKeyID 生成随机密钥ID
+func NewKeyID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// MarshalPrivateKey 将私钥编码为 PKCS#8 PEM
+func MarshalPrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey 解析 PKCS#8 PEM 私钥
+func ParsePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("signingkey: invalid PEM private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return signer, nil
+}
+
+// MarshalPublicKey 将公钥编码为 PKIX PEM
+func MarshalPublicKey(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// ParsePublicKey 解析 PKIX PEM 公钥
+func ParsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("signingkey: invalid PEM public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// JWK 导出公钥的 JWK 表示
+func JWK(kid, alg string, pub crypto.PublicKey) (map[string]interface{}, error) {
+	key := map[string]interface{}{
+		"kid": kid,
+		"alg": alg,
+		"use": "sig",
+	}
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if alg != AlgRS256 {
+			return nil, fmt.Errorf("signingkey: RSA key cannot be used with %s", alg)
+		}
+		key["kty"] = "RSA"
+		key["n"] = base64.RawURLEncoding.EncodeToString(k.N.Bytes())
+		key["e"] = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes())
+	case ed25519.PublicKey:
+		if alg != AlgEdDSA {
+			return nil, fmt.Errorf("signingkey: Ed25519 key cannot be used with %s", alg)
+		}
+		key["kty"] = "OKP"
+		key["crv"] = "Ed25519"
+		key["x"] = base64.RawURLEncoding.EncodeToString(k)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return key, nil
+}
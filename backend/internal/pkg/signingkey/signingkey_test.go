@@ -0,0 +1,83 @@
+package signingkey
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRoundTripAndSign(t *testing.T) {
+	for _, alg := range []string{AlgRS256, AlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			priv, err := Generate(alg)
+			if err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+
+			privPEM, err := MarshalPrivateKey(priv)
+			if err != nil {
+				t.Fatalf("MarshalPrivateKey() error: %v", err)
+			}
+			parsedPriv, err := ParsePrivateKey(privPEM)
+			if err != nil {
+				t.Fatalf("ParsePrivateKey() error: %v", err)
+			}
+			pubPEM, err := MarshalPublicKey(priv.Public())
+			if err != nil {
+				t.Fatalf("MarshalPublicKey() error: %v", err)
+			}
+			pub, err := ParsePublicKey(pubPEM)
+			if err != nil {
+				t.Fatalf("ParsePublicKey() error: %v", err)
+			}
+
+			method, err := Method(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signed, err := jwt.NewWithClaims(method, jwt.RegisteredClaims{Subject: "1"}).SignedString(parsedPriv)
+			if err != nil {
+				t.Fatalf("sign error: %v", err)
+			}
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return pub, nil }); err != nil {
+				t.Errorf("verify with parsed public key: %v", err)
+			}
+		})
+	}
+}
+
+func TestJWK(t *testing.T) {
+	rsaKey, _ := Generate(AlgRS256)
+	jwk, err := JWK("k1", AlgRS256, rsaKey.Public())
+	if err != nil {
+		t.Fatalf("JWK(RSA) error: %v", err)
+	}
+	if jwk["kty"] != "RSA" || jwk["kid"] != "k1" || jwk["e"] != "AQAB" || jwk["n"] == "" {
+		t.Errorf("unexpected RSA JWK: %v", jwk)
+	}
+
+	edKey, _ := Generate(AlgEdDSA)
+	jwk, err = JWK("k2", AlgEdDSA, edKey.Public())
+	if err != nil {
+		t.Fatalf("JWK(Ed25519) error: %v", err)
+	}
+	if jwk["kty"] != "OKP" || jwk["crv"] != "Ed25519" || len(jwk["x"].(string)) != 43 {
+		t.Errorf("unexpected OKP JWK: %v", jwk)
+	}
+
+	// 密钥类型与算法不匹配时拒绝导出
+	if _, err := JWK("k3", AlgEdDSA, rsaKey.Public().(*rsa.PublicKey)); err == nil {
+		t.Error("RSA key with EdDSA should be rejected")
+	}
+	if _, err := JWK("k4", AlgRS256, edKey.Public().(ed25519.PublicKey)); err == nil {
+		t.Error("Ed25519 key with RS256 should be rejected")
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	if _, err := Generate("HS256"); err != ErrUnsupportedAlgorithm {
+		t.Errorf("Generate(HS256) error = %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
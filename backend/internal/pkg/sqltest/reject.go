@@ -0,0 +1,33 @@
+package sqltest
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// NoRows 对所有语句返回空结果的 Handler
+func NoRows(string, []driver.Value) Reply { return Reply{} }
+
+// Request 测试请求的方法和路径
+type Request struct {
+	Method string
+	Path   string
+}
+
+// RejectsWithoutQuery 断言每个请求都返回400且没有执行任何语句，
+// 用于检查处理器在访问数据库前校验路径参数
+func RejectsWithoutQuery(t *testing.T, h http.Handler, f *DB, requests []Request) {
+	t.Helper()
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(req.Method, req.Path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status = %d, want %d", req.Method, req.Path, w.Code, http.StatusBadRequest)
+		}
+	}
+	if calls := f.Calls(""); len(calls) != 0 {
+		t.Errorf("executed %d statements for invalid IDs, want 0", len(calls))
+	}
+}
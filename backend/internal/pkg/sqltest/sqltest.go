@@ -0,0 +1,138 @@
+// Package sqltest 提供测试用的 database/sql 驱动，语句交给测试的处理函数返回结果，
+// 用于在没有 MySQL 的环境下测试依赖数据库的流程
+package sqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Call 驱动收到的一条语句
+type Call struct {
+	Query string
+	Args  []driver.Value
+}
+
+// Reply 语句的返回：查询返回列和行，写入返回影响行数和自增ID
+type Reply struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	Affected     int64
+	LastInsertID int64
+}
+
+// Handler 处理一条语句，参数按原样传入，便于按类型比较
+type Handler func(query string, args []driver.Value) Reply
+
+// DB 记录收到的语句并交给 Handler 处理
+type DB struct {
+	mu     sync.Mutex
+	calls  []Call
+	handle Handler
+}
+
+// Open 打开使用 handle 处理语句的 gorm 连接
+func Open(t testing.TB, handle Handler) (*gorm.DB, *DB) {
+	t.Helper()
+	f := &DB{handle: handle}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(f),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true, SkipDefaultTransaction: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, f
+}
+
+// Calls 包含 substr 的语句，按执行顺序
+func (f *DB) Calls(substr string) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []Call
+	for _, c := range f.calls {
+		if strings.Contains(c.Query, substr) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (f *DB) run(query string, named []driver.NamedValue) Reply {
+	args := make([]driver.Value, len(named))
+	for i, v := range named {
+		args[i] = v.Value
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Query: query, Args: args})
+	f.mu.Unlock()
+	return f.handle(query, args)
+}
+
+func (f *DB) Connect(context.Context) (driver.Conn, error) { return &conn{db: f}, nil }
+
+func (f *DB) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, driver.ErrSkip }
+
+type conn struct{ db *DB }
+
+func (c *conn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+
+func (c *conn) Close() error { return nil }
+
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+func (c *conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	reply := c.db.run(query, args)
+	return &rows{columns: reply.Columns, rows: reply.Rows}, nil
+}
+
+func (c *conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	reply := c.db.run(query, args)
+	return result{affected: reply.Affected, lastInsertID: reply.LastInsertID}, nil
+}
+
+// CheckNamedValue 原样接收参数
+func (c *conn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+type tx struct{}
+
+func (tx) Commit() error { return nil }
+
+func (tx) Rollback() error { return nil }
+
+type result struct{ affected, lastInsertID int64 }
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+
+func (r result) RowsAffected() (int64, error) { return r.affected, nil }
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+
+func (r *rows) Close() error { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
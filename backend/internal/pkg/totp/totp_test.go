@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B测试向量（SHA1，取后6位）
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" // "12345678901234567890"
+
+func TestGenerateCode_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateCode(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateCode(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("GenerateCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate_Skew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	prev, _ := GenerateCode(rfcSecret, Step(now)-1)
+
+	if _, ok := Validate(rfcSecret, prev, now, 0); ok {
+		t.Error("previous step code should be rejected without skew")
+	}
+	step, ok := Validate(rfcSecret, prev, now, 1)
+	if !ok {
+		t.Fatal("previous step code should be accepted with skew 1")
+	}
+	if step != Step(now)-1 {
+		t.Errorf("matched step = %d, want %d", step, Step(now)-1)
+	}
+
+	if _, ok := Validate(rfcSecret, "12345", now, 1); ok {
+		t.Error("short code should be rejected")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret error: %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("secret length = %d, want 32", len(secret))
+	}
+	if _, err := GenerateCode(strings.ToLower(secret), 1); err != nil {
+		t.Errorf("lowercase secret should decode: %v", err)
+	}
+
+	uri := ProvisioningURI(secret, "App Platform", "admin")
+	if !strings.HasPrefix(uri, "otpauth://totp/App%20Platform:admin?") {
+		t.Errorf("unexpected uri prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("uri should contain secret: %s", uri)
+	}
+}
@@ -0,0 +1,169 @@
+// Package profile 用户自定义属性和匿名设备识别
+// 属性支持 set、set_once、increment、unset 四种操作，匿名设备的属性和事件在 identify 时合并到已知用户，
+// 使登录前后的行为归属同一用户
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+const (
+	maxProperties       = 200  // 单个用户或匿名设备的属性数上限
+	maxOperationKeys    = 100  // 单次操作涉及的属性数上限
+	maxStringValueRunes = 1024 // 字符串属性值长度上限
+)
+
+// ErrInvalidOperation 属性操作不合法
+var ErrInvalidOperation = errors.New("属性操作不合法")
+
+// propertyNamePattern 属性名，与分群规则的事件属性名规则一致
+var propertyNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// Operations 一次属性更新，按 set、set_once、increment、unset 的顺序执行
+type Operations struct {
+	Set       map[string]interface{} `json:"set"`
+	SetOnce   map[string]interface{} `json:"set_once"`  // 属性不存在时才写入
+	Increment map[string]float64     `json:"increment"` // 数值累加，属性不存在时从0开始
+	Unset     []string               `json:"unset"`
+}
+
+// Empty 是否没有任何操作
+func (o *Operations) Empty() bool {
+	return len(o.Set) == 0 && len(o.SetOnce) == 0 && len(o.Increment) == 0 && len(o.Unset) == 0
+}
+
+// Validate 校验属性名和属性值，属性值只支持字符串、数值和布尔
+func (o *Operations) Validate() error {
+	if o.Empty() {
+		return invalid("至少需要一种属性操作")
+	}
+	if len(o.Set)+len(o.SetOnce)+len(o.Increment)+len(o.Unset) > maxOperationKeys {
+		return invalid("单次最多操作%d个属性", maxOperationKeys)
+	}
+	for _, values := range []map[string]interface{}{o.Set, o.SetOnce} {
+		for name, value := range values {
+			if err := validateName(name); err != nil {
+				return err
+			}
+			if err := validateValue(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	for name := range o.Increment {
+		if err := validateName(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range o.Unset {
+		if err := validateName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply 在属性集合上执行操作，调用前应先 Validate
+func (o *Operations) Apply(props map[string]interface{}) error {
+	for name, value := range o.Set {
+		props[name] = value
+	}
+	for name, value := range o.SetOnce {
+		if _, ok := props[name]; !ok {
+			props[name] = value
+		}
+	}
+	for name, delta := range o.Increment {
+		current := float64(0)
+		if existing, ok := props[name]; ok {
+			n, isNumber := existing.(float64)
+			if !isNumber {
+				return invalid("属性 %s 不是数值，不能累加", name)
+			}
+			current = n
+		}
+		props[name] = current + delta
+	}
+	for _, name := range o.Unset {
+		delete(props, name)
+	}
+	if len(props) > maxProperties {
+		return invalid("属性数不能超过%d个", maxProperties)
+	}
+	return nil
+}
+
+// Merge 将匿名设备的属性合并到已知用户，已知用户已有的属性保持不变
+func Merge(dst, src map[string]interface{}) {
+	for _, name := range sortedNames(src) {
+		if len(dst) >= maxProperties {
+			return
+		}
+		if _, ok := dst[name]; !ok {
+			dst[name] = src[name]
+		}
+	}
+}
+
+// decode 解析数据库中的属性JSON
+func decode(raw string) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	if raw == "" {
+		return props, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &props); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// encode 序列化属性为JSON
+func encode(props map[string]interface{}) (string, error) {
+	data, err := json.Marshal(props)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ValidatePropertyName 校验属性名
+func ValidatePropertyName(name string) error {
+	return validateName(name)
+}
+
+func validateName(name string) error {
+	if !propertyNamePattern.MatchString(name) {
+		return invalid("属性名 %q 不合法", name)
+	}
+	return nil
+}
+
+func validateValue(name string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if len([]rune(v)) > maxStringValueRunes {
+			return invalid("属性 %s 的值不能超过%d个字符", name, maxStringValueRunes)
+		}
+	case float64, bool:
+	default:
+		return invalid("属性 %s 的值只能是字符串、数值或布尔", name)
+	}
+	return nil
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidOperation, fmt.Sprintf(format, args...))
+}
+
+func sortedNames(m map[string]interface{}) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,114 @@
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseOps(t *testing.T, raw string) *Operations {
+	t.Helper()
+	var ops Operations
+	if err := json.Unmarshal([]byte(raw), &ops); err != nil {
+		t.Fatal(err)
+	}
+	return &ops
+}
+
+func TestOperations_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"set scalars", `{"set":{"plan":"pro","age":30,"vip":true}}`, false},
+		{"all operations", `{"set":{"a":"1"},"set_once":{"b":"2"},"increment":{"c":1},"unset":["d"]}`, false},
+		{"empty", `{}`, true},
+		{"bad name", `{"set":{"a.b":"1"}}`, true},
+		{"bad unset name", `{"unset":["$email"]}`, true},
+		{"null value", `{"set":{"a":null}}`, true},
+		{"nested value", `{"set_once":{"a":{"b":1}}}`, true},
+		{"long string", `{"set":{"a":"` + strings.Repeat("x", maxStringValueRunes+1) + `"}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseOps(t, tt.raw).Validate()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidOperation) {
+					t.Errorf("err = %v, want ErrInvalidOperation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestOperations_Apply(t *testing.T) {
+	props := map[string]interface{}{"plan": "free", "first_seen": "2024-01-01", "logins": float64(2), "tmp": true}
+	ops := parseOps(t, `{
+		"set": {"plan": "pro"},
+		"set_once": {"first_seen": "2024-06-01", "channel": "ads"},
+		"increment": {"logins": 1, "purchases": 2.5},
+		"unset": ["tmp"]}`)
+	if err := ops.Apply(props); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"plan":       "pro",
+		"first_seen": "2024-01-01",
+		"channel":    "ads",
+		"logins":     float64(3),
+		"purchases":  2.5,
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Errorf("props = %v, want %v", props, want)
+	}
+}
+
+func TestOperations_ApplyIncrementNonNumeric(t *testing.T) {
+	props := map[string]interface{}{"plan": "free"}
+	err := parseOps(t, `{"increment":{"plan":1}}`).Apply(props)
+	if !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("err = %v, want ErrInvalidOperation", err)
+	}
+}
+
+func TestOperations_ApplyTooManyProperties(t *testing.T) {
+	props := map[string]interface{}{}
+	for i := 0; i < maxProperties; i++ {
+		props[fmt.Sprintf("p%d", i)] = true
+	}
+	err := parseOps(t, `{"set":{"one_more":1}}`).Apply(props)
+	if !errors.Is(err, ErrInvalidOperation) {
+		t.Errorf("err = %v, want ErrInvalidOperation", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	user := map[string]interface{}{"plan": "pro"}
+	anon := map[string]interface{}{"plan": "free", "channel": "ads"}
+	Merge(user, anon)
+	want := map[string]interface{}{"plan": "pro", "channel": "ads"}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("merged = %v, want %v", user, want)
+	}
+}
+
+func TestValidateAnonymousID(t *testing.T) {
+	for _, id := range []string{"3f2b8c1e-7d4a-4b8e-9f00-1a2b3c4d5e6f", "dev_123", "ios:ABC.1"} {
+		if err := ValidateAnonymousID(id); err != nil {
+			t.Errorf("ValidateAnonymousID(%q) = %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "has space", strings.Repeat("a", 65), "a'b"} {
+		if err := ValidateAnonymousID(id); !errors.Is(err, ErrInvalidAnonymousID) {
+			t.Errorf("ValidateAnonymousID(%q) = %v, want ErrInvalidAnonymousID", id, err)
+		}
+	}
+}
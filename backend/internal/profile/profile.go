@@ -0,0 +1,258 @@
+package profile
+
+import (
+	"errors"
+	"regexp"
+	"time"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// backfillBatchSize identify 时每批回填的事件数
+const backfillBatchSize = 1000
+
+var (
+	ErrInvalidAnonymousID = errors.New("无效的匿名设备ID")
+	ErrAlreadyIdentified  = errors.New("该匿名设备已识别为其他用户")
+)
+
+// anonymousIDPattern 匿名设备ID，由SDK生成，通常是UUID
+var anonymousIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// ValidateAnonymousID 校验匿名设备ID格式
+func ValidateAnonymousID(id string) error {
+	if !anonymousIDPattern.MatchString(id) {
+		return ErrInvalidAnonymousID
+	}
+	return nil
+}
+
+// IdentifyResult identify 的合并结果
+type IdentifyResult struct {
+	UserID       uint   `json:"user_id"`
+	AnonymousID  string `json:"anonymous_id"`
+	MergedEvents int64  `json:"merged_events"` // 回填 user_id 的匿名事件数
+}
+
+// UserProperties 已知用户的自定义属性
+func UserProperties(db *gorm.DB, appID, userID uint) (map[string]interface{}, error) {
+	var p model.UserProfile
+	err := db.Where("app_id = ? AND user_id = ?", appID, userID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(p.Properties)
+}
+
+// UsersProperties 批量查询已知用户的自定义属性，没有属性的用户不在结果中
+func UsersProperties(db *gorm.DB, appID uint, userIDs []uint) (map[uint]map[string]interface{}, error) {
+	result := make(map[uint]map[string]interface{}, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+	var profiles []model.UserProfile
+	if err := db.Where("app_id = ? AND user_id IN ?", appID, userIDs).Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		props, err := decode(p.Properties)
+		if err != nil {
+			return nil, err
+		}
+		result[p.UserID] = props
+	}
+	return result, nil
+}
+
+// UpdateUser 在已知用户的属性上执行操作，返回更新后的属性
+func UpdateUser(db *gorm.DB, appID, userID uint, ops *Operations) (map[string]interface{}, error) {
+	if err := ops.Validate(); err != nil {
+		return nil, err
+	}
+	var props map[string]interface{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		p, err := lockUserProfile(tx, appID, userID)
+		if err != nil {
+			return err
+		}
+		if props, err = decode(p.Properties); err != nil {
+			return err
+		}
+		if err := ops.Apply(props); err != nil {
+			return err
+		}
+		return saveProperties(tx, p, props)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+// UpdateAnonymous 在匿名设备的属性上执行操作，设备已识别时改为更新对应用户的属性
+func UpdateAnonymous(db *gorm.DB, appID uint, anonymousID string, ops *Operations) (map[string]interface{}, error) {
+	if err := ValidateAnonymousID(anonymousID); err != nil {
+		return nil, err
+	}
+	if err := ops.Validate(); err != nil {
+		return nil, err
+	}
+
+	var props map[string]interface{}
+	var mergedUserID *uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		p, err := lockAnonymousProfile(tx, appID, anonymousID)
+		if err != nil {
+			return err
+		}
+		if p.UserID != nil {
+			mergedUserID = p.UserID
+			return nil
+		}
+		if props, err = decode(p.Properties); err != nil {
+			return err
+		}
+		if err := ops.Apply(props); err != nil {
+			return err
+		}
+		return saveProperties(tx, p, props)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if mergedUserID != nil {
+		return UpdateUser(db, appID, *mergedUserID, ops)
+	}
+	return props, nil
+}
+
+// Identify 将匿名设备识别为已知用户：合并匿名属性（已知用户已有的属性优先），
+// 并把该设备尚未归属用户的事件回填为该用户。重复调用是幂等的，会补齐合并后新上报的匿名事件
+func Identify(db *gorm.DB, appID uint, anonymousID string, userID uint) (*IdentifyResult, error) {
+	if err := ValidateAnonymousID(anonymousID); err != nil {
+		return nil, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		anon, err := lockAnonymousProfile(tx, appID, anonymousID)
+		if err != nil {
+			return err
+		}
+		if anon.UserID != nil {
+			if *anon.UserID != userID {
+				return ErrAlreadyIdentified
+			}
+			return nil
+		}
+
+		anonProps, err := decode(anon.Properties)
+		if err != nil {
+			return err
+		}
+		if len(anonProps) > 0 {
+			user, err := lockUserProfile(tx, appID, userID)
+			if err != nil {
+				return err
+			}
+			userProps, err := decode(user.Properties)
+			if err != nil {
+				return err
+			}
+			Merge(userProps, anonProps)
+			if err := saveProperties(tx, user, userProps); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		return tx.Model(anon).Updates(map[string]interface{}{"user_id": userID, "merged_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := backfillEvents(db, appID, anonymousID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &IdentifyResult{UserID: userID, AnonymousID: anonymousID, MergedEvents: merged}, nil
+}
+
+// ResolveAnonymous 查询已识别的匿名设备对应的用户，未识别的设备不在结果中
+func ResolveAnonymous(db *gorm.DB, appID uint, anonymousIDs []string) (map[string]uint, error) {
+	result := make(map[string]uint)
+	if len(anonymousIDs) == 0 {
+		return result, nil
+	}
+	var profiles []model.AnonymousProfile
+	if err := db.Select("anonymous_id", "user_id").
+		Where("app_id = ? AND anonymous_id IN ? AND user_id IS NOT NULL", appID, anonymousIDs).
+		Find(&profiles).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		result[p.AnonymousID] = *p.UserID
+	}
+	return result, nil
+}
+
+// backfillEvents 分批把匿名设备未归属用户的事件回填为该用户，避免单条语句长时间锁表
+func backfillEvents(db *gorm.DB, appID uint, anonymousID string, userID uint) (int64, error) {
+	var total int64
+	for {
+		result := db.Exec(
+			"UPDATE events SET user_id = ? WHERE app_id = ? AND anonymous_id = ? AND user_id IS NULL LIMIT ?",
+			userID, appID, anonymousID, backfillBatchSize,
+		)
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < backfillBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// lockUserProfile 加锁读取用户属性，不存在时先创建
+func lockUserProfile(tx *gorm.DB, appID, userID uint) (*model.UserProfile, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.UserProfile{AppID: appID, UserID: userID, Properties: "{}"}).Error; err != nil {
+		return nil, err
+	}
+	var p model.UserProfile
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("app_id = ? AND user_id = ?", appID, userID).First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// lockAnonymousProfile 加锁读取匿名设备属性，不存在时先创建
+func lockAnonymousProfile(tx *gorm.DB, appID uint, anonymousID string) (*model.AnonymousProfile, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.AnonymousProfile{AppID: appID, AnonymousID: anonymousID, Properties: "{}"}).Error; err != nil {
+		return nil, err
+	}
+	var p model.AnonymousProfile
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("app_id = ? AND anonymous_id = ?", appID, anonymousID).First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// saveProperties 写回属性JSON
+func saveProperties(tx *gorm.DB, value interface{}, props map[string]interface{}) error {
+	data, err := encode(props)
+	if err != nil {
+		return err
+	}
+	return tx.Model(value).Update("properties", data).Error
+}
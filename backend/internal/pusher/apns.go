@@ -0,0 +1,150 @@
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+	// apnsTokenTTL 提供方令牌的有效期，APNs 要求每20到60分钟刷新一次
+	apnsTokenTTL = 50 * time.Minute
+)
+
+func init() { Register(ProviderAPNs, newAPNs) }
+
+// apnsCredentials APNs 令牌认证凭证，私钥为开发者后台下载的 .p8 文件内容
+type apnsCredentials struct {
+	TeamID     string `json:"teamId"`
+	KeyID      string `json:"keyId"`
+	PrivateKey string `json:"privateKey"`
+	BundleID   string `json:"bundleId"`
+	Sandbox    bool   `json:"sandbox"`
+}
+
+// APNs 通过 HTTP/2 接口和 ES256 签名的提供方令牌向 iOS 设备投递
+type APNs struct {
+	teamID   string
+	keyID    string
+	key      *ecdsa.PrivateKey
+	topic    string
+	endpoint string
+	client   *http.Client
+	token    cachedToken
+}
+
+func newAPNs(raw json.RawMessage) (Provider, error) {
+	var cred apnsCredentials
+	if err := decodeCredentials(raw, &cred); err != nil {
+		return nil, err
+	}
+	if err := requireFields(map[string]string{
+		"teamId": cred.TeamID, "keyId": cred.KeyID, "privateKey": cred.PrivateKey, "bundleId": cred.BundleID,
+	}); err != nil {
+		return nil, err
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(cred.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("私钥格式错误: %w", err)
+	}
+
+	endpoint := apnsProductionURL
+	if cred.Sandbox {
+		endpoint = apnsSandboxURL
+	}
+	return &APNs{
+		teamID:   cred.TeamID,
+		keyID:    cred.KeyID,
+		key:      key,
+		topic:    cred.BundleID,
+		endpoint: endpoint,
+		client:   newHTTPClient(),
+	}, nil
+}
+
+// apnsPayload 通知负载，自定义数据与 aps 并列
+func apnsPayload(n *Notification) map[string]interface{} {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": n.Title, "body": n.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range n.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	return payload
+}
+
+// Send 投递到单个设备
+func (p *APNs) Send(ctx context.Context, token string, n *Notification) error {
+	body, err := json.Marshal(apnsPayload(n))
+	if err != nil {
+		return err
+	}
+	bearer, err := p.token.get(p.signToken)
+	if err != nil {
+		return fmt.Errorf("apns: sign token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+bearer)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if n.TTL > 0 {
+		req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(n.TTL).Unix(), 10))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var result struct {
+		Reason string `json:"reason"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(data, &result)
+
+	switch {
+	case resp.StatusCode == http.StatusGone || result.Reason == "BadDeviceToken" || result.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("apns: %s: %w", result.Reason, ErrUnregistered)
+	case result.Reason == "ExpiredProviderToken" || result.Reason == "InvalidProviderToken":
+		p.token.reset()
+	}
+	return fmt.Errorf("apns: status %d: %s", resp.StatusCode, result.Reason)
+}
+
+// signToken 签发提供方令牌
+func (p *APNs) signToken() (string, time.Duration, error) {
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.teamID,
+		"iat": time.Now().Unix(),
+	})
+	t.Header["kid"] = p.keyID
+	signed, err := t.SignedString(p.key)
+	if err != nil {
+		return "", 0, err
+	}
+	return signed, apnsTokenTTL, nil
+}
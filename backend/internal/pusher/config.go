@@ -0,0 +1,129 @@
+package pusher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ModuleCode 推送服务模块，通道凭证保存在该模块的环境配置中
+const ModuleCode = "push_service"
+
+var (
+	// ErrNotConfigured 应用环境没有推送服务模块配置
+	ErrNotConfigured = errors.New("推送服务未配置")
+	// ErrDisabled 推送服务模块配置中关闭了推送
+	ErrDisabled = errors.New("推送服务未启用")
+)
+
+// Config 推送服务模块配置中与投递相关的部分，providers 按通道名称保存凭证（密钥字段加密保存，见 SealConfig），例如
+//
+//	{"enabled": true, "providers": {"apns": {"teamId": "...", "keyId": "...", "privateKey": "...", "bundleId": "..."}}}
+type Config struct {
+	Enabled   *bool                      `json:"enabled"`
+	Providers map[string]json.RawMessage `json:"providers"`
+}
+
+// Channels 应用环境中已配置的推送通道
+type Channels struct {
+	Env       string // 通道凭证所属的环境，只向该环境登记的设备投递
+	Enabled   bool
+	providers map[string]Provider
+	errs      map[string]error
+}
+
+// NewChannels 按模块配置创建推送通道，凭证无效的通道记录错误，投递到该通道时返回
+func NewChannels(cfg *Config) *Channels {
+	ch := &Channels{
+		Enabled:   cfg.Enabled == nil || *cfg.Enabled,
+		providers: map[string]Provider{},
+		errs:      map[string]error{},
+	}
+	for name, raw := range cfg.Providers {
+		p, err := New(name, raw)
+		if err != nil {
+			ch.errs[name] = err
+			continue
+		}
+		ch.providers[name] = p
+	}
+	return ch
+}
+
+// Get 按名称获取推送通道
+func (ch *Channels) Get(name string) (Provider, error) {
+	if p, ok := ch.providers[name]; ok {
+		return p, nil
+	}
+	if err, ok := ch.errs[name]; ok {
+		return nil, fmt.Errorf("推送通道配置无效: %w", err)
+	}
+	return nil, fmt.Errorf("%s: 推送通道未配置", name)
+}
+
+// channelsEntry 缓存的推送通道，配置未变化时复用以保留各通道的访问令牌
+type channelsEntry struct {
+	raw      string
+	channels *Channels
+}
+
+var (
+	channelsMu    sync.Mutex
+	channelsCache = map[uint]*channelsEntry{} // 按环境ID缓存
+)
+
+// LoadChannels 加载应用环境的推送通道，env 为空时使用应用的默认环境
+func LoadChannels(db *gorm.DB, appID uint, env string) (*Channels, error) {
+	var environment model.AppEnvironment
+	query := db.Where("app_id = ?", appID)
+	if env != "" {
+		query = query.Where("code = ?", env)
+	} else {
+		query = query.Where("is_default = ?", true)
+	}
+	if err := query.Order("sort_order ASC").First(&environment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotConfigured
+		}
+		return nil, err
+	}
+
+	var moduleConfig model.AppModuleConfig
+	if err := db.Where("app_id = ? AND env_id = ? AND module_code = ?", appID, environment.ID, ModuleCode).
+		First(&moduleConfig).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotConfigured
+		}
+		return nil, err
+	}
+
+	channelsMu.Lock()
+	defer channelsMu.Unlock()
+	if entry, ok := channelsCache[environment.ID]; ok && entry.raw == moduleConfig.Config {
+		return entry.channels, nil
+	}
+
+	var cfg Config
+	if moduleConfig.Config != "" {
+		opened, err := OpenConfig(moduleConfig.Config)
+		if err != nil {
+			return nil, fmt.Errorf("推送服务配置无法解密: %w", err)
+		}
+		if err := json.Unmarshal([]byte(opened), &cfg); err != nil {
+			return nil, fmt.Errorf("推送服务配置格式错误: %w", err)
+		}
+	}
+	channels := NewChannels(&cfg)
+	channels.Env = environment.Code
+	for name, err := range channels.errs {
+		log.Printf("[Pusher] App %d env %s: invalid %s credentials: %v", appID, environment.Code, name, err)
+	}
+	channelsCache[environment.ID] = &channelsEntry{raw: moduleConfig.Config, channels: channels}
+	return channels, nil
+}
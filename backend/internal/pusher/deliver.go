@@ -0,0 +1,320 @@
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/segment"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// deliveryBatchSize 每批加载的设备数
+	deliveryBatchSize = 500
+	// deliveryConcurrency 同时投递的设备数
+	deliveryConcurrency = 16
+	// maxErrorLength 推送记录中错误信息的最大长度
+	maxErrorLength = 500
+)
+
+// Result 一次推送的投递结果，按设备计数
+type Result struct {
+	Sent         int    `json:"sent_count"`
+	Success      int    `json:"success_count"`
+	Failed       int    `json:"failed_count"`
+	Unregistered int    `json:"unregistered_count"` // 失败中令牌已失效的设备数
+	Error        string `json:"error,omitempty"`    // 最近一次设备投递错误
+
+	unregistered []model.PushDevice // 令牌已失效的设备，由投递流程标记失效
+}
+
+func (r *Result) add(o *Result) {
+	r.Sent += o.Sent
+	r.Success += o.Success
+	r.Failed += o.Failed
+	r.Unregistered += o.Unregistered
+	r.unregistered = append(r.unregistered, o.unregistered...)
+	if o.Error != "" {
+		r.Error = o.Error
+	}
+}
+
+// Deliver 解析推送目标的设备，按设备所属通道分批投递，每批完成后写回进度并续期租约，
+// 最后写回推送记录的状态、计数和错误。调用方需先认领记录（Claim 或 ClaimDue）
+// 记录中已有进度时从断点继续；推送服务未配置或全部设备投递失败时记录为发送失败。
+// 发送途中被取消时返回 ErrCancelled，ctx 结束时释放租约以便其他实例立即接手
+func Deliver(ctx context.Context, db *gorm.DB, record *model.PushRecord) (*Result, error) {
+	result := &Result{
+		Sent:    record.SentCount,
+		Success: record.SuccessCount,
+		Failed:  record.FailedCount,
+		Error:   record.Error,
+	}
+	err := deliver(ctx, db, record, result)
+	if errors.Is(err, ErrCancelled) {
+		log.Printf("[Pusher] Push %d of app %d cancelled after %d devices", record.ID, record.AppID, result.Sent)
+		return result, err
+	}
+	if ctx.Err() != nil {
+		if dbErr := db.Model(&model.PushRecord{}).
+			Where("id = ? AND status = ?", record.ID, model.PushSending).
+			Update("lease_until", nil).Error; dbErr != nil {
+			return result, dbErr
+		}
+		return result, ctx.Err()
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	status := model.PushSent
+	if err != nil || (result.Sent > 0 && result.Success == 0) {
+		status = model.PushFailed
+	}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        status,
+		"sent_at":       now,
+		"sent_count":    result.Sent,
+		"success_count": result.Success,
+		"failed_count":  result.Failed,
+		"error":         truncate(result.Error, maxErrorLength),
+		"lease_until":   nil,
+	}
+	res := db.Model(&model.PushRecord{}).Where("id = ? AND status = ?", record.ID, model.PushSending).Updates(updates)
+	if res.Error != nil {
+		return result, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return result, ErrCancelled
+	}
+	record.Status, record.SentAt, record.LeaseUntil = status, &now, nil
+	record.SentCount, record.SuccessCount, record.FailedCount = result.Sent, result.Success, result.Failed
+	record.Error = updates["error"].(string)
+
+	log.Printf("[Pusher] Push %d of app %d: sent=%d success=%d failed=%d unregistered=%d",
+		record.ID, record.AppID, result.Sent, result.Success, result.Failed, result.Unregistered)
+	return result, err
+}
+
+func deliver(ctx context.Context, db *gorm.DB, record *model.PushRecord, result *Result) error {
+	channels, err := LoadChannels(db, record.AppID, record.Env)
+	if err != nil {
+		return err
+	}
+	if !channels.Enabled {
+		return ErrDisabled
+	}
+
+	recipients, err := segment.RecipientIDs(db, record.AppID, record.TargetType, segment.ParseIDs(record.TargetIDs))
+	if err != nil {
+		return err
+	}
+
+	// 模板推送按设备用户和语言逐个渲染，普通推送所有设备使用同一通知
+	var tpl *Template
+	var params map[string]string
+	if record.TemplateID != nil {
+		if tpl, params, err = recordTemplate(db, record); err != nil {
+			return err
+		}
+	}
+	n := &Notification{Title: record.Title, Body: record.Content}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var devices []model.PushDevice
+		// 只投递到通道凭证所属环境登记的设备，env 为空的推送按默认环境解析
+		if err := db.Where("app_id = ? AND env = ? AND id > ? AND user_id IN (?) AND invalidated_at IS NULL",
+			record.AppID, channels.Env, record.Cursor, recipients).
+			Order("id").Limit(deliveryBatchSize).Find(&devices).Error; err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+		notifications := make([]*Notification, len(devices))
+		if tpl != nil {
+			if notifications, err = tpl.renderBatch(db, record.AppID, params, devices); err != nil {
+				return err
+			}
+		} else {
+			for i := range notifications {
+				notifications[i] = n
+			}
+		}
+		batch, err := sendBatch(ctx, db, record, func(ctx context.Context) *Result {
+			return SendEach(ctx, channels, devices, notifications)
+		})
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			// 中断的批次不计入进度，接手后重新投递
+			return err
+		}
+		result.add(batch)
+		if err := Invalidate(db, record.AppID, channels.Env, batch.unregistered); err != nil {
+			log.Printf("[Pusher] Failed to invalidate %d devices of app %d: %v", len(batch.unregistered), record.AppID, err)
+		}
+		record.Cursor = devices[len(devices)-1].ID
+		if err := checkpoint(db, record, result); err != nil {
+			return err
+		}
+	}
+}
+
+// sendBatch 投递一批设备，投递期间定期续期租约，慢批次不会因租约过期被其他实例认领后重复投递；
+// 续期时发现推送已被取消则中止本批次，返回 ErrCancelled
+func sendBatch(ctx context.Context, db *gorm.DB, record *model.PushRecord, send func(ctx context.Context) *Result) (*Result, error) {
+	batchCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(leaseRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-batchCtx.Done():
+				return
+			case <-ticker.C:
+				err := renewLease(db, record.ID)
+				if errors.Is(err, ErrCancelled) {
+					cancel(ErrCancelled)
+					return
+				}
+				if err != nil {
+					log.Printf("[Pusher] Failed to renew lease of push %d: %v", record.ID, err)
+				}
+			}
+		}
+	}()
+
+	batch := send(batchCtx)
+	close(done)
+	<-stopped
+	if errors.Is(context.Cause(batchCtx), ErrCancelled) {
+		return batch, ErrCancelled
+	}
+	return batch, nil
+}
+
+// renewLease 续期发送租约，记录已不在发送中（被取消）时返回 ErrCancelled
+func renewLease(db *gorm.DB, id uint) error {
+	res := db.Model(&model.PushRecord{}).
+		Where("id = ? AND status = ?", id, model.PushSending).
+		Update("lease_until", time.Now().Add(DeliveryLease))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCancelled
+	}
+	return nil
+}
+
+// checkpoint 保存投递进度并续期租约，记录已不在发送中（被取消）时返回 ErrCancelled
+func checkpoint(db *gorm.DB, record *model.PushRecord, result *Result) error {
+	lease := time.Now().Add(DeliveryLease)
+	res := db.Model(&model.PushRecord{}).
+		Where("id = ? AND status = ?", record.ID, model.PushSending).
+		Updates(map[string]interface{}{
+			"cursor":        record.Cursor,
+			"sent_count":    result.Sent,
+			"success_count": result.Success,
+			"failed_count":  result.Failed,
+			"error":         truncate(result.Error, maxErrorLength),
+			"lease_until":   lease,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrCancelled
+	}
+	record.LeaseUntil = &lease
+	return nil
+}
+
+// recordTemplate 加载推送引用的模板，并按模板当前的变量声明重新校验推送的变量
+func recordTemplate(db *gorm.DB, record *model.PushRecord) (*Template, map[string]string, error) {
+	_, tpl, err := LoadTemplate(db, record.AppID, *record.TemplateID)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := map[string]interface{}{}
+	if record.Variables != "" {
+		if err := json.Unmarshal([]byte(record.Variables), &params); err != nil {
+			return nil, nil, fmt.Errorf("推送变量解析失败: %w", err)
+		}
+	}
+	bound, err := tpl.Bind(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tpl, bound, nil
+}
+
+// Send 并发向一批设备投递同一条通知
+func Send(ctx context.Context, channels *Channels, devices []model.PushDevice, n *Notification) *Result {
+	notifications := make([]*Notification, len(devices))
+	for i := range notifications {
+		notifications[i] = n
+	}
+	return SendEach(ctx, channels, devices, notifications)
+}
+
+// SendEach 并发向一批设备投递各自的通知，notifications 与 devices 一一对应
+func SendEach(ctx context.Context, channels *Channels, devices []model.PushDevice, notifications []*Notification) *Result {
+	result := &Result{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, deliveryConcurrency)
+
+	for i := range devices {
+		device, n := &devices[i], notifications[i]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+
+			err := sendOne(ctx, channels, device, n)
+			mu.Lock()
+			defer mu.Unlock()
+			result.Sent++
+			if err == nil {
+				result.Success++
+				return
+			}
+			result.Failed++
+			result.Error = err.Error()
+			if errors.Is(err, ErrUnregistered) {
+				result.Unregistered++
+				result.unregistered = append(result.unregistered, *device)
+			}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+func sendOne(ctx context.Context, channels *Channels, device *model.PushDevice, n *Notification) error {
+	p, err := channels.Get(device.Provider)
+	if err != nil {
+		return err
+	}
+	return p.Send(ctx, device.Token, n)
+}
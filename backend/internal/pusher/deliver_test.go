@@ -0,0 +1,122 @@
+package pusher
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/sqltest"
+)
+
+// pushStore 投递测试的数据：一个环境、其推送服务配置和设备
+type pushStore struct {
+	envID   uint
+	env     string
+	config  string
+	devices []model.PushDevice
+}
+
+// handle 按投递流程发出的语句返回数据；设备查询按语句中的条件筛选，
+// 条件缺失时不筛选，从而暴露查询遗漏的条件
+func (s *pushStore) handle(query string, args []driver.Value) sqltest.Reply {
+	switch {
+	case strings.Contains(query, "FROM `app_environments`"):
+		return sqltest.Reply{
+			Columns: []string{"id", "app_id", "code", "is_default"},
+			Rows:    [][]driver.Value{{int64(s.envID), int64(1), s.env, true}},
+		}
+	case strings.Contains(query, "FROM `app_module_configs`"):
+		return sqltest.Reply{
+			Columns: []string{"id", "app_id", "env_id", "module_code", "config"},
+			Rows:    [][]driver.Value{{int64(1), int64(1), int64(s.envID), ModuleCode, s.config}},
+		}
+	case strings.Contains(query, "FROM `push_devices`"):
+		return s.selectDevices(query, args)
+	case strings.HasPrefix(query, "UPDATE"):
+		return sqltest.Reply{Affected: 1}
+	}
+	return sqltest.Reply{}
+}
+
+func (s *pushStore) selectDevices(query string, args []driver.Value) sqltest.Reply {
+	next := func() string {
+		v := args[0]
+		args = args[1:]
+		return fmt.Sprint(v)
+	}
+	appID := next()
+	env := ""
+	if strings.Contains(query, "env = ?") {
+		env = next()
+	}
+	cursor, _ := strconv.ParseUint(next(), 10, 64)
+
+	reply := sqltest.Reply{Columns: []string{"id", "app_id", "env", "user_id", "provider", "token", "locale", "invalidated_at"}}
+	for _, d := range s.devices {
+		if fmt.Sprint(d.AppID) != appID || (env != "" && d.Env != env) || uint64(d.ID) <= cursor {
+			continue
+		}
+		if d.InvalidatedAt != nil && strings.Contains(query, "invalidated_at IS NULL") {
+			continue
+		}
+		var invalidatedAt driver.Value
+		if d.InvalidatedAt != nil {
+			invalidatedAt = *d.InvalidatedAt
+		}
+		reply.Rows = append(reply.Rows, []driver.Value{
+			int64(d.ID), int64(d.AppID), d.Env, int64(d.UserID), d.Provider, d.Token, d.Locale, invalidatedAt,
+		})
+	}
+	return reply
+}
+
+func TestDeliver_SkipsInvalidatedAndOtherEnvDevices(t *testing.T) {
+	invalidated := time.Now().Add(-time.Hour)
+	store := &pushStore{
+		envID:  9001,
+		env:    "prod",
+		config: `{"providers": {"mock": {"unregistered": ["stale"]}}}`,
+		devices: []model.PushDevice{
+			{ID: 1, AppID: 1, Env: "prod", UserID: 1, Provider: ProviderMock, Token: "active"},
+			{ID: 2, AppID: 1, Env: "prod", UserID: 1, Provider: ProviderMock, Token: "invalidated", InvalidatedAt: &invalidated},
+			{ID: 3, AppID: 1, Env: "dev", UserID: 1, Provider: ProviderMock, Token: "dev-device"},
+			{ID: 4, AppID: 1, Env: "prod", UserID: 2, Provider: ProviderMock, Token: "stale"},
+		},
+	}
+	db, fake := sqltest.Open(t, store.handle)
+
+	record := &model.PushRecord{ID: 1, AppID: 1, Env: "prod", Title: "t", Content: "c", TargetType: "all", Status: model.PushSending}
+	result, err := Deliver(context.Background(), db, record)
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if result.Sent != 2 || result.Success != 1 || result.Unregistered != 1 {
+		t.Errorf("result = %+v, want sent=2 success=1 unregistered=1", result)
+	}
+
+	channels, err := LoadChannels(db, 1, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := channels.providers[ProviderMock].(*MockProvider).Sent()
+	if len(sent) != 1 || sent[0].Token != "active" {
+		t.Errorf("sent = %+v, want only the active prod device", sent)
+	}
+
+	// 失效标记限定在推送所属环境和通道
+	calls := fake.Calls("UPDATE `push_devices`")
+	if len(calls) != 1 {
+		t.Fatalf("invalidate calls = %d, want 1", len(calls))
+	}
+	if q := calls[0].Query; !strings.Contains(q, "env = ?") || !strings.Contains(q, "provider = ?") {
+		t.Errorf("invalidate query not scoped by env and provider: %s", q)
+	}
+	if got := fmt.Sprint(calls[0].Args); !strings.Contains(got, "prod") || !strings.Contains(got, ProviderMock) {
+		t.Errorf("invalidate args = %s, want env prod and provider mock", got)
+	}
+}
@@ -0,0 +1,167 @@
+package pusher
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 设备平台
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformHarmony = "harmony"
+	PlatformWeb     = "web"
+)
+
+// ErrInvalidDevice 设备登记参数无效
+var ErrInvalidDevice = errors.New("设备参数无效")
+
+var (
+	platforms   = map[string]bool{PlatformIOS: true, PlatformAndroid: true, PlatformHarmony: true, PlatformWeb: true}
+	localeRegex = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+// DeviceInfo 终端登记的设备信息
+type DeviceInfo struct {
+	Platform      string `json:"platform"`
+	Provider      string `json:"provider"`
+	Token         string `json:"token"`
+	PreviousToken string `json:"previous_token"` // 令牌刷新时的旧令牌，登记新令牌的同时移除旧令牌
+	AppVersion    string `json:"app_version"`
+	Locale        string `json:"locale"`   // 例如 zh-CN
+	Timezone      string `json:"timezone"` // IANA 时区，例如 Asia/Shanghai
+}
+
+// Normalize 校验并规范化设备信息，平台和通道统一为小写，语言中的下划线替换为连字符
+func (d *DeviceInfo) Normalize() error {
+	d.Platform = strings.ToLower(strings.TrimSpace(d.Platform))
+	d.Provider = strings.ToLower(strings.TrimSpace(d.Provider))
+	d.Locale = strings.ReplaceAll(strings.TrimSpace(d.Locale), "_", "-")
+	d.Timezone = strings.TrimSpace(d.Timezone)
+	d.AppVersion = strings.TrimSpace(d.AppVersion)
+
+	if !platforms[d.Platform] {
+		return invalidDevice("平台应为 ios、android、harmony 或 web")
+	}
+	if !Registered(d.Provider) {
+		return invalidDevice("不支持的推送通道，可选: " + strings.Join(Names(), ", "))
+	}
+	if err := validToken(d.Token); err != nil {
+		return err
+	}
+	if d.PreviousToken != "" {
+		if err := validToken(d.PreviousToken); err != nil {
+			return err
+		}
+	}
+	if len(d.AppVersion) > 50 {
+		return invalidDevice("应用版本不能超过50个字符")
+	}
+	if d.Locale != "" && !validLocale(d.Locale) {
+		return invalidDevice("语言格式错误，例如 zh-CN")
+	}
+	if d.Timezone != "" {
+		if _, err := time.LoadLocation(d.Timezone); err != nil || d.Timezone == "Local" || len(d.Timezone) > 64 {
+			return invalidDevice("时区格式错误，例如 Asia/Shanghai")
+		}
+	}
+	return nil
+}
+
+// validLocale 校验 BCP 47 风格的语言标签，例如 zh、zh-CN、zh-Hant-TW
+func validLocale(locale string) bool {
+	return len(locale) <= 20 && localeRegex.MatchString(locale)
+}
+
+func validToken(token string) error {
+	if token == "" || len(token) > 255 || strings.ContainsAny(token, " \t\r\n") {
+		return invalidDevice("设备令牌应为1-255个字符且不含空白")
+	}
+	return nil
+}
+
+func invalidDevice(msg string) error {
+	return &validationError{msg: msg, kind: ErrInvalidDevice}
+}
+
+// validationError 校验失败的具体原因，可用 errors.Is 判断所属的错误类别
+type validationError struct {
+	msg  string
+	kind error
+}
+
+func (e *validationError) Error() string { return e.msg }
+
+func (e *validationError) Unwrap() error { return e.kind }
+
+// RegisterDevice 登记或刷新用户在环境 env 中的设备令牌，调用前需先 Normalize
+// 同一通道的令牌在应用的每个环境内只有一条记录：应用重装后其他用户登记同一令牌时归属转移到该用户，
+// 已失效的令牌重新登记后恢复投递
+func RegisterDevice(db *gorm.DB, appID uint, env string, userID uint, info DeviceInfo) (*model.PushDevice, error) {
+	device := model.PushDevice{
+		AppID:      appID,
+		Env:        env,
+		UserID:     userID,
+		Platform:   info.Platform,
+		Provider:   info.Provider,
+		Token:      info.Token,
+		AppVersion: info.AppVersion,
+		Locale:     info.Locale,
+		Timezone:   info.Timezone,
+		LastSeenAt: time.Now(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if info.PreviousToken != "" && info.PreviousToken != info.Token {
+			if err := tx.Where("app_id = ? AND env = ? AND user_id = ? AND provider = ? AND token = ?",
+				appID, env, userID, info.Provider, info.PreviousToken).Delete(&model.PushDevice{}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "app_id"}, {Name: "env"}, {Name: "provider"}, {Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"user_id", "platform", "app_version", "locale", "timezone", "last_seen_at", "invalidated_at", "updated_at",
+			}),
+		}).Create(&device).Error; err != nil {
+			return err
+		}
+		// 冲突更新时自增 ID 不可靠，重新读取
+		return tx.Where("app_id = ? AND env = ? AND provider = ? AND token = ?", appID, env, info.Provider, info.Token).First(&device).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+// UnregisterDevice 注销用户在环境 env 中的设备令牌，令牌不属于该用户时返回 false
+func UnregisterDevice(db *gorm.DB, appID uint, env string, userID uint, provider, token string) (bool, error) {
+	res := db.Where("app_id = ? AND env = ? AND user_id = ? AND provider = ? AND token = ?",
+		appID, env, userID, strings.ToLower(provider), token).Delete(&model.PushDevice{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Invalidate 标记通道报告已失效的设备令牌，失效的设备不再投递
+// 只标记环境 env 中对应通道的设备：通道凭证属于该环境，对其他环境令牌的失效判断不可信
+func Invalidate(db *gorm.DB, appID uint, env string, devices []model.PushDevice) error {
+	byProvider := map[string][]uint{}
+	for _, d := range devices {
+		byProvider[d.Provider] = append(byProvider[d.Provider], d.ID)
+	}
+	now := time.Now()
+	for provider, ids := range byProvider {
+		if err := db.Model(&model.PushDevice{}).
+			Where("app_id = ? AND env = ? AND provider = ? AND id IN ? AND invalidated_at IS NULL", appID, env, provider, ids).
+			Update("invalidated_at", now).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
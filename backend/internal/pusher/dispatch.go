@@ -0,0 +1,142 @@
+package pusher
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/cron"
+	"app-platform-backend/internal/quota"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeliveryLease 发送租约时长，投递期间每隔 leaseRenewInterval 续期一次，每批完成时也会续期；
+// 实例异常退出后，其他实例在租约过期后从断点接手
+const DeliveryLease = 5 * time.Minute
+
+// leaseRenewInterval 批次投递期间续期租约的间隔，远小于租约时长，续期偶尔失败也不会让租约过期
+var leaseRenewInterval = DeliveryLease / 5
+
+// ErrCancelled 推送在发送途中被取消
+var ErrCancelled = errors.New("推送已取消")
+
+// ParseCron 解析周期推送的 cron 表达式，按服务器本地时区计算发送时间
+func ParseCron(expr string) (*cron.Schedule, error) {
+	return cron.Parse(expr)
+}
+
+// Claim 认领一条待发送或定时推送立即发送，条件更新保证与调度器和取消操作互斥
+// 周期推送本身不发送，返回 false
+func Claim(db *gorm.DB, record *model.PushRecord) (bool, error) {
+	lease := time.Now().Add(DeliveryLease)
+	res := db.Model(&model.PushRecord{}).
+		Where("id = ? AND status IN ? AND cron = ?", record.ID, []string{model.PushPending, model.PushScheduled}, "").
+		Updates(map[string]interface{}{"status": model.PushSending, "lease_until": lease})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return false, nil
+	}
+	record.Status, record.LeaseUntil = model.PushSending, &lease
+	return true, nil
+}
+
+// ClaimDue 以行锁认领到期的定时推送和租约过期的发送中推送，多实例部署时各实例认领不同的记录
+// 周期推送到期时生成一条子推送认领发送，并将周期推送的发送时间推进到下一次
+func ClaimDue(db *gorm.DB, now time.Time, limit int) ([]model.PushRecord, error) {
+	var claimed []model.PushRecord
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var due []model.PushRecord
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND scheduled_at <= ?) OR (status = ? AND (lease_until IS NULL OR lease_until < ?))",
+				model.PushScheduled, now, model.PushSending, now).
+			Order("id").Limit(limit).Find(&due).Error; err != nil {
+			return err
+		}
+
+		lease := now.Add(DeliveryLease)
+		for i := range due {
+			record := &due[i]
+			if record.Status == model.PushScheduled && record.Cron != "" {
+				child, err := spawn(tx, record, now, lease)
+				if err != nil {
+					return err
+				}
+				if child != nil {
+					claimed = append(claimed, *child)
+				}
+				continue
+			}
+
+			if err := tx.Model(&model.PushRecord{}).Where("id = ?", record.ID).
+				Updates(map[string]interface{}{"status": model.PushSending, "lease_until": lease}).Error; err != nil {
+				return err
+			}
+			if record.Status == model.PushSending {
+				log.Printf("[Pusher] Resuming push %d of app %d from device %d", record.ID, record.AppID, record.Cursor)
+			}
+			record.Status, record.LeaseUntil = model.PushSending, &lease
+			claimed = append(claimed, *record)
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// spawn 为到期的周期推送生成本次发送的子推送，并推进下次发送时间；没有下次时间时周期推送结束
+// 只有子推送计入每日推送配额，用量在认领事务中累计，事务回滚时一并撤销；超出配额时子推送直接记为失败，不认领发送
+func spawn(tx *gorm.DB, parent *model.PushRecord, now time.Time, lease time.Time) (*model.PushRecord, error) {
+	parentUpdates := map[string]interface{}{}
+	schedule, cronErr := ParseCron(parent.Cron)
+	if cronErr == nil {
+		if next := schedule.Next(now); !next.IsZero() {
+			parentUpdates["scheduled_at"] = next
+		} else {
+			parentUpdates["status"] = model.PushSent
+		}
+	} else {
+		parentUpdates["status"] = model.PushFailed
+		parentUpdates["error"] = truncate(cronErr.Error(), maxErrorLength)
+	}
+	if err := tx.Model(&model.PushRecord{}).Where("id = ?", parent.ID).Updates(parentUpdates).Error; err != nil {
+		return nil, err
+	}
+	if cronErr != nil {
+		log.Printf("[Pusher] Recurring push %d of app %d has invalid cron %q: %v", parent.ID, parent.AppID, parent.Cron, cronErr)
+		return nil, nil
+	}
+
+	child := &model.PushRecord{
+		AppID:       parent.AppID,
+		Env:         parent.Env,
+		Title:       parent.Title,
+		Content:     parent.Content,
+		TargetType:  parent.TargetType,
+		TargetIDs:   parent.TargetIDs,
+		TemplateID:  parent.TemplateID,
+		Variables:   parent.Variables,
+		ParentID:    &parent.ID,
+		ScheduledAt: parent.ScheduledAt,
+		Status:      model.PushSending,
+		LeaseUntil:  &lease,
+	}
+	if qErr := quota.ConsumeIn(tx, parent.AppID, quota.MetricPushesPerDay, 1); qErr != nil {
+		var exceeded *quota.ExceededError
+		if !errors.As(qErr, &exceeded) {
+			return nil, qErr
+		}
+		child.Status, child.LeaseUntil = model.PushFailed, nil
+		child.Error = truncate(exceeded.Error(), maxErrorLength)
+	}
+	if err := tx.Create(child).Error; err != nil {
+		return nil, err
+	}
+	if child.Status != model.PushSending {
+		return nil, nil
+	}
+	return child, nil
+}
@@ -0,0 +1,167 @@
+package pusher
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/sqltest"
+)
+
+var setColumnRegex = regexp.MustCompile("`(\\w+)`=\\?")
+
+// recordStore 认领测试的推送记录，按认领查询的条件筛选，按更新语句写回状态和租约
+type recordStore struct {
+	mu      sync.Mutex
+	records map[uint]*model.PushRecord
+}
+
+func (s *recordStore) handle(query string, args []driver.Value) sqltest.Reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT") && strings.Contains(query, "FROM `push_records`"):
+		return s.selectDue(query, args)
+	case strings.HasPrefix(query, "UPDATE `push_records`"):
+		return s.update(query, args)
+	}
+	return sqltest.Reply{}
+}
+
+// selectDue 按 ClaimDue 的条件 (status = scheduled AND scheduled_at <= now) OR
+// (status = sending AND (lease_until IS NULL OR lease_until < now)) 筛选
+func (s *recordStore) selectDue(query string, args []driver.Value) sqltest.Reply {
+	if !strings.Contains(query, "lease_until IS NULL OR lease_until < ?") || !strings.Contains(query, "SKIP LOCKED") {
+		return sqltest.Reply{}
+	}
+	scheduled, scheduledNow, sending, leaseNow := args[0], args[1].(time.Time), args[2], args[3].(time.Time)
+	reply := sqltest.Reply{Columns: []string{"id", "app_id", "status", "scheduled_at", "lease_until"}}
+	for id := uint(1); id <= uint(len(s.records)); id++ {
+		r := s.records[id]
+		due := r.Status == scheduled && r.ScheduledAt != nil && !r.ScheduledAt.After(scheduledNow)
+		expired := r.Status == sending && (r.LeaseUntil == nil || r.LeaseUntil.Before(leaseNow))
+		if due || expired {
+			reply.Rows = append(reply.Rows, []driver.Value{int64(r.ID), int64(r.AppID), r.Status, timeValue(r.ScheduledAt), timeValue(r.LeaseUntil)})
+		}
+	}
+	return reply
+}
+
+func (s *recordStore) update(query string, args []driver.Value) sqltest.Reply {
+	set, where, _ := strings.Cut(query, " WHERE ")
+	columns := setColumnRegex.FindAllStringSubmatch(set, -1)
+	values, conds := args[:len(columns)], args[len(columns):]
+	r := s.records[uint(conds[0].(uint))]
+	if r == nil || (strings.Contains(where, "status = ?") && r.Status != conds[1]) {
+		return sqltest.Reply{}
+	}
+	for i, c := range columns {
+		switch c[1] {
+		case "status":
+			r.Status = values[i].(string)
+		case "lease_until":
+			if t, ok := values[i].(time.Time); ok {
+				r.LeaseUntil = &t
+			} else {
+				r.LeaseUntil = nil
+			}
+		}
+	}
+	return sqltest.Reply{Affected: 1}
+}
+
+func timeValue(t *time.Time) driver.Value {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+func TestClaimDue_SkipsLiveLease(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+	store := &recordStore{records: map[uint]*model.PushRecord{
+		1: {ID: 1, AppID: 1, Status: model.PushSending, LeaseUntil: &future},
+		2: {ID: 2, AppID: 1, Status: model.PushSending, LeaseUntil: &past},
+		3: {ID: 3, AppID: 1, Status: model.PushSending},
+		4: {ID: 4, AppID: 1, Status: model.PushScheduled, ScheduledAt: &past},
+		5: {ID: 5, AppID: 1, Status: model.PushScheduled, ScheduledAt: &future},
+	}}
+	db, _ := sqltest.Open(t, store.handle)
+
+	claimed, err := ClaimDue(db, now, 10)
+	if err != nil {
+		t.Fatalf("ClaimDue: %v", err)
+	}
+	var ids []uint
+	for _, r := range claimed {
+		ids = append(ids, r.ID)
+	}
+	if fmt.Sprint(ids) != "[2 3 4]" {
+		t.Errorf("claimed = %v, want [2 3 4]", ids)
+	}
+	if l := store.records[1].LeaseUntil; l == nil || !l.Equal(future) {
+		t.Errorf("live lease changed to %v", l)
+	}
+}
+
+func TestSendBatch_RenewsLeaseDuringSlowBatch(t *testing.T) {
+	defer func(d time.Duration) { leaseRenewInterval = d }(leaseRenewInterval)
+	leaseRenewInterval = 5 * time.Millisecond
+
+	// 认领时的租约即将过期，批次耗时超过原租约
+	expiring := time.Now().Add(10 * time.Millisecond)
+	store := &recordStore{records: map[uint]*model.PushRecord{
+		1: {ID: 1, AppID: 1, Status: model.PushSending, LeaseUntil: &expiring},
+	}}
+	db, fake := sqltest.Open(t, store.handle)
+
+	var reclaimed []model.PushRecord
+	_, err := sendBatch(context.Background(), db, store.records[1], func(ctx context.Context) *Result {
+		time.Sleep(40 * time.Millisecond)
+		// 另一实例在批次进行中尝试接手
+		var err error
+		if reclaimed, err = ClaimDue(db, time.Now(), 10); err != nil {
+			t.Errorf("ClaimDue: %v", err)
+		}
+		return &Result{}
+	})
+	if err != nil {
+		t.Fatalf("sendBatch: %v", err)
+	}
+	if len(reclaimed) != 0 {
+		t.Errorf("record with a renewed lease was reclaimed: %+v", reclaimed)
+	}
+	if n := len(fake.Calls("SET `lease_until`=?")); n < 2 {
+		t.Errorf("lease renewed %d times, want at least 2", n)
+	}
+}
+
+func TestSendBatch_StopsWhenCancelled(t *testing.T) {
+	defer func(d time.Duration) { leaseRenewInterval = d }(leaseRenewInterval)
+	leaseRenewInterval = 5 * time.Millisecond
+
+	store := &recordStore{records: map[uint]*model.PushRecord{
+		1: {ID: 1, AppID: 1, Status: model.PushCancelled},
+	}}
+	db, _ := sqltest.Open(t, store.handle)
+
+	_, err := sendBatch(context.Background(), db, store.records[1], func(ctx context.Context) *Result {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Error("batch was not interrupted after the push was cancelled")
+		}
+		return &Result{}
+	})
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("err = %v, want ErrCancelled", err)
+	}
+}
@@ -0,0 +1,124 @@
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	huaweiTokenURL = "https://oauth-login.cloud.huawei.com/oauth2/v3/token"
+	huaweiEndpoint = "https://push-api.cloud.huawei.com"
+
+	huaweiSuccess      = "80000000"
+	huaweiTokenInvalid = "80300007" // 全部令牌无效
+	huaweiAuthExpired  = "80200003" // 访问令牌过期
+	huaweiAuthFailed   = "80200001" // 访问令牌认证失败
+	huaweiClickOpenApp = 3
+)
+
+func init() { Register(ProviderHuawei, newHuawei) }
+
+// huaweiCredentials 华为推送凭证，即 AppGallery Connect 中应用的 App ID 和 App Secret
+type huaweiCredentials struct {
+	AppID        string `json:"appId"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// Huawei 华为推送服务（HMS Push Kit）
+type Huawei struct {
+	appID    string
+	secret   string
+	tokenURL string
+	endpoint string
+	client   *http.Client
+	token    cachedToken
+}
+
+func newHuawei(raw json.RawMessage) (Provider, error) {
+	var cred huaweiCredentials
+	if err := decodeCredentials(raw, &cred); err != nil {
+		return nil, err
+	}
+	if err := requireFields(map[string]string{"appId": cred.AppID, "clientSecret": cred.ClientSecret}); err != nil {
+		return nil, err
+	}
+	return &Huawei{
+		appID:    cred.AppID,
+		secret:   cred.ClientSecret,
+		tokenURL: huaweiTokenURL,
+		endpoint: huaweiEndpoint,
+		client:   newHTTPClient(),
+	}, nil
+}
+
+// Send 投递到单个设备，自定义数据以JSON字符串透传
+func (p *Huawei) Send(ctx context.Context, token string, n *Notification) error {
+	accessToken, err := p.token.get(func() (string, time.Duration, error) { return p.fetchToken(ctx) })
+	if err != nil {
+		return fmt.Errorf("huawei: access token: %w", err)
+	}
+
+	android := map[string]interface{}{
+		"notification": map[string]interface{}{
+			"title":        n.Title,
+			"body":         n.Body,
+			"click_action": map[string]int{"type": huaweiClickOpenApp},
+		},
+	}
+	if n.TTL > 0 {
+		android["ttl"] = strconv.FormatInt(int64(n.TTL/time.Second), 10) + "s"
+	}
+	message := map[string]interface{}{
+		"android": android,
+		"token":   []string{token},
+	}
+	if len(n.Data) > 0 {
+		data, _ := json.Marshal(n.Data)
+		message["data"] = string(data)
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	header := http.Header{"Authorization": {"Bearer " + accessToken}}
+	if err := postJSON(ctx, p.client, p.endpoint+"/v1/"+p.appID+"/messages:send",
+		map[string]interface{}{"message": message}, header, &result); err != nil {
+		return fmt.Errorf("huawei: %w", err)
+	}
+
+	switch result.Code {
+	case huaweiSuccess:
+		return nil
+	case huaweiTokenInvalid:
+		return fmt.Errorf("huawei: %s %s: %w", result.Code, result.Msg, ErrUnregistered)
+	case huaweiAuthExpired, huaweiAuthFailed:
+		p.token.reset()
+	}
+	return fmt.Errorf("huawei: %s %s", result.Code, result.Msg)
+}
+
+// fetchToken 以客户端凭证模式获取访问令牌
+func (p *Huawei) fetchToken(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.appID},
+		"client_secret": {p.secret},
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := postForm(ctx, p.client, p.tokenURL, form, nil, &result); err != nil {
+		return "", 0, err
+	}
+	if result.AccessToken == "" {
+		return "", 0, fmt.Errorf("empty access token")
+	}
+	return result.AccessToken, time.Duration(result.ExpiresIn) * time.Second, nil
+}
@@ -0,0 +1,109 @@
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// mockHistoryLimit 模拟通道保留的最近投递条数，超出后丢弃最早的记录
+const mockHistoryLimit = 1000
+
+// RegisterMock 注册本地模拟通道。模拟通道不向设备投递，只应在测试或本地联调时
+// 通过 push.enable_mock_provider 显式启用，生产环境不注册，应用无法配置该通道
+func RegisterMock() { Register(ProviderMock, newMock) }
+
+// mockCredentials 本地模拟通道的配置，列出的令牌按已失效处理，用于联调失效令牌的处理
+type mockCredentials struct {
+	Unregistered []string `json:"unregistered"`
+}
+
+// MockMessage 模拟通道记录的一次投递
+type MockMessage struct {
+	Token        string
+	Notification Notification
+	SentAt       time.Time
+}
+
+// MockProvider 本地模拟通道，不调用外部服务，只记录投递内容，用于测试和本地联调
+type MockProvider struct {
+	mu           sync.Mutex
+	sent         []MockMessage
+	unregistered map[string]bool
+	failures     map[string]error
+}
+
+// NewMockProvider 创建模拟通道，unregistered 中的令牌投递时返回 ErrUnregistered
+func NewMockProvider(unregistered ...string) *MockProvider {
+	p := &MockProvider{unregistered: map[string]bool{}, failures: map[string]error{}}
+	for _, token := range unregistered {
+		p.unregistered[token] = true
+	}
+	return p
+}
+
+func newMock(raw json.RawMessage) (Provider, error) {
+	var cred mockCredentials
+	if len(raw) > 0 {
+		if err := decodeCredentials(raw, &cred); err != nil {
+			return nil, err
+		}
+	}
+	return NewMockProvider(cred.Unregistered...), nil
+}
+
+// FailWith 之后向该令牌投递时返回指定错误
+func (p *MockProvider) FailWith(token string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures[token] = err
+}
+
+// Send 记录投递内容
+func (p *MockProvider) Send(ctx context.Context, token string, n *Notification) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unregistered[token] {
+		return fmt.Errorf("mock: %w", ErrUnregistered)
+	}
+	if err := p.failures[token]; err != nil {
+		return err
+	}
+	msg := MockMessage{Token: token, Notification: *n, SentAt: time.Now()}
+	msg.Notification.Data = copyData(n.Data)
+	if len(p.sent) >= mockHistoryLimit {
+		p.sent = append(p.sent[:0], p.sent[len(p.sent)-mockHistoryLimit+1:]...)
+	}
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+// Sent 已记录的投递，最多保留最近 mockHistoryLimit 条
+func (p *MockProvider) Sent() []MockMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]MockMessage(nil), p.sent...)
+}
+
+// Reset 清空已记录的投递
+func (p *MockProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = nil
+}
+
+func copyData(data map[string]string) map[string]string {
+	if data == nil {
+		return nil
+	}
+	out := make(map[string]string, len(data))
+	for k, v := range data {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package pusher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// 模拟通道只在测试中注册，投递测试通过应用配置使用它
+func init() { RegisterMock() }
+
+func TestMockProvider_KeepsRecentHistory(t *testing.T) {
+	p := NewMockProvider()
+	for i := 0; i < mockHistoryLimit+10; i++ {
+		if err := p.Send(context.Background(), fmt.Sprint(i), &Notification{Title: "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent := p.Sent()
+	if len(sent) != mockHistoryLimit {
+		t.Fatalf("history = %d, want %d", len(sent), mockHistoryLimit)
+	}
+	if sent[0].Token != "10" || sent[len(sent)-1].Token != fmt.Sprint(mockHistoryLimit+9) {
+		t.Errorf("history spans %s..%s, want the most recent sends", sent[0].Token, sent[len(sent)-1].Token)
+	}
+}
@@ -0,0 +1,130 @@
+package pusher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	oppoEndpoint = "https://api.push.oppomobile.com"
+	// oppoTokenTTL 鉴权令牌有效期为24小时
+	oppoTokenTTL = 24 * time.Hour
+
+	oppoInvalidAuthToken = 11
+	oppoTargetRegID      = 2
+)
+
+func init() { Register(ProviderOPPO, newOPPO) }
+
+// oppoCredentials OPPO 推送凭证
+type oppoCredentials struct {
+	AppKey       string `json:"appKey"`
+	MasterSecret string `json:"masterSecret"`
+}
+
+// OPPO OPPO 推送，按 registration_id 单推通知栏消息
+type OPPO struct {
+	appKey       string
+	masterSecret string
+	endpoint     string
+	client       *http.Client
+	token        cachedToken
+}
+
+func newOPPO(raw json.RawMessage) (Provider, error) {
+	var cred oppoCredentials
+	if err := decodeCredentials(raw, &cred); err != nil {
+		return nil, err
+	}
+	if err := requireFields(map[string]string{"appKey": cred.AppKey, "masterSecret": cred.MasterSecret}); err != nil {
+		return nil, err
+	}
+	return &OPPO{
+		appKey:       cred.AppKey,
+		masterSecret: cred.MasterSecret,
+		endpoint:     oppoEndpoint,
+		client:       newHTTPClient(),
+	}, nil
+}
+
+// oppoResult 接口的通用响应
+type oppoResult struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Send 投递到单个设备
+func (p *OPPO) Send(ctx context.Context, token string, n *Notification) error {
+	authToken, err := p.token.get(func() (string, time.Duration, error) { return p.fetchToken(ctx) })
+	if err != nil {
+		return fmt.Errorf("oppo: auth token: %w", err)
+	}
+
+	notification := map[string]interface{}{
+		"title":   n.Title,
+		"content": n.Body,
+	}
+	if len(n.Data) > 0 {
+		data, _ := json.Marshal(n.Data)
+		notification["action_parameters"] = string(data)
+	}
+	if n.TTL > 0 {
+		notification["off_line_ttl"] = int64(n.TTL / time.Second)
+	}
+	message, err := json.Marshal(map[string]interface{}{
+		"target_type":  oppoTargetRegID,
+		"target_value": token,
+		"notification": notification,
+	})
+	if err != nil {
+		return err
+	}
+
+	var result oppoResult
+	header := http.Header{"Auth_token": {authToken}}
+	if err := postForm(ctx, p.client, p.endpoint+"/server/v1/message/notification/unicast",
+		url.Values{"message": {string(message)}}, header, &result); err != nil {
+		return fmt.Errorf("oppo: %w", err)
+	}
+	if result.Code == oppoInvalidAuthToken {
+		p.token.reset()
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("oppo: %d %s", result.Code, result.Message)
+	}
+	return nil
+}
+
+// fetchToken 以 sha256(appKey+timestamp+masterSecret) 签名获取鉴权令牌
+func (p *OPPO) fetchToken(ctx context.Context) (string, time.Duration, error) {
+	timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	sum := sha256.Sum256([]byte(p.appKey + timestamp + p.masterSecret))
+	form := url.Values{
+		"app_key":   {p.appKey},
+		"timestamp": {timestamp},
+		"sign":      {hex.EncodeToString(sum[:])},
+	}
+
+	var result oppoResult
+	if err := postForm(ctx, p.client, p.endpoint+"/server/v1/auth", form, nil, &result); err != nil {
+		return "", 0, err
+	}
+	if result.Code != 0 {
+		return "", 0, fmt.Errorf("%d %s", result.Code, result.Message)
+	}
+	var data struct {
+		AuthToken string `json:"auth_token"`
+	}
+	if err := json.Unmarshal(result.Data, &data); err != nil || data.AuthToken == "" {
+		return "", 0, fmt.Errorf("empty auth token")
+	}
+	return data.AuthToken, oppoTokenTTL, nil
+}
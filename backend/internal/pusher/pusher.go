@@ -0,0 +1,220 @@
+// Package pusher 推送通道：按设备所属的推送通道（APNs、FCM、各安卓厂商通道）投递通知。
+// 各应用的通道凭证保存在所属环境的推送服务模块配置中，按配置构建通道实例并缓存，
+// 以便复用各通道的访问令牌
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 推送通道名称，与设备的 provider 字段一致
+const (
+	ProviderAPNs   = "apns"
+	ProviderFCM    = "fcm"
+	ProviderHuawei = "huawei"
+	ProviderXiaomi = "xiaomi"
+	ProviderOPPO   = "oppo"
+	ProviderVivo   = "vivo"
+	ProviderMock   = "mock"
+)
+
+// httpTimeout 调用推送通道接口的超时时间
+const httpTimeout = 10 * time.Second
+
+var (
+	// ErrUnregistered 通道报告设备令牌已失效（应用卸载或令牌过期），不应再向其投递
+	ErrUnregistered = errors.New("设备令牌已失效")
+	// ErrUnknownProvider 未注册的推送通道
+	ErrUnknownProvider = errors.New("推送通道不存在")
+)
+
+// Notification 投递到设备的通知
+type Notification struct {
+	Title string
+	Body  string
+	Data  map[string]string // 随通知下发的自定义数据
+	TTL   time.Duration     // 设备离线时通道保留通知的时长，为0时使用通道默认值
+}
+
+// Provider 推送通道，向单个设备令牌投递通知
+// 令牌失效时返回包装了 ErrUnregistered 的错误
+type Provider interface {
+	Send(ctx context.Context, token string, n *Notification) error
+}
+
+// Factory 按应用的通道凭证创建推送通道
+type Factory func(credentials json.RawMessage) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+)
+
+// Register 注册推送通道，名称重复时覆盖
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New 按名称和凭证创建推送通道
+func New(name string, credentials json.RawMessage) (Provider, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	p, err := factory(credentials)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return p, nil
+}
+
+// Registered 推送通道是否已注册
+func Registered(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := factories[name]
+	return ok
+}
+
+// Names 已注册的推送通道名称，按名称排序
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newHTTPClient 调用推送通道接口的HTTP客户端，HTTP/2 在TLS协商时自动启用
+func newHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ForceAttemptHTTP2 = true
+	return &http.Client{Transport: transport, Timeout: httpTimeout}
+}
+
+// postForm 提交表单并解析JSON响应，非2xx状态返回错误
+func postForm(ctx context.Context, client *http.Client, endpoint string, form url.Values, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doJSON(client, req, out)
+}
+
+// postJSON 提交JSON并解析JSON响应，非2xx状态返回错误
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body interface{}, header http.Header, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doJSON(client, req, out)
+}
+
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d: %s", resp.StatusCode, truncate(string(data), 200))
+	}
+	return json.Unmarshal(data, out)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// cachedToken 通道访问令牌缓存，过期前提前刷新
+type cachedToken struct {
+	mu      sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// get 返回未过期的令牌，否则调用 fetch 获取新令牌及其有效期
+func (t *cachedToken) get(fetch func() (string, time.Duration, error)) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.value != "" && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+	value, ttl, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	// 提前一分钟过期，避免令牌在请求途中失效
+	t.value, t.expires = value, time.Now().Add(ttl-time.Minute)
+	return value, nil
+}
+
+// reset 丢弃缓存的令牌，通道报告令牌无效时调用
+func (t *cachedToken) reset() {
+	t.mu.Lock()
+	t.value = ""
+	t.mu.Unlock()
+}
+
+// decodeCredentials 解析通道凭证
+func decodeCredentials(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return errors.New("缺少通道凭证")
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("通道凭证格式错误: %w", err)
+	}
+	return nil
+}
+
+// requireFields 检查凭证必填项，按名称顺序报告第一个缺失项
+func requireFields(fields map[string]string) error {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if fields[name] == "" {
+			return fmt.Errorf("缺少 %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package pusher
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/model"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestSend(t *testing.T) {
+	mock := NewMockProvider("gone")
+	mock.FailWith("broken", errors.New("boom"))
+	channels := &Channels{
+		Enabled:   true,
+		providers: map[string]Provider{ProviderMock: mock},
+		errs:      map[string]error{ProviderAPNs: errors.New("缺少 teamId")},
+	}
+	devices := []model.PushDevice{
+		{ID: 1, Provider: ProviderMock, Token: "a"},
+		{ID: 2, Provider: ProviderMock, Token: "b"},
+		{ID: 3, Provider: ProviderMock, Token: "gone"},
+		{ID: 4, Provider: ProviderMock, Token: "broken"},
+		{ID: 5, Provider: ProviderAPNs, Token: "c"},
+		{ID: 6, Provider: ProviderFCM, Token: "d"},
+	}
+
+	result := Send(context.Background(), channels, devices, &Notification{Title: "t", Body: "b", Data: map[string]string{"k": "v"}})
+	if result.Sent != 6 || result.Success != 2 || result.Failed != 4 || result.Unregistered != 1 {
+		t.Errorf("result = %+v, want sent=6 success=2 failed=4 unregistered=1", result)
+	}
+	if len(result.unregistered) != 1 || result.unregistered[0].ID != 3 {
+		t.Errorf("unregistered devices = %v, want [3]", result.unregistered)
+	}
+
+	sent := mock.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("mock recorded %d messages, want 2", len(sent))
+	}
+	for _, m := range sent {
+		if m.Notification.Title != "t" || m.Notification.Data["k"] != "v" {
+			t.Errorf("recorded %+v", m)
+		}
+	}
+}
+
+func TestDeviceInfoNormalize(t *testing.T) {
+	info := DeviceInfo{Platform: " iOS", Provider: "APNs", Token: "abc", Locale: "zh_CN", Timezone: "Asia/Shanghai"}
+	if err := info.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if info.Platform != PlatformIOS || info.Provider != ProviderAPNs || info.Locale != "zh-CN" {
+		t.Errorf("normalized = %+v", info)
+	}
+
+	invalid := []DeviceInfo{
+		{Platform: "symbian", Provider: ProviderFCM, Token: "t"},
+		{Platform: PlatformAndroid, Provider: "pigeon", Token: "t"},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: ""},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: "a b"},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: strings.Repeat("x", 256)},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: "t", Locale: "zh-CN;drop"},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: "t", Timezone: "Mars/Base"},
+		{Platform: PlatformAndroid, Provider: ProviderFCM, Token: "t", Timezone: "Local"},
+	}
+	for _, d := range invalid {
+		if err := d.Normalize(); !errors.Is(err, ErrInvalidDevice) {
+			t.Errorf("Normalize(%+v) err = %v, want ErrInvalidDevice", d, err)
+		}
+	}
+}
+
+func TestNewChannels(t *testing.T) {
+	disabled := false
+	ch := NewChannels(&Config{
+		Enabled: &disabled,
+		Providers: map[string]json.RawMessage{
+			ProviderMock:   json.RawMessage(`{"unregistered": ["x"]}`),
+			ProviderXiaomi: json.RawMessage(`{"appSecret": "s"}`),
+			"unknown":      json.RawMessage(`{}`),
+		},
+	})
+	if ch.Enabled {
+		t.Error("channels should be disabled")
+	}
+	if _, err := ch.Get(ProviderMock); err != nil {
+		t.Errorf("mock: %v", err)
+	}
+	if _, err := ch.Get(ProviderXiaomi); err == nil || !strings.Contains(err.Error(), "packageName") {
+		t.Errorf("xiaomi err = %v, want missing packageName", err)
+	}
+	if _, err := ch.Get("unknown"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown err = %v, want ErrUnknownProvider", err)
+	}
+	if _, err := ch.Get(ProviderVivo); err == nil {
+		t.Error("unconfigured provider should fail")
+	}
+
+	if !NewChannels(&Config{}).Enabled {
+		t.Error("channels should be enabled by default")
+	}
+}
+
+func TestAPNs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	p8 := string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("apns-topic") != "com.example.app" {
+			t.Errorf("apns-topic = %q", r.Header.Get("apns-topic"))
+		}
+		bearer := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		token, err := jwt.Parse(bearer, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+		if err != nil || token.Header["kid"] != "KEY123" || token.Claims.(jwt.MapClaims)["iss"] != "TEAM123" {
+			t.Errorf("invalid provider token: %v", err)
+		}
+
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["order_id"] != "42" || payload["aps"] == nil {
+			t.Errorf("payload = %v", payload)
+		}
+
+		switch r.URL.Path {
+		case "/3/device/ok":
+		case "/3/device/gone":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"PayloadTooLarge"}`))
+		}
+	}))
+	defer srv.Close()
+
+	raw, _ := json.Marshal(apnsCredentials{TeamID: "TEAM123", KeyID: "KEY123", PrivateKey: p8, BundleID: "com.example.app"})
+	p, err := New(ProviderAPNs, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apns := p.(*APNs)
+	apns.endpoint, apns.client = srv.URL, srv.Client()
+
+	n := &Notification{Title: "t", Body: "b", Data: map[string]string{"order_id": "42"}}
+	if err := apns.Send(context.Background(), "ok", n); err != nil {
+		t.Errorf("ok: %v", err)
+	}
+	if err := apns.Send(context.Background(), "gone", n); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("gone: err = %v, want ErrUnregistered", err)
+	}
+	if err := apns.Send(context.Background(), "big", n); err == nil || errors.Is(err, ErrUnregistered) {
+		t.Errorf("big: err = %v, want plain error", err)
+	}
+}
+
+func TestFCM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+
+	tokenRequests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		if _, err := jwt.Parse(r.FormValue("assertion"), func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil }); err != nil {
+			t.Errorf("invalid assertion: %v", err)
+		}
+		w.Write([]byte(`{"access_token":"at","expires_in":3600}`))
+	})
+	mux.HandleFunc("/v1/projects/demo/messages:send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Message struct {
+				Token string `json:"token"`
+			} `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Message.Token == "gone" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`))
+			return
+		}
+		w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	sa, _ := json.Marshal(map[string]string{
+		"project_id": "demo", "client_email": "push@demo.iam", "private_key": keyPEM,
+		"token_uri": "http://169.254.169.254/token",
+	})
+	raw, _ := json.Marshal(fcmCredentials{ServiceAccount: string(sa)})
+	p, err := New(ProviderFCM, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fcm := p.(*FCM)
+	if fcm.tokenURL != fcmTokenURL {
+		t.Fatalf("tokenURL = %q, want %q regardless of token_uri", fcm.tokenURL, fcmTokenURL)
+	}
+	fcm.endpoint, fcm.tokenURL, fcm.client = srv.URL, srv.URL+"/token", srv.Client()
+
+	n := &Notification{Title: "t", Body: "b"}
+	if err := fcm.Send(context.Background(), "ok", n); err != nil {
+		t.Errorf("ok: %v", err)
+	}
+	if err := fcm.Send(context.Background(), "gone", n); !errors.Is(err, ErrUnregistered) {
+		t.Errorf("gone: err = %v, want ErrUnregistered", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("token requested %d times, want 1 (cached)", tokenRequests)
+	}
+}
@@ -0,0 +1,209 @@
+package pusher
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/secretbox"
+
+	"gorm.io/gorm"
+)
+
+// RedactedValue 读取配置时密钥字段的占位值，保存时原样提交或省略该字段表示保留已保存的密钥
+const RedactedValue = "******"
+
+// secretFields 各通道凭证中加密保存、读取时隐藏的字段
+var secretFields = map[string][]string{
+	ProviderAPNs:   {"privateKey"},
+	ProviderFCM:    {"serviceAccount"},
+	ProviderHuawei: {"clientSecret"},
+	ProviderXiaomi: {"appSecret"},
+	ProviderOPPO:   {"masterSecret"},
+	ProviderVivo:   {"appSecret"},
+}
+
+var (
+	credentialBox *secretbox.Box
+
+	errNoCredentialBox = errors.New("推送凭证加密未初始化")
+)
+
+// InitCredentials 设置通道凭证的加密器，与应用密钥使用同一加密密钥
+func InitCredentials(box *secretbox.Box) {
+	credentialBox = box
+}
+
+// SealConfig 加密推送服务配置中的通道密钥后返回待保存的配置
+// 密钥字段省略或为 RedactedValue 时沿用 previous（已保存的配置）中的值，因此读取到的脱敏配置可以原样提交
+func SealConfig(raw, previous string) (string, error) {
+	sealed, _, err := sealConfig(raw, previous)
+	return sealed, err
+}
+
+// OpenConfig 解密推送服务配置中的通道密钥，加密前保存的明文原样保留
+func OpenConfig(raw string) (string, error) {
+	cfg, err := parseConfig(raw)
+	if err != nil {
+		return "", err
+	}
+	for name, provider := range providerObjects(cfg) {
+		for _, field := range secretFields[name] {
+			s, ok := provider[field].(string)
+			if !ok || !secretbox.IsCiphertext(s) {
+				continue
+			}
+			if credentialBox == nil {
+				return "", errNoCredentialBox
+			}
+			plain, err := credentialBox.Decrypt(s)
+			if err != nil {
+				return "", err
+			}
+			provider[field] = plain
+		}
+	}
+	data, err := json.Marshal(cfg)
+	return string(data), err
+}
+
+// RedactConfig 将推送服务配置中的通道密钥替换为 RedactedValue，用于接口返回配置和历史
+func RedactConfig(raw string) string {
+	cfg, err := parseConfig(raw)
+	if err != nil {
+		return "{}"
+	}
+	for name, provider := range providerObjects(cfg) {
+		for _, field := range secretFields[name] {
+			if s, ok := provider[field].(string); ok && s != "" {
+				provider[field] = RedactedValue
+			}
+		}
+	}
+	data, _ := json.Marshal(cfg)
+	return string(data)
+}
+
+// IsSecretPath 判断 configdiff 展开的路径（providers.<通道>.<字段>）是否为通道密钥
+func IsSecretPath(path string) bool {
+	parts := strings.Split(path, ".")
+	if len(parts) != 3 || parts[0] != "providers" {
+		return false
+	}
+	for _, field := range secretFields[parts[1]] {
+		if field == parts[2] {
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateLegacyCredentials 将加密前保存的明文通道密钥（当前配置和配置历史）改为加密存储
+func MigrateLegacyCredentials(db *gorm.DB) error {
+	var configs []model.AppModuleConfig
+	if err := db.Where("module_code = ?", ModuleCode).Find(&configs).Error; err != nil {
+		return err
+	}
+	for _, cfg := range configs {
+		sealed, changed, err := sealConfig(cfg.Config, "")
+		if err != nil {
+			log.Printf("[Pusher] Skip migrating credentials of module config %d: %v", cfg.ID, err)
+			continue
+		}
+		if changed {
+			if err := db.Model(&cfg).Update("config", sealed).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var histories []model.ModuleConfigHistory
+	if err := db.Where("module_code = ?", ModuleCode).Find(&histories).Error; err != nil {
+		return err
+	}
+	for _, h := range histories {
+		sealed, changed, err := sealConfig(h.Config, "")
+		if err != nil {
+			log.Printf("[Pusher] Skip migrating credentials of config history %d: %v", h.ID, err)
+			continue
+		}
+		if changed {
+			if err := db.Model(&h).Update("config", sealed).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sealConfig 加密配置中尚未加密的通道密钥，changed 表示配置有变化
+func sealConfig(raw, previous string) (string, bool, error) {
+	cfg, err := parseConfig(raw)
+	if err != nil {
+		return "", false, err
+	}
+	prev, _ := parseConfig(previous)
+	prevProviders := providerObjects(prev)
+
+	changed := false
+	for name, provider := range providerObjects(cfg) {
+		for _, field := range secretFields[name] {
+			v, present := provider[field]
+			if !present || v == RedactedValue {
+				old, ok := prevProviders[name][field]
+				if !ok {
+					delete(provider, field)
+					changed = changed || present
+					continue
+				}
+				v = old
+				changed = true
+			}
+			s, ok := v.(string)
+			if !ok || s == "" || secretbox.IsCiphertext(s) {
+				provider[field] = v
+				continue
+			}
+			if credentialBox == nil {
+				return "", false, errNoCredentialBox
+			}
+			encrypted, err := credentialBox.Encrypt(s)
+			if err != nil {
+				return "", false, err
+			}
+			provider[field] = encrypted
+			changed = true
+		}
+	}
+	if !changed {
+		return raw, false, nil
+	}
+	data, err := json.Marshal(cfg)
+	return string(data), true, err
+}
+
+// parseConfig 解析配置JSON，空文本视为空对象
+func parseConfig(raw string) (map[string]interface{}, error) {
+	cfg := map[string]interface{}{}
+	if raw == "" {
+		return cfg, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// providerObjects 配置中各通道的凭证对象，修改返回的对象即修改原配置
+func providerObjects(cfg map[string]interface{}) map[string]map[string]interface{} {
+	out := map[string]map[string]interface{}{}
+	providers, _ := cfg["providers"].(map[string]interface{})
+	for name, v := range providers {
+		if obj, ok := v.(map[string]interface{}); ok {
+			out[name] = obj
+		}
+	}
+	return out
+}
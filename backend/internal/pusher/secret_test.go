@@ -0,0 +1,67 @@
+package pusher
+
+import (
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/pkg/secretbox"
+)
+
+func TestSealConfig_EncryptsSecretsAndKeepsRedacted(t *testing.T) {
+	box, err := secretbox.New("push-credentials-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	InitCredentials(box)
+	defer InitCredentials(nil)
+
+	sealed, err := SealConfig(`{"enabled":true,"providers":{"xiaomi":{"appSecret":"s3cret","packageName":"com.demo"}}}`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "s3cret") || !strings.Contains(sealed, "com.demo") {
+		t.Fatalf("sealed = %s, want secret encrypted and other fields kept", sealed)
+	}
+
+	redacted := RedactConfig(sealed)
+	if !strings.Contains(redacted, `"appSecret":"`+RedactedValue+`"`) || strings.Contains(redacted, "v1:") {
+		t.Errorf("redacted = %s, want only the placeholder", redacted)
+	}
+
+	// 原样提交脱敏配置或省略密钥字段时沿用已保存的密钥
+	for _, update := range []string{redacted, `{"enabled":true,"providers":{"xiaomi":{"packageName":"com.demo"}}}`} {
+		resealed, err := SealConfig(update, sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opened, err := OpenConfig(resealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(opened, `"appSecret":"s3cret"`) {
+			t.Errorf("update %s: opened = %s, want previous secret kept", update, opened)
+		}
+	}
+
+	// 明文保存的旧配置可以直接读取
+	if opened, err := OpenConfig(`{"providers":{"xiaomi":{"appSecret":"legacy"}}}`); err != nil || !strings.Contains(opened, "legacy") {
+		t.Errorf("legacy: opened = %s, err = %v", opened, err)
+	}
+}
+
+func TestIsSecretPath(t *testing.T) {
+	for path, want := range map[string]bool{
+		"providers.apns.privateKey":    true,
+		"providers.fcm.serviceAccount": true,
+		"providers.oppo.masterSecret":  true,
+		"providers.apns.teamId":        false,
+		"providers.xiaomi.packageName": false,
+		"enabled":                      false,
+		"providers.unknown.privateKey": false,
+		"other.apns.privateKey":        false,
+	} {
+		if got := IsSecretPath(path); got != want {
+			t.Errorf("IsSecretPath(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,484 @@
+package pusher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/profile"
+
+	"gorm.io/gorm"
+)
+
+// 模板变量类型
+const (
+	VarString  = "string"
+	VarNumber  = "number"
+	VarBoolean = "boolean"
+)
+
+const (
+	// userVarPrefix 以此开头的变量取自接收用户的属性，例如 user.nickname、user.level
+	userVarPrefix = "user."
+
+	maxTemplateVariables = 50
+	maxTemplateLocales   = 50
+	maxTemplateData      = 20
+	maxTemplateTitle     = 255
+	maxTemplateBody      = 2000
+	maxTemplateDataValue = 1000
+)
+
+var (
+	// ErrInvalidTemplate 推送模板或模板变量不合法
+	ErrInvalidTemplate = errors.New("推送模板不合法")
+	// ErrTemplateNotFound 推送引用的模板不存在
+	ErrTemplateNotFound = errors.New("推送模板不存在")
+)
+
+var (
+	placeholderRegex  = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+(?:\.[A-Za-z0-9_]+)?)\s*\}\}`)
+	variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+	dataKeyRegex      = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+	varTypes          = map[string]bool{VarString: true, VarNumber: true, VarBoolean: true}
+)
+
+// userFields 用户内置字段，优先于同名的自定义属性
+var userFields = map[string]func(u *model.User) interface{}{
+	"id":       func(u *model.User) interface{} { return u.ID },
+	"open_id":  func(u *model.User) interface{} { return u.OpenID },
+	"nickname": func(u *model.User) interface{} { return u.Nickname },
+	"email":    func(u *model.User) interface{} { return u.Email },
+	"phone":    func(u *model.User) interface{} { return u.Phone },
+}
+
+// Content 模板内容，标题、正文和数据中可用 {{变量名}} 引用变量
+type Content struct {
+	Title string            `json:"title"`
+	Body  string            `json:"body"`
+	Data  map[string]string `json:"data,omitempty"` // 语言版本的数据为空时沿用默认内容的数据
+}
+
+// Variable 模板变量。普通变量由创建推送的请求传入，user. 开头的变量取自接收用户的内置字段或自定义属性；
+// 未传入或用户没有该属性时使用默认值，没有默认值时渲染为空
+type Variable struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // string/number/boolean，为空时为 string
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required,omitempty"` // 创建推送时必须传入，仅普通变量可用
+	Description string      `json:"description,omitempty"`
+}
+
+func (v *Variable) fromUser() bool {
+	return strings.HasPrefix(v.Name, userVarPrefix)
+}
+
+// Template 推送模板，按接收设备的语言选择语言版本，没有匹配的版本时使用默认内容
+type Template struct {
+	Content
+	Variables []Variable         `json:"variables"`
+	Locales   map[string]Content `json:"locales"` // 键为语言标签，例如 en、zh-TW
+
+	vars map[string]*Variable
+}
+
+// FromModel 解析保存的推送模板
+func FromModel(m *model.PushTemplate) (*Template, error) {
+	t := &Template{Content: Content{Title: m.Title, Body: m.Body}}
+	for _, f := range []struct {
+		raw string
+		v   interface{}
+	}{{m.Data, &t.Data}, {m.Variables, &t.Variables}, {m.Locales, &t.Locales}} {
+		if f.raw == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(f.raw), f.v); err != nil {
+			return nil, fmt.Errorf("推送模板 %d 解析失败: %w", m.ID, err)
+		}
+	}
+	t.index()
+	return t, nil
+}
+
+// ToModel 将模板内容写入模型，调用前应先 Validate
+func (t *Template) ToModel(m *model.PushTemplate) error {
+	if t.Data == nil {
+		t.Data = map[string]string{}
+	}
+	if t.Variables == nil {
+		t.Variables = []Variable{}
+	}
+	if t.Locales == nil {
+		t.Locales = map[string]Content{}
+	}
+	data, err := json.Marshal(t.Data)
+	if err != nil {
+		return err
+	}
+	variables, err := json.Marshal(t.Variables)
+	if err != nil {
+		return err
+	}
+	locales, err := json.Marshal(t.Locales)
+	if err != nil {
+		return err
+	}
+	m.Title, m.Body = t.Title, t.Body
+	m.Data, m.Variables, m.Locales = string(data), string(variables), string(locales)
+	return nil
+}
+
+func (t *Template) index() {
+	t.vars = make(map[string]*Variable, len(t.Variables))
+	for i := range t.Variables {
+		t.vars[t.Variables[i].Name] = &t.Variables[i]
+	}
+}
+
+// Validate 校验并规范化模板：内容长度、变量声明和默认值类型、语言标签，以及引用的变量均已声明
+func (t *Template) Validate() error {
+	if len(t.Variables) > maxTemplateVariables {
+		return invalidTemplate("变量不能超过%d个", maxTemplateVariables)
+	}
+	t.vars = make(map[string]*Variable, len(t.Variables))
+	for i := range t.Variables {
+		v := &t.Variables[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Type == "" {
+			v.Type = VarString
+		}
+		if v.fromUser() {
+			if profile.ValidatePropertyName(strings.TrimPrefix(v.Name, userVarPrefix)) != nil {
+				return invalidTemplate("变量名 %q 不合法", v.Name)
+			}
+			if v.Required {
+				return invalidTemplate("用户属性变量 %s 不能设为必填，请设置默认值", v.Name)
+			}
+		} else if !variableNameRegex.MatchString(v.Name) {
+			return invalidTemplate("变量名 %q 不合法，应以字母或下划线开头，最长64个字符", v.Name)
+		}
+		if _, dup := t.vars[v.Name]; dup {
+			return invalidTemplate("变量 %s 重复", v.Name)
+		}
+		if !varTypes[v.Type] {
+			return invalidTemplate("变量 %s 的类型应为 string、number 或 boolean", v.Name)
+		}
+		if v.Default != nil {
+			if _, ok := coerce(v.Type, v.Default); !ok {
+				return invalidTemplate("变量 %s 的默认值应为 %s", v.Name, v.Type)
+			}
+		}
+		if len(v.Description) > 255 {
+			return invalidTemplate("变量 %s 的说明不能超过255个字符", v.Name)
+		}
+		t.vars[v.Name] = v
+	}
+
+	if err := t.validateContent("默认内容", &t.Content, true); err != nil {
+		return err
+	}
+	if len(t.Locales) > maxTemplateLocales {
+		return invalidTemplate("语言版本不能超过%d个", maxTemplateLocales)
+	}
+	locales := make(map[string]Content, len(t.Locales))
+	seen := map[string]bool{}
+	for key, c := range t.Locales {
+		locale := normalizeLocale(key)
+		if !validLocale(locale) {
+			return invalidTemplate("语言标签 %q 不合法，例如 en、zh-TW", key)
+		}
+		if seen[strings.ToLower(locale)] {
+			return invalidTemplate("语言版本 %s 重复", locale)
+		}
+		seen[strings.ToLower(locale)] = true
+		if err := t.validateContent("语言版本 "+locale, &c, false); err != nil {
+			return err
+		}
+		locales[locale] = c
+	}
+	t.Locales = locales
+	return nil
+}
+
+func (t *Template) validateContent(label string, c *Content, base bool) error {
+	c.Title = strings.TrimSpace(c.Title)
+	if c.Title == "" || utf8.RuneCountInString(c.Title) > maxTemplateTitle {
+		return invalidTemplate("%s的标题长度应在1-%d个字符之间", label, maxTemplateTitle)
+	}
+	if strings.TrimSpace(c.Body) == "" || utf8.RuneCountInString(c.Body) > maxTemplateBody {
+		return invalidTemplate("%s的正文长度应在1-%d个字符之间", label, maxTemplateBody)
+	}
+	if len(c.Data) > maxTemplateData {
+		return invalidTemplate("%s的数据不能超过%d项", label, maxTemplateData)
+	}
+	texts := []string{c.Title, c.Body}
+	for key, value := range c.Data {
+		if !dataKeyRegex.MatchString(key) {
+			return invalidTemplate("%s的数据键 %q 不合法", label, key)
+		}
+		if utf8.RuneCountInString(value) > maxTemplateDataValue {
+			return invalidTemplate("%s的数据 %s 不能超过%d个字符", label, key, maxTemplateDataValue)
+		}
+		texts = append(texts, value)
+	}
+	if !base && len(c.Data) == 0 {
+		c.Data = nil
+	}
+	for _, text := range texts {
+		for _, m := range placeholderRegex.FindAllStringSubmatch(text, -1) {
+			if _, ok := t.vars[m[1]]; !ok {
+				return invalidTemplate("%s引用了未声明的变量 %s", label, m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// Bind 校验创建推送时传入的变量，按声明的类型规范化为字符串；
+// 未声明的变量、用户属性变量和缺少的必填变量返回 ErrInvalidTemplate
+func (t *Template) Bind(params map[string]interface{}) (map[string]string, error) {
+	bound := make(map[string]string, len(params))
+	for name, value := range params {
+		v, ok := t.vars[name]
+		if !ok {
+			return nil, invalidTemplate("模板没有声明变量 %s", name)
+		}
+		if v.fromUser() {
+			return nil, invalidTemplate("变量 %s 取自接收用户的属性，不能传入", name)
+		}
+		s, ok := coerce(v.Type, value)
+		if !ok {
+			return nil, invalidTemplate("变量 %s 应为 %s", name, v.Type)
+		}
+		bound[name] = s
+	}
+	for _, v := range t.Variables {
+		if _, ok := bound[v.Name]; v.Required && !ok {
+			return nil, invalidTemplate("缺少必填变量 %s", v.Name)
+		}
+	}
+	return bound, nil
+}
+
+// UsesRecipient 模板是否引用接收用户的属性，不引用时所有用户的渲染结果只取决于语言
+func (t *Template) UsesRecipient() bool {
+	for i := range t.Variables {
+		if t.Variables[i].fromUser() {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 按接收用户和设备语言渲染通知，params 为 Bind 规范化后的变量，r 为空时用户属性变量取默认值
+func (t *Template) Render(params map[string]string, r *Recipient, locale string) *Notification {
+	c := t.content(locale)
+	expand := func(s string) string {
+		return placeholderRegex.ReplaceAllStringFunc(s, func(m string) string {
+			return t.resolve(placeholderRegex.FindStringSubmatch(m)[1], params, r)
+		})
+	}
+	n := &Notification{Title: expand(c.Title), Body: expand(c.Body)}
+	if len(c.Data) > 0 {
+		n.Data = make(map[string]string, len(c.Data))
+		for key, value := range c.Data {
+			n.Data[key] = expand(value)
+		}
+	}
+	return n
+}
+
+func (t *Template) resolve(name string, params map[string]string, r *Recipient) string {
+	v, ok := t.vars[name]
+	if !ok {
+		return ""
+	}
+	if v.fromUser() {
+		if value, ok := r.attribute(strings.TrimPrefix(name, userVarPrefix)); ok {
+			if s, ok := coerce(v.Type, value); ok {
+				return s
+			}
+		}
+	} else if s, ok := params[name]; ok {
+		return s
+	}
+	if s, ok := coerce(v.Type, v.Default); ok {
+		return s
+	}
+	return ""
+}
+
+// content 选择与设备语言最接近的语言版本：先完全匹配，再逐级去掉末尾的子标签匹配，
+// 例如 zh-Hant-TW 依次匹配 zh-Hant-TW、zh-Hant、zh
+func (t *Template) content(locale string) Content {
+	locale = normalizeLocale(locale)
+	for locale != "" && len(t.Locales) > 0 {
+		for key, c := range t.Locales {
+			if strings.EqualFold(key, locale) {
+				if c.Data == nil {
+					c.Data = t.Data
+				}
+				return c
+			}
+		}
+		i := strings.LastIndexByte(locale, '-')
+		if i < 0 {
+			break
+		}
+		locale = locale[:i]
+	}
+	return t.Content
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+}
+
+// coerce 按变量类型将值规范化为字符串，类型不符时返回 false
+func coerce(typ string, value interface{}) (string, bool) {
+	switch typ {
+	case VarNumber:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case int:
+			f = float64(v)
+		case uint:
+			f = float64(v)
+		case json.Number:
+			parsed, err := v.Float64()
+			if err != nil {
+				return "", false
+			}
+			f = parsed
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return "", false
+			}
+			f = parsed
+		default:
+			return "", false
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), true
+	case VarBoolean:
+		switch v := value.(type) {
+		case bool:
+			return strconv.FormatBool(v), true
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return "", false
+			}
+			return strconv.FormatBool(b), true
+		}
+		return "", false
+	default:
+		switch v := value.(type) {
+		case string:
+			return v, true
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(v), true
+		case int, uint, json.Number:
+			return fmt.Sprint(v), true
+		}
+		return "", false
+	}
+}
+
+func invalidTemplate(format string, args ...interface{}) error {
+	return &validationError{msg: fmt.Sprintf(format, args...), kind: ErrInvalidTemplate}
+}
+
+// Recipient 渲染模板时的接收用户
+type Recipient struct {
+	User       *model.User
+	Properties map[string]interface{} // 用户自定义属性
+}
+
+func (r *Recipient) attribute(field string) (interface{}, bool) {
+	if r == nil {
+		return nil, false
+	}
+	if get, ok := userFields[field]; ok && r.User != nil {
+		return get(r.User), true
+	}
+	value, ok := r.Properties[field]
+	return value, ok && value != nil
+}
+
+// LoadRecipients 批量加载接收用户及其自定义属性，不存在的用户不在结果中
+func LoadRecipients(db *gorm.DB, appID uint, userIDs []uint) (map[uint]*Recipient, error) {
+	var users []model.User
+	if err := db.Where("app_id = ? AND id IN ?", appID, userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	props, err := profile.UsersProperties(db, appID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	recipients := make(map[uint]*Recipient, len(users))
+	for i := range users {
+		recipients[users[i].ID] = &Recipient{User: &users[i], Properties: props[users[i].ID]}
+	}
+	return recipients, nil
+}
+
+// LoadTemplate 加载应用的推送模板
+func LoadTemplate(db *gorm.DB, appID, templateID uint) (*model.PushTemplate, *Template, error) {
+	var m model.PushTemplate
+	if err := db.Where("app_id = ?", appID).First(&m, templateID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrTemplateNotFound
+		}
+		return nil, nil, err
+	}
+	t, err := FromModel(&m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &m, t, nil
+}
+
+// renderBatch 为一批设备逐个渲染模板，同一用户同一语言只渲染一次
+func (t *Template) renderBatch(db *gorm.DB, appID uint, params map[string]string, devices []model.PushDevice) ([]*Notification, error) {
+	var recipients map[uint]*Recipient
+	if t.UsesRecipient() {
+		userIDs := make([]uint, 0, len(devices))
+		for _, d := range devices {
+			userIDs = append(userIDs, d.UserID)
+		}
+		var err error
+		if recipients, err = LoadRecipients(db, appID, userIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	type key struct {
+		userID uint
+		locale string
+	}
+	cache := map[key]*Notification{}
+	notifications := make([]*Notification, len(devices))
+	for i, d := range devices {
+		k := key{locale: strings.ToLower(d.Locale)}
+		if recipients != nil {
+			k.userID = d.UserID
+		}
+		n, ok := cache[k]
+		if !ok {
+			n = t.Render(params, recipients[d.UserID], d.Locale)
+			cache[k] = n
+		}
+		notifications[i] = n
+	}
+	return notifications, nil
+}
@@ -0,0 +1,134 @@
+package pusher
+
+import (
+	"errors"
+	"testing"
+
+	"app-platform-backend/internal/model"
+)
+
+func newTestTemplate(t *testing.T) *Template {
+	t.Helper()
+	tpl := &Template{
+		Content: Content{
+			Title: "{{user.nickname}}，订单{{order_id}}已发货",
+			Body:  "共{{count}}件，加急：{{urgent}}，等级{{user.level}}",
+			Data:  map[string]string{"url": "app://orders/{{order_id}}"},
+		},
+		Variables: []Variable{
+			{Name: "order_id", Required: true},
+			{Name: "count", Type: VarNumber, Default: 1.0},
+			{Name: "urgent", Type: VarBoolean, Default: false},
+			{Name: "user.nickname", Default: "亲爱的用户"},
+			{Name: "user.level", Type: VarNumber, Default: 0.0},
+		},
+		Locales: map[string]Content{
+			"en":      {Title: "Order {{order_id}} shipped", Body: "{{count}} items"},
+			"zh_Hant": {Title: "{{user.nickname}}，訂單{{order_id}}已出貨", Body: "共{{count}}件", Data: map[string]string{"url": "app://zh-hant/{{order_id}}"}},
+		},
+	}
+	if err := tpl.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	return tpl
+}
+
+func TestTemplateRender(t *testing.T) {
+	tpl := newTestTemplate(t)
+	params, err := tpl.Bind(map[string]interface{}{"order_id": 42.0, "count": "3"})
+	if err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	alice := &Recipient{
+		User:       &model.User{ID: 7, Nickname: "Alice"},
+		Properties: map[string]interface{}{"level": 5.0, "nickname": "ignored"},
+	}
+
+	tests := []struct {
+		name      string
+		recipient *Recipient
+		locale    string
+		title     string
+		body      string
+		url       string
+	}{
+		{"默认内容", alice, "", "Alice，订单42已发货", "共3件，加急：false，等级5", "app://orders/42"},
+		{"无接收用户取默认值", nil, "zh-CN", "亲爱的用户，订单42已发货", "共3件，加急：false，等级0", "app://orders/42"},
+		{"语言前缀匹配并沿用默认数据", alice, "en-US", "Order 42 shipped", "3 items", "app://orders/42"},
+		{"逐级去掉子标签", alice, "zh-Hant-TW", "Alice，訂單42已出貨", "共3件", "app://zh-hant/42"},
+		{"属性类型不符取默认值", &Recipient{Properties: map[string]interface{}{"level": "vip"}}, "", "亲爱的用户，订单42已发货", "共3件，加急：false，等级0", "app://orders/42"},
+	}
+	for _, tt := range tests {
+		n := tpl.Render(params, tt.recipient, tt.locale)
+		if n.Title != tt.title || n.Body != tt.body || n.Data["url"] != tt.url {
+			t.Errorf("%s: got %q / %q / %q, want %q / %q / %q", tt.name, n.Title, n.Body, n.Data["url"], tt.title, tt.body, tt.url)
+		}
+	}
+}
+
+func TestTemplateBind(t *testing.T) {
+	tpl := newTestTemplate(t)
+	invalid := []map[string]interface{}{
+		{},
+		{"order_id": "1", "count": "many"},
+		{"order_id": "1", "urgent": "maybe"},
+		{"order_id": "1", "coupon": "x"},
+		{"order_id": "1", "user.nickname": "Bob"},
+		{"order_id": map[string]interface{}{"id": 1}},
+	}
+	for _, params := range invalid {
+		if _, err := tpl.Bind(params); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("Bind(%v) err = %v, want ErrInvalidTemplate", params, err)
+		}
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	base := func() Template {
+		return Template{Content: Content{Title: "t", Body: "b"}, Variables: []Variable{{Name: "x"}}}
+	}
+	tests := []struct {
+		name   string
+		modify func(*Template)
+	}{
+		{"空标题", func(t *Template) { t.Title = " " }},
+		{"未声明的变量", func(t *Template) { t.Body = "{{y}}" }},
+		{"重复变量", func(t *Template) { t.Variables = append(t.Variables, Variable{Name: "x"}) }},
+		{"变量名不合法", func(t *Template) { t.Variables[0].Name = "1x" }},
+		{"类型不支持", func(t *Template) { t.Variables[0].Type = "date" }},
+		{"默认值类型不符", func(t *Template) { t.Variables[0].Type, t.Variables[0].Default = VarNumber, "abc" }},
+		{"用户属性必填", func(t *Template) { t.Variables = []Variable{{Name: "user.level", Required: true}} }},
+		{"语言标签不合法", func(t *Template) { t.Locales = map[string]Content{"en US": {Title: "t", Body: "b"}} }},
+		{"语言版本重复", func(t *Template) {
+			t.Locales = map[string]Content{"en-us": {Title: "t", Body: "b"}, "en_US": {Title: "t", Body: "b"}}
+		}},
+		{"语言版本引用未声明的变量", func(t *Template) { t.Locales = map[string]Content{"en": {Title: "{{z}}", Body: "b"}} }},
+		{"数据键不合法", func(t *Template) { t.Data = map[string]string{"a b": "v"} }},
+	}
+	for _, tt := range tests {
+		tpl := base()
+		tt.modify(&tpl)
+		if err := tpl.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("%s: err = %v, want ErrInvalidTemplate", tt.name, err)
+		}
+	}
+}
+
+func TestTemplateModelRoundTrip(t *testing.T) {
+	tpl := newTestTemplate(t)
+	var m model.PushTemplate
+	if err := tpl.ToModel(&m); err != nil {
+		t.Fatalf("ToModel: %v", err)
+	}
+	loaded, err := FromModel(&m)
+	if err != nil {
+		t.Fatalf("FromModel: %v", err)
+	}
+	params, _ := loaded.Bind(map[string]interface{}{"order_id": "9"})
+	if n := loaded.Render(params, nil, "zh-Hant"); n.Title != "亲爱的用户，訂單9已出貨" {
+		t.Errorf("title = %q", n.Title)
+	}
+	if !loaded.UsesRecipient() {
+		t.Error("UsesRecipient = false, want true")
+	}
+}
@@ -0,0 +1,77 @@
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const xiaomiEndpoint = "https://api.xmpush.xiaomi.com"
+
+func init() { Register(ProviderXiaomi, newXiaomi) }
+
+// xiaomiCredentials 小米推送凭证，AppSecret 直接用于接口认证
+type xiaomiCredentials struct {
+	AppSecret   string `json:"appSecret"`
+	PackageName string `json:"packageName"`
+}
+
+// Xiaomi 小米推送，按 regId 投递通知栏消息
+type Xiaomi struct {
+	secret      string
+	packageName string
+	endpoint    string
+	client      *http.Client
+}
+
+func newXiaomi(raw json.RawMessage) (Provider, error) {
+	var cred xiaomiCredentials
+	if err := decodeCredentials(raw, &cred); err != nil {
+		return nil, err
+	}
+	if err := requireFields(map[string]string{"appSecret": cred.AppSecret, "packageName": cred.PackageName}); err != nil {
+		return nil, err
+	}
+	return &Xiaomi{
+		secret:      cred.AppSecret,
+		packageName: cred.PackageName,
+		endpoint:    xiaomiEndpoint,
+		client:      newHTTPClient(),
+	}, nil
+}
+
+// Send 投递到单个设备，自定义数据以 extra 参数下发
+func (p *Xiaomi) Send(ctx context.Context, token string, n *Notification) error {
+	form := url.Values{
+		"registration_id":         {token},
+		"restricted_package_name": {p.packageName},
+		"title":                   {n.Title},
+		"description":             {n.Body},
+		"notify_type":             {"-1"}, // 使用默认提示音、振动和指示灯
+		"pass_through":            {"0"},
+	}
+	if n.TTL > 0 {
+		form.Set("time_to_live", strconv.FormatInt(n.TTL.Milliseconds(), 10))
+	}
+	for k, v := range n.Data {
+		form.Set("extra."+k, v)
+	}
+
+	var result struct {
+		Result      string `json:"result"`
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+		Reason      string `json:"reason"`
+	}
+	header := http.Header{"Authorization": {"key=" + p.secret}}
+	if err := postForm(ctx, p.client, p.endpoint+"/v3/message/regid", form, header, &result); err != nil {
+		return fmt.Errorf("xiaomi: %w", err)
+	}
+	if result.Code != 0 {
+		return fmt.Errorf("xiaomi: %d %s %s", result.Code, result.Description, result.Reason)
+	}
+	return nil
+}
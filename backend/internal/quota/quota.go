@@ -0,0 +1,345 @@
+// Package quota 应用配额：套餐提供默认值，应用可单独覆盖，各模块在数据写入入口检查并累计用量
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 配额项
+const (
+	MetricStorageBytes     = "storage_bytes"
+	MetricFiles            = "files"
+	MetricEventsPerDay     = "events_per_day"
+	MetricLogsPerDay       = "logs_per_day"
+	MetricMetricsPerMinute = "metrics_per_minute"
+	MetricPushesPerDay     = "pushes_per_day"
+	MetricWSConnections    = "ws_connections"
+)
+
+// Metrics 全部配额项，用量接口按此顺序返回
+var Metrics = []string{
+	MetricStorageBytes,
+	MetricFiles,
+	MetricEventsPerDay,
+	MetricLogsPerDay,
+	MetricMetricsPerMinute,
+	MetricPushesPerDay,
+	MetricWSConnections,
+}
+
+// metricLabels 配额项名称，用于错误提示
+var metricLabels = map[string]string{
+	MetricStorageBytes:     "存储空间",
+	MetricFiles:            "文件数量",
+	MetricEventsPerDay:     "每日事件上报数",
+	MetricLogsPerDay:       "每日日志上报数",
+	MetricMetricsPerMinute: "每分钟指标上报数",
+	MetricPushesPerDay:     "每日推送数",
+	MetricWSConnections:    "WebSocket连接数",
+}
+
+// columns 配额项在套餐和应用配额表中对应的字段
+var columns = map[string]string{
+	MetricStorageBytes:     "max_storage_bytes",
+	MetricFiles:            "max_files",
+	MetricEventsPerDay:     "events_per_day",
+	MetricLogsPerDay:       "logs_per_day",
+	MetricMetricsPerMinute: "metrics_per_minute",
+	MetricPushesPerDay:     "pushes_per_day",
+	MetricWSConnections:    "max_ws_connections",
+}
+
+// 按时间窗口累计的配额项及窗口长度，其余配额项按当前存量计算
+var windows = map[string]time.Duration{
+	MetricEventsPerDay:     24 * time.Hour,
+	MetricLogsPerDay:       24 * time.Hour,
+	MetricMetricsPerMinute: time.Minute,
+	MetricPushesPerDay:     24 * time.Hour,
+}
+
+// limitsCacheTTL 配额缓存时长，修改配额时主动失效
+const limitsCacheTTL = 30 * time.Second
+
+// Limits 各配额项的上限，0 表示不限制
+type Limits map[string]int64
+
+// ExceededError 超出配额
+type ExceededError struct {
+	Metric string
+	Limit  int64
+	Used   int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("已超出应用配额：%s（上限 %d，已用 %d）", metricLabels[e.Metric], e.Limit, e.Used)
+}
+
+// Gauge 返回应用当前存量，用于不按时间窗口累计的配额项
+type Gauge func(appID uint) (int64, error)
+
+type cachedLimits struct {
+	limits  Limits
+	planID  uint
+	expires time.Time
+}
+
+var (
+	db *gorm.DB
+
+	cacheMu sync.Mutex
+	cache   = map[uint]cachedLimits{}
+
+	gaugeMu sync.RWMutex
+	gauges  = map[string]Gauge{}
+)
+
+// Column 配额项对应的字段名，未知配额项返回false
+func Column(metric string) (string, bool) {
+	c, ok := columns[metric]
+	return c, ok
+}
+
+// Window 配额项的统计窗口：day、minute，存量配额项返回空字符串
+func Window(metric string) string {
+	switch windows[metric] {
+	case 24 * time.Hour:
+		return "day"
+	case time.Minute:
+		return "minute"
+	}
+	return ""
+}
+
+// Init 初始化配额检查
+func Init(database *gorm.DB) {
+	db = database
+}
+
+// RegisterGauge 模块注册存量配额项的当前用量，供用量接口展示
+func RegisterGauge(metric string, g Gauge) {
+	gaugeMu.Lock()
+	defer gaugeMu.Unlock()
+	gauges[metric] = g
+}
+
+// PlanLimits 套餐的配额
+func PlanLimits(p *model.Plan) Limits {
+	return Limits{
+		MetricStorageBytes:     p.MaxStorageBytes,
+		MetricFiles:            p.MaxFiles,
+		MetricEventsPerDay:     p.EventsPerDay,
+		MetricLogsPerDay:       p.LogsPerDay,
+		MetricMetricsPerMinute: p.MetricsPerMinute,
+		MetricPushesPerDay:     p.PushesPerDay,
+		MetricWSConnections:    p.MaxWSConnections,
+	}
+}
+
+// Merge 在套餐配额上应用应用单独设置的配额
+func Merge(plan Limits, q *model.AppQuota) Limits {
+	limits := make(Limits, len(plan))
+	for k, v := range plan {
+		limits[k] = v
+	}
+	if q == nil {
+		return limits
+	}
+	overrides := map[string]*int64{
+		MetricStorageBytes:     q.MaxStorageBytes,
+		MetricFiles:            q.MaxFiles,
+		MetricEventsPerDay:     q.EventsPerDay,
+		MetricLogsPerDay:       q.LogsPerDay,
+		MetricMetricsPerMinute: q.MetricsPerMinute,
+		MetricPushesPerDay:     q.PushesPerDay,
+		MetricWSConnections:    q.MaxWSConnections,
+	}
+	for k, v := range overrides {
+		if v != nil {
+			limits[k] = *v
+		}
+	}
+	return limits
+}
+
+// WindowStart 配额项当前时间窗口的开始时间，存量配额项返回零值
+func WindowStart(metric string, now time.Time) time.Time {
+	switch windows[metric] {
+	case 24 * time.Hour:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	case time.Minute:
+		return now.Truncate(time.Minute)
+	}
+	return time.Time{}
+}
+
+// Resolve 查询应用生效的配额和所用套餐，结果缓存一小段时间
+func Resolve(appID uint) (Limits, uint, error) {
+	cacheMu.Lock()
+	if c, ok := cache[appID]; ok && time.Now().Before(c.expires) {
+		cacheMu.Unlock()
+		return c.limits, c.planID, nil
+	}
+	cacheMu.Unlock()
+
+	var q *model.AppQuota
+	var row model.AppQuota
+	err := db.Where("app_id = ?", appID).First(&row).Error
+	switch {
+	case err == nil:
+		q = &row
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, 0, err
+	}
+
+	var plan model.Plan
+	if q != nil && q.PlanID != nil {
+		err = db.First(&plan, *q.PlanID).Error
+	} else {
+		err = db.Where("is_default = ?", true).Order("id").First(&plan).Error
+	}
+	planLimits := Limits{}
+	if err == nil {
+		planLimits = PlanLimits(&plan)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, err
+	}
+
+	limits := Merge(planLimits, q)
+	cacheMu.Lock()
+	cache[appID] = cachedLimits{limits: limits, planID: plan.ID, expires: time.Now().Add(limitsCacheTTL)}
+	cacheMu.Unlock()
+	return limits, plan.ID, nil
+}
+
+// Invalidate 应用配额修改后清除缓存
+func Invalidate(appID uint) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	delete(cache, appID)
+}
+
+// Consume 累计按时间窗口计算的用量，超出上限时回退本次用量并返回 *ExceededError
+// 用量不受上限影响时同样累计，供用量接口展示
+func Consume(appID uint, metric string, n int64) error {
+	return ConsumeIn(db, appID, metric, n)
+}
+
+// ConsumeIn 在调用方的事务 tx 中累计用量，事务回滚时本次用量一并撤销
+func ConsumeIn(tx *gorm.DB, appID uint, metric string, n int64) error {
+	if n <= 0 {
+		return nil
+	}
+	limits, _, err := Resolve(appID)
+	if err != nil {
+		return err
+	}
+
+	window := WindowStart(metric, time.Now())
+	counter := model.AppUsageCounter{AppID: appID, Metric: metric, WindowStart: window, Count: n}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "app_id"}, {Name: "metric"}, {Name: "window_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + ?", n)}),
+	}).Create(&counter).Error; err != nil {
+		return err
+	}
+
+	limit := limits[metric]
+	if limit <= 0 {
+		return nil
+	}
+	used, err := windowUsage(tx, appID, metric, window)
+	if err != nil {
+		return err
+	}
+	if used > limit {
+		// 回退本次用量，被拒绝的请求不计入
+		tx.Model(&model.AppUsageCounter{}).
+			Where("app_id = ? AND metric = ? AND window_start = ?", appID, metric, window).
+			Update("count", gorm.Expr("count - ?", n))
+		return &ExceededError{Metric: metric, Limit: limit, Used: used - n}
+	}
+	return nil
+}
+
+// ConsumeWith 在同一事务中执行写入并累计用量：写入失败时不计入用量，超出上限时写入一并回滚
+func ConsumeWith(appID uint, metric string, n int64, write func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		return ConsumeIn(tx, appID, metric, n)
+	})
+}
+
+// Check 检查存量配额项：当前用量加上本次新增是否超出上限
+func Check(appID uint, metric string, current, n int64) error {
+	limits, _, err := Resolve(appID)
+	if err != nil {
+		return err
+	}
+	if limit := limits[metric]; limit > 0 && current+n > limit {
+		return &ExceededError{Metric: metric, Limit: limit, Used: current}
+	}
+	return nil
+}
+
+// Usage 应用各配额项的当前用量
+func Usage(appID uint) (map[string]int64, error) {
+	now := time.Now()
+	used := make(map[string]int64, len(Metrics))
+	for _, m := range Metrics {
+		if _, ok := windows[m]; ok {
+			n, err := windowUsage(db, appID, m, WindowStart(m, now))
+			if err != nil {
+				return nil, err
+			}
+			used[m] = n
+			continue
+		}
+
+		gaugeMu.RLock()
+		g, ok := gauges[m]
+		gaugeMu.RUnlock()
+		if !ok {
+			continue
+		}
+		n, err := g(appID)
+		if err != nil {
+			return nil, err
+		}
+		used[m] = n
+	}
+	return used, nil
+}
+
+func windowUsage(tx *gorm.DB, appID uint, metric string, window time.Time) (int64, error) {
+	var count int64
+	err := tx.Model(&model.AppUsageCounter{}).
+		Where("app_id = ? AND metric = ? AND window_start = ?", appID, metric, window).
+		Select("COALESCE(SUM(count), 0)").Scan(&count).Error
+	return count, err
+}
+
+// Reject 写入超出配额的响应：存储空间返回413，其余返回429；其他错误按数据库错误处理
+func Reject(c *gin.Context, err error) {
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) {
+		response.DBError(c, err)
+		return
+	}
+	if exceeded.Metric == MetricStorageBytes {
+		response.PayloadTooLarge(c, exceeded.Error())
+		return
+	}
+	response.TooManyRequests(c, exceeded.Error())
+}
@@ -0,0 +1,128 @@
+package quota
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/sqltest"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func TestMerge(t *testing.T) {
+	plan := PlanLimits(&model.Plan{MaxStorageBytes: 100, EventsPerDay: 50, PushesPerDay: 10})
+	events := int64(500)
+	unlimited := int64(0)
+
+	tests := []struct {
+		name  string
+		quota *model.AppQuota
+		want  Limits
+	}{
+		{"no overrides", nil, Limits{MetricStorageBytes: 100, MetricEventsPerDay: 50, MetricPushesPerDay: 10}},
+		{"raise one limit", &model.AppQuota{EventsPerDay: &events}, Limits{MetricStorageBytes: 100, MetricEventsPerDay: 500, MetricPushesPerDay: 10}},
+		{"override to unlimited", &model.AppQuota{PushesPerDay: &unlimited}, Limits{MetricStorageBytes: 100, MetricEventsPerDay: 50, MetricPushesPerDay: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Merge(plan, tt.quota)
+			for metric, want := range tt.want {
+				if got[metric] != want {
+					t.Errorf("Merge()[%s] = %d, want %d", metric, got[metric], want)
+				}
+			}
+		})
+	}
+
+	if plan[MetricEventsPerDay] != 50 {
+		t.Errorf("Merge() modified plan limits")
+	}
+}
+
+func TestWindowStart(t *testing.T) {
+	now := time.Date(2024, 5, 6, 13, 45, 30, 0, time.Local)
+
+	tests := []struct {
+		metric string
+		want   time.Time
+	}{
+		{MetricEventsPerDay, time.Date(2024, 5, 6, 0, 0, 0, 0, time.Local)},
+		{MetricMetricsPerMinute, time.Date(2024, 5, 6, 13, 45, 0, 0, time.Local)},
+		{MetricStorageBytes, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.metric, func(t *testing.T) {
+			if got := WindowStart(tt.metric, now); !got.Equal(tt.want) {
+				t.Errorf("WindowStart() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReject(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"storage exceeded", &ExceededError{Metric: MetricStorageBytes, Limit: 10, Used: 10}, http.StatusRequestEntityTooLarge},
+		{"rate exceeded", &ExceededError{Metric: MetricEventsPerDay, Limit: 10, Used: 10}, http.StatusTooManyRequests},
+		{"connections exceeded", &ExceededError{Metric: MetricWSConnections, Limit: 1, Used: 1}, http.StatusTooManyRequests},
+		{"database error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			Reject(c, tt.err)
+			if w.Code != tt.want {
+				t.Errorf("Reject() status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestConsumeWith(t *testing.T) {
+	var used int64
+	database, fake := sqltest.Open(t, func(query string, args []driver.Value) sqltest.Reply {
+		if strings.HasPrefix(query, "SELECT") {
+			return sqltest.Reply{Columns: []string{"sum"}, Rows: [][]driver.Value{{used}}}
+		}
+		return sqltest.Reply{Affected: 1}
+	})
+	Init(database)
+	cache[1] = cachedLimits{limits: Limits{MetricEventsPerDay: 10}, expires: time.Now().Add(time.Minute)}
+	defer Invalidate(1)
+
+	// 写入失败时不计入用量
+	writeErr := errors.New("insert failed")
+	if err := ConsumeWith(1, MetricEventsPerDay, 1, func(*gorm.DB) error { return writeErr }); !errors.Is(err, writeErr) {
+		t.Errorf("err = %v, want write error", err)
+	}
+	if n := len(fake.Calls("app_usage_counters")); n != 0 {
+		t.Errorf("failed write ran %d counter statements, want 0", n)
+	}
+
+	used = 10
+	if err := ConsumeWith(1, MetricEventsPerDay, 1, func(*gorm.DB) error { return nil }); err != nil {
+		t.Errorf("within limit: err = %v", err)
+	}
+
+	used = 11
+	var exceeded *ExceededError
+	if err := ConsumeWith(1, MetricEventsPerDay, 1, func(*gorm.DB) error { return nil }); !errors.As(err, &exceeded) {
+		t.Errorf("over limit: err = %v, want *ExceededError", err)
+	}
+}
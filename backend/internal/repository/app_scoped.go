@@ -0,0 +1,218 @@
+// Package repository 提供按应用隔离的数据访问
+// 应用下的资源（推送、事件、版本、文件等）必须通过 AppScoped 读写，
+// 所有查询、更新和删除自动附加当前应用条件，新建记录自动写入应用ID，避免遗漏导致跨应用访问
+package repository
+
+import (
+	"errors"
+	"reflect"
+
+	"app-platform-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNoAppIDField 模型没有 AppID 字段，不能作为应用资源写入
+var ErrNoAppIDField = errors.New("repository: model has no AppID field")
+
+// ErrNoEnvField 模型没有 Env 字段，不能按环境写入
+var ErrNoEnvField = errors.New("repository: model has no Env field")
+
+// AppScoped 绑定到单个应用的数据访问，InEnv 后同时按环境隔离
+type AppScoped struct {
+	db    *gorm.DB
+	appID uint
+	env   string
+}
+
+// ForApp 创建绑定到指定应用的数据访问
+func ForApp(db *gorm.DB, appID uint) *AppScoped {
+	return &AppScoped{db: db, appID: appID}
+}
+
+// FromContext 绑定到应用范围中间件解析出的应用
+// 路由未挂载应用范围中间件时应用ID为0，查询不会匹配任何记录
+func FromContext(c *gin.Context, db *gorm.DB) *AppScoped {
+	return ForApp(db, middleware.ScopedAppID(c))
+}
+
+// FromEnvContext 绑定到请求的应用和环境，环境无效时写入响应并返回false
+// 只能用于带 Env 字段的模型（事件、日志、监控指标、配置中心配置）
+func FromEnvContext(c *gin.Context, db *gorm.DB) (*AppScoped, bool) {
+	env, ok := middleware.RequestEnv(c)
+	if !ok {
+		return nil, false
+	}
+	return FromContext(c, db).InEnv(env.Code), true
+}
+
+// InEnv 返回同时按环境过滤的数据访问，新建记录自动写入环境
+func (r *AppScoped) InEnv(env string) *AppScoped {
+	return &AppScoped{db: r.db, appID: r.appID, env: env}
+}
+
+// AppID 当前绑定的应用ID
+func (r *AppScoped) AppID() uint {
+	return r.appID
+}
+
+// Env 当前绑定的环境，未按环境隔离时为空
+func (r *AppScoped) Env() string {
+	return r.env
+}
+
+// Scope 追加当前表的 app_id 条件，可用于 db.Scopes(...)
+// 使用限定表名的条件，联表查询时不会产生歧义
+func (r *AppScoped) Scope(db *gorm.DB) *gorm.DB {
+	return db.Where(r.condition())
+}
+
+func (r *AppScoped) condition() clause.Expression {
+	app := clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "app_id"},
+		Value:  r.appID,
+	}
+	if r.env == "" {
+		return app
+	}
+	return clause.And(app, clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "env"},
+		Value:  r.env,
+	})
+}
+
+// Model 返回已附加应用条件的查询，用于列表、统计和批量更新
+func (r *AppScoped) Model(value interface{}) *gorm.DB {
+	return r.db.Model(value).Where(r.condition())
+}
+
+// First 按主键查询属于当前应用的记录，其他应用的记录返回 gorm.ErrRecordNotFound
+func (r *AppScoped) First(dest interface{}, id interface{}) error {
+	return r.db.Where(r.condition()).First(dest, id).Error
+}
+
+// Find 查询属于当前应用且满足条件的记录
+func (r *AppScoped) Find(dest interface{}, conds ...interface{}) error {
+	return r.db.Where(r.condition()).Find(dest, conds...).Error
+}
+
+// Create 新建记录并写入当前应用ID，支持单个结构体或切片
+func (r *AppScoped) Create(value interface{}) error {
+	if err := assignAppID(value, r.appID); err != nil {
+		return err
+	}
+	if r.env != "" {
+		if err := assignEnv(value, r.env); err != nil {
+			return err
+		}
+	}
+	return r.db.Create(value).Error
+}
+
+// Updates 更新属于当前应用的记录，value 须已加载主键
+func (r *AppScoped) Updates(value interface{}, updates interface{}) error {
+	return r.db.Model(value).Where(r.condition()).Updates(updates).Error
+}
+
+// Delete 删除属于当前应用的记录，返回实际删除行数
+func (r *AppScoped) Delete(value interface{}, conds ...interface{}) (int64, error) {
+	result := r.db.Where(r.condition()).Delete(value, conds...)
+	return result.RowsAffected, result.Error
+}
+
+// PurgeBatch 物理删除当前应用的一批记录（包括已软删除的），返回删除行数，为0表示已清理完毕
+// 先按主键取一批再删除，避免大表上的长事务
+func (r *AppScoped) PurgeBatch(value interface{}, batchSize int) (int64, error) {
+	var ids []uint
+	if err := r.db.Unscoped().Model(value).Where(r.condition()).
+		Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Unscoped().Delete(value, ids)
+	return result.RowsAffected, result.Error
+}
+
+// PurgeInOrder 按顺序清理多个模型，每次只删除第一个仍有数据的模型的一批记录
+// 用于实现 module.AppDataPurger，返回0表示全部模型都已清理
+func (r *AppScoped) PurgeInOrder(batchSize int, values ...interface{}) (int64, error) {
+	for _, v := range values {
+		n, err := r.PurgeBatch(v, batchSize)
+		if err != nil || n > 0 {
+			return n, err
+		}
+	}
+	return 0, nil
+}
+
+// WithTx 返回在调用方事务 tx 中执行的数据访问，仍绑定当前应用和环境
+func (r *AppScoped) WithTx(tx *gorm.DB) *AppScoped {
+	return &AppScoped{db: tx, appID: r.appID, env: r.env}
+}
+
+// Transaction 在事务中执行，回调中的 AppScoped 仍绑定当前应用和环境
+func (r *AppScoped) Transaction(fn func(tx *AppScoped) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AppScoped{db: tx, appID: r.appID, env: r.env})
+	})
+}
+
+// assignAppID 通过反射写入 AppID 字段
+func assignAppID(value interface{}, appID uint) error {
+	return eachStruct(value, ErrNoAppIDField, func(v reflect.Value) bool {
+		f := v.FieldByName("AppID")
+		if !f.IsValid() || !f.CanSet() || f.Kind() != reflect.Uint {
+			return false
+		}
+		f.SetUint(uint64(appID))
+		return true
+	})
+}
+
+// assignEnv 通过反射写入 Env 字段
+func assignEnv(value interface{}, env string) error {
+	return eachStruct(value, ErrNoEnvField, func(v reflect.Value) bool {
+		f := v.FieldByName("Env")
+		if !f.IsValid() || !f.CanSet() || f.Kind() != reflect.String {
+			return false
+		}
+		f.SetString(env)
+		return true
+	})
+}
+
+// eachStruct 对结构体指针或切片中的每个结构体执行 set，任一失败返回 errMissing
+func eachStruct(value interface{}, errMissing error, set func(v reflect.Value) bool) error {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errMissing
+	}
+	v = v.Elem()
+
+	apply := func(item reflect.Value) error {
+		if item.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+		if item.Kind() != reflect.Struct || !set(item) {
+			return errMissing
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return apply(v)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := apply(v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errMissing
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/pkg/sqltest"
+)
+
+type pushRecord struct {
+	ID     uint
+	AppID  uint
+	Status string
+}
+
+type noAppModel struct {
+	ID uint
+}
+
+func TestAppScoped_QueriesCarryAppCondition(t *testing.T) {
+	db, fake := sqltest.Open(t, sqltest.NoRows)
+	repo := ForApp(db, 7)
+
+	tests := []struct {
+		name string
+		run  func()
+	}{
+		{"first", func() {
+			var r pushRecord
+			repo.First(&r, 3)
+		}},
+		{"find", func() {
+			var rs []pushRecord
+			repo.Find(&rs, "status = ?", "pending")
+		}},
+		{"model", func() {
+			var n int64
+			repo.Model(&pushRecord{}).Where("status = ?", "pending").Count(&n)
+		}},
+		{"updates", func() {
+			repo.Updates(&pushRecord{ID: 3}, map[string]interface{}{"status": "sent"})
+		}},
+		{"delete", func() {
+			repo.Delete(&pushRecord{}, 3)
+		}},
+		{"purge batch", func() {
+			repo.PurgeBatch(&pushRecord{}, 500)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(fake.Calls(""))
+			tt.run()
+			calls := fake.Calls("")[before:]
+			if len(calls) != 1 {
+				t.Fatalf("executed %d statements, want 1", len(calls))
+			}
+			call := calls[0]
+			if !strings.Contains(call.Query, "`push_records`.`app_id` = ?") {
+				t.Errorf("SQL missing app condition: %s", call.Query)
+			}
+			found := false
+			for _, v := range call.Args {
+				if v == uint(7) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("args %v do not contain app id 7", call.Args)
+			}
+		})
+	}
+}
+
+func TestAssignAppID(t *testing.T) {
+	one := pushRecord{AppID: 99}
+	if err := assignAppID(&one, 7); err != nil || one.AppID != 7 {
+		t.Errorf("struct: AppID = %d, err = %v", one.AppID, err)
+	}
+
+	many := []pushRecord{{AppID: 1}, {AppID: 2}}
+	if err := assignAppID(&many, 7); err != nil || many[0].AppID != 7 || many[1].AppID != 7 {
+		t.Errorf("slice: %+v, err = %v", many, err)
+	}
+
+	ptrs := []*pushRecord{{}, {}}
+	if err := assignAppID(&ptrs, 7); err != nil || ptrs[1].AppID != 7 {
+		t.Errorf("pointer slice: err = %v", err)
+	}
+
+	if err := assignAppID(&noAppModel{}, 7); err != ErrNoAppIDField {
+		t.Errorf("model without AppID: err = %v, want ErrNoAppIDField", err)
+	}
+	if err := assignAppID(one, 7); err != ErrNoAppIDField {
+		t.Errorf("non-pointer: err = %v, want ErrNoAppIDField", err)
+	}
+}
+
+type eventRecord struct {
+	ID    uint
+	AppID uint
+	Env   string
+}
+
+func TestAppScoped_InEnvAddsEnvCondition(t *testing.T) {
+	db, fake := sqltest.Open(t, sqltest.NoRows)
+	repo := ForApp(db, 7).InEnv("staging")
+
+	var n int64
+	repo.Model(&eventRecord{}).Count(&n)
+	calls := fake.Calls("")
+	if len(calls) != 1 {
+		t.Fatalf("executed %d statements, want 1", len(calls))
+	}
+	sql := calls[0].Query
+	if !strings.Contains(sql, "`event_records`.`app_id` = ?") || !strings.Contains(sql, "`event_records`.`env` = ?") {
+		t.Errorf("SQL missing app or env condition: %s", sql)
+	}
+
+	ForApp(db, 7).Model(&eventRecord{}).Count(&n)
+	if sql := fake.Calls("")[1].Query; strings.Contains(sql, "`env`") {
+		t.Errorf("repository without env should not filter by env: %s", sql)
+	}
+}
+
+func TestAssignEnv(t *testing.T) {
+	many := []eventRecord{{Env: "prod"}, {}}
+	if err := assignEnv(&many, "dev"); err != nil || many[0].Env != "dev" || many[1].Env != "dev" {
+		t.Errorf("slice: %+v, err = %v", many, err)
+	}
+	if err := assignEnv(&pushRecord{}, "dev"); err != ErrNoEnvField {
+		t.Errorf("model without Env: err = %v, want ErrNoEnvField", err)
+	}
+}
@@ -29,6 +29,7 @@ const (
 	CodeForbidden        = 403
 	CodeNotFound         = 404
 	CodeConflict         = 409
+	CodePayloadTooLarge  = 413
 	CodeTooManyRequests  = 429
 	CodeInternalError    = 500
 	CodeServiceUnavailable = 503
@@ -42,6 +43,7 @@ var codeMessages = map[int]string{
 	CodeForbidden:        "Forbidden",
 	CodeNotFound:         "Not found",
 	CodeConflict:         "Conflict",
+	CodePayloadTooLarge:  "Payload too large",
 	CodeTooManyRequests:  "Too many requests",
 	CodeInternalError:    "Internal server error",
 	CodeServiceUnavailable: "Service unavailable",
@@ -65,6 +67,15 @@ func SuccessWithMessage(c *gin.Context, data interface{}, message string) {
 	})
 }
 
+// Accepted 请求已受理、在后台处理的响应，HTTP状态码为202
+func Accepted(c *gin.Context, data interface{}, message string) {
+	c.JSON(http.StatusAccepted, Response{
+		Code:    CodeSuccess,
+		Message: message,
+		Data:    data,
+	})
+}
+
 // SuccessPage 分页成功响应
 func SuccessPage(c *gin.Context, list interface{}, total int64, page, size int) {
 	c.JSON(http.StatusOK, Response{
@@ -138,6 +149,14 @@ func Conflict(c *gin.Context, message string) {
 	Error(c, CodeConflict, message)
 }
 
+// PayloadTooLarge 413错误
+func PayloadTooLarge(c *gin.Context, message string) {
+	if message == "" {
+		message = codeMessages[CodePayloadTooLarge]
+	}
+	Error(c, CodePayloadTooLarge, message)
+}
+
 // TooManyRequests 429错误
 func TooManyRequests(c *gin.Context, message string) {
 	if message == "" {
@@ -208,6 +227,8 @@ func getHTTPStatus(code int) int {
 		return http.StatusNotFound
 	case CodeConflict:
 		return http.StatusConflict
+	case CodePayloadTooLarge:
+		return http.StatusRequestEntityTooLarge
 	case CodeTooManyRequests:
 		return http.StatusTooManyRequests
 	case CodeServiceUnavailable:
@@ -0,0 +1,294 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"app-platform-backend/core/module"
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// appPurgeCoreStep 平台核心数据（应用模块及各环境配置、配置历史、密钥、环境、nonce、配额、用量和每日统计）在各模块数据之后清理
+const appPurgeCoreStep = "core"
+
+// AppPurgeConfig 已删除应用数据清理配置
+type AppPurgeConfig struct {
+	BatchSize     int           // 每批删除的记录数，默认1000
+	Interval      time.Duration // 检查待清理应用的间隔，默认10分钟
+	LeaseDuration time.Duration // 单个清理任务的执行租约，每批完成后续期，默认5分钟
+	AppsPerRun    int           // 每次检查最多处理的应用数，默认10
+}
+
+// DefaultAppPurgeConfig 默认配置
+var DefaultAppPurgeConfig = AppPurgeConfig{
+	BatchSize:     1000,
+	Interval:      10 * time.Minute,
+	LeaseDuration: 5 * time.Minute,
+	AppsPerRun:    10,
+}
+
+// AppPurgeScheduler 已删除应用数据清理调度器，恢复期满后按模块分批清理应用数据
+type AppPurgeScheduler struct {
+	db       *gorm.DB
+	config   AppPurgeConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+// purgeStep 一个清理步骤，返回0表示该步骤已清理完毕
+type purgeStep struct {
+	name  string
+	purge func(appID uint, batchSize int) (int64, error)
+}
+
+var (
+	appPurgeScheduler *AppPurgeScheduler
+	appPurgeOnce      sync.Once
+)
+
+// InitAppPurgeScheduler 初始化已删除应用数据清理调度器
+func InitAppPurgeScheduler(db *gorm.DB, config ...AppPurgeConfig) *AppPurgeScheduler {
+	appPurgeOnce.Do(func() {
+		cfg := DefaultAppPurgeConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.BatchSize <= 0 {
+			cfg.BatchSize = DefaultAppPurgeConfig.BatchSize
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultAppPurgeConfig.Interval
+		}
+		if cfg.LeaseDuration <= 0 {
+			cfg.LeaseDuration = DefaultAppPurgeConfig.LeaseDuration
+		}
+		if cfg.AppsPerRun <= 0 {
+			cfg.AppsPerRun = DefaultAppPurgeConfig.AppsPerRun
+		}
+
+		appPurgeScheduler = &AppPurgeScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[AppPurge] Scheduler initialized with config: BatchSize=%d, Interval=%s",
+			cfg.BatchSize, cfg.Interval)
+	})
+
+	return appPurgeScheduler
+}
+
+// Start 启动定时清理任务
+func (s *AppPurgeScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[AppPurge] Scheduler started")
+}
+
+// Stop 停止定时清理任务，正在执行的任务在当前批次完成后释放租约
+func (s *AppPurgeScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[AppPurge] Scheduler stopped")
+}
+
+// run 运行清理任务
+func (s *AppPurgeScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.executePurge()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.executePurge()
+		}
+	}
+}
+
+// executePurge 查找恢复期已满的应用并逐个清理
+func (s *AppPurgeScheduler) executePurge() {
+	var apps []model.App
+	if err := s.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND purge_after IS NOT NULL AND purge_after <= ?", time.Now()).
+		Order("purge_after ASC").
+		Limit(s.config.AppsPerRun).
+		Find(&apps).Error; err != nil {
+		log.Printf("[AppPurge] Failed to load apps pending purge: %v", err)
+		return
+	}
+
+	for i := range apps {
+		if s.stopping() {
+			return
+		}
+		s.purgeApp(&apps[i])
+	}
+}
+
+// purgeApp 清理单个应用：领取租约后依次执行各清理步骤，全部完成后物理删除应用
+func (s *AppPurgeScheduler) purgeApp(app *model.App) {
+	job := model.AppPurgeJob{AppID: app.ID}
+	if err := s.db.Where(model.AppPurgeJob{AppID: app.ID}).
+		Attrs(model.AppPurgeJob{Status: model.AppPurgePending, Progress: "{}"}).
+		FirstOrCreate(&job).Error; err != nil {
+		log.Printf("[AppPurge] Failed to create purge job for app %d: %v", app.ID, err)
+		return
+	}
+	if !s.claim(&job) {
+		return
+	}
+
+	progress := map[string]int64{}
+	if job.Progress != "" {
+		json.Unmarshal([]byte(job.Progress), &progress)
+	}
+
+	startTime := time.Now()
+	log.Printf("[AppPurge] Purging app %d (%s)", app.ID, app.Name)
+
+	for _, step := range s.purgeSteps() {
+		for {
+			if s.stopping() {
+				s.release(&job)
+				return
+			}
+
+			n, err := step.purge(app.ID, s.config.BatchSize)
+			if err != nil {
+				s.fail(&job, fmt.Errorf("%s: %w", step.name, err))
+				return
+			}
+			if n == 0 {
+				break
+			}
+
+			progress[step.name] += n
+			job.DeletedRows += n
+			data, _ := json.Marshal(progress)
+			lease := time.Now().Add(s.config.LeaseDuration)
+			if err := s.db.Model(&job).Updates(map[string]interface{}{
+				"current_module": step.name,
+				"deleted_rows":   job.DeletedRows,
+				"progress":       string(data),
+				"lease_until":    lease,
+			}).Error; err != nil {
+				log.Printf("[AppPurge] Failed to save progress for app %d: %v", app.ID, err)
+			}
+
+			// 短暂休眠，避免对数据库造成过大压力
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	now := time.Now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 只删除仍处于已删除状态的应用
+		if err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", app.ID).Delete(&model.App{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":         model.AppPurgeCompleted,
+			"current_module": "",
+			"error":          "",
+			"lease_until":    nil,
+			"finished_at":    now,
+		}).Error
+	})
+	if err != nil {
+		s.fail(&job, err)
+		return
+	}
+
+	log.Printf("[AppPurge] App %d purged: deleted %d rows in %dms",
+		app.ID, job.DeletedRows, time.Since(startTime).Milliseconds())
+}
+
+// purgeSteps 各模块的清理步骤按模块编码排序，平台核心数据最后清理
+func (s *AppPurgeScheduler) purgeSteps() []purgeStep {
+	var steps []purgeStep
+	for _, m := range module.GetAllModules() {
+		if p, ok := m.(module.AppDataPurger); ok {
+			steps = append(steps, purgeStep{name: m.Meta().Code, purge: p.PurgeAppData})
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].name < steps[j].name })
+
+	return append(steps, purgeStep{name: appPurgeCoreStep, purge: func(appID uint, batchSize int) (int64, error) {
+		return repository.ForApp(s.db, appID).PurgeInOrder(batchSize,
+			&model.AppModule{}, &model.AppModuleConfig{}, &model.ModuleConfigHistory{}, &model.AppCredential{},
+			&model.AppEnvironment{}, &model.SDKNonce{}, &model.AppQuota{}, &model.AppUsageCounter{}, &model.AppDailyStat{})
+	}})
+}
+
+// claim 领取任务租约，已完成或其他实例持有未过期租约时返回false
+func (s *AppPurgeScheduler) claim(job *model.AppPurgeJob) bool {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      model.AppPurgeRunning,
+		"lease_until": now.Add(s.config.LeaseDuration),
+	}
+	if job.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	result := s.db.Model(&model.AppPurgeJob{}).
+		Where("id = ? AND status <> ? AND (lease_until IS NULL OR lease_until < ?)", job.ID, model.AppPurgeCompleted, now).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("[AppPurge] Failed to claim purge job %d: %v", job.ID, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// release 停止时释放租约，下次调度从已清理的位置继续
+func (s *AppPurgeScheduler) release(job *model.AppPurgeJob) {
+	s.db.Model(job).Updates(map[string]interface{}{
+		"status":      model.AppPurgePending,
+		"lease_until": nil,
+	})
+}
+
+// fail 记录失败原因并释放租约，下次调度重试
+func (s *AppPurgeScheduler) fail(job *model.AppPurgeJob, err error) {
+	log.Printf("[AppPurge] Purge job for app %d failed: %v", job.AppID, err)
+	s.db.Model(job).Updates(map[string]interface{}{
+		"status":      model.AppPurgeFailed,
+		"error":       err.Error(),
+		"lease_until": nil,
+	})
+}
+
+func (s *AppPurgeScheduler) stopping() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,259 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/datasubject"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// DataSubjectConfig 用户数据导出和删除任务配置
+type DataSubjectConfig struct {
+	Interval       time.Duration // 检查待处理请求的间隔，默认30秒
+	LeaseDuration  time.Duration // 单个请求的执行租约，默认10分钟
+	ArchiveTTL     time.Duration // 导出归档的保留时长，默认7天
+	ArchiveDir     string        // 导出归档的存储目录，默认 /tmp/data-exports
+	RequestsPerRun int           // 每次检查最多处理的请求数，默认5
+}
+
+// DefaultDataSubjectConfig 默认配置
+var DefaultDataSubjectConfig = DataSubjectConfig{
+	Interval:       30 * time.Second,
+	LeaseDuration:  10 * time.Minute,
+	ArchiveTTL:     7 * 24 * time.Hour,
+	ArchiveDir:     "/tmp/data-exports",
+	RequestsPerRun: 5,
+}
+
+// DataSubjectScheduler 执行用户数据导出和删除请求，并清理过期的导出归档
+type DataSubjectScheduler struct {
+	db       *gorm.DB
+	config   DataSubjectConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	dataSubjectScheduler *DataSubjectScheduler
+	dataSubjectOnce      sync.Once
+)
+
+// InitDataSubjectScheduler 初始化用户数据请求调度器
+func InitDataSubjectScheduler(db *gorm.DB, config ...DataSubjectConfig) *DataSubjectScheduler {
+	dataSubjectOnce.Do(func() {
+		cfg := DefaultDataSubjectConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultDataSubjectConfig.Interval
+		}
+		if cfg.LeaseDuration <= 0 {
+			cfg.LeaseDuration = DefaultDataSubjectConfig.LeaseDuration
+		}
+		if cfg.ArchiveTTL <= 0 {
+			cfg.ArchiveTTL = DefaultDataSubjectConfig.ArchiveTTL
+		}
+		if cfg.ArchiveDir == "" {
+			cfg.ArchiveDir = DefaultDataSubjectConfig.ArchiveDir
+		}
+		if cfg.RequestsPerRun <= 0 {
+			cfg.RequestsPerRun = DefaultDataSubjectConfig.RequestsPerRun
+		}
+
+		dataSubjectScheduler = &DataSubjectScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[DataSubject] Scheduler initialized with config: Interval=%v, ArchiveTTL=%v, RequestsPerRun=%d",
+			cfg.Interval, cfg.ArchiveTTL, cfg.RequestsPerRun)
+	})
+
+	return dataSubjectScheduler
+}
+
+// Start 启动定时任务
+func (s *DataSubjectScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[DataSubject] Scheduler started")
+}
+
+// Stop 停止定时任务，正在执行的请求完成后退出
+func (s *DataSubjectScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[DataSubject] Scheduler stopped")
+}
+
+// run 运行定时任务
+func (s *DataSubjectScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.execute()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.execute()
+		}
+	}
+}
+
+// execute 处理待执行的请求，失败或租约过期的请求会被重新执行，然后清理过期归档
+func (s *DataSubjectScheduler) execute() {
+	var requests []model.DataSubjectRequest
+	if err := s.db.
+		Where("status IN ? AND (lease_until IS NULL OR lease_until < ?)",
+			[]string{model.DataRequestPending, model.DataRequestRunning, model.DataRequestFailed}, time.Now()).
+		Order("id ASC").
+		Limit(s.config.RequestsPerRun).
+		Find(&requests).Error; err != nil {
+		log.Printf("[DataSubject] Failed to load pending requests: %v", err)
+		return
+	}
+
+	for i := range requests {
+		if s.stopping() {
+			return
+		}
+		if s.claim(&requests[i]) {
+			s.process(&requests[i])
+		}
+	}
+
+	s.cleanupArchives()
+}
+
+// process 执行单个请求，删除请求完成后清空数据主体快照，只保留报告
+func (s *DataSubjectScheduler) process(req *model.DataSubjectRequest) {
+	subject, err := datasubject.DecodeSubject(req.Subject)
+	if err != nil {
+		s.fail(req, fmt.Errorf("invalid subject: %w", err))
+		return
+	}
+
+	startTime := time.Now()
+	var report *datasubject.Report
+	updates := map[string]interface{}{}
+	switch req.Type {
+	case model.DataRequestExport:
+		archivePath := filepath.Join(s.config.ArchiveDir, fmt.Sprintf("app_%d", req.AppID), fmt.Sprintf("request_%d.zip", req.ID))
+		var size int64
+		report, size, err = datasubject.Export(req, subject, archivePath)
+		updates["archive_path"] = archivePath
+		updates["archive_size"] = size
+		updates["expires_at"] = time.Now().Add(s.config.ArchiveTTL)
+	case model.DataRequestErase:
+		report, err = datasubject.Erase(req, subject)
+		updates["subject"] = nil
+	default:
+		err = fmt.Errorf("unknown request type %q", req.Type)
+	}
+	if err != nil {
+		s.fail(req, err)
+		return
+	}
+
+	data, hash, err := report.Seal()
+	if err != nil {
+		s.fail(req, err)
+		return
+	}
+	updates["status"] = model.DataRequestCompleted
+	updates["report"] = data
+	updates["report_hash"] = hash
+	updates["error"] = ""
+	updates["lease_until"] = nil
+	updates["finished_at"] = time.Now()
+	if err := s.db.Model(req).Updates(updates).Error; err != nil {
+		log.Printf("[DataSubject] Failed to save request %d: %v", req.ID, err)
+		return
+	}
+
+	log.Printf("[DataSubject] Request %d (%s, app %d, user %d) completed in %dms",
+		req.ID, req.Type, req.AppID, req.UserID, time.Since(startTime).Milliseconds())
+}
+
+// claim 领取请求租约，其他实例持有未过期租约时返回false
+func (s *DataSubjectScheduler) claim(req *model.DataSubjectRequest) bool {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      model.DataRequestRunning,
+		"lease_until": now.Add(s.config.LeaseDuration),
+	}
+	if req.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	result := s.db.Model(&model.DataSubjectRequest{}).
+		Where("id = ? AND status <> ? AND (lease_until IS NULL OR lease_until < ?)", req.ID, model.DataRequestCompleted, now).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("[DataSubject] Failed to claim request %d: %v", req.ID, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// fail 记录失败原因，租约到期后重试；各模块的删除可重复执行
+func (s *DataSubjectScheduler) fail(req *model.DataSubjectRequest, err error) {
+	log.Printf("[DataSubject] Request %d failed: %v", req.ID, err)
+	s.db.Model(req).Updates(map[string]interface{}{
+		"status": model.DataRequestFailed,
+		"error":  err.Error(),
+	})
+}
+
+// cleanupArchives 删除过期的导出归档
+func (s *DataSubjectScheduler) cleanupArchives() {
+	var requests []model.DataSubjectRequest
+	if err := s.db.
+		Where("type = ? AND archive_path <> '' AND expires_at < ?", model.DataRequestExport, time.Now()).
+		Limit(100).
+		Find(&requests).Error; err != nil {
+		log.Printf("[DataSubject] Failed to load expired archives: %v", err)
+		return
+	}
+	for _, req := range requests {
+		if err := os.Remove(req.ArchivePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("[DataSubject] Failed to remove archive %s: %v", req.ArchivePath, err)
+			continue
+		}
+		s.db.Model(&req).Update("archive_path", "")
+	}
+}
+
+func (s *DataSubjectScheduler) stopping() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pusher"
+
+	"gorm.io/gorm"
+)
+
+// PushDispatchConfig 定时推送调度配置
+type PushDispatchConfig struct {
+	Interval     time.Duration // 检查到期推送的间隔，默认15秒
+	PushesPerRun int           // 每次检查最多认领的推送数，认领的推送并发发送，默认10
+}
+
+// DefaultPushDispatchConfig 默认配置
+var DefaultPushDispatchConfig = PushDispatchConfig{
+	Interval:     15 * time.Second,
+	PushesPerRun: 10,
+}
+
+// PushDispatchScheduler 发送到期的定时和周期推送，并从断点接手租约过期的发送中推送
+type PushDispatchScheduler struct {
+	db       *gorm.DB
+	config   PushDispatchConfig
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	pushDispatchScheduler *PushDispatchScheduler
+	pushDispatchOnce      sync.Once
+)
+
+// InitPushDispatchScheduler 初始化定时推送调度器
+func InitPushDispatchScheduler(db *gorm.DB, config ...PushDispatchConfig) *PushDispatchScheduler {
+	pushDispatchOnce.Do(func() {
+		cfg := DefaultPushDispatchConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultPushDispatchConfig.Interval
+		}
+		if cfg.PushesPerRun <= 0 {
+			cfg.PushesPerRun = DefaultPushDispatchConfig.PushesPerRun
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pushDispatchScheduler = &PushDispatchScheduler{
+			db:       db,
+			config:   cfg,
+			ctx:      ctx,
+			cancel:   cancel,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[PushDispatch] Scheduler initialized with config: Interval=%v, PushesPerRun=%d",
+			cfg.Interval, cfg.PushesPerRun)
+	})
+
+	return pushDispatchScheduler
+}
+
+// GetPushDispatchScheduler 获取定时推送调度器实例
+func GetPushDispatchScheduler() *PushDispatchScheduler {
+	return pushDispatchScheduler
+}
+
+// Running 调度器是否在运行
+func (s *PushDispatchScheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Dispatch 在后台发送已认领的推送，与到期推送共用调度器的生命周期；
+// 调度器已停止时返回 false，推送保持发送中，租约过期后由其他实例接手
+func (s *PushDispatchScheduler) Dispatch(record model.PushRecord) bool {
+	if !s.Running() {
+		return false
+	}
+	go s.deliver(&record)
+	return true
+}
+
+// Start 启动定时任务
+func (s *PushDispatchScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[PushDispatch] Scheduler started")
+}
+
+// Stop 停止定时任务，发送中的推送在当前批次后释放租约，由其他实例或重启后继续
+func (s *PushDispatchScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.cancel()
+	s.running = false
+	log.Printf("[PushDispatch] Scheduler stopped")
+}
+
+// run 运行定时任务
+func (s *PushDispatchScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.execute()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.execute()
+		}
+	}
+}
+
+// execute 认领到期的推送并发发送，全部完成后返回，避免同一实例重复认领
+func (s *PushDispatchScheduler) execute() {
+	records, err := pusher.ClaimDue(s.db, time.Now(), s.config.PushesPerRun)
+	if err != nil {
+		log.Printf("[PushDispatch] Failed to claim due pushes: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for i := range records {
+		wg.Add(1)
+		go func(record *model.PushRecord) {
+			defer wg.Done()
+			s.deliver(record)
+		}(&records[i])
+	}
+	wg.Wait()
+}
+
+// deliver 发送一条已认领的推送，结果写回推送记录
+func (s *PushDispatchScheduler) deliver(record *model.PushRecord) {
+	_, err := pusher.Deliver(s.ctx, s.db, record)
+	switch {
+	case err == nil, errors.Is(err, pusher.ErrCancelled), errors.Is(err, context.Canceled):
+	default:
+		log.Printf("[PushDispatch] Push %d of app %d failed: %v", record.ID, record.AppID, err)
+	}
+}
@@ -0,0 +1,137 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/segment"
+
+	"gorm.io/gorm"
+)
+
+// SegmentRefreshConfig 用户分群定时刷新配置
+type SegmentRefreshConfig struct {
+	Interval       time.Duration // 检查间隔，默认10分钟
+	MaxAge         time.Duration // 分群成员超过该时长未刷新时重新物化，默认1小时
+	SegmentsPerRun int           // 每次检查最多刷新的分群数，默认50
+}
+
+// DefaultSegmentRefreshConfig 默认配置
+var DefaultSegmentRefreshConfig = SegmentRefreshConfig{
+	Interval:       10 * time.Minute,
+	MaxAge:         time.Hour,
+	SegmentsPerRun: 50,
+}
+
+// SegmentRefreshScheduler 定时重新物化用户分群成员，使行为类条件随时间滚动
+type SegmentRefreshScheduler struct {
+	db       *gorm.DB
+	config   SegmentRefreshConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	segmentRefreshScheduler *SegmentRefreshScheduler
+	segmentRefreshOnce      sync.Once
+)
+
+// InitSegmentRefreshScheduler 初始化用户分群刷新调度器
+func InitSegmentRefreshScheduler(db *gorm.DB, config ...SegmentRefreshConfig) *SegmentRefreshScheduler {
+	segmentRefreshOnce.Do(func() {
+		cfg := DefaultSegmentRefreshConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultSegmentRefreshConfig.Interval
+		}
+		if cfg.MaxAge <= 0 {
+			cfg.MaxAge = DefaultSegmentRefreshConfig.MaxAge
+		}
+		if cfg.SegmentsPerRun <= 0 {
+			cfg.SegmentsPerRun = DefaultSegmentRefreshConfig.SegmentsPerRun
+		}
+
+		segmentRefreshScheduler = &SegmentRefreshScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[SegmentRefresh] Scheduler initialized with config: Interval=%v, MaxAge=%v, SegmentsPerRun=%d",
+			cfg.Interval, cfg.MaxAge, cfg.SegmentsPerRun)
+	})
+
+	return segmentRefreshScheduler
+}
+
+// Start 启动定时刷新任务
+func (s *SegmentRefreshScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[SegmentRefresh] Scheduler started")
+}
+
+// Stop 停止定时刷新任务
+func (s *SegmentRefreshScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[SegmentRefresh] Scheduler stopped")
+}
+
+func (s *SegmentRefreshScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.executeRefresh()
+		}
+	}
+}
+
+// executeRefresh 按最久未刷新优先，重新物化过期的分群
+func (s *SegmentRefreshScheduler) executeRefresh() {
+	cutoff := time.Now().Add(-s.config.MaxAge)
+
+	var segments []model.UserSegment
+	if err := s.db.Where("evaluated_at IS NULL OR evaluated_at < ?", cutoff).
+		Order("evaluated_at").Limit(s.config.SegmentsPerRun).Find(&segments).Error; err != nil {
+		log.Printf("[SegmentRefresh] Failed to load segments: %v", err)
+		return
+	}
+
+	refreshed := 0
+	for i := range segments {
+		if _, err := segment.Materialize(s.db, &segments[i]); err != nil {
+			log.Printf("[SegmentRefresh] Failed to refresh segment %d: %v", segments[i].ID, err)
+			continue
+		}
+		refreshed++
+	}
+
+	if refreshed > 0 {
+		log.Printf("[SegmentRefresh] Refreshed %d segments", refreshed)
+	}
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatsRollupConfig 每日统计汇总配置
+type StatsRollupConfig struct {
+	Interval     time.Duration // 刷新当天和前一天汇总的间隔，默认5分钟
+	BackfillDays int           // 启动时回填的天数，默认30天
+}
+
+// DefaultStatsRollupConfig 默认配置
+var DefaultStatsRollupConfig = StatsRollupConfig{
+	Interval:     5 * time.Minute,
+	BackfillDays: 30,
+}
+
+// statsRollups 各统计项的汇总语句：按应用统计 [start, end) 内的明细并写入 app_daily_stats 的对应字段
+var statsRollups = []struct {
+	column string
+	query  string
+}{
+	{"new_users", "SELECT app_id, COUNT(*) AS n FROM users WHERE created_at >= ? AND created_at < ? AND deleted_at IS NULL GROUP BY app_id"},
+	{"events", "SELECT app_id, COUNT(*) AS n FROM events WHERE created_at >= ? AND created_at < ? GROUP BY app_id"},
+	{"errors", "SELECT app_id, COUNT(*) AS n FROM logs WHERE created_at >= ? AND created_at < ? AND level = 'error' GROUP BY app_id"},
+	{"pushes_sent", "SELECT app_id, COALESCE(SUM(sent_count), 0) AS n FROM push_records WHERE sent_at >= ? AND sent_at < ? AND deleted_at IS NULL GROUP BY app_id"},
+}
+
+// StatsRollupScheduler 将用户、事件、日志和推送明细按应用和日期汇总到 app_daily_stats
+// 控制台首页只读取汇总表，避免每次打开都扫描明细表
+type StatsRollupScheduler struct {
+	db       *gorm.DB
+	config   StatsRollupConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	statsRollupScheduler *StatsRollupScheduler
+	statsRollupOnce      sync.Once
+)
+
+// InitStatsRollupScheduler 初始化每日统计汇总调度器
+func InitStatsRollupScheduler(db *gorm.DB, config ...StatsRollupConfig) *StatsRollupScheduler {
+	statsRollupOnce.Do(func() {
+		cfg := DefaultStatsRollupConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultStatsRollupConfig.Interval
+		}
+		if cfg.BackfillDays <= 0 {
+			cfg.BackfillDays = DefaultStatsRollupConfig.BackfillDays
+		}
+
+		statsRollupScheduler = &StatsRollupScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[StatsRollup] Scheduler initialized with config: Interval=%s, BackfillDays=%d",
+			cfg.Interval, cfg.BackfillDays)
+	})
+
+	return statsRollupScheduler
+}
+
+// Start 启动定时汇总任务，启动时先回填最近的汇总数据
+func (s *StatsRollupScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[StatsRollup] Scheduler started")
+}
+
+// Stop 停止定时汇总任务
+func (s *StatsRollupScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[StatsRollup] Scheduler stopped")
+}
+
+func (s *StatsRollupScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.rollupRecent(s.config.BackfillDays)
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			// 同时刷新前一天，补上跨零点写入的数据
+			s.rollupRecent(2)
+		}
+	}
+}
+
+// rollupRecent 重新汇总包括今天在内的最近 days 天
+func (s *StatsRollupScheduler) rollupRecent(days int) {
+	startTime := time.Now()
+	today := time.Date(startTime.Year(), startTime.Month(), startTime.Day(), 0, 0, 0, 0, startTime.Location())
+
+	for i := days - 1; i >= 0; i-- {
+		select {
+		case <-s.stopChan:
+			return
+		default:
+		}
+		day := today.AddDate(0, 0, -i)
+		if err := s.rollupDay(day); err != nil {
+			log.Printf("[StatsRollup] Failed to roll up %s: %v", day.Format("2006-01-02"), err)
+		}
+	}
+
+	if days > 2 {
+		log.Printf("[StatsRollup] Rolled up %d days in %dms", days, time.Since(startTime).Milliseconds())
+	}
+}
+
+// rollupDay 重新汇总某一天，已有的汇总行按最新统计覆盖
+func (s *StatsRollupScheduler) rollupDay(day time.Time) error {
+	end := day.AddDate(0, 0, 1)
+	date := day.Format("2006-01-02")
+	for _, r := range statsRollups {
+		sql := "INSERT INTO app_daily_stats (app_id, date, " + r.column + ", updated_at) " +
+			"SELECT t.app_id, ?, t.n, NOW() FROM (" + r.query + ") AS t " +
+			"ON DUPLICATE KEY UPDATE " + r.column + " = VALUES(" + r.column + "), updated_at = VALUES(updated_at)"
+		if err := s.db.Exec(sql, date, day, end).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UsageCleanupConfig 配额用量计数清理配置
+type UsageCleanupConfig struct {
+	RetentionHours int           // 用量计数保留时长，默认48小时（保留前一天的按天用量）
+	BatchSize      int           // 每批删除的记录数，默认1000
+	Interval       time.Duration // 清理间隔，默认1小时
+}
+
+// DefaultUsageCleanupConfig 默认配置
+var DefaultUsageCleanupConfig = UsageCleanupConfig{
+	RetentionHours: 48,
+	BatchSize:      1000,
+	Interval:       time.Hour,
+}
+
+// UsageCleanupScheduler 清理过期的配额用量计数，按分钟统计的配额项每个应用每天产生大量窗口记录
+type UsageCleanupScheduler struct {
+	db       *gorm.DB
+	config   UsageCleanupConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	usageCleanupScheduler *UsageCleanupScheduler
+	usageCleanupOnce      sync.Once
+)
+
+// InitUsageCleanupScheduler 初始化配额用量计数清理调度器
+func InitUsageCleanupScheduler(db *gorm.DB, config ...UsageCleanupConfig) *UsageCleanupScheduler {
+	usageCleanupOnce.Do(func() {
+		cfg := DefaultUsageCleanupConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+
+		usageCleanupScheduler = &UsageCleanupScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[UsageCleanup] Scheduler initialized with config: RetentionHours=%d, BatchSize=%d",
+			cfg.RetentionHours, cfg.BatchSize)
+	})
+
+	return usageCleanupScheduler
+}
+
+// Start 启动定时清理任务
+func (s *UsageCleanupScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[UsageCleanup] Scheduler started")
+}
+
+// Stop 停止定时清理任务
+func (s *UsageCleanupScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[UsageCleanup] Scheduler stopped")
+}
+
+func (s *UsageCleanupScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.executeCleanup()
+		}
+	}
+}
+
+// executeCleanup 分批删除过期窗口的用量计数
+func (s *UsageCleanupScheduler) executeCleanup() {
+	cutoff := time.Now().Add(-time.Duration(s.config.RetentionHours) * time.Hour)
+
+	var totalDeleted int64
+	for {
+		result := s.db.Exec(
+			"DELETE FROM app_usage_counters WHERE window_start < ? LIMIT ?",
+			cutoff, s.config.BatchSize,
+		)
+		if result.Error != nil {
+			log.Printf("[UsageCleanup] Error during cleanup: %v", result.Error)
+			break
+		}
+
+		totalDeleted += result.RowsAffected
+		if result.RowsAffected < int64(s.config.BatchSize) {
+			break
+		}
+
+		// 短暂休眠，避免对数据库造成过大压力
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if totalDeleted > 0 {
+		log.Printf("[UsageCleanup] Deleted %d expired usage counters", totalDeleted)
+	}
+}
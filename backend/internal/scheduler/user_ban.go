@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/userban"
+)
+
+// UserBanExpiryConfig 封禁到期解除配置
+type UserBanExpiryConfig struct {
+	Interval     time.Duration // 检查间隔，默认1分钟
+	UsersPerRun  int           // 每次检查最多解除的用户数，默认500
+	SyncInterval time.Duration // 同步其他实例封禁事件的间隔，默认2秒
+}
+
+// DefaultUserBanExpiryConfig 默认配置
+var DefaultUserBanExpiryConfig = UserBanExpiryConfig{
+	Interval:     time.Minute,
+	UsersPerRun:  500,
+	SyncInterval: 2 * time.Second,
+}
+
+// UserBanExpiryScheduler 定时解除已到期的用户封禁并记录审计日志，同时同步其他实例发布的封禁事件
+type UserBanExpiryScheduler struct {
+	config   UserBanExpiryConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	userBanExpiryScheduler *UserBanExpiryScheduler
+	userBanExpiryOnce      sync.Once
+)
+
+// InitUserBanExpiryScheduler 初始化封禁到期解除调度器，依赖 userban.Init
+func InitUserBanExpiryScheduler(config ...UserBanExpiryConfig) *UserBanExpiryScheduler {
+	userBanExpiryOnce.Do(func() {
+		cfg := DefaultUserBanExpiryConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultUserBanExpiryConfig.Interval
+		}
+		if cfg.UsersPerRun <= 0 {
+			cfg.UsersPerRun = DefaultUserBanExpiryConfig.UsersPerRun
+		}
+		if cfg.SyncInterval <= 0 {
+			cfg.SyncInterval = DefaultUserBanExpiryConfig.SyncInterval
+		}
+
+		userBanExpiryScheduler = &UserBanExpiryScheduler{
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[UserBanExpiry] Scheduler initialized with config: Interval=%v, UsersPerRun=%d, SyncInterval=%v",
+			cfg.Interval, cfg.UsersPerRun, cfg.SyncInterval)
+	})
+
+	return userBanExpiryScheduler
+}
+
+// Start 启动定时任务
+func (s *UserBanExpiryScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[UserBanExpiry] Scheduler started")
+}
+
+// Stop 停止定时任务
+func (s *UserBanExpiryScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[UserBanExpiry] Scheduler stopped")
+}
+
+func (s *UserBanExpiryScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	syncTicker := time.NewTicker(s.config.SyncInterval)
+	defer syncTicker.Stop()
+
+	s.sync()
+	s.execute()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.execute()
+		case <-syncTicker.C:
+			s.sync()
+		}
+	}
+}
+
+// sync 处理其他实例的封禁变更，断开被封禁用户在本实例上的连接
+func (s *UserBanExpiryScheduler) sync() {
+	if err := userban.SyncEvents(); err != nil {
+		log.Printf("[UserBanExpiry] Failed to sync ban events: %v", err)
+	}
+}
+
+// execute 解除到期的封禁，每个用户记录一条系统审计日志
+func (s *UserBanExpiryScheduler) execute() {
+	users, err := userban.LiftExpired(time.Now(), s.config.UsersPerRun)
+	if err != nil {
+		log.Printf("[UserBanExpiry] Failed to lift expired bans: %v", err)
+	}
+
+	for _, u := range users {
+		middleware.RecordSystemAuditEvent(u.AppID, "unban", "app_user", strconv.Itoa(int(u.ID)), "封禁到期自动解除", map[string]interface{}{
+			"app_id":       u.AppID,
+			"open_id":      u.OpenID,
+			"reason":       u.BanReason,
+			"banned_until": u.BannedUntil,
+		})
+	}
+
+	if len(users) > 0 {
+		log.Printf("[UserBanExpiry] Lifted %d expired bans", len(users))
+	}
+
+	if _, err := userban.PruneEvents(time.Now()); err != nil {
+		log.Printf("[UserBanExpiry] Failed to prune ban events: %v", err)
+	}
+}
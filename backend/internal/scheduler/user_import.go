@@ -0,0 +1,329 @@
+package scheduler
+
+import (
+	"encoding/csv"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/userimport"
+
+	"gorm.io/gorm"
+)
+
+// errImportStopping 调度器停止，任务在当前批次完成后释放租约
+var errImportStopping = errors.New("scheduler stopping")
+
+// UserImportConfig 用户导入任务配置
+type UserImportConfig struct {
+	Interval      time.Duration // 检查待执行任务的间隔，默认15秒
+	LeaseDuration time.Duration // 任务执行租约，每批完成后续期，默认5分钟
+	BatchSize     int           // 每批写入的行数，默认500
+	ReportTTL     time.Duration // 错误报告的保留时长，默认7天
+	JobsPerRun    int           // 每次检查最多执行的任务数，默认3
+}
+
+// DefaultUserImportConfig 默认配置
+var DefaultUserImportConfig = UserImportConfig{
+	Interval:      15 * time.Second,
+	LeaseDuration: 5 * time.Minute,
+	BatchSize:     500,
+	ReportTTL:     7 * 24 * time.Hour,
+	JobsPerRun:    3,
+}
+
+// UserImportScheduler 执行用户导入任务，按已处理行数断点续跑，并清理过期的错误报告
+type UserImportScheduler struct {
+	db       *gorm.DB
+	config   UserImportConfig
+	stopChan chan struct{}
+	running  bool
+	mu       sync.Mutex
+}
+
+var (
+	userImportScheduler *UserImportScheduler
+	userImportOnce      sync.Once
+)
+
+// InitUserImportScheduler 初始化用户导入调度器
+func InitUserImportScheduler(db *gorm.DB, config ...UserImportConfig) *UserImportScheduler {
+	userImportOnce.Do(func() {
+		cfg := DefaultUserImportConfig
+		if len(config) > 0 {
+			cfg = config[0]
+		}
+		if cfg.Interval <= 0 {
+			cfg.Interval = DefaultUserImportConfig.Interval
+		}
+		if cfg.LeaseDuration <= 0 {
+			cfg.LeaseDuration = DefaultUserImportConfig.LeaseDuration
+		}
+		if cfg.BatchSize <= 0 {
+			cfg.BatchSize = DefaultUserImportConfig.BatchSize
+		}
+		if cfg.ReportTTL <= 0 {
+			cfg.ReportTTL = DefaultUserImportConfig.ReportTTL
+		}
+		if cfg.JobsPerRun <= 0 {
+			cfg.JobsPerRun = DefaultUserImportConfig.JobsPerRun
+		}
+
+		userImportScheduler = &UserImportScheduler{
+			db:       db,
+			config:   cfg,
+			stopChan: make(chan struct{}),
+		}
+
+		log.Printf("[UserImport] Scheduler initialized with config: Interval=%v, BatchSize=%d, ReportTTL=%v",
+			cfg.Interval, cfg.BatchSize, cfg.ReportTTL)
+	})
+
+	return userImportScheduler
+}
+
+// Start 启动定时任务
+func (s *UserImportScheduler) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go s.run()
+	log.Printf("[UserImport] Scheduler started")
+}
+
+// Stop 停止定时任务，正在执行的任务在当前批次完成后释放租约
+func (s *UserImportScheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+
+	close(s.stopChan)
+	s.running = false
+	log.Printf("[UserImport] Scheduler stopped")
+}
+
+// run 运行定时任务
+func (s *UserImportScheduler) run() {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	s.execute()
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.execute()
+		}
+	}
+}
+
+// execute 执行待处理和租约过期的任务，然后清理过期的错误报告
+func (s *UserImportScheduler) execute() {
+	var jobs []model.UserImportJob
+	if err := s.db.
+		Where("status IN ? AND (lease_until IS NULL OR lease_until < ?)",
+			[]string{model.UserImportPending, model.UserImportRunning}, time.Now()).
+		Order("id ASC").
+		Limit(s.config.JobsPerRun).
+		Find(&jobs).Error; err != nil {
+		log.Printf("[UserImport] Failed to load pending jobs: %v", err)
+		return
+	}
+
+	for i := range jobs {
+		if s.stopping() {
+			return
+		}
+		if s.claim(&jobs[i]) {
+			s.process(&jobs[i])
+		}
+	}
+
+	s.cleanupReports()
+}
+
+// process 执行单个导入任务
+func (s *UserImportScheduler) process(job *model.UserImportJob) {
+	mapping, err := userimport.ParseMapping(job.Mapping)
+	if err != nil {
+		s.fail(job, err)
+		return
+	}
+	if job.TotalRows == 0 {
+		total, err := userimport.CountRows(job.FilePath, job.Format)
+		if err != nil {
+			s.fail(job, err)
+			return
+		}
+		job.TotalRows = total
+		s.db.Model(job).Update("total_rows", total)
+	}
+
+	// 从头开始时重建错误报告，续跑时追加
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if job.ProcessedRows == 0 {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	report, err := os.OpenFile(job.ErrorReportPath, flags, 0644)
+	if err != nil {
+		s.fail(job, err)
+		return
+	}
+	defer report.Close()
+	if job.ProcessedRows == 0 {
+		w := csv.NewWriter(report)
+		w.Write(userimport.ErrorReportHeader)
+		w.Flush()
+	}
+
+	startTime := time.Now()
+	opts := userimport.Options{
+		Format:     job.Format,
+		Mapping:    mapping,
+		OnConflict: job.OnConflict,
+		DryRun:     job.DryRun,
+	}
+	resume := userimport.Progress{
+		Processed: job.ProcessedRows,
+		Created:   job.CreatedRows,
+		Updated:   job.UpdatedRows,
+		Skipped:   job.SkippedRows,
+		Failed:    job.FailedRows,
+	}
+	progress, err := userimport.Run(s.db, job.AppID, job.FilePath, opts, resume, s.config.BatchSize, report,
+		func(p userimport.Progress) error {
+			if err := s.db.Model(job).Updates(progressUpdates(p, map[string]interface{}{
+				"lease_until": time.Now().Add(s.config.LeaseDuration),
+			})).Error; err != nil {
+				return err
+			}
+			if s.stopping() {
+				return errImportStopping
+			}
+			return nil
+		})
+	if errors.Is(err, errImportStopping) {
+		s.db.Model(job).Updates(map[string]interface{}{
+			"status":      model.UserImportPending,
+			"lease_until": nil,
+		})
+		return
+	}
+	if err != nil {
+		s.db.Model(job).Updates(progressUpdates(progress, map[string]interface{}{}))
+		s.fail(job, err)
+		return
+	}
+
+	updates := progressUpdates(progress, map[string]interface{}{
+		"status":      model.UserImportCompleted,
+		"file_path":   "",
+		"error":       "",
+		"lease_until": nil,
+		"finished_at": time.Now(),
+	})
+	if progress.Failed == 0 {
+		report.Close()
+		os.Remove(job.ErrorReportPath)
+		updates["error_report_path"] = ""
+	}
+	if err := s.db.Model(job).Updates(updates).Error; err != nil {
+		log.Printf("[UserImport] Failed to save job %d: %v", job.ID, err)
+		return
+	}
+	os.Remove(job.FilePath)
+
+	log.Printf("[UserImport] Job %d (app %d, dry_run=%v) completed in %dms: created=%d updated=%d skipped=%d failed=%d",
+		job.ID, job.AppID, job.DryRun, time.Since(startTime).Milliseconds(),
+		progress.Created, progress.Updated, progress.Skipped, progress.Failed)
+}
+
+// progressUpdates 将导入进度合并到要更新的列
+func progressUpdates(p userimport.Progress, updates map[string]interface{}) map[string]interface{} {
+	updates["processed_rows"] = p.Processed
+	updates["created_rows"] = p.Created
+	updates["updated_rows"] = p.Updated
+	updates["skipped_rows"] = p.Skipped
+	updates["failed_rows"] = p.Failed
+	return updates
+}
+
+// claim 领取任务租约，其他实例持有未过期租约时返回false
+func (s *UserImportScheduler) claim(job *model.UserImportJob) bool {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      model.UserImportRunning,
+		"lease_until": now.Add(s.config.LeaseDuration),
+	}
+	if job.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	result := s.db.Model(&model.UserImportJob{}).
+		Where("id = ? AND status IN ? AND (lease_until IS NULL OR lease_until < ?)",
+			job.ID, []string{model.UserImportPending, model.UserImportRunning}, now).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("[UserImport] Failed to claim job %d: %v", job.ID, result.Error)
+		return false
+	}
+	return result.RowsAffected == 1
+}
+
+// fail 记录失败原因，文件无法读取等错误重试无意义，任务不再自动执行
+func (s *UserImportScheduler) fail(job *model.UserImportJob, err error) {
+	log.Printf("[UserImport] Job %d failed: %v", job.ID, err)
+	s.db.Model(job).Updates(map[string]interface{}{
+		"status":      model.UserImportFailed,
+		"error":       err.Error(),
+		"lease_until": nil,
+		"finished_at": time.Now(),
+	})
+}
+
+// cleanupReports 删除过期的错误报告和失败任务遗留的上传文件
+func (s *UserImportScheduler) cleanupReports() {
+	var jobs []model.UserImportJob
+	if err := s.db.
+		Where("status IN ? AND finished_at < ? AND (error_report_path <> '' OR file_path <> '')",
+			[]string{model.UserImportCompleted, model.UserImportFailed}, time.Now().Add(-s.config.ReportTTL)).
+		Limit(100).
+		Find(&jobs).Error; err != nil {
+		log.Printf("[UserImport] Failed to load expired reports: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		for _, path := range []string{job.ErrorReportPath, job.FilePath} {
+			if path == "" {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("[UserImport] Failed to remove %s: %v", path, err)
+			}
+		}
+		s.db.Model(&job).Updates(map[string]interface{}{
+			"error_report_path": "",
+			"file_path":         "",
+		})
+	}
+}
+
+func (s *UserImportScheduler) stopping() bool {
+	select {
+	case <-s.stopChan:
+		return true
+	default:
+		return false
+	}
+}
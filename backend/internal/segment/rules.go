@@ -0,0 +1,332 @@
+// Package segment 用户分群规则引擎
+// 分群规则由用户属性、事件行为和标签条件组成，可嵌套条件组，编译为 users 表上的SQL条件，
+// 用于预估分群规模、物化分群成员，以及为推送、消息和版本灰度解析目标用户
+package segment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 条件类型
+const (
+	CondAttribute = "attribute" // 用户属性
+	CondEvent     = "event"     // 事件行为：一段时间内某事件的次数
+	CondTag       = "tag"       // 是否拥有标签
+	CondGroup     = "group"     // 嵌套条件组
+)
+
+// 条件组匹配方式
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+const (
+	maxDepth         = 3
+	maxConditions    = 20
+	maxWithinDays    = 365
+	maxPropertyCount = 5
+)
+
+// ErrInvalidRules 分群规则不合法
+var ErrInvalidRules = errors.New("分群规则不合法")
+
+// propertyPattern 事件属性名，拼接为JSON路径前校验
+var propertyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// attributeFields 允许作为条件的用户属性及其类型
+var attributeFields = map[string]string{
+	"status":        "number",
+	"source":        "string",
+	"nickname":      "string",
+	"email":         "string",
+	"phone":         "string",
+	"open_id":       "string",
+	"created_at":    "time",
+	"last_login_at": "time",
+}
+
+// comparisonOps 比较运算符
+var comparisonOps = map[string]string{
+	"eq":  "=",
+	"neq": "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// Rules 分群规则，根节点是一个条件组
+//
+//	{"match": "all", "conditions": [
+//	  {"type": "event", "event_code": "purchase", "op": "gte", "count": 2, "within_days": 30},
+//	  {"type": "event", "event_code": "app_open", "op": "gte", "count": 1, "within_days": 30, "properties": {"platform": "ios"}}
+//	]}
+type Rules struct {
+	Match      string      `json:"match"`
+	Conditions []Condition `json:"conditions"`
+}
+
+// Condition 单个条件
+type Condition struct {
+	Type string `json:"type"`
+	Op   string `json:"op,omitempty"`
+
+	// 用户属性条件
+	Field string      `json:"field,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	// 事件条件：within_days 天内 event_code 事件的次数与 count 比较，properties 按事件属性等值过滤
+	EventCode  string            `json:"event_code,omitempty"`
+	Count      int               `json:"count,omitempty"`
+	WithinDays int               `json:"within_days,omitempty"`
+	Env        string            `json:"env,omitempty"` // 为空时统计全部环境
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// 标签条件，op 为 has 或 not_has
+	TagID uint `json:"tag_id,omitempty"`
+
+	// 嵌套条件组
+	Match      string      `json:"match,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Parse 解析并校验分群规则
+func Parse(raw string) (*Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// Validate 校验规则结构、字段和运算符
+func (r *Rules) Validate() error {
+	count := 0
+	return validateGroup(r.Match, r.Conditions, 1, &count)
+}
+
+func validateGroup(match string, conds []Condition, depth int, count *int) error {
+	if depth > maxDepth {
+		return invalid("条件组最多嵌套%d层", maxDepth)
+	}
+	if match != "" && match != MatchAll && match != MatchAny {
+		return invalid("match 只能是 all 或 any")
+	}
+	if len(conds) == 0 {
+		return invalid("条件组不能为空")
+	}
+	for i := range conds {
+		*count++
+		if *count > maxConditions {
+			return invalid("条件总数不能超过%d个", maxConditions)
+		}
+		if err := validateCondition(&conds[i], depth, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCondition(c *Condition, depth int, count *int) error {
+	switch c.Type {
+	case CondAttribute:
+		kind, ok := attributeFields[c.Field]
+		if !ok {
+			return invalid("不支持的用户属性 %q", c.Field)
+		}
+		return validateAttributeOp(c, kind)
+	case CondEvent:
+		if c.EventCode == "" || len(c.EventCode) > 100 {
+			return invalid("事件条件缺少 event_code")
+		}
+		if _, ok := comparisonOps[c.Op]; !ok {
+			return invalid("事件条件不支持运算符 %q", c.Op)
+		}
+		if c.Count < 0 {
+			return invalid("事件次数不能为负数")
+		}
+		if c.WithinDays <= 0 || c.WithinDays > maxWithinDays {
+			return invalid("within_days 应在1-%d之间", maxWithinDays)
+		}
+		if len(c.Properties) > maxPropertyCount {
+			return invalid("事件属性过滤最多%d个", maxPropertyCount)
+		}
+		for name := range c.Properties {
+			if !propertyPattern.MatchString(name) {
+				return invalid("事件属性名 %q 不合法", name)
+			}
+		}
+		return nil
+	case CondTag:
+		if c.TagID == 0 {
+			return invalid("标签条件缺少 tag_id")
+		}
+		if c.Op != "has" && c.Op != "not_has" {
+			return invalid("标签条件的 op 只能是 has 或 not_has")
+		}
+		return nil
+	case CondGroup:
+		return validateGroup(c.Match, c.Conditions, depth+1, count)
+	default:
+		return invalid("不支持的条件类型 %q", c.Type)
+	}
+}
+
+func validateAttributeOp(c *Condition, kind string) error {
+	switch c.Op {
+	case "exists", "not_exists":
+		return nil
+	case "in", "not_in":
+		values, ok := c.Value.([]interface{})
+		if !ok || len(values) == 0 {
+			return invalid("%s 运算符需要非空数组", c.Op)
+		}
+		return nil
+	case "contains":
+		if kind != "string" {
+			return invalid("属性 %s 不支持 contains", c.Field)
+		}
+		if _, ok := c.Value.(string); !ok {
+			return invalid("contains 运算符需要字符串")
+		}
+		return nil
+	case "within_days", "before_days":
+		if kind != "time" {
+			return invalid("属性 %s 不支持 %s", c.Field, c.Op)
+		}
+		if n, ok := c.Value.(float64); !ok || n <= 0 || n > maxWithinDays {
+			return invalid("%s 需要1-%d之间的天数", c.Op, maxWithinDays)
+		}
+		return nil
+	}
+	if _, ok := comparisonOps[c.Op]; !ok {
+		return invalid("不支持的运算符 %q", c.Op)
+	}
+	switch c.Value.(type) {
+	case string, float64, bool:
+		return nil
+	default:
+		return invalid("属性 %s 的比较值不合法", c.Field)
+	}
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRules, fmt.Sprintf(format, args...))
+}
+
+// Compile 将规则编译为 users 表上的SQL条件，调用方需另外限定 users.app_id
+// now 用于计算相对时间，便于测试
+func (r *Rules) Compile(now time.Time) (string, []interface{}) {
+	return compileGroup(r.Match, r.Conditions, now)
+}
+
+func compileGroup(match string, conds []Condition, now time.Time) (string, []interface{}) {
+	joiner := " AND "
+	if match == MatchAny {
+		joiner = " OR "
+	}
+	parts := make([]string, 0, len(conds))
+	var args []interface{}
+	for i := range conds {
+		sql, a := compileCondition(&conds[i], now)
+		parts = append(parts, sql)
+		args = append(args, a...)
+	}
+	return "(" + strings.Join(parts, joiner) + ")", args
+}
+
+func compileCondition(c *Condition, now time.Time) (string, []interface{}) {
+	switch c.Type {
+	case CondAttribute:
+		return compileAttribute(c, now)
+	case CondEvent:
+		sql := "(SELECT COUNT(*) FROM events WHERE events.app_id = users.app_id AND events.user_id = users.id" +
+			" AND events.event_code = ? AND events.created_at >= ?"
+		args := []interface{}{c.EventCode, now.AddDate(0, 0, -c.WithinDays)}
+		if c.Env != "" {
+			sql += " AND events.env = ?"
+			args = append(args, c.Env)
+		}
+		for _, name := range sortedKeys(c.Properties) {
+			sql += " AND JSON_UNQUOTE(JSON_EXTRACT(events.properties, ?)) = ?"
+			args = append(args, "$."+name, c.Properties[name])
+		}
+		sql += ") " + comparisonOps[c.Op] + " ?"
+		return sql, append(args, c.Count)
+	case CondTag:
+		sql := "EXISTS (SELECT 1 FROM user_tag_members WHERE user_tag_members.user_id = users.id AND user_tag_members.tag_id = ?)"
+		if c.Op == "not_has" {
+			sql = "NOT " + sql
+		}
+		return sql, []interface{}{c.TagID}
+	default:
+		return compileGroup(c.Match, c.Conditions, now)
+	}
+}
+
+func compileAttribute(c *Condition, now time.Time) (string, []interface{}) {
+	column := "users." + c.Field
+	switch c.Op {
+	case "exists":
+		if attributeFields[c.Field] == "string" {
+			return "(" + column + " IS NOT NULL AND " + column + " <> '')", nil
+		}
+		return column + " IS NOT NULL", nil
+	case "not_exists":
+		if attributeFields[c.Field] == "string" {
+			return "(" + column + " IS NULL OR " + column + " = '')", nil
+		}
+		return column + " IS NULL", nil
+	case "in":
+		return column + " IN ?", []interface{}{c.Value}
+	case "not_in":
+		return column + " NOT IN ?", []interface{}{c.Value}
+	case "contains":
+		return column + " LIKE ?", []interface{}{"%" + escapeLike(c.Value.(string)) + "%"}
+	case "within_days":
+		return column + " >= ?", []interface{}{now.AddDate(0, 0, -int(c.Value.(float64)))}
+	case "before_days":
+		return column + " < ?", []interface{}{now.AddDate(0, 0, -int(c.Value.(float64)))}
+	default:
+		return column + " " + comparisonOps[c.Op] + " ?", []interface{}{c.Value}
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TagIDs 规则中引用的标签ID，保存前用于校验标签属于同一应用
+func (r *Rules) TagIDs() []uint {
+	var ids []uint
+	var walk func(conds []Condition)
+	walk = func(conds []Condition) {
+		for _, c := range conds {
+			if c.Type == CondTag {
+				ids = append(ids, c.TagID)
+			}
+			walk(c.Conditions)
+		}
+	}
+	walk(r.Conditions)
+	return ids
+}
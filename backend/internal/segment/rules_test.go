@@ -0,0 +1,129 @@
+package segment
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		{"event and attribute", `{"match":"all","conditions":[
+			{"type":"event","event_code":"purchase","op":"gte","count":2,"within_days":30},
+			{"type":"attribute","field":"source","op":"eq","value":"native"}]}`, false},
+		{"nested any group", `{"conditions":[{"type":"group","match":"any","conditions":[
+			{"type":"tag","tag_id":1,"op":"has"},
+			{"type":"attribute","field":"created_at","op":"within_days","value":7}]}]}`, false},
+		{"malformed json", `{"conditions":`, true},
+		{"empty", `{"conditions":[]}`, true},
+		{"unknown type", `{"conditions":[{"type":"device"}]}`, true},
+		{"unknown field", `{"conditions":[{"type":"attribute","field":"password_hash","op":"exists"}]}`, true},
+		{"bad match", `{"match":"xor","conditions":[{"type":"tag","tag_id":1,"op":"has"}]}`, true},
+		{"event without window", `{"conditions":[{"type":"event","event_code":"purchase","op":"gte","count":1}]}`, true},
+		{"unsafe property name", `{"conditions":[{"type":"event","event_code":"a","op":"gte","count":1,"within_days":1,"properties":{"x') OR 1=1 --":"y"}}]}`, true},
+		{"contains on time", `{"conditions":[{"type":"attribute","field":"created_at","op":"contains","value":"2024"}]}`, true},
+		{"empty in", `{"conditions":[{"type":"attribute","field":"source","op":"in","value":[]}]}`, true},
+		{"tag bad op", `{"conditions":[{"type":"tag","tag_id":1,"op":"eq"}]}`, true},
+		{"too deep", `{"conditions":[{"type":"group","conditions":[{"type":"group","conditions":[{"type":"group","conditions":[{"type":"tag","tag_id":1,"op":"has"}]}]}]}]}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRules) {
+					t.Errorf("err = %v, want ErrInvalidRules", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	rules, err := Parse(`{"match":"all","conditions":[
+		{"type":"event","event_code":"purchase","op":"gte","count":2,"within_days":30},
+		{"type":"event","event_code":"app_open","op":"gte","count":1,"within_days":30,"properties":{"platform":"ios"}},
+		{"type":"group","match":"any","conditions":[
+			{"type":"tag","tag_id":3,"op":"not_has"},
+			{"type":"attribute","field":"nickname","op":"contains","value":"50%"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	sql, args := rules.Compile(now)
+
+	wantSQL := "((SELECT COUNT(*) FROM events WHERE events.app_id = users.app_id AND events.user_id = users.id AND events.event_code = ? AND events.created_at >= ?) >= ?" +
+		" AND (SELECT COUNT(*) FROM events WHERE events.app_id = users.app_id AND events.user_id = users.id AND events.event_code = ? AND events.created_at >= ?" +
+		" AND JSON_UNQUOTE(JSON_EXTRACT(events.properties, ?)) = ?) >= ?" +
+		" AND (NOT EXISTS (SELECT 1 FROM user_tag_members WHERE user_tag_members.user_id = users.id AND user_tag_members.tag_id = ?)" +
+		" OR users.nickname LIKE ?))"
+	if sql != wantSQL {
+		t.Errorf("sql =\n%s\nwant\n%s", sql, wantSQL)
+	}
+
+	since := now.AddDate(0, 0, -30)
+	wantArgs := []interface{}{"purchase", since, 2, "app_open", since, "$.platform", "ios", 1, uint(3), `%50\%%`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %#v\nwant %#v", args, wantArgs)
+	}
+	if strings.Count(sql, "?") != len(args) {
+		t.Errorf("placeholder count %d != args %d", strings.Count(sql, "?"), len(args))
+	}
+}
+
+func TestRulesTagIDs(t *testing.T) {
+	rules, err := Parse(`{"conditions":[{"type":"tag","tag_id":1,"op":"has"},
+		{"type":"group","conditions":[{"type":"tag","tag_id":2,"op":"not_has"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.TagIDs(); !reflect.DeepEqual(got, []uint{1, 2}) {
+		t.Errorf("TagIDs() = %v", got)
+	}
+}
+
+func TestParseAndFormatIDs(t *testing.T) {
+	ids := ParseIDs(" 3, 5,,abc,0,7 ")
+	if !reflect.DeepEqual(ids, []uint{3, 5, 7}) {
+		t.Errorf("ParseIDs = %v", ids)
+	}
+	if s := FormatIDs(ids); s != "3,5,7" {
+		t.Errorf("FormatIDs = %q", s)
+	}
+	if ids := ParseIDs(""); ids != nil {
+		t.Errorf("ParseIDs(\"\") = %v, want nil", ids)
+	}
+}
+
+func TestMatches_WithoutDB(t *testing.T) {
+	tests := []struct {
+		name       string
+		userID     uint
+		targetType string
+		ids        []uint
+		want       bool
+	}{
+		{"all", 0, TargetAll, nil, true},
+		{"empty type", 0, "", nil, true},
+		{"anonymous", 0, TargetTag, []uint{1}, false},
+		{"listed user", 5, TargetUser, []uint{4, 5}, true},
+		{"unlisted user", 6, TargetUser, []uint{4, 5}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Matches(nil, 1, tt.userID, tt.targetType, tt.ids)
+			if err != nil || got != tt.want {
+				t.Errorf("Matches = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
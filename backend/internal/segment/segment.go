@@ -0,0 +1,253 @@
+package segment
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// 推送、消息和版本灰度的目标类型
+const (
+	TargetAll     = "all"
+	TargetUser    = "user"
+	TargetTag     = "tag"
+	TargetSegment = "segment"
+)
+
+// previewSampleSize 预估分群时返回的样例用户数
+const previewSampleSize = 10
+
+// ErrInvalidTarget 目标类型不支持或目标不属于该应用
+var ErrInvalidTarget = errors.New("无效的目标")
+
+// Preview 预估规则圈选的用户数，并返回少量样例用户
+func Preview(db *gorm.DB, appID uint, rules *Rules) (int64, []model.User, error) {
+	where, args := rules.Compile(time.Now())
+	query := db.Model(&model.User{}).Where("users.app_id = ?", appID).Where(where, args...)
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, nil, err
+	}
+	var sample []model.User
+	if err := query.Order("users.id DESC").Limit(previewSampleSize).Find(&sample).Error; err != nil {
+		return 0, nil, err
+	}
+	return count, sample, nil
+}
+
+// Materialize 按当前规则重新计算分群成员并写入 user_segment_members，返回成员数
+func Materialize(db *gorm.DB, seg *model.UserSegment) (int64, error) {
+	rules, err := Parse(seg.Rules)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	where, args := rules.Compile(now)
+
+	var count int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("segment_id = ?", seg.ID).Delete(&model.UserSegmentMember{}).Error; err != nil {
+			return err
+		}
+		result := tx.Exec("INSERT INTO user_segment_members (app_id, segment_id, user_id, created_at) "+
+			"SELECT ?, ?, users.id, ? FROM users WHERE users.app_id = ? AND users.deleted_at IS NULL AND "+where,
+			append([]interface{}{seg.AppID, seg.ID, now, seg.AppID}, args...)...)
+		if result.Error != nil {
+			return result.Error
+		}
+		count = result.RowsAffected
+		return tx.Model(seg).Updates(map[string]interface{}{"member_count": count, "evaluated_at": now}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	seg.MemberCount = count
+	seg.EvaluatedAt = &now
+	return count, nil
+}
+
+// ParseIDs 解析逗号分隔的目标ID，忽略空白和非法项
+func ParseIDs(s string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// FormatIDs 将目标ID格式化为逗号分隔的字符串
+func FormatIDs(ids []uint) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ValidateTarget 校验目标类型，以及目标用户、标签或分群都属于该应用
+func ValidateTarget(db *gorm.DB, appID uint, targetType string, ids []uint) error {
+	var table interface{}
+	switch targetType {
+	case TargetAll:
+		return nil
+	case TargetUser:
+		table = &model.User{}
+	case TargetTag:
+		table = &model.UserTag{}
+	case TargetSegment:
+		table = &model.UserSegment{}
+	default:
+		return ErrInvalidTarget
+	}
+	if len(ids) == 0 {
+		return ErrInvalidTarget
+	}
+
+	var count int64
+	if err := db.Model(table).Where("app_id = ? AND id IN ?", appID, ids).Count(&count).Error; err != nil {
+		return err
+	}
+	if count != int64(len(uniqueIDs(ids))) {
+		return ErrInvalidTarget
+	}
+	return nil
+}
+
+// recipientQuery 目标用户查询，只包含状态正常的用户
+func recipientQuery(db *gorm.DB, appID uint, targetType string, ids []uint) (*gorm.DB, error) {
+	query := db.Model(&model.User{}).Where("users.app_id = ? AND users.status = ?", appID, model.UserStatusNormal)
+	switch targetType {
+	case TargetAll:
+		return query, nil
+	case TargetUser:
+		return query.Where("users.id IN ?", ids), nil
+	case TargetTag:
+		return query.Where("users.id IN (?)",
+			db.Model(&model.UserTagMember{}).Select("user_id").Where("app_id = ? AND tag_id IN ?", appID, ids)), nil
+	case TargetSegment:
+		if err := ensureMaterialized(db, appID, ids); err != nil {
+			return nil, err
+		}
+		return query.Where("users.id IN (?)",
+			db.Model(&model.UserSegmentMember{}).Select("user_id").Where("app_id = ? AND segment_id IN ?", appID, ids)), nil
+	default:
+		return nil, ErrInvalidTarget
+	}
+}
+
+// ensureMaterialized 物化从未计算过成员的分群
+func ensureMaterialized(db *gorm.DB, appID uint, ids []uint) error {
+	var pending []model.UserSegment
+	if err := db.Where("app_id = ? AND id IN ? AND evaluated_at IS NULL", appID, ids).Find(&pending).Error; err != nil {
+		return err
+	}
+	for i := range pending {
+		if _, err := Materialize(db, &pending[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve 解析目标用户ID
+func Resolve(db *gorm.DB, appID uint, targetType string, ids []uint) ([]uint, error) {
+	query, err := recipientQuery(db, appID, targetType, ids)
+	if err != nil {
+		return nil, err
+	}
+	var userIDs []uint
+	if err := query.Order("users.id").Pluck("users.id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	return userIDs, nil
+}
+
+// RecipientIDs 目标用户ID子查询，用于大批量投递时联查设备等关联表，不一次加载全部用户
+func RecipientIDs(db *gorm.DB, appID uint, targetType string, ids []uint) (*gorm.DB, error) {
+	query, err := recipientQuery(db, appID, targetType, ids)
+	if err != nil {
+		return nil, err
+	}
+	return query.Select("users.id"), nil
+}
+
+// CountRecipients 统计目标用户数
+func CountRecipients(db *gorm.DB, appID uint, targetType string, ids []uint) (int64, error) {
+	query, err := recipientQuery(db, appID, targetType, ids)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
+
+// Matches 判断单个用户是否属于目标，分群按当前规则实时计算，不依赖物化结果
+func Matches(db *gorm.DB, appID, userID uint, targetType string, ids []uint) (bool, error) {
+	if targetType == TargetAll || targetType == "" {
+		return true, nil
+	}
+	if userID == 0 {
+		return false, nil
+	}
+
+	switch targetType {
+	case TargetUser:
+		for _, id := range ids {
+			if id == userID {
+				return true, nil
+			}
+		}
+		return false, nil
+	case TargetTag:
+		var count int64
+		err := db.Model(&model.UserTagMember{}).
+			Where("app_id = ? AND user_id = ? AND tag_id IN ?", appID, userID, ids).Count(&count).Error
+		return count > 0, err
+	case TargetSegment:
+		var segments []model.UserSegment
+		if err := db.Where("app_id = ? AND id IN ?", appID, ids).Find(&segments).Error; err != nil {
+			return false, err
+		}
+		now := time.Now()
+		for _, seg := range segments {
+			rules, err := Parse(seg.Rules)
+			if err != nil {
+				continue
+			}
+			where, args := rules.Compile(now)
+			var count int64
+			if err := db.Model(&model.User{}).Where("users.app_id = ? AND users.id = ?", appID, userID).
+				Where(where, args...).Count(&count).Error; err != nil {
+				return false, err
+			}
+			if count > 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, ErrInvalidTarget
+	}
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	out := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
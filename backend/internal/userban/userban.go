@@ -0,0 +1,315 @@
+// Package userban 应用用户封禁：封禁即将用户状态置为禁用，可附带原因和到期时间。
+// 封禁在SDK认证、WebSocket连接、事件和日志上报以及推送和消息圈选中统一生效，
+// 上报链路按 (应用, 用户) 查询封禁状态并缓存一小段时间，避免每次请求查询用户表；
+// 封禁变更写入事件表，各实例轮询后清除本地缓存并断开被封禁用户在本实例上的连接
+package userban
+
+import (
+	"container/list"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"app-platform-backend/internal/appauth"
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// cacheTTL 封禁状态缓存时长，事件同步延迟或遗漏时其他实例最多延迟该时长生效
+const cacheTTL = 30 * time.Second
+
+// cacheSize 封禁状态缓存的最大条目数，超出后淘汰最久未使用的条目
+const cacheSize = 100000
+
+// eventRetention 封禁事件保留时长，各实例需在此期间内完成同步
+const eventRetention = 10 * time.Minute
+
+// syncBatchSize 每次同步读取的事件数
+const syncBatchSize = 500
+
+// MaxReasonLength 封禁原因的最大长度
+const MaxReasonLength = 255
+
+var (
+	ErrUserNotFound  = errors.New("用户不存在")
+	ErrInvalidExpiry = errors.New("封禁到期时间不能早于当前时间")
+	ErrReasonTooLong = errors.New("封禁原因不能超过255个字符")
+)
+
+// Listener 封禁回调，例如断开用户的WebSocket连接
+type Listener func(user *model.User)
+
+// cacheKey 缓存键，ref 为用户ID或 open_id
+type cacheKey struct {
+	appID uint
+	ref   string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	banned  bool
+	expires time.Time
+}
+
+var (
+	db *gorm.DB
+
+	cacheMu    sync.Mutex
+	cacheList  = list.New() // 最近使用的在前
+	cacheIndex = map[cacheKey]*list.Element{}
+
+	syncMu     sync.Mutex
+	syncCursor uint // 已处理的最大事件ID
+	syncReady  bool
+
+	listenerMu sync.RWMutex
+	listeners  []Listener
+)
+
+// Init 初始化封禁检查
+func Init(database *gorm.DB) {
+	db = database
+}
+
+// OnBan 注册封禁回调，模块在 init 中调用
+func OnBan(fn Listener) {
+	listenerMu.Lock()
+	defer listenerMu.Unlock()
+	listeners = append(listeners, fn)
+}
+
+// Ban 封禁用户：置为禁用并记录原因和到期时间，until 为空表示永久封禁；注销已登录的会话并通知各模块
+func Ban(appID, userID uint, until *time.Time, reason string) (*model.User, error) {
+	if until != nil && !until.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+	if len([]rune(reason)) > MaxReasonLength {
+		return nil, ErrReasonTooLong
+	}
+	now := time.Now()
+	user, err := update(appID, userID, map[string]interface{}{
+		"status":       model.UserStatusDisabled,
+		"ban_reason":   reason,
+		"banned_until": until,
+		"banned_at":    now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := appauth.RevokeUser(appID, userID); err != nil {
+		log.Printf("[UserBan] Failed to revoke sessions of user %d: %v", userID, err)
+	}
+	forget(user)
+	publish(user, true)
+	notify(user)
+	return user, nil
+}
+
+// Unban 解除封禁，恢复为正常状态
+func Unban(appID, userID uint) (*model.User, error) {
+	user, err := update(appID, userID, map[string]interface{}{
+		"status":       model.UserStatusNormal,
+		"ban_reason":   "",
+		"banned_until": nil,
+		"banned_at":    nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	forget(user)
+	publish(user, false)
+	return user, nil
+}
+
+// LiftExpired 解除已到期的封禁，返回解除的用户
+func LiftExpired(now time.Time, limit int) ([]model.User, error) {
+	var users []model.User
+	if err := db.Where("status = ? AND banned_until IS NOT NULL AND banned_until <= ?", model.UserStatusDisabled, now).
+		Order("banned_until").Limit(limit).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	lifted := users[:0]
+	for _, u := range users {
+		// 只解除仍为该到期时间的封禁，期间被重新封禁的不受影响
+		result := db.Model(&model.User{}).
+			Where("id = ? AND status = ? AND banned_until = ?", u.ID, model.UserStatusDisabled, u.BannedUntil).
+			Updates(map[string]interface{}{
+				"status":       model.UserStatusNormal,
+				"ban_reason":   "",
+				"banned_until": nil,
+				"banned_at":    nil,
+			})
+		if result.Error != nil {
+			return lifted, result.Error
+		}
+		if result.RowsAffected == 1 {
+			forget(&u)
+			publish(&u, false)
+			lifted = append(lifted, u)
+		}
+	}
+	return lifted, nil
+}
+
+// IsBanned 用户是否处于封禁中，查询失败时放行
+func IsBanned(appID, userID uint) bool {
+	if userID == 0 {
+		return false
+	}
+	return lookup(appID, strconv.FormatUint(uint64(userID), 10), "id = ?", userID)
+}
+
+// IsBannedRef 按用户ID或 open_id 判断是否处于封禁中，用于日志上下文等只有字符串标识的场景
+func IsBannedRef(appID uint, ref string) bool {
+	if ref == "" {
+		return false
+	}
+	if id, err := strconv.ParseUint(ref, 10, 64); err == nil {
+		return lookup(appID, ref, "id = ? OR open_id = ?", uint(id), ref)
+	}
+	return lookup(appID, ref, "open_id = ?", ref)
+}
+
+// SyncEvents 处理其他实例发布的封禁变更：清除本地缓存，封禁事件同时通知各模块（如断开本实例上的连接）
+// 首次调用只记录当前位置；本实例发布的事件也会再次处理，回调需可重复执行
+func SyncEvents() error {
+	syncMu.Lock()
+	defer syncMu.Unlock()
+
+	if !syncReady {
+		if err := db.Model(&model.UserBanEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&syncCursor).Error; err != nil {
+			return err
+		}
+		syncReady = true
+		return nil
+	}
+
+	for {
+		var events []model.UserBanEvent
+		if err := db.Where("id > ?", syncCursor).Order("id").Limit(syncBatchSize).Find(&events).Error; err != nil {
+			return err
+		}
+		for _, e := range events {
+			user := &model.User{ID: e.UserID, AppID: e.AppID, OpenID: e.OpenID}
+			forget(user)
+			if e.Banned {
+				notify(user)
+			}
+			syncCursor = e.ID
+		}
+		if len(events) < syncBatchSize {
+			return nil
+		}
+	}
+}
+
+// PruneEvents 删除超过保留时长的封禁事件
+func PruneEvents(now time.Time) (int64, error) {
+	result := db.Where("created_at < ?", now.Add(-eventRetention)).Delete(&model.UserBanEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// lookup 查询用户当前是否处于封禁中，结果按 (应用, 标识) 缓存；查询失败时放行且不缓存
+func lookup(appID uint, ref string, where string, args ...interface{}) bool {
+	key := cacheKey{appID: appID, ref: ref}
+	now := time.Now()
+	if banned, ok := cacheGet(key, now); ok {
+		return banned
+	}
+
+	var count int64
+	if err := db.Model(&model.User{}).
+		Where("app_id = ? AND status = ? AND (banned_until IS NULL OR banned_until > ?)",
+			appID, model.UserStatusDisabled, now).
+		Where(where, args...).Count(&count).Error; err != nil {
+		log.Printf("[UserBan] Failed to check ban of %s in app %d: %v", ref, appID, err)
+		return false
+	}
+	cachePut(key, count > 0, now.Add(cacheTTL))
+	return count > 0
+}
+
+func cacheGet(key cacheKey, now time.Time) (bool, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	el, ok := cacheIndex[key]
+	if !ok {
+		return false, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if !now.Before(entry.expires) {
+		cacheList.Remove(el)
+		delete(cacheIndex, key)
+		return false, false
+	}
+	cacheList.MoveToFront(el)
+	return entry.banned, true
+}
+
+func cachePut(key cacheKey, banned bool, expires time.Time) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if el, ok := cacheIndex[key]; ok {
+		el.Value = &cacheEntry{key: key, banned: banned, expires: expires}
+		cacheList.MoveToFront(el)
+		return
+	}
+	cacheIndex[key] = cacheList.PushFront(&cacheEntry{key: key, banned: banned, expires: expires})
+	for cacheList.Len() > cacheSize {
+		oldest := cacheList.Back()
+		cacheList.Remove(oldest)
+		delete(cacheIndex, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// forget 清除用户按ID和 open_id 缓存的封禁状态
+func forget(user *model.User) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	for _, ref := range []string{strconv.FormatUint(uint64(user.ID), 10), user.OpenID} {
+		key := cacheKey{appID: user.AppID, ref: ref}
+		if el, ok := cacheIndex[key]; ok {
+			cacheList.Remove(el)
+			delete(cacheIndex, key)
+		}
+	}
+}
+
+// publish 写入封禁变更事件，供其他实例同步；写入失败时其他实例在缓存过期后生效
+func publish(user *model.User, banned bool) {
+	event := model.UserBanEvent{AppID: user.AppID, UserID: user.ID, OpenID: user.OpenID, Banned: banned}
+	if err := db.Create(&event).Error; err != nil {
+		log.Printf("[UserBan] Failed to publish ban event of user %d: %v", user.ID, err)
+	}
+}
+
+// notify 调用封禁回调
+func notify(user *model.User) {
+	listenerMu.RLock()
+	defer listenerMu.RUnlock()
+	for _, fn := range listeners {
+		fn(user)
+	}
+}
+
+// update 更新应用内用户的封禁字段并返回更新后的用户
+func update(appID, userID uint, updates map[string]interface{}) (*model.User, error) {
+	var user model.User
+	if err := db.Where("app_id = ? AND id = ?", appID, userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if err := db.Model(&user).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	if err := db.First(&user, user.ID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,100 @@
+package userban
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/pkg/sqltest"
+)
+
+// bannedRefs 应用1中处于封禁的用户：ID 42，open_id u_abc
+func bannedRefs(query string, args []driver.Value) sqltest.Reply {
+	if !strings.Contains(query, "FROM `users`") {
+		return sqltest.Reply{}
+	}
+	var count int64
+	for _, arg := range args[3:] {
+		if s := fmt.Sprint(arg); s == "42" || s == "u_abc" {
+			count = 1
+		}
+	}
+	return sqltest.Reply{Columns: []string{"count"}, Rows: [][]driver.Value{{count}}}
+}
+
+func TestIsBannedRef(t *testing.T) {
+	db, fake := sqltest.Open(t, bannedRefs)
+	Init(db)
+
+	tests := []struct {
+		ref  string
+		want bool
+	}{
+		{"42", true},
+		{"u_abc", true},
+		{"43", false},
+		{"u_def", false},
+		{"", false},
+		{"-1", false},
+	}
+	for _, tt := range tests {
+		if got := IsBannedRef(1, tt.ref); got != tt.want {
+			t.Errorf("IsBannedRef(%q) = %v, want %v", tt.ref, got, tt.want)
+		}
+	}
+	if !IsBanned(1, 42) || IsBanned(1, 0) {
+		t.Errorf("IsBanned mismatch")
+	}
+
+	// 结果按 (应用, 标识) 缓存，重复判断不再查询
+	queries := len(fake.Calls("FROM `users`"))
+	IsBannedRef(1, "u_abc")
+	IsBanned(1, 42)
+	if n := len(fake.Calls("FROM `users`")); n != queries {
+		t.Errorf("cached lookups ran %d more queries", n-queries)
+	}
+
+	// 清除缓存后重新查询
+	forget(&model.User{ID: 42, AppID: 1, OpenID: "u_abc"})
+	IsBanned(1, 42)
+	if n := len(fake.Calls("FROM `users`")); n != queries+1 {
+		t.Errorf("lookup after forget ran %d queries, want 1", n-queries)
+	}
+}
+
+func TestSyncEvents_NotifiesBansFromOtherInstances(t *testing.T) {
+	db, _ := sqltest.Open(t, func(query string, args []driver.Value) sqltest.Reply {
+		switch {
+		case strings.Contains(query, "MAX(id)"):
+			return sqltest.Reply{Columns: []string{"max"}, Rows: [][]driver.Value{{int64(7)}}}
+		case strings.Contains(query, "FROM `user_ban_events`"):
+			if fmt.Sprint(args[0]) != "7" {
+				return sqltest.Reply{}
+			}
+			return sqltest.Reply{
+				Columns: []string{"id", "app_id", "user_id", "open_id", "banned"},
+				Rows:    [][]driver.Value{{int64(8), int64(1), int64(42), "u_abc", true}, {int64(9), int64(1), int64(43), "u_def", false}},
+			}
+		}
+		return sqltest.Reply{}
+	})
+	Init(db)
+	syncReady = false
+	defer func() { syncReady = false }()
+
+	var notified []uint
+	OnBan(func(user *model.User) { notified = append(notified, user.ID) })
+	defer func() { listeners = nil }()
+
+	for i := 0; i < 3; i++ {
+		if err := SyncEvents(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 首次只记录位置，之后只通知封禁事件，已处理的事件不重复通知
+	if len(notified) != 1 || notified[0] != 42 || syncCursor != 9 {
+		t.Errorf("notified = %v, cursor = %d, want [42] and cursor 9", notified, syncCursor)
+	}
+}
@@ -0,0 +1,358 @@
+// Package userimport 应用用户批量导入：支持 CSV 和 NDJSON、字段映射、逐行校验、
+// 按 open_id 和邮箱去重以及只校验不写入的试运行，由后台任务分批执行，出错的行写入错误报告
+package userimport
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/profile"
+
+	"gorm.io/gorm"
+)
+
+// SourceImport 通过批量导入创建的用户来源
+const SourceImport = "import"
+
+// 已存在的用户的处理方式
+const (
+	OnConflictSkip   = "skip"   // 跳过已存在的用户
+	OnConflictUpdate = "update" // 用文件中的非空字段更新已存在的用户
+)
+
+// Options 导入选项
+type Options struct {
+	Format     string
+	Mapping    Mapping
+	OnConflict string
+	DryRun     bool // 只校验和统计，不写入
+}
+
+// Progress 导入进度，Processed 为已处理的行数（含出错的行），续跑时跳过这些行
+type Progress struct {
+	Processed int64
+	Created   int64
+	Updated   int64
+	Skipped   int64
+	Failed    int64
+}
+
+// ErrorReportHeader 错误报告的表头
+var ErrorReportHeader = []string{"line", "open_id", "email", "error"}
+
+// CountRows 统计文件中的数据行数，用于展示进度
+func CountRows(path, format string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r, err := NewReader(format, f)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for {
+		_, err := r.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		var rowErr *RowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return n, err
+		}
+		n++
+	}
+}
+
+// importer 一次导入的执行状态
+type importer struct {
+	db       *gorm.DB
+	appID    uint
+	opts     Options
+	progress Progress
+	errs     *csv.Writer
+	seen     map[string]int // 文件中已出现的 open_id 和邮箱，值为首次出现的行号
+}
+
+// Run 从 resume 记录的位置继续导入文件，每处理完一批调用 onBatch 保存进度，onBatch 返回错误时停止
+func Run(db *gorm.DB, appID uint, path string, opts Options, resume Progress, batchSize int,
+	errorReport io.Writer, onBatch func(Progress) error) (Progress, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return resume, err
+	}
+	defer f.Close()
+	r, err := NewReader(opts.Format, f)
+	if err != nil {
+		return resume, err
+	}
+
+	imp := &importer{
+		db:       db,
+		appID:    appID,
+		opts:     opts,
+		progress: resume,
+		errs:     csv.NewWriter(errorReport),
+		seen:     map[string]int{},
+	}
+	var index int64
+	var batch []*Record
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return imp.progress, err
+		}
+		index++
+
+		var rec *Record
+		if rowErr == nil {
+			rec, err = opts.Mapping.Record(row)
+			if err == nil {
+				err = imp.dedupe(rec)
+			}
+			if err != nil {
+				rowErr = &RowError{Line: row.Line, Err: err}
+			}
+		}
+		// 已处理的行只用于恢复文件内去重状态
+		if index <= resume.Processed {
+			continue
+		}
+		if rowErr != nil {
+			imp.fail(rowErr.Line, rec, rowErr.Err)
+			imp.progress.Processed++
+			continue
+		}
+
+		batch = append(batch, rec)
+		if len(batch) >= batchSize {
+			if err := imp.flush(batch, onBatch); err != nil {
+				return imp.progress, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := imp.flush(batch, onBatch); err != nil {
+		return imp.progress, err
+	}
+	return imp.progress, nil
+}
+
+// dedupe 文件内按 open_id 和邮箱去重，重复的行报错，保留首次出现的行
+func (imp *importer) dedupe(rec *Record) error {
+	keys := make([]string, 0, 2)
+	if rec.OpenID != "" {
+		keys = append(keys, "open_id:"+rec.OpenID)
+	}
+	if rec.Email != "" {
+		keys = append(keys, "email:"+rec.Email)
+	}
+	for _, key := range keys {
+		if line, ok := imp.seen[key]; ok {
+			return fmt.Errorf("与第%d行重复", line)
+		}
+	}
+	for _, key := range keys {
+		imp.seen[key] = rec.Line
+	}
+	return nil
+}
+
+// flush 写入一批记录并保存进度
+func (imp *importer) flush(batch []*Record, onBatch func(Progress) error) error {
+	if len(batch) > 0 {
+		if err := imp.apply(batch); err != nil {
+			return err
+		}
+	}
+	imp.errs.Flush()
+	if err := imp.errs.Error(); err != nil {
+		return err
+	}
+	return onBatch(imp.progress)
+}
+
+// apply 按 open_id 和邮箱匹配已有用户，新建或按冲突策略更新
+func (imp *importer) apply(batch []*Record) error {
+	var openIDs, emails []string
+	for _, rec := range batch {
+		if rec.OpenID != "" {
+			openIDs = append(openIDs, rec.OpenID)
+		}
+		if rec.Email != "" {
+			emails = append(emails, rec.Email)
+		}
+	}
+
+	// open_id 唯一索引包含已删除的用户，按 open_id 匹配时也要查出已删除的
+	byOpenID := map[string]*model.User{}
+	if len(openIDs) > 0 {
+		var users []model.User
+		if err := imp.db.Unscoped().Where("app_id = ? AND open_id IN ?", imp.appID, openIDs).Find(&users).Error; err != nil {
+			return err
+		}
+		for i := range users {
+			byOpenID[users[i].OpenID] = &users[i]
+		}
+	}
+	byEmail := map[string][]*model.User{}
+	if len(emails) > 0 {
+		var users []model.User
+		if err := imp.db.Where("app_id = ? AND email IN ?", imp.appID, emails).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+		for i := range users {
+			byEmail[users[i].Email] = append(byEmail[users[i].Email], &users[i])
+		}
+	}
+
+	for _, rec := range batch {
+		imp.progress.Processed++
+		existing, err := match(rec, byOpenID[rec.OpenID], byEmail[rec.Email])
+		if err != nil {
+			imp.fail(rec.Line, rec, err)
+			continue
+		}
+		switch {
+		case existing == nil:
+			err = imp.create(rec)
+		case imp.opts.OnConflict == OnConflictUpdate:
+			err = imp.update(existing, rec)
+		default:
+			imp.progress.Skipped++
+			continue
+		}
+		if err != nil {
+			imp.fail(rec.Line, rec, err)
+		}
+	}
+	return nil
+}
+
+// match 确定记录对应的已有用户，open_id 和邮箱指向不同用户时报错
+func match(rec *Record, byOpenID *model.User, byEmail []*model.User) (*model.User, error) {
+	if byOpenID != nil && byOpenID.DeletedAt.Valid {
+		return nil, errors.New("open_id 已被已删除的用户占用")
+	}
+	if len(byEmail) > 1 && byOpenID == nil {
+		return nil, errors.New("该邮箱对应多个用户")
+	}
+	if byOpenID != nil {
+		for _, u := range byEmail {
+			if u.ID != byOpenID.ID {
+				return nil, fmt.Errorf("邮箱已属于 open_id 为 %s 的用户", u.OpenID)
+			}
+		}
+		return byOpenID, nil
+	}
+	if len(byEmail) == 1 {
+		if rec.OpenID != "" && byEmail[0].OpenID != rec.OpenID {
+			return nil, fmt.Errorf("邮箱已属于 open_id 为 %s 的用户", byEmail[0].OpenID)
+		}
+		return byEmail[0], nil
+	}
+	return nil, nil
+}
+
+// create 新建用户，未提供 open_id 时随机生成
+func (imp *importer) create(rec *Record) error {
+	if imp.opts.DryRun {
+		imp.progress.Created++
+		return nil
+	}
+	user := model.User{
+		AppID:    imp.appID,
+		OpenID:   rec.OpenID,
+		Nickname: rec.Nickname,
+		Avatar:   rec.Avatar,
+		Phone:    rec.Phone,
+		Email:    rec.Email,
+		Status:   model.UserStatusNormal,
+		Source:   SourceImport,
+	}
+	if user.OpenID == "" {
+		id, err := randomHex(16)
+		if err != nil {
+			return err
+		}
+		user.OpenID = "u_" + id
+	}
+	if rec.Status != nil {
+		user.Status = *rec.Status
+	}
+	if rec.CreatedAt != nil {
+		user.CreatedAt = *rec.CreatedAt
+	}
+	if err := imp.db.Create(&user).Error; err != nil {
+		return err
+	}
+	imp.progress.Created++
+	return imp.setProperties(user.ID, rec)
+}
+
+// update 用记录中的非空字段更新已有用户
+func (imp *importer) update(user *model.User, rec *Record) error {
+	if imp.opts.DryRun {
+		imp.progress.Updated++
+		return nil
+	}
+	updates := map[string]interface{}{}
+	for column, value := range map[string]string{
+		"nickname": rec.Nickname,
+		"avatar":   rec.Avatar,
+		"phone":    rec.Phone,
+		"email":    rec.Email,
+	} {
+		if value != "" {
+			updates[column] = value
+		}
+	}
+	if rec.Status != nil {
+		updates["status"] = *rec.Status
+	}
+	if len(updates) > 0 {
+		if err := imp.db.Model(user).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	imp.progress.Updated++
+	return imp.setProperties(user.ID, rec)
+}
+
+func (imp *importer) setProperties(userID uint, rec *Record) error {
+	if len(rec.Properties) == 0 {
+		return nil
+	}
+	_, err := profile.UpdateUser(imp.db, imp.appID, userID, &profile.Operations{Set: rec.Properties})
+	return err
+}
+
+// fail 记录出错的行
+func (imp *importer) fail(line int, rec *Record, err error) {
+	imp.progress.Failed++
+	var openID, email string
+	if rec != nil {
+		openID, email = rec.OpenID, rec.Email
+	}
+	imp.errs.Write([]string{strconv.Itoa(line), openID, email, err.Error()})
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,242 @@
+package userimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"app-platform-backend/internal/model"
+	"app-platform-backend/internal/profile"
+	"app-platform-backend/internal/validator"
+)
+
+// ErrInvalidImport 导入参数或文件不合法
+var ErrInvalidImport = errors.New("导入参数不合法")
+
+// propertyPrefix 映射到自定义属性的目标字段前缀，例如 properties.plan
+const propertyPrefix = "properties."
+
+// 可导入的用户字段，与导出的列名一致，导出的文件可直接导入
+const (
+	FieldOpenID    = "open_id"
+	FieldNickname  = "nickname"
+	FieldAvatar    = "avatar"
+	FieldPhone     = "phone"
+	FieldEmail     = "email"
+	FieldStatus    = "status"
+	FieldCreatedAt = "created_at"
+)
+
+// fieldLimits 字段长度限制，与 model.User 的列定义一致
+var fieldLimits = map[string]int{
+	FieldOpenID:   255,
+	FieldNickname: 255,
+	FieldAvatar:   500,
+	FieldPhone:    20,
+	FieldEmail:    255,
+}
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{6,19}$`)
+
+// Mapping 目标字段到文件列名的映射，为空时按同名列映射
+type Mapping map[string]string
+
+// ParseMapping 解析并校验字段映射
+func ParseMapping(raw string) (Mapping, error) {
+	m := Mapping{}
+	if strings.TrimSpace(raw) == "" {
+		return m, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, fmt.Errorf("%w: 字段映射格式错误: %v", ErrInvalidImport, err)
+	}
+	for target, column := range m {
+		if !isField(target) {
+			return nil, fmt.Errorf("%w: 未知的目标字段 %q", ErrInvalidImport, target)
+		}
+		if strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("%w: 字段 %s 未指定列名", ErrInvalidImport, target)
+		}
+	}
+	return m, nil
+}
+
+// isField 是否为可导入的目标字段
+func isField(target string) bool {
+	if name := strings.TrimPrefix(target, propertyPrefix); name != target {
+		return profile.ValidatePropertyName(name) == nil
+	}
+	_, ok := fieldLimits[target]
+	return ok || target == FieldStatus || target == FieldCreatedAt
+}
+
+// resolve 确定每个目标字段的值：显式映射优先，未映射的字段读取同名列
+func (m Mapping) resolve(values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for column, v := range values {
+		if isField(column) {
+			out[column] = v
+		}
+	}
+	for target, column := range m {
+		if v, ok := values[column]; ok {
+			out[target] = v
+		} else {
+			delete(out, target)
+		}
+	}
+	return out
+}
+
+// Record 校验后的一行用户数据，未提供的字段为空
+type Record struct {
+	Line       int
+	OpenID     string
+	Nickname   string
+	Avatar     string
+	Phone      string
+	Email      string
+	Status     *int
+	CreatedAt  *time.Time
+	Properties map[string]interface{}
+}
+
+// Record 按映射转换并校验一行，open_id 和 email 至少提供一个
+func (m Mapping) Record(row *Row) (*Record, error) {
+	values := m.resolve(row.Values)
+	rec := &Record{Line: row.Line}
+
+	strs := map[string]*string{
+		FieldOpenID:   &rec.OpenID,
+		FieldNickname: &rec.Nickname,
+		FieldAvatar:   &rec.Avatar,
+		FieldPhone:    &rec.Phone,
+		FieldEmail:    &rec.Email,
+	}
+	for _, field := range sortedKeys(fieldLimits) {
+		s, err := stringValue(values[field])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", field, err)
+		}
+		if utf8.RuneCountInString(s) > fieldLimits[field] {
+			return nil, fmt.Errorf("%s 长度不能超过%d个字符", field, fieldLimits[field])
+		}
+		*strs[field] = s
+	}
+
+	if rec.OpenID == "" && rec.Email == "" {
+		return nil, errors.New("open_id 和 email 至少提供一个")
+	}
+	if rec.Email != "" {
+		rec.Email = strings.ToLower(rec.Email)
+		if err := validator.ValidateEmail(rec.Email); err != nil {
+			return nil, err
+		}
+	}
+	if rec.Phone != "" && !phonePattern.MatchString(rec.Phone) {
+		return nil, errors.New("手机号格式不正确")
+	}
+
+	if v, ok := values[FieldStatus]; ok {
+		status, err := parseStatus(v)
+		if err != nil {
+			return nil, err
+		}
+		rec.Status = status
+	}
+	if v, ok := values[FieldCreatedAt]; ok {
+		t, err := parseTime(v)
+		if err != nil {
+			return nil, err
+		}
+		rec.CreatedAt = t
+	}
+
+	for target, v := range values {
+		name := strings.TrimPrefix(target, propertyPrefix)
+		if name == target || v == nil || v == "" {
+			continue
+		}
+		if rec.Properties == nil {
+			rec.Properties = map[string]interface{}{}
+		}
+		if n, ok := v.(json.Number); ok {
+			f, err := n.Float64()
+			if err != nil {
+				return nil, fmt.Errorf("属性 %s 的值不合法", name)
+			}
+			v = f
+		}
+		rec.Properties[name] = v
+	}
+	if rec.Properties != nil {
+		ops := profile.Operations{Set: rec.Properties}
+		if err := ops.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// stringValue 字段值转为字符串，NDJSON 中的数值按原样保留
+func stringValue(v interface{}) (string, error) {
+	switch x := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(x), nil
+	case json.Number:
+		return x.String(), nil
+	default:
+		return "", errors.New("应为字符串")
+	}
+}
+
+// parseStatus 状态支持 0/1 和 disabled/normal，空值表示不修改
+func parseStatus(v interface{}) (*int, error) {
+	s, err := stringValue(v)
+	if err != nil {
+		return nil, fmt.Errorf("status: %v", err)
+	}
+	var status int
+	switch strings.ToLower(s) {
+	case "":
+		return nil, nil
+	case strconv.Itoa(model.UserStatusNormal), "normal":
+		status = model.UserStatusNormal
+	case strconv.Itoa(model.UserStatusDisabled), "disabled":
+		status = model.UserStatusDisabled
+	default:
+		return nil, fmt.Errorf("status 只能是 %d 或 %d", model.UserStatusDisabled, model.UserStatusNormal)
+	}
+	return &status, nil
+}
+
+// parseTime 注册时间支持 RFC3339 和 2006-01-02 15:04:05，空值表示使用导入时间
+func parseTime(v interface{}) (*time.Time, error) {
+	s, err := stringValue(v)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("created_at 时间格式不正确: %s", s)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,142 @@
+package userimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 导入文件格式
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// maxLineSize NDJSON 单行的最大长度
+const maxLineSize = 1 << 20
+
+// Row 文件中的一行，Line 为文件中的行号（CSV 表头为第1行）
+type Row struct {
+	Line   int
+	Values map[string]interface{}
+}
+
+// Reader 按行读取导入文件，读完返回 io.EOF；行格式错误返回 *RowError，可以继续读取下一行
+type Reader interface {
+	Next() (*Row, error)
+}
+
+// RowError 单行数据错误，不影响其他行
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("第%d行: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// NewReader 按格式创建读取器
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+		return &ndjsonReader{scanner: scanner}, nil
+	default:
+		return nil, fmt.Errorf("%w: 不支持的文件格式 %q", ErrInvalidImport, format)
+	}
+}
+
+// csvReader 第一行为表头，列名即字段名
+type csvReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: 文件为空", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: 表头格式错误: %v", ErrInvalidImport, err)
+	}
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+	}
+	// 去除 Excel 导出的 UTF-8 BOM
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	return &csvReader{r: cr, header: header}, nil
+}
+
+func (r *csvReader) Next() (*Row, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			return nil, &RowError{Line: pe.StartLine, Err: pe.Err}
+		}
+		return nil, err
+	}
+	line, _ := r.r.FieldPos(0)
+	if len(record) != len(r.header) {
+		return nil, &RowError{Line: line, Err: fmt.Errorf("列数 %d 与表头 %d 不一致", len(record), len(r.header))}
+	}
+	values := make(map[string]interface{}, len(record))
+	for i, v := range record {
+		values[r.header[i]] = v
+	}
+	return &Row{Line: line, Values: values}, nil
+}
+
+// ndjsonReader 每行一个 JSON 对象，嵌套的 properties 对象展开为 properties.<名称>
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Next() (*Row, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var obj map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return nil, &RowError{Line: r.line, Err: fmt.Errorf("JSON格式错误: %v", err)}
+		}
+		values := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			if props, ok := v.(map[string]interface{}); ok && k == "properties" {
+				for name, pv := range props {
+					values[propertyPrefix+name] = pv
+				}
+				continue
+			}
+			values[k] = v
+		}
+		return &Row{Line: r.line, Values: values}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
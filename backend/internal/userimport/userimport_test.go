@@ -0,0 +1,183 @@
+package userimport
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"app-platform-backend/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func readAll(t *testing.T, format, data string) ([]*Row, []*RowError) {
+	t.Helper()
+	r, err := NewReader(format, strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []*Row
+	var rowErrs []*RowError
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return rows, rowErrs
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			rowErrs = append(rowErrs, rowErr)
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	rows, rowErrs := readAll(t, FormatCSV, "\ufeffopen_id, email\nu1,a@x.com\nu2\nu3,c@x.com\n")
+	if len(rows) != 2 || len(rowErrs) != 1 {
+		t.Fatalf("rows = %d, errors = %d", len(rows), len(rowErrs))
+	}
+	if rows[0].Line != 2 || rows[0].Values["open_id"] != "u1" || rows[0].Values["email"] != "a@x.com" {
+		t.Errorf("row = %+v", rows[0])
+	}
+	if rowErrs[0].Line != 3 {
+		t.Errorf("error line = %d, want 3", rowErrs[0].Line)
+	}
+	if rows[1].Line != 4 {
+		t.Errorf("line = %d, want 4", rows[1].Line)
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	rows, rowErrs := readAll(t, FormatNDJSON, `{"open_id":12345678901234567890,"properties":{"plan":"pro","age":30}}
+
+{bad json}
+{"email":"b@x.com"}
+`)
+	if len(rows) != 2 || len(rowErrs) != 1 || rowErrs[0].Line != 3 {
+		t.Fatalf("rows = %d, errors = %v", len(rows), rowErrs)
+	}
+	rec, err := Mapping{}.Record(rows[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.OpenID != "12345678901234567890" {
+		t.Errorf("OpenID = %q", rec.OpenID)
+	}
+	if rec.Properties["plan"] != "pro" || rec.Properties["age"] != float64(30) {
+		t.Errorf("Properties = %v", rec.Properties)
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	tests := []struct {
+		raw     string
+		wantErr bool
+	}{
+		{"", false},
+		{`{"open_id":"uid","properties.plan":"tier"}`, false},
+		{`{"password_hash":"pw"}`, true},
+		{`{"properties.bad name":"x"}`, true},
+		{`{"email":""}`, true},
+		{`[1]`, true},
+	}
+	for _, tt := range tests {
+		_, err := ParseMapping(tt.raw)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMapping(%s) err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("ParseMapping(%s) err = %v, want ErrInvalidImport", tt.raw, err)
+		}
+	}
+}
+
+func TestMappingRecord(t *testing.T) {
+	m := Mapping{"open_id": "uid", "properties.plan": "tier"}
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		wantErr bool
+		check   func(*Record) bool
+	}{
+		{"mapped columns", map[string]interface{}{"uid": "u1", "tier": "pro", "nickname": " Tom "}, false,
+			func(r *Record) bool { return r.OpenID == "u1" && r.Nickname == "Tom" && r.Properties["plan"] == "pro" }},
+		{"email lowercased", map[string]interface{}{"email": "A@X.com"}, false,
+			func(r *Record) bool { return r.Email == "a@x.com" && r.OpenID == "" }},
+		{"status name", map[string]interface{}{"uid": "u1", "status": "disabled"}, false,
+			func(r *Record) bool { return r.Status != nil && *r.Status == model.UserStatusDisabled }},
+		{"created_at", map[string]interface{}{"uid": "u1", "created_at": "2023-01-02 03:04:05"}, false,
+			func(r *Record) bool { return r.CreatedAt != nil && r.CreatedAt.Year() == 2023 }},
+		{"unmapped open_id column ignored", map[string]interface{}{"open_id": "u1"}, true, nil},
+		{"no identity", map[string]interface{}{"nickname": "Tom"}, true, nil},
+		{"bad email", map[string]interface{}{"uid": "u1", "email": "nope"}, true, nil},
+		{"bad phone", map[string]interface{}{"uid": "u1", "phone": "12ab"}, true, nil},
+		{"bad status", map[string]interface{}{"uid": "u1", "status": "2"}, true, nil},
+		{"too long", map[string]interface{}{"uid": "u1", "phone": strings.Repeat("1", 21)}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := m.Record(&Row{Line: 2, Values: tt.values})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %+v", rec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(rec) {
+				t.Errorf("record = %+v", rec)
+			}
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	imp := &importer{seen: map[string]int{}}
+	if err := imp.dedupe(&Record{Line: 2, OpenID: "u1", Email: "a@x.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := imp.dedupe(&Record{Line: 3, OpenID: "u2", Email: "a@x.com"}); err == nil {
+		t.Error("duplicate email not detected")
+	}
+	if err := imp.dedupe(&Record{Line: 4, OpenID: "u2"}); err != nil {
+		t.Errorf("rejected row must not reserve its keys: %v", err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	alice := &model.User{ID: 1, OpenID: "alice", Email: "a@x.com"}
+	bob := &model.User{ID: 2, OpenID: "bob", Email: "b@x.com"}
+	deleted := &model.User{ID: 3, OpenID: "gone", DeletedAt: gorm.DeletedAt{Valid: true}}
+	tests := []struct {
+		name    string
+		rec     *Record
+		open    *model.User
+		email   []*model.User
+		want    *model.User
+		wantErr bool
+	}{
+		{"new", &Record{OpenID: "carol"}, nil, nil, nil, false},
+		{"by open_id", &Record{OpenID: "alice"}, alice, nil, alice, false},
+		{"by email", &Record{Email: "a@x.com"}, nil, []*model.User{alice}, alice, false},
+		{"both agree", &Record{OpenID: "alice", Email: "a@x.com"}, alice, []*model.User{alice}, alice, false},
+		{"email owned by other", &Record{OpenID: "alice", Email: "b@x.com"}, alice, []*model.User{bob}, nil, true},
+		{"email owned by other open_id", &Record{OpenID: "carol", Email: "a@x.com"}, nil, []*model.User{alice}, nil, true},
+		{"ambiguous email", &Record{Email: "a@x.com"}, nil, []*model.User{alice, bob}, nil, true},
+		{"deleted open_id", &Record{OpenID: "gone"}, deleted, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := match(tt.rec, tt.open, tt.email)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("match = %v, %v; want %v, wantErr %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"errors"
+	"regexp"
+	"unicode/utf8"
+)
+
+// AdminCreateRequest 管理员创建请求
+// Password 为空时走邀请流程，否则使用初始密码创建并要求首次登录修改
+type AdminCreateRequest struct {
+	Username string `json:"username"`
+	Nickname string `json:"nickname"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// AdminUpdateRequest 管理员资料更新请求
+type AdminUpdateRequest struct {
+	Nickname *string `json:"nickname"`
+	Email    *string `json:"email"`
+	Avatar   *string `json:"avatar"`
+	Role     *string `json:"role"`
+}
+
+var (
+	usernamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]{2,49}$`)
+	emailPattern    = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+)
+
+// ValidateAdminCreate 验证管理员创建请求
+func ValidateAdminCreate(req *AdminCreateRequest) error {
+	if err := ValidateUsername(req.Username); err != nil {
+		return err
+	}
+
+	if utf8.RuneCountInString(req.Nickname) > 100 {
+		return errors.New("昵称不能超过100个字符")
+	}
+
+	if req.Email != "" {
+		if err := ValidateEmail(req.Email); err != nil {
+			return err
+		}
+	}
+
+	if req.Role != "" {
+		if err := ValidateAdminRole(req.Role); err != nil {
+			return err
+		}
+	}
+
+	// 邀请流程需要邮箱用于发送邀请
+	if req.Password == "" && req.Email == "" {
+		return errors.New("未设置初始密码时必须填写邮箱以发送邀请")
+	}
+
+	return nil
+}
+
+// ValidateAdminUpdate 验证管理员资料更新请求
+func ValidateAdminUpdate(req *AdminUpdateRequest) error {
+	if req.Nickname != nil && utf8.RuneCountInString(*req.Nickname) > 100 {
+		return errors.New("昵称不能超过100个字符")
+	}
+
+	if req.Email != nil && *req.Email != "" {
+		if err := ValidateEmail(*req.Email); err != nil {
+			return err
+		}
+	}
+
+	if req.Avatar != nil {
+		if err := validateURL(*req.Avatar); err != nil {
+			return errors.New("头像URL格式不正确")
+		}
+	}
+
+	if req.Role != nil {
+		if err := ValidateAdminRole(*req.Role); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateAdminRole 验证管理员角色
+func ValidateAdminRole(role string) error {
+	if role != "admin" && role != "viewer" {
+		return errors.New("角色只能是 admin 或 viewer")
+	}
+	return nil
+}
+
+// ValidateUsername 验证管理员用户名：字母开头，3-50位字母、数字、下划线、点或连字符
+func ValidateUsername(username string) error {
+	if username == "" {
+		return errors.New("用户名不能为空")
+	}
+	if len(username) < 3 || len(username) > 50 {
+		return errors.New("用户名长度应在3-50个字符之间")
+	}
+	if !usernamePattern.MatchString(username) {
+		return errors.New("用户名只能包含字母、数字、下划线、点和连字符，且必须以字母开头")
+	}
+	return nil
+}
+
+// ValidateEmail 验证邮箱格式
+func ValidateEmail(email string) error {
+	if len(email) > 100 {
+		return errors.New("邮箱长度不能超过100个字符")
+	}
+	if !emailPattern.MatchString(email) {
+		return errors.New("邮箱格式不正确")
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestValidateAdminCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *AdminCreateRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid request with initial password",
+			req:     &AdminCreateRequest{Username: "ops_admin", Password: "Init@1234"},
+			wantErr: false,
+		},
+		{
+			name:    "valid invite request",
+			req:     &AdminCreateRequest{Username: "alice.w", Email: "alice@example.com"},
+			wantErr: false,
+		},
+		{
+			name:    "empty username",
+			req:     &AdminCreateRequest{Password: "Init@1234"},
+			wantErr: true,
+			errMsg:  "用户名不能为空",
+		},
+		{
+			name:    "username starts with digit",
+			req:     &AdminCreateRequest{Username: "1admin", Password: "Init@1234"},
+			wantErr: true,
+			errMsg:  "用户名只能包含字母、数字、下划线、点和连字符，且必须以字母开头",
+		},
+		{
+			name:    "invalid email",
+			req:     &AdminCreateRequest{Username: "bob", Email: "bob@invalid"},
+			wantErr: true,
+			errMsg:  "邮箱格式不正确",
+		},
+		{
+			name:    "invite without email",
+			req:     &AdminCreateRequest{Username: "carol"},
+			wantErr: true,
+			errMsg:  "未设置初始密码时必须填写邮箱以发送邀请",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAdminCreate(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAdminCreate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && err.Error() != tt.errMsg {
+				t.Errorf("ValidateAdminCreate() error message = %v, want %v", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateAdminUpdate(t *testing.T) {
+	badAvatar := "ftp://example.com/a.png"
+	if err := ValidateAdminUpdate(&AdminUpdateRequest{Avatar: &badAvatar}); err == nil {
+		t.Error("expected error for non-http avatar url")
+	}
+
+	emptyEmail := ""
+	if err := ValidateAdminUpdate(&AdminUpdateRequest{Email: &emptyEmail}); err != nil {
+		t.Errorf("clearing email should be allowed, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 // AppCreateRequest APP创建请求
 type AppCreateRequest struct {
+	OrgID       uint     `json:"org_id"` // 所属组织，管理员只属于一个组织时可省略
 	Name        string   `json:"name"`
 	AppName     string   `json:"app_name"`
 	PackageName string   `json:"package_name"`
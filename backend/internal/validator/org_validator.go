@@ -0,0 +1,92 @@
+package validator
+
+import (
+	"errors"
+	"regexp"
+	"unicode/utf8"
+)
+
+// OrgCreateRequest 组织创建请求
+type OrgCreateRequest struct {
+	Name        string `json:"name"`
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+}
+
+// OrgUpdateRequest 组织资料和设置更新请求
+type OrgUpdateRequest struct {
+	Name           *string   `json:"name"`
+	Description    *string   `json:"description"`
+	DefaultModules *[]string `json:"default_modules"`
+	MaxApps        *int      `json:"max_apps"`
+	MaxMembers     *int      `json:"max_members"`
+}
+
+// OrgInviteRequest 组织成员邀请请求
+type OrgInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+var orgSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+// ValidateOrgCreate 验证组织创建请求
+func ValidateOrgCreate(req *OrgCreateRequest) error {
+	if err := validateOrgName(req.Name); err != nil {
+		return err
+	}
+	if !orgSlugPattern.MatchString(req.Slug) {
+		return errors.New("组织标识只能包含小写字母、数字和连字符，长度2-50个字符")
+	}
+	if utf8.RuneCountInString(req.Description) > 255 {
+		return errors.New("描述不能超过255个字符")
+	}
+	return nil
+}
+
+// ValidateOrgUpdate 验证组织更新请求
+func ValidateOrgUpdate(req *OrgUpdateRequest) error {
+	if req.Name != nil {
+		if err := validateOrgName(*req.Name); err != nil {
+			return err
+		}
+	}
+	if req.Description != nil && utf8.RuneCountInString(*req.Description) > 255 {
+		return errors.New("描述不能超过255个字符")
+	}
+	if req.MaxApps != nil && *req.MaxApps < 0 {
+		return errors.New("应用数量上限不能为负数")
+	}
+	if req.MaxMembers != nil && *req.MaxMembers < 0 {
+		return errors.New("成员数量上限不能为负数")
+	}
+	return nil
+}
+
+// ValidateOrgInvite 验证组织成员邀请请求
+func ValidateOrgInvite(req *OrgInviteRequest) error {
+	if req.Email == "" {
+		return errors.New("邮箱不能为空")
+	}
+	if err := ValidateEmail(req.Email); err != nil {
+		return err
+	}
+	return ValidateOrgRole(req.Role)
+}
+
+// ValidateOrgRole 验证组织成员角色
+func ValidateOrgRole(role string) error {
+	switch role {
+	case "owner", "admin", "member", "viewer":
+		return nil
+	}
+	return errors.New("角色只能是 owner、admin、member 或 viewer")
+}
+
+func validateOrgName(name string) error {
+	n := utf8.RuneCountInString(name)
+	if n < 2 || n > 100 {
+		return errors.New("组织名称长度应在2-100个字符之间")
+	}
+	return nil
+}
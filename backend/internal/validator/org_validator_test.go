@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestValidateOrgCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *OrgCreateRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "valid request",
+			req:     &OrgCreateRequest{Name: "支付事业部", Slug: "payments"},
+			wantErr: false,
+		},
+		{
+			name:    "name too short",
+			req:     &OrgCreateRequest{Name: "a", Slug: "payments"},
+			wantErr: true,
+			errMsg:  "组织名称长度应在2-100个字符之间",
+		},
+		{
+			name:    "uppercase slug",
+			req:     &OrgCreateRequest{Name: "Payments", Slug: "Payments"},
+			wantErr: true,
+			errMsg:  "组织标识只能包含小写字母、数字和连字符，长度2-50个字符",
+		},
+		{
+			name:    "slug starts with hyphen",
+			req:     &OrgCreateRequest{Name: "Payments", Slug: "-pay"},
+			wantErr: true,
+			errMsg:  "组织标识只能包含小写字母、数字和连字符，长度2-50个字符",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrgCreate(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOrgCreate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && err.Error() != tt.errMsg {
+				t.Errorf("ValidateOrgCreate() error = %v, want %v", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestValidateOrgUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *OrgUpdateRequest
+		wantErr bool
+	}{
+		{"empty update", &OrgUpdateRequest{}, false},
+		{"set quotas", &OrgUpdateRequest{MaxApps: intPtr(10), MaxMembers: intPtr(0)}, false},
+		{"negative max apps", &OrgUpdateRequest{MaxApps: intPtr(-1)}, true},
+		{"negative max members", &OrgUpdateRequest{MaxMembers: intPtr(-5)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateOrgUpdate(tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOrgUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOrgInvite(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *OrgInviteRequest
+		wantErr bool
+	}{
+		{"valid member invite", &OrgInviteRequest{Email: "alice@example.com", Role: "member"}, false},
+		{"missing email", &OrgInviteRequest{Role: "member"}, true},
+		{"invalid email", &OrgInviteRequest{Email: "alice", Role: "member"}, true},
+		{"unknown role", &OrgInviteRequest{Email: "alice@example.com", Role: "superuser"}, true},
+		{"empty role", &OrgInviteRequest{Email: "alice@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateOrgInvite(tt.req); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOrgInvite() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
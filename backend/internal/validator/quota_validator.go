@@ -0,0 +1,30 @@
+package validator
+
+import (
+	"errors"
+
+	"app-platform-backend/internal/quota"
+)
+
+// QuotaUpdateRequest 应用配额更新请求
+// Overrides 中的值为 null 表示取消单独设置，沿用套餐配额；0 表示不限制
+type QuotaUpdateRequest struct {
+	PlanID    *uint             `json:"plan_id"` // 0 表示使用默认套餐
+	Overrides map[string]*int64 `json:"overrides"`
+}
+
+// ValidateQuotaUpdate 验证应用配额更新请求
+func ValidateQuotaUpdate(req *QuotaUpdateRequest) error {
+	if req.PlanID == nil && len(req.Overrides) == 0 {
+		return errors.New("没有需要更新的配额")
+	}
+	for metric, v := range req.Overrides {
+		if _, ok := quota.Column(metric); !ok {
+			return errors.New("未知的配额项: " + metric)
+		}
+		if v != nil && *v < 0 {
+			return errors.New("配额不能为负数: " + metric)
+		}
+	}
+	return nil
+}
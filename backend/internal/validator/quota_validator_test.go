@@ -0,0 +1,60 @@
+package validator
+
+import (
+	"testing"
+)
+
+func TestValidateQuotaUpdate(t *testing.T) {
+	limit := int64(1000)
+	negative := int64(-1)
+	plan := uint(2)
+
+	tests := []struct {
+		name    string
+		req     *QuotaUpdateRequest
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name:    "plan only",
+			req:     &QuotaUpdateRequest{PlanID: &plan},
+			wantErr: false,
+		},
+		{
+			name:    "set and clear overrides",
+			req:     &QuotaUpdateRequest{Overrides: map[string]*int64{"events_per_day": &limit, "storage_bytes": nil}},
+			wantErr: false,
+		},
+		{
+			name:    "empty request",
+			req:     &QuotaUpdateRequest{},
+			wantErr: true,
+			errMsg:  "没有需要更新的配额",
+		},
+		{
+			name:    "unknown metric",
+			req:     &QuotaUpdateRequest{Overrides: map[string]*int64{"cpu": &limit}},
+			wantErr: true,
+			errMsg:  "未知的配额项: cpu",
+		},
+		{
+			name:    "negative limit",
+			req:     &QuotaUpdateRequest{Overrides: map[string]*int64{"logs_per_day": &negative}},
+			wantErr: true,
+			errMsg:  "配额不能为负数: logs_per_day",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateQuotaUpdate(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuotaUpdate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && err.Error() != tt.errMsg {
+				t.Errorf("ValidateQuotaUpdate() error = %v, want %v", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
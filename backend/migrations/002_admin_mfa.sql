@@ -0,0 +1,17 @@
+-- 管理员双因素认证字段
+ALTER TABLE `admins`
+  ADD COLUMN `totp_secret` VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'TOTP密钥(Base32)',
+  ADD COLUMN `totp_enabled` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否启用TOTP',
+  ADD COLUMN `totp_last_step` BIGINT NOT NULL DEFAULT 0 COMMENT '最近一次使用的时间步，防止验证码重放',
+  ADD COLUMN `totp_enabled_at` DATETIME DEFAULT NULL COMMENT 'TOTP启用时间';
+
+-- 管理员双因素恢复码表
+CREATE TABLE IF NOT EXISTS `admin_recovery_codes` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `admin_id` INT UNSIGNED NOT NULL COMMENT '管理员ID',
+  `code_hash` VARCHAR(64) NOT NULL COMMENT '恢复码SHA-256哈希',
+  `used_at` DATETIME DEFAULT NULL COMMENT '使用时间，NULL表示未使用',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_admin_id` (`admin_id`),
+  INDEX `idx_admin_code` (`admin_id`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员双因素恢复码表';
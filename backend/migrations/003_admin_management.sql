@@ -0,0 +1,11 @@
+-- 管理员账号管理字段
+ALTER TABLE `admins`
+  ADD COLUMN `email` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '邮箱',
+  ADD COLUMN `must_change_password` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '下次登录是否必须修改密码',
+  ADD COLUMN `token_version` INT NOT NULL DEFAULT 0 COMMENT '令牌版本，递增后已签发令牌失效',
+  ADD COLUMN `invite_token_hash` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '邀请令牌SHA-256哈希',
+  ADD COLUMN `invite_expires_at` DATETIME DEFAULT NULL COMMENT '邀请过期时间',
+  ADD COLUMN `password_changed_at` DATETIME DEFAULT NULL COMMENT '最近修改密码时间',
+  ADD COLUMN `last_login_at` DATETIME DEFAULT NULL COMMENT '最近登录时间',
+  ADD COLUMN `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人管理员ID',
+  ADD INDEX `idx_invite_token_hash` (`invite_token_hash`);
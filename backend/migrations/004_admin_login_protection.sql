@@ -0,0 +1,23 @@
+-- 管理员登录失败统计表（按用户名）
+CREATE TABLE IF NOT EXISTS `admin_login_attempts` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `username` VARCHAR(50) NOT NULL COMMENT '登录用户名',
+  `failed_count` INT NOT NULL DEFAULT 0 COMMENT '窗口期内连续失败次数',
+  `lockout_count` INT NOT NULL DEFAULT 0 COMMENT '累计锁定次数，用于渐进延长锁定时间',
+  `locked_until` DATETIME DEFAULT NULL COMMENT '锁定截止时间',
+  `last_failed_at` DATETIME DEFAULT NULL COMMENT '最近失败时间',
+  `last_failed_ip` VARCHAR(50) DEFAULT NULL COMMENT '最近失败IP',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_username` (`username`),
+  INDEX `idx_locked_until` (`locked_until`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员登录失败统计表';
+
+-- 管理员历史密码表
+CREATE TABLE IF NOT EXISTS `admin_password_histories` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `admin_id` INT UNSIGNED NOT NULL COMMENT '管理员ID',
+  `password_hash` VARCHAR(255) NOT NULL COMMENT 'bcrypt哈希',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员历史密码表';
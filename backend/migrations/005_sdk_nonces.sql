@@ -0,0 +1,9 @@
+-- 客户端SDK签名请求随机串表（防重放，过期记录定期清理）
+CREATE TABLE IF NOT EXISTS `sdk_nonces` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `nonce` VARCHAR(64) NOT NULL COMMENT '请求随机串',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_nonce` (`app_id`, `nonce`),
+  INDEX `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='SDK请求随机串表';
@@ -0,0 +1,15 @@
+-- 应用密钥表（AES-GCM加密存储，支持轮换过渡期与吊销）
+CREATE TABLE IF NOT EXISTS `app_credentials` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `secret_encrypted` VARCHAR(255) NOT NULL COMMENT '加密后的密钥',
+  `secret_hint` VARCHAR(20) DEFAULT NULL COMMENT '密钥末4位',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人',
+  `expires_at` DATETIME DEFAULT NULL COMMENT '过期时间，为空表示长期有效',
+  `last_used_at` DATETIME DEFAULT NULL COMMENT '最近使用时间',
+  `revoked_at` DATETIME DEFAULT NULL COMMENT '吊销时间',
+  `revoked_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '吊销人',
+  `revoke_reason` VARCHAR(255) DEFAULT NULL COMMENT '吊销原因',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用密钥表';
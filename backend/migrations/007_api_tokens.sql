@@ -0,0 +1,18 @@
+-- 自动化API令牌表（CI等非交互场景使用，只保存令牌哈希）
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `name` VARCHAR(100) NOT NULL COMMENT '令牌名称',
+  `token_hash` VARCHAR(64) NOT NULL COMMENT '令牌SHA256哈希',
+  `token_prefix` VARCHAR(20) NOT NULL COMMENT '令牌前缀',
+  `scopes` JSON NOT NULL COMMENT '允许的模块功能Code列表',
+  `app_ids` JSON NOT NULL COMMENT '允许访问的应用ID列表',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '签发人',
+  `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+  `last_used_at` DATETIME DEFAULT NULL COMMENT '最近使用时间',
+  `last_used_ip` VARCHAR(50) DEFAULT NULL COMMENT '最近使用IP',
+  `revoked_at` DATETIME DEFAULT NULL COMMENT '吊销时间',
+  `revoked_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '吊销人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_token_hash` (`token_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='自动化API令牌表';
@@ -0,0 +1,17 @@
+-- 管理员角色与单点登录
+ALTER TABLE `admins`
+  ADD COLUMN `role` VARCHAR(20) NOT NULL DEFAULT 'admin' COMMENT '角色：admin/viewer' AFTER `status`,
+  ADD COLUMN `sso_subject` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '身份提供方用户标识(sub)' AFTER `role`,
+  ADD INDEX `idx_sso_subject` (`sso_subject`);
+
+-- 单点登录授权请求（state/nonce/PKCE，回调时一次性消费）
+CREATE TABLE IF NOT EXISTS `admin_sso_states` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `state_hash` VARCHAR(64) NOT NULL COMMENT 'state哈希',
+  `nonce` VARCHAR(64) NOT NULL COMMENT 'ID Token nonce',
+  `code_verifier` VARCHAR(128) NOT NULL COMMENT 'PKCE code_verifier',
+  `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_state_hash` (`state_hash`),
+  INDEX `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='管理员单点登录授权请求表';
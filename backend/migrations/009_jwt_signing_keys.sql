@@ -0,0 +1,15 @@
+-- 管理后台会话令牌签名密钥表（RS256/EdDSA，私钥加密存储，支持轮换）
+CREATE TABLE IF NOT EXISTS `jwt_signing_keys` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `kid` VARCHAR(64) NOT NULL COMMENT '密钥ID，写入令牌头部',
+  `algorithm` VARCHAR(16) NOT NULL COMMENT '签名算法',
+  `public_key` TEXT NOT NULL COMMENT 'PEM格式公钥',
+  `private_key_encrypted` TEXT NOT NULL COMMENT '加密后的私钥',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'active' COMMENT 'active/retired',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人，0为自动轮换',
+  `retired_at` DATETIME DEFAULT NULL COMMENT '轮换时间',
+  `verify_until` DATETIME DEFAULT NULL COMMENT '公钥保留截止时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_kid` (`kid`),
+  INDEX `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='JWT签名密钥表';
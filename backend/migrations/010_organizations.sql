@@ -0,0 +1,62 @@
+-- 组织：应用归属于组织，管理员通过组织成员关系访问应用
+CREATE TABLE IF NOT EXISTS `organizations` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `name` VARCHAR(100) NOT NULL COMMENT '组织名称',
+  `slug` VARCHAR(50) NOT NULL COMMENT '组织标识',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '描述',
+  `default_modules` JSON DEFAULT NULL COMMENT '新建应用默认启用的模块',
+  `max_apps` INT NOT NULL DEFAULT 0 COMMENT '应用数量上限，0表示不限制',
+  `max_members` INT NOT NULL DEFAULT 0 COMMENT '成员数量上限，0表示不限制',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deleted_at` DATETIME DEFAULT NULL,
+  UNIQUE INDEX `idx_slug` (`slug`),
+  INDEX `idx_deleted_at` (`deleted_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织表';
+
+-- 组织成员
+CREATE TABLE IF NOT EXISTS `org_members` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `org_id` INT UNSIGNED NOT NULL COMMENT '组织ID',
+  `admin_id` INT UNSIGNED NOT NULL COMMENT '管理员ID',
+  `role` VARCHAR(20) NOT NULL COMMENT '角色：owner/admin/member/viewer',
+  `invited_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '邀请人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_org_admin` (`org_id`, `admin_id`),
+  INDEX `idx_admin_id` (`admin_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织成员表';
+
+-- 组织成员邀请（只保存令牌哈希）
+CREATE TABLE IF NOT EXISTS `org_invitations` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `org_id` INT UNSIGNED NOT NULL COMMENT '组织ID',
+  `email` VARCHAR(100) NOT NULL COMMENT '被邀请人邮箱',
+  `role` VARCHAR(20) NOT NULL COMMENT '加入后的角色',
+  `token_hash` VARCHAR(64) NOT NULL COMMENT '邀请令牌SHA-256哈希',
+  `invited_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '邀请人',
+  `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+  `accepted_at` DATETIME DEFAULT NULL COMMENT '接受时间',
+  `accepted_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '接受人管理员ID',
+  `revoked_at` DATETIME DEFAULT NULL COMMENT '撤销时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_token_hash` (`token_hash`),
+  INDEX `idx_org_id` (`org_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='组织成员邀请表';
+
+ALTER TABLE `apps`
+  ADD COLUMN `org_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '所属组织ID' AFTER `id`,
+  ADD INDEX `idx_org_id` (`org_id`);
+
+-- 已有应用归入默认组织，已有管理员按原角色加入，保持升级前的访问范围不变
+INSERT INTO `organizations` (`name`, `slug`, `description`)
+SELECT '默认组织', 'default', '升级时自动创建，包含已有的全部应用'
+FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM `organizations` WHERE `slug` = 'default');
+
+UPDATE `apps` SET `org_id` = (SELECT `id` FROM `organizations` WHERE `slug` = 'default') WHERE `org_id` = 0;
+
+INSERT IGNORE INTO `org_members` (`org_id`, `admin_id`, `role`)
+SELECT o.`id`, a.`id`, IF(a.`role` = 'viewer', 'viewer', 'owner')
+FROM `admins` a JOIN `organizations` o ON o.`slug` = 'default'
+WHERE a.`deleted_at` IS NULL;
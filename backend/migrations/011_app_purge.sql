@@ -0,0 +1,23 @@
+-- 应用删除后进入待清理状态，恢复期满由后台任务清理全部应用数据
+ALTER TABLE `apps`
+  ADD COLUMN `purge_after` DATETIME DEFAULT NULL COMMENT '可恢复截止时间，之后清理应用数据' AFTER `status`,
+  ADD COLUMN `deleted_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '删除人' AFTER `purge_after`,
+  ADD INDEX `idx_purge_after` (`purge_after`);
+
+-- 应用数据清理任务
+CREATE TABLE IF NOT EXISTS `app_purge_jobs` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed',
+  `current_module` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '正在清理的模块',
+  `deleted_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '已删除行数',
+  `progress` JSON DEFAULT NULL COMMENT '各模块已删除行数',
+  `error` TEXT COMMENT '最近一次失败原因',
+  `lease_until` DATETIME DEFAULT NULL COMMENT '执行租约到期时间',
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用数据清理任务表';
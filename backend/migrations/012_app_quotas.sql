@@ -0,0 +1,54 @@
+-- 应用配额：套餐提供默认配额，应用可单独覆盖，0 表示不限制
+CREATE TABLE IF NOT EXISTS `plans` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `code` VARCHAR(50) NOT NULL COMMENT '套餐标识',
+  `name` VARCHAR(100) NOT NULL COMMENT '套餐名称',
+  `is_default` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否为默认套餐',
+  `max_storage_bytes` BIGINT NOT NULL DEFAULT 0 COMMENT '文件存储总字节数',
+  `max_files` BIGINT NOT NULL DEFAULT 0 COMMENT '文件数量',
+  `events_per_day` BIGINT NOT NULL DEFAULT 0 COMMENT '每天事件上报数',
+  `logs_per_day` BIGINT NOT NULL DEFAULT 0 COMMENT '每天日志上报数',
+  `metrics_per_minute` BIGINT NOT NULL DEFAULT 0 COMMENT '每分钟监控指标上报数',
+  `pushes_per_day` BIGINT NOT NULL DEFAULT 0 COMMENT '每天创建推送数',
+  `max_ws_connections` BIGINT NOT NULL DEFAULT 0 COMMENT 'WebSocket并发连接数（单实例）',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='配额套餐表';
+
+-- 应用套餐及单独设置的配额，字段为 NULL 表示沿用套餐
+CREATE TABLE IF NOT EXISTS `app_quotas` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `plan_id` INT UNSIGNED DEFAULT NULL COMMENT '套餐ID，为空使用默认套餐',
+  `max_storage_bytes` BIGINT DEFAULT NULL,
+  `max_files` BIGINT DEFAULT NULL,
+  `events_per_day` BIGINT DEFAULT NULL,
+  `logs_per_day` BIGINT DEFAULT NULL,
+  `metrics_per_minute` BIGINT DEFAULT NULL,
+  `pushes_per_day` BIGINT DEFAULT NULL,
+  `max_ws_connections` BIGINT DEFAULT NULL,
+  `updated_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最后修改人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_id` (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用配额表';
+
+-- 按时间窗口累计的用量计数
+CREATE TABLE IF NOT EXISTS `app_usage_counters` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `metric` VARCHAR(30) NOT NULL COMMENT '配额项',
+  `window_start` DATETIME NOT NULL COMMENT '窗口开始时间',
+  `count` BIGINT NOT NULL DEFAULT 0 COMMENT '窗口内用量',
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_metric_window` (`app_id`, `metric`, `window_start`),
+  INDEX `idx_window_start` (`window_start`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用量计数表';
+
+INSERT IGNORE INTO `plans`
+  (`code`, `name`, `is_default`, `max_storage_bytes`, `max_files`, `events_per_day`, `logs_per_day`, `metrics_per_minute`, `pushes_per_day`, `max_ws_connections`)
+VALUES
+  ('free', '免费版', 1, 1073741824, 10000, 100000, 100000, 600, 100, 100),
+  ('pro', '专业版', 0, 53687091200, 500000, 5000000, 5000000, 12000, 5000, 5000),
+  ('enterprise', '企业版', 0, 0, 0, 0, 0, 0, 0, 0);
@@ -0,0 +1,82 @@
+-- 应用环境：每个应用默认有 dev、staging、prod 三个环境，各自有独立的密钥、模块配置和配置中心命名空间
+CREATE TABLE IF NOT EXISTS `app_environments` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `code` VARCHAR(20) NOT NULL COMMENT '环境标识',
+  `name` VARCHAR(50) NOT NULL COMMENT '环境名称',
+  `sort_order` INT NOT NULL DEFAULT 0 COMMENT '晋级顺序',
+  `is_default` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否为默认环境',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_env` (`app_id`, `code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用环境表';
+
+-- 应用模块在各环境中的配置
+CREATE TABLE IF NOT EXISTS `app_module_configs` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `env_id` INT UNSIGNED NOT NULL COMMENT '环境ID',
+  `module_code` VARCHAR(50) NOT NULL COMMENT '模块编码',
+  `config` JSON DEFAULT NULL COMMENT '模块配置',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_env_module` (`app_id`, `env_id`, `module_code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用模块环境配置表';
+
+-- 模块配置历史，按环境记录
+CREATE TABLE IF NOT EXISTS `module_config_histories` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `env_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '环境ID',
+  `module_code` VARCHAR(50) NOT NULL COMMENT '模块编码',
+  `config` JSON DEFAULT NULL COMMENT '配置快照',
+  `version` INT NOT NULL DEFAULT 0 COMMENT '版本号',
+  `operator` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作人',
+  `remark` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '备注',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_env_id` (`env_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='模块配置历史表';
+
+-- 已有应用（包括恢复期内的）创建默认环境，生产环境为默认环境
+INSERT IGNORE INTO `app_environments` (`app_id`, `code`, `name`, `sort_order`, `is_default`)
+SELECT `id`, 'dev', '开发', 1, 0 FROM `apps`
+UNION ALL SELECT `id`, 'staging', '预发布', 2, 0 FROM `apps`
+UNION ALL SELECT `id`, 'prod', '生产', 3, 1 FROM `apps`;
+
+-- 已有密钥、模块配置和配置中心数据归入生产环境
+ALTER TABLE `app_credentials`
+  ADD COLUMN `env_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '所属环境ID' AFTER `app_id`,
+  ADD INDEX `idx_env_id` (`env_id`);
+
+UPDATE `app_credentials` c JOIN `app_environments` e ON e.`app_id` = c.`app_id` AND e.`code` = 'prod'
+SET c.`env_id` = e.`id` WHERE c.`env_id` = 0;
+
+INSERT IGNORE INTO `app_module_configs` (`app_id`, `env_id`, `module_code`, `config`)
+SELECT m.`app_id`, e.`id`, m.`module_code`, COALESCE(m.`config`, JSON_OBJECT())
+FROM `app_modules` m JOIN `app_environments` e ON e.`app_id` = m.`app_id` AND e.`code` = 'prod'
+WHERE m.`deleted_at` IS NULL;
+
+UPDATE `module_config_histories` h JOIN `app_environments` e ON e.`app_id` = h.`app_id` AND e.`code` = 'prod'
+SET h.`env_id` = e.`id` WHERE h.`env_id` = 0;
+
+ALTER TABLE `configs`
+  ADD COLUMN `env_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '所属环境ID' AFTER `app_id`,
+  DROP INDEX `uk_app_key`,
+  ADD UNIQUE KEY `uk_app_env_key` (`app_id`, `env_id`, `config_key`);
+
+UPDATE `configs` c JOIN `app_environments` e ON e.`app_id` = c.`app_id` AND e.`code` = 'prod'
+SET c.`env_id` = e.`id` WHERE c.`env_id` = 0;
+
+-- 上报数据记录所属环境，已有数据视为生产环境
+ALTER TABLE `events`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT 'prod' COMMENT '环境' AFTER `app_id`,
+  ADD INDEX `idx_app_env` (`app_id`, `env`);
+
+ALTER TABLE `logs`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT 'prod' COMMENT '环境' AFTER `app_id`,
+  ADD INDEX `idx_app_env` (`app_id`, `env`);
+
+ALTER TABLE `monitor_metrics`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT 'prod' COMMENT '环境' AFTER `app_id`,
+  ADD INDEX `idx_app_env` (`app_id`, `env`);
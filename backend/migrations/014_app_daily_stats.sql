@@ -0,0 +1,17 @@
+-- 应用每日统计汇总，由定时任务从用户、事件、日志和推送明细表汇总，控制台首页按此表展示趋势
+CREATE TABLE IF NOT EXISTS `app_daily_stats` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `date` DATE NOT NULL COMMENT '统计日期',
+  `new_users` BIGINT NOT NULL DEFAULT 0 COMMENT '新增用户数',
+  `events` BIGINT NOT NULL DEFAULT 0 COMMENT '上报事件数',
+  `errors` BIGINT NOT NULL DEFAULT 0 COMMENT 'error级别日志数',
+  `pushes_sent` BIGINT NOT NULL DEFAULT 0 COMMENT '推送送达设备数',
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_date` (`app_id`, `date`),
+  INDEX `idx_date` (`date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用每日统计表';
+
+-- 汇总查询按时间范围扫描明细表
+ALTER TABLE `users` ADD INDEX `idx_created_at` (`created_at`);
+ALTER TABLE `push_records` ADD INDEX `idx_sent_at` (`sent_at`);
@@ -0,0 +1,4 @@
+-- 应用用户按应用隔离：open_id 在应用内唯一，记录用户来源以便从外部身份源同步
+ALTER TABLE `users`
+  ADD COLUMN `source` VARCHAR(50) NOT NULL DEFAULT 'native' COMMENT '用户来源：native 或外部身份源名称' AFTER `status`,
+  ADD UNIQUE KEY `uk_app_open_id` (`app_id`, `open_id`);
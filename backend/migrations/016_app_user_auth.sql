@@ -0,0 +1,38 @@
+-- 终端用户认证：登录密码、登录会话和一次性验证码
+ALTER TABLE `users`
+  ADD COLUMN `password_hash` VARCHAR(255) DEFAULT NULL COMMENT '登录密码bcrypt哈希' AFTER `source`,
+  ADD INDEX `idx_app_email` (`app_id`, `email`),
+  ADD INDEX `idx_app_phone` (`app_id`, `phone`);
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `access_token_hash` VARCHAR(64) NOT NULL COMMENT '访问令牌SHA256哈希',
+  `refresh_token_hash` VARCHAR(64) NOT NULL COMMENT '刷新令牌SHA256哈希',
+  `access_expires_at` DATETIME NOT NULL COMMENT '访问令牌过期时间',
+  `refresh_expires_at` DATETIME NOT NULL COMMENT '刷新令牌过期时间',
+  `client_ip` VARCHAR(50) DEFAULT NULL COMMENT '登录IP',
+  `user_agent` VARCHAR(500) DEFAULT NULL COMMENT '登录客户端',
+  `revoked_at` DATETIME DEFAULT NULL COMMENT '注销时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_access_token_hash` (`access_token_hash`),
+  UNIQUE INDEX `idx_refresh_token_hash` (`refresh_token_hash`),
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用户登录会话表';
+
+CREATE TABLE IF NOT EXISTS `user_verify_codes` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `target` VARCHAR(255) NOT NULL COMMENT '邮箱或手机号',
+  `channel` VARCHAR(20) NOT NULL COMMENT '发送渠道：email/sms',
+  `purpose` VARCHAR(30) NOT NULL COMMENT '用途：login/register/reset_password',
+  `code_hash` VARCHAR(64) NOT NULL COMMENT '验证码SHA256哈希',
+  `attempts` INT NOT NULL DEFAULT 0 COMMENT '已尝试次数',
+  `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+  `used_at` DATETIME DEFAULT NULL COMMENT '使用时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_app_target` (`app_id`, `target`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用户验证码表';
@@ -0,0 +1,55 @@
+-- 用户标签和动态分群，推送、消息和版本灰度可按标签或分群圈选用户
+CREATE TABLE IF NOT EXISTS `user_tags` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `name` VARCHAR(50) NOT NULL COMMENT '标签名',
+  `description` VARCHAR(255) DEFAULT NULL COMMENT '描述',
+  `color` VARCHAR(20) DEFAULT NULL COMMENT '显示颜色',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY `uk_app_tag_name` (`app_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用户标签表';
+
+CREATE TABLE IF NOT EXISTS `user_tag_members` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `tag_id` INT UNSIGNED NOT NULL COMMENT '标签ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY `uk_tag_user` (`tag_id`, `user_id`),
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户标签关联表';
+
+CREATE TABLE IF NOT EXISTS `user_segments` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `name` VARCHAR(100) NOT NULL COMMENT '分群名称',
+  `description` VARCHAR(255) DEFAULT NULL COMMENT '描述',
+  `rules` JSON NOT NULL COMMENT '分群规则',
+  `member_count` BIGINT NOT NULL DEFAULT 0 COMMENT '最近一次物化的成员数',
+  `evaluated_at` DATETIME DEFAULT NULL COMMENT '最近一次物化时间',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='动态用户分群表';
+
+CREATE TABLE IF NOT EXISTS `user_segment_members` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `segment_id` INT UNSIGNED NOT NULL COMMENT '分群ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY `uk_segment_user` (`segment_id`, `user_id`),
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='分群成员表';
+
+-- 分群的行为条件按 (app_id, user_id, event_code, created_at) 统计事件
+ALTER TABLE `events` ADD INDEX `idx_app_user_event` (`app_id`, `user_id`, `event_code`, `created_at`);
+
+-- 版本灰度对象
+ALTER TABLE `versions`
+  ADD COLUMN `target_type` VARCHAR(50) NOT NULL DEFAULT 'all' COMMENT '灰度对象：all/user/tag/segment' AFTER `is_force_update`,
+  ADD COLUMN `target_ids` TEXT COMMENT '灰度对象ID，逗号分隔' AFTER `target_type`;
@@ -0,0 +1,28 @@
+-- 用户自定义属性和匿名设备识别，登录前后的事件通过 identify 归并到同一用户
+CREATE TABLE IF NOT EXISTS `user_profiles` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `properties` JSON NOT NULL COMMENT '自定义属性',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY `uk_app_user` (`app_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户自定义属性表';
+
+CREATE TABLE IF NOT EXISTS `anonymous_profiles` (
+  `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `anonymous_id` VARCHAR(64) NOT NULL COMMENT '匿名设备ID',
+  `properties` JSON NOT NULL COMMENT '自定义属性',
+  `user_id` INT UNSIGNED DEFAULT NULL COMMENT '已合并到的应用用户ID',
+  `merged_at` DATETIME DEFAULT NULL COMMENT '合并时间',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY `uk_app_anonymous` (`app_id`, `anonymous_id`),
+  INDEX `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='匿名设备属性表';
+
+-- 事件记录匿名设备ID，identify 时按 (app_id, anonymous_id) 回填 user_id
+ALTER TABLE `events`
+  ADD COLUMN `anonymous_id` VARCHAR(64) NOT NULL DEFAULT '' COMMENT '匿名设备ID' AFTER `user_id`,
+  ADD INDEX `idx_app_anonymous` (`app_id`, `anonymous_id`);
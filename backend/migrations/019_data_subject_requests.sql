@@ -0,0 +1,29 @@
+-- 数据主体请求：按应用用户导出全部数据，或删除并匿名化，各模块通过导出/删除钩子参与
+CREATE TABLE IF NOT EXISTS `data_subject_requests` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `type` VARCHAR(20) NOT NULL COMMENT '请求类型：export/erase',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed',
+  `subject` JSON DEFAULT NULL COMMENT '数据主体快照，删除完成后清空',
+  `report` JSON NOT NULL COMMENT '各模块处理的记录数及删除后的残留核验',
+  `report_hash` VARCHAR(64) DEFAULT NULL COMMENT '报告的SHA-256',
+  `archive_path` VARCHAR(500) DEFAULT NULL COMMENT '导出归档路径',
+  `archive_size` BIGINT NOT NULL DEFAULT 0 COMMENT '导出归档大小',
+  `expires_at` DATETIME DEFAULT NULL COMMENT '导出归档下载截止时间',
+  `error` TEXT COMMENT '失败原因',
+  `requested_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '发起的管理员ID',
+  `lease_until` DATETIME DEFAULT NULL COMMENT '执行实例租约',
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_user_id` (`user_id`),
+  INDEX `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='数据主体请求表';
+
+-- 日志按上下文中的 user_id 定位数据主体
+ALTER TABLE `logs` ADD COLUMN `context_user_id` VARCHAR(64)
+  GENERATED ALWAYS AS (JSON_UNQUOTE(JSON_EXTRACT(`context`, '$.user_id'))) VIRTUAL COMMENT '上下文中的用户ID',
+  ADD INDEX `idx_app_context_user` (`app_id`, `context_user_id`);
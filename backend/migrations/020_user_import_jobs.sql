@@ -0,0 +1,28 @@
+-- 应用用户批量导入任务：上传 CSV/NDJSON 文件后由后台任务分批校验和写入
+CREATE TABLE IF NOT EXISTS `user_import_jobs` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `filename` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '上传的原始文件名',
+  `format` VARCHAR(20) NOT NULL COMMENT '文件格式：csv/ndjson',
+  `mapping` JSON NOT NULL COMMENT '目标字段到文件列名的映射',
+  `on_conflict` VARCHAR(20) NOT NULL DEFAULT 'skip' COMMENT '已存在用户的处理方式：skip/update',
+  `dry_run` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '试运行：只校验不写入',
+  `file_path` VARCHAR(500) NOT NULL DEFAULT '' COMMENT '上传文件路径，完成后删除',
+  `status` VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态：pending/running/completed/failed',
+  `total_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '文件中的数据行数',
+  `processed_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '已处理的行数',
+  `created_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '新建的用户数',
+  `updated_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '更新的用户数',
+  `skipped_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '跳过的已存在用户数',
+  `failed_rows` BIGINT NOT NULL DEFAULT 0 COMMENT '出错的行数',
+  `error_report_path` VARCHAR(500) NOT NULL DEFAULT '' COMMENT '错误报告路径',
+  `error` TEXT COMMENT '任务失败原因',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '发起的管理员ID',
+  `lease_until` DATETIME DEFAULT NULL COMMENT '执行实例租约',
+  `started_at` DATETIME DEFAULT NULL,
+  `finished_at` DATETIME DEFAULT NULL,
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用户导入任务表';
@@ -0,0 +1,11 @@
+-- 应用用户封禁：禁用状态附带原因和到期时间，到期后自动解除
+ALTER TABLE `users`
+  ADD COLUMN `ban_reason` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '封禁原因' AFTER `password_hash`,
+  ADD COLUMN `banned_until` DATETIME DEFAULT NULL COMMENT '封禁到期时间，禁用且为空表示永久封禁' AFTER `ban_reason`,
+  ADD COLUMN `banned_at` DATETIME DEFAULT NULL COMMENT '封禁时间' AFTER `banned_until`,
+  ADD INDEX `idx_status_banned_until` (`status`, `banned_until`);
+
+-- 上报时上下文中的用户处于封禁中的日志
+ALTER TABLE `logs`
+  ADD COLUMN `flagged` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '上报用户处于封禁中' AFTER `ip`,
+  ADD INDEX `idx_app_flagged` (`app_id`, `flagged`);
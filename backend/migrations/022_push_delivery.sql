@@ -0,0 +1,17 @@
+-- 推送按设备经各推送通道投递，通道凭证取推送记录所属环境的推送服务模块配置
+ALTER TABLE `push_records`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '所属环境，为空时使用默认环境' AFTER `app_id`,
+  ADD COLUMN `error` VARCHAR(500) NOT NULL DEFAULT '' COMMENT '发送失败原因或最近一次设备投递错误' AFTER `failed_count`;
+
+-- 应用用户的推送设备
+CREATE TABLE IF NOT EXISTS `push_devices` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `provider` VARCHAR(20) NOT NULL COMMENT '推送通道：apns/fcm/huawei/xiaomi/oppo/vivo/mock',
+  `token` VARCHAR(255) NOT NULL COMMENT '设备令牌',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_app_provider_token` (`app_id`, `provider`, `token`),
+  INDEX `idx_app_user` (`app_id`, `user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='推送设备表';
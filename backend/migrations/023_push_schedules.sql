@@ -0,0 +1,11 @@
+-- 定时和周期推送由调度器认领发送，按设备ID记录投递进度，发送中断后从断点继续
+ALTER TABLE `push_records`
+  ADD COLUMN `cron` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '周期推送的 cron 表达式' AFTER `error`,
+  ADD COLUMN `parent_id` INT UNSIGNED DEFAULT NULL COMMENT '周期推送生成的子推送所属的周期推送' AFTER `cron`,
+  ADD COLUMN `cursor` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '已投递到的设备ID' AFTER `scheduled_at`,
+  ADD COLUMN `lease_until` DATETIME DEFAULT NULL COMMENT '发送实例租约' AFTER `cursor`,
+  ADD INDEX `idx_parent_id` (`parent_id`),
+  ADD INDEX `idx_status_scheduled_at` (`status`, `scheduled_at`);
+
+-- 已设置发送时间的待发送推送改由调度器发送
+UPDATE `push_records` SET `status` = 'scheduled' WHERE `status` = 'pending' AND `scheduled_at` IS NOT NULL AND `deleted_at` IS NULL;
@@ -0,0 +1,8 @@
+-- 推送设备登记：终端登记设备令牌及平台、版本、语言和时区，通道报告令牌失效时标记失效
+ALTER TABLE `push_devices`
+  ADD COLUMN `platform` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '平台：ios/android/harmony/web' AFTER `user_id`,
+  ADD COLUMN `app_version` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '应用版本' AFTER `token`,
+  ADD COLUMN `locale` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '语言，例如 zh-CN' AFTER `app_version`,
+  ADD COLUMN `timezone` VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'IANA 时区，例如 Asia/Shanghai' AFTER `locale`,
+  ADD COLUMN `last_seen_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '最近一次登记或刷新的时间' AFTER `timezone`,
+  ADD COLUMN `invalidated_at` DATETIME DEFAULT NULL COMMENT '通道报告令牌失效的时间' AFTER `last_seen_at`;
@@ -0,0 +1,22 @@
+-- 推送模板：按应用管理标题、正文、数据、类型化变量和语言版本，按接收用户和设备语言渲染
+CREATE TABLE IF NOT EXISTS `push_templates` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `name` VARCHAR(100) NOT NULL COMMENT '模板名称',
+  `description` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '模板说明',
+  `title` VARCHAR(255) NOT NULL COMMENT '标题，可用 {{变量名}} 引用变量',
+  `body` TEXT NOT NULL COMMENT '正文',
+  `data` JSON NOT NULL COMMENT '随通知下发的数据',
+  `variables` JSON NOT NULL COMMENT '变量声明：名称、类型、默认值、是否必填',
+  `locales` JSON NOT NULL COMMENT '按语言标签的标题、正文和数据',
+  `created_by` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE INDEX `uk_app_push_template` (`app_id`, `name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='推送模板表';
+
+-- 推送引用模板时保存创建推送时传入的变量
+ALTER TABLE `push_records`
+  ADD COLUMN `template_id` INT UNSIGNED DEFAULT NULL COMMENT '推送模板ID' AFTER `error`,
+  ADD COLUMN `variables` TEXT COMMENT '模板变量，JSON对象' AFTER `template_id`,
+  ADD INDEX `idx_template_id` (`template_id`);
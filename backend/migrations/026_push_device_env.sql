@@ -0,0 +1,12 @@
+-- 推送设备按环境隔离：设备只接收登记时SDK签名密钥所属环境的推送，避免测试凭证的推送发到生产设备
+ALTER TABLE `push_devices`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '登记设备的环境' AFTER `app_id`;
+
+-- 已登记的设备归入应用的默认环境
+UPDATE `push_devices` d
+  JOIN `app_environments` e ON e.`app_id` = d.`app_id` AND e.`is_default` = 1
+  SET d.`env` = e.`code`;
+
+ALTER TABLE `push_devices`
+  DROP INDEX `idx_app_provider_token`,
+  ADD UNIQUE INDEX `idx_app_env_provider_token` (`app_id`, `env`, `provider`, `token`);
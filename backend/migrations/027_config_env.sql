@@ -0,0 +1,12 @@
+-- 配置中心按环境编码划分命名空间，与事件、日志一致，请求通过 env 参数或 SDK 签名密钥确定环境
+ALTER TABLE `configs`
+  ADD COLUMN `env` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '所属环境' AFTER `app_id`;
+
+UPDATE `configs` c
+  JOIN `app_environments` e ON e.`id` = c.`env_id`
+  SET c.`env` = e.`code`;
+
+ALTER TABLE `configs`
+  DROP INDEX `uk_app_env_key`,
+  DROP COLUMN `env_id`,
+  ADD UNIQUE KEY `uk_app_env_key` (`app_id`, `env`, `config_key`);
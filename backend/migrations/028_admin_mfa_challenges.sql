@@ -0,0 +1,10 @@
+-- 已使用的MFA登录挑战令牌（按令牌ID去重，防止挑战令牌在有效期内被重放）
+CREATE TABLE IF NOT EXISTS `admin_mfa_challenges` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `token_id` VARCHAR(64) NOT NULL COMMENT '挑战令牌ID(jti)',
+  `admin_id` INT UNSIGNED NOT NULL COMMENT '管理员ID',
+  `expires_at` DATETIME NOT NULL COMMENT '令牌过期时间，过期后记录可清理',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE INDEX `idx_token_id` (`token_id`),
+  INDEX `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='已使用的管理员MFA登录挑战表';
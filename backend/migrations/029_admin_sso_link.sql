@@ -0,0 +1,3 @@
+-- 单点登录不再按邮箱自动关联已有账号，本地管理员登录后显式发起关联，授权请求记录发起人
+ALTER TABLE `admin_sso_states`
+  ADD COLUMN `link_admin_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '发起关联的管理员ID，0表示登录' AFTER `code_verifier`;
@@ -0,0 +1,4 @@
+-- 配置项定向：只对命中目标用户、标签或分群的终端用户下发，与版本灰度使用相同的目标类型
+ALTER TABLE `configs`
+  ADD COLUMN `target_type` VARCHAR(50) NOT NULL DEFAULT 'all' COMMENT '定向对象：all/user/tag/segment' AFTER `config_value`,
+  ADD COLUMN `target_ids` TEXT COMMENT '定向对象ID，逗号分隔' AFTER `target_type`;
@@ -0,0 +1,11 @@
+-- 封禁变更事件：多实例部署时各实例轮询此表，清除本地封禁缓存并断开被封禁用户在本实例上的连接
+CREATE TABLE IF NOT EXISTS `user_ban_events` (
+  `id` INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `app_id` INT UNSIGNED NOT NULL COMMENT '应用ID',
+  `user_id` INT UNSIGNED NOT NULL COMMENT '应用用户ID',
+  `open_id` VARCHAR(255) NOT NULL DEFAULT '' COMMENT '应用用户open_id',
+  `banned` TINYINT(1) NOT NULL DEFAULT 0 COMMENT '1封禁 0解除封禁',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `idx_app_id` (`app_id`),
+  INDEX `idx_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='应用用户封禁变更事件表';
@@ -3,6 +3,10 @@ package config
 import (
 "app-platform-backend/core/module"
 configapi "app-platform-backend/internal/api/v1/config"
+"app-platform-backend/internal/middleware"
+"app-platform-backend/internal/model"
+"app-platform-backend/internal/pkg/database"
+"app-platform-backend/internal/repository"
 "github.com/gin-gonic/gin"
 )
 
@@ -20,11 +24,42 @@ return []module.Function{
 {Code: "config_history", Name: "配置历史", Type: "passive", Description: "查看配置历史"},
 }
 }
+// RegisterRoutes 配置按应用和环境隔离，环境由 env 参数或 X-App-Env 请求头指定，未指定时为默认环境
 func (m *ConfigModule) RegisterRoutes(group *gin.RouterGroup) {
-group.GET("/configs", configapi.List)
-group.POST("/configs", configapi.Create)
-group.PUT("/configs/:id", configapi.Update)
-group.POST("/configs/:id/publish", configapi.Publish)
-group.GET("/configs/:id/history", configapi.History)
+configapi.InitDB(database.GetDB())
+g := group.Group("/configs", middleware.AppScopeMiddleware())
+g.GET("", configapi.List)
+g.POST("", configapi.Create)
+g.PUT("/:id", configapi.Update)
+g.POST("/:id/publish", configapi.Publish)
+g.GET("/:id/history", configapi.History)
+}
+// RegisterClientRoutes 客户端读取已发布配置，环境由SDK签名密钥确定，定向配置按当前登录用户匹配
+func (m *ConfigModule) RegisterClientRoutes(group *gin.RouterGroup) {
+group.GET("/configs", configapi.ClientList)
 }
 func (m *ConfigModule) Init() error { return nil }
+// PurgeAppData 清理已删除应用的配置，配置历史通过 config_id 关联，随所属配置一起删除
+func (m *ConfigModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+db := database.GetDB()
+var ids []uint
+if err := db.Unscoped().Model(&model.Config{}).Scopes(repository.ForApp(db, appID).Scope).
+Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+return 0, err
+}
+if len(ids) == 0 {
+return 0, nil
+}
+var deleted int64
+err := database.WithTransaction(func(tx *database.DB) error {
+result := tx.Where("config_id IN ?", ids).Delete(&model.ConfigHistory{})
+if result.Error != nil {
+return result.Error
+}
+deleted = result.RowsAffected
+result = tx.Unscoped().Delete(&model.Config{}, ids)
+deleted += result.RowsAffected
+return result.Error
+})
+return deleted, err
+}
@@ -3,7 +3,10 @@ package event
 import (
 	"app-platform-backend/core/module"
 	eventapi "app-platform-backend/internal/api/v1/event"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,6 +26,7 @@ func (m *EventModule) GetFunctions() []module.Function {
 		{Code: "event_list", Name: "事件列表", Type: "passive", Description: "获取事件列表"},
 		{Code: "event_stats", Name: "事件统计", Type: "passive", Description: "事件数据统计"},
 		{Code: "event_funnel", Name: "漏斗分析", Type: "passive", Description: "漏斗分析"},
+		{Code: "event_retention", Name: "留存分析", Type: "passive", Description: "留存分析"},
 		{Code: "event_definition", Name: "事件定义", Type: "passive", Description: "管理事件定义"},
 	}
 }
@@ -30,13 +34,14 @@ func (m *EventModule) GetFunctions() []module.Function {
 func (m *EventModule) RegisterRoutes(group *gin.RouterGroup) {
 	eventapi.InitDB(database.GetDB())
 
-	g := group.Group("/events")
+	g := group.Group("/events", middleware.AppScopeMiddleware())
 	{
 		g.GET("", eventapi.List)
 		g.POST("", eventapi.Report)
 		g.POST("/batch", eventapi.BatchReport)
 		g.GET("/stats", eventapi.Stats)
 		g.GET("/funnel", eventapi.Funnel)
+		g.GET("/retention", eventapi.Retention)
 		// 事件定义管理
 		g.GET("/definitions", eventapi.Definitions)
 		g.POST("/definitions", eventapi.CreateDefinition)
@@ -45,4 +50,28 @@ func (m *EventModule) RegisterRoutes(group *gin.RouterGroup) {
 	}
 }
 
+// RegisterClientRoutes 注册SDK事件上报路由
+func (m *EventModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	g := group.Group("/events")
+	{
+		g.POST("", eventapi.Report)
+		g.POST("/batch", eventapi.BatchReport)
+	}
+}
+
 func (m *EventModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的事件和事件定义
+func (m *EventModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.Event{}, &model.EventDefinition{})
+}
+
+// ExportUserData 导出该用户的事件
+func (m *EventModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return eventapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 匿名化该用户的事件
+func (m *EventModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return eventapi.EraseUserData(database.GetDB(), subject)
+}
@@ -5,6 +5,7 @@ import (
 	fileapi "app-platform-backend/internal/api/v1/file"
 	"app-platform-backend/internal/middleware"
 	"app-platform-backend/internal/pkg/database"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,17 +32,38 @@ func (m *FileModule) GetFunctions() []module.Function {
 func (m *FileModule) RegisterRoutes(group *gin.RouterGroup) {
 	fileapi.InitDB(database.GetDB())
 
-	g := group.Group("/files")
+	g := group.Group("/files", middleware.AppScopeMiddleware())
 	{
-		g.GET("", fileapi.List)
+		// 文件上传下载可由CI使用API令牌调用
+		middleware.ScopedRoute(g, http.MethodGet, "", "file_list", fileapi.List)
 		// 文件上传限流: 20次/分钟/IP，防止恶意上传
-		g.POST("", middleware.APIRateLimitMiddleware(20, time.Minute), fileapi.Upload)
-		g.GET("/stats", fileapi.Stats)
-		g.GET("/:id", fileapi.Detail)
-		g.GET("/download/:id", fileapi.Download)
-		g.DELETE("/:id", fileapi.Delete)
+		middleware.ScopedRoute(g, http.MethodPost, "", "file_upload", middleware.APIRateLimitMiddleware(20, time.Minute), fileapi.Upload)
+		middleware.ScopedRoute(g, http.MethodGet, "/stats", "file_stats", fileapi.Stats)
+		middleware.ScopedRoute(g, http.MethodGet, "/:id", "file_list", fileapi.Detail)
+		middleware.ScopedRoute(g, http.MethodGet, "/download/:id", "file_download", fileapi.Download)
+		middleware.ScopedRoute(g, http.MethodDelete, "/:id", "file_delete", fileapi.Delete)
 		g.POST("/batch-delete", fileapi.BatchDelete)
 	}
 }
 
+// RegisterClientRoutes 注册终端用户上传路由，上传记录关联到当前用户
+func (m *FileModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	group.POST("/files", middleware.RequireAppUser(), middleware.APIRateLimitMiddleware(20, time.Minute), fileapi.Upload)
+}
+
 func (m *FileModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的文件记录和物理文件
+func (m *FileModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return fileapi.PurgeAppData(database.GetDB(), appID, batchSize)
+}
+
+// ExportUserData 导出该用户上传的文件
+func (m *FileModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return fileapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 删除该用户上传的文件
+func (m *FileModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return fileapi.EraseUserData(database.GetDB(), subject)
+}
@@ -3,7 +3,10 @@ package log
 import (
 	"app-platform-backend/core/module"
 	logapi "app-platform-backend/internal/api/v1/log"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,7 +32,7 @@ func (m *LogModule) GetFunctions() []module.Function {
 func (m *LogModule) RegisterRoutes(group *gin.RouterGroup) {
 	logapi.InitDB(database.GetDB())
 
-	g := group.Group("/logs")
+	g := group.Group("/logs", middleware.AppScopeMiddleware())
 	{
 		g.GET("", logapi.List)
 		g.POST("/report", logapi.Report)
@@ -43,4 +46,28 @@ func (m *LogModule) RegisterRoutes(group *gin.RouterGroup) {
 	}
 }
 
+// RegisterClientRoutes 注册SDK日志上报路由
+func (m *LogModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	g := group.Group("/logs")
+	{
+		g.POST("/report", logapi.Report)
+		g.POST("/batch-report", logapi.BatchReport)
+	}
+}
+
 func (m *LogModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的日志
+func (m *LogModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.Log{})
+}
+
+// ExportUserData 导出上下文中包含该用户的日志
+func (m *LogModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return logapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 去除日志上下文中的该用户
+func (m *LogModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return logapi.EraseUserData(database.GetDB(), subject)
+}
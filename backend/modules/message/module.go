@@ -3,7 +3,10 @@ package message
 import (
 	"app-platform-backend/core/module"
 	messageapi "app-platform-backend/internal/api/v1/message"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,11 +33,12 @@ func (m *MessageModule) GetFunctions() []module.Function {
 func (m *MessageModule) RegisterRoutes(group *gin.RouterGroup) {
 	messageapi.InitDB(database.GetDB())
 
-	g := group.Group("/messages")
+	group.GET("/messages/templates", messageapi.Templates)
+
+	g := group.Group("/messages", middleware.AppScopeMiddleware())
 	{
 		g.GET("", messageapi.List)
 		g.POST("", messageapi.Send)
-		g.GET("/templates", messageapi.Templates)
 		g.GET("/unread", messageapi.UnreadCount)
 		g.GET("/stats", messageapi.Stats)
 		g.GET("/:id", messageapi.Detail)
@@ -47,3 +51,18 @@ func (m *MessageModule) RegisterRoutes(group *gin.RouterGroup) {
 }
 
 func (m *MessageModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的消息
+func (m *MessageModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.Message{})
+}
+
+// ExportUserData 导出发给该用户的消息
+func (m *MessageModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return messageapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 删除发给该用户的消息
+func (m *MessageModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return messageapi.EraseUserData(database.GetDB(), subject)
+}
@@ -3,7 +3,10 @@ package monitor
 import (
 	"app-platform-backend/core/module"
 	monitorapi "app-platform-backend/internal/api/v1/monitor"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -29,13 +32,14 @@ func (m *MonitorModule) GetFunctions() []module.Function {
 func (m *MonitorModule) RegisterRoutes(group *gin.RouterGroup) {
 	monitorapi.InitDB(database.GetDB())
 
-	g := group.Group("/monitor")
+	group.GET("/monitor/health", monitorapi.Health)
+
+	g := group.Group("/monitor", middleware.AppScopeMiddleware())
 	{
 		g.GET("/metrics", monitorapi.Metrics)
 		g.POST("/metrics", monitorapi.ReportMetric)
 		g.GET("/metrics/stats", monitorapi.MetricStats)
 		g.GET("/stats", monitorapi.Stats)
-		g.GET("/health", monitorapi.Health)
 		// 告警管理
 		g.GET("/alerts", monitorapi.Alerts)
 		g.POST("/alerts", monitorapi.CreateAlert)
@@ -47,4 +51,14 @@ func (m *MonitorModule) RegisterRoutes(group *gin.RouterGroup) {
 	}
 }
 
+// RegisterClientRoutes 注册SDK指标上报路由
+func (m *MonitorModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	group.POST("/monitor/metrics", monitorapi.ReportMetric)
+}
+
 func (m *MonitorModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的监控指标和告警规则
+func (m *MonitorModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.MonitorMetric{}, &model.MonitorAlert{})
+}
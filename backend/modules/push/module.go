@@ -3,7 +3,11 @@ package push
 import (
 	"app-platform-backend/core/module"
 	pushapi "app-platform-backend/internal/api/v1/push"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,12 +34,21 @@ func (m *PushModule) GetFunctions() []module.Function {
 func (m *PushModule) RegisterRoutes(group *gin.RouterGroup) {
 	pushapi.InitDB(database.GetDB())
 
-	g := group.Group("/push")
+	group.GET("/push/providers", pushapi.Providers)
+
+	g := group.Group("/push", middleware.AppScopeMiddleware())
 	{
 		g.GET("", pushapi.List)
 		g.POST("", pushapi.Create)
 		g.GET("/stats", pushapi.Stats)
-		g.GET("/templates", pushapi.Templates)
+		g.GET("/templates", pushapi.ListTemplates)
+		g.POST("/templates", pushapi.CreateTemplate)
+		g.GET("/templates/:template_id", pushapi.GetTemplate)
+		g.PUT("/templates/:template_id", pushapi.UpdateTemplate)
+		g.DELETE("/templates/:template_id", pushapi.DeleteTemplate)
+		g.POST("/templates/:template_id/preview", pushapi.PreviewTemplate)
+		g.GET("/devices", pushapi.Devices)
+		g.DELETE("/devices/:id", pushapi.DeleteDevice)
 		g.GET("/:id", pushapi.Detail)
 		g.POST("/:id/send", pushapi.Send)
 		g.POST("/:id/cancel", pushapi.Cancel)
@@ -45,4 +58,28 @@ func (m *PushModule) RegisterRoutes(group *gin.RouterGroup) {
 	}
 }
 
+// RegisterClientRoutes 注册终端设备令牌登记和注销路由
+func (m *PushModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	g := group.Group("/push/devices", middleware.RequireAppUser(), middleware.APIRateLimitMiddleware(30, time.Minute))
+	{
+		g.POST("", pushapi.RegisterDevice)
+		g.DELETE("", pushapi.UnregisterDevice)
+	}
+}
+
 func (m *PushModule) Init() error { return nil }
+
+// PurgeAppData 清理已删除应用的推送记录、推送模板和推送设备
+func (m *PushModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.PushRecord{}, &model.PushTemplate{}, &model.PushDevice{})
+}
+
+// ExportUserData 导出以该用户为目标的推送和该用户的推送设备
+func (m *PushModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return pushapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 删除该用户的推送设备并从推送目标中移除该用户
+func (m *PushModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return pushapi.EraseUserData(database.GetDB(), subject)
+}
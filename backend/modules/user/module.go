@@ -1,9 +1,15 @@
 package user
 
 import (
+	"net/http"
+	"time"
+
 	"app-platform-backend/core/module"
 	userapi "app-platform-backend/internal/api/v1/user"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,16 +31,87 @@ func (m *UserModule) GetFunctions() []module.Function {
 	return []module.Function{
 		{Code: "user_list", Name: "用户列表", Type: "passive", Description: "获取APP用户列表"},
 		{Code: "user_detail", Name: "用户详情", Type: "passive", Description: "获取用户详细信息"},
-		{Code: "user_status", Name: "用户状态管理", Type: "active", Description: "启用/禁用用户"},
+		{Code: "user_status", Name: "用户状态管理", Type: "active", Description: "启用/禁用用户，封禁和解除封禁"},
 		{Code: "user_stats", Name: "用户统计", Type: "passive", Description: "用户数据统计"},
+		{Code: "user_sync", Name: "用户同步", Type: "active", Description: "从外部身份源同步用户"},
+		{Code: "user_auth", Name: "用户认证", Type: "passive", Description: "终端用户注册、登录和令牌管理"},
+		{Code: "user_tag", Name: "用户标签", Type: "active", Description: "管理标签并为用户打标"},
+		{Code: "user_segment", Name: "用户分群", Type: "active", Description: "按属性和行为规则圈选用户"},
+		{Code: "user_profile", Name: "用户属性", Type: "active", Description: "管理用户自定义属性"},
+		{Code: "user_identify", Name: "用户识别", Type: "active", Description: "将匿名设备的属性和事件合并到已知用户"},
+		{Code: "user_data_request", Name: "用户数据请求", Type: "active", Description: "导出或删除用户在各模块中的全部数据"},
+		{Code: "user_import", Name: "用户导入", Type: "active", Description: "从 CSV 或 NDJSON 文件批量导入用户"},
+		{Code: "user_export", Name: "用户导出", Type: "passive", Description: "按筛选条件导出用户"},
 	}
 }
 
 func (m *UserModule) RegisterRoutes(group *gin.RouterGroup) {
-	group.GET("/users", userapi.List)
-	group.GET("/users/:id", userapi.Detail)
-	group.PUT("/users/:id/status", userapi.UpdateStatus)
-	group.GET("/users/stats", userapi.Stats)
+	group.GET("/users/sources", userapi.Sources)
+
+	g := group.Group("/users", middleware.AppScopeMiddleware())
+	{
+		g.GET("", userapi.List)
+		g.GET("/stats", userapi.Stats)
+		// 批量导入导出可由迁移脚本使用API令牌调用
+		middleware.ScopedRoute(g, http.MethodGet, "/export", "user_export", userapi.Export)
+		middleware.ScopedRoute(g, http.MethodPost, "/import", "user_import", userapi.Import)
+		middleware.ScopedRoute(g, http.MethodGet, "/import-jobs", "user_import", userapi.ListImportJobs)
+		middleware.ScopedRoute(g, http.MethodGet, "/import-jobs/:job_id", "user_import", userapi.GetImportJob)
+		middleware.ScopedRoute(g, http.MethodGet, "/import-jobs/:job_id/errors", "user_import", userapi.DownloadImportErrors)
+		g.POST("/sync", userapi.Sync)
+		g.GET("/:id", userapi.Detail)
+		g.GET("/:id/tags", userapi.UserTags)
+		g.GET("/:id/properties", userapi.GetUserProperties)
+		// 属性更新和匿名设备识别可由业务系统使用API令牌调用
+		middleware.ScopedRoute(g, http.MethodPost, "/:id/properties", "user_profile", userapi.UpdateUserProperties)
+		middleware.ScopedRoute(g, http.MethodPost, "/alias", "user_identify", userapi.Alias)
+		g.PUT("/:id/status", userapi.UpdateStatus)
+		// 封禁可由风控系统使用API令牌调用
+		middleware.ScopedRoute(g, http.MethodPost, "/:id/ban", "user_status", userapi.Ban)
+		middleware.ScopedRoute(g, http.MethodPost, "/:id/unban", "user_status", userapi.Unban)
+		g.POST("/:id/data-requests", userapi.CreateDataRequest)
+		g.GET("/data-requests", userapi.ListDataRequests)
+		g.GET("/data-requests/:request_id", userapi.GetDataRequest)
+		g.GET("/data-requests/:request_id/download", userapi.DownloadDataRequest)
+
+		g.GET("/tags", userapi.ListTags)
+		g.POST("/tags", userapi.CreateTag)
+		g.PUT("/tags/:tag_id", userapi.UpdateTag)
+		g.DELETE("/tags/:tag_id", userapi.DeleteTag)
+		g.GET("/tags/:tag_id/users", userapi.TagUsers)
+		// 打标可由业务系统使用API令牌调用
+		middleware.ScopedRoute(g, http.MethodPost, "/tags/:tag_id/members", "user_tag", userapi.AddTagMembers)
+		middleware.ScopedRoute(g, http.MethodDelete, "/tags/:tag_id/members", "user_tag", userapi.RemoveTagMembers)
+
+		g.GET("/segments", userapi.ListSegments)
+		g.POST("/segments", userapi.CreateSegment)
+		g.POST("/segments/preview", userapi.PreviewSegment)
+		g.GET("/segments/:segment_id", userapi.GetSegment)
+		g.PUT("/segments/:segment_id", userapi.UpdateSegment)
+		g.DELETE("/segments/:segment_id", userapi.DeleteSegment)
+		g.POST("/segments/:segment_id/refresh", userapi.RefreshSegment)
+		g.GET("/segments/:segment_id/users", userapi.SegmentUsers)
+	}
+}
+
+// RegisterClientRoutes 注册终端用户认证、属性和识别路由
+func (m *UserModule) RegisterClientRoutes(group *gin.RouterGroup) {
+	group.POST("/profile", userapi.UpdateMyProfile)
+	group.POST("/identify", middleware.RequireAppUser(), userapi.Identify)
+
+	// 登录、注册和发送验证码按IP限流，防止暴力尝试和短信轰炸
+	limit := middleware.APIRateLimitMiddleware(10, time.Minute)
+	g := group.Group("/auth")
+	{
+		g.POST("/code", limit, userapi.SendCode)
+		g.POST("/register", limit, userapi.Register)
+		g.POST("/login", limit, userapi.Login)
+		g.POST("/login/code", limit, userapi.LoginByCode)
+		g.POST("/password/reset", limit, userapi.ResetPassword)
+		g.POST("/refresh", userapi.Refresh)
+		g.POST("/logout", middleware.RequireAppUser(), userapi.Logout)
+		g.GET("/me", middleware.RequireAppUser(), userapi.Me)
+	}
 }
 
 func (m *UserModule) Init() error {
@@ -42,3 +119,27 @@ func (m *UserModule) Init() error {
 	userapi.InitDB(database.GetDB())
 	return nil
 }
+
+// PurgeAppData 清理已删除应用的应用用户及其会话、验证码、标签、分群、属性、封禁事件、数据请求和导入任务
+// 带导出归档的数据请求和导入任务先删除磁盘文件，避免记录删除后文件无人清理
+func (m *UserModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	if n, err := userapi.PurgeDataRequestArchives(database.GetDB(), appID, batchSize); err != nil || n > 0 {
+		return n, err
+	}
+	if n, err := userapi.PurgeImportFiles(database.GetDB(), appID, batchSize); err != nil || n > 0 {
+		return n, err
+	}
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.DataSubjectRequest{},
+		&model.UserSession{}, &model.UserVerifyCode{}, &model.UserTagMember{}, &model.UserTag{},
+		&model.UserSegmentMember{}, &model.UserSegment{}, &model.UserProfile{}, &model.AnonymousProfile{}, &model.UserBanEvent{}, &model.User{})
+}
+
+// ExportUserData 导出用户资料、属性、会话、标签和分群
+func (m *UserModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return userapi.ExportUserData(database.GetDB(), subject, w)
+}
+
+// EraseUserData 删除并匿名化用户资料
+func (m *UserModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return userapi.EraseUserData(database.GetDB(), subject)
+}
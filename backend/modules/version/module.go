@@ -1,9 +1,14 @@
 package version
 
 import (
+	"net/http"
+
 	"app-platform-backend/core/module"
 	versionapi "app-platform-backend/internal/api/v1/version"
+	"app-platform-backend/internal/middleware"
+	"app-platform-backend/internal/model"
 	"app-platform-backend/internal/pkg/database"
+	"app-platform-backend/internal/repository"
 
 	"github.com/gin-gonic/gin"
 )
@@ -34,17 +39,30 @@ func (m *VersionModule) GetFunctions() []module.Function {
 }
 
 func (m *VersionModule) RegisterRoutes(group *gin.RouterGroup) {
-	group.GET("/versions", versionapi.List)
-	group.POST("/versions", versionapi.Create)
-	group.PUT("/versions/:id", versionapi.Update)
-	group.DELETE("/versions/:id", versionapi.Delete)
-	group.POST("/versions/:id/publish", versionapi.Publish)
-	group.POST("/versions/:id/offline", versionapi.Offline)
+	g := group.Group("/versions", middleware.AppScopeMiddleware())
+
+	// 版本发布可由CI使用API令牌调用
+	middleware.ScopedRoute(g, http.MethodGet, "", "version_list", versionapi.List)
+	middleware.ScopedRoute(g, http.MethodPost, "", "version_create", versionapi.Create)
+	middleware.ScopedRoute(g, http.MethodPut, "/:id", "version_create", versionapi.Update)
+	g.DELETE("/:id", versionapi.Delete)
+	middleware.ScopedRoute(g, http.MethodPost, "/:id/publish", "version_publish", versionapi.Publish)
+	middleware.ScopedRoute(g, http.MethodPost, "/:id/offline", "version_offline", versionapi.Offline)
+	g.GET("/check", versionapi.CheckUpdate)
+	middleware.ScopedRoute(g, http.MethodGet, "/stats", "version_stats", versionapi.Stats)
+}
+
+// RegisterClientRoutes 注册SDK版本检查路由
+func (m *VersionModule) RegisterClientRoutes(group *gin.RouterGroup) {
 	group.GET("/versions/check", versionapi.CheckUpdate)
-	group.GET("/versions/stats", versionapi.Stats)
 }
 
 func (m *VersionModule) Init() error {
 	versionapi.InitDB(database.GetDB())
 	return nil
 }
+
+// PurgeAppData 清理已删除应用的版本
+func (m *VersionModule) PurgeAppData(appID uint, batchSize int) (int64, error) {
+	return repository.ForApp(database.GetDB(), appID).PurgeInOrder(batchSize, &model.Version{})
+}
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"app-platform-backend/core/module"
+	wsapi "app-platform-backend/internal/api/v1/websocket"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,3 +29,13 @@ func (m *WebSocketModule) RegisterRoutes(group *gin.RouterGroup) {
 }
 
 func (m *WebSocketModule) Init() error { return nil }
+
+// ExportUserData 导出该用户当前的WebSocket连接
+func (m *WebSocketModule) ExportUserData(subject module.DataSubject, w module.UserDataWriter) error {
+	return wsapi.ExportUserData(subject, w)
+}
+
+// EraseUserData 断开该用户的WebSocket连接
+func (m *WebSocketModule) EraseUserData(subject module.DataSubject) (map[string]int64, error) {
+	return wsapi.EraseUserData(subject)
+}
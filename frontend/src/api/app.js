@@ -7,12 +7,57 @@ export const updateApp = (id, data) => request.put(`/apps/${id}`, data)
 export const deleteApp = (id) => request.delete(`/apps/${id}`)
 export const resetAppSecret = (id) => request.post(`/apps/${id}/reset-secret`)
 export const getAppDetail = (id) => request.get(`/apps/${id}`)
+export const getDeletedApps = (params) => request.get('/apps/deleted', { params })
+export const restoreApp = (id) => request.post(`/apps/deleted/${id}/restore`)
+export const getAppUsage = (id) => request.get(`/apps/${id}/usage`)
+export const updateAppQuota = (id, data) => request.put(`/apps/${id}/quota`, data)
+export const getPlans = () => request.get('/plans')
+
+// 应用环境
+export const getAppEnvironments = (id) => request.get(`/apps/${id}/environments`)
+export const diffAppEnvironments = (id, params) => request.get(`/apps/${id}/environments/diff`, { params })
+export const promoteAppConfigs = (id, data) => request.post(`/apps/${id}/environments/promote`, data)
 
 // 用户管理
 export const getUserList = (params) => request.get('/users', { params })
-export const getUserDetail = (id) => request.get(`/users/${id}`)
-export const updateUserStatus = (id, status) => request.put(`/users/${id}/status`, { status })
+export const getUserDetail = (appId, id) => request.get(`/users/${id}`, { params: { app_id: appId } })
+export const updateUserStatus = (appId, id, status) => request.put(`/users/${id}/status`, { status }, { params: { app_id: appId } })
+export const banUser = (appId, id, data) => request.post(`/users/${id}/ban`, data, { params: { app_id: appId } })
+export const unbanUser = (appId, id) => request.post(`/users/${id}/unban`, {}, { params: { app_id: appId } })
 export const getUserStats = (appId) => request.get('/users/stats', { params: { app_id: appId } })
+export const getIdentitySources = () => request.get('/users/sources')
+export const syncUsers = (appId, source) => request.post('/users/sync', { app_id: appId, source })
+export const exportUsers = (appId, params) => request.get('/users/export', { params: { ...params, app_id: appId }, responseType: 'blob' })
+export const importUsers = (appId, formData) => request.post('/users/import', formData, { params: { app_id: appId }, headers: { 'Content-Type': 'multipart/form-data' } })
+export const getUserImportJobs = (appId, params) => request.get('/users/import-jobs', { params: { ...params, app_id: appId } })
+export const getUserImportJob = (appId, id) => request.get(`/users/import-jobs/${id}`, { params: { app_id: appId } })
+export const downloadUserImportErrors = (appId, id) => request.get(`/users/import-jobs/${id}/errors`, { params: { app_id: appId }, responseType: 'blob' })
+
+// 用户数据导出与删除
+export const createUserDataRequest = (appId, userId, type) => request.post(`/users/${userId}/data-requests`, { type }, { params: { app_id: appId } })
+export const getUserDataRequests = (appId, params) => request.get('/users/data-requests', { params: { ...params, app_id: appId } })
+export const getUserDataRequest = (appId, id) => request.get(`/users/data-requests/${id}`, { params: { app_id: appId } })
+export const downloadUserDataExport = (appId, id) => request.get(`/users/data-requests/${id}/download`, { params: { app_id: appId }, responseType: 'blob' })
+
+// 用户标签与分群
+export const getUserTags = (appId) => request.get('/users/tags', { params: { app_id: appId } })
+export const createUserTag = (appId, data) => request.post('/users/tags', data, { params: { app_id: appId } })
+export const updateUserTag = (appId, id, data) => request.put(`/users/tags/${id}`, data, { params: { app_id: appId } })
+export const deleteUserTag = (appId, id) => request.delete(`/users/tags/${id}`, { params: { app_id: appId } })
+export const getTagUsers = (appId, id, params) => request.get(`/users/tags/${id}/users`, { params: { ...params, app_id: appId } })
+export const addTagMembers = (appId, id, data) => request.post(`/users/tags/${id}/members`, data, { params: { app_id: appId } })
+export const removeTagMembers = (appId, id, data) => request.delete(`/users/tags/${id}/members`, { data, params: { app_id: appId } })
+export const getUserTagList = (appId, userId) => request.get(`/users/${userId}/tags`, { params: { app_id: appId } })
+export const getUserProperties = (appId, userId) => request.get(`/users/${userId}/properties`, { params: { app_id: appId } })
+export const updateUserProperties = (appId, userId, ops) => request.post(`/users/${userId}/properties`, ops, { params: { app_id: appId } })
+export const getUserSegments = (appId) => request.get('/users/segments', { params: { app_id: appId } })
+export const getUserSegment = (appId, id) => request.get(`/users/segments/${id}`, { params: { app_id: appId } })
+export const createUserSegment = (appId, data) => request.post('/users/segments', data, { params: { app_id: appId } })
+export const updateUserSegment = (appId, id, data) => request.put(`/users/segments/${id}`, data, { params: { app_id: appId } })
+export const deleteUserSegment = (appId, id) => request.delete(`/users/segments/${id}`, { params: { app_id: appId } })
+export const previewUserSegment = (appId, rules) => request.post('/users/segments/preview', { rules }, { params: { app_id: appId } })
+export const refreshUserSegment = (appId, id) => request.post(`/users/segments/${id}/refresh`, null, { params: { app_id: appId } })
+export const getSegmentUsers = (appId, id, params) => request.get(`/users/segments/${id}/users`, { params: { ...params, app_id: appId } })
 
 // 日志服务
 export const getLogList = (params) => request.get('/logs', { params })
@@ -24,25 +69,34 @@ export const cleanLogs = (data) => request.delete('/logs/clean', { data })
 // 消息中心
 export const getMessageList = (params) => request.get('/messages', { params })
 export const sendMessage = (data) => request.post('/messages', data)
-export const getMessageDetail = (id) => request.get(`/messages/${id}`)
-export const markMessageRead = (id) => request.put(`/messages/${id}/read`)
+export const getMessageDetail = (appId, id) => request.get(`/messages/${id}`, { params: { app_id: appId } })
+export const markMessageRead = (appId, id) => request.post(`/messages/${id}/read`, { app_id: appId })
 export const getMessageStats = (appId) => request.get('/messages/stats', { params: { app_id: appId } })
 export const batchSendMessage = (data) => request.post('/messages/batch', data)
 
 // 版本管理
 export const getVersionList = (params) => request.get('/versions', { params })
 export const createVersion = (data) => request.post('/versions', data)
-export const publishVersion = (id) => request.post(`/versions/${id}/publish`)
-export const offlineVersion = (id) => request.post(`/versions/${id}/offline`)
+export const publishVersion = (appId, id) => request.post(`/versions/${id}/publish`, { app_id: appId })
+export const offlineVersion = (appId, id) => request.post(`/versions/${id}/offline`, { app_id: appId })
 export const checkUpdate = (params) => request.get('/versions/check', { params })
 
 // 推送服务
 export const getPushList = (params) => request.get('/push', { params })
 export const createPush = (data) => request.post('/push', data)
-export const getPushDetail = (id) => request.get(`/push/${id}`)
-export const sendPush = (id) => request.post(`/push/${id}/send`)
-export const cancelPush = (id) => request.post(`/push/${id}/cancel`)
+export const getPushDetail = (appId, id) => request.get(`/push/${id}`, { params: { app_id: appId } })
+export const sendPush = (appId, id) => request.post(`/push/${id}/send`, { app_id: appId })
+export const cancelPush = (appId, id) => request.post(`/push/${id}/cancel`, { app_id: appId })
 export const getPushStats = (appId) => request.get('/push/stats', { params: { app_id: appId } })
+export const getPushProviders = () => request.get('/push/providers')
+export const getPushTemplates = (appId) => request.get('/push/templates', { params: { app_id: appId } })
+export const getPushTemplate = (appId, id) => request.get(`/push/templates/${id}`, { params: { app_id: appId } })
+export const createPushTemplate = (appId, data) => request.post('/push/templates', data, { params: { app_id: appId } })
+export const updatePushTemplate = (appId, id, data) => request.put(`/push/templates/${id}`, data, { params: { app_id: appId } })
+export const deletePushTemplate = (appId, id) => request.delete(`/push/templates/${id}`, { params: { app_id: appId } })
+export const previewPushTemplate = (appId, id, data) => request.post(`/push/templates/${id}/preview`, data, { params: { app_id: appId } })
+export const getPushDevices = (params) => request.get('/push/devices', { params })
+export const deletePushDevice = (appId, id) => request.delete(`/push/devices/${id}`, { params: { app_id: appId } })
 
 // 数据埋点
 export const reportEvent = (data) => request.post('/events', data)
@@ -50,6 +104,7 @@ export const batchReportEvents = (data) => request.post('/events/batch', data)
 export const getEventList = (params) => request.get('/events', { params })
 export const getEventStats = (params) => request.get('/events/stats', { params })
 export const getFunnelAnalysis = (params) => request.get('/events/funnel', { params })
+export const getRetentionAnalysis = (params) => request.get('/events/retention', { params })
 
 // 监控告警
 export const getMonitorMetrics = (params) => request.get('/monitor/metrics', { params })
@@ -66,24 +121,26 @@ export const getConfigHistory = (appId, moduleCode) => request.get('/configs/his
 
 // 统计数据
 export const getDashboardStats = () => request.get('/stats')
+export const getDashboardTrends = (params) => request.get('/stats/trends', { params })
+export const getDashboardAppRollups = (params) => request.get('/stats/apps', { params })
 
 // 存储服务
 export const uploadFile = (formData) => request.post('/files', formData, { headers: { 'Content-Type': 'multipart/form-data' } })
 export const getFileList = (params) => request.get('/files', { params })
-export const downloadFile = (id) => request.get(`/files/download/${id}`, { responseType: 'blob' })
-export const deleteFile = (id) => request.delete(`/files/${id}`)
+export const downloadFile = (appId, id) => request.get(`/files/download/${id}`, { params: { app_id: appId }, responseType: 'blob' })
+export const deleteFile = (appId, id) => request.delete(`/files/${id}`, { params: { app_id: appId } })
 export const getFileStats = (params) => request.get('/files/stats', { params })
-export const batchDeleteFiles = (ids) => request.post('/files/batch-delete', { ids })
+export const batchDeleteFiles = (appId, ids) => request.post('/files/batch-delete', { app_id: appId, ids })
 
 // 事件定义管理
 export const getEventDefinitions = (params) => request.get('/events/definitions', { params })
 export const createEventDefinition = (data) => request.post('/events/definitions', data)
-export const updateEventDefinition = (id, data) => request.put(`/events/definitions/${id}`, data)
-export const deleteEventDefinition = (id) => request.delete(`/events/definitions/${id}`)
+export const updateEventDefinition = (appId, id, data) => request.put(`/events/definitions/${id}`, data, { params: { app_id: appId } })
+export const deleteEventDefinition = (appId, id) => request.delete(`/events/definitions/${id}`, { params: { app_id: appId } })
 
 // 告警规则管理
-export const updateAlert = (id, data) => request.put(`/monitor/alerts/${id}`, data)
-export const deleteAlert = (id) => request.delete(`/monitor/alerts/${id}`)
+export const updateAlert = (appId, id, data) => request.put(`/monitor/alerts/${id}`, data, { params: { app_id: appId } })
+export const deleteAlert = (appId, id) => request.delete(`/monitor/alerts/${id}`, { params: { app_id: appId } })
 export const getHealthCheck = (params) => request.get('/monitor/health', { params })
 
 // 审计日志API
@@ -0,0 +1,21 @@
+import request from '@/utils/request'
+
+// 组织
+export const getOrgList = () => request.get('/orgs')
+export const createOrg = (data) => request.post('/orgs', data)
+export const getOrgDetail = (orgId) => request.get(`/orgs/${orgId}`)
+export const updateOrg = (orgId, data) => request.put(`/orgs/${orgId}`, data)
+export const deleteOrg = (orgId) => request.delete(`/orgs/${orgId}`)
+export const leaveOrg = (orgId) => request.post(`/orgs/${orgId}/leave`)
+
+// 成员与邀请
+export const getOrgMembers = (orgId) => request.get(`/orgs/${orgId}/members`)
+export const updateOrgMember = (orgId, adminId, role) => request.put(`/orgs/${orgId}/members/${adminId}`, { role })
+export const removeOrgMember = (orgId, adminId) => request.delete(`/orgs/${orgId}/members/${adminId}`)
+export const getOrgInvitations = (orgId) => request.get(`/orgs/${orgId}/invitations`)
+export const inviteOrgMember = (orgId, data) => request.post(`/orgs/${orgId}/invitations`, data)
+export const revokeOrgInvitation = (orgId, invitationId) => request.delete(`/orgs/${orgId}/invitations/${invitationId}`)
+export const acceptOrgInvitation = (token) => request.post('/org-invitations/accept', { token })
+
+// 应用归属
+export const transferApp = (appId, orgId) => request.post(`/apps/${appId}/transfer`, { org_id: orgId })